@@ -5,9 +5,13 @@
 package cache
 
 import (
+	"context"
+	"io"
 	"time"
 
+	"github.com/garthoid/asset-db/internal/transfer"
 	"github.com/garthoid/asset-db/repository"
+	"github.com/garthoid/asset-db/types"
 )
 
 type Cache struct {
@@ -36,7 +40,64 @@ func (c *Cache) Close() error {
 	return c.cache.Close()
 }
 
+// Ping implements the Repository interface.
+func (c *Cache) Ping(ctx context.Context) error {
+	if err := c.cache.Ping(ctx); err != nil {
+		return err
+	}
+	return c.db.Ping(ctx)
+}
+
+// Maintenance implements the Repository interface.
+func (c *Cache) Maintenance(ctx context.Context) error {
+	if err := c.cache.Maintenance(ctx); err != nil {
+		return err
+	}
+	return c.db.Maintenance(ctx)
+}
+
+// Stats implements the Repository interface.
+// The statistics are those of the database, since the cache only holds a subset of its records.
+func (c *Cache) Stats(ctx context.Context) (*types.DBStats, error) {
+	return c.db.Stats(ctx)
+}
+
 // GetDBType implements the Repository interface.
 func (c *Cache) GetDBType() string {
 	return c.db.GetDBType()
 }
+
+// ExportJSON implements the Repository interface.
+// The records are exported from the database, since the cache only holds a subset of them.
+func (c *Cache) ExportJSON(ctx context.Context, w io.Writer) error {
+	return c.db.ExportJSON(ctx, w)
+}
+
+// ExportSubgraph implements the Repository interface.
+// The subgraph is traversed through the cache, so the root must be an entity of the cache, and the
+// entities and edges reached in the database are added to the cache along the way.
+func (c *Cache) ExportSubgraph(ctx context.Context, root *types.Entity, maxDepth int, w io.Writer) error {
+	return transfer.ExportSubgraph(ctx, c, root, maxDepth, w)
+}
+
+// ImportJSON implements the Repository interface.
+// The records are created through the cache, so they are written to both the cache and the database.
+func (c *Cache) ImportJSON(ctx context.Context, r io.Reader) (types.ImportStats, error) {
+	return transfer.ImportJSON(ctx, c, r)
+}
+
+// WithTransaction implements the Repository interface.
+// The cache and the database are each scoped to a transaction, so that a failure
+// rolls back the changes made to both of them.
+func (c *Cache) WithTransaction(ctx context.Context, fn func(tx repository.Repository) error) error {
+	return c.cache.WithTransaction(ctx, func(cachetx repository.Repository) error {
+		return c.db.WithTransaction(ctx, func(dbtx repository.Repository) error {
+			return fn(&Cache{
+				start: c.start,
+				freq:  c.freq,
+				cache: cachetx,
+				db:    dbtx,
+			})
+		})
+	})
+}
@@ -5,13 +5,14 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"time"
 
 	"github.com/garthoid/asset-db/types"
 )
 
-func (c *Cache) createCacheEntityTag(entity *types.Entity, name, refID string, since time.Time) error {
+func (c *Cache) createCacheEntityTag(ctx context.Context, entity *types.Entity, name, refID string, since time.Time) error {
 	if entity == nil {
 		return errors.New("entity cannot be nil")
 	} else if name == "" {
@@ -20,13 +21,13 @@ func (c *Cache) createCacheEntityTag(entity *types.Entity, name, refID string, s
 		return errors.New("reference ID cannot be empty")
 	}
 	// remove all existing tags with the same name
-	if tags, err := c.cache.GetEntityTags(entity, c.start, name); err == nil {
+	if tags, err := c.cache.GetEntityTags(ctx, entity, c.start, name); err == nil {
 		for _, tag := range tags {
-			_ = c.cache.DeleteEntityTag(tag.ID)
+			_, _ = c.cache.DeleteEntityTag(ctx, tag.ID)
 		}
 	}
 
-	_, err := c.cache.CreateEntityProperty(entity, &types.CacheProperty{
+	_, err := c.cache.CreateEntityProperty(ctx, entity, &types.CacheProperty{
 		ID:        name,
 		RefID:     refID,
 		Timestamp: since.Format(time.RFC3339Nano),
@@ -34,12 +35,12 @@ func (c *Cache) createCacheEntityTag(entity *types.Entity, name, refID string, s
 	return err
 }
 
-func (c *Cache) checkCacheEntityTag(entity *types.Entity, name string) (*types.EntityTag, time.Time, bool) {
+func (c *Cache) checkCacheEntityTag(ctx context.Context, entity *types.Entity, name string) (*types.EntityTag, time.Time, bool) {
 	if entity == nil || name == "" {
 		return nil, time.Time{}, false
 	}
 
-	if tags, err := c.cache.GetEntityTags(entity, c.start, name); err == nil && len(tags) == 1 {
+	if tags, err := c.cache.GetEntityTags(ctx, entity, c.start, name); err == nil && len(tags) == 1 {
 		tag := tags[0]
 
 		prop, ok := tag.Property.(*types.CacheProperty)
@@ -60,7 +61,7 @@ func (c *Cache) checkCacheEntityTag(entity *types.Entity, name string) (*types.E
 	return nil, time.Time{}, false
 }
 
-func (c *Cache) createCacheEdgeTag(edge *types.Edge, name, refID string, since time.Time) error {
+func (c *Cache) createCacheEdgeTag(ctx context.Context, edge *types.Edge, name, refID string, since time.Time) error {
 	if edge == nil {
 		return errors.New("entity cannot be nil")
 	} else if name == "" {
@@ -69,13 +70,13 @@ func (c *Cache) createCacheEdgeTag(edge *types.Edge, name, refID string, since t
 		return errors.New("reference ID cannot be empty")
 	}
 	// remove all existing tags with the same name
-	if tags, err := c.cache.GetEdgeTags(edge, c.start, name); err == nil {
+	if tags, err := c.cache.GetEdgeTags(ctx, edge, c.start, name); err == nil {
 		for _, tag := range tags {
-			_ = c.cache.DeleteEdgeTag(tag.ID)
+			_, _ = c.cache.DeleteEdgeTag(ctx, tag.ID)
 		}
 	}
 
-	_, err := c.cache.CreateEdgeProperty(edge, &types.CacheProperty{
+	_, err := c.cache.CreateEdgeProperty(ctx, edge, &types.CacheProperty{
 		ID:        name,
 		RefID:     refID,
 		Timestamp: since.Format(time.RFC3339Nano),
@@ -83,12 +84,12 @@ func (c *Cache) createCacheEdgeTag(edge *types.Edge, name, refID string, since t
 	return err
 }
 
-func (c *Cache) checkCacheEdgeTag(edge *types.Edge, name string) (*types.EdgeTag, time.Time, bool) {
+func (c *Cache) checkCacheEdgeTag(ctx context.Context, edge *types.Edge, name string) (*types.EdgeTag, time.Time, bool) {
 	if edge == nil || name == "" {
 		return nil, time.Time{}, false
 	}
 
-	if tags, err := c.cache.GetEdgeTags(edge, c.start, name); err == nil && len(tags) == 1 {
+	if tags, err := c.cache.GetEdgeTags(ctx, edge, c.start, name); err == nil && len(tags) == 1 {
 		tag := tags[0]
 
 		prop, ok := tag.Property.(*types.CacheProperty)
@@ -5,6 +5,7 @@
 package cache
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -27,7 +28,7 @@ func TestCacheEntityTag(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { _ = c.Close() }()
 
-	db2ent, err := db2.CreateEntity(&types.Entity{
+	db2ent, err := db2.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: time.Now(),
 		LastSeen:  time.Now(),
 		Asset:     &dns.FQDN{Name: "owasp.org"},
@@ -35,11 +36,11 @@ func TestCacheEntityTag(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, db2ent)
 
-	tag, _, ok := c.checkCacheEntityTag(nil, "cache_create_entity")
+	tag, _, ok := c.checkCacheEntityTag(context.Background(), nil, "cache_create_entity")
 	assert.Nil(t, tag)
 	assert.False(t, ok)
 
-	entity, err := c.CreateEntity(&types.Entity{
+	entity, err := c.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: time.Now(),
 		LastSeen:  time.Now(),
 		Asset:     &dns.FQDN{Name: "owasp.org"},
@@ -47,13 +48,13 @@ func TestCacheEntityTag(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, entity)
 
-	tag, _, ok = c.checkCacheEntityTag(entity, "cache_create_entity")
+	tag, _, ok = c.checkCacheEntityTag(context.Background(), entity, "cache_create_entity")
 	assert.NotNil(t, tag)
 	assert.False(t, ok)
 	assert.Equal(t, db2ent.ID, tag.Property.Value())
 
 	time.Sleep(3 * time.Second) // Ensure the tag is expired
-	tag, _, ok = c.checkCacheEntityTag(entity, "cache_create_entity")
+	tag, _, ok = c.checkCacheEntityTag(context.Background(), entity, "cache_create_entity")
 	assert.NotNil(t, tag)
 	assert.True(t, ok)
 	assert.Equal(t, db2ent.ID, tag.Property.Value())
@@ -73,7 +74,7 @@ func TestCacheEdgeTag(t *testing.T) {
 	defer func() { _ = c.Close() }()
 
 	now := time.Now()
-	db2ent1, err := db2.CreateEntity(&types.Entity{
+	db2ent1, err := db2.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: now,
 		LastSeen:  now,
 		Asset:     &dns.FQDN{Name: "owasp.org"},
@@ -82,7 +83,7 @@ func TestCacheEdgeTag(t *testing.T) {
 	assert.NotNil(t, db2ent1)
 
 	now = time.Now()
-	db2ent2, err := db2.CreateEntity(&types.Entity{
+	db2ent2, err := db2.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: now,
 		LastSeen:  now,
 		Asset:     &dns.FQDN{Name: "example.com"},
@@ -91,7 +92,7 @@ func TestCacheEdgeTag(t *testing.T) {
 	assert.NotNil(t, db2ent2)
 
 	now = time.Now()
-	db2edge, err := db2.CreateEdge(&types.Edge{
+	db2edge, err := db2.CreateEdge(context.Background(), &types.Edge{
 		CreatedAt: now,
 		LastSeen:  now,
 		Relation: &dns.BasicDNSRelation{
@@ -108,12 +109,12 @@ func TestCacheEdgeTag(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, db2edge)
 
-	tag, _, ok := c.checkCacheEdgeTag(nil, "cache_create_edge")
+	tag, _, ok := c.checkCacheEdgeTag(context.Background(), nil, "cache_create_edge")
 	assert.Nil(t, tag)
 	assert.False(t, ok)
 
 	now = time.Now()
-	entity1, err := c.CreateEntity(&types.Entity{
+	entity1, err := c.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: now,
 		LastSeen:  now,
 		Asset:     &dns.FQDN{Name: "owasp.org"},
@@ -122,7 +123,7 @@ func TestCacheEdgeTag(t *testing.T) {
 	assert.NotNil(t, entity1)
 
 	now = time.Now()
-	entity2, err := c.CreateEntity(&types.Entity{
+	entity2, err := c.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: now,
 		LastSeen:  now,
 		Asset:     &dns.FQDN{Name: "example.com"},
@@ -131,7 +132,7 @@ func TestCacheEdgeTag(t *testing.T) {
 	assert.NotNil(t, entity2)
 
 	now = time.Now()
-	edge, err := c.CreateEdge(&types.Edge{
+	edge, err := c.CreateEdge(context.Background(), &types.Edge{
 		CreatedAt: now,
 		LastSeen:  now,
 		Relation: &dns.BasicDNSRelation{
@@ -148,13 +149,13 @@ func TestCacheEdgeTag(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, edge)
 
-	tag, _, ok = c.checkCacheEdgeTag(edge, "cache_create_edge")
+	tag, _, ok = c.checkCacheEdgeTag(context.Background(), edge, "cache_create_edge")
 	assert.NotNil(t, tag)
 	assert.False(t, ok)
 	assert.Equal(t, db2edge.ID, tag.Property.Value())
 
 	time.Sleep(3 * time.Second) // Ensure the tag is expired
-	tag, _, ok = c.checkCacheEdgeTag(edge, "cache_create_edge")
+	tag, _, ok = c.checkCacheEdgeTag(context.Background(), edge, "cache_create_edge")
 	assert.NotNil(t, tag)
 	assert.True(t, ok)
 	assert.Equal(t, db2edge.ID, tag.Property.Value())
@@ -5,6 +5,8 @@
 package cache
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -16,6 +18,7 @@ import (
 	assetdb "github.com/garthoid/asset-db"
 	"github.com/garthoid/asset-db/repository"
 	"github.com/garthoid/asset-db/repository/sqlrepo"
+	"github.com/owasp-amass/open-asset-model/dns"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -66,6 +69,90 @@ func TestGetDBType(t *testing.T) {
 	}
 }
 
+func TestPing(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	cache, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = cache.Close() }()
+	assert.NoError(t, cache.Ping(context.Background()))
+
+	// the cache is unhealthy when the database is no longer reachable
+	_ = db2.Close()
+	assert.Error(t, cache.Ping(context.Background()))
+}
+
+func TestWithTransaction(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	cache, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = cache.Close() }()
+
+	fqdn := &dns.FQDN{Name: "commit.owasp.org"}
+	err = cache.WithTransaction(context.Background(), func(tx repository.Repository) error {
+		_, err := tx.CreateAsset(context.Background(), fqdn)
+		return err
+	})
+	assert.NoError(t, err)
+
+	_, err = db1.FindEntitiesByContent(context.Background(), fqdn, time.Time{})
+	assert.NoError(t, err)
+	_, err = db2.FindEntitiesByContent(context.Background(), fqdn, time.Time{})
+	assert.NoError(t, err)
+
+	// a failure rolls back both the cache and the database
+	fqdn = &dns.FQDN{Name: "rollback.owasp.org"}
+	err = cache.WithTransaction(context.Background(), func(tx repository.Repository) error {
+		if _, err := tx.CreateAsset(context.Background(), fqdn); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+
+	_, err = db1.FindEntitiesByContent(context.Background(), fqdn, time.Time{})
+	assert.Error(t, err)
+	_, err = db2.FindEntitiesByContent(context.Background(), fqdn, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestExportJSON(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	cache, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = cache.Close() }()
+
+	ctx := context.Background()
+	// the entity is only created in the database, which holds all the records
+	_, err = db2.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+
+	var expected, got bytes.Buffer
+	assert.NoError(t, db2.ExportJSON(ctx, &expected))
+	assert.NoError(t, cache.ExportJSON(ctx, &got))
+	assert.Contains(t, got.String(), `"name":"owasp.org"`)
+	assert.Equal(t, expected.String(), got.String())
+}
+
 func createTestRepositories() (repository.Repository, repository.Repository, string, error) {
 	dir, err := os.MkdirTemp("", fmt.Sprintf("test-%d", rand.Intn(100)))
 	if err != nil {
@@ -5,6 +5,7 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -12,36 +13,36 @@ import (
 )
 
 // CreateEdge implements the Repository interface.
-func (c *Cache) CreateEdge(edge *types.Edge) (*types.Edge, error) {
-	e, err := c.cache.CreateEdge(edge)
+func (c *Cache) CreateEdge(ctx context.Context, edge *types.Edge) (*types.Edge, error) {
+	e, err := c.cache.CreateEdge(ctx, edge)
 	if err != nil {
 		return nil, err
 	}
 
-	if tag, _, ok := c.checkCacheEdgeTag(edge, "cache_create_edge"); tag == nil || ok {
-		stag, _, _ := c.checkCacheEntityTag(e.FromEntity, "cache_create_entity")
+	if tag, _, ok := c.checkCacheEdgeTag(ctx, edge, "cache_create_edge"); tag == nil || ok {
+		stag, _, _ := c.checkCacheEntityTag(ctx, e.FromEntity, "cache_create_entity")
 		if stag == nil {
-			return nil, errors.New("cache entity tag not found")
+			return nil, types.NotFound("cache entity tag not found")
 		}
 		scp := stag.Property.(*types.CacheProperty)
 
-		otag2, _, _ := c.checkCacheEntityTag(e.ToEntity, "cache_create_entity")
+		otag2, _, _ := c.checkCacheEntityTag(ctx, e.ToEntity, "cache_create_entity")
 		if otag2 == nil {
-			return nil, errors.New("cache entity tag not found")
+			return nil, types.NotFound("cache entity tag not found")
 		}
 		ocp := otag2.Property.(*types.CacheProperty)
 
-		from, err := c.db.FindEntityById(scp.RefID)
+		from, err := c.db.FindEntityById(ctx, scp.RefID)
 		if err != nil || from == nil {
-			return nil, errors.New("source entity not found in database")
+			return nil, types.NotFound("source entity not found in database")
 		}
 
-		to, err := c.db.FindEntityById(ocp.RefID)
+		to, err := c.db.FindEntityById(ctx, ocp.RefID)
 		if err != nil || to == nil {
-			return nil, errors.New("destination entity not found in database")
+			return nil, types.NotFound("destination entity not found in database")
 		}
 
-		newedge, err := c.db.CreateEdge(&types.Edge{
+		newedge, err := c.db.CreateEdge(ctx, &types.Edge{
 			CreatedAt:  edge.CreatedAt,
 			LastSeen:   edge.LastSeen,
 			Relation:   e.Relation,
@@ -51,24 +52,72 @@ func (c *Cache) CreateEdge(edge *types.Edge) (*types.Edge, error) {
 		if err != nil || newedge == nil {
 			return nil, err
 		}
-		_ = c.createCacheEdgeTag(e, "cache_create_edge", newedge.ID, time.Now())
+		_ = c.createCacheEdgeTag(ctx, e, "cache_create_edge", newedge.ID, time.Now())
 	}
 
 	return e, err
 }
 
+// UpsertEdge implements the Repository interface.
+func (c *Cache) UpsertEdge(ctx context.Context, edge *types.Edge) (*types.Edge, types.UpsertResult, error) {
+	e, result, err := c.cache.UpsertEdge(ctx, edge)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if tag, _, ok := c.checkCacheEdgeTag(ctx, e, "cache_create_edge"); tag == nil || ok {
+		stag, _, _ := c.checkCacheEntityTag(ctx, e.FromEntity, "cache_create_entity")
+		if stag == nil {
+			return nil, "", types.NotFound("cache entity tag not found")
+		}
+		scp := stag.Property.(*types.CacheProperty)
+
+		otag, _, _ := c.checkCacheEntityTag(ctx, e.ToEntity, "cache_create_entity")
+		if otag == nil {
+			return nil, "", types.NotFound("cache entity tag not found")
+		}
+		ocp := otag.Property.(*types.CacheProperty)
+
+		from, err := c.db.FindEntityById(ctx, scp.RefID)
+		if err != nil || from == nil {
+			return nil, "", types.NotFound("source entity not found in database")
+		}
+
+		to, err := c.db.FindEntityById(ctx, ocp.RefID)
+		if err != nil || to == nil {
+			return nil, "", types.NotFound("destination entity not found in database")
+		}
+
+		// the database determines the outcome of the upsert
+		newedge, dbresult, err := c.db.UpsertEdge(ctx, &types.Edge{
+			CreatedAt:  edge.CreatedAt,
+			LastSeen:   edge.LastSeen,
+			Relation:   e.Relation,
+			FromEntity: from,
+			ToEntity:   to,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		result = dbresult
+		_ = c.createCacheEdgeTag(ctx, e, "cache_create_edge", newedge.ID, time.Now())
+	}
+
+	return e, result, nil
+}
+
 // FindEdgeById implements the Repository interface.
-func (c *Cache) FindEdgeById(id string) (*types.Edge, error) {
-	return c.cache.FindEdgeById(id)
+func (c *Cache) FindEdgeById(ctx context.Context, id string) (*types.Edge, error) {
+	return c.cache.FindEdgeById(ctx, id)
 }
 
 // IncomingEdges implements the Repository interface.
-func (c *Cache) IncomingEdges(entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+func (c *Cache) IncomingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
 	var refID string
 	var dbquery, found bool
 
 	if since.IsZero() || since.Before(c.start) {
-		if tag, ts, _ := c.checkCacheEntityTag(entity, "cache_incoming_edges"); tag == nil {
+		if tag, ts, _ := c.checkCacheEntityTag(ctx, entity, "cache_incoming_edges"); tag == nil {
 			dbquery = true
 		} else if since.Before(ts) {
 			found = true
@@ -79,54 +128,54 @@ func (c *Cache) IncomingEdges(entity *types.Entity, since time.Time, labels ...s
 
 	if dbquery {
 		if !found {
-			tag, _, _ := c.checkCacheEntityTag(entity, "cache_create_entity")
+			tag, _, _ := c.checkCacheEntityTag(ctx, entity, "cache_create_entity")
 			if tag == nil {
-				return nil, errors.New("cache entity tag not found")
+				return nil, types.NotFound("cache entity tag not found")
 			}
 			refID = tag.Property.(*types.CacheProperty).RefID
 		}
 
-		_ = c.createCacheEntityTag(entity, "cache_incoming_edges", refID, since)
+		_ = c.createCacheEntityTag(ctx, entity, "cache_incoming_edges", refID, since)
 
-		if dbedges, dberr := c.db.IncomingEdges(&types.Entity{ID: refID}, since); dberr == nil && len(dbedges) > 0 {
+		if dbedges, dberr := c.db.IncomingEdges(ctx, &types.Entity{ID: refID}, since); dberr == nil && len(dbedges) > 0 {
 			for _, edge := range dbedges {
-				e, err := c.db.FindEntityById(edge.FromEntity.ID)
+				e, err := c.db.FindEntityById(ctx, edge.FromEntity.ID)
 				if err != nil || e == nil {
 					continue
 				}
 				edge.FromEntity = e
 
-				if e, err := c.cache.CreateEntity(&types.Entity{
+				if e, err := c.cache.CreateEntity(ctx, &types.Entity{
 					CreatedAt: edge.FromEntity.CreatedAt,
 					LastSeen:  edge.FromEntity.LastSeen,
 					Asset:     edge.FromEntity.Asset,
 				}); err == nil && e != nil {
-					_ = c.createCacheEntityTag(e, "cache_create_entity", edge.FromEntity.ID, time.Now())
+					_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", edge.FromEntity.ID, time.Now())
 
-					if newedge, err := c.cache.CreateEdge(&types.Edge{
+					if newedge, err := c.cache.CreateEdge(ctx, &types.Edge{
 						CreatedAt:  edge.CreatedAt,
 						LastSeen:   edge.LastSeen,
 						Relation:   edge.Relation,
 						FromEntity: e,
 						ToEntity:   entity,
 					}); err == nil && newedge != nil {
-						_ = c.createCacheEdgeTag(newedge, "cache_create_edge", edge.ID, time.Now())
+						_ = c.createCacheEdgeTag(ctx, newedge, "cache_create_edge", edge.ID, time.Now())
 					}
 				}
 			}
 		}
 	}
 
-	return c.cache.IncomingEdges(entity, since, labels...)
+	return c.cache.IncomingEdges(ctx, entity, since, labels...)
 }
 
 // OutgoingEdges implements the Repository interface.
-func (c *Cache) OutgoingEdges(entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+func (c *Cache) OutgoingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
 	var refID string
 	var dbquery, found bool
 
 	if since.IsZero() || since.Before(c.start) {
-		if tag, ts, _ := c.checkCacheEntityTag(entity, "cache_outgoing_edges"); !found {
+		if tag, ts, _ := c.checkCacheEntityTag(ctx, entity, "cache_outgoing_edges"); !found {
 			dbquery = true
 		} else if since.Before(ts) {
 			found = true
@@ -137,57 +186,290 @@ func (c *Cache) OutgoingEdges(entity *types.Entity, since time.Time, labels ...s
 
 	if dbquery {
 		if !found {
-			tag, _, _ := c.checkCacheEntityTag(entity, "cache_create_entity")
+			tag, _, _ := c.checkCacheEntityTag(ctx, entity, "cache_create_entity")
 			if tag == nil {
-				return nil, errors.New("cache entity tag not found")
+				return nil, types.NotFound("cache entity tag not found")
 			}
 			refID = tag.Property.(*types.CacheProperty).RefID
 		}
 
-		_ = c.createCacheEntityTag(entity, "cache_outgoing_edges", refID, since)
+		_ = c.createCacheEntityTag(ctx, entity, "cache_outgoing_edges", refID, since)
 
-		if dbedges, dberr := c.db.OutgoingEdges(&types.Entity{ID: refID}, since); dberr == nil && len(dbedges) > 0 {
+		if dbedges, dberr := c.db.OutgoingEdges(ctx, &types.Entity{ID: refID}, since); dberr == nil && len(dbedges) > 0 {
 			for _, edge := range dbedges {
-				e, err := c.db.FindEntityById(edge.ToEntity.ID)
+				e, err := c.db.FindEntityById(ctx, edge.ToEntity.ID)
 				if err != nil || e == nil {
 					continue
 				}
 				edge.ToEntity = e
 
-				if e, err := c.cache.CreateEntity(&types.Entity{
+				if e, err := c.cache.CreateEntity(ctx, &types.Entity{
 					CreatedAt: edge.ToEntity.CreatedAt,
 					LastSeen:  edge.ToEntity.LastSeen,
 					Asset:     edge.ToEntity.Asset,
 				}); err == nil && e != nil {
-					_ = c.createCacheEntityTag(e, "cache_create_entity", edge.ToEntity.ID, time.Now())
+					_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", edge.ToEntity.ID, time.Now())
 
-					if newedge, err := c.cache.CreateEdge(&types.Edge{
+					if newedge, err := c.cache.CreateEdge(ctx, &types.Edge{
 						CreatedAt:  edge.CreatedAt,
 						LastSeen:   edge.LastSeen,
 						Relation:   edge.Relation,
 						FromEntity: entity,
 						ToEntity:   e,
 					}); err == nil && newedge != nil {
-						_ = c.createCacheEdgeTag(newedge, "cache_create_edge", edge.ID, time.Now())
+						_ = c.createCacheEdgeTag(ctx, newedge, "cache_create_edge", edge.ID, time.Now())
 					}
 				}
 			}
 		}
 	}
 
-	return c.cache.OutgoingEdges(entity, since, labels...)
+	return c.cache.OutgoingEdges(ctx, entity, since, labels...)
+}
+
+// IncomingEdgesBetween implements the Repository interface.
+// The edges last seen since the start of the window are brought into the cache
+// by IncomingEdges, so the window is then found within the cache.
+func (c *Cache) IncomingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	if _, err := c.IncomingEdges(ctx, entity, from); err != nil {
+		return nil, err
+	}
+	return c.cache.IncomingEdgesBetween(ctx, entity, from, to, labels...)
+}
+
+// OutgoingEdgesBetween implements the Repository interface.
+// The edges last seen since the start of the window are brought into the cache
+// by OutgoingEdges, so the window is then found within the cache.
+func (c *Cache) OutgoingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	if _, err := c.OutgoingEdges(ctx, entity, from); err != nil {
+		return nil, err
+	}
+	return c.cache.OutgoingEdgesBetween(ctx, entity, from, to, labels...)
+}
+
+// IncomingEdgesForAll implements the Repository interface.
+// The edges of each entity are found by IncomingEdges, so the edges in the
+// database that are missing from the cache are brought into it.
+func (c *Cache) IncomingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return c.edgesForAll(ctx, entities, func(entity *types.Entity) ([]*types.Edge, error) {
+		return c.IncomingEdges(ctx, entity, since, labels...)
+	})
+}
+
+// OutgoingEdgesForAll implements the Repository interface.
+// The edges of each entity are found by OutgoingEdges, so the edges in the
+// database that are missing from the cache are brought into it.
+func (c *Cache) OutgoingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return c.edgesForAll(ctx, entities, func(entity *types.Entity) ([]*types.Edge, error) {
+		return c.OutgoingEdges(ctx, entity, since, labels...)
+	})
+}
+
+// edgesForAll returns the edges found for each of the entities, keyed by the entity ID.
+func (c *Cache) edgesForAll(ctx context.Context, entities []*types.Entity, find func(*types.Entity) ([]*types.Edge, error)) (map[string][]*types.Edge, error) {
+	if len(entities) == 0 {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	results := make(map[string][]*types.Edge)
+	for _, entity := range entities {
+		if entity == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+		if _, found := results[entity.ID]; found {
+			continue
+		}
+
+		edges, err := find(entity)
+		if err != nil && !errors.Is(err, types.ErrNotFound) {
+			return nil, err
+		}
+		if len(edges) > 0 {
+			results[entity.ID] = edges
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero edges found")
+	}
+	return results, nil
+}
+
+// Neighborhood implements the Repository interface.
+// The traversal follows the outgoing edges of each entity through the cache, so the
+// entities and edges reached in the database are added to the cache along the way.
+func (c *Cache) Neighborhood(ctx context.Context, entity *types.Entity, maxDepth int, since time.Time, labels ...string) ([]*types.Entity, []*types.Edge, error) {
+	if entity == nil {
+		return nil, nil, errors.New("failed input validation checks")
+	}
+	if maxDepth < 1 {
+		return nil, nil, errors.New("the maximum depth must be at least one")
+	}
+
+	var entities []*types.Entity
+	var results []*types.Edge
+	frontier := []*types.Entity{entity}
+	visited := map[string]struct{}{entity.ID: {}}
+
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		var next []*types.Entity
+
+		for _, from := range frontier {
+			edges, err := c.OutgoingEdges(ctx, from, since, labels...)
+			if err != nil {
+				continue
+			}
+
+			for _, edge := range edges {
+				results = append(results, edge)
+				if _, found := visited[edge.ToEntity.ID]; found {
+					continue
+				}
+				visited[edge.ToEntity.ID] = struct{}{}
+
+				if e, err := c.cache.FindEntityById(ctx, edge.ToEntity.ID); err == nil {
+					entities = append(entities, e)
+					next = append(next, e)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	if len(results) == 0 {
+		return nil, nil, types.NotFound("zero edges found")
+	}
+	return entities, results, nil
+}
+
+// FindEdgesByLabel implements the Repository interface.
+func (c *Cache) FindEdgesByLabel(ctx context.Context, label string, since time.Time) ([]*types.Edge, error) {
+	// the database holds the complete set of edges, so the search is performed against it
+	dbedges, err := c.db.FindEdgesByLabel(ctx, label, since)
+	if err != nil {
+		return nil, err
+	}
+	return c.cacheEdges(ctx, dbedges)
+}
+
+// FindEdgesSince implements the Repository interface.
+// The tags of the edges are brought into the cache along with the edges.
+func (c *Cache) FindEdgesSince(ctx context.Context, since time.Time, includeTags bool) ([]*types.Edge, error) {
+	// the database holds the complete set of edges, so the search is performed against it
+	dbedges, err := c.db.FindEdgesSince(ctx, since, includeTags)
+	if err != nil {
+		return nil, err
+	}
+	return c.cacheEdges(ctx, dbedges)
+}
+
+// cacheEdges creates the database edges in the cache, along with the entities they connect and the tags held
+// in their Tags field. The edges whose entities cannot be brought into the cache are skipped.
+// Returns the edges of the cache, or an error matching types.ErrNotFound if none were created.
+func (c *Cache) cacheEdges(ctx context.Context, dbedges []*types.Edge) ([]*types.Edge, error) {
+	entities := make(map[string]*types.Entity)
+	entity := func(id string) *types.Entity {
+		if e, found := entities[id]; found {
+			return e
+		}
+
+		dbentity, err := c.db.FindEntityById(ctx, id)
+		if err != nil || dbentity == nil {
+			return nil
+		}
+
+		e, err := c.cache.CreateEntity(ctx, &types.Entity{
+			CreatedAt: dbentity.CreatedAt,
+			LastSeen:  dbentity.LastSeen,
+			Asset:     dbentity.Asset,
+		})
+		if err != nil || e == nil {
+			return nil
+		}
+
+		_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", id, time.Now())
+		entities[id] = e
+		return e
+	}
+
+	var results []*types.Edge
+	for _, edge := range dbedges {
+		from := entity(edge.FromEntity.ID)
+		to := entity(edge.ToEntity.ID)
+		if from == nil || to == nil {
+			continue
+		}
+
+		if e, err := c.cache.CreateEdge(ctx, &types.Edge{
+			CreatedAt:  edge.CreatedAt,
+			LastSeen:   edge.LastSeen,
+			Relation:   edge.Relation,
+			FromEntity: from,
+			ToEntity:   to,
+		}); err == nil && e != nil {
+			results = append(results, e)
+			_ = c.createCacheEdgeTag(ctx, e, "cache_create_edge", edge.ID, time.Now())
+
+			for _, tag := range edge.Tags {
+				if t, err := c.cache.CreateEdgeTag(ctx, e, &types.EdgeTag{
+					CreatedAt: tag.CreatedAt,
+					LastSeen:  tag.LastSeen,
+					ExpiresAt: tag.ExpiresAt,
+					Property:  tag.Property,
+				}); err == nil && t != nil {
+					e.Tags = append(e.Tags, t)
+				}
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero edges found")
+	}
+	return results, nil
+}
+
+// ResolveEdgeEndpoints implements the Repository interface.
+func (c *Cache) ResolveEdgeEndpoints(ctx context.Context, edges []*types.Edge) (map[string]*types.Entity, error) {
+	// the edges returned by the cache refer to the entities in the cache
+	return c.cache.ResolveEdgeEndpoints(ctx, edges)
+}
+
+// CountEdges implements the Repository interface.
+func (c *Cache) CountEdges(ctx context.Context, since time.Time) (int64, error) {
+	// the database holds the complete set of edges, so it determines the count
+	return c.db.CountEdges(ctx, since)
+}
+
+// EntityDegree implements the Repository interface.
+func (c *Cache) EntityDegree(ctx context.Context, entity *types.Entity, since time.Time) (int64, int64, error) {
+	if entity == nil {
+		return 0, 0, errors.New("failed input validation checks")
+	}
+
+	// the database holds the complete set of edges, so it determines the degree of the entity it refers to
+	tag, _, _ := c.checkCacheEntityTag(ctx, entity, "cache_create_entity")
+	if tag == nil {
+		return 0, 0, types.NotFound("cache entity tag not found")
+	}
+	return c.db.EntityDegree(ctx, &types.Entity{ID: tag.Property.(*types.CacheProperty).RefID}, since)
 }
 
 // DeleteEdge implements the Repository interface.
-func (c *Cache) DeleteEdge(id string) error {
-	tag, _, _ := c.checkCacheEdgeTag(&types.Edge{ID: id}, "cache_create_edge")
+// Returns the number of edges deleted from the database.
+func (c *Cache) DeleteEdge(ctx context.Context, id string) (int64, error) {
+	tag, _, _ := c.checkCacheEdgeTag(ctx, &types.Edge{ID: id}, "cache_create_edge")
 	if tag == nil {
-		return errors.New("cache edge tag not found")
+		return 0, types.NotFound("cache edge tag not found")
 	}
 	cp := tag.Property.(*types.CacheProperty)
 
-	if err := c.db.DeleteEdge(cp.RefID); err != nil {
-		return err
+	n, err := c.db.DeleteEdge(ctx, cp.RefID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := c.cache.DeleteEdge(ctx, id); err != nil {
+		return n, err
 	}
-	return c.cache.DeleteEdge(id)
+	return n, nil
 }
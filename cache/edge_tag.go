@@ -5,7 +5,7 @@
 package cache
 
 import (
-	"errors"
+	"context"
 	"time"
 
 	"github.com/garthoid/asset-db/types"
@@ -13,9 +13,9 @@ import (
 )
 
 // CreateEdgeTag implements the Repository interface.
-func (c *Cache) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag) (*types.EdgeTag, error) {
+func (c *Cache) CreateEdgeTag(ctx context.Context, edge *types.Edge, input *types.EdgeTag) (*types.EdgeTag, error) {
 	// if the tag already exists, then do not create it again
-	if tags, err := c.cache.GetEdgeTags(edge, time.Time{}, input.Property.Name()); err == nil && len(tags) > 0 {
+	if tags, err := c.cache.GetEdgeTags(ctx, edge, time.Time{}, input.Property.Name()); err == nil && len(tags) > 0 {
 		for _, tag := range tags {
 			if input.Property.Value() == tag.Property.Value() && tag.LastSeen.Add(c.freq).After(time.Now()) {
 				return tag, nil
@@ -23,25 +23,30 @@ func (c *Cache) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag) (*types.Ed
 		}
 	}
 
-	tag, err := c.cache.CreateEdgeTag(edge, input)
+	tag, err := c.cache.CreateEdgeTag(ctx, edge, input)
 	if err != nil {
 		return nil, err
 	}
 
-	ctag, _, _ := c.checkCacheEdgeTag(edge, "cache_create_edge")
+	ctag, _, _ := c.checkCacheEdgeTag(ctx, edge, "cache_create_edge")
 	if ctag == nil {
-		return nil, errors.New("cache edge tag not found")
+		return nil, types.NotFound("cache edge tag not found")
 	}
 	cp := ctag.Property.(*types.CacheProperty)
 
-	_, err = c.db.CreateEdgeProperty(&types.Edge{ID: cp.RefID}, input.Property)
+	_, err = c.db.CreateEdgeTag(ctx, &types.Edge{ID: cp.RefID}, &types.EdgeTag{
+		CreatedAt: input.CreatedAt,
+		LastSeen:  input.LastSeen,
+		ExpiresAt: input.ExpiresAt,
+		Property:  input.Property,
+	})
 	return tag, err
 }
 
 // CreateEdgeProperty implements the Repository interface.
-func (c *Cache) CreateEdgeProperty(edge *types.Edge, property oam.Property) (*types.EdgeTag, error) {
+func (c *Cache) CreateEdgeProperty(ctx context.Context, edge *types.Edge, property oam.Property) (*types.EdgeTag, error) {
 	// if the tag already exists, then do not create it again
-	if tags, err := c.cache.GetEdgeTags(edge, time.Time{}, property.Name()); err == nil && len(tags) > 0 {
+	if tags, err := c.cache.GetEdgeTags(ctx, edge, time.Time{}, property.Name()); err == nil && len(tags) > 0 {
 		for _, tag := range tags {
 			if property.Value() == tag.Property.Value() && tag.LastSeen.Add(c.freq).After(time.Now()) {
 				return tag, nil
@@ -49,42 +54,42 @@ func (c *Cache) CreateEdgeProperty(edge *types.Edge, property oam.Property) (*ty
 		}
 	}
 
-	tag, err := c.cache.CreateEdgeProperty(edge, property)
+	tag, err := c.cache.CreateEdgeProperty(ctx, edge, property)
 	if err != nil {
 		return nil, err
 	}
 
-	ctag, _, _ := c.checkCacheEdgeTag(edge, "cache_create_edge")
+	ctag, _, _ := c.checkCacheEdgeTag(ctx, edge, "cache_create_edge")
 	if ctag == nil {
-		return nil, errors.New("cache edge tag not found")
+		return nil, types.NotFound("cache edge tag not found")
 	}
 	cp := ctag.Property.(*types.CacheProperty)
 
-	_, err = c.db.CreateEdgeProperty(&types.Edge{ID: cp.RefID}, property)
+	_, err = c.db.CreateEdgeProperty(ctx, &types.Edge{ID: cp.RefID}, property)
 	return tag, err
 }
 
 // FindEdgeTagById implements the Repository interface.
-func (c *Cache) FindEdgeTagById(id string) (*types.EdgeTag, error) {
-	return c.cache.FindEdgeTagById(id)
+func (c *Cache) FindEdgeTagById(ctx context.Context, id string) (*types.EdgeTag, error) {
+	return c.cache.FindEdgeTagById(ctx, id)
 }
 
 // FindEdgeTagsByContent implements the Repository interface.
 // TODO: Consider adding a check for the last time the cache was updated
-func (c *Cache) FindEdgeTagsByContent(prop oam.Property, since time.Time) ([]*types.EdgeTag, error) {
+func (c *Cache) FindEdgeTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EdgeTag, error) {
 	if since.IsZero() || since.Before(c.start) {
 		var dbedges []*types.Edge
 		var froms, tos []*types.Entity
 
-		dbtags, dberr := c.db.FindEdgeTagsByContent(prop, since)
+		dbtags, dberr := c.db.FindEdgeTagsByContent(ctx, prop, since)
 		if dberr == nil && len(dbtags) > 0 {
 			for _, tag := range dbtags {
-				if edge, err := c.db.FindEdgeById(tag.Edge.ID); err == nil && edge != nil {
-					from, err := c.db.FindEntityById(edge.FromEntity.ID)
+				if edge, err := c.db.FindEdgeById(ctx, tag.Edge.ID); err == nil && edge != nil {
+					from, err := c.db.FindEntityById(ctx, edge.FromEntity.ID)
 					if err != nil {
 						continue
 					}
-					to, err := c.db.FindEntityById(edge.ToEntity.ID)
+					to, err := c.db.FindEntityById(ctx, edge.ToEntity.ID)
 					if err != nil {
 						continue
 					}
@@ -97,7 +102,7 @@ func (c *Cache) FindEdgeTagsByContent(prop oam.Property, since time.Time) ([]*ty
 
 		if dberr == nil {
 			for i, tag := range dbtags {
-				from, err := c.cache.CreateEntity(&types.Entity{
+				from, err := c.cache.CreateEntity(ctx, &types.Entity{
 					CreatedAt: froms[i].CreatedAt,
 					LastSeen:  froms[i].LastSeen,
 					Asset:     froms[i].Asset,
@@ -106,7 +111,7 @@ func (c *Cache) FindEdgeTagsByContent(prop oam.Property, since time.Time) ([]*ty
 					continue
 				}
 
-				to, err := c.cache.CreateEntity(&types.Entity{
+				to, err := c.cache.CreateEntity(ctx, &types.Entity{
 					CreatedAt: tos[i].CreatedAt,
 					LastSeen:  tos[i].LastSeen,
 					Asset:     tos[i].Asset,
@@ -115,7 +120,7 @@ func (c *Cache) FindEdgeTagsByContent(prop oam.Property, since time.Time) ([]*ty
 					continue
 				}
 
-				edge, err := c.cache.CreateEdge(&types.Edge{
+				edge, err := c.cache.CreateEdge(ctx, &types.Edge{
 					CreatedAt:  dbedges[i].CreatedAt,
 					LastSeen:   dbedges[i].LastSeen,
 					Relation:   dbedges[i].Relation,
@@ -126,79 +131,136 @@ func (c *Cache) FindEdgeTagsByContent(prop oam.Property, since time.Time) ([]*ty
 					continue
 				}
 
-				_, _ = c.cache.CreateEdgeTag(edge, &types.EdgeTag{
+				_, _ = c.cache.CreateEdgeTag(ctx, edge, &types.EdgeTag{
 					CreatedAt: tag.CreatedAt,
 					LastSeen:  tag.LastSeen,
+					ExpiresAt: tag.ExpiresAt,
 					Property:  tag.Property,
 				})
 			}
 		}
 	}
 
-	return c.cache.FindEdgeTagsByContent(prop, since)
+	return c.cache.FindEdgeTagsByContent(ctx, prop, since)
 }
 
 // GetEdgeTags implements the Repository interface.
-func (c *Cache) GetEdgeTags(edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error) {
+func (c *Cache) GetEdgeTags(ctx context.Context, edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error) {
 	var dbquery bool
 
 	if since.IsZero() || since.Before(c.start) {
-		if tag, ts, _ := c.checkCacheEdgeTag(edge, "cache_get_edge_tags"); tag == nil || since.Before(ts) {
+		if tag, ts, _ := c.checkCacheEdgeTag(ctx, edge, "cache_get_edge_tags"); tag == nil || since.Before(ts) {
 			dbquery = true
 		}
 	}
 
 	if dbquery {
-		ctag, _, _ := c.checkCacheEdgeTag(edge, "cache_create_edge")
+		ctag, _, _ := c.checkCacheEdgeTag(ctx, edge, "cache_create_edge")
 		if ctag == nil {
-			return nil, errors.New("cache edge tag not found")
+			return nil, types.NotFound("cache edge tag not found")
 		}
 		cp := ctag.Property.(*types.CacheProperty)
 
-		dbtags, dberr := c.db.GetEdgeTags(&types.Edge{ID: cp.RefID}, since)
-		_ = c.createCacheEdgeTag(edge, "cache_get_edge_tags", cp.RefID, since)
+		dbtags, dberr := c.db.GetEdgeTags(ctx, &types.Edge{ID: cp.RefID}, since)
+		_ = c.createCacheEdgeTag(ctx, edge, "cache_get_edge_tags", cp.RefID, since)
 
 		if dberr == nil && len(dbtags) > 0 {
 			for _, tag := range dbtags {
-				_, _ = c.cache.CreateEdgeTag(edge, &types.EdgeTag{
+				_, _ = c.cache.CreateEdgeTag(ctx, edge, &types.EdgeTag{
 					CreatedAt: tag.CreatedAt,
 					LastSeen:  tag.LastSeen,
+					ExpiresAt: tag.ExpiresAt,
 					Property:  tag.Property,
 				})
 			}
 		}
 	}
 
-	return c.cache.GetEdgeTags(edge, since, names...)
+	return c.cache.GetEdgeTags(ctx, edge, since, names...)
+}
+
+// GetEdgeTagsBetween implements the Repository interface.
+// The tags last seen since the start of the window are brought into the cache
+// by GetEdgeTags, so the window is then found within the cache.
+func (c *Cache) GetEdgeTagsBetween(ctx context.Context, edge *types.Edge, from, to time.Time, names ...string) ([]*types.EdgeTag, error) {
+	if _, err := c.GetEdgeTags(ctx, edge, from); err != nil {
+		return nil, err
+	}
+	return c.cache.GetEdgeTagsBetween(ctx, edge, from, to, names...)
+}
+
+// UpdateEdgeTag implements the Repository interface.
+func (c *Cache) UpdateEdgeTag(ctx context.Context, id string, value string) (*types.EdgeTag, error) {
+	tag, err := c.cache.FindEdgeTagById(ctx, id)
+	if err != nil {
+		return nil, types.ErrTagNotFound
+	}
+
+	updated, err := c.cache.UpdateEdgeTag(ctx, id, value)
+	if err != nil {
+		return nil, err
+	}
+
+	ctag, _, _ := c.checkCacheEdgeTag(ctx, tag.Edge, "cache_create_edge")
+	if ctag == nil {
+		return updated, nil
+	}
+	cp := ctag.Property.(*types.CacheProperty)
+
+	var ferr error
+	if tags, err := c.db.GetEdgeTags(ctx, &types.Edge{ID: cp.RefID},
+		time.Time{}, tag.Property.Name()); err == nil && len(tags) > 0 {
+		for _, t := range tags {
+			if tag.Property.Value() == t.Property.Value() {
+				if _, err := c.db.UpdateEdgeTag(ctx, t.ID, value); err != nil {
+					ferr = err
+				}
+			}
+		}
+	}
+	return updated, ferr
 }
 
 // DeleteEdgeTag implements the Repository interface.
-func (c *Cache) DeleteEdgeTag(id string) error {
-	tag, err := c.cache.FindEdgeTagById(id)
+// Returns the number of matching tags deleted from the database.
+func (c *Cache) DeleteEdgeTag(ctx context.Context, id string) (int64, error) {
+	tag, err := c.cache.FindEdgeTagById(ctx, id)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	ctag, _, _ := c.checkCacheEdgeTag(tag.Edge, "cache_create_edge")
+	ctag, _, _ := c.checkCacheEdgeTag(ctx, tag.Edge, "cache_create_edge")
 	if ctag == nil {
-		return err
+		return 0, err
 	}
 	cp := ctag.Property.(*types.CacheProperty)
 
-	if err := c.cache.DeleteEdgeTag(id); err != nil {
-		return err
+	if _, err := c.cache.DeleteEdgeTag(ctx, id); err != nil {
+		return 0, err
 	}
 
+	var total int64
 	var ferr error
-	if tags, err := c.db.GetEdgeTags(&types.Edge{ID: cp.RefID},
+	if tags, err := c.db.GetEdgeTags(ctx, &types.Edge{ID: cp.RefID},
 		time.Time{}, tag.Property.Name()); err == nil && len(tags) > 0 {
 		for _, t := range tags {
 			if tag.Property.Value() == t.Property.Value() {
-				if err := c.db.DeleteEdgeTag(t.ID); err != nil {
+				n, err := c.db.DeleteEdgeTag(ctx, t.ID)
+				if err != nil {
 					ferr = err
 				}
+				total += n
 			}
 		}
 	}
-	return ferr
+	return total, ferr
+}
+
+// PurgeExpiredTags implements the Repository interface.
+// Returns the number of expired tags removed from the database.
+func (c *Cache) PurgeExpiredTags(ctx context.Context, before time.Time) (int64, error) {
+	if _, err := c.cache.PurgeExpiredTags(ctx, before); err != nil {
+		return 0, err
+	}
+	return c.db.PurgeExpiredTags(ctx, before)
 }
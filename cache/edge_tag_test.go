@@ -5,6 +5,7 @@
 package cache
 
 import (
+	"context"
 	"os"
 	"reflect"
 	"testing"
@@ -34,9 +35,9 @@ func TestCreateEdgeTag(t *testing.T) {
 	ctime := now.Add(-8 * time.Hour)
 	before := ctime.Add(-2 * time.Second)
 	after := ctime.Add(2 * time.Second)
-	entity, err := c.CreateAsset(&dns.FQDN{Name: "owasp.org"})
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err)
-	tag, err := c.CreateEntityTag(entity, &types.EntityTag{
+	tag, err := c.CreateEntityTag(context.Background(), entity, &types.EntityTag{
 		CreatedAt: ctime,
 		LastSeen:  ctime,
 		Property: &general.SimpleProperty{
@@ -51,7 +52,7 @@ func TestCreateEdgeTag(t *testing.T) {
 	assert.WithinRange(t, tag.LastSeen, before, after)
 
 	time.Sleep(250 * time.Millisecond)
-	dbents, err := c.db.FindEntitiesByContent(entity.Asset, before)
+	dbents, err := c.db.FindEntitiesByContent(context.Background(), entity.Asset, before)
 	assert.NoError(t, err)
 
 	if num := len(dbents); num != 1 {
@@ -59,7 +60,7 @@ func TestCreateEdgeTag(t *testing.T) {
 	}
 	dbent := dbents[0]
 
-	dbtags, err := c.db.GetEntityTags(dbent, before, tag.Property.Name())
+	dbtags, err := c.db.GetEntityTags(context.Background(), dbent, before, tag.Property.Name())
 	assert.NoError(t, err)
 	if num := len(dbtags); num != 1 {
 		t.Errorf("failed to return the corrent number of tags: %d", num)
@@ -90,7 +91,7 @@ func TestCreateEdgeProperty(t *testing.T) {
 	before := now.Add(-2 * time.Second)
 	edge, err := createTestEdge(c, now)
 	assert.NoError(t, err)
-	tag, err := c.CreateEdgeProperty(edge, &general.SimpleProperty{
+	tag, err := c.CreateEdgeProperty(context.Background(), edge, &general.SimpleProperty{
 		PropertyName:  "test",
 		PropertyValue: "foobar",
 	})
@@ -101,13 +102,13 @@ func TestCreateEdgeProperty(t *testing.T) {
 	assert.WithinRange(t, tag.LastSeen, before, after)
 
 	time.Sleep(250 * time.Millisecond)
-	s, err := c.db.FindEntitiesByContent(edge.FromEntity.Asset, time.Time{})
+	s, err := c.db.FindEntitiesByContent(context.Background(), edge.FromEntity.Asset, time.Time{})
 	assert.NoError(t, err)
 
-	o, err := c.db.FindEntitiesByContent(edge.ToEntity.Asset, time.Time{})
+	o, err := c.db.FindEntitiesByContent(context.Background(), edge.ToEntity.Asset, time.Time{})
 	assert.NoError(t, err)
 
-	edges, err := c.db.OutgoingEdges(s[0], time.Time{}, edge.Relation.Label())
+	edges, err := c.db.OutgoingEdges(context.Background(), s[0], time.Time{}, edge.Relation.Label())
 	assert.NoError(t, err)
 
 	var target *types.Edge
@@ -118,7 +119,7 @@ func TestCreateEdgeProperty(t *testing.T) {
 		}
 	}
 
-	dbtags, err := c.db.GetEdgeTags(target, before, tag.Property.Name())
+	dbtags, err := c.db.GetEdgeTags(context.Background(), target, before, tag.Property.Name())
 	assert.NoError(t, err)
 	if num := len(dbtags); num != 1 {
 		t.Errorf("failed to return the corrent number of tags: %d", num)
@@ -147,13 +148,13 @@ func TestFindEdgeTagById(t *testing.T) {
 
 	edge, err := createTestEdge(c, time.Now())
 	assert.NoError(t, err)
-	tag, err := c.CreateEdgeProperty(edge, &general.SimpleProperty{
+	tag, err := c.CreateEdgeProperty(context.Background(), edge, &general.SimpleProperty{
 		PropertyName:  "test",
 		PropertyValue: "foobar",
 	})
 	assert.NoError(t, err)
 
-	tag2, err := c.FindEdgeTagById(tag.ID)
+	tag2, err := c.FindEdgeTagById(context.Background(), tag.ID)
 	assert.NoError(t, err)
 
 	if !reflect.DeepEqual(tag.Property, tag2.Property) {
@@ -184,7 +185,7 @@ func TestFindEdgeTagsByContent(t *testing.T) {
 		PropertyName:  "test1",
 		PropertyValue: "foobar",
 	}
-	_, err = c.CreateEdgeTag(edge, &types.EdgeTag{
+	_, err = c.CreateEdgeTag(context.Background(), edge, &types.EdgeTag{
 		CreatedAt: ctime1,
 		LastSeen:  ctime1,
 		Property:  prop1,
@@ -198,7 +199,7 @@ func TestFindEdgeTagsByContent(t *testing.T) {
 		PropertyName:  "test2",
 		PropertyValue: "foobar",
 	}
-	_, err = c.CreateEdgeTag(edge, &types.EdgeTag{
+	_, err = c.CreateEdgeTag(context.Background(), edge, &types.EdgeTag{
 		CreatedAt: ctime2,
 		LastSeen:  ctime2,
 		Property:  prop2,
@@ -210,26 +211,26 @@ func TestFindEdgeTagsByContent(t *testing.T) {
 		PropertyName:  "test3",
 		PropertyValue: "foobar",
 	}
-	_, err = c.CreateEdgeProperty(edge, prop3)
+	_, err = c.CreateEdgeProperty(context.Background(), edge, prop3)
 	assert.NoError(t, err)
 	after := time.Now().Add(time.Second)
 
-	_, err = c.FindEdgeTagsByContent(prop3, after)
+	_, err = c.FindEdgeTagsByContent(context.Background(), prop3, after)
 	assert.Error(t, err)
 
-	tags, err := c.FindEdgeTagsByContent(prop3, c.StartTime())
+	tags, err := c.FindEdgeTagsByContent(context.Background(), prop3, c.StartTime())
 	assert.NoError(t, err)
 	if len(tags) != 1 {
 		t.Errorf("first request failed to produce the expected number of tags")
 	}
 
-	tags, err = c.FindEdgeTagsByContent(prop2, cbefore2)
+	tags, err = c.FindEdgeTagsByContent(context.Background(), prop2, cbefore2)
 	assert.NoError(t, err)
 	if len(tags) != 1 {
 		t.Errorf("second request failed to produce the expected number of tags")
 	}
 
-	tags, err = c.FindEdgeTagsByContent(prop1, cbefore1)
+	tags, err = c.FindEdgeTagsByContent(context.Background(), prop1, cbefore1)
 	assert.NoError(t, err)
 	if len(tags) != 1 {
 		t.Errorf("third request failed to produce the expected number of tags")
@@ -256,13 +257,13 @@ func TestGetEdgeTags(t *testing.T) {
 	assert.NoError(t, err)
 
 	time.Sleep(250 * time.Millisecond)
-	s, err := c.db.FindEntitiesByContent(edge.FromEntity.Asset, time.Time{})
+	s, err := c.db.FindEntitiesByContent(context.Background(), edge.FromEntity.Asset, time.Time{})
 	assert.NoError(t, err)
 
-	o, err := c.db.FindEntitiesByContent(edge.ToEntity.Asset, time.Time{})
+	o, err := c.db.FindEntitiesByContent(context.Background(), edge.ToEntity.Asset, time.Time{})
 	assert.NoError(t, err)
 
-	edges, err := c.db.OutgoingEdges(s[0], time.Time{}, edge.Relation.Label())
+	edges, err := c.db.OutgoingEdges(context.Background(), s[0], time.Time{}, edge.Relation.Label())
 	assert.NoError(t, err)
 
 	var target *types.Edge
@@ -279,7 +280,7 @@ func TestGetEdgeTags(t *testing.T) {
 	// add some old stuff to the database
 	for _, name := range []string{"owasp.org", "utica.edu", "sunypoly.edu"} {
 		set1.Insert(name)
-		_, err := c.db.CreateEdgeTag(target, &types.EdgeTag{
+		_, err := c.db.CreateEdgeTag(context.Background(), target, &types.EdgeTag{
 			CreatedAt: ctime,
 			LastSeen:  ctime,
 			Property: &general.SimpleProperty{
@@ -295,7 +296,7 @@ func TestGetEdgeTags(t *testing.T) {
 	// add some new stuff to the database
 	for _, name := range []string{"www.owasp.org", "www.utica.edu", "www.sunypoly.edu"} {
 		set2.Insert(name)
-		_, err := c.CreateEdgeProperty(edge, &general.SimpleProperty{
+		_, err := c.CreateEdgeProperty(context.Background(), edge, &general.SimpleProperty{
 			PropertyName:  "test",
 			PropertyValue: name,
 		})
@@ -305,13 +306,13 @@ func TestGetEdgeTags(t *testing.T) {
 	after := time.Now()
 
 	// some tests that shouldn't return anything
-	_, err = c.GetEdgeTags(edge, after)
+	_, err = c.GetEdgeTags(context.Background(), edge, after)
 	assert.Error(t, err)
 	// there shouldn't be a tag for this entity, since it didn't require the database
-	_, err = c.cache.GetEdgeTags(edge, time.Time{}, "cache_get_edge_tags")
+	_, err = c.cache.GetEdgeTags(context.Background(), edge, time.Time{}, "cache_get_edge_tags")
 	assert.Error(t, err)
 
-	tags, err := c.GetEdgeTags(edge, c.StartTime(), "test")
+	tags, err := c.GetEdgeTags(context.Background(), edge, c.StartTime(), "test")
 	assert.NoError(t, err)
 	if num := len(tags); num != 3 {
 		t.Errorf("incorrect number of edge tags: %d", num)
@@ -325,10 +326,10 @@ func TestGetEdgeTags(t *testing.T) {
 		t.Errorf("first request failed to produce the correct tags")
 	}
 	// there shouldn't be a tag for this entity, since it didn't require the database
-	_, err = c.cache.GetEdgeTags(edge, time.Time{}, "cache_get_edge_tags")
+	_, err = c.cache.GetEdgeTags(context.Background(), edge, time.Time{}, "cache_get_edge_tags")
 	assert.Error(t, err)
 
-	tags, err = c.GetEdgeTags(edge, before, "test")
+	tags, err = c.GetEdgeTags(context.Background(), edge, before, "test")
 	assert.NoError(t, err)
 	if num := len(tags); num != 6 {
 		t.Errorf("incorrect number of edge tags: %d", num)
@@ -343,7 +344,7 @@ func TestGetEdgeTags(t *testing.T) {
 		t.Errorf("second request failed to produce the correct tags")
 	}
 	// there should be a tag for this entity
-	tags, err = c.cache.GetEdgeTags(edge, time.Time{}, "cache_get_edge_tags")
+	tags, err = c.cache.GetEdgeTags(context.Background(), edge, time.Time{}, "cache_get_edge_tags")
 	assert.NoError(t, err)
 	if len(tags) != 1 {
 		t.Errorf("second request failed to produce the expected number of edge tags")
@@ -351,6 +352,51 @@ func TestGetEdgeTags(t *testing.T) {
 	assert.WithinRange(t, tags[0].CreatedAt, before, after)
 }
 
+func TestUpdateEdgeTag(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	edge, err := createTestEdge(c, time.Now())
+	assert.NoError(t, err)
+
+	tag, err := c.CreateEdgeProperty(context.Background(), edge, &general.SimpleProperty{
+		PropertyName:  "test",
+		PropertyValue: "foo",
+	})
+	assert.NoError(t, err)
+	time.Sleep(250 * time.Millisecond)
+
+	updated, err := c.UpdateEdgeTag(context.Background(), tag.ID, "bar")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", updated.Property.Value())
+	assert.Equal(t, tag.CreatedAt, updated.CreatedAt)
+
+	s, err := c.db.FindEntitiesByContent(context.Background(), edge.FromEntity.Asset, time.Time{})
+	assert.NoError(t, err)
+
+	edges, err := c.db.OutgoingEdges(context.Background(), s[0], time.Time{}, edge.Relation.Label())
+	assert.NoError(t, err)
+
+	dbtags, err := c.db.GetEdgeTags(context.Background(), edges[0], time.Time{}, "test")
+	assert.NoError(t, err)
+	if num := len(dbtags); num != 1 {
+		t.Errorf("failed to return the corrent number of tags: %d", num)
+	}
+	assert.Equal(t, "bar", dbtags[0].Property.Value())
+
+	_, err = c.UpdateEdgeTag(context.Background(), "9999", "bar")
+	assert.ErrorIs(t, err, types.ErrTagNotFound)
+}
+
 func TestDeleteEdgeTag(t *testing.T) {
 	db1, db2, dir, err := createTestRepositories()
 	assert.NoError(t, err)
@@ -368,13 +414,13 @@ func TestDeleteEdgeTag(t *testing.T) {
 	assert.NoError(t, err)
 
 	time.Sleep(250 * time.Millisecond)
-	s, err := c.db.FindEntitiesByContent(edge.FromEntity.Asset, time.Time{})
+	s, err := c.db.FindEntitiesByContent(context.Background(), edge.FromEntity.Asset, time.Time{})
 	assert.NoError(t, err)
 
-	o, err := c.db.FindEntitiesByContent(edge.ToEntity.Asset, time.Time{})
+	o, err := c.db.FindEntitiesByContent(context.Background(), edge.ToEntity.Asset, time.Time{})
 	assert.NoError(t, err)
 
-	edges, err := c.db.OutgoingEdges(s[0], time.Time{}, edge.Relation.Label())
+	edges, err := c.db.OutgoingEdges(context.Background(), s[0], time.Time{}, edge.Relation.Label())
 	assert.NoError(t, err)
 
 	var target *types.Edge
@@ -385,20 +431,20 @@ func TestDeleteEdgeTag(t *testing.T) {
 		}
 	}
 
-	tag, err := c.CreateEdgeProperty(edge, &general.SimpleProperty{
+	tag, err := c.CreateEdgeProperty(context.Background(), edge, &general.SimpleProperty{
 		PropertyName:  "test",
 		PropertyValue: "foobar",
 	})
 	assert.NoError(t, err)
 	time.Sleep(250 * time.Millisecond)
 
-	err = c.DeleteEdgeTag(tag.ID)
+	_, err = c.DeleteEdgeTag(context.Background(), tag.ID)
 	assert.NoError(t, err)
 	time.Sleep(250 * time.Millisecond)
 
-	_, err = c.FindEdgeTagById(tag.ID)
+	_, err = c.FindEdgeTagById(context.Background(), tag.ID)
 	assert.Error(t, err)
 
-	_, err = c.db.GetEdgeTags(target, c.StartTime())
+	_, err = c.db.GetEdgeTags(context.Background(), target, c.StartTime())
 	assert.Error(t, err)
 }
@@ -5,6 +5,7 @@
 package cache
 
 import (
+	"context"
 	"os"
 	"reflect"
 	"testing"
@@ -40,12 +41,12 @@ func TestCreateEdge(t *testing.T) {
 	assert.WithinRange(t, edge.CreatedAt, before, after)
 	assert.WithinRange(t, edge.LastSeen, before, after)
 
-	if tags, err := c.cache.GetEdgeTags(edge, time.Time{}, "cache_create_edge"); err != nil || len(tags) != 1 {
+	if tags, err := c.cache.GetEdgeTags(context.Background(), edge, time.Time{}, "cache_create_edge"); err != nil || len(tags) != 1 {
 		t.Errorf("failed to create the cache tag:")
 	}
 
 	time.Sleep(250 * time.Millisecond)
-	dbents, err := c.db.FindEntitiesByContent(edge.FromEntity.Asset, before)
+	dbents, err := c.db.FindEntitiesByContent(context.Background(), edge.FromEntity.Asset, before)
 	assert.NoError(t, err)
 
 	if num := len(dbents); num != 1 {
@@ -53,7 +54,7 @@ func TestCreateEdge(t *testing.T) {
 	}
 	dbent := dbents[0]
 
-	dbedges, err := c.db.OutgoingEdges(dbent, before, "dns_record")
+	dbedges, err := c.db.OutgoingEdges(context.Background(), dbent, before, "dns_record")
 	assert.NoError(t, err)
 
 	if num := len(dbedges); num != 1 {
@@ -69,7 +70,7 @@ func TestCreateEdge(t *testing.T) {
 }
 
 func createTestEdge(cache *Cache, ctime time.Time) (*types.Edge, error) {
-	entity1, err := cache.CreateEntity(&types.Entity{
+	entity1, err := cache.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: ctime,
 		LastSeen:  ctime,
 		Asset:     &dns.FQDN{Name: "owasp.org"},
@@ -78,7 +79,7 @@ func createTestEdge(cache *Cache, ctime time.Time) (*types.Edge, error) {
 		return nil, err
 	}
 
-	entity2, err := cache.CreateEntity(&types.Entity{
+	entity2, err := cache.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: ctime,
 		LastSeen:  ctime,
 		Asset:     &dns.FQDN{Name: "www.owasp.org"},
@@ -87,7 +88,7 @@ func createTestEdge(cache *Cache, ctime time.Time) (*types.Edge, error) {
 		return nil, err
 	}
 
-	edge, err := cache.CreateEdge(&types.Edge{
+	edge, err := cache.CreateEdge(context.Background(), &types.Edge{
 		CreatedAt: ctime,
 		LastSeen:  ctime,
 		Relation: &dns.BasicDNSRelation{
@@ -129,7 +130,7 @@ func TestFindEdgeById(t *testing.T) {
 	edge, err := createTestEdge(c, ctime)
 	assert.NoError(t, err)
 
-	e, err := c.FindEdgeById(edge.ID)
+	e, err := c.FindEdgeById(context.Background(), edge.ID)
 	assert.NoError(t, err)
 
 	if !reflect.DeepEqual(edge.Relation, e.Relation) {
@@ -153,7 +154,7 @@ func TestIncomingEdges(t *testing.T) {
 	now := time.Now()
 	ctime := now.Add(-8 * time.Hour)
 	before := ctime.Add(-2 * time.Second)
-	from, err := c.CreateEntity(&types.Entity{
+	from, err := c.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: ctime,
 		LastSeen:  ctime,
 		Asset:     &dns.FQDN{Name: "caffix.com"},
@@ -161,7 +162,7 @@ func TestIncomingEdges(t *testing.T) {
 	assert.NoError(t, err)
 	time.Sleep(250 * time.Millisecond)
 
-	dbfrom, err := c.db.FindEntitiesByContent(from.Asset, time.Time{})
+	dbfrom, err := c.db.FindEntitiesByContent(context.Background(), from.Asset, time.Time{})
 	assert.NoError(t, err)
 
 	set1 := stringset.New()
@@ -170,13 +171,13 @@ func TestIncomingEdges(t *testing.T) {
 	var entities1 []*types.Entity
 	for _, name := range []string{"owasp.org", "utica.edu", "sunypoly.edu"} {
 		set1.Insert(name)
-		e, err := c.db.CreateEntity(&types.Entity{
+		e, err := c.db.CreateEntity(context.Background(), &types.Entity{
 			CreatedAt: ctime,
 			LastSeen:  ctime,
 			Asset:     &dns.FQDN{Name: name},
 		})
 		assert.NoError(t, err)
-		_, err = c.db.CreateEdge(&types.Edge{
+		_, err = c.db.CreateEdge(context.Background(), &types.Edge{
 			CreatedAt:  ctime,
 			LastSeen:   ctime,
 			Relation:   general.SimpleRelation{Name: "node"},
@@ -193,9 +194,9 @@ func TestIncomingEdges(t *testing.T) {
 	var entities2 []*types.Entity
 	for _, name := range []string{"www.owasp.org", "www.utica.edu", "www.sunypoly.edu"} {
 		set2.Insert(name)
-		e, err := c.CreateAsset(&dns.FQDN{Name: name})
+		e, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: name})
 		assert.NoError(t, err)
-		_, err = c.CreateEdge(&types.Edge{
+		_, err = c.CreateEdge(context.Background(), &types.Edge{
 			Relation:   general.SimpleRelation{Name: "node"},
 			FromEntity: from,
 			ToEntity:   e,
@@ -206,14 +207,14 @@ func TestIncomingEdges(t *testing.T) {
 	after := time.Now().Add(time.Second)
 
 	// some tests that shouldn't return anything
-	_, err = c.IncomingEdges(entities2[0], after)
+	_, err = c.IncomingEdges(context.Background(), entities2[0], after)
 	assert.Error(t, err)
 	// there shouldn't be a tag for this entity, since it didn't require the database
-	_, err = c.cache.GetEntityTags(entities2[0], time.Time{}, "cache_incoming_edges")
+	_, err = c.cache.GetEntityTags(context.Background(), entities2[0], time.Time{}, "cache_incoming_edges")
 	assert.Error(t, err)
 
 	for _, entity := range entities2 {
-		edges, err := c.IncomingEdges(entity, c.StartTime(), "node")
+		edges, err := c.IncomingEdges(context.Background(), entity, c.StartTime(), "node")
 		assert.NoError(t, err)
 		if len(edges) != 1 {
 			t.Errorf("%s had the incorrect number of incoming edges", entity.Asset.Key())
@@ -226,15 +227,15 @@ func TestIncomingEdges(t *testing.T) {
 		t.Errorf("first request failed to produce the correct edges")
 	}
 	// there shouldn't be a tag for this entity, since it didn't require the database
-	_, err = c.cache.GetEntityTags(entities2[0], time.Time{}, "cache_incoming_edges")
+	_, err = c.cache.GetEntityTags(context.Background(), entities2[0], time.Time{}, "cache_incoming_edges")
 	assert.Error(t, err)
 
 	var rentity *types.Entity
 	for _, entity := range entities1 {
-		e, err := c.FindEntitiesByContent(entity.Asset, time.Time{})
+		e, err := c.FindEntitiesByContent(context.Background(), entity.Asset, time.Time{})
 		assert.NoError(t, err)
 		rentity = e[0]
-		edges, err := c.IncomingEdges(rentity, before, "node")
+		edges, err := c.IncomingEdges(context.Background(), rentity, before, "node")
 		assert.NoError(t, err)
 		if len(edges) != 1 {
 			t.Errorf("%s had the incorrect number of incoming edges", rentity.Asset.Key())
@@ -247,7 +248,7 @@ func TestIncomingEdges(t *testing.T) {
 		t.Errorf("second request failed to produce the correct entities")
 	}
 	// there should be a tag for this entity
-	tags, err := c.cache.GetEntityTags(rentity, time.Time{}, "cache_incoming_edges")
+	tags, err := c.cache.GetEntityTags(context.Background(), rentity, time.Time{}, "cache_incoming_edges")
 	assert.NoError(t, err)
 	if len(tags) != 1 {
 		t.Errorf("second request failed to produce the expected number of entity tags")
@@ -275,7 +276,7 @@ func TestOutgoingEdges(t *testing.T) {
 	now := time.Now()
 	ctime := now.Add(-8 * time.Hour)
 	before := ctime.Add(-2 * time.Second)
-	from, err := c.CreateEntity(&types.Entity{
+	from, err := c.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: ctime,
 		LastSeen:  ctime,
 		Asset:     &dns.FQDN{Name: "caffix.com"},
@@ -283,7 +284,7 @@ func TestOutgoingEdges(t *testing.T) {
 	assert.NoError(t, err)
 	time.Sleep(250 * time.Millisecond)
 
-	dbfrom, err := c.db.FindEntitiesByContent(from.Asset, time.Time{})
+	dbfrom, err := c.db.FindEntitiesByContent(context.Background(), from.Asset, time.Time{})
 	assert.NoError(t, err)
 
 	set1 := stringset.New()
@@ -291,13 +292,13 @@ func TestOutgoingEdges(t *testing.T) {
 	// add some old stuff to the database
 	for _, name := range []string{"owasp.org", "utica.edu", "sunypoly.edu"} {
 		set1.Insert(name)
-		e, err := c.db.CreateEntity(&types.Entity{
+		e, err := c.db.CreateEntity(context.Background(), &types.Entity{
 			CreatedAt: ctime,
 			LastSeen:  ctime,
 			Asset:     &dns.FQDN{Name: name},
 		})
 		assert.NoError(t, err)
-		_, err = c.db.CreateEdge(&types.Edge{
+		_, err = c.db.CreateEdge(context.Background(), &types.Edge{
 			CreatedAt:  ctime,
 			LastSeen:   ctime,
 			Relation:   general.SimpleRelation{Name: "node"},
@@ -312,9 +313,9 @@ func TestOutgoingEdges(t *testing.T) {
 	// add some new stuff to the database
 	for _, name := range []string{"www.owasp.org", "www.utica.edu", "www.sunypoly.edu"} {
 		set2.Insert(name)
-		e, err := c.CreateAsset(&dns.FQDN{Name: name})
+		e, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: name})
 		assert.NoError(t, err)
-		_, err = c.CreateEdge(&types.Edge{
+		_, err = c.CreateEdge(context.Background(), &types.Edge{
 			Relation:   general.SimpleRelation{Name: "node"},
 			FromEntity: from,
 			ToEntity:   e,
@@ -324,20 +325,20 @@ func TestOutgoingEdges(t *testing.T) {
 	after := time.Now().Add(time.Second)
 
 	// some tests that shouldn't return anything
-	_, err = c.OutgoingEdges(from, after)
+	_, err = c.OutgoingEdges(context.Background(), from, after)
 	assert.Error(t, err)
 	// there shouldn't be a tag for this entity, since it didn't require the database
-	_, err = c.cache.GetEntityTags(from, time.Time{}, "cache_outgoing_edges")
+	_, err = c.cache.GetEntityTags(context.Background(), from, time.Time{}, "cache_outgoing_edges")
 	assert.Error(t, err)
 
-	edges, err := c.OutgoingEdges(from, c.StartTime(), "node")
+	edges, err := c.OutgoingEdges(context.Background(), from, c.StartTime(), "node")
 	assert.NoError(t, err)
 	if len(edges) != 3 {
 		t.Errorf("incorrect number of outgoing edges")
 	}
 
 	for _, edge := range edges {
-		e, err := c.FindEntityById(edge.ToEntity.ID)
+		e, err := c.FindEntityById(context.Background(), edge.ToEntity.ID)
 		assert.NoError(t, err)
 		set2.Remove(e.Asset.Key())
 	}
@@ -347,17 +348,17 @@ func TestOutgoingEdges(t *testing.T) {
 		t.Errorf("first request failed to produce the correct edges")
 	}
 	// there shouldn't be a tag for this entity, since it didn't require the database
-	_, err = c.cache.GetEntityTags(from, time.Time{}, "cache_outgoing_edges")
+	_, err = c.cache.GetEntityTags(context.Background(), from, time.Time{}, "cache_outgoing_edges")
 	assert.Error(t, err)
 
-	edges, err = c.OutgoingEdges(from, before, "node")
+	edges, err = c.OutgoingEdges(context.Background(), from, before, "node")
 	assert.NoError(t, err)
 	if len(edges) != 6 {
 		t.Errorf("incorrect number of outgoing edges")
 	}
 
 	for _, edge := range edges {
-		e, err := c.FindEntityById(edge.ToEntity.ID)
+		e, err := c.FindEntityById(context.Background(), edge.ToEntity.ID)
 		assert.NoError(t, err)
 		set1.Remove(e.Asset.Key())
 	}
@@ -367,7 +368,7 @@ func TestOutgoingEdges(t *testing.T) {
 		t.Errorf("second request failed to produce the correct entities")
 	}
 	// there should be a tag for this entity
-	tags, err := c.cache.GetEntityTags(from, time.Time{}, "cache_outgoing_edges")
+	tags, err := c.cache.GetEntityTags(context.Background(), from, time.Time{}, "cache_outgoing_edges")
 	assert.NoError(t, err)
 	if len(tags) != 1 {
 		t.Errorf("second request failed to produce the expected number of entity tags")
@@ -379,6 +380,44 @@ func TestOutgoingEdges(t *testing.T) {
 	assert.WithinRange(t, tagtime, before, after)
 }
 
+func TestEntityDegree(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	from, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+
+	// the edges only present in the database are included in the degree
+	dbfrom, err := db2.FindEntitiesByContent(context.Background(), from.Asset, time.Time{})
+	assert.NoError(t, err)
+	for _, name := range []string{"www.owasp.org", "mail.owasp.org"} {
+		to, err := db2.CreateAsset(context.Background(), &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		_, err = db2.CreateEdge(context.Background(), &types.Edge{
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: dbfrom[0],
+			ToEntity:   to,
+		})
+		assert.NoError(t, err)
+	}
+
+	in, out, err := c.EntityDegree(context.Background(), from, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 2}, []int64{in, out})
+
+	_, _, err = c.EntityDegree(context.Background(), nil, time.Time{})
+	assert.Error(t, err)
+}
+
 func TestDeleteEdge(t *testing.T) {
 	db1, db2, dir, err := createTestRepositories()
 	assert.NoError(t, err)
@@ -399,15 +438,202 @@ func TestDeleteEdge(t *testing.T) {
 	edge, err := createTestEdge(c, ctime)
 	assert.NoError(t, err)
 
-	err = c.DeleteEdge(edge.ID)
+	n, err := c.DeleteEdge(context.Background(), edge.ID)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
 
-	_, err = c.cache.FindEdgeById(edge.ID)
+	_, err = c.cache.FindEdgeById(context.Background(), edge.ID)
 	assert.Error(t, err)
 
 	time.Sleep(250 * time.Millisecond)
-	dbent, err := c.db.FindEntitiesByContent(edge.FromEntity.Asset, time.Time{})
+	dbent, err := c.db.FindEntitiesByContent(context.Background(), edge.FromEntity.Asset, time.Time{})
 	assert.NoError(t, err)
-	_, err = c.db.OutgoingEdges(dbent[0], before, edge.Relation.Label())
+	_, err = c.db.OutgoingEdges(context.Background(), dbent[0], before, edge.Relation.Label())
 	assert.Error(t, err)
 }
+
+func TestFindEdgesByLabel(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	// the edge only exists in the database
+	ctx := context.Background()
+	from, err := c.db.CreateAsset(ctx, &dns.FQDN{Name: "cname.owasp.org"})
+	assert.NoError(t, err)
+	to, err := c.db.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	_, err = c.db.CreateEdge(ctx, &types.Edge{
+		Relation:   &dns.BasicDNSRelation{Name: "dns_record", Header: dns.RRHeader{RRType: 5, Class: 1}},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	edges, err := c.FindEdgesByLabel(ctx, "dns_record", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 1)
+
+	// the edge and its entities are added to the cache
+	cached, err := c.cache.FindEdgesByLabel(ctx, "dns_record", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, cached, 1)
+	assert.Equal(t, edges[0].ID, cached[0].ID)
+
+	fe, err := c.cache.FindEntityById(ctx, edges[0].FromEntity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, from.Asset, fe.Asset)
+	te, err := c.cache.FindEntityById(ctx, edges[0].ToEntity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, to.Asset, te.Asset)
+
+	_, err = c.FindEdgesByLabel(ctx, "node", time.Time{})
+	assert.Error(t, err)
+}
+
+func TestFindEdgesSince(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	// the edge and its tag only exist in the database
+	ctx := context.Background()
+	from, err := c.db.CreateAsset(ctx, &dns.FQDN{Name: "cname.owasp.org"})
+	assert.NoError(t, err)
+	to, err := c.db.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	edge, err := c.db.CreateEdge(ctx, &types.Edge{
+		Relation:   &dns.BasicDNSRelation{Name: "dns_record", Header: dns.RRHeader{RRType: 5, Class: 1}},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	_, err = c.db.CreateEdgeProperty(ctx, edge, &general.SimpleProperty{PropertyName: "source", PropertyValue: "dns"})
+	assert.NoError(t, err)
+
+	edges, err := c.FindEdgesSince(ctx, time.Time{}, true)
+	assert.NoError(t, err)
+	if assert.Len(t, edges, 1) && assert.Len(t, edges[0].Tags, 1) {
+		assert.Equal(t, "dns", edges[0].Tags[0].Property.Value())
+	}
+
+	// the edge and its tag are added to the cache
+	cached, err := c.cache.GetEdgeTags(ctx, edges[0], time.Time{}, "source")
+	assert.NoError(t, err)
+	if assert.Len(t, cached, 1) {
+		assert.Equal(t, edges[0].Tags[0].ID, cached[0].ID)
+	}
+
+	_, err = c.FindEdgesSince(ctx, time.Now().Add(time.Minute), false)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestNeighborhood(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	root, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	dbroot, err := c.db.FindEntitiesByContent(context.Background(), root.Asset, time.Time{})
+	assert.NoError(t, err)
+
+	// add a chain of entities to the database
+	from := dbroot[0]
+	for _, name := range []string{"www.owasp.org", "mail.owasp.org"} {
+		to, err := c.db.CreateAsset(context.Background(), &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+
+		_, err = c.db.CreateEdge(context.Background(), &types.Edge{
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: from,
+			ToEntity:   to,
+		})
+		assert.NoError(t, err)
+		from = to
+	}
+
+	entities, edges, err := c.Neighborhood(context.Background(), root, 1, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 1)
+	assert.Len(t, edges, 1)
+
+	entities, edges, err = c.Neighborhood(context.Background(), root, 3, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 2)
+	if assert.Len(t, entities, 2) {
+		assert.Equal(t, "mail.owasp.org", entities[1].Asset.Key())
+	}
+
+	// the reached entities are added to the cache
+	_, err = db1.FindEntitiesByContent(context.Background(), &dns.FQDN{Name: "mail.owasp.org"}, time.Time{})
+	assert.NoError(t, err)
+}
+
+func TestOutgoingEdgesForAll(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	var roots []*types.Entity
+	for _, name := range []string{"owasp.org", "example.com"} {
+		root, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		roots = append(roots, root)
+
+		// add an edge to the database that is not in the cache
+		dbroot, err := c.db.FindEntitiesByContent(context.Background(), root.Asset, time.Time{})
+		assert.NoError(t, err)
+		to, err := c.db.CreateAsset(context.Background(), &dns.FQDN{Name: "www." + name})
+		assert.NoError(t, err)
+		_, err = c.db.CreateEdge(context.Background(), &types.Edge{
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: dbroot[0],
+			ToEntity:   to,
+		})
+		assert.NoError(t, err)
+	}
+
+	edges, err := c.OutgoingEdgesForAll(context.Background(), roots, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 2)
+	for _, root := range roots {
+		assert.Len(t, edges[root.ID], 1)
+	}
+
+	incoming, err := c.IncomingEdgesForAll(context.Background(), roots, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	assert.Empty(t, incoming)
+}
@@ -5,6 +5,7 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -13,8 +14,8 @@ import (
 )
 
 // CreateEntity implements the Repository interface.
-func (c *Cache) CreateEntity(input *types.Entity) (*types.Entity, error) {
-	entity, err := c.cache.CreateEntity(input)
+func (c *Cache) CreateEntity(ctx context.Context, input *types.Entity) (*types.Entity, error) {
+	entity, err := c.cache.CreateEntity(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -24,17 +25,17 @@ func (c *Cache) CreateEntity(input *types.Entity) (*types.Entity, error) {
 		// If the entity ID is set, it means that the entity was previously created,
 		// and we need to update that entity in the database regardless of frequency
 		create = true
-	} else if tag, _, ok := c.checkCacheEntityTag(entity, "cache_create_entity"); tag == nil || ok {
+	} else if tag, _, ok := c.checkCacheEntityTag(ctx, entity, "cache_create_entity"); tag == nil || ok {
 		create = true
 	}
 
 	if create {
-		if e, err := c.db.CreateEntity(&types.Entity{
+		if e, err := c.db.CreateEntity(ctx, &types.Entity{
 			CreatedAt: input.CreatedAt,
 			LastSeen:  input.LastSeen,
 			Asset:     input.Asset,
 		}); err == nil {
-			_ = c.createCacheEntityTag(entity, "cache_create_entity", e.ID, time.Now())
+			_ = c.createCacheEntityTag(ctx, entity, "cache_create_entity", e.ID, time.Now())
 		}
 	}
 
@@ -42,29 +43,150 @@ func (c *Cache) CreateEntity(input *types.Entity) (*types.Entity, error) {
 }
 
 // CreateAsset implements the Repository interface.
-func (c *Cache) CreateAsset(asset oam.Asset) (*types.Entity, error) {
-	entity, err := c.cache.CreateAsset(asset)
+func (c *Cache) CreateAsset(ctx context.Context, asset oam.Asset) (*types.Entity, error) {
+	entity, err := c.cache.CreateAsset(ctx, asset)
 	if err != nil {
 		return nil, err
 	}
 
-	if tag, _, ok := c.checkCacheEntityTag(entity, "cache_create_entity"); tag == nil || ok {
-		if e, err := c.db.CreateAsset(asset); err == nil {
-			_ = c.createCacheEntityTag(entity, "cache_create_entity", e.ID, time.Now())
+	if tag, _, ok := c.checkCacheEntityTag(ctx, entity, "cache_create_entity"); tag == nil || ok {
+		if e, err := c.db.CreateAsset(ctx, asset); err == nil {
+			_ = c.createCacheEntityTag(ctx, entity, "cache_create_entity", e.ID, time.Now())
 		}
 	}
 
 	return entity, err
 }
 
+// CreateEntities implements the Repository interface.
+func (c *Cache) CreateEntities(ctx context.Context, inputs []*types.Entity) ([]*types.Entity, error) {
+	entities, err := c.cache.CreateEntities(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*types.Entity
+	var dbinputs []*types.Entity
+	for i, entity := range entities {
+		// entities with the ID set are updated in the database regardless of frequency
+		if inputs[i].ID == "" {
+			if tag, _, ok := c.checkCacheEntityTag(ctx, entity, "cache_create_entity"); tag != nil && !ok {
+				continue
+			}
+		}
+
+		pending = append(pending, entity)
+		dbinputs = append(dbinputs, &types.Entity{
+			CreatedAt: inputs[i].CreatedAt,
+			LastSeen:  inputs[i].LastSeen,
+			Asset:     inputs[i].Asset,
+		})
+	}
+
+	if len(dbinputs) > 0 {
+		if dbents, err := c.db.CreateEntities(ctx, dbinputs); err == nil {
+			for i, e := range dbents {
+				_ = c.createCacheEntityTag(ctx, pending[i], "cache_create_entity", e.ID, time.Now())
+			}
+		}
+	}
+
+	return entities, nil
+}
+
+// UpsertEntity implements the Repository interface.
+func (c *Cache) UpsertEntity(ctx context.Context, input *types.Entity) (*types.Entity, types.UpsertResult, error) {
+	entity, result, err := c.cache.UpsertEntity(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if tag, _, ok := c.checkCacheEntityTag(ctx, entity, "cache_create_entity"); tag == nil || ok {
+		// the database determines the outcome of the upsert
+		if e, dbresult, err := c.db.UpsertEntity(ctx, &types.Entity{
+			CreatedAt: input.CreatedAt,
+			LastSeen:  input.LastSeen,
+			Asset:     input.Asset,
+		}); err == nil {
+			result = dbresult
+			_ = c.createCacheEntityTag(ctx, entity, "cache_create_entity", e.ID, time.Now())
+		}
+	}
+
+	return entity, result, nil
+}
+
+// UpdateEntity implements the Repository interface.
+// The entity is updated in the database first, so the cache is left unchanged when the update is rejected.
+func (c *Cache) UpdateEntity(ctx context.Context, id string, asset oam.Asset) (*types.Entity, error) {
+	tag, _, _ := c.checkCacheEntityTag(ctx, &types.Entity{ID: id}, "cache_create_entity")
+	if tag == nil {
+		return nil, types.NotFound("cache entity tag not found")
+	}
+	cp := tag.Property.(*types.CacheProperty)
+
+	if _, err := c.db.UpdateEntity(ctx, cp.RefID, asset); err != nil {
+		return nil, err
+	}
+	return c.cache.UpdateEntity(ctx, id, asset)
+}
+
+// TouchEntity implements the Repository interface.
+func (c *Cache) TouchEntity(ctx context.Context, id string) error {
+	tag, _, _ := c.checkCacheEntityTag(ctx, &types.Entity{ID: id}, "cache_create_entity")
+	if tag == nil {
+		return types.NotFound("cache entity tag not found")
+	}
+	cp := tag.Property.(*types.CacheProperty)
+
+	if err := c.cache.TouchEntity(ctx, id); err != nil {
+		return err
+	}
+	return c.db.TouchEntity(ctx, cp.RefID)
+}
+
 // FindEntityById implements the Repository interface.
-func (c *Cache) FindEntityById(id string) (*types.Entity, error) {
-	return c.cache.FindEntityById(id)
+func (c *Cache) FindEntityById(ctx context.Context, id string) (*types.Entity, error) {
+	return c.cache.FindEntityById(ctx, id)
+}
+
+// FindEntityByHash implements the Repository interface.
+func (c *Cache) FindEntityByHash(ctx context.Context, hash string) (*types.Entity, error) {
+	// the content hash of an entity is the same in the cache and the database
+	if entity, err := c.cache.FindEntityByHash(ctx, hash); err == nil {
+		return entity, nil
+	}
+
+	entity, err := c.db.FindEntityByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := c.cache.CreateEntity(ctx, &types.Entity{
+		CreatedAt: entity.CreatedAt,
+		LastSeen:  entity.LastSeen,
+		Asset:     entity.Asset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", entity.ID, time.Now())
+	return e, nil
+}
+
+// EntityExists implements the Repository interface.
+// The entity found in the database is not added to the cache, since only its existence is reported.
+func (c *Cache) EntityExists(ctx context.Context, asset oam.Asset) (bool, error) {
+	if found, err := c.cache.EntityExists(ctx, asset); err == nil && found {
+		return true, nil
+	}
+	return c.db.EntityExists(ctx, asset)
 }
 
 // FindEntitiesByContent implements the Repository interface.
-func (c *Cache) FindEntitiesByContent(asset oam.Asset, since time.Time) ([]*types.Entity, error) {
-	entities, err := c.cache.FindEntitiesByContent(asset, since)
+func (c *Cache) FindEntitiesByContent(ctx context.Context, asset oam.Asset, since time.Time) ([]*types.Entity, error) {
+	entities, err := c.cache.FindEntitiesByContent(ctx, asset, since)
 	if err == nil && len(entities) > 0 {
 		return entities, nil
 	}
@@ -73,71 +195,388 @@ func (c *Cache) FindEntitiesByContent(asset oam.Asset, since time.Time) ([]*type
 		return nil, err
 	}
 
-	dbentities, dberr := c.db.FindEntitiesByContent(asset, since)
+	dbentities, dberr := c.db.FindEntitiesByContent(ctx, asset, since)
 	if dberr != nil {
 		return entities, err
 	}
 
 	var results []*types.Entity
 	for _, entity := range dbentities {
-		if e, err := c.cache.CreateEntity(&types.Entity{
+		if e, err := c.cache.CreateEntity(ctx, &types.Entity{
 			CreatedAt: entity.CreatedAt,
 			LastSeen:  entity.LastSeen,
 			Asset:     entity.Asset,
 		}); err == nil {
 			results = append(results, e)
-			_ = c.createCacheEntityTag(e, "cache_create_entity", entity.ID, time.Now())
+			_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", entity.ID, time.Now())
 		}
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero entities found")
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// FindEntitiesByContents implements the Repository interface.
+func (c *Cache) FindEntitiesByContents(ctx context.Context, assets []oam.Asset, since time.Time) (map[string]*types.Entity, error) {
+	results, err := c.cache.FindEntitiesByContents(ctx, assets, since)
+	if err != nil {
+		return nil, err
+	}
+
+	if !since.IsZero() && !since.Before(c.start) {
+		return results, nil
+	}
+
+	var missing []oam.Asset
+	for _, asset := range assets {
+		if _, found := results[types.ContentHash(asset)]; !found {
+			missing = append(missing, asset)
+		}
+	}
+	if len(missing) == 0 {
+		return results, nil
+	}
+
+	dbentities, err := c.db.FindEntitiesByContents(ctx, missing, since)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, entity := range dbentities {
+		if e, err := c.cache.CreateEntity(ctx, &types.Entity{
+			CreatedAt: entity.CreatedAt,
+			LastSeen:  entity.LastSeen,
+			Asset:     entity.Asset,
+		}); err == nil {
+			results[key] = e
+			_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", entity.ID, time.Now())
+		}
 	}
 	return results, nil
 }
 
 // FindEntitiesByType implements the Repository interface.
-func (c *Cache) FindEntitiesByType(atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
-	entities, err := c.cache.FindEntitiesByType(atype, since)
+func (c *Cache) FindEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	entities, err := c.cache.FindEntitiesByType(ctx, atype, since)
 	if err == nil && len(entities) > 0 {
 		if !since.IsZero() && !since.Before(c.start) {
 			return entities, err
 		}
-		if tag, ts, _ := c.checkCacheEntityTag(entities[0], "cache_find_entities_by_type"); tag != nil && !since.Before(ts) {
+		if tag, ts, _ := c.checkCacheEntityTag(ctx, entities[0], "cache_find_entities_by_type"); tag != nil && !since.Before(ts) {
 			return entities, err
 		}
 	}
 
-	dbentities, dberr := c.db.FindEntitiesByType(atype, since)
+	dbentities, dberr := c.db.FindEntitiesByType(ctx, atype, since)
 	if dberr != nil {
 		return entities, err
 	}
 
 	var results []*types.Entity
 	for _, entity := range dbentities {
-		if e, err := c.cache.CreateEntity(&types.Entity{
+		if e, err := c.cache.CreateEntity(ctx, &types.Entity{
+			CreatedAt: entity.CreatedAt,
+			LastSeen:  entity.LastSeen,
+			Asset:     entity.Asset,
+		}); err == nil {
+			results = append(results, e)
+			_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", entity.ID, time.Now())
+			_ = c.createCacheEntityTag(ctx, entity, "cache_find_entities_by_type", entity.ID, since)
+		}
+	}
+	return results, nil
+}
+
+// FindEntitiesByTypes implements the Repository interface.
+func (c *Cache) FindEntitiesByTypes(ctx context.Context, since time.Time, atypes ...oam.AssetType) ([]*types.Entity, error) {
+	// the database holds the complete set of entities, so it determines the order across the asset types
+	dbentities, err := c.db.FindEntitiesByTypes(ctx, since, atypes...)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, entity := range dbentities {
+		if e, err := c.cache.CreateEntity(ctx, &types.Entity{
 			CreatedAt: entity.CreatedAt,
 			LastSeen:  entity.LastSeen,
 			Asset:     entity.Asset,
 		}); err == nil {
 			results = append(results, e)
-			_ = c.createCacheEntityTag(e, "cache_create_entity", entity.ID, time.Now())
-			_ = c.createCacheEntityTag(entity, "cache_find_entities_by_type", entity.ID, since)
+			_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", entity.ID, time.Now())
 		}
 	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
 	return results, nil
 }
 
+// FindEntitiesByTypeBetween implements the Repository interface.
+// The entities last seen since the start of the window are brought into the cache
+// by FindEntitiesByType, so the window is then found within the cache.
+func (c *Cache) FindEntitiesByTypeBetween(ctx context.Context, atype oam.AssetType, from, to time.Time) ([]*types.Entity, error) {
+	if _, err := c.FindEntitiesByType(ctx, atype, from); err != nil {
+		return nil, err
+	}
+	return c.cache.FindEntitiesByTypeBetween(ctx, atype, from, to)
+}
+
+// DiffEntities implements the Repository interface.
+func (c *Cache) DiffEntities(ctx context.Context, atype oam.AssetType, t1, t2 time.Time) ([]*types.Entity, []*types.Entity, error) {
+	// the database holds the complete history of the entities, so the comparison is performed against it
+	dbadded, dbremoved, err := c.db.DiffEntities(ctx, atype, t1, t2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mirror := func(dbentities []*types.Entity) []*types.Entity {
+		var results []*types.Entity
+		for _, entity := range dbentities {
+			if e, err := c.cache.CreateEntity(ctx, &types.Entity{
+				CreatedAt: entity.CreatedAt,
+				LastSeen:  entity.LastSeen,
+				Asset:     entity.Asset,
+			}); err == nil {
+				results = append(results, e)
+				_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", entity.ID, time.Now())
+			}
+		}
+		return results
+	}
+	return mirror(dbadded), mirror(dbremoved), nil
+}
+
+// SearchEntities implements the Repository interface.
+func (c *Cache) SearchEntities(ctx context.Context, atype oam.AssetType, query string, since time.Time) ([]*types.Entity, error) {
+	// the database holds the complete set of entities, so the search is performed against it
+	dbentities, err := c.db.SearchEntities(ctx, atype, query, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, entity := range dbentities {
+		if e, err := c.cache.CreateEntity(ctx, &types.Entity{
+			CreatedAt: entity.CreatedAt,
+			LastSeen:  entity.LastSeen,
+			Asset:     entity.Asset,
+		}); err == nil {
+			results = append(results, e)
+			_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", entity.ID, time.Now())
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// FindEntitiesByTypePaged implements the Repository interface.
+func (c *Cache) FindEntitiesByTypePaged(ctx context.Context, atype oam.AssetType, since time.Time, offset, limit int) ([]*types.Entity, int64, error) {
+	// the database holds the complete set of entities, so it determines the page contents and total
+	dbentities, total, err := c.db.FindEntitiesByTypePaged(ctx, atype, since, offset, limit)
+	if err != nil {
+		return nil, total, err
+	}
+
+	var results []*types.Entity
+	for _, entity := range dbentities {
+		if e, err := c.cache.CreateEntity(ctx, &types.Entity{
+			CreatedAt: entity.CreatedAt,
+			LastSeen:  entity.LastSeen,
+			Asset:     entity.Asset,
+		}); err == nil {
+			results = append(results, e)
+			_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", entity.ID, time.Now())
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, total, types.NotFound("no entities of the specified type")
+	}
+	return results, total, nil
+}
+
+// IterateEntitiesByType implements the Repository interface.
+func (c *Cache) IterateEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (types.EntityIterator, error) {
+	// the database holds the complete set of entities, so the iteration is performed against it
+	iter, err := c.db.IterateEntitiesByType(ctx, atype, since)
+	if err != nil {
+		return nil, err
+	}
+	return &entityIterator{ctx: ctx, c: c, iter: iter}, nil
+}
+
+// entityIterator adds the entities produced by the database iterator to the cache as they are visited.
+type entityIterator struct {
+	ctx     context.Context
+	c       *Cache
+	iter    types.EntityIterator
+	current *types.Entity
+	err     error
+}
+
+// Next implements the types.EntityIterator interface.
+func (it *entityIterator) Next() bool {
+	it.current = nil
+	if it.err != nil || !it.iter.Next() {
+		return false
+	}
+
+	entity := it.iter.Entity()
+	e, err := it.c.cache.CreateEntity(it.ctx, &types.Entity{
+		CreatedAt: entity.CreatedAt,
+		LastSeen:  entity.LastSeen,
+		Asset:     entity.Asset,
+	})
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	_ = it.c.createCacheEntityTag(it.ctx, e, "cache_create_entity", entity.ID, time.Now())
+	it.current = e
+	return true
+}
+
+// Entity implements the types.EntityIterator interface.
+func (it *entityIterator) Entity() *types.Entity {
+	return it.current
+}
+
+// Err implements the types.EntityIterator interface.
+func (it *entityIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.iter.Err()
+}
+
+// Close implements the types.EntityIterator interface.
+func (it *entityIterator) Close() error {
+	it.current = nil
+	return it.iter.Close()
+}
+
+// CountEntitiesByType implements the Repository interface.
+func (c *Cache) CountEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (int64, error) {
+	// the database holds the complete set of entities, so it determines the count
+	return c.db.CountEntitiesByType(ctx, atype, since)
+}
+
+// CountEntitiesGrouped implements the Repository interface.
+func (c *Cache) CountEntitiesGrouped(ctx context.Context, since time.Time) (map[oam.AssetType]int64, error) {
+	// the database holds the complete set of entities, so it determines the counts
+	return c.db.CountEntitiesGrouped(ctx, since)
+}
+
 // DeleteEntity implements the Repository interface.
-func (c *Cache) DeleteEntity(id string) error {
-	tag, _, _ := c.checkCacheEntityTag(&types.Entity{ID: id}, "cache_create_entity")
+// Returns the number of entities deleted from the database.
+func (c *Cache) DeleteEntity(ctx context.Context, id string) (int64, error) {
+	tag, _, _ := c.checkCacheEntityTag(ctx, &types.Entity{ID: id}, "cache_create_entity")
 	if tag == nil {
-		return errors.New("cache entity tag not found")
+		return 0, types.NotFound("cache entity tag not found")
 	}
 	cp := tag.Property.(*types.CacheProperty)
 
-	if err := c.cache.DeleteEntity(id); err != nil {
+	if _, err := c.cache.DeleteEntity(ctx, id); err != nil {
+		return 0, err
+	}
+	return c.db.DeleteEntity(ctx, cp.RefID)
+}
+
+// DeleteEntityCascade implements the Repository interface.
+// Returns the number of entities, edges, and tags deleted from the database.
+func (c *Cache) DeleteEntityCascade(ctx context.Context, id string) (int64, error) {
+	tag, _, _ := c.checkCacheEntityTag(ctx, &types.Entity{ID: id}, "cache_create_entity")
+	if tag == nil {
+		return 0, types.NotFound("cache entity tag not found")
+	}
+	cp := tag.Property.(*types.CacheProperty)
+
+	if _, err := c.cache.DeleteEntityCascade(ctx, id); err != nil {
+		return 0, err
+	}
+	return c.db.DeleteEntityCascade(ctx, cp.RefID)
+}
+
+// DeleteEntitiesByType implements the Repository interface.
+// Returns the number of entities deleted from the database.
+func (c *Cache) DeleteEntitiesByType(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	if _, err := c.cache.DeleteEntitiesByType(ctx, atype, before); err != nil {
+		return 0, err
+	}
+	return c.db.DeleteEntitiesByType(ctx, atype, before)
+}
+
+// FindOrphanEntities implements the Repository interface.
+// The cache may not hold the edges of its entities, so the orphans are found in the database and brought into the cache.
+func (c *Cache) FindOrphanEntities(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	dbentities, err := c.db.FindOrphanEntities(ctx, atype, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, entity := range dbentities {
+		if e, err := c.cache.CreateEntity(ctx, &types.Entity{
+			CreatedAt: entity.CreatedAt,
+			LastSeen:  entity.LastSeen,
+			Asset:     entity.Asset,
+		}); err == nil {
+			results = append(results, e)
+			_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", entity.ID, time.Now())
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("no orphan entities of the specified type")
+	}
+	return results, nil
+}
+
+// DeleteOrphanEntities implements the Repository interface.
+// The orphans are determined by the database, and their copies are removed from the cache.
+// Returns the number of entities deleted from the database.
+func (c *Cache) DeleteOrphanEntities(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	orphans, err := c.db.FindOrphanEntities(ctx, atype, time.Time{})
+	if err != nil && !errors.Is(err, types.ErrNotFound) {
+		return 0, err
+	}
+
+	total, err := c.db.DeleteOrphanEntities(ctx, atype, before)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, orphan := range orphans {
+		if !before.IsZero() && !orphan.LastSeen.Before(before) {
+			continue
+		}
+		if entities, err := c.cache.FindEntitiesByContent(ctx, orphan.Asset, time.Time{}); err == nil {
+			for _, e := range entities {
+				_, _ = c.cache.DeleteEntity(ctx, e.ID)
+			}
+		}
+	}
+	return total, nil
+}
+
+// FindDeletedEntities implements the Repository interface.
+func (c *Cache) FindDeletedEntities(ctx context.Context, since time.Time) ([]*types.Entity, error) {
+	// the cache only holds live entities, so the tombstones are kept by the database
+	return c.db.FindDeletedEntities(ctx, since)
+}
+
+// PurgeDeleted implements the Repository interface.
+func (c *Cache) PurgeDeleted(ctx context.Context, before time.Time) error {
+	if err := c.cache.PurgeDeleted(ctx, before); err != nil {
 		return err
 	}
-	return c.db.DeleteEntity(cp.RefID)
+	return c.db.PurgeDeleted(ctx, before)
 }
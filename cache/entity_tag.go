@@ -5,6 +5,7 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -13,9 +14,9 @@ import (
 )
 
 // CreateEntityTag implements the Repository interface.
-func (c *Cache) CreateEntityTag(entity *types.Entity, input *types.EntityTag) (*types.EntityTag, error) {
+func (c *Cache) CreateEntityTag(ctx context.Context, entity *types.Entity, input *types.EntityTag) (*types.EntityTag, error) {
 	// if the tag already exists, then do not create it again
-	if tags, err := c.cache.GetEntityTags(entity, time.Time{}, input.Property.Name()); err == nil && len(tags) > 0 {
+	if tags, err := c.cache.GetEntityTags(ctx, entity, time.Time{}, input.Property.Name()); err == nil && len(tags) > 0 {
 		for _, tag := range tags {
 			if input.Property.Value() == tag.Property.Value() && tag.LastSeen.Add(c.freq).After(time.Now()) {
 				return tag, nil
@@ -23,29 +24,58 @@ func (c *Cache) CreateEntityTag(entity *types.Entity, input *types.EntityTag) (*
 		}
 	}
 
-	tag, err := c.cache.CreateEntityTag(entity, input)
+	tag, err := c.cache.CreateEntityTag(ctx, entity, input)
 	if err != nil {
 		return nil, err
 	}
 
-	ctag, _, _ := c.checkCacheEntityTag(entity, "cache_create_entity")
+	ctag, _, _ := c.checkCacheEntityTag(ctx, entity, "cache_create_entity")
 	if ctag == nil {
-		return nil, errors.New("cache entity tag not found")
+		return nil, types.NotFound("cache entity tag not found")
 	}
 	cp := ctag.Property.(*types.CacheProperty)
 
-	_, err = c.db.CreateEntityTag(&types.Entity{ID: cp.RefID}, &types.EntityTag{
+	_, err = c.db.CreateEntityTag(ctx, &types.Entity{ID: cp.RefID}, &types.EntityTag{
 		CreatedAt: input.CreatedAt,
 		LastSeen:  input.LastSeen,
+		ExpiresAt: input.ExpiresAt,
 		Property:  input.Property,
 	})
 	return tag, err
 }
 
+// CreateEntityTags implements the Repository interface.
+func (c *Cache) CreateEntityTags(ctx context.Context, entityIDs []string, input *types.EntityTag) ([]*types.EntityTag, error) {
+	tags, err := c.cache.CreateEntityTags(ctx, entityIDs, input)
+	var missing *types.MissingEntitiesError
+	if err != nil && !errors.As(err, &missing) {
+		return nil, err
+	}
+
+	var refs []string
+	for _, tag := range tags {
+		if ctag, _, _ := c.checkCacheEntityTag(ctx, tag.Entity, "cache_create_entity"); ctag != nil {
+			refs = append(refs, ctag.Property.(*types.CacheProperty).RefID)
+		}
+	}
+
+	if len(refs) > 0 {
+		if _, dberr := c.db.CreateEntityTags(ctx, refs, &types.EntityTag{
+			CreatedAt: input.CreatedAt,
+			LastSeen:  input.LastSeen,
+			ExpiresAt: input.ExpiresAt,
+			Property:  input.Property,
+		}); dberr != nil {
+			return tags, dberr
+		}
+	}
+	return tags, err
+}
+
 // CreateEntityProperty implements the Repository interface.
-func (c *Cache) CreateEntityProperty(entity *types.Entity, property oam.Property) (*types.EntityTag, error) {
+func (c *Cache) CreateEntityProperty(ctx context.Context, entity *types.Entity, property oam.Property) (*types.EntityTag, error) {
 	// if the tag already exists, then do not create it again
-	if tags, err := c.cache.GetEntityTags(entity, time.Time{}, property.Name()); err == nil && len(tags) > 0 {
+	if tags, err := c.cache.GetEntityTags(ctx, entity, time.Time{}, property.Name()); err == nil && len(tags) > 0 {
 		for _, tag := range tags {
 			if property.Value() == tag.Property.Value() && tag.LastSeen.Add(c.freq).After(time.Now()) {
 				return tag, nil
@@ -53,36 +83,36 @@ func (c *Cache) CreateEntityProperty(entity *types.Entity, property oam.Property
 		}
 	}
 
-	tag, err := c.cache.CreateEntityProperty(entity, property)
+	tag, err := c.cache.CreateEntityProperty(ctx, entity, property)
 	if err != nil {
 		return nil, err
 	}
 
-	ctag, _, _ := c.checkCacheEntityTag(entity, "cache_create_entity")
+	ctag, _, _ := c.checkCacheEntityTag(ctx, entity, "cache_create_entity")
 	if ctag == nil {
-		return nil, errors.New("cache entity tag not found")
+		return nil, types.NotFound("cache entity tag not found")
 	}
 	cp := ctag.Property.(*types.CacheProperty)
 
-	_, err = c.db.CreateEntityProperty(&types.Entity{ID: cp.RefID}, property)
+	_, err = c.db.CreateEntityProperty(ctx, &types.Entity{ID: cp.RefID}, property)
 	return tag, err
 }
 
 // FindEntityTagById implements the Repository interface.
-func (c *Cache) FindEntityTagById(id string) (*types.EntityTag, error) {
-	return c.cache.FindEntityTagById(id)
+func (c *Cache) FindEntityTagById(ctx context.Context, id string) (*types.EntityTag, error) {
+	return c.cache.FindEntityTagById(ctx, id)
 }
 
 // FindEntityTagsByContent implements the Repository interface.
 // TODO: Consider adding a check for the last time the cache was updated
-func (c *Cache) FindEntityTagsByContent(prop oam.Property, since time.Time) ([]*types.EntityTag, error) {
+func (c *Cache) FindEntityTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EntityTag, error) {
 	if since.IsZero() || since.Before(c.start) {
 		var dbentities []*types.Entity
 
-		dbtags, dberr := c.db.FindEntityTagsByContent(prop, since)
+		dbtags, dberr := c.db.FindEntityTagsByContent(ctx, prop, since)
 		if dberr == nil && len(dbtags) > 0 {
 			for _, tag := range dbtags {
-				if entity, err := c.db.FindEntityById(tag.Entity.ID); err == nil && entity != nil {
+				if entity, err := c.db.FindEntityById(ctx, tag.Entity.ID); err == nil && entity != nil {
 					dbentities = append(dbentities, entity)
 				}
 			}
@@ -90,15 +120,16 @@ func (c *Cache) FindEntityTagsByContent(prop oam.Property, since time.Time) ([]*
 
 		if dberr == nil {
 			for i, tag := range dbtags {
-				if entity, err := c.cache.CreateEntity(&types.Entity{
+				if entity, err := c.cache.CreateEntity(ctx, &types.Entity{
 					CreatedAt: dbentities[i].CreatedAt,
 					LastSeen:  dbentities[i].LastSeen,
 					Asset:     dbentities[i].Asset,
 				}); err == nil && entity != nil {
-					_ = c.createCacheEntityTag(entity, "cache_create_entity", dbentities[i].ID, time.Now())
-					_, _ = c.cache.CreateEntityTag(entity, &types.EntityTag{
+					_ = c.createCacheEntityTag(ctx, entity, "cache_create_entity", dbentities[i].ID, time.Now())
+					_, _ = c.cache.CreateEntityTag(ctx, entity, &types.EntityTag{
 						CreatedAt: tag.CreatedAt,
 						LastSeen:  tag.LastSeen,
+						ExpiresAt: tag.ExpiresAt,
 						Property:  tag.Property,
 						Entity:    entity,
 					})
@@ -107,16 +138,16 @@ func (c *Cache) FindEntityTagsByContent(prop oam.Property, since time.Time) ([]*
 		}
 	}
 
-	return c.cache.FindEntityTagsByContent(prop, since)
+	return c.cache.FindEntityTagsByContent(ctx, prop, since)
 }
 
 // GetEntityTags implements the Repository interface.
-func (c *Cache) GetEntityTags(entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error) {
+func (c *Cache) GetEntityTags(ctx context.Context, entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error) {
 	var refID string
 	var dbquery, found bool
 
 	if since.IsZero() || since.Before(c.start) {
-		if tag, ts, _ := c.checkCacheEntityTag(entity, "cache_get_entity_tags"); tag == nil {
+		if tag, ts, _ := c.checkCacheEntityTag(ctx, entity, "cache_get_entity_tags"); tag == nil {
 			dbquery = true
 		} else if since.Before(ts) {
 			found = true
@@ -127,50 +158,133 @@ func (c *Cache) GetEntityTags(entity *types.Entity, since time.Time, names ...st
 
 	if dbquery {
 		if !found {
-			ctag, _, _ := c.checkCacheEntityTag(entity, "cache_create_entity")
+			ctag, _, _ := c.checkCacheEntityTag(ctx, entity, "cache_create_entity")
 			if ctag == nil {
-				return nil, errors.New("cache entity tag not found")
+				return nil, types.NotFound("cache entity tag not found")
 			}
 			cp := ctag.Property.(*types.CacheProperty)
 			refID = cp.RefID
 		}
 
-		dbtags, dberr := c.db.GetEntityTags(&types.Entity{ID: refID}, since)
+		dbtags, dberr := c.db.GetEntityTags(ctx, &types.Entity{ID: refID}, since)
 		if dberr != nil {
 			return nil, dberr
 		}
-		_ = c.createCacheEntityTag(entity, "cache_get_entity_tags", refID, since)
+		_ = c.createCacheEntityTag(ctx, entity, "cache_get_entity_tags", refID, since)
 
 		if dberr == nil && len(dbtags) > 0 {
 			for _, tag := range dbtags {
-				_, _ = c.cache.CreateEntityTag(entity, &types.EntityTag{
+				_, _ = c.cache.CreateEntityTag(ctx, entity, &types.EntityTag{
 					CreatedAt: tag.CreatedAt,
 					LastSeen:  tag.LastSeen,
+					ExpiresAt: tag.ExpiresAt,
 					Property:  tag.Property,
 				})
 			}
 		}
 	}
 
-	return c.cache.GetEntityTags(entity, since, names...)
+	return c.cache.GetEntityTags(ctx, entity, since, names...)
 }
 
-// DeleteEntityTag implements the Repository interface.
-func (c *Cache) DeleteEntityTag(id string) error {
-	tag, err := c.cache.FindEntityTagById(id)
+// GetEntityTagsBetween implements the Repository interface.
+// The tags last seen since the start of the window are brought into the cache
+// by GetEntityTags, so the window is then found within the cache.
+func (c *Cache) GetEntityTagsBetween(ctx context.Context, entity *types.Entity, from, to time.Time, names ...string) ([]*types.EntityTag, error) {
+	if _, err := c.GetEntityTags(ctx, entity, from); err != nil {
+		return nil, err
+	}
+	return c.cache.GetEntityTagsBetween(ctx, entity, from, to, names...)
+}
+
+// GetEntityTagsMatching implements the Repository interface.
+// The tags last seen since the since parameter are brought into the cache
+// by GetEntityTags, so the matching tags are then found within the cache.
+func (c *Cache) GetEntityTagsMatching(ctx context.Context, entity *types.Entity, since time.Time, name, value string) ([]*types.EntityTag, error) {
+	if _, err := c.GetEntityTags(ctx, entity, since); err != nil {
+		return nil, err
+	}
+	return c.cache.GetEntityTagsMatching(ctx, entity, since, name, value)
+}
+
+// FindEntitiesByTag implements the Repository interface.
+func (c *Cache) FindEntitiesByTag(ctx context.Context, name, value string, since time.Time) ([]*types.Entity, error) {
+	// the database holds the complete set of tags, so the search is performed against it
+	dbentities, err := c.db.FindEntitiesByTag(ctx, name, value, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, entity := range dbentities {
+		if e, err := c.cache.CreateEntity(ctx, &types.Entity{
+			CreatedAt: entity.CreatedAt,
+			LastSeen:  entity.LastSeen,
+			Asset:     entity.Asset,
+		}); err == nil {
+			results = append(results, e)
+			_ = c.createCacheEntityTag(ctx, e, "cache_create_entity", entity.ID, time.Now())
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// UpdateEntityTag implements the Repository interface.
+func (c *Cache) UpdateEntityTag(ctx context.Context, id string, value string) (*types.EntityTag, error) {
+	tag, err := c.cache.FindEntityTagById(ctx, id)
+	if err != nil {
+		return nil, types.ErrTagNotFound
+	}
+
+	updated, err := c.cache.UpdateEntityTag(ctx, id, value)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	ctag, _, _ := c.checkCacheEntityTag(tag.Entity, "cache_create_entity")
+	ctag, _, _ := c.checkCacheEntityTag(ctx, tag.Entity, "cache_create_entity")
 	if ctag == nil {
-		return errors.New("cache entity tag not found")
+		return updated, nil
 	}
 	cp := ctag.Property.(*types.CacheProperty)
 
-	if err := c.db.DeleteEntityTag(cp.RefID); err != nil {
-		return err
+	var ferr error
+	if tags, err := c.db.GetEntityTags(ctx, &types.Entity{ID: cp.RefID},
+		time.Time{}, tag.Property.Name()); err == nil && len(tags) > 0 {
+		for _, t := range tags {
+			if tag.Property.Value() == t.Property.Value() {
+				if _, err := c.db.UpdateEntityTag(ctx, t.ID, value); err != nil {
+					ferr = err
+				}
+			}
+		}
+	}
+	return updated, ferr
+}
+
+// DeleteEntityTag implements the Repository interface.
+// Returns the number of tags deleted from the database.
+func (c *Cache) DeleteEntityTag(ctx context.Context, id string) (int64, error) {
+	tag, err := c.cache.FindEntityTagById(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	ctag, _, _ := c.checkCacheEntityTag(ctx, tag.Entity, "cache_create_entity")
+	if ctag == nil {
+		return 0, types.NotFound("cache entity tag not found")
 	}
+	cp := ctag.Property.(*types.CacheProperty)
 
-	return c.cache.DeleteEntityTag(id)
+	n, err := c.db.DeleteEntityTag(ctx, cp.RefID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := c.cache.DeleteEntityTag(ctx, id); err != nil {
+		return n, err
+	}
+	return n, nil
 }
@@ -5,6 +5,8 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"os"
 	"reflect"
 	"testing"
@@ -34,9 +36,9 @@ func TestCreateEntityTag(t *testing.T) {
 	ctime := now.Add(-8 * time.Hour)
 	before := ctime.Add(-2 * time.Second)
 	after := ctime.Add(2 * time.Second)
-	entity, err := c.CreateAsset(&dns.FQDN{Name: "owasp.org"})
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err)
-	tag, err := c.CreateEntityTag(entity, &types.EntityTag{
+	tag, err := c.CreateEntityTag(context.Background(), entity, &types.EntityTag{
 		CreatedAt: ctime,
 		LastSeen:  ctime,
 		Property: &general.SimpleProperty{
@@ -50,7 +52,7 @@ func TestCreateEntityTag(t *testing.T) {
 	assert.WithinRange(t, tag.LastSeen, before, after)
 
 	time.Sleep(250 * time.Millisecond)
-	dbents, err := c.db.FindEntitiesByContent(entity.Asset, before)
+	dbents, err := c.db.FindEntitiesByContent(context.Background(), entity.Asset, before)
 	assert.NoError(t, err)
 
 	if num := len(dbents); num != 1 {
@@ -58,7 +60,7 @@ func TestCreateEntityTag(t *testing.T) {
 	}
 	dbent := dbents[0]
 
-	dbtags, err := c.db.GetEntityTags(dbent, before, tag.Property.Name())
+	dbtags, err := c.db.GetEntityTags(context.Background(), dbent, before, tag.Property.Name())
 	assert.NoError(t, err)
 	if num := len(dbtags); num != 1 {
 		t.Errorf("failed to return the corrent number of tags: %d", num)
@@ -72,6 +74,44 @@ func TestCreateEntityTag(t *testing.T) {
 	assert.WithinRange(t, dbtag.LastSeen, before, after)
 }
 
+func TestCreateEntityTags(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	var ids []string
+	for _, name := range []string{"owasp.org", "www.owasp.org"} {
+		entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		ids = append(ids, entity.ID)
+	}
+
+	prop := &general.SimpleProperty{
+		PropertyName:  "test",
+		PropertyValue: "foobar",
+	}
+	tags, err := c.CreateEntityTags(context.Background(), append(ids, "9999"), &types.EntityTag{Property: prop})
+	var missing *types.MissingEntitiesError
+	assert.True(t, errors.As(err, &missing))
+	assert.Equal(t, []string{"9999"}, missing.IDs)
+	assert.Len(t, tags, 2)
+
+	time.Sleep(250 * time.Millisecond)
+	dbtags, err := c.db.FindEntityTagsByContent(context.Background(), prop, time.Time{})
+	assert.NoError(t, err)
+	if num := len(dbtags); num != 2 {
+		t.Errorf("failed to return the corrent number of tags: %d", num)
+	}
+}
+
 func TestCreateEntityProperty(t *testing.T) {
 	db1, db2, dir, err := createTestRepositories()
 	assert.NoError(t, err)
@@ -87,9 +127,9 @@ func TestCreateEntityProperty(t *testing.T) {
 
 	now := time.Now()
 	before := now.Add(-2 * time.Second)
-	entity, err := c.CreateAsset(&dns.FQDN{Name: "owasp.org"})
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err)
-	tag, err := c.CreateEntityProperty(entity, &general.SimpleProperty{
+	tag, err := c.CreateEntityProperty(context.Background(), entity, &general.SimpleProperty{
 		PropertyName:  "test",
 		PropertyValue: "foobar",
 	})
@@ -99,7 +139,7 @@ func TestCreateEntityProperty(t *testing.T) {
 	assert.WithinRange(t, tag.LastSeen, before, after)
 
 	time.Sleep(250 * time.Millisecond)
-	dbents, err := c.db.FindEntitiesByContent(entity.Asset, before)
+	dbents, err := c.db.FindEntitiesByContent(context.Background(), entity.Asset, before)
 	assert.NoError(t, err)
 
 	if num := len(dbents); num != 1 {
@@ -107,7 +147,7 @@ func TestCreateEntityProperty(t *testing.T) {
 	}
 	dbent := dbents[0]
 
-	dbtags, err := c.db.GetEntityTags(dbent, before, tag.Property.Name())
+	dbtags, err := c.db.GetEntityTags(context.Background(), dbent, before, tag.Property.Name())
 	assert.NoError(t, err)
 	if num := len(dbtags); num != 1 {
 		t.Errorf("failed to return the corrent number of tags: %d", num)
@@ -134,15 +174,15 @@ func TestFindEntityTagById(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { _ = c.Close() }()
 
-	entity, err := c.CreateAsset(&dns.FQDN{Name: "owasp.org"})
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err)
-	tag, err := c.CreateEntityProperty(entity, &general.SimpleProperty{
+	tag, err := c.CreateEntityProperty(context.Background(), entity, &general.SimpleProperty{
 		PropertyName:  "test",
 		PropertyValue: "foobar",
 	})
 	assert.NoError(t, err)
 
-	tag2, err := c.FindEntityTagById(tag.ID)
+	tag2, err := c.FindEntityTagById(context.Background(), tag.ID)
 	assert.NoError(t, err)
 
 	if !reflect.DeepEqual(tag.Property, tag2.Property) {
@@ -172,13 +212,13 @@ func TestFindEntityTagsByContent(t *testing.T) {
 	ctime1 := now.Add(-24 * time.Hour)
 	cbefore1 := ctime1.Add(-20 * time.Second)
 	fqdn1 := &dns.FQDN{Name: "owasp.org"}
-	entity1, err := c.db.CreateEntity(&types.Entity{
+	entity1, err := c.db.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: ctime1,
 		LastSeen:  ctime1,
 		Asset:     fqdn1,
 	})
 	assert.NoError(t, err)
-	_, err = c.db.CreateEntityTag(entity1, &types.EntityTag{
+	_, err = c.db.CreateEntityTag(context.Background(), entity1, &types.EntityTag{
 		CreatedAt: ctime1,
 		LastSeen:  ctime1,
 		Property:  prop,
@@ -188,41 +228,41 @@ func TestFindEntityTagsByContent(t *testing.T) {
 	ctime2 := now.Add(-8 * time.Hour)
 	cbefore2 := ctime2.Add(-20 * time.Second)
 	fqdn2 := &dns.FQDN{Name: "utica.edu"}
-	entity2, err := c.db.CreateEntity(&types.Entity{
+	entity2, err := c.db.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: ctime2,
 		LastSeen:  ctime2,
 		Asset:     fqdn2,
 	})
 	assert.NoError(t, err)
-	_, err = c.db.CreateEntityTag(entity2, &types.EntityTag{
+	_, err = c.db.CreateEntityTag(context.Background(), entity2, &types.EntityTag{
 		CreatedAt: ctime2,
 		LastSeen:  ctime2,
 		Property:  prop,
 	})
 	assert.NoError(t, err)
 	// add new entities to the database
-	entity3, err := c.CreateAsset(&dns.FQDN{Name: "sunypoly.edu"})
+	entity3, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "sunypoly.edu"})
 	assert.NoError(t, err)
-	_, err = c.CreateEntityProperty(entity3, prop)
+	_, err = c.CreateEntityProperty(context.Background(), entity3, prop)
 	assert.NoError(t, err)
 	after := time.Now().Add(time.Second)
 
-	_, err = c.FindEntityTagsByContent(prop, after)
+	_, err = c.FindEntityTagsByContent(context.Background(), prop, after)
 	assert.Error(t, err)
 
-	tags, err := c.FindEntityTagsByContent(prop, c.StartTime())
+	tags, err := c.FindEntityTagsByContent(context.Background(), prop, c.StartTime())
 	assert.NoError(t, err)
 	if len(tags) != 1 {
 		t.Errorf("first request failed to produce the expected number of tags")
 	}
 
-	tags, err = c.FindEntityTagsByContent(prop, cbefore2)
+	tags, err = c.FindEntityTagsByContent(context.Background(), prop, cbefore2)
 	assert.NoError(t, err)
 	if len(tags) != 2 {
 		t.Errorf("second request failed to produce the expected number of tags")
 	}
 
-	tags, err = c.FindEntityTagsByContent(prop, cbefore1)
+	tags, err = c.FindEntityTagsByContent(context.Background(), prop, cbefore1)
 	assert.NoError(t, err)
 	if len(tags) != 3 {
 		t.Errorf("third request failed to produce the expected number of tags")
@@ -245,7 +285,7 @@ func TestGetEntityTags(t *testing.T) {
 	now := time.Now()
 	ctime := now.Add(-8 * time.Hour)
 	before := ctime.Add(-2 * time.Second)
-	entity, err := c.CreateEntity(&types.Entity{
+	entity, err := c.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: ctime,
 		LastSeen:  ctime,
 		Asset:     &dns.FQDN{Name: "caffix.com"},
@@ -253,7 +293,7 @@ func TestGetEntityTags(t *testing.T) {
 	assert.NoError(t, err)
 	time.Sleep(250 * time.Millisecond)
 
-	dbents, err := c.db.FindEntitiesByContent(entity.Asset, time.Time{})
+	dbents, err := c.db.FindEntitiesByContent(context.Background(), entity.Asset, time.Time{})
 	assert.NoError(t, err)
 
 	if num := len(dbents); num != 1 {
@@ -267,7 +307,7 @@ func TestGetEntityTags(t *testing.T) {
 	// add some old stuff to the database
 	for _, name := range []string{"owasp.org", "utica.edu", "sunypoly.edu"} {
 		set1.Insert(name)
-		_, err := c.db.CreateEntityTag(dbent, &types.EntityTag{
+		_, err := c.db.CreateEntityTag(context.Background(), dbent, &types.EntityTag{
 			CreatedAt: ctime,
 			LastSeen:  ctime,
 			Property: &general.SimpleProperty{
@@ -283,7 +323,7 @@ func TestGetEntityTags(t *testing.T) {
 	// add some new stuff to the database
 	for _, name := range []string{"www.owasp.org", "www.utica.edu", "www.sunypoly.edu"} {
 		set2.Insert(name)
-		_, err := c.CreateEntityProperty(entity, &general.SimpleProperty{
+		_, err := c.CreateEntityProperty(context.Background(), entity, &general.SimpleProperty{
 			PropertyName:  "test",
 			PropertyValue: name,
 		})
@@ -293,13 +333,13 @@ func TestGetEntityTags(t *testing.T) {
 	after := time.Now()
 
 	// some tests that shouldn't return anything
-	_, err = c.GetEntityTags(entity, after)
+	_, err = c.GetEntityTags(context.Background(), entity, after)
 	assert.Error(t, err)
 	// there shouldn't be a tag for this entity, since it didn't require the database
-	_, err = c.cache.GetEntityTags(entity, time.Time{}, "cache_get_entity_tags")
+	_, err = c.cache.GetEntityTags(context.Background(), entity, time.Time{}, "cache_get_entity_tags")
 	assert.Error(t, err)
 
-	tags, err := c.GetEntityTags(entity, c.StartTime(), "test")
+	tags, err := c.GetEntityTags(context.Background(), entity, c.StartTime(), "test")
 	assert.NoError(t, err)
 	if num := len(tags); num != 3 {
 		t.Errorf("incorrect number of entity tags: %d", num)
@@ -313,10 +353,10 @@ func TestGetEntityTags(t *testing.T) {
 		t.Errorf("first request failed to produce the correct tags")
 	}
 	// there shouldn't be a tag for this entity, since it didn't require the database
-	_, err = c.cache.GetEntityTags(entity, time.Time{}, "cache_get_entity_tags")
+	_, err = c.cache.GetEntityTags(context.Background(), entity, time.Time{}, "cache_get_entity_tags")
 	assert.Error(t, err)
 
-	tags, err = c.GetEntityTags(entity, before, "test")
+	tags, err = c.GetEntityTags(context.Background(), entity, before, "test")
 	assert.NoError(t, err)
 	if num := len(tags); num != 6 {
 		t.Errorf("incorrect number of entity tags: %d", num)
@@ -330,7 +370,7 @@ func TestGetEntityTags(t *testing.T) {
 		t.Errorf("second request failed to produce the correct tags")
 	}
 	// there should be a tag for this entity
-	tags, err = c.cache.GetEntityTags(entity, time.Time{}, "cache_get_entity_tags")
+	tags, err = c.cache.GetEntityTags(context.Background(), entity, time.Time{}, "cache_get_entity_tags")
 	assert.NoError(t, err)
 	if len(tags) != 1 {
 		t.Errorf("second request failed to produce the expected number of edge tags")
@@ -342,6 +382,148 @@ func TestGetEntityTags(t *testing.T) {
 	assert.WithinRange(t, tagtime, before, after)
 }
 
+func TestFindEntitiesByTag(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	for _, name := range []string{"owasp.org", "www.owasp.org"} {
+		entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+
+		_, err = c.CreateEntityProperty(context.Background(), entity, &general.SourceProperty{
+			Source:     "crtsh",
+			Confidence: 100,
+		})
+		assert.NoError(t, err)
+	}
+	time.Sleep(250 * time.Millisecond)
+
+	entities, err := c.FindEntitiesByTag(context.Background(), "crtsh", "100", time.Time{})
+	assert.NoError(t, err)
+	if num := len(entities); num != 2 {
+		t.Errorf("failed to return the corrent number of entities: %d", num)
+	}
+
+	_, err = c.FindEntitiesByTag(context.Background(), "crtsh", "50", time.Time{})
+	assert.Error(t, err)
+}
+
+func TestGetEntityTagsMatching(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	for _, value := range []string{"crtsh", "dns"} {
+		_, err = c.CreateEntityProperty(context.Background(), entity, &general.SimpleProperty{
+			PropertyName:  "source",
+			PropertyValue: value,
+		})
+		assert.NoError(t, err)
+	}
+	time.Sleep(250 * time.Millisecond)
+
+	tags, err := c.GetEntityTagsMatching(context.Background(), entity, time.Time{}, "source", "crtsh")
+	assert.NoError(t, err)
+	if assert.Len(t, tags, 1) {
+		assert.Equal(t, "crtsh", tags[0].Property.Value())
+	}
+
+	_, err = c.GetEntityTagsMatching(context.Background(), entity, time.Time{}, "source", "bing")
+	assert.Error(t, err)
+}
+
+func TestPurgeExpiredTags(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	_, err = c.CreateEntityTag(context.Background(), entity, &types.EntityTag{
+		ExpiresAt: time.Now().Add(-time.Minute),
+		Property:  &general.SimpleProperty{PropertyName: "source", PropertyValue: "crtsh"},
+	})
+	assert.NoError(t, err)
+	time.Sleep(250 * time.Millisecond)
+
+	_, err = c.GetEntityTags(context.Background(), entity, time.Time{}, "source")
+	assert.Error(t, err)
+
+	count, err := c.PurgeExpiredTags(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	// the count is of the expired tags removed from the database
+	assert.Equal(t, int64(1), count)
+}
+
+func TestUpdateEntityTag(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+
+	tag, err := c.CreateEntityProperty(context.Background(), entity, &general.SimpleProperty{
+		PropertyName:  "test",
+		PropertyValue: "foo",
+	})
+	assert.NoError(t, err)
+	time.Sleep(250 * time.Millisecond)
+
+	updated, err := c.UpdateEntityTag(context.Background(), tag.ID, "bar")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", updated.Property.Value())
+	assert.Equal(t, tag.CreatedAt, updated.CreatedAt)
+
+	dbents, err := c.db.FindEntitiesByContent(context.Background(), entity.Asset, time.Time{})
+	assert.NoError(t, err)
+
+	dbtags, err := c.db.GetEntityTags(context.Background(), dbents[0], time.Time{}, "test")
+	assert.NoError(t, err)
+	if num := len(dbtags); num != 1 {
+		t.Errorf("failed to return the corrent number of tags: %d", num)
+	}
+	assert.Equal(t, "bar", dbtags[0].Property.Value())
+
+	_, err = c.UpdateEntityTag(context.Background(), "9999", "bar")
+	assert.ErrorIs(t, err, types.ErrTagNotFound)
+}
+
 func TestDeleteEntityTag(t *testing.T) {
 	db1, db2, dir, err := createTestRepositories()
 	assert.NoError(t, err)
@@ -355,32 +537,32 @@ func TestDeleteEntityTag(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { _ = c.Close() }()
 
-	entity, err := c.CreateAsset(&dns.FQDN{Name: "owasp.org"})
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err)
 
 	time.Sleep(250 * time.Millisecond)
-	dbents, err := c.db.FindEntitiesByContent(entity.Asset, time.Time{})
+	dbents, err := c.db.FindEntitiesByContent(context.Background(), entity.Asset, time.Time{})
 	assert.NoError(t, err)
 	if num := len(dbents); num != 1 {
 		t.Errorf("failed to return the corrent number of entities: %d", num)
 	}
 	dbent := dbents[0]
 
-	tag, err := c.CreateEntityProperty(entity, &general.SimpleProperty{
+	tag, err := c.CreateEntityProperty(context.Background(), entity, &general.SimpleProperty{
 		PropertyName:  "test",
 		PropertyValue: "foobar",
 	})
 	assert.NoError(t, err)
 	time.Sleep(250 * time.Millisecond)
 
-	err = c.DeleteEntityTag(tag.ID)
+	_, err = c.DeleteEntityTag(context.Background(), tag.ID)
 	assert.NoError(t, err)
 	time.Sleep(250 * time.Millisecond)
 
-	_, err = c.FindEntityTagById(tag.ID)
+	_, err = c.FindEntityTagById(context.Background(), tag.ID)
 	assert.Error(t, err)
 
-	tags, err := c.db.GetEntityTags(dbent, c.StartTime())
+	tags, err := c.db.GetEntityTags(context.Background(), dbent, c.StartTime())
 	assert.Error(t, err)
 	if len(tags) > 0 {
 		for _, tag := range tags {
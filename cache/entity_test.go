@@ -5,15 +5,21 @@
 package cache
 
 import (
+	"context"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/caffix/stringset"
+	assetdb "github.com/garthoid/asset-db"
+	"github.com/garthoid/asset-db/options"
+	"github.com/garthoid/asset-db/repository/sqlrepo"
 	"github.com/garthoid/asset-db/types"
 	oam "github.com/owasp-amass/open-asset-model"
 	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,7 +40,7 @@ func TestCreateEntity(t *testing.T) {
 	ctime := now.Add(-8 * time.Hour)
 	before := ctime.Add(-2 * time.Second)
 	after := ctime.Add(2 * time.Second)
-	entity, err := c.CreateEntity(&types.Entity{
+	entity, err := c.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: ctime,
 		LastSeen:  ctime,
 		Asset:     &dns.FQDN{Name: "owasp.org"},
@@ -43,12 +49,12 @@ func TestCreateEntity(t *testing.T) {
 	assert.WithinRange(t, entity.CreatedAt, before, after)
 	assert.WithinRange(t, entity.LastSeen, before, after)
 
-	if tags, err := c.cache.GetEntityTags(entity, now, "cache_create_entity"); err != nil || len(tags) != 1 {
+	if tags, err := c.cache.GetEntityTags(context.Background(), entity, now, "cache_create_entity"); err != nil || len(tags) != 1 {
 		t.Errorf("failed to create the cache tag:")
 	}
 
 	time.Sleep(250 * time.Millisecond)
-	dbents, err := db2.FindEntitiesByContent(entity.Asset, before)
+	dbents, err := db2.FindEntitiesByContent(context.Background(), entity.Asset, before)
 	assert.NoError(t, err)
 
 	if num := len(dbents); num != 1 {
@@ -79,17 +85,17 @@ func TestCreateAsset(t *testing.T) {
 	now := time.Now()
 	before := now.Add(-2 * time.Second)
 	after := now.Add(2 * time.Second)
-	entity, err := c.CreateAsset(&dns.FQDN{Name: "owasp.org"})
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err)
 	assert.WithinRange(t, entity.CreatedAt, before, after)
 	assert.WithinRange(t, entity.LastSeen, before, after)
 
-	if tags, err := c.cache.GetEntityTags(entity, now, "cache_create_entity"); err != nil || len(tags) != 1 {
+	if tags, err := c.cache.GetEntityTags(context.Background(), entity, now, "cache_create_entity"); err != nil || len(tags) != 1 {
 		t.Errorf("failed to create the cache tag:")
 	}
 
 	time.Sleep(250 * time.Millisecond)
-	dbents, err := db2.FindEntitiesByContent(entity.Asset, now)
+	dbents, err := db2.FindEntitiesByContent(context.Background(), entity.Asset, now)
 	assert.NoError(t, err)
 
 	if num := len(dbents); num != 1 {
@@ -104,6 +110,41 @@ func TestCreateAsset(t *testing.T) {
 	assert.WithinRange(t, dbent.LastSeen, before, after)
 }
 
+func TestCreateEntities(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	inputs := []*types.Entity{
+		{Asset: &dns.FQDN{Name: "owasp.org"}},
+		{Asset: &dns.FQDN{Name: "www.owasp.org"}},
+		{Asset: &dns.FQDN{Name: "owasp.org"}},
+	}
+
+	entities, err := c.CreateEntities(context.Background(), inputs)
+	assert.NoError(t, err)
+	assert.Len(t, entities, len(inputs))
+	assert.Equal(t, entities[0].ID, entities[2].ID)
+
+	for i, input := range inputs {
+		dbents, err := db2.FindEntitiesByContent(context.Background(), input.Asset, time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, dbents, 1)
+
+		tags, err := c.cache.GetEntityTags(context.Background(), entities[i], time.Time{}, "cache_create_entity")
+		assert.NoError(t, err)
+		assert.Len(t, tags, 1)
+	}
+}
+
 func TestFindEntityById(t *testing.T) {
 	db1, db2, dir, err := createTestRepositories()
 	assert.NoError(t, err)
@@ -117,10 +158,10 @@ func TestFindEntityById(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { _ = c.Close() }()
 
-	entity1, err := c.CreateAsset(&dns.FQDN{Name: "owasp.org"})
+	entity1, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err)
 
-	entity2, err := c.FindEntityById(entity1.ID)
+	entity2, err := c.FindEntityById(context.Background(), entity1.ID)
 	assert.NoError(t, err)
 
 	if !reflect.DeepEqual(entity1.Asset, entity2.Asset) {
@@ -146,7 +187,7 @@ func TestFindEntityByContent(t *testing.T) {
 	ctime1 := now.Add(-24 * time.Hour)
 	cbefore1 := ctime1.Add(-20 * time.Second)
 	fqdn1 := &dns.FQDN{Name: "owasp.org"}
-	entity1, err := c.db.CreateEntity(&types.Entity{
+	entity1, err := c.db.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: ctime1,
 		LastSeen:  ctime1,
 		Asset:     fqdn1,
@@ -156,7 +197,7 @@ func TestFindEntityByContent(t *testing.T) {
 	ctime2 := now.Add(-8 * time.Hour)
 	cbefore2 := ctime2.Add(-20 * time.Second)
 	fqdn2 := &dns.FQDN{Name: "utica.edu"}
-	entity2, err := c.db.CreateEntity(&types.Entity{
+	entity2, err := c.db.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: ctime2,
 		LastSeen:  ctime2,
 		Asset:     fqdn2,
@@ -164,7 +205,7 @@ func TestFindEntityByContent(t *testing.T) {
 	assert.NoError(t, err)
 	// add new entities to the database
 	fqdn3 := &dns.FQDN{Name: "sunypoly.edu"}
-	entity3, err := c.CreateEntity(&types.Entity{
+	entity3, err := c.CreateEntity(context.Background(), &types.Entity{
 		CreatedAt: now,
 		LastSeen:  now,
 		Asset:     fqdn3,
@@ -172,10 +213,10 @@ func TestFindEntityByContent(t *testing.T) {
 	assert.NoError(t, err)
 	after := time.Now().Add(2 * time.Second)
 
-	_, err = c.FindEntitiesByContent(fqdn3, after)
+	_, err = c.FindEntitiesByContent(context.Background(), fqdn3, after)
 	assert.Error(t, err)
 
-	entities, err := c.FindEntitiesByContent(fqdn3, now)
+	entities, err := c.FindEntitiesByContent(context.Background(), fqdn3, now)
 	assert.NoError(t, err)
 	if len(entities) != 1 {
 		t.Errorf("first request failed to produce the expected number of entities")
@@ -186,10 +227,10 @@ func TestFindEntityByContent(t *testing.T) {
 		t.Errorf("DeepEqual failed for the assets in the two entities")
 	}
 
-	_, err = c.FindEntitiesByContent(fqdn2, c.StartTime())
+	_, err = c.FindEntitiesByContent(context.Background(), fqdn2, c.StartTime())
 	assert.Error(t, err)
 
-	entities, err = c.FindEntitiesByContent(fqdn2, cbefore2)
+	entities, err = c.FindEntitiesByContent(context.Background(), fqdn2, cbefore2)
 	assert.NoError(t, err)
 	if len(entities) != 1 {
 		t.Errorf("second request failed to produce the expected number of entities")
@@ -200,10 +241,10 @@ func TestFindEntityByContent(t *testing.T) {
 		t.Errorf("DeepEqual failed for the assets in the two entities")
 	}
 
-	_, err = c.FindEntitiesByContent(fqdn1, cbefore2)
+	_, err = c.FindEntitiesByContent(context.Background(), fqdn1, cbefore2)
 	assert.Error(t, err)
 
-	entities, err = c.FindEntitiesByContent(fqdn1, cbefore1)
+	entities, err = c.FindEntitiesByContent(context.Background(), fqdn1, cbefore1)
 	assert.NoError(t, err)
 	if len(entities) != 1 {
 		t.Errorf("third request failed to produce the expected number of entities")
@@ -215,6 +256,44 @@ func TestFindEntityByContent(t *testing.T) {
 	}
 }
 
+func TestFindEntitiesByContents(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	cached := &dns.FQDN{Name: "owasp.org"}
+	_, err = c.CreateAsset(context.Background(), cached)
+	assert.NoError(t, err)
+
+	stored := &dns.FQDN{Name: "utica.edu"}
+	_, err = db2.CreateAsset(context.Background(), stored)
+	assert.NoError(t, err)
+
+	missing := &dns.FQDN{Name: "sunypoly.edu"}
+	entities, err := c.FindEntitiesByContents(context.Background(), []oam.Asset{cached, stored, missing}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 2)
+	assert.Equal(t, cached, entities[types.ContentHash(cached)].Asset)
+	assert.Equal(t, stored, entities[types.ContentHash(stored)].Asset)
+
+	// the entities found in the database are added to the cache
+	_, err = db1.FindEntitiesByContent(context.Background(), stored, time.Time{})
+	assert.NoError(t, err)
+
+	// the database is not searched for entities last seen after the cache was started
+	entities, err = c.FindEntitiesByContents(context.Background(), []oam.Asset{missing}, c.StartTime())
+	assert.NoError(t, err)
+	assert.Empty(t, entities)
+}
+
 func TestFindEntitiesByType(t *testing.T) {
 	db1, db2, dir, err := createTestRepositories()
 	assert.NoError(t, err)
@@ -237,7 +316,7 @@ func TestFindEntitiesByType(t *testing.T) {
 	cafter1 := ctime1.Add(20 * time.Second)
 	for _, name := range []string{"owasp.org", "utica.edu", "sunypoly.edu"} {
 		set1.Insert(name)
-		_, err := c.db.CreateEntity(&types.Entity{
+		_, err := c.db.CreateEntity(context.Background(), &types.Entity{
 			CreatedAt: ctime1,
 			LastSeen:  ctime1,
 			Asset:     &dns.FQDN{Name: name},
@@ -253,7 +332,7 @@ func TestFindEntitiesByType(t *testing.T) {
 	cafter2 := ctime2.Add(20 * time.Second)
 	for _, name := range []string{"www.owasp.org", "www.utica.edu", "www.sunypoly.edu"} {
 		set2.Insert(name)
-		_, err := c.db.CreateEntity(&types.Entity{
+		_, err := c.db.CreateEntity(context.Background(), &types.Entity{
 			CreatedAt: ctime2,
 			LastSeen:  ctime2,
 			Asset:     &dns.FQDN{Name: name},
@@ -267,15 +346,15 @@ func TestFindEntitiesByType(t *testing.T) {
 	after := now.Add(20 * time.Second)
 	for _, name := range []string{"ns1.owasp.org", "ns1.utica.edu", "ns1.sunypoly.edu"} {
 		set3.Insert(name)
-		_, err := c.CreateAsset(&dns.FQDN{Name: name})
+		_, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: name})
 		assert.NoError(t, err)
 	}
 
 	// no results should be produced with this since param
-	_, err = c.FindEntitiesByType(oam.FQDN, after)
+	_, err = c.FindEntitiesByType(context.Background(), oam.FQDN, after)
 	assert.Error(t, err)
 
-	entities, err := c.FindEntitiesByType(oam.FQDN, c.StartTime())
+	entities, err := c.FindEntitiesByType(context.Background(), oam.FQDN, c.StartTime())
 	assert.NoError(t, err)
 	if len(entities) != 3 {
 		t.Errorf("first request failed to produce the expected number of entities")
@@ -294,10 +373,10 @@ func TestFindEntitiesByType(t *testing.T) {
 		t.Errorf("first request failed to produce the correct entities")
 	}
 	// there shouldn't be a tag for this entity, since it didn't require the database
-	_, err = c.cache.GetEntityTags(entities[0], now, "cache_find_entities_by_type")
+	_, err = c.cache.GetEntityTags(context.Background(), entities[0], now, "cache_find_entities_by_type")
 	assert.Error(t, err)
 
-	entities, err = c.FindEntitiesByType(oam.FQDN, ctime2)
+	entities, err = c.FindEntitiesByType(context.Background(), oam.FQDN, ctime2)
 	assert.NoError(t, err)
 	if len(entities) != 6 {
 		t.Errorf("second request failed to produce the expected number of entities")
@@ -316,7 +395,7 @@ func TestFindEntitiesByType(t *testing.T) {
 		t.Errorf("second request failed to produce the correct entities")
 	}
 	// there should be a tag for this entity
-	tags, err := c.cache.GetEntityTags(entities[0], time.Time{}, "cache_find_entities_by_type")
+	tags, err := c.cache.GetEntityTags(context.Background(), entities[0], time.Time{}, "cache_find_entities_by_type")
 	assert.NoError(t, err)
 	if len(tags) != 1 {
 		t.Errorf("second request failed to produce the expected number of entity tags")
@@ -327,7 +406,7 @@ func TestFindEntitiesByType(t *testing.T) {
 	assert.NoError(t, err)
 	assert.WithinRange(t, tagtime, cbefore2, cafter2)
 
-	entities, err = c.FindEntitiesByType(oam.FQDN, ctime1)
+	entities, err = c.FindEntitiesByType(context.Background(), oam.FQDN, ctime1)
 	assert.NoError(t, err)
 	if len(entities) != 9 {
 		t.Errorf("third request failed to produce the expected number of entities")
@@ -346,7 +425,7 @@ func TestFindEntitiesByType(t *testing.T) {
 		t.Errorf("third request failed to produce the correct entities")
 	}
 	// there should now be a new tag for this entity
-	tags, err = c.cache.GetEntityTags(entities[0], time.Time{}, "cache_find_entities_by_type")
+	tags, err = c.cache.GetEntityTags(context.Background(), entities[0], time.Time{}, "cache_find_entities_by_type")
 	assert.NoError(t, err)
 	if len(tags) != 1 {
 		t.Errorf("third request failed to produce the expected number of entity tags")
@@ -358,6 +437,79 @@ func TestFindEntitiesByType(t *testing.T) {
 	assert.WithinRange(t, tagtime, cbefore1, cafter1)
 }
 
+func TestFindEntitiesByTypePaged(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	names := []string{"owasp.org", "utica.edu", "sunypoly.edu", "example.com", "example.org"}
+	for _, name := range names {
+		_, err := c.db.CreateAsset(context.Background(), &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+	}
+
+	entities, total, err := c.FindEntitiesByTypePaged(context.Background(), oam.FQDN, time.Time{}, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(names)), total)
+	assert.Len(t, entities, 2)
+
+	for _, entity := range entities {
+		_, err := c.cache.FindEntityById(context.Background(), entity.ID)
+		assert.NoError(t, err)
+	}
+
+	_, total, err = c.FindEntitiesByTypePaged(context.Background(), oam.FQDN, time.Time{}, len(names), 2)
+	assert.Error(t, err)
+	assert.Equal(t, int64(len(names)), total)
+}
+
+func TestIterateEntitiesByType(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	set := stringset.New("owasp.org", "utica.edu", "sunypoly.edu")
+	defer set.Close()
+
+	for _, name := range set.Slice() {
+		_, err := c.db.CreateAsset(context.Background(), &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+	}
+
+	iter, err := c.IterateEntitiesByType(context.Background(), oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+
+	var count int
+	for iter.Next() {
+		e := iter.Entity()
+		assert.True(t, set.Has(e.Asset.Key()))
+
+		// the entity must be present in the cache
+		_, err := c.cache.FindEntityById(context.Background(), e.ID)
+		assert.NoError(t, err)
+		count++
+	}
+	assert.NoError(t, iter.Err())
+	assert.NoError(t, iter.Close())
+	assert.Equal(t, set.Len(), count)
+}
+
 func TestDeleteEntity(t *testing.T) {
 	db1, db2, dir, err := createTestRepositories()
 	assert.NoError(t, err)
@@ -371,16 +523,381 @@ func TestDeleteEntity(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { _ = c.Close() }()
 
-	entity, err := c.CreateAsset(&dns.FQDN{Name: "owasp.org"})
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err)
 
-	err = c.DeleteEntity(entity.ID)
+	n, err := c.DeleteEntity(context.Background(), entity.ID)
 	assert.NoError(t, err)
+	// the count is that of the entities deleted from the database
+	assert.Equal(t, int64(1), n)
 
-	_, err = c.FindEntityById(entity.ID)
+	_, err = c.FindEntityById(context.Background(), entity.ID)
 	assert.Error(t, err)
 
 	time.Sleep(250 * time.Millisecond)
-	_, err = db2.FindEntitiesByContent(entity.Asset, time.Time{})
+	_, err = db2.FindEntitiesByContent(context.Background(), entity.Asset, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestOrphanEntities(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	orphan, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "orphan.owasp.org"})
+	assert.NoError(t, err)
+
+	// the edge only present in the database keeps the entity from being an orphan
+	dbentity, err := db2.FindEntitiesByContent(context.Background(), entity.Asset, time.Time{})
+	assert.NoError(t, err)
+	target, err := db2.CreateAsset(context.Background(), &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	_, err = db2.CreateEdge(context.Background(), &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: dbentity[0],
+		ToEntity:   target,
+	})
+	assert.NoError(t, err)
+
+	orphans, err := c.FindOrphanEntities(context.Background(), oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, orphans, 1) {
+		assert.Equal(t, orphan.ID, orphans[0].ID)
+	}
+
+	n, err := c.DeleteOrphanEntities(context.Background(), oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = c.FindEntityById(context.Background(), orphan.ID)
 	assert.Error(t, err)
+	_, err = c.FindEntityById(context.Background(), entity.ID)
+	assert.NoError(t, err)
+	_, err = db2.FindEntitiesByContent(context.Background(), orphan.Asset, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestSoftDelete(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	db, err := assetdb.New(sqlrepo.SQLite, filepath.Join(dir, "softdelete.sqlite"), options.WithSoftDelete())
+	assert.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	c, err := New(db1, db, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	_, err = c.DeleteEntity(context.Background(), entity.ID)
+	assert.NoError(t, err)
+
+	// the tombstone is kept by the database
+	deleted, err := c.FindDeletedEntities(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 1)
+	assert.Equal(t, entity.Asset, deleted[0].Asset)
+
+	assert.NoError(t, c.PurgeDeleted(context.Background(), time.Time{}))
+	_, err = c.FindDeletedEntities(context.Background(), time.Time{})
+	assert.Error(t, err)
+}
+
+func TestUpsertEntity(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	_, err = db2.CreateAsset(context.Background(), &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+
+	// the entity is new to the cache, but already exists in the database
+	_, result, err := c.UpsertEntity(context.Background(), &types.Entity{Asset: &dns.FQDN{Name: "www.owasp.org"}})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUnchanged, result)
+
+	entity, result, err := c.UpsertEntity(context.Background(), &types.Entity{Asset: &dns.FQDN{Name: "owasp.org"}})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertCreated, result)
+
+	time.Sleep(250 * time.Millisecond)
+	dbents, err := db2.FindEntitiesByContent(context.Background(), entity.Asset, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, dbents, 1)
+}
+
+func TestCountEntitiesByType(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	// entities only present in the database are included in the count
+	for _, name := range []string{"owasp.org", "www.owasp.org"} {
+		_, err := db2.CreateAsset(context.Background(), &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+	}
+
+	total, err := c.CountEntitiesByType(context.Background(), oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+
+	grouped, err := c.CountEntitiesGrouped(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[oam.AssetType]int64{oam.FQDN: 2}, grouped)
+}
+
+func TestSearchEntities(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	for _, name := range []string{"owasp.org", "www.owasp.org", "example.com"} {
+		_, err := db2.CreateAsset(context.Background(), &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+	}
+
+	entities, err := c.SearchEntities(context.Background(), oam.FQDN, "owasp", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 2)
+
+	// the matching entities are added to the cache
+	_, err = db1.FindEntitiesByContent(context.Background(), &dns.FQDN{Name: "www.owasp.org"}, time.Time{})
+	assert.NoError(t, err)
+}
+
+func TestFindEntityByHash(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	entity, err := db2.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+
+	found, err := c.FindEntityByHash(context.Background(), entity.ContentHash())
+	assert.NoError(t, err)
+	assert.Equal(t, "owasp.org", found.Asset.Key())
+
+	// the matching entity is added to the cache
+	_, err = db1.FindEntityByHash(context.Background(), entity.ContentHash())
+	assert.NoError(t, err)
+
+	_, err = c.FindEntityByHash(context.Background(), types.ContentHash(&dns.FQDN{Name: "example.com"}))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestEntityExists(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	_, err = db2.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+
+	found, err := c.EntityExists(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	// the entity found in the database is not added to the cache
+	found, err = db1.EntityExists(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = c.EntityExists(context.Background(), &dns.FQDN{Name: "example.com"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestFindEntitiesByTypeBetween(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"owasp.org", "www.owasp.org"} {
+		_, err := db2.CreateEntity(context.Background(), &types.Entity{
+			LastSeen: start.Add(time.Duration(i) * time.Hour),
+			Asset:    &dns.FQDN{Name: name},
+		})
+		assert.NoError(t, err)
+	}
+
+	// the entities only present in the database are found within the window
+	entities, err := c.FindEntitiesByTypeBetween(context.Background(), oam.FQDN, start, start.Add(time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, entities, 1) {
+		assert.Equal(t, "owasp.org", entities[0].Asset.Key())
+	}
+
+	_, err = c.FindEntitiesByTypeBetween(context.Background(), oam.FQDN, start.Add(2*time.Hour), time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestDiffEntities(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	t1 := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(24 * time.Hour)
+	_, err = db2.CreateEntity(context.Background(), &types.Entity{
+		CreatedAt: t1.Add(time.Hour),
+		LastSeen:  t2.Add(time.Hour),
+		Asset:     &dns.FQDN{Name: "owasp.org"},
+	})
+	assert.NoError(t, err)
+
+	added, removed, err := c.DiffEntities(context.Background(), oam.FQDN, t1, t2)
+	assert.NoError(t, err)
+	assert.Len(t, added, 1)
+	assert.Empty(t, removed)
+
+	// the added entities are added to the cache
+	_, err = db1.FindEntitiesByContent(context.Background(), &dns.FQDN{Name: "owasp.org"}, time.Time{})
+	assert.NoError(t, err)
+}
+
+func TestTouchEntity(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	seen := time.Now().Add(-time.Hour)
+	entity, err := c.CreateEntity(context.Background(), &types.Entity{
+		LastSeen: seen,
+		Asset:    &dns.FQDN{Name: "owasp.org"},
+	})
+	assert.NoError(t, err)
+
+	// the last seen time is updated in both the cache and the database
+	assert.NoError(t, c.TouchEntity(context.Background(), entity.ID))
+	touched, err := c.FindEntityById(context.Background(), entity.ID)
+	assert.NoError(t, err)
+	assert.True(t, touched.LastSeen.After(seen))
+
+	dbents, err := db2.FindEntitiesByContent(context.Background(), entity.Asset, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, dbents, 1) {
+		assert.True(t, dbents[0].LastSeen.After(seen))
+	}
+
+	assert.ErrorIs(t, c.TouchEntity(context.Background(), "999"), types.ErrNotFound)
+}
+
+func TestUpdateEntity(t *testing.T) {
+	db1, db2, dir, err := createTestRepositories()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db1.Close()
+		_ = db2.Close()
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, err := New(db1, db2, time.Minute)
+	assert.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	entity, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	other, err := c.CreateAsset(context.Background(), &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+
+	// the content is updated in both the cache and the database
+	updated, err := c.UpdateEntity(context.Background(), entity.ID, &dns.FQDN{Name: "docs.owasp.org"})
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ID, updated.ID)
+	found, err := c.FindEntityById(context.Background(), entity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "docs.owasp.org", found.Asset.Key())
+	_, err = db2.FindEntityByHash(context.Background(), types.ContentHash(&dns.FQDN{Name: "docs.owasp.org"}))
+	assert.NoError(t, err)
+
+	// a rejected update leaves the cache unchanged
+	_, err = c.UpdateEntity(context.Background(), entity.ID, other.Asset)
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+	found, err = c.FindEntityById(context.Background(), entity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "docs.owasp.org", found.Asset.Key())
+
+	_, err = c.UpdateEntity(context.Background(), "999", &dns.FQDN{Name: "missing.owasp.org"})
+	assert.ErrorIs(t, err, types.ErrNotFound)
 }
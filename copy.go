@@ -0,0 +1,228 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/garthoid/asset-db/repository"
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// defaultCopyBatchSize is the number of entities written to the destination by each batch of CopyAll.
+const defaultCopyBatchSize = 100
+
+// CopyStats counts the records written to the destination repository by CopyAll.
+type CopyStats struct {
+	Entities   int
+	Edges      int
+	EntityTags int
+	EdgeTags   int
+}
+
+// CopyOption configures the behavior of CopyAll.
+type CopyOption func(*copyOptions)
+
+type copyOptions struct {
+	batchSize int
+	progress  func(CopyStats)
+}
+
+// WithCopyBatchSize sets the number of entities written to the destination by each batch.
+// Values less than one keep the default of 100.
+func WithCopyBatchSize(n int) CopyOption {
+	return func(o *copyOptions) {
+		if n > 0 {
+			o.batchSize = n
+		}
+	}
+}
+
+// WithCopyProgress sets the function called with the running totals after each batch is written.
+func WithCopyProgress(fn func(CopyStats)) CopyOption {
+	return func(o *copyOptions) {
+		o.progress = fn
+	}
+}
+
+// copyBatch holds the source entities of a batch, along with the destination entities they were written to.
+type copyBatch struct {
+	src []*types.Entity
+	dst []*types.Entity
+}
+
+// CopyAll reads the entities, edges, and tags from src and writes them to dst, such as when moving the data
+// from SQLite to Postgres. The entities of each asset type are streamed with IterateEntitiesByType and written
+// with CreateEntities in batches, and the source IDs are resolved to the destination IDs, so the edges and tags
+// are attached to the entities they belong to. The records keep their timestamps, and the records that already
+// exist in dst are updated rather than duplicated. The edges and tags of each batch are written within a single
+// transaction of dst. The source and destination entities are held in memory until the copy is complete, so
+// the IDs can be resolved. Returns the number of records written, including the batches written before an error.
+func CopyAll(ctx context.Context, src, dst repository.Repository, opts ...CopyOption) (CopyStats, error) {
+	var stats CopyStats
+	if src == nil || dst == nil {
+		return stats, errors.New("failed input validation checks")
+	}
+
+	o := &copyOptions{batchSize: defaultCopyBatchSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// the entities are copied before the edges, since an edge may refer to an entity of any asset type
+	ids := make(map[string]*types.Entity)
+	var batches []*copyBatch
+	for _, atype := range oam.AssetList {
+		b, err := copyEntities(ctx, src, dst, atype, o, ids, &stats)
+		if err != nil {
+			return stats, err
+		}
+		batches = append(batches, b...)
+	}
+
+	for _, b := range batches {
+		// the counts of a batch are only added once its transaction is committed
+		var batch CopyStats
+		if err := dst.WithTransaction(ctx, func(tx repository.Repository) error {
+			batch = CopyStats{}
+			return copyBatchRelations(ctx, src, tx, b, ids, &batch)
+		}); err != nil {
+			return stats, err
+		}
+
+		stats.Edges += batch.Edges
+		stats.EntityTags += batch.EntityTags
+		stats.EdgeTags += batch.EdgeTags
+		if o.progress != nil {
+			o.progress(stats)
+		}
+	}
+	return stats, nil
+}
+
+// copyEntities writes the entities of the asset type in src to dst in batches, and records the destination entity of each source ID.
+// The batches are returned, so that the edges and tags of the entities can be copied once all the entities exist in dst.
+func copyEntities(ctx context.Context, src, dst repository.Repository, atype oam.AssetType,
+	o *copyOptions, ids map[string]*types.Entity, stats *CopyStats) ([]*copyBatch, error) {
+	it, err := src.IterateEntitiesByType(ctx, atype, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = it.Close() }()
+
+	var batches []*copyBatch
+	var pending []*types.Entity
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+
+		inputs := make([]*types.Entity, 0, len(pending))
+		for _, e := range pending {
+			inputs = append(inputs, &types.Entity{CreatedAt: e.CreatedAt, LastSeen: e.LastSeen, Asset: e.Asset})
+		}
+
+		results, err := dst.CreateEntities(ctx, inputs)
+		if err != nil {
+			return err
+		}
+
+		for i, e := range pending {
+			ids[e.ID] = results[i]
+		}
+		batches = append(batches, &copyBatch{src: pending, dst: results})
+		stats.Entities += len(results)
+		if o.progress != nil {
+			o.progress(*stats)
+		}
+
+		pending = nil
+		return nil
+	}
+
+	for it.Next() {
+		pending = append(pending, it.Entity())
+		if len(pending) >= o.batchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// copyBatchRelations writes the tags of the entities in the batch to dst, along with their outgoing edges and the tags of those edges.
+// The edges that refer to entities that were not copied are skipped.
+func copyBatchRelations(ctx context.Context, src, dst repository.Repository,
+	b *copyBatch, ids map[string]*types.Entity, stats *CopyStats) error {
+	for i, from := range b.src {
+		to := b.dst[i]
+
+		tags, err := src.GetEntityTags(ctx, from, time.Time{})
+		if err != nil && !errors.Is(err, types.ErrNotFound) {
+			return err
+		}
+		for _, t := range tags {
+			if _, err := dst.CreateEntityTag(ctx, to, &types.EntityTag{
+				CreatedAt: t.CreatedAt,
+				LastSeen:  t.LastSeen,
+				ExpiresAt: t.ExpiresAt,
+				Property:  t.Property,
+			}); err != nil {
+				return err
+			}
+			stats.EntityTags++
+		}
+
+		edges, err := src.OutgoingEdges(ctx, from, time.Time{})
+		if err != nil && !errors.Is(err, types.ErrNotFound) {
+			return err
+		}
+		for _, e := range edges {
+			target, found := ids[e.ToEntity.ID]
+			if !found {
+				continue
+			}
+
+			edge, err := dst.CreateEdge(ctx, &types.Edge{
+				CreatedAt:  e.CreatedAt,
+				LastSeen:   e.LastSeen,
+				Relation:   e.Relation,
+				FromEntity: to,
+				ToEntity:   target,
+			})
+			if err != nil {
+				return err
+			}
+			stats.Edges++
+
+			etags, err := src.GetEdgeTags(ctx, e, time.Time{})
+			if err != nil && !errors.Is(err, types.ErrNotFound) {
+				return err
+			}
+			for _, t := range etags {
+				if _, err := dst.CreateEdgeTag(ctx, edge, &types.EdgeTag{
+					CreatedAt: t.CreatedAt,
+					LastSeen:  t.LastSeen,
+					ExpiresAt: t.ExpiresAt,
+					Property:  t.Property,
+				}); err != nil {
+					return err
+				}
+				stats.EdgeTags++
+			}
+		}
+	}
+	return nil
+}
@@ -6,88 +6,480 @@ package assetdb
 
 import (
 	"context"
-	"crypto/tls"
+	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/garthoid/asset-db/internal/redact"
+	mysqlmigrations "github.com/garthoid/asset-db/migrations/mysql"
 	neomigrations "github.com/garthoid/asset-db/migrations/neo4j"
 	pgmigrations "github.com/garthoid/asset-db/migrations/postgres"
 	sqlitemigrations "github.com/garthoid/asset-db/migrations/sqlite3"
+	"github.com/garthoid/asset-db/options"
 	"github.com/garthoid/asset-db/repository"
 	"github.com/garthoid/asset-db/repository/neo4j"
 	"github.com/garthoid/asset-db/repository/sqlrepo"
-	"github.com/glebarez/sqlite"
+	"github.com/garthoid/asset-db/types"
+	"github.com/jackc/pgx/v5"
 	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j/config"
 	migrate "github.com/rubenv/sql-migrate"
-	"gorm.io/driver/postgres"
+	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
+// backfillBatchSize is the number of entities updated by each batch of the content hash backfill.
+const backfillBatchSize = 500
+
 // New creates a new assetDB instance.
 // It initializes the asset database with the specified database type and DSN.
-func New(dbtype, dsn string) (repository.Repository, error) {
+// The options, such as options.WithMaxConnections, are passed to the repository implementation.
+// The pending schema migrations are applied, unless options.WithoutMigrations is provided.
+// The database type is matched case-insensitively, and an unsupported type is reported before connecting.
+// For sqlrepo.SQLiteMemory, the DSN is ignored and each call creates a new in-memory database.
+func New(dbtype, dsn string, opts ...options.Option) (repository.Repository, error) {
+	dbtype, err := repository.ParseType(dbtype)
+	if err != nil {
+		return nil, err
+	}
+
 	if dbtype == sqlrepo.SQLiteMemory {
-		dsn = fmt.Sprintf("file:mem%d?mode=memory&cache=shared", rand.Intn(1000))
+		dsn = memoryDSN()
+	}
+	return newMigrated(dbtype, dsn, opts...)
+}
+
+// SupportedDBTypes returns the database types accepted by New, Open, and Migrate, as the constants of the
+// sqlrepo and neo4j packages. The list is maintained by the repository package, so it includes every backend.
+func SupportedDBTypes() []string {
+	return repository.SupportedDBTypes()
+}
+
+// NewSQLiteMemory creates a new assetDB instance for the in-memory SQLite database with the provided name.
+// Calls with the same name open handles to the same database, so that it can be shared, such as by the
+// repositories of a test. The database exists as long as at least one of the handles is open.
+func NewSQLiteMemory(name string, opts ...options.Option) (repository.Repository, error) {
+	if name == "" || strings.ContainsAny(name, "/?#&") {
+		return nil, fmt.Errorf("invalid name for the in-memory database: %q", name)
+	}
+	return newMigrated(sqlrepo.SQLiteMemory, fmt.Sprintf("file:mem%s?mode=memory&cache=shared", name), opts...)
+}
+
+// NewWithSQLDB creates a new assetDB instance for the SQL database type that shares the *sql.DB provided by the caller,
+// such as one opened with stdlib.OpenDBFromPool for an existing pgxpool.Pool, so that a second connection pool is not
+// opened. The pending schema migrations are applied with the handle, unless options.WithoutMigrations is provided.
+// The handle remains owned by the caller, so closing the repository leaves it open.
+func NewWithSQLDB(dbtype string, db *sql.DB, opts ...options.Option) (repository.Repository, error) {
+	r, err := repository.NewWithSQLDB(dbtype, db, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if o := options.Apply(opts...); !o.SkipMigrations {
+		dbtype, _ = repository.ParseType(dbtype)
+		name, source := sqlMigrationSource(dbtype, o.TablePrefix)
+		if err := migrateSQLDB(name, db, source, o); err != nil {
+			_ = r.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// NewWithDialector creates a new assetDB instance for the SQL database type with the GORM dialector provided by the caller,
+// such as one created with a custom driver configuration. The pending schema migrations are applied, unless
+// options.WithoutMigrations is provided, with a separate handle opened by the dialector, as New does with the DSN.
+// The dialector must therefore open a new handle each time, as those of postgres.Open and mysql.Open do, and
+// an existing *sql.DB is shared with NewWithSQLDB instead.
+func NewWithDialector(dbtype string, dialector gorm.Dialector, opts ...options.Option) (repository.Repository, error) {
+	r, err := repository.NewWithDialector(dbtype, dialector, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if o := options.Apply(opts...); !o.SkipMigrations {
+		dbtype, _ = repository.ParseType(dbtype)
+		name, source := sqlMigrationSource(dbtype, o.TablePrefix)
+		if err := sqlMigrate(name, dialector, source, o); err != nil {
+			_ = r.Close()
+			return nil, err
+		}
 	}
+	return r, nil
+}
 
-	db, err := repository.New(dbtype, dsn)
+// NewWithNeo4jDriver creates a new assetDB instance that uses the Neo4j driver provided by the caller, such as one with a
+// custom TLS configuration, resolver, or logger, for the database with the provided name. The pending schema migrations
+// are applied with the driver, unless options.WithoutMigrations is provided. The driver remains owned by the caller,
+// so closing the repository leaves it open.
+func NewWithNeo4jDriver(driver neo4jdb.DriverWithContext, dbname string, opts ...options.Option) (repository.Repository, error) {
+	r, err := repository.NewWithNeo4jDriver(driver, dbname, opts...)
 	if err != nil {
 		return nil, err
 	}
-	if err := migrateDatabase(dbtype, dsn); err != nil {
+
+	if o := options.Apply(opts...); !o.SkipMigrations {
+		if o.Neo4jDatabase != "" {
+			dbname = o.Neo4jDatabase
+		}
+		if err := neoMigrateDriver(driver, dbname); err != nil {
+			_ = r.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// newMigrated creates the repository and applies the pending schema migrations, unless options.WithoutMigrations is provided.
+func newMigrated(dbtype, dsn string, opts ...options.Option) (repository.Repository, error) {
+	db, err := repository.New(dbtype, dsn, opts...)
+	if err != nil {
 		return nil, err
 	}
+
+	if o := options.Apply(opts...); !o.SkipMigrations {
+		if err := migrateDatabase(dbtype, dsn, o); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
 	return db, nil
 }
 
-func migrateDatabase(dbtype, dsn string) error {
+// Open connects to the asset database with the specified database type and DSN, without applying
+// the schema migrations. It's intended for read replicas, and for deployments where the migrations
+// are run separately with Migrate. The options are passed to the repository implementation.
+// Since each in-memory SQLite database is private to its repository, it must be created with New or NewSQLiteMemory.
+func Open(dbtype, dsn string, opts ...options.Option) (repository.Repository, error) {
+	dbtype, err := repository.ParseType(dbtype)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbtype == sqlrepo.SQLiteMemory {
+		dsn = memoryDSN()
+	}
+	return repository.New(dbtype, dsn, opts...)
+}
+
+// Migrate applies the pending schema migrations to the asset database with the specified database type and DSN.
+// The options, such as options.WithTLSConfig, are used to connect to the database.
+func Migrate(dbtype, dsn string, opts ...options.Option) error {
+	dbtype, err := repository.ParseType(dbtype)
+	if err != nil {
+		return err
+	}
+	return migrateDatabase(dbtype, dsn, options.Apply(opts...))
+}
+
+// memoryDSN returns the DSN of a new in-memory SQLite database.
+// The name is random, so that the databases created by separate calls do not collide.
+func memoryDSN() string {
+	return fmt.Sprintf("file:mem%d?mode=memory&cache=shared", rand.Int63())
+}
+
+func migrateDatabase(dbtype, dsn string, o *options.Options) error {
+	if dbtype == neo4j.Neo4j {
+		return neoMigrate(dsn, o)
+	}
+
+	name, database, source, err := sqlMigrations(dbtype, dsn, o)
+	if err != nil {
+		return err
+	}
+	if database == nil {
+		return errors.New("unknown DB type")
+	}
+	return sqlMigrate(name, database, source, o)
+}
+
+// sqlMigrations returns the sql-migrate dialect, the GORM dialector, and the migrations for the SQL database type.
+// The dialector is nil when the database type does not use SQL migrations.
+func sqlMigrations(dbtype, dsn string, o *options.Options) (string, gorm.Dialector, migrate.MigrationSource, error) {
+	var database gorm.Dialector
+
 	switch dbtype {
 	case sqlrepo.SQLite:
 		fallthrough
 	case sqlrepo.SQLiteMemory:
-		return sqlMigrate("sqlite3", sqlite.Open(dsn), sqlitemigrations.Migrations())
+		// the migrations use the same pragmas as the repository
+		database = sqlrepo.SQLiteDialector(dsn, o)
 	case sqlrepo.Postgres:
-		return sqlMigrate("postgres", postgres.Open(dsn), pgmigrations.Migrations())
-	case neo4j.Neo4j:
-		return neoMigrate(dsn)
+		// the migrations use the same TLS configuration as the repository, but are not bound by the query timeout
+		// or the read-only mode
+		mo := *o
+		mo.QueryTimeout = 0
+		mo.ReadOnly = false
+		dialector, err := sqlrepo.PostgresDialector(dsn, &mo)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		database = dialector
+	case sqlrepo.MySQL:
+		database = mysql.Open(dsn)
+	default:
+		return "", nil, nil, nil
 	}
-	return nil
+
+	name, source := sqlMigrationSource(dbtype, o.TablePrefix)
+	return name, database, source, nil
 }
 
-func sqlMigrate(name string, database gorm.Dialector, fs embed.FS) error {
-	sql, err := gorm.Open(database, &gorm.Config{})
-	if err != nil {
-		return err
+// sqlMigrationSource returns the sql-migrate dialect and the migrations for the SQL database type,
+// or an empty dialect when the database type does not use SQL migrations.
+func sqlMigrationSource(dbtype, prefix string) (string, migrate.MigrationSource) {
+	var name string
+	var fs embed.FS
+
+	switch dbtype {
+	case sqlrepo.SQLite, sqlrepo.SQLiteMemory:
+		name, fs = "sqlite3", sqlitemigrations.Migrations()
+	case sqlrepo.Postgres:
+		name, fs = "postgres", pgmigrations.Migrations()
+	case sqlrepo.MySQL:
+		name, fs = "mysql", mysqlmigrations.Migrations()
+	default:
+		return "", nil
 	}
 
-	migrationsSource := migrate.EmbedFileSystemMigrationSource{
+	var source migrate.MigrationSource = migrate.EmbedFileSystemMigrationSource{
 		FileSystem: fs,
 		Root:       "/",
 	}
+	if prefix != "" {
+		source = prefixedSource{source: source, prefix: prefix}
+	}
+	return name, source
+}
+
+// prefixedSource adds the table prefix set by options.WithTablePrefix to the names of the schema in the statements
+// of the migrations, so the tables, indexes, and constraints are created with the names used by the repository.
+type prefixedSource struct {
+	source migrate.MigrationSource
+	prefix string
+}
+
+// FindMigrations implements the migrate.MigrationSource interface.
+func (s prefixedSource) FindMigrations() ([]*migrate.Migration, error) {
+	if err := sqlrepo.CheckTablePrefix(s.prefix); err != nil {
+		return nil, err
+	}
+
+	migrations, err := s.source.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range migrations {
+		for i, stmt := range m.Up {
+			m.Up[i] = sqlrepo.PrefixSchema(s.prefix, stmt)
+		}
+		for i, stmt := range m.Down {
+			m.Down[i] = sqlrepo.PrefixSchema(s.prefix, stmt)
+		}
+	}
+	return migrations, nil
+}
+
+// migrationSet returns the sql-migrate settings of the options, where the table that records the applied migrations
+// is named with the table prefix, so the schemas with different prefixes are migrated independently.
+func migrationSet(o *options.Options) migrate.MigrationSet {
+	return migrate.MigrationSet{TableName: o.TablePrefix + "gorp_migrations"}
+}
 
-	sqlDb, err := sql.DB()
+// sqlMigrate applies the pending migrations while holding the migration lock, so that concurrent
+// runners wait for the migrations to be applied rather than racing to apply them.
+func sqlMigrate(name string, database gorm.Dialector, source migrate.MigrationSource, o *options.Options) (err error) {
+	sqlDb, err := openSQL(database)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = sqlDb.Close() }()
 
-	_, err = migrate.Exec(sqlDb, name, migrationsSource, migrate.Up)
+	if name == "sqlite3" {
+		// the busy timeout is set per connection, so a single connection is used for the migrations
+		sqlDb.SetMaxOpenConns(1)
+		if _, err := sqlDb.Exec(fmt.Sprintf("PRAGMA busy_timeout = %d", sqliteBusyTimeout)); err != nil {
+			return err
+		}
+	}
+	return migrateSQLDB(name, sqlDb, source, o)
+}
+
+// migrateSQLDB applies the pending migrations with the database handle while holding the migration lock.
+// The handle is left open, so it can be shared with the repository, such as by NewWithSQLDB.
+func migrateSQLDB(name string, sqlDb *sql.DB, source migrate.MigrationSource, o *options.Options) (err error) {
+	unlock, err := lockMigrations(context.Background(), name, sqlDb)
 	if err != nil {
 		return err
 	}
-	return nil
+	// the lock is released even when the migrations fail
+	defer func() {
+		if uerr := unlock(); err == nil {
+			err = uerr
+		}
+	}()
+
+	if err := createSchema(sqlDb, name, o); err != nil {
+		return err
+	}
+
+	// the hashes are set before the migration that makes them unique, which would otherwise
+	// be applied while the existing entities have no hash
+	before := migrationSet(o)
+	before.IgnoreUnknown = true
+	if _, err := before.Exec(sqlDb, name, beforeUniqueHash{source: source}, migrate.Up); err != nil {
+		return err
+	}
+	if err := backfillContentHashes(sqlDb, name, o.TablePrefix); err != nil {
+		return err
+	}
+
+	_, err = migrationSet(o).Exec(sqlDb, name, source, migrate.Up)
+	return err
 }
 
-func neoMigrate(dsn string) error {
-	u, err := url.Parse(dsn)
+// uniqueHashMigration is the name shared by the migrations of each dialect that make the content hash unique.
+const uniqueHashMigration = "_entities_content_hash_unique.sql"
+
+// beforeUniqueHash limits the migrations to those that precede the migration that makes the content hash unique.
+type beforeUniqueHash struct {
+	source migrate.MigrationSource
+}
+
+// FindMigrations implements the migrate.MigrationSource interface.
+func (s beforeUniqueHash) FindMigrations() ([]*migrate.Migration, error) {
+	migrations, err := s.source.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Less(migrations[j]) })
+	for i, m := range migrations {
+		if strings.HasSuffix(m.Id, uniqueHashMigration) {
+			return migrations[:i], nil
+		}
+	}
+	return migrations, nil
+}
+
+// backfillContentHashes sets the content hash of the entities written before the content_hash column was added.
+// The entities are updated in batches, selected in the order of their IDs. The content that cannot be parsed
+// keeps a NULL hash, which the unique index allows more than once, and is passed over by the ID of the last
+// entity selected. The entities written by the repositories already have the hash.
+func backfillContentHashes(db *sql.DB, name, prefix string) error {
+	update := "UPDATE entities SET content_hash = ? WHERE entity_id = ?"
+	if name == "postgres" {
+		update = "UPDATE entities SET content_hash = $1 WHERE entity_id = $2"
+	}
+	update = sqlrepo.PrefixSchema(prefix, update)
+
+	var last uint64
+	for {
+		rows, err := db.Query(sqlrepo.PrefixSchema(prefix, fmt.Sprintf("SELECT entity_id, etype, content FROM entities "+
+			"WHERE content_hash IS NULL AND entity_id > %d ORDER BY entity_id LIMIT %d", last, backfillBatchSize)))
+		if err != nil {
+			return err
+		}
+
+		var selected int
+		hashes := make(map[uint64]string)
+		for rows.Next() {
+			var id uint64
+			var etype string
+			var content []byte
+			if err := rows.Scan(&id, &etype, &content); err != nil {
+				_ = rows.Close()
+				return err
+			}
+
+			selected++
+			last = id
+			if asset, err := types.ParseAsset(etype, content); err == nil {
+				hashes[id] = types.ContentHash(asset)
+			}
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if selected == 0 {
+			return nil
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for id, hash := range hashes {
+			if _, err := tx.Exec(update, hash, id); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+}
+
+// createSchema creates the Postgres schema selected by the options, so the migrations can create their tables in it.
+// The schema is created while holding the migration lock, since concurrent creations of the same schema conflict.
+func createSchema(db *sql.DB, name string, o *options.Options) error {
+	if name != "postgres" || o.Schema == "" {
+		return nil
+	}
+
+	_, err := db.Exec("CREATE SCHEMA IF NOT EXISTS " + pgx.Identifier{o.Schema}.Sanitize())
+	return err
+}
+
+// openSQL opens the database handle used to run the migrations.
+func openSQL(database gorm.Dialector) (*sql.DB, error) {
+	db, err := gorm.Open(database, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	return db.DB()
+}
+
+func neoMigrate(dsn string, o *options.Options) error {
+	driver, dbname, err := neoDriver(dsn, o)
 	if err != nil {
 		return err
 	}
+	defer func() { _ = driver.Close(context.Background()) }()
+
+	return neoMigrateDriver(driver, dbname)
+}
+
+// neoMigrateDriver applies the schema migrations with the driver, which is left open.
+func neoMigrateDriver(driver neo4jdb.DriverWithContext, dbname string) error {
+	// the hashes are set before the migration that makes them unique
+	if err := neomigrations.InitializeSchemaBefore(driver, dbname, neomigrations.UniqueContentHash); err != nil {
+		return err
+	}
+	if err := neo4j.BackfillContentHashes(context.Background(), driver, dbname); err != nil {
+		return err
+	}
+	return neomigrations.InitializeSchema(driver, dbname)
+}
+
+// neoDriver creates the Neo4j driver for the DSN and verifies the connectivity to the server.
+// Returns the driver along with the name of the database selected by the options or the DSN.
+func neoDriver(dsn string, o *options.Options) (neo4jdb.DriverWithContext, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, "", redact.Error(err)
+	}
 
 	auth := neo4jdb.NoAuth()
 	var username, password string
@@ -97,38 +489,32 @@ func neoMigrate(dsn string) error {
 		auth = neo4jdb.BasicAuth(username, password, "")
 	}
 	dbname := strings.TrimPrefix(u.Path, "/")
+	if o.Neo4jDatabase != "" {
+		dbname = o.Neo4jDatabase
+	}
 
-	// --- SUGGESTED CHANGE: START ---
-	// Use the original DSN. The driver natively handles bolt+s and bolt+ssc.
-	originalDSN := dsn
-	var tlsConfig *tls.Config // Will remain nil for +s and +ssc
-
+	// the DSN is passed to the driver unchanged, so the "neo4j" schemes use the routing of a cluster,
+	// while the "bolt" schemes connect directly to a single instance
 	switch u.Scheme {
-	case "bolt+ssc", "neo4j+ssc":
-		// Let the driver handle this scheme natively
-	case "bolt+s", "neo4j+s":
-		// Let the driver handle this scheme natively
-	case "bolt", "neo4j":
-		// Driver may default to encryption, so explicitly disable it.
-		tlsConfig = nil
+	case "bolt", "bolt+s", "bolt+ssc", "neo4j", "neo4j+s", "neo4j+ssc":
 	default:
-		return fmt.Errorf("neoMigrate: unsupported scheme %q", u.Scheme)
+		return nil, "", fmt.Errorf("neoMigrate: unsupported scheme %q", u.Scheme)
 	}
-	// --- SUGGESTED CHANGE: END ---
 
-	driver, err := neo4jdb.NewDriverWithContext(originalDSN, auth, func(cfg *config.Config) { // <-- Use originalDSN
+	driver, err := neo4jdb.NewDriverWithContext(dsn, auth, func(cfg *config.Config) {
 		cfg.MaxConnectionPoolSize = 20
 		cfg.MaxConnectionLifetime = time.Hour
 		cfg.ConnectionLivenessCheckTimeout = 10 * time.Minute
-		// --- SUGGESTED CHANGE: START ---
-		// Only set TlsConfig if we're *forcing* no-TLS.
+		// the driver encrypts the "+s" and "+ssc" schemes, and the plain schemes are left unencrypted
+		if o.Neo4jTLSConfig != nil {
+			cfg.TlsConfig = o.Neo4jTLSConfig.Clone()
+		}
 		if u.Scheme == "bolt" || u.Scheme == "neo4j" {
-			cfg.TlsConfig = tlsConfig // which is nil
+			cfg.TlsConfig = nil
 		}
-		// --- SUGGESTED CHANGE: END ---
 	})
 	if err != nil {
-		return fmt.Errorf("neoMigrate: create driver: %w", err)
+		return nil, "", fmt.Errorf("neoMigrate: create driver: %w", err)
 	}
 
 	// Set timeout for TLS Handshake and initial connect.
@@ -136,11 +522,8 @@ func neoMigrate(dsn string) error {
 	defer cancel()
 
 	if err := driver.VerifyConnectivity(ctx); err != nil {
-		// --- SUGGESTED CHANGE: Use originalDSN in error ---
-		return fmt.Errorf("neoMigrate: verify connectivity to %s: %w", originalDSN, err)
+		_ = driver.Close(context.Background())
+		return nil, "", fmt.Errorf("neoMigrate: verify connectivity to %s: %w", redact.DSN(dsn), err)
 	}
-
-	defer func() { _ = driver.Close(context.Background()) }()
-
-	return neomigrations.InitializeSchema(driver, dbname)
+	return driver, dbname, nil
 }
@@ -0,0 +1,293 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetdb
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/garthoid/asset-db/repository/neo4j"
+	"github.com/garthoid/asset-db/repository/sqlrepo"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// TLSMode selects how the connection described by a DSN is encrypted.
+type TLSMode int
+
+const (
+	// TLSDefault leaves the choice of encryption to the database driver.
+	TLSDefault TLSMode = iota
+	// TLSDisabled connects without encryption.
+	TLSDisabled
+	// TLSVerified requires encryption and verifies the server certificate against the system roots.
+	TLSVerified
+	// TLSSkipVerify requires encryption, but accepts any server certificate, such as a self-signed one.
+	TLSSkipVerify
+)
+
+// DSN builds the data source name for one of the supported databases, which can be passed to New.
+// The builder methods can be chained, and any invalid input is reported by Build.
+type DSN struct {
+	dbtype   string
+	host     string
+	user     *url.Userinfo
+	tls      TLSMode
+	database string
+	direct   bool
+	params   url.Values
+	err      error
+}
+
+// NewNeo4jDSN returns a builder for a Neo4j DSN that connects to the host, which may include the port.
+// The "neo4j" scheme is used unless WithDirectConnection is called, and the TLS mode
+// selects the "+s" and "+ssc" variants of the scheme.
+func NewNeo4jDSN(host string) *DSN {
+	return &DSN{dbtype: neo4j.Neo4j, host: host}
+}
+
+// NewPostgresDSN returns a builder for a Postgres DSN that connects to the host, which may include the port.
+// The TLS mode sets the sslmode parameter of the DSN.
+func NewPostgresDSN(host string) *DSN {
+	return &DSN{dbtype: sqlrepo.Postgres, host: host}
+}
+
+// NewMySQLDSN returns a builder for a MySQL DSN that connects to the host, which may include the port.
+// The TLS mode sets the tls parameter of the DSN.
+func NewMySQLDSN(host string) *DSN {
+	return &DSN{dbtype: sqlrepo.MySQL, host: host}
+}
+
+// NewSQLiteDSN returns a builder for a SQLite DSN that opens the database file at the path.
+// The authentication, TLS, and database settings do not apply to SQLite and are reported as errors by Build.
+func NewSQLiteDSN(path string) *DSN {
+	return &DSN{dbtype: sqlrepo.SQLite, database: path}
+}
+
+// WithAuth sets the credentials used to authenticate with the database.
+func (d *DSN) WithAuth(user, password string) *DSN {
+	if d.dbtype == sqlrepo.SQLite {
+		d.fail(errors.New("SQLite does not support authentication"))
+	} else if user == "" {
+		d.fail(errors.New("the username is empty"))
+	}
+
+	d.user = url.UserPassword(user, password)
+	return d
+}
+
+// WithTLS sets the encryption of the connection to the database.
+func (d *DSN) WithTLS(mode TLSMode) *DSN {
+	if d.dbtype == sqlrepo.SQLite {
+		d.fail(errors.New("SQLite does not support TLS"))
+	} else if mode < TLSDefault || mode > TLSSkipVerify {
+		d.fail(fmt.Errorf("unknown TLS mode: %d", mode))
+	}
+
+	d.tls = mode
+	return d
+}
+
+// WithDatabase sets the name of the database to use on the server.
+func (d *DSN) WithDatabase(name string) *DSN {
+	if d.dbtype == sqlrepo.SQLite {
+		d.fail(errors.New("SQLite does not support database names"))
+	} else if name == "" || strings.ContainsAny(name, "/?#") {
+		d.fail(fmt.Errorf("invalid database name: %q", name))
+	}
+
+	d.database = name
+	return d
+}
+
+// WithDirectConnection selects the "bolt" scheme for a Neo4j DSN, which connects directly to
+// the host rather than using the cluster routing of the "neo4j" scheme.
+func (d *DSN) WithDirectConnection() *DSN {
+	if d.dbtype != neo4j.Neo4j {
+		d.fail(errors.New("direct connections only apply to Neo4j"))
+	}
+
+	d.direct = true
+	return d
+}
+
+// WithParam adds a query parameter to the DSN, such as the connect_timeout for Postgres
+// or a _pragma for SQLite. Parameters that are set by other builder methods are rejected.
+func (d *DSN) WithParam(key, value string) *DSN {
+	if key == "" {
+		d.fail(errors.New("the parameter name is empty"))
+	} else if (d.dbtype == sqlrepo.Postgres && key == "sslmode") || (d.dbtype == sqlrepo.MySQL && key == "tls") {
+		d.fail(fmt.Errorf("the %s parameter is set with WithTLS", key))
+	}
+
+	if d.params == nil {
+		d.params = make(url.Values)
+	}
+	d.params.Add(key, value)
+	return d
+}
+
+// Build returns the DSN, or an error if the builder was provided with invalid input.
+func (d *DSN) Build() (string, error) {
+	if d.err != nil {
+		return "", d.err
+	}
+
+	switch d.dbtype {
+	case neo4j.Neo4j:
+		return d.buildNeo4j()
+	case sqlrepo.Postgres:
+		return d.buildPostgres()
+	case sqlrepo.MySQL:
+		return d.buildMySQL()
+	case sqlrepo.SQLite:
+		return d.buildSQLite()
+	}
+	return "", errors.New("unknown DB type")
+}
+
+// String returns the DSN, or an empty string if the builder was provided with invalid input.
+func (d *DSN) String() string {
+	dsn, err := d.Build()
+	if err != nil {
+		return ""
+	}
+	return dsn
+}
+
+func (d *DSN) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *DSN) buildNeo4j() (string, error) {
+	if err := validateHost(d.host); err != nil {
+		return "", err
+	}
+
+	scheme := "neo4j"
+	if d.direct {
+		scheme = "bolt"
+	}
+
+	switch d.tls {
+	case TLSVerified:
+		scheme += "+s"
+	case TLSSkipVerify:
+		scheme += "+ssc"
+	}
+
+	u := &url.URL{
+		Scheme:   scheme,
+		User:     d.user,
+		Host:     d.host,
+		RawQuery: d.params.Encode(),
+	}
+	if d.database != "" {
+		u.Path = "/" + d.database
+	}
+	return u.String(), nil
+}
+
+func (d *DSN) buildPostgres() (string, error) {
+	if err := validateHost(d.host); err != nil {
+		return "", err
+	}
+
+	params := make(url.Values)
+	for k, v := range d.params {
+		params[k] = v
+	}
+
+	switch d.tls {
+	case TLSDisabled:
+		params.Set("sslmode", "disable")
+	case TLSVerified:
+		params.Set("sslmode", "verify-full")
+	case TLSSkipVerify:
+		params.Set("sslmode", "require")
+	}
+
+	u := &url.URL{
+		Scheme:   "postgres",
+		User:     d.user,
+		Host:     d.host,
+		RawQuery: params.Encode(),
+	}
+	if d.database != "" {
+		u.Path = "/" + d.database
+	}
+	return u.String(), nil
+}
+
+func (d *DSN) buildMySQL() (string, error) {
+	if err := validateHost(d.host); err != nil {
+		return "", err
+	}
+
+	cfg := mysqldriver.NewConfig()
+	cfg.Net = "tcp"
+	cfg.Addr = d.host
+	cfg.DBName = d.database
+	if d.user != nil {
+		cfg.User = d.user.Username()
+		cfg.Passwd, _ = d.user.Password()
+	}
+
+	switch d.tls {
+	case TLSDisabled:
+		cfg.TLSConfig = "false"
+	case TLSVerified:
+		cfg.TLSConfig = "true"
+	case TLSSkipVerify:
+		cfg.TLSConfig = "skip-verify"
+	}
+
+	if len(d.params) > 0 {
+		cfg.Params = make(map[string]string, len(d.params))
+		for k := range d.params {
+			cfg.Params[k] = d.params.Get(k)
+		}
+	}
+	return cfg.FormatDSN(), nil
+}
+
+func (d *DSN) buildSQLite() (string, error) {
+	if d.database == "" {
+		return "", errors.New("the SQLite database path is empty")
+	}
+	if len(d.params) == 0 {
+		return d.database, nil
+	}
+	return "file:" + d.database + "?" + d.params.Encode(), nil
+}
+
+// validateHost rejects the hosts that would produce a malformed DSN, such as one that includes a scheme.
+func validateHost(host string) error {
+	if host == "" {
+		return errors.New("the host is empty")
+	}
+	if strings.ContainsAny(host, "/@?# \t") {
+		return fmt.Errorf("invalid host: %q", host)
+	}
+
+	// the port is optional, but must be valid when it is provided
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if h == "" {
+			return fmt.Errorf("invalid host: %q", host)
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("invalid port: %q", port)
+		}
+	} else if strings.Contains(strings.Trim(host, "[]"), ":") && !strings.HasPrefix(host, "[") {
+		// an IPv6 address must be enclosed in brackets
+		return fmt.Errorf("invalid host: %q", host)
+	}
+	return nil
+}
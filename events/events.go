@@ -0,0 +1,557 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/garthoid/asset-db/internal/transfer"
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// Events decorates a repository so that the handler is called with an event for each entity or edge that is
+// created, updated, or deleted, such as for keeping a search index in sync without polling. The handler is
+// called by the goroutine of the operation once the operation has succeeded, and the events of the operations
+// made within WithTransaction are held until the transaction is committed. The tags are not reported.
+type Events struct {
+	db      types.Repository
+	handler func(types.Event)
+	// pending collects the events of a transaction, and is nil outside of a transaction
+	pending *[]types.Event
+}
+
+// New returns a repository that reports the mutations of the provided repository to the handler.
+func New(db types.Repository, handler func(types.Event)) (*Events, error) {
+	if handler == nil {
+		return nil, errors.New("the event handler is nil")
+	}
+	return &Events{db: db, handler: handler}, nil
+}
+
+// emit calls the handler with the event, or holds the event until the transaction is committed.
+func (e *Events) emit(ev types.Event) {
+	if e.pending != nil {
+		*e.pending = append(*e.pending, ev)
+		return
+	}
+	e.handler(ev)
+}
+
+// entityType returns the asset type of the entity, or an empty string if the entity is not found.
+func (e *Events) entityType(ctx context.Context, id string) string {
+	if entity, err := e.db.FindEntityById(ctx, id); err == nil && entity.Asset != nil {
+		return string(entity.Asset.AssetType())
+	}
+	return ""
+}
+
+// entityEvent returns the event of the operation on the entity.
+func entityEvent(op types.EventOp, entity *types.Entity) types.Event {
+	ev := types.Event{Op: op, Kind: types.EntityRecordKind, ID: entity.ID}
+	if entity.Asset != nil {
+		ev.Type = string(entity.Asset.AssetType())
+	}
+	return ev
+}
+
+// edgeEvent returns the event of the operation on the edge.
+func edgeEvent(op types.EventOp, edge *types.Edge) types.Event {
+	ev := types.Event{Op: op, Kind: types.EdgeRecordKind, ID: edge.ID}
+	if edge.Relation != nil {
+		ev.Type = string(edge.Relation.RelationType())
+	}
+	return ev
+}
+
+// op returns the operation reported by the upserts and the creates.
+func op(created bool) types.EventOp {
+	if created {
+		return types.EventCreate
+	}
+	return types.EventUpdate
+}
+
+// GetDBType implements the Repository interface.
+func (e *Events) GetDBType() string {
+	return e.db.GetDBType()
+}
+
+// Ping implements the Repository interface.
+func (e *Events) Ping(ctx context.Context) error {
+	return e.db.Ping(ctx)
+}
+
+// Maintenance implements the Repository interface.
+func (e *Events) Maintenance(ctx context.Context) error {
+	return e.db.Maintenance(ctx)
+}
+
+// Stats implements the Repository interface.
+func (e *Events) Stats(ctx context.Context) (*types.DBStats, error) {
+	return e.db.Stats(ctx)
+}
+
+// existing returns the IDs of the entities that match the assets of the input before they are created, so the
+// creates that only refresh an existing entity are reported as updates. No IDs are returned if the search fails.
+func (e *Events) existing(ctx context.Context, entities []*types.Entity) map[string]bool {
+	var assets []oam.Asset
+	for _, entity := range entities {
+		if entity != nil && entity.Asset != nil {
+			assets = append(assets, entity.Asset)
+		}
+	}
+
+	ids := make(map[string]bool)
+	if len(assets) == 0 {
+		return ids
+	}
+	if found, err := e.db.FindEntitiesByContents(ctx, assets, time.Time{}); err == nil {
+		for _, entity := range found {
+			ids[entity.ID] = true
+		}
+	}
+	return ids
+}
+
+// CreateEntity implements the Repository interface.
+// The entity is created through UpsertEntity when no ID is provided, which matches an existing entity by its
+// content in the same way, so that a matched entity is reported as updated rather than created. An entity with
+// an ID is reported as updated when an entity matched its content before the call.
+func (e *Events) CreateEntity(ctx context.Context, input *types.Entity) (*types.Entity, error) {
+	if input != nil && input.ID == "" {
+		entity, result, err := e.db.UpsertEntity(ctx, input)
+		if err == nil {
+			e.emit(entityEvent(op(result == types.UpsertCreated), entity))
+		}
+		return entity, err
+	}
+
+	existing := e.existing(ctx, []*types.Entity{input})
+	entity, err := e.db.CreateEntity(ctx, input)
+	if err == nil {
+		e.emit(entityEvent(op(!existing[entity.ID]), entity))
+	}
+	return entity, err
+}
+
+// CreateAsset implements the Repository interface.
+// The entity is created through UpsertEntity, so that an entity matched by its content is reported as updated.
+func (e *Events) CreateAsset(ctx context.Context, asset oam.Asset) (*types.Entity, error) {
+	return e.CreateEntity(ctx, &types.Entity{Asset: asset})
+}
+
+// CreateEntities implements the Repository interface.
+// The entities that matched an existing entity by their content before the call are reported as updated.
+func (e *Events) CreateEntities(ctx context.Context, entities []*types.Entity) ([]*types.Entity, error) {
+	existing := e.existing(ctx, entities)
+	results, err := e.db.CreateEntities(ctx, entities)
+	if err == nil {
+		for _, entity := range results {
+			e.emit(entityEvent(op(!existing[entity.ID]), entity))
+		}
+	}
+	return results, err
+}
+
+// UpsertEntity implements the Repository interface.
+func (e *Events) UpsertEntity(ctx context.Context, input *types.Entity) (*types.Entity, types.UpsertResult, error) {
+	entity, result, err := e.db.UpsertEntity(ctx, input)
+	if err == nil {
+		e.emit(entityEvent(op(result == types.UpsertCreated), entity))
+	}
+	return entity, result, err
+}
+
+// UpdateEntity implements the Repository interface.
+func (e *Events) UpdateEntity(ctx context.Context, id string, asset oam.Asset) (*types.Entity, error) {
+	entity, err := e.db.UpdateEntity(ctx, id, asset)
+	if err == nil {
+		e.emit(entityEvent(types.EventUpdate, entity))
+	}
+	return entity, err
+}
+
+// TouchEntity implements the Repository interface.
+func (e *Events) TouchEntity(ctx context.Context, id string) error {
+	err := e.db.TouchEntity(ctx, id)
+	if err == nil {
+		e.emit(types.Event{Op: types.EventUpdate, Kind: types.EntityRecordKind, ID: id})
+	}
+	return err
+}
+
+// FindEntityById implements the Repository interface.
+func (e *Events) FindEntityById(ctx context.Context, id string) (*types.Entity, error) {
+	return e.db.FindEntityById(ctx, id)
+}
+
+// FindEntitiesByContent implements the Repository interface.
+func (e *Events) FindEntitiesByContent(ctx context.Context, asset oam.Asset, since time.Time) ([]*types.Entity, error) {
+	return e.db.FindEntitiesByContent(ctx, asset, since)
+}
+
+// FindEntitiesByContents implements the Repository interface.
+func (e *Events) FindEntitiesByContents(ctx context.Context, assets []oam.Asset, since time.Time) (map[string]*types.Entity, error) {
+	return e.db.FindEntitiesByContents(ctx, assets, since)
+}
+
+// FindEntityByHash implements the Repository interface.
+func (e *Events) FindEntityByHash(ctx context.Context, hash string) (*types.Entity, error) {
+	return e.db.FindEntityByHash(ctx, hash)
+}
+
+// EntityExists implements the Repository interface.
+func (e *Events) EntityExists(ctx context.Context, asset oam.Asset) (bool, error) {
+	return e.db.EntityExists(ctx, asset)
+}
+
+// FindEntitiesByType implements the Repository interface.
+func (e *Events) FindEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	return e.db.FindEntitiesByType(ctx, atype, since)
+}
+
+// FindEntitiesByTypes implements the Repository interface.
+func (e *Events) FindEntitiesByTypes(ctx context.Context, since time.Time, atypes ...oam.AssetType) ([]*types.Entity, error) {
+	return e.db.FindEntitiesByTypes(ctx, since, atypes...)
+}
+
+// FindEntitiesByTypeBetween implements the Repository interface.
+func (e *Events) FindEntitiesByTypeBetween(ctx context.Context, atype oam.AssetType, from, to time.Time) ([]*types.Entity, error) {
+	return e.db.FindEntitiesByTypeBetween(ctx, atype, from, to)
+}
+
+// DiffEntities implements the Repository interface.
+func (e *Events) DiffEntities(ctx context.Context, atype oam.AssetType, t1, t2 time.Time) ([]*types.Entity, []*types.Entity, error) {
+	return e.db.DiffEntities(ctx, atype, t1, t2)
+}
+
+// SearchEntities implements the Repository interface.
+func (e *Events) SearchEntities(ctx context.Context, atype oam.AssetType, query string, since time.Time) ([]*types.Entity, error) {
+	return e.db.SearchEntities(ctx, atype, query, since)
+}
+
+// FindEntitiesByTypePaged implements the Repository interface.
+func (e *Events) FindEntitiesByTypePaged(ctx context.Context, atype oam.AssetType, since time.Time, offset, limit int) ([]*types.Entity, int64, error) {
+	return e.db.FindEntitiesByTypePaged(ctx, atype, since, offset, limit)
+}
+
+// IterateEntitiesByType implements the Repository interface.
+func (e *Events) IterateEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (types.EntityIterator, error) {
+	return e.db.IterateEntitiesByType(ctx, atype, since)
+}
+
+// CountEntitiesByType implements the Repository interface.
+func (e *Events) CountEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (int64, error) {
+	return e.db.CountEntitiesByType(ctx, atype, since)
+}
+
+// CountEntitiesGrouped implements the Repository interface.
+func (e *Events) CountEntitiesGrouped(ctx context.Context, since time.Time) (map[oam.AssetType]int64, error) {
+	return e.db.CountEntitiesGrouped(ctx, since)
+}
+
+// DeleteEntity implements the Repository interface.
+// The entity is read before it is deleted, so that its asset type is reported.
+func (e *Events) DeleteEntity(ctx context.Context, id string) (int64, error) {
+	atype := e.entityType(ctx, id)
+	n, err := e.db.DeleteEntity(ctx, id)
+	if err == nil && n > 0 {
+		e.emit(types.Event{Op: types.EventDelete, Kind: types.EntityRecordKind, ID: id, Type: atype})
+	}
+	return n, err
+}
+
+// DeleteEntityCascade implements the Repository interface.
+func (e *Events) DeleteEntityCascade(ctx context.Context, id string) (int64, error) {
+	atype := e.entityType(ctx, id)
+	n, err := e.db.DeleteEntityCascade(ctx, id)
+	if err == nil && n > 0 {
+		e.emit(types.Event{Op: types.EventDelete, Kind: types.EntityRecordKind, ID: id, Type: atype})
+	}
+	return n, err
+}
+
+// DeleteEntitiesByType implements the Repository interface.
+// A single event without an ID reports the deleted entities, since the wrapped repository only returns their count.
+func (e *Events) DeleteEntitiesByType(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	n, err := e.db.DeleteEntitiesByType(ctx, atype, before)
+	if err == nil && n > 0 {
+		e.emit(types.Event{Op: types.EventDelete, Kind: types.EntityRecordKind, Type: string(atype)})
+	}
+	return n, err
+}
+
+// FindOrphanEntities implements the Repository interface.
+func (e *Events) FindOrphanEntities(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	return e.db.FindOrphanEntities(ctx, atype, since)
+}
+
+// DeleteOrphanEntities implements the Repository interface.
+// A single event without an ID reports the deleted entities, since the wrapped repository only returns their count.
+func (e *Events) DeleteOrphanEntities(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	n, err := e.db.DeleteOrphanEntities(ctx, atype, before)
+	if err == nil && n > 0 {
+		e.emit(types.Event{Op: types.EventDelete, Kind: types.EntityRecordKind, Type: string(atype)})
+	}
+	return n, err
+}
+
+// FindDeletedEntities implements the Repository interface.
+func (e *Events) FindDeletedEntities(ctx context.Context, since time.Time) ([]*types.Entity, error) {
+	return e.db.FindDeletedEntities(ctx, since)
+}
+
+// PurgeDeleted implements the Repository interface.
+func (e *Events) PurgeDeleted(ctx context.Context, before time.Time) error {
+	return e.db.PurgeDeleted(ctx, before)
+}
+
+// CreateEdge implements the Repository interface.
+func (e *Events) CreateEdge(ctx context.Context, edge *types.Edge) (*types.Edge, error) {
+	result, err := e.db.CreateEdge(ctx, edge)
+	if err == nil {
+		e.emit(edgeEvent(types.EventCreate, result))
+	}
+	return result, err
+}
+
+// UpsertEdge implements the Repository interface.
+func (e *Events) UpsertEdge(ctx context.Context, edge *types.Edge) (*types.Edge, types.UpsertResult, error) {
+	upserted, result, err := e.db.UpsertEdge(ctx, edge)
+	if err == nil {
+		e.emit(edgeEvent(op(result == types.UpsertCreated), upserted))
+	}
+	return upserted, result, err
+}
+
+// FindEdgeById implements the Repository interface.
+func (e *Events) FindEdgeById(ctx context.Context, id string) (*types.Edge, error) {
+	return e.db.FindEdgeById(ctx, id)
+}
+
+// IncomingEdges implements the Repository interface.
+func (e *Events) IncomingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	return e.db.IncomingEdges(ctx, entity, since, labels...)
+}
+
+// OutgoingEdges implements the Repository interface.
+func (e *Events) OutgoingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	return e.db.OutgoingEdges(ctx, entity, since, labels...)
+}
+
+// IncomingEdgesBetween implements the Repository interface.
+func (e *Events) IncomingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	return e.db.IncomingEdgesBetween(ctx, entity, from, to, labels...)
+}
+
+// OutgoingEdgesBetween implements the Repository interface.
+func (e *Events) OutgoingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	return e.db.OutgoingEdgesBetween(ctx, entity, from, to, labels...)
+}
+
+// IncomingEdgesForAll implements the Repository interface.
+func (e *Events) IncomingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return e.db.IncomingEdgesForAll(ctx, entities, since, labels...)
+}
+
+// OutgoingEdgesForAll implements the Repository interface.
+func (e *Events) OutgoingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return e.db.OutgoingEdgesForAll(ctx, entities, since, labels...)
+}
+
+// Neighborhood implements the Repository interface.
+func (e *Events) Neighborhood(ctx context.Context, entity *types.Entity, maxDepth int, since time.Time, labels ...string) ([]*types.Entity, []*types.Edge, error) {
+	return e.db.Neighborhood(ctx, entity, maxDepth, since, labels...)
+}
+
+// FindEdgesByLabel implements the Repository interface.
+func (e *Events) FindEdgesByLabel(ctx context.Context, label string, since time.Time) ([]*types.Edge, error) {
+	return e.db.FindEdgesByLabel(ctx, label, since)
+}
+
+// FindEdgesSince implements the Repository interface.
+func (e *Events) FindEdgesSince(ctx context.Context, since time.Time, includeTags bool) ([]*types.Edge, error) {
+	return e.db.FindEdgesSince(ctx, since, includeTags)
+}
+
+// ResolveEdgeEndpoints implements the Repository interface.
+func (e *Events) ResolveEdgeEndpoints(ctx context.Context, edges []*types.Edge) (map[string]*types.Entity, error) {
+	return e.db.ResolveEdgeEndpoints(ctx, edges)
+}
+
+// CountEdges implements the Repository interface.
+func (e *Events) CountEdges(ctx context.Context, since time.Time) (int64, error) {
+	return e.db.CountEdges(ctx, since)
+}
+
+// EntityDegree implements the Repository interface.
+func (e *Events) EntityDegree(ctx context.Context, entity *types.Entity, since time.Time) (int64, int64, error) {
+	return e.db.EntityDegree(ctx, entity, since)
+}
+
+// DeleteEdge implements the Repository interface.
+// The edge is read before it is deleted, so that its relation type is reported.
+func (e *Events) DeleteEdge(ctx context.Context, id string) (int64, error) {
+	var rtype string
+	if edge, err := e.db.FindEdgeById(ctx, id); err == nil && edge.Relation != nil {
+		rtype = string(edge.Relation.RelationType())
+	}
+
+	n, err := e.db.DeleteEdge(ctx, id)
+	if err == nil && n > 0 {
+		e.emit(types.Event{Op: types.EventDelete, Kind: types.EdgeRecordKind, ID: id, Type: rtype})
+	}
+	return n, err
+}
+
+// CreateEntityTag implements the Repository interface.
+func (e *Events) CreateEntityTag(ctx context.Context, entity *types.Entity, tag *types.EntityTag) (*types.EntityTag, error) {
+	return e.db.CreateEntityTag(ctx, entity, tag)
+}
+
+// CreateEntityTags implements the Repository interface.
+func (e *Events) CreateEntityTags(ctx context.Context, entityIDs []string, tag *types.EntityTag) ([]*types.EntityTag, error) {
+	return e.db.CreateEntityTags(ctx, entityIDs, tag)
+}
+
+// CreateEntityProperty implements the Repository interface.
+func (e *Events) CreateEntityProperty(ctx context.Context, entity *types.Entity, property oam.Property) (*types.EntityTag, error) {
+	return e.db.CreateEntityProperty(ctx, entity, property)
+}
+
+// FindEntityTagById implements the Repository interface.
+func (e *Events) FindEntityTagById(ctx context.Context, id string) (*types.EntityTag, error) {
+	return e.db.FindEntityTagById(ctx, id)
+}
+
+// FindEntityTagsByContent implements the Repository interface.
+func (e *Events) FindEntityTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EntityTag, error) {
+	return e.db.FindEntityTagsByContent(ctx, prop, since)
+}
+
+// GetEntityTags implements the Repository interface.
+func (e *Events) GetEntityTags(ctx context.Context, entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error) {
+	return e.db.GetEntityTags(ctx, entity, since, names...)
+}
+
+// GetEntityTagsBetween implements the Repository interface.
+func (e *Events) GetEntityTagsBetween(ctx context.Context, entity *types.Entity, from, to time.Time, names ...string) ([]*types.EntityTag, error) {
+	return e.db.GetEntityTagsBetween(ctx, entity, from, to, names...)
+}
+
+// GetEntityTagsMatching implements the Repository interface.
+func (e *Events) GetEntityTagsMatching(ctx context.Context, entity *types.Entity, since time.Time, name, value string) ([]*types.EntityTag, error) {
+	return e.db.GetEntityTagsMatching(ctx, entity, since, name, value)
+}
+
+// FindEntitiesByTag implements the Repository interface.
+func (e *Events) FindEntitiesByTag(ctx context.Context, name, value string, since time.Time) ([]*types.Entity, error) {
+	return e.db.FindEntitiesByTag(ctx, name, value, since)
+}
+
+// UpdateEntityTag implements the Repository interface.
+func (e *Events) UpdateEntityTag(ctx context.Context, id string, value string) (*types.EntityTag, error) {
+	return e.db.UpdateEntityTag(ctx, id, value)
+}
+
+// DeleteEntityTag implements the Repository interface.
+func (e *Events) DeleteEntityTag(ctx context.Context, id string) (int64, error) {
+	return e.db.DeleteEntityTag(ctx, id)
+}
+
+// CreateEdgeTag implements the Repository interface.
+func (e *Events) CreateEdgeTag(ctx context.Context, edge *types.Edge, tag *types.EdgeTag) (*types.EdgeTag, error) {
+	return e.db.CreateEdgeTag(ctx, edge, tag)
+}
+
+// CreateEdgeProperty implements the Repository interface.
+func (e *Events) CreateEdgeProperty(ctx context.Context, edge *types.Edge, property oam.Property) (*types.EdgeTag, error) {
+	return e.db.CreateEdgeProperty(ctx, edge, property)
+}
+
+// FindEdgeTagById implements the Repository interface.
+func (e *Events) FindEdgeTagById(ctx context.Context, id string) (*types.EdgeTag, error) {
+	return e.db.FindEdgeTagById(ctx, id)
+}
+
+// FindEdgeTagsByContent implements the Repository interface.
+func (e *Events) FindEdgeTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EdgeTag, error) {
+	return e.db.FindEdgeTagsByContent(ctx, prop, since)
+}
+
+// GetEdgeTags implements the Repository interface.
+func (e *Events) GetEdgeTags(ctx context.Context, edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error) {
+	return e.db.GetEdgeTags(ctx, edge, since, names...)
+}
+
+// GetEdgeTagsBetween implements the Repository interface.
+func (e *Events) GetEdgeTagsBetween(ctx context.Context, edge *types.Edge, from, to time.Time, names ...string) ([]*types.EdgeTag, error) {
+	return e.db.GetEdgeTagsBetween(ctx, edge, from, to, names...)
+}
+
+// UpdateEdgeTag implements the Repository interface.
+func (e *Events) UpdateEdgeTag(ctx context.Context, id string, value string) (*types.EdgeTag, error) {
+	return e.db.UpdateEdgeTag(ctx, id, value)
+}
+
+// DeleteEdgeTag implements the Repository interface.
+func (e *Events) DeleteEdgeTag(ctx context.Context, id string) (int64, error) {
+	return e.db.DeleteEdgeTag(ctx, id)
+}
+
+// PurgeExpiredTags implements the Repository interface.
+func (e *Events) PurgeExpiredTags(ctx context.Context, before time.Time) (int64, error) {
+	return e.db.PurgeExpiredTags(ctx, before)
+}
+
+// ExportJSON implements the Repository interface.
+func (e *Events) ExportJSON(ctx context.Context, w io.Writer) error {
+	return e.db.ExportJSON(ctx, w)
+}
+
+// ExportSubgraph implements the Repository interface.
+func (e *Events) ExportSubgraph(ctx context.Context, root *types.Entity, maxDepth int, w io.Writer) error {
+	return e.db.ExportSubgraph(ctx, root, maxDepth, w)
+}
+
+// ImportJSON implements the Repository interface.
+func (e *Events) ImportJSON(ctx context.Context, rd io.Reader) (types.ImportStats, error) {
+	// the records are created through the decorator, so that each of them is reported
+	return transfer.ImportJSON(ctx, e, rd)
+}
+
+// WithTransaction implements the Repository interface.
+// The events of the operations made with the scoped repository are reported once the transaction is committed,
+// and are dropped when it is rolled back. A nested transaction adds its events to those of the outer transaction.
+func (e *Events) WithTransaction(ctx context.Context, fn func(tx types.Repository) error) error {
+	if e.pending != nil {
+		return e.db.WithTransaction(ctx, func(tx types.Repository) error {
+			return fn(&Events{db: tx, handler: e.handler, pending: e.pending})
+		})
+	}
+
+	var pending []types.Event
+	if err := e.db.WithTransaction(ctx, func(tx types.Repository) error {
+		// a transaction that is retried starts over
+		pending = nil
+		return fn(&Events{db: tx, handler: e.handler, pending: &pending})
+	}); err != nil {
+		return err
+	}
+
+	for _, ev := range pending {
+		e.handler(ev)
+	}
+	return nil
+}
+
+// Close implements the Repository interface.
+func (e *Events) Close() error {
+	return e.db.Close()
+}
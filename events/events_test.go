@@ -0,0 +1,125 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/repository/memrepo"
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventsImplementsRepository(t *testing.T) {
+	var _ types.Repository = (*Events)(nil)
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := New(memrepo.New(), nil)
+	assert.Error(t, err)
+
+	var got []types.Event
+	e, err := New(memrepo.New(), func(ev types.Event) {
+		got = append(got, ev)
+	})
+	assert.NoError(t, err)
+
+	from, err := e.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	// the entity matched by its content is refreshed rather than created
+	again, err := e.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	assert.Equal(t, from.ID, again.ID)
+	_, err = e.CreateEntity(ctx, again)
+	assert.NoError(t, err)
+	batch, err := e.CreateEntities(ctx, []*types.Entity{
+		{Asset: &dns.FQDN{Name: "owasp.org"}},
+		{Asset: &dns.FQDN{Name: "docs.owasp.org"}},
+	})
+	assert.NoError(t, err)
+	to, _, err := e.UpsertEntity(ctx, &types.Entity{Asset: &dns.FQDN{Name: "www.owasp.org"}})
+	assert.NoError(t, err)
+	_, _, err = e.UpsertEntity(ctx, &types.Entity{Asset: &dns.FQDN{Name: "www.owasp.org"}})
+	assert.NoError(t, err)
+	edge, err := e.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	_, err = e.DeleteEdge(ctx, edge.ID)
+	assert.NoError(t, err)
+	_, err = e.DeleteEntity(ctx, to.ID)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []types.Event{
+		{Op: types.EventCreate, Kind: types.EntityRecordKind, ID: from.ID, Type: string(oam.FQDN)},
+		{Op: types.EventUpdate, Kind: types.EntityRecordKind, ID: from.ID, Type: string(oam.FQDN)},
+		{Op: types.EventUpdate, Kind: types.EntityRecordKind, ID: from.ID, Type: string(oam.FQDN)},
+		{Op: types.EventUpdate, Kind: types.EntityRecordKind, ID: from.ID, Type: string(oam.FQDN)},
+		{Op: types.EventCreate, Kind: types.EntityRecordKind, ID: batch[1].ID, Type: string(oam.FQDN)},
+		{Op: types.EventCreate, Kind: types.EntityRecordKind, ID: to.ID, Type: string(oam.FQDN)},
+		{Op: types.EventUpdate, Kind: types.EntityRecordKind, ID: to.ID, Type: string(oam.FQDN)},
+		{Op: types.EventCreate, Kind: types.EdgeRecordKind, ID: edge.ID, Type: string(oam.SimpleRelation)},
+		{Op: types.EventDelete, Kind: types.EdgeRecordKind, ID: edge.ID, Type: string(oam.SimpleRelation)},
+		{Op: types.EventDelete, Kind: types.EntityRecordKind, ID: to.ID, Type: string(oam.FQDN)},
+	}, got)
+
+	// the failed operations and the deletes that remove nothing are not reported
+	got = nil
+	_, err = e.CreateEntity(ctx, nil)
+	assert.Error(t, err)
+	_, err = e.DeleteEntity(ctx, to.ID)
+	assert.Error(t, err)
+	_, err = e.DeleteEntitiesByType(ctx, oam.IPAddress, time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestEventsWithTransaction(t *testing.T) {
+	ctx := context.Background()
+
+	var got []types.Event
+	e, err := New(memrepo.New(), func(ev types.Event) {
+		got = append(got, ev)
+	})
+	assert.NoError(t, err)
+
+	// the events are held until the transaction is committed
+	err = e.WithTransaction(ctx, func(tx types.Repository) error {
+		if _, err := tx.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"}); err != nil {
+			return err
+		}
+		assert.Empty(t, got)
+
+		return tx.WithTransaction(ctx, func(nested types.Repository) error {
+			_, err := nested.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+			return err
+		})
+	})
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	// the events of a rolled back transaction are dropped
+	got = nil
+	err = e.WithTransaction(ctx, func(tx types.Repository) error {
+		if _, err := tx.CreateAsset(ctx, &dns.FQDN{Name: "docs.owasp.org"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+	assert.Empty(t, got)
+	_, err = e.FindEntitiesByContent(ctx, &dns.FQDN{Name: "docs.owasp.org"}, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
@@ -0,0 +1,110 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package explain
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// methods are the read methods of the Repository interface whose queries can be explained.
+// The write methods are excluded, since the plans are analyzed by running the queries.
+var methods = map[string]struct{}{
+	"FindEntityById":            {},
+	"FindEntitiesByContent":     {},
+	"FindEntitiesByContents":    {},
+	"FindEntityByHash":          {},
+	"EntityExists":              {},
+	"FindEntitiesByType":        {},
+	"FindEntitiesByTypes":       {},
+	"FindEntitiesByTypeBetween": {},
+	"SearchEntities":            {},
+	"FindEntitiesByTypePaged":   {},
+	"CountEntitiesByType":       {},
+	"CountEntitiesGrouped":      {},
+	"FindOrphanEntities":        {},
+	"FindDeletedEntities":       {},
+	"FindEdgeById":              {},
+	"IncomingEdges":             {},
+	"OutgoingEdges":             {},
+	"IncomingEdgesBetween":      {},
+	"OutgoingEdgesBetween":      {},
+	"IncomingEdgesForAll":       {},
+	"OutgoingEdgesForAll":       {},
+	"FindEdgesByLabel":          {},
+	"FindEdgesSince":            {},
+	"ResolveEdgeEndpoints":      {},
+	"CountEdges":                {},
+	"EntityDegree":              {},
+	"FindEntityTagById":         {},
+	"FindEntityTagsByContent":   {},
+	"GetEntityTags":             {},
+	"GetEntityTagsBetween":      {},
+	"GetEntityTagsMatching":     {},
+	"FindEntitiesByTag":         {},
+	"FindEdgeTagById":           {},
+	"FindEdgeTagsByContent":     {},
+	"GetEdgeTags":               {},
+	"GetEdgeTagsBetween":        {},
+}
+
+// Call calls the read method of the repository named by method, with the context followed by the args,
+// and returns the error returned by the method. The args must match the parameters that follow the context,
+// and a nil arg is passed as the zero value of its parameter. An error is returned without calling the method
+// when it is not a read method, or the args do not match its parameters.
+func Call(ctx context.Context, repo interface{}, method string, args ...interface{}) error {
+	if _, found := methods[method]; !found {
+		return fmt.Errorf("%s is not a read method of the repository", method)
+	}
+
+	fn := reflect.ValueOf(repo).MethodByName(method)
+	if !fn.IsValid() {
+		return fmt.Errorf("the repository does not implement %s", method)
+	}
+
+	in, err := arguments(fn.Type(), method, append([]interface{}{ctx}, args...))
+	if err != nil {
+		return err
+	}
+
+	out := fn.Call(in)
+	if err, ok := out[len(out)-1].Interface().(error); ok && err != nil {
+		return err
+	}
+	return nil
+}
+
+// arguments converts the args to the values of the parameters of the method type, including those of a variadic parameter.
+func arguments(ftype reflect.Type, method string, args []interface{}) ([]reflect.Value, error) {
+	nparams := ftype.NumIn()
+	if (!ftype.IsVariadic() && len(args) != nparams) || (ftype.IsVariadic() && len(args) < nparams-1) {
+		return nil, fmt.Errorf("%s takes %d arguments after the context, but %d were provided", method, nparams-1, len(args)-1)
+	}
+
+	in := make([]reflect.Value, 0, len(args))
+	for i, arg := range args {
+		ptype := ftype.In(min(i, nparams-1))
+		if ftype.IsVariadic() && i >= nparams-1 {
+			ptype = ptype.Elem()
+		}
+
+		if arg == nil {
+			in = append(in, reflect.Zero(ptype))
+			continue
+		}
+
+		// a value of the same kind is converted, such as a string to an oam.AssetType
+		v := reflect.ValueOf(arg)
+		if !v.Type().AssignableTo(ptype) {
+			if v.Kind() != ptype.Kind() || !v.Type().ConvertibleTo(ptype) {
+				return nil, fmt.Errorf("argument %d of %s must be a %s, not a %s", i, method, ptype, v.Type())
+			}
+			v = v.Convert(ptype)
+		}
+		in = append(in, v)
+	}
+	return in, nil
+}
@@ -0,0 +1,44 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package explain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/repository/memrepo"
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCall(t *testing.T) {
+	ctx := context.Background()
+	repo := memrepo.New()
+
+	entity, err := repo.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, Call(ctx, repo, "FindEntityById", entity.ID))
+	assert.ErrorIs(t, Call(ctx, repo, "FindEntityById", "1000"), types.ErrNotFound)
+
+	// the args are converted to the parameter types, including the variadic labels
+	assert.NoError(t, Call(ctx, repo, "CountEntitiesByType", "FQDN", time.Time{}))
+	assert.NoError(t, Call(ctx, repo, "FindEntitiesByTypes", time.Time{}, oam.FQDN, oam.IPAddress))
+	assert.ErrorIs(t, Call(ctx, repo, "GetEntityTags", entity, nil), types.ErrNotFound)
+	assert.ErrorIs(t, Call(ctx, repo, "OutgoingEdges", entity, time.Time{}, "dns_record"), types.ErrNotFound)
+
+	// the write methods are not called
+	assert.Error(t, Call(ctx, repo, "DeleteEntity", entity.ID))
+	_, err = repo.FindEntityById(ctx, entity.ID)
+	assert.NoError(t, err)
+
+	assert.Error(t, Call(ctx, repo, "FindEntityById"))
+	assert.Error(t, Call(ctx, repo, "FindEntityById", entity.ID, "extra"))
+	assert.Error(t, Call(ctx, repo, "FindEntityById", 42))
+	assert.Error(t, Call(ctx, struct{}{}, "FindEntityById", entity.ID))
+}
@@ -0,0 +1,29 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package jsoncmp
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// Equal reports whether a and b hold the same JSON value, regardless of the whitespace and the order of the keys,
+// such as the content written by the JSON method of an asset and the content read back from a Postgres jsonb column.
+// Content that cannot be decoded is only equal to identical bytes.
+func Equal(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+
+	var av, bv interface{}
+	if err := json.Unmarshal(a, &av); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, &bv); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
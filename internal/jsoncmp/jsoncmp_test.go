@@ -0,0 +1,19 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package jsoncmp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEqual(t *testing.T) {
+	assert.True(t, Equal([]byte(`{"name":"owasp.org","port":443}`), []byte(`{"port": 443, "name": "owasp.org"}`)))
+	assert.False(t, Equal([]byte(`{"name":"owasp.org"}`), []byte(`{"name":"www.owasp.org"}`)))
+	assert.False(t, Equal([]byte(`{"name":"owasp.org"}`), []byte(`{"name":"owasp.org","port":443}`)))
+	assert.False(t, Equal([]byte(`{"name":`), []byte(`{"name":"owasp.org"}`)))
+	assert.True(t, Equal([]byte(`{"name":`), []byte(`{"name":`)))
+}
@@ -0,0 +1,34 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// Operation returns the name of the innermost exported method with the provided receiver,
+// such as "(*sqlRepository).", that is found on the call stack. An empty string is returned
+// when the caller was not invoked by such a method.
+func Operation(receiver string) string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	for {
+		frame, more := frames.Next()
+
+		if _, name, found := strings.Cut(frame.Function, receiver); found {
+			// closures declared within the method carry a suffix, such as ".func1"
+			name, _, _ = strings.Cut(name, ".")
+			if name != "" && unicode.IsUpper(rune(name[0])) {
+				return name
+			}
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type repo struct{}
+
+func (r *repo) FindEntity() string {
+	return func() string {
+		return r.query()
+	}()
+}
+
+func (r *repo) query() string {
+	return Operation("(*repo).")
+}
+
+func TestOperation(t *testing.T) {
+	r := &repo{}
+
+	assert.Equal(t, "FindEntity", r.FindEntity())
+	// unexported methods are not reported
+	assert.Equal(t, "", r.query())
+}
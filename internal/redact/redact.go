@@ -0,0 +1,61 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package redact
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces the passwords removed from the DSNs.
+const Placeholder = "****"
+
+// passwordParam matches the password of the Postgres keyword/value DSNs, and of the query parameters.
+var passwordParam = regexp.MustCompile(`(?i)(\bpassword\s*=\s*)('(?:[^'\\]|\\.)*'|[^\s&]+)`)
+
+// DSN returns the DSN with the password replaced by the placeholder, so that it can be included in errors
+// and logs. The password is removed from the userinfo of the URL DSNs, from the "user:password@" prefix
+// of the MySQL DSNs, and from the password parameter. The DSN is not required to be valid, so the
+// password is removed from the DSNs that fail to parse as well.
+func DSN(dsn string) string {
+	dsn = passwordParam.ReplaceAllString(dsn, "${1}"+Placeholder)
+
+	start, end := 0, len(dsn)
+	if i := strings.Index(dsn, "://"); i >= 0 {
+		// the userinfo of a URL precedes the query and the fragment
+		start = i + len("://")
+		if j := strings.IndexAny(dsn[start:], "?#"); j >= 0 {
+			end = start + j
+		}
+	} else if j := strings.LastIndex(dsn, "/"); j >= 0 {
+		// the userinfo of a MySQL DSN precedes the database name
+		end = j
+	}
+
+	at := strings.LastIndex(dsn[start:end], "@")
+	if at < 0 {
+		return dsn
+	}
+	at += start
+
+	colon := strings.Index(dsn[start:at], ":")
+	if colon < 0 {
+		return dsn
+	}
+	colon += start
+	return dsn[:colon+1] + Placeholder + dsn[at:]
+}
+
+// Error removes the password from the DSN held by a *url.Error, such as the error returned when the
+// DSN fails to parse. Other errors are returned unchanged.
+func Error(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		uerr.URL = DSN(uerr.URL)
+	}
+	return err
+}
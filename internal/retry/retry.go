@@ -0,0 +1,34 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Do calls fn until it succeeds, returns an error that is not retryable, or the attempts are exhausted.
+// The delay between attempts starts at base and doubles after each failure. A value of attempts less
+// than two results in a single call to fn. The last error returned by fn is returned to the caller,
+// including when the context is done while waiting for the next attempt.
+func Do(ctx context.Context, attempts int, base time.Duration, retryable func(error) bool, fn func() error) error {
+	delay := base
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= attempts || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		delay *= 2
+	}
+}
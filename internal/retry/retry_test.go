@@ -0,0 +1,73 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient")
+
+func retryable(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+func TestDo(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), 3, time.Millisecond, retryable, func() error {
+		calls++
+		if calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	// the attempts are exhausted
+	calls = 0
+	err = Do(context.Background(), 3, time.Millisecond, retryable, func() error {
+		calls++
+		return errTransient
+	})
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 3, calls)
+
+	// errors that are not retryable are returned immediately
+	calls = 0
+	permanent := errors.New("permanent")
+	err = Do(context.Background(), 3, time.Millisecond, retryable, func() error {
+		calls++
+		return permanent
+	})
+	assert.ErrorIs(t, err, permanent)
+	assert.Equal(t, 1, calls)
+
+	// a single attempt is made when retries are disabled
+	calls = 0
+	_ = Do(context.Background(), 0, time.Millisecond, retryable, func() error {
+		calls++
+		return errTransient
+	})
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls int
+	err := Do(ctx, 5, time.Hour, retryable, func() error {
+		calls++
+		cancel()
+		return errTransient
+	})
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,20 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package timeout
+
+import (
+	"context"
+	"time"
+)
+
+// Context returns a context derived from ctx that is canceled after d, when ctx has no deadline of its own.
+// The deadline of ctx is kept when it has one, and a value of d less than or equal to zero applies no timeout.
+// The returned cancel function must be called once the operation is complete.
+func Context(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,39 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package timeout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	// the timeout is applied when the context has no deadline
+	ctx, cancel := Context(context.Background(), time.Minute)
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+	cancel()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	// the deadline of the context is kept
+	parent, pcancel := context.WithTimeout(context.Background(), time.Hour)
+	defer pcancel()
+	ctx, cancel = Context(parent, time.Minute)
+	defer cancel()
+	deadline, ok = ctx.Deadline()
+	assert.True(t, ok)
+	pdeadline, _ := parent.Deadline()
+	assert.Equal(t, pdeadline, deadline)
+
+	// no timeout is applied by default
+	ctx, cancel = Context(context.Background(), 0)
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+}
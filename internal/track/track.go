@@ -0,0 +1,79 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package track
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// Set holds the resources of a repository that remain open, such as the iterators that hold a connection,
+// so that closing the repository can release those the caller did not close. A nil Set records nothing.
+type Set struct {
+	mu   sync.Mutex
+	open map[io.Closer]struct{}
+}
+
+// New returns an empty Set.
+func New() *Set {
+	return &Set{open: make(map[io.Closer]struct{})}
+}
+
+// Add records the resource as open.
+func (s *Set) Add(c io.Closer) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.open[c] = struct{}{}
+}
+
+// Remove forgets the resource, once it has been closed by its owner.
+func (s *Set) Remove(c io.Closer) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.open, c)
+}
+
+// Len returns the number of resources that remain open.
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.open)
+}
+
+// CloseAll closes the resources that remain open, and returns how many were closed along with the errors
+// returned by their Close methods. The resources must tolerate being closed more than once, since the owner
+// may close a resource at the same time.
+func (s *Set) CloseAll() (int, error) {
+	if s == nil {
+		return 0, nil
+	}
+
+	// the resources are closed without the lock, since their Close methods remove them from the set
+	s.mu.Lock()
+	open := s.open
+	s.open = make(map[io.Closer]struct{})
+	s.mu.Unlock()
+
+	var errs []error
+	for c := range open {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(open), errors.Join(errs...)
+}
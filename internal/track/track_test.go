@@ -0,0 +1,62 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package track
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type closer struct {
+	set    *Set
+	closed int
+	err    error
+}
+
+func (c *closer) Close() error {
+	c.closed++
+	c.set.Remove(c)
+	return c.err
+}
+
+func TestSet(t *testing.T) {
+	s := New()
+	a := &closer{set: s}
+	b := &closer{set: s, err: errors.New("close failed")}
+	c := &closer{set: s}
+	s.Add(a)
+	s.Add(b)
+	s.Add(c)
+	assert.Equal(t, 3, s.Len())
+
+	// a resource closed by its owner is not closed again
+	assert.NoError(t, c.Close())
+	assert.Equal(t, 2, s.Len())
+
+	n, err := s.CloseAll()
+	assert.Equal(t, 2, n)
+	assert.ErrorIs(t, err, b.err)
+	assert.Equal(t, 1, a.closed)
+	assert.Equal(t, 1, b.closed)
+	assert.Equal(t, 1, c.closed)
+	assert.Equal(t, 0, s.Len())
+
+	n, err = s.CloseAll()
+	assert.Equal(t, 0, n)
+	assert.NoError(t, err)
+}
+
+func TestNilSet(t *testing.T) {
+	var s *Set
+	s.Add(&closer{})
+	s.Remove(&closer{})
+	assert.Equal(t, 0, s.Len())
+
+	n, err := s.CloseAll()
+	assert.Equal(t, 0, n)
+	assert.NoError(t, err)
+}
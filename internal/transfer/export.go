@@ -0,0 +1,87 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+)
+
+// ExportSubgraph writes the root entity, and the entities and edges reached from it by Neighborhood within
+// maxDepth outgoing edges, to w as newline-delimited JSON in the format of ExportJSON, along with the tags of
+// those entities and edges. The records are written in the order of ExportJSON, so the output can be read
+// by ImportJSON. The entities and edges are held in memory while their tags are read and written.
+func ExportSubgraph(ctx context.Context, repo types.Repository, root *types.Entity, maxDepth int, w io.Writer) error {
+	if root == nil || maxDepth < 1 {
+		return errors.New("failed input validation checks")
+	}
+
+	entity, err := repo.FindEntityById(ctx, root.ID)
+	if err != nil {
+		return err
+	}
+
+	// an entity without outgoing edges is exported on its own
+	entities, edges, err := repo.Neighborhood(ctx, entity, maxDepth, time.Time{})
+	if err != nil && !errors.Is(err, types.ErrNotFound) {
+		return err
+	}
+	entities = append([]*types.Entity{entity}, entities...)
+
+	enc := json.NewEncoder(w)
+	for _, e := range entities {
+		if err := encode(ctx, enc, e, types.EntityRecord); err != nil {
+			return err
+		}
+	}
+	for _, e := range edges {
+		if err := encode(ctx, enc, e, types.EdgeRecord); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range entities {
+		tags, err := repo.GetEntityTags(ctx, e, time.Time{})
+		if err != nil && !errors.Is(err, types.ErrNotFound) {
+			return err
+		}
+		for _, t := range tags {
+			if err := encode(ctx, enc, t, types.EntityTagRecord); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, e := range edges {
+		tags, err := repo.GetEdgeTags(ctx, e, time.Time{})
+		if err != nil && !errors.Is(err, types.ErrNotFound) {
+			return err
+		}
+		for _, t := range tags {
+			if err := encode(ctx, enc, t, types.EdgeTagRecord); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encode writes the export record of v to the encoder.
+func encode[T any](ctx context.Context, enc *json.Encoder, v T, record func(T) (*types.ExportRecord, error)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r, err := record(v)
+	if err != nil {
+		return err
+	}
+	return enc.Encode(r)
+}
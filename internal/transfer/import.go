@@ -0,0 +1,258 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+)
+
+// jsonContent is satisfied by the assets, relations, and properties of the Open Asset Model.
+type jsonContent interface {
+	JSON() ([]byte, error)
+}
+
+// importer recreates the exported records in a repository, and maps the IDs of the exported
+// entities and edges to those of the records in the repository.
+type importer struct {
+	repo     types.Repository
+	entities map[string]*types.Entity
+	edges    map[string]*types.Edge
+}
+
+// ImportJSON reads the newline-delimited JSON written by ExportJSON and recreates the records in the repository.
+// The entities, edges, and tags are matched on their content, so the records that already exist are skipped
+// rather than duplicated, and the timestamps of the exported records are kept for those that are created.
+// The records must refer to entities and edges that precede them, in the order written by ExportJSON, and the
+// IDs are remapped to those of the repository. The import stops at the first record that cannot be imported,
+// and the returned stats count the records imported before it.
+func ImportJSON(ctx context.Context, repo types.Repository, r io.Reader) (types.ImportStats, error) {
+	var stats types.ImportStats
+	im := &importer{
+		repo:     repo,
+		entities: make(map[string]*types.Entity),
+		edges:    make(map[string]*types.Edge),
+	}
+
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		var rec types.ExportRecord
+		if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+			return stats, nil
+		} else if err != nil {
+			return stats, fmt.Errorf("record %d: %w", n, err)
+		}
+
+		if err := im.record(ctx, &rec, &stats); err != nil {
+			return stats, fmt.Errorf("record %d: %w", n, err)
+		}
+	}
+}
+
+// record imports the record and counts it in the stats.
+func (im *importer) record(ctx context.Context, rec *types.ExportRecord, stats *types.ImportStats) error {
+	switch rec.Kind {
+	case types.EntityRecordKind:
+		created, err := im.entity(ctx, rec)
+		if err != nil {
+			return err
+		}
+		count(created, &stats.EntitiesCreated, &stats.EntitiesSkipped)
+	case types.EdgeRecordKind:
+		created, err := im.edge(ctx, rec)
+		if err != nil {
+			return err
+		}
+		count(created, &stats.EdgesCreated, &stats.EdgesSkipped)
+	case types.EntityTagRecordKind:
+		created, err := im.entityTag(ctx, rec)
+		if err != nil {
+			return err
+		}
+		count(created, &stats.EntityTagsCreated, &stats.EntityTagsSkipped)
+	case types.EdgeTagRecordKind:
+		created, err := im.edgeTag(ctx, rec)
+		if err != nil {
+			return err
+		}
+		count(created, &stats.EdgeTagsCreated, &stats.EdgeTagsSkipped)
+	default:
+		return fmt.Errorf("unknown record kind: %s", rec.Kind)
+	}
+	return nil
+}
+
+// entity creates the entity of the record, unless an entity with the same content exists.
+// Returns true if the entity was created.
+func (im *importer) entity(ctx context.Context, rec *types.ExportRecord) (bool, error) {
+	asset, err := types.ParseAsset(rec.Type, rec.Content)
+	if err != nil {
+		return false, err
+	}
+
+	if entities, err := im.repo.FindEntitiesByContent(ctx, asset, time.Time{}); err == nil && len(entities) > 0 {
+		im.entities[rec.ID] = entities[0]
+		return false, nil
+	} else if err != nil && !errors.Is(err, types.ErrNotFound) {
+		return false, err
+	}
+
+	entity, err := im.repo.CreateEntity(ctx, &types.Entity{
+		CreatedAt: rec.CreatedAt,
+		LastSeen:  rec.LastSeen,
+		Asset:     asset,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	im.entities[rec.ID] = entity
+	return true, nil
+}
+
+// edge creates the edge of the record, unless the same relation exists between the entities.
+// Returns true if the edge was created.
+func (im *importer) edge(ctx context.Context, rec *types.ExportRecord) (bool, error) {
+	from, found := im.entities[rec.FromEntityID]
+	if !found {
+		return false, fmt.Errorf("the edge %s refers to the entity %s, which was not imported", rec.ID, rec.FromEntityID)
+	}
+	to, found := im.entities[rec.ToEntityID]
+	if !found {
+		return false, fmt.Errorf("the edge %s refers to the entity %s, which was not imported", rec.ID, rec.ToEntityID)
+	}
+
+	rel, err := types.ParseRelation(rec.Type, rec.Content)
+	if err != nil {
+		return false, err
+	}
+
+	if edges, err := im.repo.OutgoingEdges(ctx, from, time.Time{}, rel.Label()); err == nil {
+		for _, e := range edges {
+			if e.ToEntity.ID == to.ID && e.Relation.RelationType() == rel.RelationType() && sameContent(e.Relation, rel) {
+				im.edges[rec.ID] = e
+				return false, nil
+			}
+		}
+	} else if !errors.Is(err, types.ErrNotFound) {
+		return false, err
+	}
+
+	edge, err := im.repo.CreateEdge(ctx, &types.Edge{
+		CreatedAt:  rec.CreatedAt,
+		LastSeen:   rec.LastSeen,
+		Relation:   rel,
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	im.edges[rec.ID] = edge
+	return true, nil
+}
+
+// entityTag creates the entity tag of the record, unless the entity has a tag with the same property.
+// Returns true if the tag was created.
+func (im *importer) entityTag(ctx context.Context, rec *types.ExportRecord) (bool, error) {
+	entity, found := im.entities[rec.EntityID]
+	if !found {
+		return false, fmt.Errorf("the entity tag %s refers to the entity %s, which was not imported", rec.ID, rec.EntityID)
+	}
+
+	prop, err := types.ParseProperty(rec.Type, rec.Content)
+	if err != nil {
+		return false, err
+	}
+
+	if tags, err := im.repo.GetEntityTags(ctx, entity, time.Time{}, prop.Name()); err == nil {
+		for _, t := range tags {
+			if t.Property.PropertyType() == prop.PropertyType() && t.Property.Value() == prop.Value() {
+				return false, nil
+			}
+		}
+	} else if !errors.Is(err, types.ErrNotFound) {
+		return false, err
+	}
+
+	if _, err := im.repo.CreateEntityTag(ctx, entity, &types.EntityTag{
+		CreatedAt: rec.CreatedAt,
+		LastSeen:  rec.LastSeen,
+		ExpiresAt: rec.ExpiresAt,
+		Property:  prop,
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// edgeTag creates the edge tag of the record, unless the edge has a tag with the same property.
+// Returns true if the tag was created.
+func (im *importer) edgeTag(ctx context.Context, rec *types.ExportRecord) (bool, error) {
+	edge, found := im.edges[rec.EdgeID]
+	if !found {
+		return false, fmt.Errorf("the edge tag %s refers to the edge %s, which was not imported", rec.ID, rec.EdgeID)
+	}
+
+	prop, err := types.ParseProperty(rec.Type, rec.Content)
+	if err != nil {
+		return false, err
+	}
+
+	if tags, err := im.repo.GetEdgeTags(ctx, edge, time.Time{}, prop.Name()); err == nil {
+		for _, t := range tags {
+			if t.Property.PropertyType() == prop.PropertyType() && t.Property.Value() == prop.Value() {
+				return false, nil
+			}
+		}
+	} else if !errors.Is(err, types.ErrNotFound) {
+		return false, err
+	}
+
+	if _, err := im.repo.CreateEdgeTag(ctx, edge, &types.EdgeTag{
+		CreatedAt: rec.CreatedAt,
+		LastSeen:  rec.LastSeen,
+		ExpiresAt: rec.ExpiresAt,
+		Property:  prop,
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// count increments the created or the skipped counter.
+func count(created bool, createdCount, skippedCount *int) {
+	if created {
+		*createdCount++
+	} else {
+		*skippedCount++
+	}
+}
+
+// sameContent reports whether the JSON content of a and b is identical.
+func sameContent(a, b jsonContent) bool {
+	ac, err := a.JSON()
+	if err != nil {
+		return false
+	}
+
+	bc, err := b.JSON()
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ac, bc)
+}
@@ -0,0 +1,101 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// migrationLockKey identifies the Postgres advisory lock, and spells "assetdb" in ASCII.
+	migrationLockKey int64 = 0x61737365746462
+	// migrationLockName identifies the MySQL named lock.
+	migrationLockName = "assetdb_migrations"
+	// migrationLockTimeout is the age after which a SQLite lock is considered abandoned by a runner that has exited.
+	migrationLockTimeout = 15 * time.Minute
+	// migrationLockDelay is the time waited between the attempts to acquire a SQLite lock.
+	migrationLockDelay = 100 * time.Millisecond
+	// sqliteBusyTimeout is the time, in milliseconds, that a SQLite statement waits for the locks held by other runners.
+	sqliteBusyTimeout = 30000
+)
+
+// lockMigrations acquires the lock that allows a single runner to apply the migrations, and waits
+// while the lock is held by another runner. Returns the function that releases the lock.
+func lockMigrations(ctx context.Context, name string, db *sql.DB) (func() error, error) {
+	switch name {
+	case "postgres":
+		return sessionLock(ctx, db, "SELECT pg_advisory_lock($1)", "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	case "mysql":
+		return sessionLock(ctx, db, "SELECT GET_LOCK(?, -1)", "SELECT RELEASE_LOCK(?)", migrationLockName)
+	case "sqlite3":
+		return sqliteLock(ctx, db)
+	}
+	return nil, errors.New("unknown DB type")
+}
+
+// sessionLock acquires a lock that belongs to the database session, so the connection is reserved until the
+// lock is released. The server releases the lock if the connection of the runner is lost.
+func sessionLock(ctx context.Context, db *sql.DB, lock, unlock string, key interface{}) (func() error, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, lock, key); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to acquire the migration lock: %w", err)
+	}
+
+	return func() error {
+		_, err := conn.ExecContext(context.Background(), unlock, key)
+		if cerr := conn.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
+
+// sqliteLock acquires the lock by inserting the row of the lock table, since SQLite has no session locks.
+// The database handle must use a busy timeout, so that the statements wait for the other runners.
+func sqliteLock(ctx context.Context, db *sql.DB) (func() error, error) {
+	if _, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS assetdb_migration_lock "+
+		"(lock_id INTEGER PRIMARY KEY, acquired_at INTEGER NOT NULL)"); err != nil {
+		return nil, fmt.Errorf("failed to acquire the migration lock: %w", err)
+	}
+
+	for {
+		// remove the lock of a runner that exited without releasing it
+		stale := time.Now().Add(-migrationLockTimeout).Unix()
+		if _, err := db.ExecContext(ctx, "DELETE FROM assetdb_migration_lock WHERE acquired_at < ?", stale); err != nil {
+			return nil, fmt.Errorf("failed to acquire the migration lock: %w", err)
+		}
+
+		result, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO assetdb_migration_lock "+
+			"(lock_id, acquired_at) VALUES (1, ?)", time.Now().Unix())
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire the migration lock: %w", err)
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 1 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(migrationLockDelay):
+		}
+	}
+
+	return func() error {
+		_, err := db.ExecContext(context.Background(), "DELETE FROM assetdb_migration_lock WHERE lock_id = 1")
+		return err
+	}, nil
+}
@@ -0,0 +1,681 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectors holds the Prometheus collectors shared by a repository and its transaction scopes.
+type collectors struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+	inflight *prometheus.GaugeVec
+}
+
+// Metrics decorates a repository with the Prometheus instrumentation of its operations.
+// Each operation is observed with the labels "method" and "db_type".
+type Metrics struct {
+	db     types.Repository
+	dbtype string
+	c      *collectors
+}
+
+// New returns a repository that instruments the operations of the provided repository, along with
+// the collectors registered with the provided registerer. When the collectors were already registered,
+// such as by another repository, the existing collectors are reused.
+func New(db types.Repository, registerer prometheus.Registerer) (*Metrics, error) {
+	if registerer == nil {
+		return nil, errors.New("the registerer is nil")
+	}
+
+	duration, err := register(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "assetdb",
+		Name:      "operation_duration_seconds",
+		Help:      "The latency of the asset database operations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "db_type"}))
+	if err != nil {
+		return nil, err
+	}
+
+	errs, err := register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "assetdb",
+		Name:      "operation_errors_total",
+		Help:      "The number of asset database operations that returned an error.",
+	}, []string{"method", "db_type"}))
+	if err != nil {
+		return nil, err
+	}
+
+	inflight, err := register(registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "assetdb",
+		Name:      "operations_in_flight",
+		Help:      "The number of asset database operations currently in progress.",
+	}, []string{"method", "db_type"}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metrics{
+		db:     db,
+		dbtype: db.GetDBType(),
+		c: &collectors{
+			duration: duration,
+			errors:   errs,
+			inflight: inflight,
+		},
+	}, nil
+}
+
+func register[T prometheus.Collector](registerer prometheus.Registerer, c T) (T, error) {
+	if err := registerer.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+// observe marks the start of an operation and returns the function that records its outcome.
+func (m *Metrics) observe(method string) func(error) {
+	start := time.Now()
+	inflight := m.c.inflight.WithLabelValues(method, m.dbtype)
+
+	inflight.Inc()
+	return func(err error) {
+		inflight.Dec()
+		m.c.duration.WithLabelValues(method, m.dbtype).Observe(time.Since(start).Seconds())
+		if err != nil {
+			m.c.errors.WithLabelValues(method, m.dbtype).Inc()
+		}
+	}
+}
+
+// GetDBType implements the Repository interface.
+func (m *Metrics) GetDBType() string {
+	return m.dbtype
+}
+
+// Ping implements the Repository interface.
+func (m *Metrics) Ping(ctx context.Context) error {
+	done := m.observe("Ping")
+	err := m.db.Ping(ctx)
+	done(err)
+	return err
+}
+
+// Maintenance implements the Repository interface.
+func (m *Metrics) Maintenance(ctx context.Context) error {
+	done := m.observe("Maintenance")
+	err := m.db.Maintenance(ctx)
+	done(err)
+	return err
+}
+
+// Stats implements the Repository interface.
+func (m *Metrics) Stats(ctx context.Context) (*types.DBStats, error) {
+	done := m.observe("Stats")
+	stats, err := m.db.Stats(ctx)
+	done(err)
+	return stats, err
+}
+
+// CreateEntity implements the Repository interface.
+func (m *Metrics) CreateEntity(ctx context.Context, entity *types.Entity) (*types.Entity, error) {
+	done := m.observe("CreateEntity")
+	e, err := m.db.CreateEntity(ctx, entity)
+	done(err)
+	return e, err
+}
+
+// CreateAsset implements the Repository interface.
+func (m *Metrics) CreateAsset(ctx context.Context, asset oam.Asset) (*types.Entity, error) {
+	done := m.observe("CreateAsset")
+	e, err := m.db.CreateAsset(ctx, asset)
+	done(err)
+	return e, err
+}
+
+// CreateEntities implements the Repository interface.
+func (m *Metrics) CreateEntities(ctx context.Context, entities []*types.Entity) ([]*types.Entity, error) {
+	done := m.observe("CreateEntities")
+	results, err := m.db.CreateEntities(ctx, entities)
+	done(err)
+	return results, err
+}
+
+// UpsertEntity implements the Repository interface.
+func (m *Metrics) UpsertEntity(ctx context.Context, entity *types.Entity) (*types.Entity, types.UpsertResult, error) {
+	done := m.observe("UpsertEntity")
+	e, result, err := m.db.UpsertEntity(ctx, entity)
+	done(err)
+	return e, result, err
+}
+
+// UpdateEntity implements the Repository interface.
+func (m *Metrics) UpdateEntity(ctx context.Context, id string, asset oam.Asset) (*types.Entity, error) {
+	done := m.observe("UpdateEntity")
+	e, err := m.db.UpdateEntity(ctx, id, asset)
+	done(err)
+	return e, err
+}
+
+// TouchEntity implements the Repository interface.
+func (m *Metrics) TouchEntity(ctx context.Context, id string) error {
+	done := m.observe("TouchEntity")
+	err := m.db.TouchEntity(ctx, id)
+	done(err)
+	return err
+}
+
+// FindEntityById implements the Repository interface.
+func (m *Metrics) FindEntityById(ctx context.Context, id string) (*types.Entity, error) {
+	done := m.observe("FindEntityById")
+	e, err := m.db.FindEntityById(ctx, id)
+	done(err)
+	return e, err
+}
+
+// FindEntityByHash implements the Repository interface.
+func (m *Metrics) FindEntityByHash(ctx context.Context, hash string) (*types.Entity, error) {
+	done := m.observe("FindEntityByHash")
+	e, err := m.db.FindEntityByHash(ctx, hash)
+	done(err)
+	return e, err
+}
+
+// EntityExists implements the Repository interface.
+func (m *Metrics) EntityExists(ctx context.Context, asset oam.Asset) (bool, error) {
+	done := m.observe("EntityExists")
+	found, err := m.db.EntityExists(ctx, asset)
+	done(err)
+	return found, err
+}
+
+// FindEntitiesByContent implements the Repository interface.
+func (m *Metrics) FindEntitiesByContent(ctx context.Context, asset oam.Asset, since time.Time) ([]*types.Entity, error) {
+	done := m.observe("FindEntitiesByContent")
+	results, err := m.db.FindEntitiesByContent(ctx, asset, since)
+	done(err)
+	return results, err
+}
+
+// FindEntitiesByContents implements the Repository interface.
+func (m *Metrics) FindEntitiesByContents(ctx context.Context, assets []oam.Asset, since time.Time) (map[string]*types.Entity, error) {
+	done := m.observe("FindEntitiesByContents")
+	results, err := m.db.FindEntitiesByContents(ctx, assets, since)
+	done(err)
+	return results, err
+}
+
+// FindEntitiesByType implements the Repository interface.
+func (m *Metrics) FindEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	done := m.observe("FindEntitiesByType")
+	results, err := m.db.FindEntitiesByType(ctx, atype, since)
+	done(err)
+	return results, err
+}
+
+// FindEntitiesByTypes implements the Repository interface.
+func (m *Metrics) FindEntitiesByTypes(ctx context.Context, since time.Time, atypes ...oam.AssetType) ([]*types.Entity, error) {
+	done := m.observe("FindEntitiesByTypes")
+	results, err := m.db.FindEntitiesByTypes(ctx, since, atypes...)
+	done(err)
+	return results, err
+}
+
+// FindEntitiesByTypeBetween implements the Repository interface.
+func (m *Metrics) FindEntitiesByTypeBetween(ctx context.Context, atype oam.AssetType, from, to time.Time) ([]*types.Entity, error) {
+	done := m.observe("FindEntitiesByTypeBetween")
+	results, err := m.db.FindEntitiesByTypeBetween(ctx, atype, from, to)
+	done(err)
+	return results, err
+}
+
+// DiffEntities implements the Repository interface.
+func (m *Metrics) DiffEntities(ctx context.Context, atype oam.AssetType, t1, t2 time.Time) ([]*types.Entity, []*types.Entity, error) {
+	done := m.observe("DiffEntities")
+	added, removed, err := m.db.DiffEntities(ctx, atype, t1, t2)
+	done(err)
+	return added, removed, err
+}
+
+// SearchEntities implements the Repository interface.
+func (m *Metrics) SearchEntities(ctx context.Context, atype oam.AssetType, query string, since time.Time) ([]*types.Entity, error) {
+	done := m.observe("SearchEntities")
+	results, err := m.db.SearchEntities(ctx, atype, query, since)
+	done(err)
+	return results, err
+}
+
+// FindEntitiesByTypePaged implements the Repository interface.
+func (m *Metrics) FindEntitiesByTypePaged(ctx context.Context, atype oam.AssetType, since time.Time, offset, limit int) ([]*types.Entity, int64, error) {
+	done := m.observe("FindEntitiesByTypePaged")
+	results, total, err := m.db.FindEntitiesByTypePaged(ctx, atype, since, offset, limit)
+	done(err)
+	return results, total, err
+}
+
+// IterateEntitiesByType implements the Repository interface.
+// Only the creation of the iterator is observed, since the iteration is driven by the caller.
+func (m *Metrics) IterateEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (types.EntityIterator, error) {
+	done := m.observe("IterateEntitiesByType")
+	iter, err := m.db.IterateEntitiesByType(ctx, atype, since)
+	done(err)
+	return iter, err
+}
+
+// CountEntitiesByType implements the Repository interface.
+func (m *Metrics) CountEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (int64, error) {
+	done := m.observe("CountEntitiesByType")
+	count, err := m.db.CountEntitiesByType(ctx, atype, since)
+	done(err)
+	return count, err
+}
+
+// CountEntitiesGrouped implements the Repository interface.
+func (m *Metrics) CountEntitiesGrouped(ctx context.Context, since time.Time) (map[oam.AssetType]int64, error) {
+	done := m.observe("CountEntitiesGrouped")
+	counts, err := m.db.CountEntitiesGrouped(ctx, since)
+	done(err)
+	return counts, err
+}
+
+// DeleteEntity implements the Repository interface.
+func (m *Metrics) DeleteEntity(ctx context.Context, id string) (int64, error) {
+	done := m.observe("DeleteEntity")
+	n, err := m.db.DeleteEntity(ctx, id)
+	done(err)
+	return n, err
+}
+
+// DeleteEntityCascade implements the Repository interface.
+func (m *Metrics) DeleteEntityCascade(ctx context.Context, id string) (int64, error) {
+	done := m.observe("DeleteEntityCascade")
+	n, err := m.db.DeleteEntityCascade(ctx, id)
+	done(err)
+	return n, err
+}
+
+// DeleteEntitiesByType implements the Repository interface.
+func (m *Metrics) DeleteEntitiesByType(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	done := m.observe("DeleteEntitiesByType")
+	n, err := m.db.DeleteEntitiesByType(ctx, atype, before)
+	done(err)
+	return n, err
+}
+
+// FindOrphanEntities implements the Repository interface.
+func (m *Metrics) FindOrphanEntities(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	done := m.observe("FindOrphanEntities")
+	results, err := m.db.FindOrphanEntities(ctx, atype, since)
+	done(err)
+	return results, err
+}
+
+// DeleteOrphanEntities implements the Repository interface.
+func (m *Metrics) DeleteOrphanEntities(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	done := m.observe("DeleteOrphanEntities")
+	n, err := m.db.DeleteOrphanEntities(ctx, atype, before)
+	done(err)
+	return n, err
+}
+
+// FindDeletedEntities implements the Repository interface.
+func (m *Metrics) FindDeletedEntities(ctx context.Context, since time.Time) ([]*types.Entity, error) {
+	done := m.observe("FindDeletedEntities")
+	results, err := m.db.FindDeletedEntities(ctx, since)
+	done(err)
+	return results, err
+}
+
+// PurgeDeleted implements the Repository interface.
+func (m *Metrics) PurgeDeleted(ctx context.Context, before time.Time) error {
+	done := m.observe("PurgeDeleted")
+	err := m.db.PurgeDeleted(ctx, before)
+	done(err)
+	return err
+}
+
+// CreateEdge implements the Repository interface.
+func (m *Metrics) CreateEdge(ctx context.Context, edge *types.Edge) (*types.Edge, error) {
+	done := m.observe("CreateEdge")
+	e, err := m.db.CreateEdge(ctx, edge)
+	done(err)
+	return e, err
+}
+
+// UpsertEdge implements the Repository interface.
+func (m *Metrics) UpsertEdge(ctx context.Context, edge *types.Edge) (*types.Edge, types.UpsertResult, error) {
+	done := m.observe("UpsertEdge")
+	e, result, err := m.db.UpsertEdge(ctx, edge)
+	done(err)
+	return e, result, err
+}
+
+// FindEdgeById implements the Repository interface.
+func (m *Metrics) FindEdgeById(ctx context.Context, id string) (*types.Edge, error) {
+	done := m.observe("FindEdgeById")
+	e, err := m.db.FindEdgeById(ctx, id)
+	done(err)
+	return e, err
+}
+
+// IncomingEdges implements the Repository interface.
+func (m *Metrics) IncomingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	done := m.observe("IncomingEdges")
+	results, err := m.db.IncomingEdges(ctx, entity, since, labels...)
+	done(err)
+	return results, err
+}
+
+// OutgoingEdges implements the Repository interface.
+func (m *Metrics) OutgoingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	done := m.observe("OutgoingEdges")
+	results, err := m.db.OutgoingEdges(ctx, entity, since, labels...)
+	done(err)
+	return results, err
+}
+
+// IncomingEdgesBetween implements the Repository interface.
+func (m *Metrics) IncomingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	done := m.observe("IncomingEdgesBetween")
+	results, err := m.db.IncomingEdgesBetween(ctx, entity, from, to, labels...)
+	done(err)
+	return results, err
+}
+
+// OutgoingEdgesBetween implements the Repository interface.
+func (m *Metrics) OutgoingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	done := m.observe("OutgoingEdgesBetween")
+	results, err := m.db.OutgoingEdgesBetween(ctx, entity, from, to, labels...)
+	done(err)
+	return results, err
+}
+
+// IncomingEdgesForAll implements the Repository interface.
+func (m *Metrics) IncomingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	done := m.observe("IncomingEdgesForAll")
+	results, err := m.db.IncomingEdgesForAll(ctx, entities, since, labels...)
+	done(err)
+	return results, err
+}
+
+// OutgoingEdgesForAll implements the Repository interface.
+func (m *Metrics) OutgoingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	done := m.observe("OutgoingEdgesForAll")
+	results, err := m.db.OutgoingEdgesForAll(ctx, entities, since, labels...)
+	done(err)
+	return results, err
+}
+
+// Neighborhood implements the Repository interface.
+func (m *Metrics) Neighborhood(ctx context.Context, entity *types.Entity, maxDepth int, since time.Time, labels ...string) ([]*types.Entity, []*types.Edge, error) {
+	done := m.observe("Neighborhood")
+	entities, edges, err := m.db.Neighborhood(ctx, entity, maxDepth, since, labels...)
+	done(err)
+	return entities, edges, err
+}
+
+// FindEdgesByLabel implements the Repository interface.
+func (m *Metrics) FindEdgesByLabel(ctx context.Context, label string, since time.Time) ([]*types.Edge, error) {
+	done := m.observe("FindEdgesByLabel")
+	results, err := m.db.FindEdgesByLabel(ctx, label, since)
+	done(err)
+	return results, err
+}
+
+// FindEdgesSince implements the Repository interface.
+func (m *Metrics) FindEdgesSince(ctx context.Context, since time.Time, includeTags bool) ([]*types.Edge, error) {
+	done := m.observe("FindEdgesSince")
+	results, err := m.db.FindEdgesSince(ctx, since, includeTags)
+	done(err)
+	return results, err
+}
+
+// ResolveEdgeEndpoints implements the Repository interface.
+func (m *Metrics) ResolveEdgeEndpoints(ctx context.Context, edges []*types.Edge) (map[string]*types.Entity, error) {
+	done := m.observe("ResolveEdgeEndpoints")
+	results, err := m.db.ResolveEdgeEndpoints(ctx, edges)
+	done(err)
+	return results, err
+}
+
+// CountEdges implements the Repository interface.
+func (m *Metrics) CountEdges(ctx context.Context, since time.Time) (int64, error) {
+	done := m.observe("CountEdges")
+	count, err := m.db.CountEdges(ctx, since)
+	done(err)
+	return count, err
+}
+
+// EntityDegree implements the Repository interface.
+func (m *Metrics) EntityDegree(ctx context.Context, entity *types.Entity, since time.Time) (int64, int64, error) {
+	done := m.observe("EntityDegree")
+	in, out, err := m.db.EntityDegree(ctx, entity, since)
+	done(err)
+	return in, out, err
+}
+
+// DeleteEdge implements the Repository interface.
+func (m *Metrics) DeleteEdge(ctx context.Context, id string) (int64, error) {
+	done := m.observe("DeleteEdge")
+	n, err := m.db.DeleteEdge(ctx, id)
+	done(err)
+	return n, err
+}
+
+// CreateEntityTag implements the Repository interface.
+func (m *Metrics) CreateEntityTag(ctx context.Context, entity *types.Entity, tag *types.EntityTag) (*types.EntityTag, error) {
+	done := m.observe("CreateEntityTag")
+	t, err := m.db.CreateEntityTag(ctx, entity, tag)
+	done(err)
+	return t, err
+}
+
+// CreateEntityTags implements the Repository interface.
+func (m *Metrics) CreateEntityTags(ctx context.Context, entityIDs []string, tag *types.EntityTag) ([]*types.EntityTag, error) {
+	done := m.observe("CreateEntityTags")
+	tags, err := m.db.CreateEntityTags(ctx, entityIDs, tag)
+	done(err)
+	return tags, err
+}
+
+// CreateEntityProperty implements the Repository interface.
+func (m *Metrics) CreateEntityProperty(ctx context.Context, entity *types.Entity, property oam.Property) (*types.EntityTag, error) {
+	done := m.observe("CreateEntityProperty")
+	t, err := m.db.CreateEntityProperty(ctx, entity, property)
+	done(err)
+	return t, err
+}
+
+// FindEntityTagById implements the Repository interface.
+func (m *Metrics) FindEntityTagById(ctx context.Context, id string) (*types.EntityTag, error) {
+	done := m.observe("FindEntityTagById")
+	t, err := m.db.FindEntityTagById(ctx, id)
+	done(err)
+	return t, err
+}
+
+// FindEntityTagsByContent implements the Repository interface.
+func (m *Metrics) FindEntityTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EntityTag, error) {
+	done := m.observe("FindEntityTagsByContent")
+	results, err := m.db.FindEntityTagsByContent(ctx, prop, since)
+	done(err)
+	return results, err
+}
+
+// GetEntityTags implements the Repository interface.
+func (m *Metrics) GetEntityTags(ctx context.Context, entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error) {
+	done := m.observe("GetEntityTags")
+	results, err := m.db.GetEntityTags(ctx, entity, since, names...)
+	done(err)
+	return results, err
+}
+
+// GetEntityTagsBetween implements the Repository interface.
+func (m *Metrics) GetEntityTagsBetween(ctx context.Context, entity *types.Entity, from, to time.Time, names ...string) ([]*types.EntityTag, error) {
+	done := m.observe("GetEntityTagsBetween")
+	results, err := m.db.GetEntityTagsBetween(ctx, entity, from, to, names...)
+	done(err)
+	return results, err
+}
+
+// GetEntityTagsMatching implements the Repository interface.
+func (m *Metrics) GetEntityTagsMatching(ctx context.Context, entity *types.Entity, since time.Time, name, value string) ([]*types.EntityTag, error) {
+	done := m.observe("GetEntityTagsMatching")
+	results, err := m.db.GetEntityTagsMatching(ctx, entity, since, name, value)
+	done(err)
+	return results, err
+}
+
+// FindEntitiesByTag implements the Repository interface.
+func (m *Metrics) FindEntitiesByTag(ctx context.Context, name, value string, since time.Time) ([]*types.Entity, error) {
+	done := m.observe("FindEntitiesByTag")
+	entities, err := m.db.FindEntitiesByTag(ctx, name, value, since)
+	done(err)
+	return entities, err
+}
+
+// UpdateEntityTag implements the Repository interface.
+func (m *Metrics) UpdateEntityTag(ctx context.Context, id string, value string) (*types.EntityTag, error) {
+	done := m.observe("UpdateEntityTag")
+	t, err := m.db.UpdateEntityTag(ctx, id, value)
+	done(err)
+	return t, err
+}
+
+// DeleteEntityTag implements the Repository interface.
+func (m *Metrics) DeleteEntityTag(ctx context.Context, id string) (int64, error) {
+	done := m.observe("DeleteEntityTag")
+	n, err := m.db.DeleteEntityTag(ctx, id)
+	done(err)
+	return n, err
+}
+
+// CreateEdgeTag implements the Repository interface.
+func (m *Metrics) CreateEdgeTag(ctx context.Context, edge *types.Edge, tag *types.EdgeTag) (*types.EdgeTag, error) {
+	done := m.observe("CreateEdgeTag")
+	t, err := m.db.CreateEdgeTag(ctx, edge, tag)
+	done(err)
+	return t, err
+}
+
+// CreateEdgeProperty implements the Repository interface.
+func (m *Metrics) CreateEdgeProperty(ctx context.Context, edge *types.Edge, property oam.Property) (*types.EdgeTag, error) {
+	done := m.observe("CreateEdgeProperty")
+	t, err := m.db.CreateEdgeProperty(ctx, edge, property)
+	done(err)
+	return t, err
+}
+
+// FindEdgeTagById implements the Repository interface.
+func (m *Metrics) FindEdgeTagById(ctx context.Context, id string) (*types.EdgeTag, error) {
+	done := m.observe("FindEdgeTagById")
+	t, err := m.db.FindEdgeTagById(ctx, id)
+	done(err)
+	return t, err
+}
+
+// FindEdgeTagsByContent implements the Repository interface.
+func (m *Metrics) FindEdgeTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EdgeTag, error) {
+	done := m.observe("FindEdgeTagsByContent")
+	results, err := m.db.FindEdgeTagsByContent(ctx, prop, since)
+	done(err)
+	return results, err
+}
+
+// GetEdgeTags implements the Repository interface.
+func (m *Metrics) GetEdgeTags(ctx context.Context, edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error) {
+	done := m.observe("GetEdgeTags")
+	results, err := m.db.GetEdgeTags(ctx, edge, since, names...)
+	done(err)
+	return results, err
+}
+
+// GetEdgeTagsBetween implements the Repository interface.
+func (m *Metrics) GetEdgeTagsBetween(ctx context.Context, edge *types.Edge, from, to time.Time, names ...string) ([]*types.EdgeTag, error) {
+	done := m.observe("GetEdgeTagsBetween")
+	results, err := m.db.GetEdgeTagsBetween(ctx, edge, from, to, names...)
+	done(err)
+	return results, err
+}
+
+// UpdateEdgeTag implements the Repository interface.
+func (m *Metrics) UpdateEdgeTag(ctx context.Context, id string, value string) (*types.EdgeTag, error) {
+	done := m.observe("UpdateEdgeTag")
+	t, err := m.db.UpdateEdgeTag(ctx, id, value)
+	done(err)
+	return t, err
+}
+
+// DeleteEdgeTag implements the Repository interface.
+func (m *Metrics) DeleteEdgeTag(ctx context.Context, id string) (int64, error) {
+	done := m.observe("DeleteEdgeTag")
+	n, err := m.db.DeleteEdgeTag(ctx, id)
+	done(err)
+	return n, err
+}
+
+// PurgeExpiredTags implements the Repository interface.
+func (m *Metrics) PurgeExpiredTags(ctx context.Context, before time.Time) (int64, error) {
+	done := m.observe("PurgeExpiredTags")
+	n, err := m.db.PurgeExpiredTags(ctx, before)
+	done(err)
+	return n, err
+}
+
+// ExportJSON implements the Repository interface.
+func (m *Metrics) ExportJSON(ctx context.Context, w io.Writer) error {
+	done := m.observe("ExportJSON")
+	err := m.db.ExportJSON(ctx, w)
+	done(err)
+	return err
+}
+
+// ExportSubgraph implements the Repository interface.
+func (m *Metrics) ExportSubgraph(ctx context.Context, root *types.Entity, maxDepth int, w io.Writer) error {
+	done := m.observe("ExportSubgraph")
+	err := m.db.ExportSubgraph(ctx, root, maxDepth, w)
+	done(err)
+	return err
+}
+
+// ImportJSON implements the Repository interface.
+// The import is observed as a whole, since the records are created through the wrapped repository.
+func (m *Metrics) ImportJSON(ctx context.Context, r io.Reader) (types.ImportStats, error) {
+	done := m.observe("ImportJSON")
+	stats, err := m.db.ImportJSON(ctx, r)
+	done(err)
+	return stats, err
+}
+
+// WithTransaction implements the Repository interface.
+// The transaction is observed as a whole, and the operations made with the scoped repository are observed individually.
+func (m *Metrics) WithTransaction(ctx context.Context, fn func(tx types.Repository) error) error {
+	done := m.observe("WithTransaction")
+	err := m.db.WithTransaction(ctx, func(tx types.Repository) error {
+		return fn(&Metrics{db: tx, dbtype: m.dbtype, c: m.c})
+	})
+	done(err)
+	return err
+}
+
+// Close implements the Repository interface.
+func (m *Metrics) Close() error {
+	return m.db.Close()
+}
@@ -0,0 +1,97 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/garthoid/asset-db/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubRepository implements the few methods exercised by the tests.
+type stubRepository struct {
+	types.Repository
+	err error
+}
+
+func (s *stubRepository) GetDBType() string {
+	return "stub"
+}
+
+func (s *stubRepository) Ping(ctx context.Context) error {
+	return s.err
+}
+
+func (s *stubRepository) DeleteEntity(ctx context.Context, id string) (int64, error) {
+	return 0, s.err
+}
+
+func (s *stubRepository) WithTransaction(ctx context.Context, fn func(tx types.Repository) error) error {
+	return fn(s)
+}
+
+func TestMetricsImplementsRepository(t *testing.T) {
+	var _ types.Repository = (*Metrics)(nil)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&stubRepository{}, nil)
+	assert.Error(t, err)
+
+	// the collectors are shared by repositories using the same registerer
+	reg := prometheus.NewRegistry()
+	m1, err := New(&stubRepository{}, reg)
+	assert.NoError(t, err)
+	m2, err := New(&stubRepository{}, reg)
+	assert.NoError(t, err)
+	assert.Same(t, m1.c.duration, m2.c.duration)
+	assert.Equal(t, "stub", m1.GetDBType())
+
+	// a conflicting collector cannot be reused
+	reg = prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "assetdb",
+		Name:      "operation_duration_seconds",
+	}, []string{"method", "db_type"}))
+	_, err = New(&stubRepository{}, reg)
+	assert.Error(t, err)
+}
+
+func TestObserve(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	stub := &stubRepository{}
+	m, err := New(stub, reg)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	assert.NoError(t, m.Ping(ctx))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.c.duration))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.c.errors.WithLabelValues("Ping", "stub")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.c.inflight.WithLabelValues("Ping", "stub")))
+
+	stub.err = errors.New("failed")
+	assert.Error(t, m.Ping(ctx))
+	_, err = m.DeleteEntity(ctx, "1")
+	assert.Error(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.c.errors.WithLabelValues("Ping", "stub")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.c.errors.WithLabelValues("DeleteEntity", "stub")))
+
+	// the operations made within a transaction are observed individually
+	err = m.WithTransaction(ctx, func(tx types.Repository) error {
+		_, ok := tx.(*Metrics)
+		assert.True(t, ok)
+		_, err := tx.DeleteEntity(ctx, "1")
+		return err
+	})
+	assert.Error(t, err)
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.c.errors.WithLabelValues("DeleteEntity", "stub")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.c.errors.WithLabelValues("WithTransaction", "stub")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.c.duration))
+}
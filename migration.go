@@ -0,0 +1,336 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	neomigrations "github.com/garthoid/asset-db/migrations/neo4j"
+	"github.com/garthoid/asset-db/options"
+	"github.com/garthoid/asset-db/repository"
+	"github.com/garthoid/asset-db/repository/neo4j"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// MigrationRecord describes the state of a schema migration in the database.
+type MigrationRecord struct {
+	ID        string
+	AppliedAt time.Time
+}
+
+// Applied reports whether the migration has been applied to the database.
+func (r MigrationRecord) Applied() bool {
+	return !r.AppliedAt.IsZero()
+}
+
+// MigrationStatus returns the schema migrations known for the database type, in the order they are applied.
+// The AppliedAt time is zero for each migration that is still pending. Migrations that were applied to the
+// database, but are unknown to this version of the package, are included at the end of the list.
+// The options, such as options.WithTLSConfig, are used to connect to the database.
+func MigrationStatus(dbtype, dsn string, opts ...options.Option) ([]MigrationRecord, error) {
+	dbtype, err := repository.ParseType(dbtype)
+	if err != nil {
+		return nil, err
+	}
+
+	o := options.Apply(opts...)
+	if dbtype == neo4j.Neo4j {
+		return neoMigrationStatus(dsn, o)
+	}
+
+	name, database, source, err := sqlMigrations(dbtype, dsn, o)
+	if err != nil {
+		return nil, err
+	}
+	if database == nil {
+		return nil, errors.New("unknown DB type")
+	}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, m := range migrations {
+		ids = append(ids, m.Id)
+	}
+
+	sqlDb, err := openSQL(database)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = sqlDb.Close() }()
+
+	records, err := migrationSet(o).GetMigrationRecords(sqlDb, name)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]time.Time, len(records))
+	for _, r := range records {
+		applied[r.Id] = r.AppliedAt
+	}
+	return mergeMigrations(ids, applied), nil
+}
+
+func neoMigrationStatus(dsn string, o *options.Options) ([]MigrationRecord, error) {
+	driver, dbname, err := neoDriver(dsn, o)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = driver.Close(context.Background()) }()
+
+	records, err := neomigrations.MigrationRecords(driver, dbname)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]time.Time, len(records))
+	for _, r := range records {
+		applied[r.ID] = r.AppliedAt
+	}
+	return mergeMigrations(neomigrations.Migrations(), applied), nil
+}
+
+// mergeMigrations combines the known migrations with the times they were applied to the database.
+func mergeMigrations(ids []string, applied map[string]time.Time) []MigrationRecord {
+	known := make(map[string]struct{}, len(ids))
+	results := make([]MigrationRecord, 0, len(ids))
+
+	for _, id := range ids {
+		known[id] = struct{}{}
+		results = append(results, MigrationRecord{ID: id, AppliedAt: applied[id]})
+	}
+
+	var unknown []string
+	for id := range applied {
+		if _, found := known[id]; !found {
+			unknown = append(unknown, id)
+		}
+	}
+
+	sort.Strings(unknown)
+	for _, id := range unknown {
+		results = append(results, MigrationRecord{ID: id, AppliedAt: applied[id]})
+	}
+	return results
+}
+
+// PlanMigrations returns the statements of the pending schema migrations, in the order they would be
+// executed by New, without modifying the database. The SQL databases return the statements planned by
+// sql-migrate, and Neo4j returns the Cypher schema statements of the migrations that have not been recorded.
+// The options, such as options.WithTLSConfig, are used to connect to the database.
+func PlanMigrations(dbtype, dsn string, opts ...options.Option) ([]string, error) {
+	dbtype, err := repository.ParseType(dbtype)
+	if err != nil {
+		return nil, err
+	}
+
+	o := options.Apply(opts...)
+	if dbtype == neo4j.Neo4j {
+		return neoPlanMigrations(dsn, o)
+	}
+
+	name, database, source, err := sqlMigrations(dbtype, dsn, o)
+	if err != nil {
+		return nil, err
+	}
+	if database == nil {
+		return nil, errors.New("unknown DB type")
+	}
+
+	sqlDb, err := openSQL(database)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = sqlDb.Close() }()
+
+	planned, _, err := migrationSet(o).PlanMigration(sqlDb, name, source, migrate.Up, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []string
+	for _, m := range planned {
+		statements = append(statements, m.Queries...)
+	}
+	return statements, nil
+}
+
+func neoPlanMigrations(dsn string, o *options.Options) ([]string, error) {
+	driver, dbname, err := neoDriver(dsn, o)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = driver.Close(context.Background()) }()
+
+	return neomigrations.PlanSchema(driver, dbname)
+}
+
+// schemaObjectPattern matches the kind and the name of the table or index created or dropped by a statement of the
+// SQL migrations, including the indexes added and dropped by the ALTER TABLE statements of MySQL. The groups hold
+// the kind of a created table or index, the kind of an added index, the kind of a dropped table or index, and the name.
+var schemaObjectPattern = regexp.MustCompile(`(?i)\b(?:CREATE\s+(?:UNIQUE\s+)?(TABLE|INDEX)\s+(?:IF\s+NOT\s+EXISTS\s+)?|` +
+	`ADD\s+(?:UNIQUE\s+)?(INDEX)\s+|DROP\s+(TABLE|INDEX)\s+(?:IF\s+EXISTS\s+)?)(\w+)`)
+
+// foreignKeyPattern matches the column of a foreign key declared by a CREATE TABLE statement of the SQL migrations.
+var foreignKeyPattern = regexp.MustCompile(`(?i)\bFOREIGN\s+KEY\s*\(\s*(\w+)\s*\)`)
+
+// sqlCommentPattern matches the comments of the SQL migrations, which may name the tables and indexes.
+var sqlCommentPattern = regexp.MustCompile(`--[^\n]*`)
+
+// schemaObjectQueries list the tables, indexes, and foreign keys of the database, as kind and name rows, for each
+// sql-migrate dialect. A foreign key is named by its table and column, since SQLite does not name them.
+var schemaObjectQueries = map[string]string{
+	"postgres": "SELECT 'TABLE', table_name FROM information_schema.tables WHERE table_schema = current_schema() " +
+		"UNION ALL SELECT 'INDEX', indexname FROM pg_indexes WHERE schemaname = current_schema() " +
+		"UNION ALL SELECT 'FOREIGN KEY', k.table_name || '.' || k.column_name FROM information_schema.table_constraints c " +
+		"JOIN information_schema.key_column_usage k ON k.constraint_schema = c.constraint_schema AND k.constraint_name = c.constraint_name " +
+		"WHERE c.constraint_type = 'FOREIGN KEY' AND c.table_schema = current_schema()",
+	"mysql": "SELECT 'TABLE', table_name FROM information_schema.tables WHERE table_schema = DATABASE() " +
+		"UNION ALL SELECT DISTINCT 'INDEX', index_name FROM information_schema.statistics WHERE table_schema = DATABASE() " +
+		"UNION ALL SELECT 'FOREIGN KEY', CONCAT(table_name, '.', column_name) FROM information_schema.key_column_usage " +
+		"WHERE table_schema = DATABASE() AND referenced_table_name IS NOT NULL",
+	"sqlite3": "SELECT upper(type), name FROM sqlite_master WHERE type IN ('table', 'index') " +
+		"UNION ALL SELECT 'FOREIGN KEY', m.name || '.' || f.\"from\" FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) f " +
+		"WHERE m.type = 'table'",
+}
+
+// VerifySchema checks that the tables, indexes, and constraints created by the schema migrations exist in the
+// database, such as after the migrations are applied by a deploy. The SQL databases are checked for the tables,
+// indexes, and foreign keys with the information_schema, or the sqlite_master table and pragma_foreign_key_list
+// of SQLite, and Neo4j is checked with SHOW CONSTRAINTS and SHOW INDEXES. The objects dropped by a later migration
+// are not expected. Returns an error that lists each missing table, index, and constraint.
+// The options, such as options.WithTLSConfig, are used to connect to the database.
+func VerifySchema(dbtype, dsn string, opts ...options.Option) error {
+	dbtype, err := repository.ParseType(dbtype)
+	if err != nil {
+		return err
+	}
+
+	if dbtype == neo4j.Neo4j {
+		return neoVerifySchema(dsn, options.Apply(opts...))
+	}
+
+	name, database, source, err := sqlMigrations(dbtype, dsn, options.Apply(opts...))
+	if err != nil {
+		return err
+	}
+	if database == nil {
+		return errors.New("unknown DB type")
+	}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return err
+	}
+
+	sqlDb, err := openSQL(database)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sqlDb.Close() }()
+
+	rows, err := sqlDb.Query(schemaObjectQueries[name])
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	present := make(map[string]struct{})
+	for rows.Next() {
+		var kind, object string
+		if err := rows.Scan(&kind, &object); err != nil {
+			return err
+		}
+		present[kind+" "+object] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, obj := range sqlSchemaObjects(migrations) {
+		if _, found := present[obj]; !found {
+			missing = append(missing, strings.ToLower(obj))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the schema is missing %d tables, indexes, and foreign keys: %s", len(missing), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// sqlSchemaObjects returns the tables, indexes, and foreign keys left by the Up statements of the migrations, as
+// kind and name keys in the order they are created. An object dropped by a later statement is only expected when
+// it is created again, such as the indexes that the later migrations replace.
+func sqlSchemaObjects(migrations []*migrate.Migration) []string {
+	var order []string
+	expected := make(map[string]bool)
+	create := func(obj string) {
+		if _, seen := expected[obj]; !seen {
+			order = append(order, obj)
+		}
+		expected[obj] = true
+	}
+
+	for _, m := range migrations {
+		for _, stmt := range m.Up {
+			stmt = sqlCommentPattern.ReplaceAllString(stmt, "")
+
+			for _, obj := range schemaObjectPattern.FindAllStringSubmatch(stmt, -1) {
+				name := obj[4]
+				switch {
+				case obj[1] != "":
+					create(strings.ToUpper(obj[1]) + " " + name)
+					if strings.EqualFold(obj[1], "TABLE") {
+						for _, fk := range foreignKeyPattern.FindAllStringSubmatch(stmt, -1) {
+							create("FOREIGN KEY " + name + "." + fk[1])
+						}
+					}
+				case obj[2] != "":
+					create("INDEX " + name)
+				default:
+					kind := strings.ToUpper(obj[3])
+					expected[kind+" "+name] = false
+					if kind != "TABLE" {
+						continue
+					}
+					// the foreign keys are dropped along with their table
+					for key := range expected {
+						if strings.HasPrefix(key, "FOREIGN KEY "+name+".") {
+							expected[key] = false
+						}
+					}
+				}
+			}
+		}
+	}
+
+	objects := make([]string, 0, len(order))
+	for _, obj := range order {
+		if expected[obj] {
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
+
+func neoVerifySchema(dsn string, o *options.Options) error {
+	driver, dbname, err := neoDriver(dsn, o)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = driver.Close(context.Background()) }()
+
+	return neomigrations.VerifySchema(driver, dbname)
+}
@@ -0,0 +1,76 @@
+-- +migrate Up
+
+-- InnoDB creates the indexes for the foreign key columns automatically
+
+CREATE TABLE IF NOT EXISTS entities(
+    entity_id INT NOT NULL AUTO_INCREMENT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    etype VARCHAR(255),
+    content JSON,
+    PRIMARY KEY(entity_id)
+);
+
+CREATE INDEX idx_entities_updated_at ON entities (updated_at);
+CREATE INDEX idx_entities_etype ON entities (etype);
+
+CREATE TABLE IF NOT EXISTS entity_tags(
+    tag_id INT NOT NULL AUTO_INCREMENT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    ttype VARCHAR(255),
+    content JSON,
+    entity_id INT,
+    PRIMARY KEY(tag_id),
+    CONSTRAINT fk_entity_tags_entities
+        FOREIGN KEY(entity_id)
+            REFERENCES entities(entity_id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX idx_enttag_updated_at ON entity_tags (updated_at);
+
+CREATE TABLE IF NOT EXISTS edges(
+    edge_id INT NOT NULL AUTO_INCREMENT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    etype VARCHAR(255),
+    content JSON,
+    from_entity_id INT,
+    to_entity_id INT,
+    PRIMARY KEY(edge_id),
+    CONSTRAINT fk_edges_entities_from
+        FOREIGN KEY(from_entity_id)
+            REFERENCES entities(entity_id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_edges_entities_to
+        FOREIGN KEY(to_entity_id)
+            REFERENCES entities(entity_id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX idx_edge_updated_at ON edges (updated_at);
+
+CREATE TABLE IF NOT EXISTS edge_tags(
+    tag_id INT NOT NULL AUTO_INCREMENT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    ttype VARCHAR(255),
+    content JSON,
+    edge_id INT,
+    PRIMARY KEY(tag_id),
+    CONSTRAINT fk_edge_tags_edges
+        FOREIGN KEY(edge_id)
+            REFERENCES edges(edge_id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX idx_edgetag_updated_at ON edge_tags (updated_at);
+
+-- +migrate Down
+
+-- the indexes are dropped along with the tables, and MySQL does not support DROP INDEX IF EXISTS
+DROP TABLE IF EXISTS edge_tags;
+DROP TABLE IF EXISTS edges;
+DROP TABLE IF EXISTS entity_tags;
+DROP TABLE IF EXISTS entities;
@@ -0,0 +1,8 @@
+-- +migrate Up
+
+ALTER TABLE entities ADD COLUMN deleted_at DATETIME NULL;
+CREATE INDEX idx_entities_deleted_at ON entities (deleted_at);
+
+-- +migrate Down
+
+ALTER TABLE entities DROP INDEX idx_entities_deleted_at, DROP COLUMN deleted_at;
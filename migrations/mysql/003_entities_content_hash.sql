@@ -0,0 +1,8 @@
+-- +migrate Up
+
+ALTER TABLE entities ADD COLUMN content_hash VARCHAR(64) NULL;
+CREATE INDEX idx_entities_content_hash ON entities (content_hash);
+
+-- +migrate Down
+
+ALTER TABLE entities DROP INDEX idx_entities_content_hash, DROP COLUMN content_hash;
@@ -0,0 +1,12 @@
+-- +migrate Up
+
+ALTER TABLE entity_tags ADD COLUMN expires_at DATETIME NULL;
+CREATE INDEX idx_enttag_expires_at ON entity_tags (expires_at);
+
+ALTER TABLE edge_tags ADD COLUMN expires_at DATETIME NULL;
+CREATE INDEX idx_edgetag_expires_at ON edge_tags (expires_at);
+
+-- +migrate Down
+
+ALTER TABLE edge_tags DROP INDEX idx_edgetag_expires_at, DROP COLUMN expires_at;
+ALTER TABLE entity_tags DROP INDEX idx_enttag_expires_at, DROP COLUMN expires_at;
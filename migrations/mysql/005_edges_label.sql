@@ -0,0 +1,8 @@
+-- +migrate Up
+
+ALTER TABLE edges ADD COLUMN label VARCHAR(255) NULL;
+CREATE UNIQUE INDEX idx_edges_endpoints_label ON edges (from_entity_id, to_entity_id, etype, label);
+
+-- +migrate Down
+
+ALTER TABLE edges DROP INDEX idx_edges_endpoints_label, DROP COLUMN label;
@@ -0,0 +1,15 @@
+-- +migrate Up
+
+ALTER TABLE entities ADD COLUMN workspace VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE edges ADD COLUMN workspace VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE entity_tags ADD COLUMN workspace VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE edge_tags ADD COLUMN workspace VARCHAR(255) NOT NULL DEFAULT '';
+CREATE INDEX idx_entities_workspace ON entities (workspace, etype);
+
+-- +migrate Down
+
+ALTER TABLE entities DROP INDEX idx_entities_workspace;
+ALTER TABLE edge_tags DROP COLUMN workspace;
+ALTER TABLE entity_tags DROP COLUMN workspace;
+ALTER TABLE edges DROP COLUMN workspace;
+ALTER TABLE entities DROP COLUMN workspace;
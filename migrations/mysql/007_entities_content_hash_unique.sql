@@ -0,0 +1,9 @@
+-- +migrate Up
+
+-- the assetdb package sets the content hashes of the existing entities before this migration is applied
+-- the type is indexed by 001_schema_init, and the edge endpoints by the indexes that back the foreign keys
+ALTER TABLE entities DROP INDEX idx_entities_content_hash, ADD UNIQUE INDEX idx_entities_content_hash (workspace, content_hash);
+
+-- +migrate Down
+
+ALTER TABLE entities DROP INDEX idx_entities_content_hash, ADD INDEX idx_entities_content_hash (content_hash);
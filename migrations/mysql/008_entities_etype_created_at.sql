@@ -0,0 +1,8 @@
+-- +migrate Up
+
+-- the pages of entities are ordered by the creation time and the ID within the workspace and the type
+CREATE INDEX idx_entities_etype_created_at ON entities (workspace, etype, created_at, entity_id);
+
+-- +migrate Down
+
+ALTER TABLE entities DROP INDEX idx_entities_etype_created_at;
@@ -0,0 +1,7 @@
+// Integrates with migration tools
+// recognizing the standard "migrate up" and "migrate down" annotations,
+// simplifying asset database schema management and rollbacks in MySQL.
+//
+// MySQL does not support partial indexes, so the per-type unique content
+// indexes used by the Postgres and SQLite3 schemas are not provided here.
+package mysql
@@ -0,0 +1,65 @@
+//go:build integration
+
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package mysql
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	migrate "github.com/rubenv/sql-migrate"
+	mysqldb "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func ExampleMigrations() {
+	user := "mysql"
+	if u, ok := os.LookupEnv("MYSQL_USER"); ok {
+		user = u
+	}
+
+	password := "mysql"
+	if p, ok := os.LookupEnv("MYSQL_PASSWORD"); ok {
+		password = p
+	}
+
+	dbname := "assetdb"
+	if db, ok := os.LookupEnv("MYSQL_DATABASE"); ok {
+		dbname = db
+	}
+
+	log.Printf("DSN: %s", fmt.Sprintf("%s:%s@tcp(localhost:3306)/%s", user, password, dbname))
+
+	dsn := fmt.Sprintf("%s:%s@tcp(localhost:3306)/%s?parseTime=true", user, password, dbname)
+	db, err := gorm.Open(mysqldb.Open(dsn), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	sqlDb, _ := db.DB()
+
+	migrationsSource := migrate.EmbedFileSystemMigrationSource{
+		FileSystem: Migrations(),
+		Root:       "/",
+	}
+
+	_, err = migrate.Exec(sqlDb, "mysql", migrationsSource, migrate.Up)
+	if err != nil {
+		panic(err)
+	}
+
+	tables := []string{"entities", "entity_tags", "edges", "edge_tags"}
+	for _, table := range tables {
+		fmt.Println(db.Migrator().HasTable(table))
+	}
+
+	// Output:
+	// true
+	// true
+	// true
+	// true
+}
@@ -6,215 +6,554 @@ package neo4j
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
+// Record describes a schema migration that was applied to the database.
+type Record struct {
+	ID        string
+	AppliedAt time.Time
+}
+
+// schemaMigration is a schema migration that passes each of its Cypher statements to the exec function.
+type schemaMigration struct {
+	id    string
+	apply func(exec func(query string) error) error
+}
+
+// UniqueContentHash is the ID of the migration that makes the content hash unique, which is applied once the
+// content hashes of the existing nodes are set.
+const UniqueContentHash = "005_entities_content_hash_unique"
+
+// schemaMigrations lists the schema migrations in the order they are applied by InitializeSchema.
+var schemaMigrations = []schemaMigration{
+	{id: "001_schema_init", apply: schemaInit},
+	{id: "002_entities_content_indexes", apply: entitiesContentIndexes},
+	{id: "003_entities_content_hash", apply: entitiesContentHash},
+	{id: "004_tags_expires_at", apply: tagsExpiresAt},
+	{id: UniqueContentHash, apply: entitiesContentHashUnique},
+}
+
+// Migrations returns the identifiers of the schema migrations applied by InitializeSchema.
+func Migrations() []string {
+	ids := make([]string, 0, len(schemaMigrations))
+	for _, m := range schemaMigrations {
+		ids = append(ids, m.id)
+	}
+	return ids
+}
+
+// InitializeSchema applies the schema migrations that have not been applied to the database. The schema is created
+// with the core CREATE CONSTRAINT and CREATE INDEX statements, so no procedures or plugins such as APOC are required.
+// The database is created when the deployment allows it. Returns an error naming the feature when the deployment
+// does not support a statement of the schema.
 func InitializeSchema(driver neo4jdb.DriverWithContext, dbname string) error {
-	_ = executeQuery(driver, dbname, "CREATE DATABASE "+dbname+" IF NOT EXISTS")
-	_ = executeQuery(driver, dbname, "START DATABASE "+dbname+" WAIT 10 SECONDS")
+	return initializeSchema(driver, dbname, "")
+}
+
+// InitializeSchemaBefore applies the schema migrations that have not been applied and precede the migration with
+// the ID, such as UniqueContentHash, so the existing nodes can be updated before the later migrations are applied.
+func InitializeSchemaBefore(driver neo4jdb.DriverWithContext, dbname, id string) error {
+	return initializeSchema(driver, dbname, id)
+}
+
+// initializeSchema applies the schema migrations that have not been applied, up to the migration with the ID.
+// All the migrations are applied when the ID is empty.
+func initializeSchema(driver neo4jdb.DriverWithContext, dbname, until string) error {
+	createDatabase(driver, dbname)
+
+	unlock, err := lockSchema(driver, dbname)
+	if err != nil {
+		return err
+	}
+	// the lock is released even when the migrations fail
+	defer unlock()
+
+	exec := func(query string) error {
+		return schemaError(query, executeQuery(driver, dbname, query))
+	}
+
+	// the migrations are read once the lock is held, so each migration is applied by a single runner
+	records, err := MigrationRecords(driver, dbname)
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[string]struct{}, len(records))
+	for _, r := range records {
+		applied[r.ID] = struct{}{}
+	}
+
+	for _, m := range schemaMigrations {
+		if m.id == until {
+			break
+		}
+		// a later migration may drop an object created by an applied migration, so the applied migrations are not repeated
+		if _, found := applied[m.id]; found {
+			continue
+		}
+		if err := m.apply(exec); err != nil {
+			return err
+		}
+		if err := recordMigration(driver, dbname, m.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockSchema acquires the lock that allows a single runner to apply the schema migrations, and waits while
+// the lock is held by another runner. The lock is the write lock of the SchemaMigrationLock node, which is
+// held by an open transaction, so the server releases it if the connection of the runner is lost.
+// Returns the function that releases the lock.
+func lockSchema(driver neo4jdb.DriverWithContext, dbname string) (func(), error) {
+	ctx := context.Background()
+
+	// the uniqueness constraint ensures that concurrent runners merge the same node
+	query := "CREATE CONSTRAINT constraint_schema_migration_lock_id IF NOT EXISTS FOR (n:SchemaMigrationLock) REQUIRE n.id IS UNIQUE"
+	if err := schemaError(query, executeQuery(driver, dbname, query)); err != nil {
+		return nil, err
+	}
+
+	session := driver.NewSession(ctx, neo4jdb.SessionConfig{
+		AccessMode:   neo4jdb.AccessModeWrite,
+		DatabaseName: dbname,
+	})
+
+	tx, err := session.BeginTransaction(ctx)
+	if err != nil {
+		_ = session.Close(ctx)
+		return nil, err
+	}
+
+	result, err := tx.Run(ctx, "MERGE (l:SchemaMigrationLock {id: $id}) SET l.acquired_at = datetime()",
+		map[string]interface{}{"id": "assetdb"})
+	if err == nil {
+		_, err = result.Consume(ctx)
+	}
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		_ = session.Close(ctx)
+		return nil, fmt.Errorf("failed to acquire the migration lock: %w", err)
+	}
+
+	return func() {
+		_ = tx.Rollback(ctx)
+		_ = session.Close(ctx)
+	}, nil
+}
+
+// PlanSchema returns the Cypher statements of the schema migrations that have not been applied
+// to the database, in the order they would be executed by InitializeSchema. No statements are executed.
+func PlanSchema(driver neo4jdb.DriverWithContext, dbname string) ([]string, error) {
+	records, err := MigrationRecords(driver, dbname)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]struct{}, len(records))
+	for _, r := range records {
+		applied[r.ID] = struct{}{}
+	}
+
+	var statements []string
+	collect := func(query string) error {
+		statements = append(statements, query)
+		return nil
+	}
+
+	for _, m := range schemaMigrations {
+		if _, found := applied[m.id]; found {
+			continue
+		}
+		if err := m.apply(collect); err != nil {
+			return nil, err
+		}
+	}
+	return statements, nil
+}
+
+// schemaObjectPattern matches the kind and the name of the constraint or index created by a schema statement.
+var schemaObjectPattern = regexp.MustCompile(`^CREATE (CONSTRAINT|INDEX) (\w+)`)
 
-	err := executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_entities_entity_id IF NOT EXISTS FOR (n:Entity) REQUIRE n.entity_id IS UNIQUE")
+// droppedObjectPattern matches the kind and the name of the constraint or index dropped by a schema statement.
+var droppedObjectPattern = regexp.MustCompile(`^DROP (CONSTRAINT|INDEX) (\w+)`)
+
+// VerifySchema checks that the constraints and indexes created by the schema migrations exist in the database,
+// as listed by SHOW CONSTRAINTS and SHOW INDEXES. A missing uniqueness constraint would otherwise allow duplicate
+// entities without an error. Returns an error that lists each missing constraint and index.
+func VerifySchema(driver neo4jdb.DriverWithContext, dbname string) error {
+	expected, err := schemaObjects()
+	if err != nil {
+		return err
+	}
+
+	// the indexes that back the constraints are also listed by SHOW INDEXES, so the names are kept by kind
+	present := make(map[string]struct{})
+	for kind, query := range map[string]string{
+		"CONSTRAINT": "SHOW CONSTRAINTS YIELD name RETURN name",
+		"INDEX":      "SHOW INDEXES YIELD name RETURN name",
+	} {
+		result, err := neo4jdb.ExecuteQuery(context.Background(), driver,
+			query, nil, neo4jdb.EagerResultTransformer, neo4jdb.ExecuteQueryWithDatabase(dbname))
+		if err != nil {
+			return schemaError(query, err)
+		}
+
+		for _, rec := range result.Records {
+			name, _, err := neo4jdb.GetRecordValue[string](rec, "name")
+			if err != nil {
+				return err
+			}
+			present[kind+" "+name] = struct{}{}
+		}
+	}
+
+	var missing []string
+	for _, obj := range expected {
+		if _, found := present[obj[0]+" "+obj[1]]; !found {
+			missing = append(missing, strings.ToLower(obj[0])+" "+obj[1])
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the schema is missing %d constraints and indexes: %s", len(missing), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// schemaObjects returns the kind and the name of each constraint and index that exists once the schema migrations
+// are applied, leaving out those dropped by a later migration.
+func schemaObjects() ([][]string, error) {
+	var objects [][]string
+	collect := func(query string) error {
+		if m := schemaObjectPattern.FindStringSubmatch(query); m != nil {
+			objects = append(objects, m[1:])
+		} else if m := droppedObjectPattern.FindStringSubmatch(query); m != nil {
+			kept := objects[:0]
+			for _, obj := range objects {
+				if obj[0] != m[1] || obj[1] != m[2] {
+					kept = append(kept, obj)
+				}
+			}
+			objects = kept
+		}
+		return nil
+	}
+
+	for _, m := range schemaMigrations {
+		if err := m.apply(collect); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// MigrationRecords returns the schema migrations that were applied to the database, ordered by the identifier.
+// Each migration is recorded by a SchemaMigration node, which keeps the time it was first applied.
+func MigrationRecords(driver neo4jdb.DriverWithContext, dbname string) ([]*Record, error) {
+	result, err := neo4jdb.ExecuteQuery(context.Background(), driver,
+		"MATCH (m:SchemaMigration) RETURN m.id AS id, m.applied_at AS applied_at ORDER BY m.id",
+		nil, neo4jdb.EagerResultTransformer, neo4jdb.ExecuteQueryWithDatabase(dbname))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*Record
+	for _, rec := range result.Records {
+		id, _, err := neo4jdb.GetRecordValue[string](rec, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		applied, _, err := neo4jdb.GetRecordValue[time.Time](rec, "applied_at")
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, &Record{ID: id, AppliedAt: applied})
+	}
+	return records, nil
+}
+
+// recordMigration creates the SchemaMigration node for the migration, unless it already exists.
+func recordMigration(driver neo4jdb.DriverWithContext, dbname, id string) error {
+	_, err := neo4jdb.ExecuteQuery(context.Background(), driver,
+		"MERGE (m:SchemaMigration {id: $id}) ON CREATE SET m.applied_at = datetime()",
+		map[string]interface{}{"id": id}, neo4jdb.EagerResultTransformer, neo4jdb.ExecuteQueryWithDatabase(dbname))
+	return err
+}
+
+func schemaInit(exec func(query string) error) error {
+	err := exec("CREATE CONSTRAINT constraint_entities_entity_id IF NOT EXISTS FOR (n:Entity) REQUIRE n.entity_id IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX entities_range_index_etype IF NOT EXISTS FOR (n:Entity) ON (n.etype)")
+	err = exec("CREATE INDEX entities_range_index_etype IF NOT EXISTS FOR (n:Entity) ON (n.etype)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX entities_range_index_updated_at IF NOT EXISTS FOR (n:Entity) ON (n.updated_at)")
+	err = exec("CREATE INDEX entities_range_index_updated_at IF NOT EXISTS FOR (n:Entity) ON (n.updated_at)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_enttag_tag_id IF NOT EXISTS FOR (n:EntityTag) REQUIRE n.tag_id IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_enttag_tag_id IF NOT EXISTS FOR (n:EntityTag) REQUIRE n.tag_id IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX enttag_range_index_ttype IF NOT EXISTS FOR (n:EntityTag) ON (n.ttype)")
+	err = exec("CREATE INDEX enttag_range_index_ttype IF NOT EXISTS FOR (n:EntityTag) ON (n.ttype)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX enttag_range_index_updated_at IF NOT EXISTS FOR (n:EntityTag) ON (n.updated_at)")
+	err = exec("CREATE INDEX enttag_range_index_updated_at IF NOT EXISTS FOR (n:EntityTag) ON (n.updated_at)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX enttag_range_index_entity_id IF NOT EXISTS FOR (n:EntityTag) ON (n.entity_id)")
+	err = exec("CREATE INDEX enttag_range_index_entity_id IF NOT EXISTS FOR (n:EntityTag) ON (n.entity_id)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_edgetag_tag_id IF NOT EXISTS FOR (n:EdgeTag) REQUIRE n.tag_id IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_edgetag_tag_id IF NOT EXISTS FOR (n:EdgeTag) REQUIRE n.tag_id IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX edgetag_range_index_ttype IF NOT EXISTS FOR (n:EdgeTag) ON (n.ttype)")
+	err = exec("CREATE INDEX edgetag_range_index_ttype IF NOT EXISTS FOR (n:EdgeTag) ON (n.ttype)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX edgetag_range_index_updated_at IF NOT EXISTS FOR (n:EdgeTag) ON (n.updated_at)")
+	err = exec("CREATE INDEX edgetag_range_index_updated_at IF NOT EXISTS FOR (n:EdgeTag) ON (n.updated_at)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX edgetag_range_index_edge_id IF NOT EXISTS FOR (n:EdgeTag) ON (n.edge_id)")
+	err = exec("CREATE INDEX edgetag_range_index_edge_id IF NOT EXISTS FOR (n:EdgeTag) ON (n.edge_id)")
 	if err != nil {
 		return err
 	}
 
-	return entitiesContentIndexes(driver, dbname)
+	return exec("CREATE CONSTRAINT constraint_schema_migration_id IF NOT EXISTS FOR (n:SchemaMigration) REQUIRE n.id IS UNIQUE")
 }
 
-func entitiesContentIndexes(driver neo4jdb.DriverWithContext, dbname string) error {
-	err := executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_account_content_unique_id IF NOT EXISTS FOR (n:Account) REQUIRE n.unique_id IS UNIQUE")
+func entitiesContentIndexes(exec func(query string) error) error {
+	err := exec("CREATE CONSTRAINT constraint_account_content_unique_id IF NOT EXISTS FOR (n:Account) REQUIRE n.unique_id IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_autnum_content_handle IF NOT EXISTS FOR (n:AutnumRecord) REQUIRE n.handle IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_autnum_content_handle IF NOT EXISTS FOR (n:AutnumRecord) REQUIRE n.handle IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_autnum_content_number IF NOT EXISTS FOR (n:AutnumRecord) REQUIRE n.number IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_autnum_content_number IF NOT EXISTS FOR (n:AutnumRecord) REQUIRE n.number IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_autsys_content_number IF NOT EXISTS FOR (n:AutonomousSystem) REQUIRE n.number IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_autsys_content_number IF NOT EXISTS FOR (n:AutonomousSystem) REQUIRE n.number IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_contact_record_content_discovered_at IF NOT EXISTS FOR (n:ContactRecord) REQUIRE n.discovered_at IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_contact_record_content_discovered_at IF NOT EXISTS FOR (n:ContactRecord) REQUIRE n.discovered_at IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_domainrec_content_domain IF NOT EXISTS FOR (n:DomainRecord) REQUIRE n.domain IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_domainrec_content_domain IF NOT EXISTS FOR (n:DomainRecord) REQUIRE n.domain IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_file_content_url IF NOT EXISTS FOR (n:File) REQUIRE n.url IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_file_content_url IF NOT EXISTS FOR (n:File) REQUIRE n.url IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_fqdn_content_name IF NOT EXISTS FOR (n:FQDN) REQUIRE n.name IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_fqdn_content_name IF NOT EXISTS FOR (n:FQDN) REQUIRE n.name IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_ft_content_unique_id IF NOT EXISTS FOR (n:FundsTransfer) REQUIRE n.unique_id IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_ft_content_unique_id IF NOT EXISTS FOR (n:FundsTransfer) REQUIRE n.unique_id IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_identifier_content_unique_id IF NOT EXISTS FOR (n:Identifier) REQUIRE n.unique_id IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_identifier_content_unique_id IF NOT EXISTS FOR (n:Identifier) REQUIRE n.unique_id IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_ipaddr_content_address IF NOT EXISTS FOR (n:IPAddress) REQUIRE n.address IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_ipaddr_content_address IF NOT EXISTS FOR (n:IPAddress) REQUIRE n.address IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX ipnetrec_range_index_cidr IF NOT EXISTS FOR (n:IPNetRecord) ON (n.cidr)")
+	err = exec("CREATE INDEX ipnetrec_range_index_cidr IF NOT EXISTS FOR (n:IPNetRecord) ON (n.cidr)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_ipnetrec_content_handle IF NOT EXISTS FOR (n:IPNetRecord) REQUIRE n.handle IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_ipnetrec_content_handle IF NOT EXISTS FOR (n:IPNetRecord) REQUIRE n.handle IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_location_content_name IF NOT EXISTS FOR (n:Location) REQUIRE n.address IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_location_content_name IF NOT EXISTS FOR (n:Location) REQUIRE n.address IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_netblock_content_cidr IF NOT EXISTS FOR (n:Netblock) REQUIRE n.cidr IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_netblock_content_cidr IF NOT EXISTS FOR (n:Netblock) REQUIRE n.cidr IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_org_content_id IF NOT EXISTS FOR (n:Organization) REQUIRE n.unique_id IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_org_content_id IF NOT EXISTS FOR (n:Organization) REQUIRE n.unique_id IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX org_range_index_name IF NOT EXISTS FOR (n:Organization) ON (n.name)")
+	err = exec("CREATE INDEX org_range_index_name IF NOT EXISTS FOR (n:Organization) ON (n.name)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX org_range_index_legal_name IF NOT EXISTS FOR (n:Organization) ON (n.legal_name)")
+	err = exec("CREATE INDEX org_range_index_legal_name IF NOT EXISTS FOR (n:Organization) ON (n.legal_name)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_person_content_id IF NOT EXISTS FOR (n:Person) REQUIRE n.unique_id IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_person_content_id IF NOT EXISTS FOR (n:Person) REQUIRE n.unique_id IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX person_range_index_full_name IF NOT EXISTS FOR (n:Person) ON (n.full_name)")
+	err = exec("CREATE INDEX person_range_index_full_name IF NOT EXISTS FOR (n:Person) ON (n.full_name)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_phone_content_e164 IF NOT EXISTS FOR (n:Phone) REQUIRE n.e164 IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_phone_content_e164 IF NOT EXISTS FOR (n:Phone) REQUIRE n.e164 IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_phone_content_raw IF NOT EXISTS FOR (n:Phone) REQUIRE n.raw IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_phone_content_raw IF NOT EXISTS FOR (n:Phone) REQUIRE n.raw IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_product_content_id IF NOT EXISTS FOR (n:Product) REQUIRE n.unique_id IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_product_content_id IF NOT EXISTS FOR (n:Product) REQUIRE n.unique_id IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE INDEX product_range_index_name IF NOT EXISTS FOR (n:Product) ON (n.product_name)")
+	err = exec("CREATE INDEX product_range_index_name IF NOT EXISTS FOR (n:Product) ON (n.product_name)")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_productrelease_content_name IF NOT EXISTS FOR (n:ProductRelease) REQUIRE n.name IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_productrelease_content_name IF NOT EXISTS FOR (n:ProductRelease) REQUIRE n.name IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_service_content_id IF NOT EXISTS FOR (n:Service) REQUIRE n.unique_id IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_service_content_id IF NOT EXISTS FOR (n:Service) REQUIRE n.unique_id IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_tls_content_serial_number IF NOT EXISTS FOR (n:TLSCertificate) REQUIRE n.serial_number IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_tls_content_serial_number IF NOT EXISTS FOR (n:TLSCertificate) REQUIRE n.serial_number IS UNIQUE")
 	if err != nil {
 		return err
 	}
 
-	err = executeQuery(driver, dbname, "CREATE CONSTRAINT constraint_url_content_url IF NOT EXISTS FOR (n:URL) REQUIRE n.url IS UNIQUE")
+	err = exec("CREATE CONSTRAINT constraint_url_content_url IF NOT EXISTS FOR (n:URL) REQUIRE n.url IS UNIQUE")
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+func entitiesContentHash(exec func(query string) error) error {
+	return exec("CREATE INDEX entities_range_index_content_hash IF NOT EXISTS FOR (n:Entity) ON (n.content_hash)")
+}
+
+func tagsExpiresAt(exec func(query string) error) error {
+	err := exec("CREATE INDEX enttag_range_index_expires_at IF NOT EXISTS FOR (n:EntityTag) ON (n.expires_at)")
+	if err != nil {
+		return err
+	}
+	return exec("CREATE INDEX edgetag_range_index_expires_at IF NOT EXISTS FOR (n:EdgeTag) ON (n.expires_at)")
+}
+
+// entitiesContentHashUnique replaces the index of the content hash with a uniqueness constraint, which is backed by an
+// index of its own. The soft-deleted nodes are labeled DeletedEntity, so they are not held to the constraint.
+func entitiesContentHashUnique(exec func(query string) error) error {
+	err := exec("DROP INDEX entities_range_index_content_hash IF EXISTS")
+	if err != nil {
+		return err
+	}
+	return exec("CREATE CONSTRAINT constraint_entities_content_hash IF NOT EXISTS FOR (n:Entity) REQUIRE n.content_hash IS UNIQUE")
+}
+
+// createDatabase creates and starts the database, using the administration commands of the system database.
+// The commands are optional, since they are not supported by every deployment, such as Neo4j Community Edition
+// and Neo4j Aura, or may not be permitted for the user. The errors are ignored, so the database must already exist
+// on those deployments.
+func createDatabase(driver neo4jdb.DriverWithContext, dbname string) {
+	name := "`" + strings.ReplaceAll(dbname, "`", "``") + "`"
+
+	_ = executeQuery(driver, "system", "CREATE DATABASE "+name+" IF NOT EXISTS WAIT 10 SECONDS")
+	_ = executeQuery(driver, "system", "START DATABASE "+name+" WAIT 10 SECONDS")
+}
+
+// unsupportedCodes lists the status codes of the errors returned by a deployment that does not support a
+// feature used by a schema statement, such as an older server that lacks the IF NOT EXISTS clause.
+var unsupportedCodes = map[string]struct{}{
+	"Neo.ClientError.Procedure.ProcedureNotFound":                {},
+	"Neo.ClientError.Statement.SyntaxError":                      {},
+	"Neo.ClientError.Statement.UnsupportedAdministrationCommand": {},
+	"Neo.ClientError.Database.DatabaseNotFound":                  {},
+}
+
+// schemaError returns an error that names the feature used by the schema statement, when the statement failed
+// because the deployment does not support the feature. The original error remains available to errors.As.
+func schemaError(query string, err error) error {
+	var neoErr *neo4jdb.Neo4jError
+	if err == nil || !errors.As(err, &neoErr) {
+		return err
+	}
+	if _, found := unsupportedCodes[neoErr.Code]; !found {
+		return err
+	}
+
+	if neoErr.Code == "Neo.ClientError.Database.DatabaseNotFound" {
+		return fmt.Errorf("the database does not exist, and the deployment did not allow it to be created: %w", err)
+	}
+	if m := schemaObjectPattern.FindStringSubmatch(query); m != nil {
+		return fmt.Errorf("the Neo4j deployment does not support CREATE %s IF NOT EXISTS, required by the %s %s: %w",
+			m[1], strings.ToLower(m[1]), m[2], err)
+	}
+	return fmt.Errorf("the Neo4j deployment does not support %q: %w", query, err)
+}
+
 func executeQuery(driver neo4jdb.DriverWithContext, dbname, query string) error {
 	_, err := neo4jdb.ExecuteQuery(context.Background(), driver,
 		query, nil, neo4jdb.EagerResultTransformer, neo4jdb.ExecuteQueryWithDatabase(dbname))
@@ -0,0 +1,78 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// coreStatementPattern matches the schema statements supported by every deployment, without procedures or plugins.
+var coreStatementPattern = regexp.MustCompile(`^(CREATE (CONSTRAINT|INDEX) \w+ IF NOT EXISTS FOR \(n:\w+\) |DROP (CONSTRAINT|INDEX) \w+ IF EXISTS$)`)
+
+func TestSchemaUsesCoreStatements(t *testing.T) {
+	for _, m := range schemaMigrations {
+		if err := m.apply(func(query string) error {
+			if !coreStatementPattern.MatchString(query) || strings.Contains(strings.ToUpper(query), "CALL ") {
+				t.Errorf("The migration %s executes a statement that is not core Cypher: %s", m.id, query)
+			}
+			return nil
+		}); err != nil {
+			t.Errorf("Failed to collect the statements of the migration %s: %v", m.id, err)
+		}
+	}
+}
+
+func TestSchemaObjects(t *testing.T) {
+	objects, err := schemaObjects()
+	if err != nil {
+		t.Fatalf("Failed to collect the schema objects: %v", err)
+	}
+
+	found := make(map[string]struct{}, len(objects))
+	for _, obj := range objects {
+		found[obj[0]+" "+obj[1]] = struct{}{}
+	}
+	if _, ok := found["CONSTRAINT constraint_entities_content_hash"]; !ok {
+		t.Error("Expected the uniqueness constraint of the content hash")
+	}
+	// the index is dropped by a later migration
+	if _, ok := found["INDEX entities_range_index_content_hash"]; ok {
+		t.Error("Expected the dropped index of the content hash to be left out")
+	}
+}
+
+func TestSchemaError(t *testing.T) {
+	query := "CREATE CONSTRAINT constraint_fqdn_content_name IF NOT EXISTS FOR (n:FQDN) REQUIRE n.name IS UNIQUE"
+
+	unsupported := &neo4jdb.Neo4jError{Code: "Neo.ClientError.Statement.SyntaxError", Msg: "Invalid input 'IF'"}
+	err := schemaError(query, unsupported)
+	if err == nil || !strings.Contains(err.Error(), "CREATE CONSTRAINT IF NOT EXISTS") ||
+		!strings.Contains(err.Error(), "constraint constraint_fqdn_content_name") {
+		t.Errorf("Expected the error to name the unsupported feature, got %v", err)
+	}
+	var neoErr *neo4jdb.Neo4jError
+	if !errors.As(err, &neoErr) || neoErr != unsupported {
+		t.Error("Expected the original error to remain available")
+	}
+
+	missing := &neo4jdb.Neo4jError{Code: "Neo.ClientError.Database.DatabaseNotFound", Msg: "Database does not exist"}
+	if err := schemaError(query, missing); err == nil || !strings.Contains(err.Error(), "database does not exist") {
+		t.Errorf("Expected the error to report the missing database, got %v", err)
+	}
+
+	// the other errors are returned unchanged
+	other := &neo4jdb.Neo4jError{Code: "Neo.ClientError.Security.Unauthorized", Msg: "unauthorized"}
+	if err := schemaError(query, other); err != other {
+		t.Errorf("Expected the error to be returned unchanged, got %v", err)
+	}
+	if err := schemaError(query, nil); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+-- +migrate Up
+
+ALTER TABLE entity_tags ADD COLUMN expires_at TIMESTAMP without time zone;
+CREATE INDEX idx_enttag_expires_at ON entity_tags (expires_at);
+
+ALTER TABLE edge_tags ADD COLUMN expires_at TIMESTAMP without time zone;
+CREATE INDEX idx_edgetag_expires_at ON edge_tags (expires_at);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_edgetag_expires_at;
+ALTER TABLE edge_tags DROP COLUMN IF EXISTS expires_at;
+
+DROP INDEX IF EXISTS idx_enttag_expires_at;
+ALTER TABLE entity_tags DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,9 @@
+-- +migrate Up
+
+ALTER TABLE edges ADD COLUMN label VARCHAR(255);
+CREATE UNIQUE INDEX idx_edges_endpoints_label ON edges (from_entity_id, to_entity_id, etype, label);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_edges_endpoints_label;
+ALTER TABLE edges DROP COLUMN IF EXISTS label;
@@ -0,0 +1,106 @@
+-- +migrate Up
+
+ALTER TABLE entities ADD COLUMN workspace VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE edges ADD COLUMN workspace VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE entity_tags ADD COLUMN workspace VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE edge_tags ADD COLUMN workspace VARCHAR(255) NOT NULL DEFAULT '';
+CREATE INDEX idx_entities_workspace ON entities (workspace, etype);
+
+-- the assets are unique within each workspace
+DROP INDEX IF EXISTS idx_account_content_unique_id;
+CREATE UNIQUE INDEX idx_account_content_unique_id ON entities( workspace, (content->>'unique_id') ) WHERE etype = 'Account';
+DROP INDEX IF EXISTS idx_autnum_content_handle;
+CREATE UNIQUE INDEX idx_autnum_content_handle ON entities( workspace, (content->>'handle') ) WHERE etype = 'AutnumRecord';
+DROP INDEX IF EXISTS idx_autsys_content_number;
+CREATE UNIQUE INDEX idx_autsys_content_number ON entities( workspace, (content->>'number') ) WHERE etype = 'AutonomousSystem';
+DROP INDEX IF EXISTS idx_contact_record_content_discovered_at;
+CREATE UNIQUE INDEX idx_contact_record_content_discovered_at ON entities( workspace, (content->>'discovered_at') ) WHERE etype = 'ContactRecord';
+DROP INDEX IF EXISTS idx_domainrec_content_domain;
+CREATE UNIQUE INDEX idx_domainrec_content_domain ON entities( workspace, (content->>'domain') ) WHERE etype = 'DomainRecord';
+DROP INDEX IF EXISTS idx_file_content_url;
+CREATE UNIQUE INDEX idx_file_content_url ON entities( workspace, (content->>'url') ) WHERE etype = 'File';
+DROP INDEX IF EXISTS idx_fqdn_content_name;
+CREATE UNIQUE INDEX idx_fqdn_content_name ON entities( workspace, (content->>'name') ) WHERE etype = 'FQDN';
+DROP INDEX IF EXISTS idx_funds_transfer_content_unique_id;
+CREATE UNIQUE INDEX idx_funds_transfer_content_unique_id ON entities( workspace, (content->>'unique_id') ) WHERE etype = 'FundsTransfer';
+DROP INDEX IF EXISTS idx_identifier_content_unique_id;
+CREATE UNIQUE INDEX idx_identifier_content_unique_id ON entities( workspace, (content->>'unique_id') ) WHERE etype = 'Identifier';
+DROP INDEX IF EXISTS idx_ipaddr_content_address;
+CREATE UNIQUE INDEX idx_ipaddr_content_address ON entities( workspace, (content->>'address') ) WHERE etype = 'IPAddress';
+DROP INDEX IF EXISTS idx_ipnetrec_content_handle;
+CREATE UNIQUE INDEX idx_ipnetrec_content_handle ON entities( workspace, (content->>'handle') ) WHERE etype = 'IPNetRecord';
+DROP INDEX IF EXISTS idx_location_content_address;
+CREATE UNIQUE INDEX idx_location_content_address ON entities( workspace, (content->>'address') ) WHERE etype = 'Location';
+DROP INDEX IF EXISTS idx_netblock_content_cidr;
+CREATE UNIQUE INDEX idx_netblock_content_cidr ON entities( workspace, (content->>'cidr') ) WHERE etype = 'Netblock';
+DROP INDEX IF EXISTS idx_org_content_unique_id;
+CREATE UNIQUE INDEX idx_org_content_unique_id ON entities( workspace, (content->>'unique_id') ) WHERE etype = 'Organization';
+DROP INDEX IF EXISTS idx_person_content_unique_id;
+CREATE UNIQUE INDEX idx_person_content_unique_id ON entities( workspace, (content->>'unique_id') ) WHERE etype = 'Person';
+DROP INDEX IF EXISTS idx_phone_content_e164;
+CREATE UNIQUE INDEX idx_phone_content_e164 ON entities( workspace, (content->>'e164') ) WHERE etype = 'Phone';
+DROP INDEX IF EXISTS idx_phone_content_raw;
+CREATE UNIQUE INDEX idx_phone_content_raw ON entities( workspace, (content->>'raw') ) WHERE etype = 'Phone';
+DROP INDEX IF EXISTS idx_product_content_unique_id;
+CREATE UNIQUE INDEX idx_product_content_unique_id ON entities( workspace, (content->>'unique_id') ) WHERE etype = 'Product';
+DROP INDEX IF EXISTS idx_product_release_content_name;
+CREATE UNIQUE INDEX idx_product_release_content_name ON entities( workspace, (content->>'name') ) WHERE etype = 'ProductRelease';
+DROP INDEX IF EXISTS idx_service_content_unique_id;
+CREATE UNIQUE INDEX idx_service_content_unique_id ON entities( workspace, (content->>'unique_id') ) WHERE etype = 'Service';
+DROP INDEX IF EXISTS idx_tls_content_serial_number;
+CREATE UNIQUE INDEX idx_tls_content_serial_number ON entities( workspace, (content->>'serial_number') ) WHERE etype = 'TLSCertificate';
+DROP INDEX IF EXISTS idx_url_content_url;
+CREATE UNIQUE INDEX idx_url_content_url ON entities( workspace, (content->>'url') ) WHERE etype = 'URL';
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_account_content_unique_id;
+CREATE UNIQUE INDEX idx_account_content_unique_id ON entities( (content->>'unique_id') ) WHERE etype = 'Account';
+DROP INDEX IF EXISTS idx_autnum_content_handle;
+CREATE UNIQUE INDEX idx_autnum_content_handle ON entities( (content->>'handle') ) WHERE etype = 'AutnumRecord';
+DROP INDEX IF EXISTS idx_autsys_content_number;
+CREATE UNIQUE INDEX idx_autsys_content_number ON entities( (content->>'number') ) WHERE etype = 'AutonomousSystem';
+DROP INDEX IF EXISTS idx_contact_record_content_discovered_at;
+CREATE UNIQUE INDEX idx_contact_record_content_discovered_at ON entities( (content->>'discovered_at') ) WHERE etype = 'ContactRecord';
+DROP INDEX IF EXISTS idx_domainrec_content_domain;
+CREATE UNIQUE INDEX idx_domainrec_content_domain ON entities( (content->>'domain') ) WHERE etype = 'DomainRecord';
+DROP INDEX IF EXISTS idx_file_content_url;
+CREATE UNIQUE INDEX idx_file_content_url ON entities( (content->>'url') ) WHERE etype = 'File';
+DROP INDEX IF EXISTS idx_fqdn_content_name;
+CREATE UNIQUE INDEX idx_fqdn_content_name ON entities( (content->>'name') ) WHERE etype = 'FQDN';
+DROP INDEX IF EXISTS idx_funds_transfer_content_unique_id;
+CREATE UNIQUE INDEX idx_funds_transfer_content_unique_id ON entities( (content->>'unique_id') ) WHERE etype = 'FundsTransfer';
+DROP INDEX IF EXISTS idx_identifier_content_unique_id;
+CREATE UNIQUE INDEX idx_identifier_content_unique_id ON entities( (content->>'unique_id') ) WHERE etype = 'Identifier';
+DROP INDEX IF EXISTS idx_ipaddr_content_address;
+CREATE UNIQUE INDEX idx_ipaddr_content_address ON entities( (content->>'address') ) WHERE etype = 'IPAddress';
+DROP INDEX IF EXISTS idx_ipnetrec_content_handle;
+CREATE UNIQUE INDEX idx_ipnetrec_content_handle ON entities( (content->>'handle') ) WHERE etype = 'IPNetRecord';
+DROP INDEX IF EXISTS idx_location_content_address;
+CREATE UNIQUE INDEX idx_location_content_address ON entities( (content->>'address') ) WHERE etype = 'Location';
+DROP INDEX IF EXISTS idx_netblock_content_cidr;
+CREATE UNIQUE INDEX idx_netblock_content_cidr ON entities( (content->>'cidr') ) WHERE etype = 'Netblock';
+DROP INDEX IF EXISTS idx_org_content_unique_id;
+CREATE UNIQUE INDEX idx_org_content_unique_id ON entities( (content->>'unique_id') ) WHERE etype = 'Organization';
+DROP INDEX IF EXISTS idx_person_content_unique_id;
+CREATE UNIQUE INDEX idx_person_content_unique_id ON entities( (content->>'unique_id') ) WHERE etype = 'Person';
+DROP INDEX IF EXISTS idx_phone_content_e164;
+CREATE UNIQUE INDEX idx_phone_content_e164 ON entities( (content->>'e164') ) WHERE etype = 'Phone';
+DROP INDEX IF EXISTS idx_phone_content_raw;
+CREATE UNIQUE INDEX idx_phone_content_raw ON entities( (content->>'raw') ) WHERE etype = 'Phone';
+DROP INDEX IF EXISTS idx_product_content_unique_id;
+CREATE UNIQUE INDEX idx_product_content_unique_id ON entities( (content->>'unique_id') ) WHERE etype = 'Product';
+DROP INDEX IF EXISTS idx_product_release_content_name;
+CREATE UNIQUE INDEX idx_product_release_content_name ON entities( (content->>'name') ) WHERE etype = 'ProductRelease';
+DROP INDEX IF EXISTS idx_service_content_unique_id;
+CREATE UNIQUE INDEX idx_service_content_unique_id ON entities( (content->>'unique_id') ) WHERE etype = 'Service';
+DROP INDEX IF EXISTS idx_tls_content_serial_number;
+CREATE UNIQUE INDEX idx_tls_content_serial_number ON entities( (content->>'serial_number') ) WHERE etype = 'TLSCertificate';
+DROP INDEX IF EXISTS idx_url_content_url;
+CREATE UNIQUE INDEX idx_url_content_url ON entities( (content->>'url') ) WHERE etype = 'URL';
+
+DROP INDEX IF EXISTS idx_entities_workspace;
+ALTER TABLE edge_tags DROP COLUMN IF EXISTS workspace;
+ALTER TABLE entity_tags DROP COLUMN IF EXISTS workspace;
+ALTER TABLE edges DROP COLUMN IF EXISTS workspace;
+ALTER TABLE entities DROP COLUMN IF EXISTS workspace;
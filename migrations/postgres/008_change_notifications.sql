@@ -0,0 +1,45 @@
+-- +migrate Up
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION asset_db_notify_change() RETURNS trigger AS $$
+DECLARE
+    rec JSONB;
+    op TEXT := lower(TG_OP);
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := to_jsonb(OLD);
+    ELSE
+        rec := to_jsonb(NEW);
+    END IF;
+
+    -- the soft-deletes and the restores of the entities are reported as deletes and inserts
+    IF TG_OP = 'UPDATE' AND TG_ARGV[0] = 'entity' THEN
+        IF NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN
+            op := 'delete';
+        ELSIF NEW.deleted_at IS NULL AND OLD.deleted_at IS NOT NULL THEN
+            op := 'insert';
+        END IF;
+    END IF;
+
+    PERFORM pg_notify('asset_db_changes', json_build_object(
+        'op', op,
+        'kind', TG_ARGV[0],
+        'id', rec->>TG_ARGV[1],
+        'type', rec->>'etype',
+        'workspace', rec->>'workspace'
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+CREATE TRIGGER trg_entities_notify_change AFTER INSERT OR UPDATE OR DELETE ON entities
+    FOR EACH ROW EXECUTE FUNCTION asset_db_notify_change('entity', 'entity_id');
+CREATE TRIGGER trg_edges_notify_change AFTER INSERT OR UPDATE OR DELETE ON edges
+    FOR EACH ROW EXECUTE FUNCTION asset_db_notify_change('edge', 'edge_id');
+
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS trg_edges_notify_change ON edges;
+DROP TRIGGER IF EXISTS trg_entities_notify_change ON entities;
+DROP FUNCTION IF EXISTS asset_db_notify_change();
@@ -0,0 +1,9 @@
+-- +migrate Up
+
+ALTER TABLE entities ADD COLUMN deleted_at DATETIME;
+CREATE INDEX idx_entities_deleted_at ON entities (deleted_at);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_entities_deleted_at;
+ALTER TABLE entities DROP COLUMN deleted_at;
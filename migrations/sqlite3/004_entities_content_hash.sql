@@ -0,0 +1,9 @@
+-- +migrate Up
+
+ALTER TABLE entities ADD COLUMN content_hash TEXT;
+CREATE INDEX idx_entities_content_hash ON entities (content_hash);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_entities_content_hash;
+ALTER TABLE entities DROP COLUMN content_hash;
@@ -0,0 +1,98 @@
+-- +migrate Up
+
+ALTER TABLE entities ADD COLUMN workspace TEXT NOT NULL DEFAULT '';
+ALTER TABLE edges ADD COLUMN workspace TEXT NOT NULL DEFAULT '';
+ALTER TABLE entity_tags ADD COLUMN workspace TEXT NOT NULL DEFAULT '';
+ALTER TABLE edge_tags ADD COLUMN workspace TEXT NOT NULL DEFAULT '';
+CREATE INDEX idx_entities_workspace ON entities (workspace, etype);
+
+-- the assets are unique within each workspace
+DROP INDEX IF EXISTS idx_account_content_handle;
+CREATE UNIQUE INDEX idx_account_content_handle ON entities (workspace, content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Account';
+DROP INDEX IF EXISTS idx_autnum_content_handle;
+CREATE UNIQUE INDEX idx_autnum_content_handle ON entities (workspace, content->>'handle' COLLATE NOCASE) WHERE etype = 'AutnumRecord';
+DROP INDEX IF EXISTS idx_autsys_content_number;
+CREATE UNIQUE INDEX idx_autsys_content_number ON entities (workspace, content->>'number' COLLATE NOCASE) WHERE etype = 'AutonomousSystem';
+DROP INDEX IF EXISTS idx_contact_record_content_discovered_at;
+CREATE UNIQUE INDEX idx_contact_record_content_discovered_at ON entities (workspace, content->>'discovered_at' COLLATE NOCASE) WHERE etype = 'ContactRecord';
+DROP INDEX IF EXISTS idx_domainrec_content_domain;
+CREATE UNIQUE INDEX idx_domainrec_content_domain ON entities (workspace, content->>'domain' COLLATE NOCASE) WHERE etype = 'DomainRecord';
+DROP INDEX IF EXISTS idx_file_content_url;
+CREATE UNIQUE INDEX idx_file_content_url ON entities (workspace, content->>'url' COLLATE NOCASE) WHERE etype = 'File';
+DROP INDEX IF EXISTS idx_fqdn_content_name;
+CREATE UNIQUE INDEX idx_fqdn_content_name ON entities (workspace, content->>'name' COLLATE NOCASE) WHERE etype = 'FQDN';
+DROP INDEX IF EXISTS idx_funds_transfer_content_unique_id;
+CREATE UNIQUE INDEX idx_funds_transfer_content_unique_id ON entities (workspace, content->>'unique_id' COLLATE NOCASE) WHERE etype = 'FundsTransfer';
+DROP INDEX IF EXISTS idx_identifier_content_unique_id;
+CREATE UNIQUE INDEX idx_identifier_content_unique_id ON entities (workspace, content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Identifier';
+DROP INDEX IF EXISTS idx_ipaddr_content_address;
+CREATE UNIQUE INDEX idx_ipaddr_content_address ON entities (workspace, content->>'address' COLLATE NOCASE) WHERE etype = 'IPAddress';
+DROP INDEX IF EXISTS idx_ipnetrec_content_handle;
+CREATE UNIQUE INDEX idx_ipnetrec_content_handle ON entities (workspace, content->>'handle' COLLATE NOCASE) WHERE etype = 'IPNetRecord';
+DROP INDEX IF EXISTS idx_location_content_address;
+CREATE UNIQUE INDEX idx_location_content_address ON entities (workspace, content->>'address' COLLATE NOCASE) WHERE etype = 'Location';
+DROP INDEX IF EXISTS idx_netblock_content_cidr;
+CREATE UNIQUE INDEX idx_netblock_content_cidr ON entities (workspace, content->>'cidr' COLLATE NOCASE) WHERE etype = 'Netblock';
+DROP INDEX IF EXISTS idx_org_content_unique_id;
+CREATE UNIQUE INDEX idx_org_content_unique_id ON entities (workspace, content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Organization';
+DROP INDEX IF EXISTS idx_person_content_unique_id;
+CREATE UNIQUE INDEX idx_person_content_unique_id ON entities (workspace, content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Person';
+DROP INDEX IF EXISTS idx_phone_content_e164;
+CREATE UNIQUE INDEX idx_phone_content_e164 ON entities (workspace, content->>'e164' COLLATE NOCASE) WHERE etype = 'Phone';
+DROP INDEX IF EXISTS idx_phone_content_raw;
+CREATE UNIQUE INDEX idx_phone_content_raw ON entities (workspace, content->>'raw' COLLATE NOCASE) WHERE etype = 'Phone';
+DROP INDEX IF EXISTS idx_product_content_unique_id;
+CREATE UNIQUE INDEX idx_product_content_unique_id ON entities (workspace, content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Product';
+DROP INDEX IF EXISTS idx_product_release_content_name;
+CREATE UNIQUE INDEX idx_product_release_content_name ON entities (workspace, content->>'name' COLLATE NOCASE) WHERE etype = 'ProductRelease';
+DROP INDEX IF EXISTS idx_service_content_unique_id;
+CREATE UNIQUE INDEX idx_service_content_unique_id ON entities (workspace, content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Service';
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_account_content_handle;
+CREATE UNIQUE INDEX idx_account_content_handle ON entities (content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Account';
+DROP INDEX IF EXISTS idx_autnum_content_handle;
+CREATE UNIQUE INDEX idx_autnum_content_handle ON entities (content->>'handle' COLLATE NOCASE) WHERE etype = 'AutnumRecord';
+DROP INDEX IF EXISTS idx_autsys_content_number;
+CREATE UNIQUE INDEX idx_autsys_content_number ON entities (content->>'number' COLLATE NOCASE) WHERE etype = 'AutonomousSystem';
+DROP INDEX IF EXISTS idx_contact_record_content_discovered_at;
+CREATE UNIQUE INDEX idx_contact_record_content_discovered_at ON entities (content->>'discovered_at' COLLATE NOCASE) WHERE etype = 'ContactRecord';
+DROP INDEX IF EXISTS idx_domainrec_content_domain;
+CREATE UNIQUE INDEX idx_domainrec_content_domain ON entities (content->>'domain' COLLATE NOCASE) WHERE etype = 'DomainRecord';
+DROP INDEX IF EXISTS idx_file_content_url;
+CREATE UNIQUE INDEX idx_file_content_url ON entities (content->>'url' COLLATE NOCASE) WHERE etype = 'File';
+DROP INDEX IF EXISTS idx_fqdn_content_name;
+CREATE UNIQUE INDEX idx_fqdn_content_name ON entities (content->>'name' COLLATE NOCASE) WHERE etype = 'FQDN';
+DROP INDEX IF EXISTS idx_funds_transfer_content_unique_id;
+CREATE UNIQUE INDEX idx_funds_transfer_content_unique_id ON entities (content->>'unique_id' COLLATE NOCASE) WHERE etype = 'FundsTransfer';
+DROP INDEX IF EXISTS idx_identifier_content_unique_id;
+CREATE UNIQUE INDEX idx_identifier_content_unique_id ON entities (content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Identifier';
+DROP INDEX IF EXISTS idx_ipaddr_content_address;
+CREATE UNIQUE INDEX idx_ipaddr_content_address ON entities (content->>'address' COLLATE NOCASE) WHERE etype = 'IPAddress';
+DROP INDEX IF EXISTS idx_ipnetrec_content_handle;
+CREATE UNIQUE INDEX idx_ipnetrec_content_handle ON entities (content->>'handle' COLLATE NOCASE) WHERE etype = 'IPNetRecord';
+DROP INDEX IF EXISTS idx_location_content_address;
+CREATE UNIQUE INDEX idx_location_content_address ON entities (content->>'address' COLLATE NOCASE) WHERE etype = 'Location';
+DROP INDEX IF EXISTS idx_netblock_content_cidr;
+CREATE UNIQUE INDEX idx_netblock_content_cidr ON entities (content->>'cidr' COLLATE NOCASE) WHERE etype = 'Netblock';
+DROP INDEX IF EXISTS idx_org_content_unique_id;
+CREATE UNIQUE INDEX idx_org_content_unique_id ON entities (content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Organization';
+DROP INDEX IF EXISTS idx_person_content_unique_id;
+CREATE UNIQUE INDEX idx_person_content_unique_id ON entities (content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Person';
+DROP INDEX IF EXISTS idx_phone_content_e164;
+CREATE UNIQUE INDEX idx_phone_content_e164 ON entities (content->>'e164' COLLATE NOCASE) WHERE etype = 'Phone';
+DROP INDEX IF EXISTS idx_phone_content_raw;
+CREATE UNIQUE INDEX idx_phone_content_raw ON entities (content->>'raw' COLLATE NOCASE) WHERE etype = 'Phone';
+DROP INDEX IF EXISTS idx_product_content_unique_id;
+CREATE UNIQUE INDEX idx_product_content_unique_id ON entities (content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Product';
+DROP INDEX IF EXISTS idx_product_release_content_name;
+CREATE UNIQUE INDEX idx_product_release_content_name ON entities (content->>'name' COLLATE NOCASE) WHERE etype = 'ProductRelease';
+DROP INDEX IF EXISTS idx_service_content_unique_id;
+CREATE UNIQUE INDEX idx_service_content_unique_id ON entities (content->>'unique_id' COLLATE NOCASE) WHERE etype = 'Service';
+
+DROP INDEX IF EXISTS idx_entities_workspace;
+ALTER TABLE edge_tags DROP COLUMN workspace;
+ALTER TABLE entity_tags DROP COLUMN workspace;
+ALTER TABLE edges DROP COLUMN workspace;
+ALTER TABLE entities DROP COLUMN workspace;
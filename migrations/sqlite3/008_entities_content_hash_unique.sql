@@ -0,0 +1,11 @@
+-- +migrate Up
+
+-- the assetdb package sets the content hashes of the existing entities before this migration is applied
+-- the type and the edge endpoints are indexed by 001_schema_init, and the content hash is unique within each workspace
+DROP INDEX IF EXISTS idx_entities_content_hash;
+CREATE UNIQUE INDEX idx_entities_content_hash ON entities (workspace, content_hash);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_entities_content_hash;
+CREATE INDEX idx_entities_content_hash ON entities (content_hash);
@@ -0,0 +1,17 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package options
+
+import "github.com/garthoid/asset-db/types"
+
+// WithEventHandler sets the function that is called with an event for each entity or edge created, updated,
+// or deleted through the repository. The handler is called once the operation has succeeded, or once the
+// transaction is committed for the operations made within WithTransaction, and it should return quickly,
+// since it runs in the goroutine of the operation.
+func WithEventHandler(fn func(types.Event)) Option {
+	return func(o *Options) {
+		o.EventHandler = fn
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package options
+
+// Logger receives the structured log records emitted by the repositories.
+// Each database operation is logged with the fields "method", "query", "duration", and "rows",
+// and the field "error" is added when the operation fails. Successful operations are logged at
+// the debug level, and failed operations are logged at the error level.
+type Logger interface {
+	Debug(msg string, fields map[string]interface{})
+	Info(msg string, fields map[string]interface{})
+	Error(msg string, fields map[string]interface{})
+}
+
+// WithLogger sets the logger that receives the records of the repository operations.
+// When no logger is provided, the repositories do not emit any logs.
+func WithLogger(l Logger) Option {
+	return func(o *Options) {
+		o.Logger = l
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package options
+
+import oam "github.com/owasp-amass/open-asset-model"
+
+// Marshaler serializes an asset to the JSON content that is stored with its entity, such as an encoder
+// that writes the keys in a canonical order. The content must decode to the same asset, since the
+// stored content is parsed when the entity is read.
+type Marshaler func(asset oam.Asset) ([]byte, error)
+
+// WithMarshaler sets the function that serializes the assets stored by the SQL repositories, in place of
+// the JSON method of the asset. The content hash of an entity is computed from the asset type and key,
+// so it does not depend on the serialization. The setting is ignored by the other repositories, which
+// do not store the serialized asset.
+func WithMarshaler(m Marshaler) Option {
+	return func(o *Options) {
+		o.Marshaler = m
+	}
+}
@@ -0,0 +1,16 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package options
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// WithMetrics enables the Prometheus instrumentation of the repository operations.
+// The collectors for the operation latency, errors, and in-flight operations are registered with
+// the provided registerer, and are shared by all the repositories that use the same registerer.
+func WithMetrics(registerer prometheus.Registerer) Option {
+	return func(o *Options) {
+		o.Registerer = registerer
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package options
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Options holds the settings used when opening an asset database repository.
+// A zero value for any field means that the repository implementation default is used.
+type Options struct {
+	MaxConnections     int
+	MaxIdleConnections int
+	ConnMaxLifetime    time.Duration
+	ConnMaxIdleTime    time.Duration
+	BatchSize          int
+	SoftDelete         bool
+	MaxAttempts        int
+	RetryBaseDelay     time.Duration
+	ConnectAttempts    int
+	ConnectRetryDelay  time.Duration
+	Logger             Logger
+	Registerer         prometheus.Registerer
+	TLSConfig          *tls.Config
+	SkipMigrations     bool
+	Neo4jDatabase      string
+	Neo4jTLSConfig     *tls.Config
+	Neo4jFetchSize     int
+	Schema             string
+	SQLitePragmas      map[string]string
+	QueryTimeout       time.Duration
+	TxRetryTimeout     time.Duration
+	ReadOnly           bool
+	LastSeenOrder      bool
+	OrderBy            OrderField
+	OrderDirection     OrderDirection
+	Marshaler          Marshaler
+	Validator          Validator
+	ExpiredTags        bool
+	AcquireTimeout     time.Duration
+	ConnHealthCheck    bool
+	IDGenerator        func() string
+	Workspace          string
+	TablePrefix        string
+	EventHandler       func(types.Event)
+}
+
+// OrderField is a sort key of the entities returned by FindEntitiesByType, as set by WithOrder.
+type OrderField string
+
+const (
+	OrderByCreatedAt OrderField = "created_at"
+	OrderByUpdatedAt OrderField = "updated_at"
+	OrderByType      OrderField = "type"
+)
+
+// OrderDirection is the direction in which the entities are sorted by the OrderField.
+type OrderDirection int
+
+const (
+	Ascending OrderDirection = iota
+	Descending
+)
+
+// Option is a functional option that modifies the repository Options.
+type Option func(*Options)
+
+// Apply returns the Options that result from applying the provided functional options in order.
+func Apply(opts ...Option) *Options {
+	o := new(Options)
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// WithMaxConnections sets the maximum number of open connections to the database.
+func WithMaxConnections(n int) Option {
+	return func(o *Options) {
+		o.MaxConnections = n
+	}
+}
+
+// WithMaxIdleConnections sets the maximum number of idle connections kept in the pool.
+// The setting is ignored by the Neo4j repository, since the driver does not distinguish idle connections.
+func WithMaxIdleConnections(n int) Option {
+	return func(o *Options) {
+		o.MaxIdleConnections = n
+	}
+}
+
+// WithConnMaxLifetime sets the maximum amount of time a connection may be reused.
+func WithConnMaxLifetime(d time.Duration) Option {
+	return func(o *Options) {
+		o.ConnMaxLifetime = d
+	}
+}
+
+// WithConnMaxIdleTime sets the maximum amount of time a connection may be idle.
+// For Neo4j, connections idle for longer than this are checked for liveness before being reused.
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return func(o *Options) {
+		o.ConnMaxIdleTime = d
+	}
+}
+
+// WithBatchSize sets the maximum number of records written by a single statement during batch operations.
+func WithBatchSize(n int) Option {
+	return func(o *Options) {
+		o.BatchSize = n
+	}
+}
+
+// WithSoftDelete enables the soft-delete mode, where deleted entities are kept as tombstones.
+// The tombstones are excluded from the Find* calls, and can be listed or purged at a later time.
+func WithSoftDelete() Option {
+	return func(o *Options) {
+		o.SoftDelete = true
+	}
+}
+
+// WithRetry enables retries of the idempotent operations that fail with a transient error, such as
+// a dropped connection. The operations are attempted up to maxAttempts times, and the delay between
+// attempts starts at baseDelay and doubles after each failure. Calls made within a transaction are not retried.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(o *Options) {
+		o.MaxAttempts = maxAttempts
+		o.RetryBaseDelay = baseDelay
+	}
+}
+
+// WithConnectRetry makes the repository constructors, such as assetdb.New, attempt the initial connection up to
+// maxAttempts times when the database cannot be reached, such as while its container is still starting. The delay
+// between attempts starts at delay, or one second when it is not positive, and doubles after each failure.
+// The errors that are not caused by the connection, such as a failed authentication or a rejected certificate,
+// are returned without a retry.
+func WithConnectRetry(maxAttempts int, delay time.Duration) Option {
+	return func(o *Options) {
+		o.ConnectAttempts = maxAttempts
+		o.ConnectRetryDelay = delay
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used for the Postgres connections, such as one that
+// trusts a custom CA bundle. The configuration replaces the one derived from the sslmode parameter
+// of the DSN, and the connections always use TLS, so the DSN must not specify sslmode=disable.
+// The certificate of the server is verified unless the configuration sets InsecureSkipVerify.
+// The setting is ignored by the other repositories.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *Options) {
+		o.TLSConfig = cfg
+	}
+}
+
+// WithoutMigrations prevents assetdb.New from applying the pending schema migrations, so that
+// the migrations can be run separately from the startup of the application.
+// The repository implementations ignore the setting.
+func WithoutMigrations() Option {
+	return func(o *Options) {
+		o.SkipMigrations = true
+	}
+}
+
+// WithNeo4jDatabase sets the name of the Neo4j database, which takes precedence over the path of the DSN.
+// When neither provides a name, the sessions use the default database of the server.
+// The setting is ignored by the other repositories.
+func WithNeo4jDatabase(name string) Option {
+	return func(o *Options) {
+		o.Neo4jDatabase = name
+	}
+}
+
+// WithNeo4jTLSConfig sets the TLS configuration used for the encrypted Neo4j schemes, such as one whose
+// RootCAs trust the private CA of a self-managed server, so the certificate can be fully verified.
+// The driver still derives the server name from the host of the DSN, and skips the verification for the
+// "+ssc" schemes. Without the option, the "+s" schemes verify the certificate with the system roots.
+// The setting is ignored by the other repositories.
+func WithNeo4jTLSConfig(cfg *tls.Config) Option {
+	return func(o *Options) {
+		o.Neo4jTLSConfig = cfg
+	}
+}
+
+// WithNeo4jFetchSize sets the number of records the Neo4j driver pulls from the server in each batch, so the
+// results streamed by IterateEntitiesByType and ExportJSON are held in memory in bounded chunks.
+// Values less than one keep the default of the driver, which is 1000 records.
+// The setting is ignored by the other repositories.
+func WithNeo4jFetchSize(n int) Option {
+	return func(o *Options) {
+		o.Neo4jFetchSize = n
+	}
+}
+
+// WithSchema sets the Postgres schema that holds the tables, in place of the public schema.
+// The search_path of each connection is set to the schema, and the migrations create it when it does not exist,
+// so repositories that use different schemas of the same database are isolated.
+// The setting is ignored by the other repositories.
+func WithSchema(name string) Option {
+	return func(o *Options) {
+		o.Schema = name
+	}
+}
+
+// WithSQLitePragma sets a PRAGMA that is applied to each new SQLite connection, such as
+// journal_mode=WAL or busy_timeout=5000. Setting the same pragma again replaces its value.
+// The setting is ignored by the other repositories.
+func WithSQLitePragma(key, value string) Option {
+	return func(o *Options) {
+		if o.SQLitePragmas == nil {
+			o.SQLitePragmas = make(map[string]string)
+		}
+		o.SQLitePragmas[key] = value
+	}
+}
+
+// WithQueryTimeout sets the timeout applied to each query whose context has no deadline, so that a call made
+// without a deadline cannot wait on the database forever. The deadline of a context is kept when it has one.
+// Postgres also sets the statement_timeout of each session, and Neo4j sets the timeout of each transaction.
+// By default, no timeout is applied.
+func WithQueryTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.QueryTimeout = d
+	}
+}
+
+// WithTxRetryTimeout sets the maximum time the Neo4j driver spends retrying a managed transaction that failed
+// with a transient error, such as a deadlock or the switch of a cluster leader, which is 30 seconds by default.
+// The driver provided to NewWithDriver keeps its own configuration, and the SQL repositories ignore the setting.
+func WithTxRetryTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.TxRetryTimeout = d
+	}
+}
+
+// WithReadOnly makes the repository returned by repository.New read-only, as with repository.NewReadOnly,
+// so the methods that create, update, or delete records return an error matching types.ErrReadOnly.
+// Postgres also sets default_transaction_read_only for each session, so the server rejects any write.
+// The schema migrations applied by assetdb.New are not affected.
+func WithReadOnly() Option {
+	return func(o *Options) {
+		o.ReadOnly = true
+	}
+}
+
+// WithLastSeenOrder makes FindEntitiesByType and FindEntitiesByTypeBetween return the entities ordered by
+// their last seen time, with the most recently seen entities first. The order is otherwise unspecified.
+func WithLastSeenOrder() Option {
+	return func(o *Options) {
+		o.LastSeenOrder = true
+	}
+}
+
+// WithOrder makes FindEntitiesByType and FindEntitiesByTypeBetween return the entities sorted by the field in the
+// direction, with ties broken by the entity ID in the same direction, so that the order is stable across calls.
+// It takes precedence over WithLastSeenOrder, and any other field leaves the order unspecified.
+func WithOrder(field OrderField, dir OrderDirection) Option {
+	return func(o *Options) {
+		o.OrderBy = field
+		o.OrderDirection = dir
+	}
+}
+
+// WithExpiredTags makes GetEntityTags, GetEdgeTags, and their variants include the tags that have expired,
+// which are otherwise excluded until they are removed by PurgeExpiredTags.
+func WithExpiredTags() Option {
+	return func(o *Options) {
+		o.ExpiredTags = true
+	}
+}
+
+// WithAcquireTimeout sets how long a call waits for a free connection of the pool, after which the call fails with
+// an error matching types.ErrPoolTimeout, rather than waiting until its context is done. The time spent executing
+// the query is not bounded by the timeout. Neo4j sets the ConnectionAcquisitionTimeout of the driver.
+// By default, a call waits until a connection becomes free or its context is done.
+func WithAcquireTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.AcquireTimeout = d
+	}
+}
+
+// WithConnHealthCheck enables a ping of each connection borrowed from the pool before it is used, so the connections
+// left dead by a failover of the server are discarded and replaced rather than failing the next queries. The ping adds
+// a round trip to each statement. Neo4j tests the liveness of each connection before reusing it.
+// By default, the connections are not checked.
+func WithConnHealthCheck(enabled bool) Option {
+	return func(o *Options) {
+		o.ConnHealthCheck = enabled
+	}
+}
+
+// WithIDGenerator sets the function that generates the ID of each new entity, such as a UUIDv7 generator,
+// in place of the random UUID assigned by Neo4j. An ID provided by the caller is kept rather than generated.
+// The function is called by concurrent writes to the repository, so it must be safe for concurrent use.
+// The IDs of the memory repository are integers, so the generated IDs must be decimal integers, such as snowflake IDs.
+// The SQL repositories return an error for the setting, since their IDs are assigned by the database.
+func WithIDGenerator(fn func() string) Option {
+	return func(o *Options) {
+		o.IDGenerator = fn
+	}
+}
+
+// WithWorkspace limits the reads and writes of a SQL repository to the workspace with the provided ID, so that
+// several datasets, such as one per client engagement, can share a database without separate schemas. The
+// repositories without the option use the default workspace, which has an empty ID. The Neo4j repository does
+// not support workspaces, and the memory repositories are already isolated from each other.
+func WithWorkspace(id string) Option {
+	return func(o *Options) {
+		o.Workspace = id
+	}
+}
+
+// WithTablePrefix adds the prefix to the names of the tables, indexes, and constraints of a SQL repository, such as
+// "assetdb_", so that the schema can share a database with the tables of another application. The prefix is applied
+// by the schema migrations and the models alike, and must only hold letters, digits, and underscores. The same prefix
+// must be provided each time the database is opened or migrated. The Neo4j repository returns an error for the setting,
+// since its data is isolated with options.WithNeo4jDatabase, and the memory repositories ignore it, since they are
+// already isolated from each other.
+func WithTablePrefix(prefix string) Option {
+	return func(o *Options) {
+		o.TablePrefix = prefix
+	}
+}
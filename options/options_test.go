@@ -0,0 +1,138 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package options
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	assert.Equal(t, &Options{}, Apply())
+
+	o := Apply(
+		WithMaxConnections(50),
+		WithMaxIdleConnections(10),
+		WithConnMaxLifetime(30*time.Minute),
+		WithConnMaxIdleTime(time.Minute),
+		WithBatchSize(500),
+		WithSoftDelete(),
+		WithRetry(3, 100*time.Millisecond),
+		WithoutMigrations(),
+		WithNeo4jDatabase("assets"),
+		WithNeo4jFetchSize(250),
+		WithSchema("tenant_a"),
+		WithSQLitePragma("journal_mode", "DELETE"),
+		WithSQLitePragma("journal_mode", "WAL"),
+		WithQueryTimeout(5*time.Second),
+		WithTxRetryTimeout(time.Minute),
+		WithReadOnly(),
+		WithLastSeenOrder(),
+		WithOrder(OrderByUpdatedAt, Descending),
+		WithExpiredTags(),
+		WithAcquireTimeout(time.Second),
+		WithConnHealthCheck(true),
+		WithWorkspace("client_a"),
+		WithTablePrefix("assetdb_"),
+		WithConnectRetry(5, 2*time.Second),
+		nil,
+	)
+	assert.Equal(t, &Options{
+		MaxConnections:     50,
+		MaxIdleConnections: 10,
+		ConnMaxLifetime:    30 * time.Minute,
+		ConnMaxIdleTime:    time.Minute,
+		BatchSize:          500,
+		SoftDelete:         true,
+		MaxAttempts:        3,
+		RetryBaseDelay:     100 * time.Millisecond,
+		SkipMigrations:     true,
+		Neo4jDatabase:      "assets",
+		Neo4jFetchSize:     250,
+		Schema:             "tenant_a",
+		SQLitePragmas:      map[string]string{"journal_mode": "WAL"},
+		QueryTimeout:       5 * time.Second,
+		TxRetryTimeout:     time.Minute,
+		ReadOnly:           true,
+		LastSeenOrder:      true,
+		OrderBy:            OrderByUpdatedAt,
+		OrderDirection:     Descending,
+		ExpiredTags:        true,
+		AcquireTimeout:     time.Second,
+		ConnHealthCheck:    true,
+		Workspace:          "client_a",
+		TablePrefix:        "assetdb_",
+		ConnectAttempts:    5,
+		ConnectRetryDelay:  2 * time.Second,
+	}, o)
+
+	// later options override earlier ones
+	o = Apply(WithMaxConnections(5), WithMaxConnections(8))
+	assert.Equal(t, 8, o.MaxConnections)
+}
+
+func TestWithMarshaler(t *testing.T) {
+	assert.Nil(t, Apply().Marshaler)
+
+	o := Apply(WithMarshaler(func(asset oam.Asset) ([]byte, error) {
+		return []byte(`{"name":"marshaled"}`), nil
+	}))
+	if assert.NotNil(t, o.Marshaler) {
+		content, err := o.Marshaler(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"name":"marshaled"}`, string(content))
+	}
+}
+
+func TestWithValidator(t *testing.T) {
+	assert.Nil(t, Apply().Validator)
+
+	o := Apply(WithValidator(func(asset oam.Asset) error {
+		return errors.New("rejected")
+	}))
+	if assert.NotNil(t, o.Validator) {
+		assert.EqualError(t, o.Validator(nil), "rejected")
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	assert.Nil(t, Apply().IDGenerator)
+
+	o := Apply(WithIDGenerator(func() string {
+		return "generated"
+	}))
+	if assert.NotNil(t, o.IDGenerator) {
+		assert.Equal(t, "generated", o.IDGenerator())
+	}
+}
+
+func TestWithEventHandler(t *testing.T) {
+	assert.Nil(t, Apply().EventHandler)
+
+	var got types.Event
+	o := Apply(WithEventHandler(func(ev types.Event) {
+		got = ev
+	}))
+	if assert.NotNil(t, o.EventHandler) {
+		o.EventHandler(types.Event{Op: types.EventCreate, ID: "1"})
+		assert.Equal(t, "1", got.ID)
+	}
+}
+
+func TestWithNeo4jTLSConfig(t *testing.T) {
+	assert.Nil(t, Apply().Neo4jTLSConfig)
+
+	cfg := &tls.Config{RootCAs: x509.NewCertPool(), MinVersion: tls.VersionTLS13}
+	o := Apply(WithNeo4jTLSConfig(cfg))
+	assert.Same(t, cfg, o.Neo4jTLSConfig)
+	assert.Nil(t, o.TLSConfig)
+}
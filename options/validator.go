@@ -0,0 +1,21 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package options
+
+import oam "github.com/owasp-amass/open-asset-model"
+
+// Validator checks the content of an asset before it is written, such as that an FQDN holds a valid domain name.
+// A non-nil error rejects the asset. The validator can switch on the asset type to check each type in its own way.
+type Validator func(asset oam.Asset) error
+
+// WithValidator sets the function that checks each asset written by CreateEntity, CreateAsset, CreateEntities,
+// UpsertEntity, and UpdateEntity, including the writes made by ImportJSON and within WithTransaction.
+// The write is aborted with the error of the validator, and CreateEntities writes none of the entities
+// when one of the assets is rejected. The assets that are read are not validated.
+func WithValidator(v Validator) Option {
+	return func(o *Options) {
+		o.Validator = v
+	}
+}
@@ -0,0 +1,401 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package readonly
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// ReadOnly decorates a repository so that it can be handed to code that must not modify the data.
+// The methods that create, update, or delete records return an error matching types.ErrReadOnly
+// without calling the wrapped repository, and the methods that read records pass through to it.
+type ReadOnly struct {
+	db types.Repository
+}
+
+// New returns a repository that only permits the read operations of the provided repository.
+func New(db types.Repository) *ReadOnly {
+	return &ReadOnly{db: db}
+}
+
+// denied returns the error of a write operation attempted through the read-only repository.
+func denied(method string) error {
+	return &types.Error{
+		Kind: types.ErrReadOnly,
+		Err:  fmt.Errorf("%s is not permitted on a read-only repository", method),
+	}
+}
+
+// GetDBType implements the Repository interface.
+func (r *ReadOnly) GetDBType() string {
+	return r.db.GetDBType()
+}
+
+// Ping implements the Repository interface.
+func (r *ReadOnly) Ping(ctx context.Context) error {
+	return r.db.Ping(ctx)
+}
+
+// Maintenance implements the Repository interface.
+// The maintenance is rejected, since it rewrites the storage and the statistics of the database.
+func (r *ReadOnly) Maintenance(ctx context.Context) error {
+	return denied("Maintenance")
+}
+
+// Stats implements the Repository interface.
+func (r *ReadOnly) Stats(ctx context.Context) (*types.DBStats, error) {
+	return r.db.Stats(ctx)
+}
+
+// CreateEntity implements the Repository interface.
+func (r *ReadOnly) CreateEntity(ctx context.Context, entity *types.Entity) (*types.Entity, error) {
+	return nil, denied("CreateEntity")
+}
+
+// CreateAsset implements the Repository interface.
+func (r *ReadOnly) CreateAsset(ctx context.Context, asset oam.Asset) (*types.Entity, error) {
+	return nil, denied("CreateAsset")
+}
+
+// CreateEntities implements the Repository interface.
+func (r *ReadOnly) CreateEntities(ctx context.Context, entities []*types.Entity) ([]*types.Entity, error) {
+	return nil, denied("CreateEntities")
+}
+
+// UpsertEntity implements the Repository interface.
+func (r *ReadOnly) UpsertEntity(ctx context.Context, entity *types.Entity) (*types.Entity, types.UpsertResult, error) {
+	return nil, "", denied("UpsertEntity")
+}
+
+// UpdateEntity implements the Repository interface.
+func (r *ReadOnly) UpdateEntity(ctx context.Context, id string, asset oam.Asset) (*types.Entity, error) {
+	return nil, denied("UpdateEntity")
+}
+
+// TouchEntity implements the Repository interface.
+func (r *ReadOnly) TouchEntity(ctx context.Context, id string) error {
+	return denied("TouchEntity")
+}
+
+// FindEntityById implements the Repository interface.
+func (r *ReadOnly) FindEntityById(ctx context.Context, id string) (*types.Entity, error) {
+	return r.db.FindEntityById(ctx, id)
+}
+
+// FindEntitiesByContent implements the Repository interface.
+func (r *ReadOnly) FindEntitiesByContent(ctx context.Context, asset oam.Asset, since time.Time) ([]*types.Entity, error) {
+	return r.db.FindEntitiesByContent(ctx, asset, since)
+}
+
+// FindEntitiesByContents implements the Repository interface.
+func (r *ReadOnly) FindEntitiesByContents(ctx context.Context, assets []oam.Asset, since time.Time) (map[string]*types.Entity, error) {
+	return r.db.FindEntitiesByContents(ctx, assets, since)
+}
+
+// FindEntityByHash implements the Repository interface.
+func (r *ReadOnly) FindEntityByHash(ctx context.Context, hash string) (*types.Entity, error) {
+	return r.db.FindEntityByHash(ctx, hash)
+}
+
+// EntityExists implements the Repository interface.
+func (r *ReadOnly) EntityExists(ctx context.Context, asset oam.Asset) (bool, error) {
+	return r.db.EntityExists(ctx, asset)
+}
+
+// FindEntitiesByType implements the Repository interface.
+func (r *ReadOnly) FindEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	return r.db.FindEntitiesByType(ctx, atype, since)
+}
+
+// FindEntitiesByTypes implements the Repository interface.
+func (r *ReadOnly) FindEntitiesByTypes(ctx context.Context, since time.Time, atypes ...oam.AssetType) ([]*types.Entity, error) {
+	return r.db.FindEntitiesByTypes(ctx, since, atypes...)
+}
+
+// FindEntitiesByTypeBetween implements the Repository interface.
+func (r *ReadOnly) FindEntitiesByTypeBetween(ctx context.Context, atype oam.AssetType, from, to time.Time) ([]*types.Entity, error) {
+	return r.db.FindEntitiesByTypeBetween(ctx, atype, from, to)
+}
+
+// DiffEntities implements the Repository interface.
+func (r *ReadOnly) DiffEntities(ctx context.Context, atype oam.AssetType, t1, t2 time.Time) ([]*types.Entity, []*types.Entity, error) {
+	return r.db.DiffEntities(ctx, atype, t1, t2)
+}
+
+// SearchEntities implements the Repository interface.
+func (r *ReadOnly) SearchEntities(ctx context.Context, atype oam.AssetType, query string, since time.Time) ([]*types.Entity, error) {
+	return r.db.SearchEntities(ctx, atype, query, since)
+}
+
+// FindEntitiesByTypePaged implements the Repository interface.
+func (r *ReadOnly) FindEntitiesByTypePaged(ctx context.Context, atype oam.AssetType, since time.Time, offset, limit int) ([]*types.Entity, int64, error) {
+	return r.db.FindEntitiesByTypePaged(ctx, atype, since, offset, limit)
+}
+
+// IterateEntitiesByType implements the Repository interface.
+func (r *ReadOnly) IterateEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (types.EntityIterator, error) {
+	return r.db.IterateEntitiesByType(ctx, atype, since)
+}
+
+// CountEntitiesByType implements the Repository interface.
+func (r *ReadOnly) CountEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (int64, error) {
+	return r.db.CountEntitiesByType(ctx, atype, since)
+}
+
+// CountEntitiesGrouped implements the Repository interface.
+func (r *ReadOnly) CountEntitiesGrouped(ctx context.Context, since time.Time) (map[oam.AssetType]int64, error) {
+	return r.db.CountEntitiesGrouped(ctx, since)
+}
+
+// DeleteEntity implements the Repository interface.
+func (r *ReadOnly) DeleteEntity(ctx context.Context, id string) (int64, error) {
+	return 0, denied("DeleteEntity")
+}
+
+// DeleteEntityCascade implements the Repository interface.
+func (r *ReadOnly) DeleteEntityCascade(ctx context.Context, id string) (int64, error) {
+	return 0, denied("DeleteEntityCascade")
+}
+
+// DeleteEntitiesByType implements the Repository interface.
+func (r *ReadOnly) DeleteEntitiesByType(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	return 0, denied("DeleteEntitiesByType")
+}
+
+// FindOrphanEntities implements the Repository interface.
+func (r *ReadOnly) FindOrphanEntities(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	return r.db.FindOrphanEntities(ctx, atype, since)
+}
+
+// DeleteOrphanEntities implements the Repository interface.
+func (r *ReadOnly) DeleteOrphanEntities(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	return 0, denied("DeleteOrphanEntities")
+}
+
+// FindDeletedEntities implements the Repository interface.
+func (r *ReadOnly) FindDeletedEntities(ctx context.Context, since time.Time) ([]*types.Entity, error) {
+	return r.db.FindDeletedEntities(ctx, since)
+}
+
+// PurgeDeleted implements the Repository interface.
+func (r *ReadOnly) PurgeDeleted(ctx context.Context, before time.Time) error {
+	return denied("PurgeDeleted")
+}
+
+// CreateEdge implements the Repository interface.
+func (r *ReadOnly) CreateEdge(ctx context.Context, edge *types.Edge) (*types.Edge, error) {
+	return nil, denied("CreateEdge")
+}
+
+// UpsertEdge implements the Repository interface.
+func (r *ReadOnly) UpsertEdge(ctx context.Context, edge *types.Edge) (*types.Edge, types.UpsertResult, error) {
+	return nil, "", denied("UpsertEdge")
+}
+
+// FindEdgeById implements the Repository interface.
+func (r *ReadOnly) FindEdgeById(ctx context.Context, id string) (*types.Edge, error) {
+	return r.db.FindEdgeById(ctx, id)
+}
+
+// IncomingEdges implements the Repository interface.
+func (r *ReadOnly) IncomingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	return r.db.IncomingEdges(ctx, entity, since, labels...)
+}
+
+// OutgoingEdges implements the Repository interface.
+func (r *ReadOnly) OutgoingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	return r.db.OutgoingEdges(ctx, entity, since, labels...)
+}
+
+// IncomingEdgesBetween implements the Repository interface.
+func (r *ReadOnly) IncomingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	return r.db.IncomingEdgesBetween(ctx, entity, from, to, labels...)
+}
+
+// OutgoingEdgesBetween implements the Repository interface.
+func (r *ReadOnly) OutgoingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	return r.db.OutgoingEdgesBetween(ctx, entity, from, to, labels...)
+}
+
+// IncomingEdgesForAll implements the Repository interface.
+func (r *ReadOnly) IncomingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return r.db.IncomingEdgesForAll(ctx, entities, since, labels...)
+}
+
+// OutgoingEdgesForAll implements the Repository interface.
+func (r *ReadOnly) OutgoingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return r.db.OutgoingEdgesForAll(ctx, entities, since, labels...)
+}
+
+// Neighborhood implements the Repository interface.
+func (r *ReadOnly) Neighborhood(ctx context.Context, entity *types.Entity, maxDepth int, since time.Time, labels ...string) ([]*types.Entity, []*types.Edge, error) {
+	return r.db.Neighborhood(ctx, entity, maxDepth, since, labels...)
+}
+
+// FindEdgesByLabel implements the Repository interface.
+func (r *ReadOnly) FindEdgesByLabel(ctx context.Context, label string, since time.Time) ([]*types.Edge, error) {
+	return r.db.FindEdgesByLabel(ctx, label, since)
+}
+
+// FindEdgesSince implements the Repository interface.
+func (r *ReadOnly) FindEdgesSince(ctx context.Context, since time.Time, includeTags bool) ([]*types.Edge, error) {
+	return r.db.FindEdgesSince(ctx, since, includeTags)
+}
+
+// ResolveEdgeEndpoints implements the Repository interface.
+func (r *ReadOnly) ResolveEdgeEndpoints(ctx context.Context, edges []*types.Edge) (map[string]*types.Entity, error) {
+	return r.db.ResolveEdgeEndpoints(ctx, edges)
+}
+
+// CountEdges implements the Repository interface.
+func (r *ReadOnly) CountEdges(ctx context.Context, since time.Time) (int64, error) {
+	return r.db.CountEdges(ctx, since)
+}
+
+// EntityDegree implements the Repository interface.
+func (r *ReadOnly) EntityDegree(ctx context.Context, entity *types.Entity, since time.Time) (int64, int64, error) {
+	return r.db.EntityDegree(ctx, entity, since)
+}
+
+// DeleteEdge implements the Repository interface.
+func (r *ReadOnly) DeleteEdge(ctx context.Context, id string) (int64, error) {
+	return 0, denied("DeleteEdge")
+}
+
+// CreateEntityTag implements the Repository interface.
+func (r *ReadOnly) CreateEntityTag(ctx context.Context, entity *types.Entity, tag *types.EntityTag) (*types.EntityTag, error) {
+	return nil, denied("CreateEntityTag")
+}
+
+// CreateEntityTags implements the Repository interface.
+func (r *ReadOnly) CreateEntityTags(ctx context.Context, entityIDs []string, tag *types.EntityTag) ([]*types.EntityTag, error) {
+	return nil, denied("CreateEntityTags")
+}
+
+// CreateEntityProperty implements the Repository interface.
+func (r *ReadOnly) CreateEntityProperty(ctx context.Context, entity *types.Entity, property oam.Property) (*types.EntityTag, error) {
+	return nil, denied("CreateEntityProperty")
+}
+
+// FindEntityTagById implements the Repository interface.
+func (r *ReadOnly) FindEntityTagById(ctx context.Context, id string) (*types.EntityTag, error) {
+	return r.db.FindEntityTagById(ctx, id)
+}
+
+// FindEntityTagsByContent implements the Repository interface.
+func (r *ReadOnly) FindEntityTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EntityTag, error) {
+	return r.db.FindEntityTagsByContent(ctx, prop, since)
+}
+
+// GetEntityTags implements the Repository interface.
+func (r *ReadOnly) GetEntityTags(ctx context.Context, entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error) {
+	return r.db.GetEntityTags(ctx, entity, since, names...)
+}
+
+// GetEntityTagsBetween implements the Repository interface.
+func (r *ReadOnly) GetEntityTagsBetween(ctx context.Context, entity *types.Entity, from, to time.Time, names ...string) ([]*types.EntityTag, error) {
+	return r.db.GetEntityTagsBetween(ctx, entity, from, to, names...)
+}
+
+// GetEntityTagsMatching implements the Repository interface.
+func (r *ReadOnly) GetEntityTagsMatching(ctx context.Context, entity *types.Entity, since time.Time, name, value string) ([]*types.EntityTag, error) {
+	return r.db.GetEntityTagsMatching(ctx, entity, since, name, value)
+}
+
+// FindEntitiesByTag implements the Repository interface.
+func (r *ReadOnly) FindEntitiesByTag(ctx context.Context, name, value string, since time.Time) ([]*types.Entity, error) {
+	return r.db.FindEntitiesByTag(ctx, name, value, since)
+}
+
+// UpdateEntityTag implements the Repository interface.
+func (r *ReadOnly) UpdateEntityTag(ctx context.Context, id string, value string) (*types.EntityTag, error) {
+	return nil, denied("UpdateEntityTag")
+}
+
+// DeleteEntityTag implements the Repository interface.
+func (r *ReadOnly) DeleteEntityTag(ctx context.Context, id string) (int64, error) {
+	return 0, denied("DeleteEntityTag")
+}
+
+// CreateEdgeTag implements the Repository interface.
+func (r *ReadOnly) CreateEdgeTag(ctx context.Context, edge *types.Edge, tag *types.EdgeTag) (*types.EdgeTag, error) {
+	return nil, denied("CreateEdgeTag")
+}
+
+// CreateEdgeProperty implements the Repository interface.
+func (r *ReadOnly) CreateEdgeProperty(ctx context.Context, edge *types.Edge, property oam.Property) (*types.EdgeTag, error) {
+	return nil, denied("CreateEdgeProperty")
+}
+
+// FindEdgeTagById implements the Repository interface.
+func (r *ReadOnly) FindEdgeTagById(ctx context.Context, id string) (*types.EdgeTag, error) {
+	return r.db.FindEdgeTagById(ctx, id)
+}
+
+// FindEdgeTagsByContent implements the Repository interface.
+func (r *ReadOnly) FindEdgeTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EdgeTag, error) {
+	return r.db.FindEdgeTagsByContent(ctx, prop, since)
+}
+
+// GetEdgeTags implements the Repository interface.
+func (r *ReadOnly) GetEdgeTags(ctx context.Context, edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error) {
+	return r.db.GetEdgeTags(ctx, edge, since, names...)
+}
+
+// GetEdgeTagsBetween implements the Repository interface.
+func (r *ReadOnly) GetEdgeTagsBetween(ctx context.Context, edge *types.Edge, from, to time.Time, names ...string) ([]*types.EdgeTag, error) {
+	return r.db.GetEdgeTagsBetween(ctx, edge, from, to, names...)
+}
+
+// UpdateEdgeTag implements the Repository interface.
+func (r *ReadOnly) UpdateEdgeTag(ctx context.Context, id string, value string) (*types.EdgeTag, error) {
+	return nil, denied("UpdateEdgeTag")
+}
+
+// DeleteEdgeTag implements the Repository interface.
+func (r *ReadOnly) DeleteEdgeTag(ctx context.Context, id string) (int64, error) {
+	return 0, denied("DeleteEdgeTag")
+}
+
+// PurgeExpiredTags implements the Repository interface.
+func (r *ReadOnly) PurgeExpiredTags(ctx context.Context, before time.Time) (int64, error) {
+	return 0, denied("PurgeExpiredTags")
+}
+
+// ExportJSON implements the Repository interface.
+func (r *ReadOnly) ExportJSON(ctx context.Context, w io.Writer) error {
+	return r.db.ExportJSON(ctx, w)
+}
+
+// ExportSubgraph implements the Repository interface.
+func (r *ReadOnly) ExportSubgraph(ctx context.Context, root *types.Entity, maxDepth int, w io.Writer) error {
+	return r.db.ExportSubgraph(ctx, root, maxDepth, w)
+}
+
+// ImportJSON implements the Repository interface.
+func (r *ReadOnly) ImportJSON(ctx context.Context, rd io.Reader) (types.ImportStats, error) {
+	return types.ImportStats{}, denied("ImportJSON")
+}
+
+// WithTransaction implements the Repository interface.
+// The repository scoped to the transaction is also read-only, so the reads of fn are made
+// within the transaction, while its writes are rejected.
+func (r *ReadOnly) WithTransaction(ctx context.Context, fn func(tx types.Repository) error) error {
+	return r.db.WithTransaction(ctx, func(tx types.Repository) error {
+		return fn(&ReadOnly{db: tx})
+	})
+}
+
+// Close implements the Repository interface.
+// The wrapped repository is closed, as with the other decorators.
+func (r *ReadOnly) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,82 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package readonly
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/repository/memrepo"
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+	db := memrepo.New()
+
+	entity, err := db.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	tag, err := db.CreateEntityProperty(ctx, entity, &general.SimpleProperty{PropertyName: "source", PropertyValue: "test"})
+	assert.NoError(t, err)
+
+	r := New(db)
+	assert.Equal(t, db.GetDBType(), r.GetDBType())
+
+	// the reads pass through to the wrapped repository
+	found, err := r.FindEntityById(ctx, entity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "owasp.org", found.Asset.Key())
+	tags, err := r.GetEntityTags(ctx, entity, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, tags, 1)
+
+	// the writes are rejected without modifying the data
+	_, err = r.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.ErrorIs(t, err, types.ErrReadOnly)
+	_, err = r.DeleteEntity(ctx, entity.ID)
+	assert.ErrorIs(t, err, types.ErrReadOnly)
+	_, _, err = r.UpsertEdge(ctx, &types.Edge{Relation: general.SimpleRelation{Name: "node"}, FromEntity: entity, ToEntity: entity})
+	assert.ErrorIs(t, err, types.ErrReadOnly)
+	_, err = r.UpdateEntityTag(ctx, tag.ID, "changed")
+	assert.ErrorIs(t, err, types.ErrReadOnly)
+	_, err = r.PurgeExpiredTags(ctx, time.Time{})
+	assert.ErrorIs(t, err, types.ErrReadOnly)
+	_, err = r.DeleteOrphanEntities(ctx, oam.FQDN, time.Time{})
+	assert.ErrorIs(t, err, types.ErrReadOnly)
+	_, err = r.ImportJSON(ctx, strings.NewReader(""))
+	assert.ErrorIs(t, err, types.ErrReadOnly)
+	assert.ErrorIs(t, r.TouchEntity(ctx, entity.ID), types.ErrReadOnly)
+	_, err = r.UpdateEntity(ctx, entity.ID, &dns.FQDN{Name: "owasp.org"})
+	assert.ErrorIs(t, err, types.ErrReadOnly)
+	assert.ErrorIs(t, r.Maintenance(ctx), types.ErrReadOnly)
+
+	count, err := db.CountEntitiesByType(ctx, oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	tags, err = db.GetEntityTags(ctx, entity, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "test", tags[0].Property.Value())
+
+	var buf bytes.Buffer
+	assert.NoError(t, r.ExportJSON(ctx, &buf))
+	assert.NotZero(t, buf.Len())
+
+	// the repository scoped to a transaction is also read-only
+	err = r.WithTransaction(ctx, func(tx types.Repository) error {
+		if _, err := tx.FindEntityById(ctx, entity.ID); err != nil {
+			return err
+		}
+		_, err := tx.CreateAsset(ctx, &dns.FQDN{Name: "mail.owasp.org"})
+		return err
+	})
+	assert.ErrorIs(t, err, types.ErrReadOnly)
+}
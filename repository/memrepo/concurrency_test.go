@@ -0,0 +1,110 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConcurrentUse shares the repository between goroutines that mix reads and writes, including the writes of
+// the same asset. Run with -race to detect the data races of the shared state.
+func TestConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	const workers, iterations = 8, 20
+
+	shared := &dns.FQDN{Name: "shared.concurrent.owasp.org"}
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*iterations)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				if err := concurrentIteration(ctx, m, shared, fmt.Sprintf("w%d-i%d.concurrent.owasp.org", w, i)); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	entities, err := m.FindEntitiesByContent(ctx, shared, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 1)
+	// the entities of the iterations were deleted
+	total, err := m.CountEntitiesByType(ctx, oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
+
+// concurrentIteration writes and reads the shared asset along with an asset of its own, which is deleted at the end.
+func concurrentIteration(ctx context.Context, store types.Repository, shared oam.Asset, name string) error {
+	from, err := store.CreateAsset(ctx, shared)
+	if err != nil {
+		return fmt.Errorf("create the shared asset: %w", err)
+	}
+
+	to, err := store.CreateAsset(ctx, &dns.FQDN{Name: name})
+	if err != nil {
+		return fmt.Errorf("create %s: %w", name, err)
+	}
+	defer func() { _, _ = store.DeleteEntity(ctx, to.ID) }()
+
+	if _, err := store.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	}); err != nil {
+		return fmt.Errorf("create the edge to %s: %w", name, err)
+	}
+
+	if _, err := store.CreateEntityProperty(ctx, to, &general.SimpleProperty{PropertyName: "worker", PropertyValue: name}); err != nil {
+		return fmt.Errorf("create the tag of %s: %w", name, err)
+	}
+
+	if _, err := store.FindEntityById(ctx, to.ID); err != nil {
+		return fmt.Errorf("find %s: %w", name, err)
+	}
+	if _, err := store.OutgoingEdges(ctx, from, time.Time{}, "node"); err != nil {
+		return fmt.Errorf("read the edges of the shared asset: %w", err)
+	}
+	if _, err := store.GetEntityTags(ctx, to, time.Time{}, "worker"); err != nil {
+		return fmt.Errorf("read the tags of %s: %w", name, err)
+	}
+
+	it, err := store.IterateEntitiesByType(ctx, oam.FQDN, time.Time{})
+	if err != nil {
+		return fmt.Errorf("iterate the entities: %w", err)
+	}
+	for it.Next() {
+	}
+	if err := errors.Join(it.Err(), it.Close()); err != nil {
+		return fmt.Errorf("iterate the entities: %w", err)
+	}
+
+	return store.WithTransaction(ctx, func(tx types.Repository) error {
+		if _, err := tx.FindEntitiesByContent(ctx, shared, time.Time{}); err != nil {
+			return fmt.Errorf("find the shared asset in a transaction: %w", err)
+		}
+		_, err := tx.CreateEntityProperty(ctx, from, &general.SimpleProperty{PropertyName: "seen_by", PropertyValue: name})
+		return err
+	})
+}
@@ -0,0 +1,239 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/garthoid/asset-db/options"
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+const Memory string = "memory"
+
+// memRepository is a repository implementation that keeps all the data in memory.
+// It is intended for unit tests, and does not provide durability.
+type memRepository struct {
+	mu          *sync.RWMutex
+	data        *data
+	softDelete  bool
+	lastSeen    bool
+	orderBy     options.OrderField
+	orderDesc   bool
+	expiredTags bool
+	validate    options.Validator
+	generateID  func() string
+	intx        bool
+}
+
+// data holds the records of a memory repository.
+type data struct {
+	nextID     uint64
+	entities   map[uint64]*entity
+	edges      map[uint64]*edge
+	entityTags map[uint64]*tag
+	edgeTags   map[uint64]*tag
+}
+
+type entity struct {
+	ID        uint64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt time.Time
+	Asset     oam.Asset
+}
+
+type edge struct {
+	ID           uint64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Relation     oam.Relation
+	FromEntityID uint64
+	ToEntityID   uint64
+}
+
+// tag is used for both the entity and edge tags, where the OwnerID refers to the entity or edge respectively.
+type tag struct {
+	ID        uint64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ExpiresAt time.Time
+	Property  oam.Property
+	OwnerID   uint64
+}
+
+// New creates a new, empty instance of the memory repository.
+// The soft-delete, order, last-seen order, expired tag, validator, and ID generator options are the only ones honored
+// by the memory repository.
+func New(opts ...options.Option) *memRepository {
+	o := options.Apply(opts...)
+
+	return &memRepository{
+		mu: new(sync.RWMutex),
+		data: &data{
+			entities:   make(map[uint64]*entity),
+			edges:      make(map[uint64]*edge),
+			entityTags: make(map[uint64]*tag),
+			edgeTags:   make(map[uint64]*tag),
+		},
+		softDelete:  o.SoftDelete,
+		lastSeen:    o.LastSeenOrder,
+		orderBy:     o.OrderBy,
+		orderDesc:   o.OrderDirection == options.Descending,
+		expiredTags: o.ExpiredTags,
+		validate:    o.Validator,
+		generateID:  o.IDGenerator,
+	}
+}
+
+// Close implements the Repository interface.
+// The memory repository does not hold any resources, so the data remains accessible after the call.
+func (m *memRepository) Close() error {
+	return nil
+}
+
+// Ping implements the Repository interface.
+// The memory repository is always reachable, so only the context is checked.
+func (m *memRepository) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Maintenance implements the Repository interface.
+// The memory repository releases the deleted records as they are deleted, so only the context is checked.
+func (m *memRepository) Maintenance(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Stats returns a snapshot of the contents of the repository, taken while holding the lock.
+// The soft-deleted entities and their edges are excluded, while the tags are counted as stored, including the expired tags.
+// The memory repository has no size on disk, so SizeBytes is zero.
+func (m *memRepository) Stats(ctx context.Context) (*types.DBStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := &types.DBStats{
+		EntitiesByType: make(map[oam.AssetType]int64),
+		EdgesByLabel:   make(map[string]int64),
+		EntityTags:     int64(len(m.data.entityTags)),
+		EdgeTags:       int64(len(m.data.edgeTags)),
+	}
+	for _, e := range m.data.entities {
+		if !e.DeletedAt.IsZero() {
+			continue
+		}
+
+		stats.EntitiesByType[e.Asset.AssetType()]++
+		if stats.OldestCreatedAt.IsZero() || e.CreatedAt.Before(stats.OldestCreatedAt) {
+			stats.OldestCreatedAt = e.CreatedAt
+		}
+		if e.UpdatedAt.After(stats.NewestLastSeen) {
+			stats.NewestLastSeen = e.UpdatedAt
+		}
+	}
+	for _, e := range m.data.edges {
+		if m.liveEdge(e) {
+			stats.EdgesByLabel[e.Relation.Label()]++
+		}
+	}
+	return stats, nil
+}
+
+// GetDBType returns the type of the database.
+func (m *memRepository) GetDBType() string {
+	return Memory
+}
+
+// newID returns the next identifier, which is shared by all the record types.
+func (d *data) newID() uint64 {
+	d.nextID++
+	return d.nextID
+}
+
+// clone returns a copy of the data, which can be modified without affecting the original records.
+func (d *data) clone() *data {
+	c := &data{
+		nextID:     d.nextID,
+		entities:   make(map[uint64]*entity, len(d.entities)),
+		edges:      make(map[uint64]*edge, len(d.edges)),
+		entityTags: make(map[uint64]*tag, len(d.entityTags)),
+		edgeTags:   make(map[uint64]*tag, len(d.edgeTags)),
+	}
+
+	for id, e := range d.entities {
+		cp := *e
+		c.entities[id] = &cp
+	}
+	for id, e := range d.edges {
+		cp := *e
+		c.edges[id] = &cp
+	}
+	for id, t := range d.entityTags {
+		cp := *t
+		c.entityTags[id] = &cp
+	}
+	for id, t := range d.edgeTags {
+		cp := *t
+		c.edgeTags[id] = &cp
+	}
+	return c
+}
+
+// sortedIDs returns the keys of the provided map in ascending order, so that results are deterministic.
+func sortedIDs[T any](records map[uint64]T) []uint64 {
+	ids := make([]uint64, 0, len(records))
+	for id := range records {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// parseID converts the string representation of an identifier used by the Repository interface.
+func parseID(id string) (uint64, error) {
+	return strconv.ParseUint(id, 10, 64)
+}
+
+// seenSince reports whether the record was last seen at or after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+func seenSince(updated, since time.Time) bool {
+	return since.IsZero() || !updated.Before(since)
+}
+
+// seenBetween reports whether the record was last seen within the [from, to) window.
+// A zero from or to leaves that side of the window open.
+func seenBetween(updated, from, to time.Time) bool {
+	return seenSince(updated, from) && (to.IsZero() || updated.Before(to))
+}
+
+func (e *entity) toEntity() *types.Entity {
+	return &types.Entity{
+		ID:        strconv.FormatUint(e.ID, 10),
+		CreatedAt: e.CreatedAt,
+		LastSeen:  e.UpdatedAt,
+		DeletedAt: e.DeletedAt,
+		Asset:     e.Asset,
+	}
+}
+
+func (e *edge) toEdge() *types.Edge {
+	return &types.Edge{
+		ID:         strconv.FormatUint(e.ID, 10),
+		CreatedAt:  e.CreatedAt,
+		LastSeen:   e.UpdatedAt,
+		Relation:   e.Relation,
+		FromEntity: &types.Entity{ID: strconv.FormatUint(e.FromEntityID, 10)},
+		ToEntity:   &types.Entity{ID: strconv.FormatUint(e.ToEntityID, 10)},
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/options"
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
+	"github.com/owasp-amass/open-asset-model/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemRepositoryImplementsRepository(t *testing.T) {
+	var _ types.Repository = (*memRepository)(nil)
+}
+
+func TestPing(t *testing.T) {
+	m := New()
+	assert.Equal(t, Memory, m.GetDBType())
+	assert.NoError(t, m.Ping(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, m.Ping(ctx))
+	assert.NoError(t, m.Maintenance(context.Background()))
+	assert.Error(t, m.Maintenance(ctx))
+	assert.NoError(t, m.Close())
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	m := New(options.WithSoftDelete())
+
+	stats, err := m.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, stats.EntitiesByType)
+	assert.True(t, stats.OldestCreatedAt.IsZero())
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	fqdn, err := m.CreateEntity(ctx, &types.Entity{CreatedAt: old, LastSeen: old, Asset: &dns.FQDN{Name: "owasp.org"}})
+	assert.NoError(t, err)
+	ip, err := m.CreateAsset(ctx, &network.IPAddress{Address: netip.MustParseAddr("192.168.1.1"), Type: "IPv4"})
+	assert.NoError(t, err)
+	deleted, err := m.CreateAsset(ctx, &dns.FQDN{Name: "deleted.owasp.org"})
+	assert.NoError(t, err)
+
+	edge, err := m.CreateEdge(ctx, &types.Edge{Relation: &dns.BasicDNSRelation{Name: "dns_record"}, FromEntity: fqdn, ToEntity: ip})
+	assert.NoError(t, err)
+	_, err = m.CreateEdge(ctx, &types.Edge{Relation: &general.SimpleRelation{Name: "node"}, FromEntity: fqdn, ToEntity: deleted})
+	assert.NoError(t, err)
+	_, err = m.CreateEntityProperty(ctx, fqdn, &general.SimpleProperty{PropertyName: "source", PropertyValue: "test"})
+	assert.NoError(t, err)
+	_, err = m.CreateEdgeProperty(ctx, edge, &general.SimpleProperty{PropertyName: "source", PropertyValue: "test"})
+	assert.NoError(t, err)
+	_, err = m.DeleteEntity(ctx, deleted.ID)
+	assert.NoError(t, err)
+
+	// the soft-deleted entity and its edge are not counted
+	stats, err = m.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[oam.AssetType]int64{oam.FQDN: 1, oam.IPAddress: 1}, stats.EntitiesByType)
+	assert.Equal(t, map[string]int64{"dns_record": 1}, stats.EdgesByLabel)
+	assert.Equal(t, int64(1), stats.EntityTags)
+	assert.Equal(t, int64(1), stats.EdgeTags)
+	assert.Zero(t, stats.SizeBytes)
+	assert.True(t, stats.OldestCreatedAt.Equal(old))
+	assert.True(t, stats.NewestLastSeen.Equal(ip.LastSeen))
+}
+
+func TestWithTransaction(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	// the changes are discarded when the function fails
+	err := m.WithTransaction(ctx, func(tx types.Repository) error {
+		if _, err := tx.CreateAsset(ctx, &dns.FQDN{Name: "rollback.example.com"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+	_, err = m.FindEntitiesByContent(ctx, &dns.FQDN{Name: "rollback.example.com"}, time.Time{})
+	assert.Error(t, err)
+
+	// the changes are kept when the function succeeds, and nested calls reuse the transaction
+	err = m.WithTransaction(ctx, func(tx types.Repository) error {
+		return tx.WithTransaction(ctx, func(inner types.Repository) error {
+			assert.Same(t, tx, inner)
+			_, err := inner.CreateAsset(ctx, &dns.FQDN{Name: "commit.example.com"})
+			return err
+		})
+	})
+	assert.NoError(t, err)
+	_, err = m.FindEntitiesByContent(ctx, &dns.FQDN{Name: "commit.example.com"}, time.Time{})
+	assert.NoError(t, err)
+}
+
+func TestExportJSON(t *testing.T) {
+	m := New(options.WithSoftDelete())
+	ctx := context.Background()
+
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	deleted, err := m.CreateAsset(ctx, &dns.FQDN{Name: "deleted.owasp.org"})
+	assert.NoError(t, err)
+
+	edge, err := m.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	_, err = m.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   deleted,
+	})
+	assert.NoError(t, err)
+
+	etag, err := m.CreateEntityProperty(ctx, from, &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	_, err = m.CreateEntityProperty(ctx, deleted, &general.SimpleProperty{PropertyName: "test", PropertyValue: "bar"})
+	assert.NoError(t, err)
+	edgetag, err := m.CreateEdgeProperty(ctx, edge, &general.SimpleProperty{PropertyName: "test", PropertyValue: "baz"})
+	assert.NoError(t, err)
+	_, err = m.DeleteEntity(ctx, deleted.ID)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.ExportJSON(ctx, &buf))
+
+	var records []*types.ExportRecord
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var r types.ExportRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, &r)
+	}
+
+	// the soft-deleted entity, along with its edge and tag, is excluded
+	if assert.Len(t, records, 5) {
+		assert.Equal(t, types.ExportRecord{
+			Kind:      types.EntityRecordKind,
+			ID:        from.ID,
+			Type:      "FQDN",
+			Content:   json.RawMessage(`{"name":"owasp.org"}`),
+			CreatedAt: from.CreatedAt.UTC(),
+			LastSeen:  from.LastSeen.UTC(),
+		}, *records[0])
+		assert.Equal(t, types.EntityRecordKind, records[1].Kind)
+		assert.Equal(t, to.ID, records[1].ID)
+		assert.Equal(t, types.EdgeRecordKind, records[2].Kind)
+		assert.Equal(t, edge.ID, records[2].ID)
+		assert.Equal(t, from.ID, records[2].FromEntityID)
+		assert.Equal(t, to.ID, records[2].ToEntityID)
+		assert.Equal(t, types.EntityTagRecordKind, records[3].Kind)
+		assert.Equal(t, etag.ID, records[3].ID)
+		assert.Equal(t, from.ID, records[3].EntityID)
+		assert.Equal(t, types.EdgeTagRecordKind, records[4].Kind)
+		assert.Equal(t, edgetag.ID, records[4].ID)
+		assert.Equal(t, edge.ID, records[4].EdgeID)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, m.ExportJSON(ctx, &bytes.Buffer{}), context.Canceled)
+}
+
+func TestImportJSON(t *testing.T) {
+	src := New()
+	ctx := context.Background()
+
+	created := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	from, err := src.CreateEntity(ctx, &types.Entity{
+		CreatedAt: created,
+		LastSeen:  created.Add(time.Hour),
+		Asset:     &dns.FQDN{Name: "owasp.org"},
+	})
+	assert.NoError(t, err)
+	to, err := src.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+
+	edge, err := src.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	_, err = src.CreateEntityProperty(ctx, from, &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	_, err = src.CreateEdgeProperty(ctx, edge, &general.SimpleProperty{PropertyName: "test", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, src.ExportJSON(ctx, &buf))
+	export := buf.String()
+
+	// the destination holds an entity before the import, so the IDs are remapped
+	dst := New()
+	existing, err := dst.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+
+	stats, err := dst.ImportJSON(ctx, strings.NewReader(export))
+	assert.NoError(t, err)
+	assert.Equal(t, types.ImportStats{
+		EntitiesCreated:   1,
+		EntitiesSkipped:   1,
+		EdgesCreated:      1,
+		EntityTagsCreated: 1,
+		EdgeTagsCreated:   1,
+	}, stats)
+
+	entities, err := dst.FindEntitiesByContent(ctx, &dns.FQDN{Name: "owasp.org"}, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, entities, 1) {
+		assert.True(t, created.Equal(entities[0].CreatedAt))
+		assert.True(t, created.Add(time.Hour).Equal(entities[0].LastSeen))
+
+		edges, err := dst.OutgoingEdges(ctx, entities[0], time.Time{})
+		assert.NoError(t, err)
+		if assert.Len(t, edges, 1) {
+			assert.Equal(t, existing.ID, edges[0].ToEntity.ID)
+
+			tags, err := dst.GetEdgeTags(ctx, edges[0], time.Time{})
+			assert.NoError(t, err)
+			assert.Len(t, tags, 1)
+		}
+	}
+
+	// a repeated import does not duplicate the records
+	stats, err = dst.ImportJSON(ctx, strings.NewReader(export))
+	assert.NoError(t, err)
+	assert.Equal(t, types.ImportStats{
+		EntitiesSkipped:   2,
+		EdgesSkipped:      1,
+		EntityTagsSkipped: 1,
+		EdgeTagsSkipped:   1,
+	}, stats)
+
+	// the records must refer to the entities that precede them
+	lines := strings.SplitAfter(export, "\n")
+	_, err = New().ImportJSON(ctx, strings.NewReader(lines[2]))
+	assert.Error(t, err)
+	_, err = New().ImportJSON(ctx, strings.NewReader(`{"kind":"unknown"}`))
+	assert.Error(t, err)
+}
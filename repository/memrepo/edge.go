@@ -0,0 +1,490 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// CreateEdge creates an edge between two entities in the repository.
+// An edge with the same entities, relation type, and label is not duplicated, and has its relation and last seen time updated.
+// Returns the created edge as a types.Edge or an error if the link creation fails.
+func (m *memRepository) CreateEdge(ctx context.Context, input *types.Edge) (*types.Edge, error) {
+	if input == nil || input.Relation == nil || input.FromEntity == nil ||
+		input.FromEntity.Asset == nil || input.ToEntity == nil || input.ToEntity.Asset == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	if !oam.ValidRelationship(input.FromEntity.Asset.AssetType(),
+		input.Relation.Label(), input.Relation.RelationType(), input.ToEntity.Asset.AssetType()) {
+		return &types.Edge{}, fmt.Errorf("%s -%s-> %s is not valid in the taxonomy",
+			input.FromEntity.Asset.AssetType(), input.Relation.Label(), input.ToEntity.Asset.AssetType())
+	}
+
+	e, _, err := m.upsertEdge(input)
+	return e, err
+}
+
+// UpsertEdge creates the edge in the repository, or updates the relation and last seen time of the existing edge
+// with the same entities, relation type, and label.
+// Returns the edge as a types.Edge, the outcome of the upsert, or an error if the upsert fails.
+func (m *memRepository) UpsertEdge(ctx context.Context, input *types.Edge) (*types.Edge, types.UpsertResult, error) {
+	if input == nil || input.Relation == nil || input.FromEntity == nil ||
+		input.FromEntity.Asset == nil || input.ToEntity == nil || input.ToEntity.Asset == nil {
+		return nil, "", errors.New("failed input validation checks")
+	}
+
+	if !oam.ValidRelationship(input.FromEntity.Asset.AssetType(),
+		input.Relation.Label(), input.Relation.RelationType(), input.ToEntity.Asset.AssetType()) {
+		return nil, "", fmt.Errorf("%s -%s-> %s is not valid in the taxonomy",
+			input.FromEntity.Asset.AssetType(), input.Relation.Label(), input.ToEntity.Asset.AssetType())
+	}
+	return m.upsertEdge(input)
+}
+
+// upsertEdge writes the edge that has passed the input validation checks, and reports the outcome of the upsert.
+func (m *memRepository) upsertEdge(input *types.Edge) (*types.Edge, types.UpsertResult, error) {
+	fromEntityId, err := parseID(input.FromEntity.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	toEntityId, err := parseID(input.ToEntity.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// both entities must exist, and must not have been soft-deleted
+	if e, found := m.data.entities[fromEntityId]; !found || !e.DeletedAt.IsZero() {
+		return nil, "", types.EdgeEntityNotFound("from", input.FromEntity.ID)
+	}
+	if e, found := m.data.entities[toEntityId]; !found || !e.DeletedAt.IsZero() {
+		return nil, "", types.EdgeEntityNotFound("to", input.ToEntity.ID)
+	}
+
+	updated := input.LastSeen
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+
+	// ensure that duplicate relationships are not entered into the repository
+	for _, e := range m.data.edges {
+		if e.FromEntityID == fromEntityId && e.ToEntityID == toEntityId &&
+			e.Relation.RelationType() == input.Relation.RelationType() && e.Relation.Label() == input.Relation.Label() {
+			result := types.UpsertUnchanged
+			if !sameContent(e.Relation, input.Relation) {
+				result = types.UpsertUpdated
+			}
+			e.Relation = input.Relation
+			e.UpdatedAt = updated
+			return e.toEdge(), result, nil
+		}
+	}
+
+	e := &edge{
+		ID:           m.data.newID(),
+		CreatedAt:    input.CreatedAt,
+		UpdatedAt:    updated,
+		Relation:     input.Relation,
+		FromEntityID: fromEntityId,
+		ToEntityID:   toEntityId,
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	m.data.edges[e.ID] = e
+	return e.toEdge(), types.UpsertCreated, nil
+}
+
+// FindEdgeById finds an edge in the repository by the ID.
+// Returns the found edge as a types.Edge or an error if the edge is not found.
+func (m *memRepository) FindEdgeById(ctx context.Context, id string) (*types.Edge, error) {
+	edgeId, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if e, found := m.data.edges[edgeId]; found && m.liveEdge(e) {
+		return e.toEdge(), nil
+	}
+	return nil, types.NotFound("edge not found")
+}
+
+// IncomingEdges finds all edges pointing to the entity of the specified labels and last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// If no labels are specified, all incoming edges are returned.
+func (m *memRepository) IncomingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	return m.IncomingEdgesBetween(ctx, entity, since, time.Time{}, labels...)
+}
+
+// IncomingEdgesBetween finds all edges pointing to the entity of the specified labels and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no labels are specified, all incoming edges in the window are returned.
+func (m *memRepository) IncomingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	entityId, err := parseID(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findEdges(func(e *edge) bool { return e.ToEntityID == entityId }, from, to, labels)
+}
+
+// OutgoingEdges finds all edges from the entity of the specified labels and last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// If no labels are specified, all outgoing edges are returned.
+func (m *memRepository) OutgoingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	return m.OutgoingEdgesBetween(ctx, entity, since, time.Time{}, labels...)
+}
+
+// OutgoingEdgesBetween finds all edges from the entity of the specified labels and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no labels are specified, all outgoing edges in the window are returned.
+func (m *memRepository) OutgoingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	entityId, err := parseID(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findEdges(func(e *edge) bool { return e.FromEntityID == entityId }, from, to, labels)
+}
+
+// IncomingEdgesForAll finds the edges pointing to any of the entities of the specified labels and last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// If no labels are specified, all incoming edges are returned.
+// Returns the edges keyed by the ID of the entity they point to, which leaves out the entities without edges.
+func (m *memRepository) IncomingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return m.edgesForAll(entities, since, labels, true)
+}
+
+// OutgoingEdgesForAll finds the edges from any of the entities of the specified labels and last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// If no labels are specified, all outgoing edges are returned.
+// Returns the edges keyed by the ID of the entity they come from, which leaves out the entities without edges.
+func (m *memRepository) OutgoingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return m.edgesForAll(entities, since, labels, false)
+}
+
+// edgesForAll finds the edges pointing to any of the entities when incoming is true, or from any of them otherwise,
+// and groups the edges by the ID of that entity.
+func (m *memRepository) edgesForAll(entities []*types.Entity, since time.Time, labels []string, incoming bool) (map[string][]*types.Edge, error) {
+	if len(entities) == 0 {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	ids := make(map[uint64]struct{}, len(entities))
+	for _, entity := range entities {
+		if entity == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+
+		id, err := parseID(entity.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids[id] = struct{}{}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	edges, err := m.findEdges(func(e *edge) bool {
+		id := e.FromEntityID
+		if incoming {
+			id = e.ToEntityID
+		}
+		_, found := ids[id]
+		return found
+	}, since, time.Time{}, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string][]*types.Edge)
+	for _, e := range edges {
+		key := e.FromEntity.ID
+		if incoming {
+			key = e.ToEntity.ID
+		}
+		results[key] = append(results[key], e)
+	}
+	return results, nil
+}
+
+// Neighborhood finds the entities reachable from the entity by following up to maxDepth outgoing edges of the
+// specified labels and last seen after the since parameter. The graph is traversed breadth-first, and each entity
+// is visited once, so the traversal does not follow cycles.
+// If since.IsZero(), the parameter will be ignored.
+// If no labels are specified, all outgoing edges are followed.
+// Returns the reachable entities, excluding the provided entity, and the edges that connect them, or an error if the traversal fails.
+func (m *memRepository) Neighborhood(ctx context.Context, entity *types.Entity, maxDepth int, since time.Time, labels ...string) ([]*types.Entity, []*types.Edge, error) {
+	if entity == nil {
+		return nil, nil, errors.New("failed input validation checks")
+	}
+	if maxDepth < 1 {
+		return nil, nil, errors.New("the maximum depth must be at least one")
+	}
+
+	entityId, err := parseID(entity.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entities []*types.Entity
+	var results []*types.Edge
+	frontier := []uint64{entityId}
+	visited := map[uint64]struct{}{entityId: {}}
+
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		from := make(map[uint64]struct{}, len(frontier))
+		for _, id := range frontier {
+			from[id] = struct{}{}
+		}
+
+		var next []uint64
+		for _, id := range sortedIDs(m.data.edges) {
+			e := m.data.edges[id]
+			if _, found := from[e.FromEntityID]; !found || !m.liveEdge(e) || !seenSince(e.UpdatedAt, since) {
+				continue
+			}
+
+			found := len(labels) == 0
+			for _, label := range labels {
+				if label == e.Relation.Label() {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+			results = append(results, e.toEdge())
+
+			if _, found := visited[e.ToEntityID]; !found {
+				visited[e.ToEntityID] = struct{}{}
+				next = append(next, e.ToEntityID)
+				entities = append(entities, m.data.entities[e.ToEntityID].toEntity())
+			}
+		}
+		frontier = next
+	}
+
+	if len(results) == 0 {
+		return nil, nil, types.NotFound("zero edges found")
+	}
+	return entities, results, nil
+}
+
+// FindEdgesByLabel finds the edges in the repository with the provided label and last seen after the since parameter,
+// regardless of the entities they connect. If since.IsZero(), the parameter will be ignored.
+// The FromEntity and ToEntity fields of each edge only hold the entity IDs.
+// Returns a slice of matching edges as []*types.Edge or an error if the search fails.
+func (m *memRepository) FindEdgesByLabel(ctx context.Context, label string, since time.Time) ([]*types.Edge, error) {
+	if label == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findEdges(func(e *edge) bool { return true }, since, time.Time{}, []string{label})
+}
+
+// FindEdgesSince finds the edges in the repository last seen after the since parameter, which includes the edges
+// created or updated since then. If since.IsZero(), all the edges are returned. When includeTags is true, the Tags
+// field of each edge holds its tags, excluding the expired tags as GetEdgeTags does.
+// The FromEntity and ToEntity fields of each edge only hold the entity IDs.
+// Returns the edges ordered by ID, or an error matching types.ErrNotFound if no edges are found.
+func (m *memRepository) FindEdgesSince(ctx context.Context, since time.Time, includeTags bool) ([]*types.Edge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results, err := m.findEdges(func(e *edge) bool { return true }, since, time.Time{}, nil)
+	if err != nil || !includeTags {
+		return results, err
+	}
+
+	for _, edge := range results {
+		edgeId, err := parseID(edge.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range getTags(m.data.edgeTags, edgeId, time.Time{}, time.Time{}, nil, m.expiredTags) {
+			edge.Tags = append(edge.Tags, t.toEdgeTag(edge))
+		}
+	}
+	return results, nil
+}
+
+// ResolveEdgeEndpoints finds the distinct entities referenced by the FromEntity and ToEntity fields of the edges.
+// The IDs that do not match a live entity are omitted.
+// Returns the entities keyed by their IDs, or an error if the edges are invalid.
+func (m *memRepository) ResolveEdgeEndpoints(ctx context.Context, edges []*types.Edge) (map[string]*types.Entity, error) {
+	for _, edge := range edges {
+		if edge == nil || edge.FromEntity == nil || edge.ToEntity == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make(map[string]*types.Entity)
+	for _, edge := range edges {
+		for _, id := range []string{edge.FromEntity.ID, edge.ToEntity.ID} {
+			if _, found := results[id]; found {
+				continue
+			}
+
+			// an invalid ID cannot match an entity
+			if entityId, err := parseID(id); err == nil {
+				if e, found := m.data.entities[entityId]; found && e.DeletedAt.IsZero() {
+					results[id] = e.toEntity()
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// CountEdges counts the edges in the repository last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the number of matching edges or an error if the count fails.
+func (m *memRepository) CountEdges(ctx context.Context, since time.Time) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var total int64
+	for _, e := range m.data.edges {
+		if m.liveEdge(e) && seenSince(e.UpdatedAt, since) {
+			total++
+		}
+	}
+	return total, nil
+}
+
+// EntityDegree counts the edges pointing to and leaving the entity that were last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the number of incoming and outgoing edges, which are zero for an entity without edges.
+func (m *memRepository) EntityDegree(ctx context.Context, entity *types.Entity, since time.Time) (int64, int64, error) {
+	if entity == nil {
+		return 0, 0, errors.New("failed input validation checks")
+	}
+
+	entityId, err := parseID(entity.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var in, out int64
+	for _, e := range m.data.edges {
+		if !m.liveEdge(e) || !seenSince(e.UpdatedAt, since) {
+			continue
+		}
+		if e.ToEntityID == entityId {
+			in++
+		}
+		if e.FromEntityID == entityId {
+			out++
+		}
+	}
+	return in, out, nil
+}
+
+// DeleteEdge removes an edge in the repository by its ID, along with the tags of the edge.
+// Returns one when the edge is removed, or an error if the edge is not found.
+func (m *memRepository) DeleteEdge(ctx context.Context, id string) (int64, error) {
+	edgeId, err := parseID(id)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.data.edges[edgeId]; !found {
+		return 0, types.NotFound("edge not found")
+	}
+
+	m.removeEdge(edgeId)
+	return 1, nil
+}
+
+// findEdges returns the live edges accepted by the filter, with one of the labels and last seen within the [from, to) window.
+func (m *memRepository) findEdges(filter func(e *edge) bool, from, to time.Time, labels []string) ([]*types.Edge, error) {
+	var results []*types.Edge
+
+	for _, id := range sortedIDs(m.data.edges) {
+		e := m.data.edges[id]
+		if !m.liveEdge(e) || !filter(e) || !seenBetween(e.UpdatedAt, from, to) {
+			continue
+		}
+
+		found := len(labels) == 0
+		for _, label := range labels {
+			if label == e.Relation.Label() {
+				found = true
+				break
+			}
+		}
+
+		if found {
+			results = append(results, e.toEdge())
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero edges found")
+	}
+	return results, nil
+}
+
+// liveEdge reports whether both entities of the edge are live, so that the edges attached
+// to soft-deleted entities are excluded from the results.
+func (m *memRepository) liveEdge(e *edge) bool {
+	from, found := m.data.entities[e.FromEntityID]
+	if !found || !from.DeletedAt.IsZero() {
+		return false
+	}
+
+	to, found := m.data.entities[e.ToEntityID]
+	return found && to.DeletedAt.IsZero()
+}
+
+// removeEdge deletes the edge along with its tags.
+func (m *memRepository) removeEdge(id uint64) {
+	delete(m.data.edges, id)
+
+	for tid, t := range m.data.edgeTags {
+		if t.OwnerID == id {
+			delete(m.data.edgeTags, tid)
+		}
+	}
+}
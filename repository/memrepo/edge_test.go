@@ -0,0 +1,450 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/options"
+	"github.com/garthoid/asset-db/types"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEdges(t *testing.T) {
+	m := New(options.WithSoftDelete())
+	ctx := context.Background()
+
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+
+	_, err = m.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "invalid"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.Error(t, err)
+
+	edge, err := m.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	// duplicate relationships update the existing edge
+	dup, err := m.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, edge.ID, dup.ID)
+
+	found, err := m.FindEdgeById(ctx, edge.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, to.ID, found.ToEntity.ID)
+
+	outs, err := m.OutgoingEdges(ctx, from, time.Time{}, "node")
+	assert.NoError(t, err)
+	assert.Len(t, outs, 1)
+	_, err = m.OutgoingEdges(ctx, from, time.Time{}, "dns_record")
+	assert.Error(t, err)
+	_, err = m.IncomingEdges(ctx, to, time.Now().Add(time.Minute))
+	assert.Error(t, err)
+	ins, err := m.IncomingEdges(ctx, to, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, ins, 1)
+
+	in, out, err := m.EntityDegree(ctx, from, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 1}, []int64{in, out})
+	in, out, err = m.EntityDegree(ctx, to, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 0}, []int64{in, out})
+	in, out, err = m.EntityDegree(ctx, to, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 0}, []int64{in, out})
+
+	// the edges attached to soft-deleted entities are excluded
+	_, err = m.DeleteEntity(ctx, to.ID)
+	assert.NoError(t, err)
+	count, err := m.CountEdges(ctx, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+	_, out, err = m.EntityDegree(ctx, from, time.Time{})
+	assert.NoError(t, err)
+	assert.Zero(t, out)
+	_, err = m.FindEdgeById(ctx, edge.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	_, err = m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	n, err := m.DeleteEdge(ctx, edge.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = m.DeleteEdge(ctx, edge.ID)
+	assert.Error(t, err)
+}
+
+func TestUpsertEdge(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+
+	cname := func(ttl int) *types.Edge {
+		return &types.Edge{
+			Relation: &dns.BasicDNSRelation{
+				Name:   "dns_record",
+				Header: dns.RRHeader{RRType: 5, Class: 1, TTL: ttl},
+			},
+			FromEntity: from,
+			ToEntity:   to,
+		}
+	}
+
+	first, result, err := m.UpsertEdge(ctx, cname(3600))
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertCreated, result)
+
+	// the edge with the same entities, relation type, and label is updated rather than duplicated
+	second, result, err := m.UpsertEdge(ctx, cname(300))
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUpdated, result)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, 300, second.Relation.(*dns.BasicDNSRelation).Header.TTL)
+
+	_, result, err = m.UpsertEdge(ctx, cname(300))
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUnchanged, result)
+
+	third, err := m.CreateEdge(ctx, cname(60))
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, third.ID)
+	count, err := m.CountEdges(ctx, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	_, _, err = m.UpsertEdge(ctx, &types.Edge{Relation: general.SimpleRelation{Name: "invalid"}, FromEntity: from, ToEntity: to})
+	assert.Error(t, err)
+}
+
+func TestCreateEdgeMissingEntity(t *testing.T) {
+	m := New(options.WithSoftDelete())
+	ctx := context.Background()
+
+	entity, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	missing := &types.Entity{ID: "999", Asset: &dns.FQDN{Name: "www.owasp.org"}}
+
+	_, err = m.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: missing,
+		ToEntity:   entity,
+	})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	assert.ErrorContains(t, err, "the from entity 999")
+
+	_, err = m.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: entity,
+		ToEntity:   missing,
+	})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+	assert.ErrorContains(t, err, "the to entity 999")
+
+	// a soft-deleted entity cannot be the end of a new edge
+	deleted, err := m.CreateAsset(ctx, &dns.FQDN{Name: "docs.owasp.org"})
+	assert.NoError(t, err)
+	_, err = m.DeleteEntity(ctx, deleted.ID)
+	assert.NoError(t, err)
+	_, err = m.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: entity,
+		ToEntity:   deleted,
+	})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+}
+
+func TestNeighborhood(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	// a -> b -> c -> a forms a cycle, and b -> d is a branch
+	entities := make(map[string]*types.Entity)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		e, err := m.CreateAsset(ctx, &dns.FQDN{Name: name + ".neighborhood.example.com"})
+		assert.NoError(t, err)
+		entities[name] = e
+	}
+	for _, pair := range [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}, {"b", "d"}} {
+		_, err := m.CreateEdge(ctx, &types.Edge{
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: entities[pair[0]],
+			ToEntity:   entities[pair[1]],
+		})
+		assert.NoError(t, err)
+	}
+
+	reached, edges, err := m.Neighborhood(ctx, entities["a"], 1, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 1)
+	if assert.Len(t, reached, 1) {
+		assert.Equal(t, entities["b"].ID, reached[0].ID)
+	}
+
+	reached, edges, err = m.Neighborhood(ctx, entities["a"], 2, time.Time{}, "node")
+	assert.NoError(t, err)
+	assert.Len(t, edges, 3)
+	assert.Len(t, reached, 3)
+
+	// the edge back to the first entity is returned, but the entity is not revisited
+	reached, edges, err = m.Neighborhood(ctx, entities["a"], 5, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 4)
+	assert.Len(t, reached, 3)
+	for _, e := range reached {
+		assert.NotEqual(t, entities["a"].ID, e.ID)
+	}
+
+	_, _, err = m.Neighborhood(ctx, entities["a"], 2, time.Time{}, "dns_record")
+	assert.Error(t, err)
+	_, _, err = m.Neighborhood(ctx, entities["a"], 2, time.Now().Add(time.Minute))
+	assert.Error(t, err)
+	_, _, err = m.Neighborhood(ctx, entities["d"], 2, time.Time{})
+	assert.Error(t, err)
+	_, _, err = m.Neighborhood(ctx, entities["a"], 0, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestEdgesForAll(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	// a -> b, a -> c, and b -> c, while d has no edges
+	entities := make(map[string]*types.Entity)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		e, err := m.CreateAsset(ctx, &dns.FQDN{Name: name + ".forall.example.com"})
+		assert.NoError(t, err)
+		entities[name] = e
+	}
+	for _, pair := range [][2]string{{"a", "b"}, {"a", "c"}, {"b", "c"}} {
+		_, err := m.CreateEdge(ctx, &types.Edge{
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: entities[pair[0]],
+			ToEntity:   entities[pair[1]],
+		})
+		assert.NoError(t, err)
+	}
+
+	// the entity without edges is left out, and a repeated entity is only counted once
+	outgoing, err := m.OutgoingEdgesForAll(ctx, []*types.Entity{entities["a"], entities["b"], entities["d"], entities["a"]}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, outgoing, 2)
+	assert.Len(t, outgoing[entities["a"].ID], 2)
+	if assert.Len(t, outgoing[entities["b"].ID], 1) {
+		assert.Equal(t, entities["c"].ID, outgoing[entities["b"].ID][0].ToEntity.ID)
+	}
+
+	incoming, err := m.IncomingEdgesForAll(ctx, []*types.Entity{entities["b"], entities["c"]}, time.Time{}, "node")
+	assert.NoError(t, err)
+	assert.Len(t, incoming, 2)
+	assert.Len(t, incoming[entities["c"].ID], 2)
+	if assert.Len(t, incoming[entities["b"].ID], 1) {
+		assert.Equal(t, entities["a"].ID, incoming[entities["b"].ID][0].FromEntity.ID)
+	}
+
+	_, err = m.OutgoingEdgesForAll(ctx, []*types.Entity{entities["a"]}, time.Time{}, "dns_record")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = m.IncomingEdgesForAll(ctx, []*types.Entity{entities["a"], entities["d"]}, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = m.OutgoingEdgesForAll(ctx, []*types.Entity{entities["a"]}, time.Now().Add(time.Minute))
+	assert.Error(t, err)
+	_, err = m.OutgoingEdgesForAll(ctx, nil, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestFindEdgesByLabel(t *testing.T) {
+	m := New(options.WithSoftDelete())
+	ctx := context.Background()
+
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	cname, err := m.CreateAsset(ctx, &dns.FQDN{Name: "cname.owasp.org"})
+	assert.NoError(t, err)
+
+	_, err = m.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	record, err := m.CreateEdge(ctx, &types.Edge{
+		Relation:   dns.BasicDNSRelation{Name: "dns_record", Header: dns.RRHeader{RRType: 5, Class: 1}},
+		FromEntity: cname,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	_, err = m.FindEdgesByLabel(ctx, "", time.Time{})
+	assert.Error(t, err)
+
+	edges, err := m.FindEdgesByLabel(ctx, "dns_record", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 1)
+	assert.Equal(t, record.ID, edges[0].ID)
+	assert.Equal(t, cname.ID, edges[0].FromEntity.ID)
+	assert.Equal(t, to.ID, edges[0].ToEntity.ID)
+
+	_, err = m.FindEdgesByLabel(ctx, "dns_record", time.Now().Add(time.Minute))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	// the edges attached to soft-deleted entities are excluded
+	_, err = m.DeleteEntity(ctx, cname.ID)
+	assert.NoError(t, err)
+	_, err = m.FindEdgesByLabel(ctx, "dns_record", time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestFindEdgesSince(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+
+	old, err := m.CreateEdge(ctx, &types.Edge{
+		LastSeen:   time.Now().Add(-time.Hour),
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	record, err := m.CreateEdge(ctx, &types.Edge{
+		Relation:   dns.BasicDNSRelation{Name: "dns_record", Header: dns.RRHeader{RRType: 5, Class: 1}},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	tag, err := m.CreateEdgeProperty(ctx, record, &general.SimpleProperty{PropertyName: "source", PropertyValue: "dns"})
+	assert.NoError(t, err)
+	_, err = m.CreateEdgeTag(ctx, record, &types.EdgeTag{
+		ExpiresAt: time.Now().Add(-time.Minute),
+		Property:  &general.SimpleProperty{PropertyName: "expired", PropertyValue: "dns"},
+	})
+	assert.NoError(t, err)
+
+	edges, err := m.FindEdgesSince(ctx, time.Time{}, false)
+	assert.NoError(t, err)
+	if assert.Len(t, edges, 2) {
+		assert.Equal(t, old.ID, edges[0].ID)
+		assert.Nil(t, edges[1].Tags)
+	}
+
+	// the tags are loaded along with the edges, excluding the expired tags
+	edges, err = m.FindEdgesSince(ctx, time.Now().Add(-time.Minute), true)
+	assert.NoError(t, err)
+	if assert.Len(t, edges, 1) {
+		assert.Equal(t, record.ID, edges[0].ID)
+		assert.Equal(t, from.ID, edges[0].FromEntity.ID)
+		if assert.Len(t, edges[0].Tags, 1) {
+			assert.Equal(t, tag.ID, edges[0].Tags[0].ID)
+			assert.Equal(t, record.ID, edges[0].Tags[0].Edge.ID)
+		}
+	}
+
+	_, err = m.FindEdgesSince(ctx, time.Now().Add(time.Minute), true)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestResolveEdgeEndpoints(t *testing.T) {
+	m := New(options.WithSoftDelete())
+	ctx := context.Background()
+
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	other, err := m.CreateAsset(ctx, &dns.FQDN{Name: "api.owasp.org"})
+	assert.NoError(t, err)
+
+	for _, e := range []*types.Entity{to, other} {
+		_, err := m.CreateEdge(ctx, &types.Edge{
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: from,
+			ToEntity:   e,
+		})
+		assert.NoError(t, err)
+	}
+
+	_, err = m.ResolveEdgeEndpoints(ctx, []*types.Edge{nil})
+	assert.Error(t, err)
+
+	edges, err := m.OutgoingEdges(ctx, from, time.Time{})
+	assert.NoError(t, err)
+	entities, err := m.ResolveEdgeEndpoints(ctx, edges)
+	assert.NoError(t, err)
+	assert.Len(t, entities, 3)
+	assert.Equal(t, from.Asset, entities[from.ID].Asset)
+	assert.Equal(t, to.Asset, entities[to.ID].Asset)
+	assert.Equal(t, other.Asset, entities[other.ID].Asset)
+
+	// the soft-deleted entities are omitted
+	_, err = m.DeleteEntity(ctx, other.ID)
+	assert.NoError(t, err)
+	entities, err = m.ResolveEdgeEndpoints(ctx, edges)
+	assert.NoError(t, err)
+	assert.Len(t, entities, 2)
+	assert.NotContains(t, entities, other.ID)
+}
+
+func TestEdgesBetween(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"www.owasp.org", "docs.owasp.org"} {
+		to, err := m.CreateAsset(ctx, &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+
+		_, err = m.CreateEdge(ctx, &types.Edge{
+			LastSeen:   start.Add(time.Duration(i) * time.Hour),
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: from,
+			ToEntity:   to,
+		})
+		assert.NoError(t, err)
+	}
+
+	outs, err := m.OutgoingEdgesBetween(ctx, from, start, start.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, outs, 1)
+	outs, err = m.OutgoingEdgesBetween(ctx, from, start, time.Time{}, "node")
+	assert.NoError(t, err)
+	assert.Len(t, outs, 2)
+	_, err = m.OutgoingEdgesBetween(ctx, from, start, time.Time{}, "dns_record")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	ins, err := m.IncomingEdgesBetween(ctx, outs[1].ToEntity, start.Add(time.Hour), start.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, ins, 1)
+	_, err = m.IncomingEdgesBetween(ctx, outs[1].ToEntity, start, start.Add(time.Hour))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
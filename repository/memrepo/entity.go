@@ -0,0 +1,895 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/garthoid/asset-db/internal/jsoncmp"
+	"github.com/garthoid/asset-db/options"
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// CreateEntity creates a new entity in the repository.
+// An entity with the same asset type and identifying content is updated rather than duplicated,
+// and a soft-deleted match is restored. An ID provided by the caller that is held by another asset,
+// or an asset that already exists with another ID, returns an error matching types.ErrDuplicate.
+// Returns the created entity as a types.Entity or an error if the creation fails.
+func (m *memRepository) CreateEntity(ctx context.Context, input *types.Entity) (*types.Entity, error) {
+	if input == nil || input.Asset == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+	if err := m.validateAsset(input.Asset); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, err := m.createEntity(input)
+	if err != nil {
+		return nil, err
+	}
+	return e.toEntity(), nil
+}
+
+func (m *memRepository) createEntity(input *types.Entity) (*entity, error) {
+	now := time.Now()
+
+	if input.ID != "" {
+		// If the entity ID is set, it means that the entity was previously created
+		// in the repository, and we need to update that entity
+		id, err := parseID(input.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		e, found := m.data.entities[id]
+		if found && !sameAsset(e.Asset, input.Asset) {
+			return nil, types.Duplicate("the entity ID is held by another asset")
+		} else if !found && (m.findEntity(input.Asset) != nil || m.findDeletedEntity(input.Asset) != nil) {
+			return nil, types.Duplicate("the asset already exists with another entity ID")
+		}
+
+		if !found {
+			e = &entity{ID: id, CreatedAt: now}
+			m.data.entities[id] = e
+			if id > m.data.nextID {
+				m.data.nextID = id
+			}
+		}
+		if !input.CreatedAt.IsZero() {
+			e.CreatedAt = input.CreatedAt
+		}
+		e.UpdatedAt = now
+		e.DeletedAt = time.Time{}
+		e.Asset = input.Asset
+		return e, nil
+	}
+
+	if e := m.findEntity(input.Asset); e != nil {
+		// ensure that duplicate entities are not entered into the repository
+		e.UpdatedAt = now
+		e.Asset = input.Asset
+		return e, nil
+	} else if e := m.findDeletedEntity(input.Asset); e != nil {
+		// a soft-deleted entity with matching content is restored rather than duplicated
+		e.UpdatedAt = now
+		e.DeletedAt = time.Time{}
+		e.Asset = input.Asset
+		return e, nil
+	}
+
+	id, err := m.newEntityID()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &entity{
+		ID:        id,
+		CreatedAt: input.CreatedAt,
+		UpdatedAt: input.LastSeen,
+		Asset:     input.Asset,
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = now
+	}
+	if e.UpdatedAt.IsZero() {
+		e.UpdatedAt = now
+	}
+
+	m.data.entities[e.ID] = e
+	return e, nil
+}
+
+// newEntityID returns the ID of a new entity, which is generated by options.WithIDGenerator when it is provided.
+// The IDs of the memory repository are integers, so a generated ID must be a positive decimal integer that is
+// not held by another entity.
+func (m *memRepository) newEntityID() (uint64, error) {
+	if m.generateID == nil {
+		return m.data.newID(), nil
+	}
+
+	id, err := parseID(m.generateID())
+	if err != nil || id == 0 {
+		return 0, errors.New("the generated entity ID must be a positive decimal integer")
+	}
+	if _, found := m.data.entities[id]; found {
+		return 0, types.Duplicate("the generated entity ID is held by another entity")
+	}
+	// the IDs assigned to the edges and tags remain larger than the generated IDs
+	if id > m.data.nextID {
+		m.data.nextID = id
+	}
+	return id, nil
+}
+
+// CreateAsset creates a new entity in the repository.
+// It takes an oam.Asset as input and persists it in the repository.
+// Returns the created entity as a types.Entity or an error if the creation fails.
+func (m *memRepository) CreateAsset(ctx context.Context, asset oam.Asset) (*types.Entity, error) {
+	return m.CreateEntity(ctx, &types.Entity{Asset: asset})
+}
+
+// CreateEntities creates the provided entities in the repository as a single operation.
+// Returns the created entities in the same order as the input or an error if the creation fails.
+func (m *memRepository) CreateEntities(ctx context.Context, inputs []*types.Entity) ([]*types.Entity, error) {
+	for _, input := range inputs {
+		if input == nil || input.Asset == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+		if err := m.validateAsset(input.Asset); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the entity IDs are validated before any changes are made, so that a failure leaves the repository untouched
+	for _, input := range inputs {
+		if input.ID != "" {
+			if _, err := parseID(input.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	results := make([]*types.Entity, len(inputs))
+	for i, input := range inputs {
+		e, err := m.createEntity(input)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = e.toEntity()
+	}
+	return results, nil
+}
+
+// UpsertEntity creates the entity in the repository, or updates the last seen time of the existing entity
+// with the same asset type and identifying content, along with its content when the asset differs.
+// Returns the entity as a types.Entity, the outcome of the upsert, or an error if the upsert fails.
+func (m *memRepository) UpsertEntity(ctx context.Context, input *types.Entity) (*types.Entity, types.UpsertResult, error) {
+	if input == nil || input.Asset == nil {
+		return nil, "", errors.New("failed input validation checks")
+	}
+	if err := m.validateAsset(input.Asset); err != nil {
+		return nil, "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e := m.findEntity(input.Asset); e != nil {
+		result := types.UpsertUnchanged
+		if !sameContent(e.Asset, input.Asset) {
+			e.Asset = input.Asset
+			result = types.UpsertUpdated
+		}
+		e.UpdatedAt = time.Now()
+		return e.toEntity(), result, nil
+	}
+
+	// a restored entity is not reported as newly created
+	result := types.UpsertCreated
+	if m.findDeletedEntity(input.Asset) != nil {
+		result = types.UpsertUpdated
+	}
+	e, err := m.createEntity(&types.Entity{
+		CreatedAt: input.CreatedAt,
+		LastSeen:  input.LastSeen,
+		Asset:     input.Asset,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return e.toEntity(), result, nil
+}
+
+// TouchEntity sets the last seen time of the entity in the repository to the current time,
+// which records that the asset was observed again without creating a duplicate entity or tag.
+// Returns an error matching types.ErrNotFound if the entity is not found.
+func (m *memRepository) TouchEntity(ctx context.Context, id string) error {
+	entityId, err := parseID(id)
+	if err != nil {
+		// an invalid ID cannot match an entity
+		return types.NotFound("entity not found")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, found := m.data.entities[entityId]
+	if !found || !e.DeletedAt.IsZero() {
+		return types.NotFound("entity not found")
+	}
+
+	e.UpdatedAt = time.Now()
+	return nil
+}
+
+// UpdateEntity replaces the asset of the entity in the repository and sets the last seen time to the current time,
+// while the ID, the creation time, and the edges and tags of the entity are kept. The asset must be of the asset type
+// of the entity, and an asset held by another entity, including a soft-deleted one, returns an error matching
+// types.ErrDuplicate. Returns an error matching types.ErrNotFound if the entity is not found.
+func (m *memRepository) UpdateEntity(ctx context.Context, id string, asset oam.Asset) (*types.Entity, error) {
+	if asset == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+	if err := m.validateAsset(asset); err != nil {
+		return nil, err
+	}
+
+	entityId, err := parseID(id)
+	if err != nil {
+		// an invalid ID cannot match an entity
+		return nil, types.ErrEntityNotFound
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, found := m.data.entities[entityId]
+	if !found || !e.DeletedAt.IsZero() {
+		return nil, types.ErrEntityNotFound
+	}
+	if e.Asset.AssetType() != asset.AssetType() {
+		return nil, errors.New("the asset type does not match the existing entity")
+	}
+
+	for oid, other := range m.data.entities {
+		if oid != entityId && (other.DeletedAt.IsZero() || m.softDelete) && sameAsset(other.Asset, asset) {
+			return nil, types.Duplicate("the asset already exists with another entity ID")
+		}
+	}
+
+	e.Asset = asset
+	e.UpdatedAt = time.Now()
+	return e.toEntity(), nil
+}
+
+// FindEntityById finds an entity in the repository by the ID.
+// Returns the found entity as a types.Entity or an error matching types.ErrNotFound if the entity is not found,
+// including when the ID is not valid.
+func (m *memRepository) FindEntityById(ctx context.Context, id string) (*types.Entity, error) {
+	entityId, err := parseID(id)
+	if err != nil {
+		// an invalid ID cannot match an entity
+		return nil, types.NotFound("entity not found")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if e, found := m.data.entities[entityId]; found && e.DeletedAt.IsZero() {
+		return e.toEntity(), nil
+	}
+	return nil, types.NotFound("entity not found")
+}
+
+// FindEntityByHash finds the entity in the repository with the provided content hash, as returned by types.ContentHash.
+// Returns the found entity as a types.Entity or an error matching types.ErrNotFound if no entity has the hash.
+func (m *memRepository) FindEntityByHash(ctx context.Context, hash string) (*types.Entity, error) {
+	if hash == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, id := range sortedIDs(m.data.entities) {
+		if e := m.data.entities[id]; e.DeletedAt.IsZero() && types.ContentHash(e.Asset) == hash {
+			return e.toEntity(), nil
+		}
+	}
+	return nil, types.NotFound("entity not found")
+}
+
+// EntityExists reports whether the repository holds an entity that matches the provided asset data, as matched by
+// FindEntitiesByContent, without copying the entity. Returns false, without an error, if no entity matches the asset.
+func (m *memRepository) EntityExists(ctx context.Context, asset oam.Asset) (bool, error) {
+	if asset == nil {
+		return false, errors.New("failed input validation checks")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.data.entities {
+		if e.DeletedAt.IsZero() && sameAsset(e.Asset, asset) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FindEntitiesByContent finds entities in the repository that match the provided asset data and last seen after
+// the since parameter. The assets are matched by their asset type and identifying content.
+// If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (m *memRepository) FindEntitiesByContent(ctx context.Context, asset oam.Asset, since time.Time) ([]*types.Entity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []*types.Entity
+	for _, id := range sortedIDs(m.data.entities) {
+		if e := m.data.entities[id]; e.DeletedAt.IsZero() && sameAsset(e.Asset, asset) && seenSince(e.UpdatedAt, since) {
+			results = append(results, e.toEntity())
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// FindEntitiesByContents finds the entities in the repository that match the provided assets and were last seen after
+// the since parameter. The assets are matched by their asset type and identifying content.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the matching entities keyed by the types.ContentHash of the asset, where the assets without a matching
+// entity are absent from the map, or an error if the search fails.
+func (m *memRepository) FindEntitiesByContents(ctx context.Context, assets []oam.Asset, since time.Time) (map[string]*types.Entity, error) {
+	wanted := make(map[string]struct{}, len(assets))
+	for _, asset := range assets {
+		if asset == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+		wanted[types.ContentHash(asset)] = struct{}{}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make(map[string]*types.Entity, len(wanted))
+	for _, id := range sortedIDs(m.data.entities) {
+		e := m.data.entities[id]
+		if !e.DeletedAt.IsZero() || !seenSince(e.UpdatedAt, since) {
+			continue
+		}
+
+		key := types.ContentHash(e.Asset)
+		if _, found := wanted[key]; !found {
+			continue
+		}
+		if _, found := results[key]; !found {
+			results[key] = e.toEntity()
+		}
+	}
+	return results, nil
+}
+
+// FindEntitiesByType finds all entities in the repository of the provided asset type and last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (m *memRepository) FindEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	return m.FindEntitiesByTypeBetween(ctx, atype, since, time.Time{})
+}
+
+// FindEntitiesByTypes finds all entities in the repository of the provided asset types and last seen after the since parameter.
+// The entities are ordered by their creation time, with ties broken by the entity ID, so the entities of the different types
+// are interleaved in a stable order. If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching entities as []*types.Entity or an error if no asset types are provided or the search fails.
+func (m *memRepository) FindEntitiesByTypes(ctx context.Context, since time.Time, atypes ...oam.AssetType) ([]*types.Entity, error) {
+	if len(atypes) == 0 {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entities []*entity
+	for _, atype := range atypes {
+		entities = append(entities, m.entitiesBetween(atype, since, time.Time{})...)
+	}
+	sort.SliceStable(entities, func(i, j int) bool {
+		if !entities[i].CreatedAt.Equal(entities[j].CreatedAt) {
+			return entities[i].CreatedAt.Before(entities[j].CreatedAt)
+		}
+		return entities[i].ID < entities[j].ID
+	})
+
+	var results []*types.Entity
+	for i, e := range entities {
+		// an asset type that was provided more than once finds the same entities
+		if i > 0 && entities[i-1].ID == e.ID {
+			continue
+		}
+		results = append(results, e.toEntity())
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("no entities of the specified types")
+	}
+	return results, nil
+}
+
+// FindEntitiesByTypeBetween finds all entities in the repository of the provided asset type and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// The entities are sorted as set by options.WithOrder, or the most recently seen entities are returned first
+// when the repository was created with options.WithLastSeenOrder.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (m *memRepository) FindEntitiesByTypeBetween(ctx context.Context, atype oam.AssetType, from, to time.Time) ([]*types.Entity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entities := m.entitiesBetween(atype, from, to)
+	if less := m.orderLess(entities); less != nil {
+		sort.SliceStable(entities, less)
+	} else if m.lastSeen {
+		sort.SliceStable(entities, func(i, j int) bool {
+			return entities[i].UpdatedAt.After(entities[j].UpdatedAt)
+		})
+	}
+
+	var results []*types.Entity
+	for _, e := range entities {
+		results = append(results, e.toEntity())
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("no entities of the specified type")
+	}
+	return results, nil
+}
+
+// DiffEntities compares the entities in the repository of the provided asset type at the t1 and t2 snapshots, such as
+// the start times of two scans. The added entities were first seen within the [t1, t2) window, and the removed entities
+// were created before t1 and last seen within the window, so they have not been seen since t2.
+// Returns the added and removed entities, or an error if t2 is not after t1.
+func (m *memRepository) DiffEntities(ctx context.Context, atype oam.AssetType, t1, t2 time.Time) ([]*types.Entity, []*types.Entity, error) {
+	if !t2.After(t1) {
+		return nil, nil, errors.New("failed input validation checks")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var added, removed []*types.Entity
+	for _, e := range m.entitiesBetween(atype, t1, time.Time{}) {
+		if !e.CreatedAt.Before(t1) && e.CreatedAt.Before(t2) {
+			added = append(added, e.toEntity())
+		} else if e.CreatedAt.Before(t1) && e.UpdatedAt.Before(t2) {
+			removed = append(removed, e.toEntity())
+		}
+	}
+	return added, removed, nil
+}
+
+// SearchEntities finds the entities in the repository of the provided asset type and last seen after the since parameter,
+// which contain the query string within their serialized content. If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (m *memRepository) SearchEntities(ctx context.Context, atype oam.AssetType, query string, since time.Time) ([]*types.Entity, error) {
+	if query == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []*types.Entity
+	for _, e := range m.entitiesByType(atype, since) {
+		if content, err := e.Asset.JSON(); err == nil && strings.Contains(string(content), query) {
+			results = append(results, e.toEntity())
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// FindEntitiesByTypePaged finds a page of entities in the repository of the provided asset type and last seen after
+// the since parameter. The entities are ordered by creation time and then by ID, so that pages remain stable.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the matching entities within the page, the total number of matching entities, or an error if the search fails.
+func (m *memRepository) FindEntitiesByTypePaged(ctx context.Context, atype oam.AssetType, since time.Time, offset, limit int) ([]*types.Entity, int64, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, 0, errors.New("failed input validation checks")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entities := m.entitiesByType(atype, since)
+	sortByCreation(entities)
+	total := int64(len(entities))
+
+	var results []*types.Entity
+	for i := offset; i < len(entities) && len(results) < limit; i++ {
+		results = append(results, entities[i].toEntity())
+	}
+
+	if len(results) == 0 {
+		return nil, total, types.NotFound("no entities of the specified type")
+	}
+	return results, total, nil
+}
+
+// IterateEntitiesByType returns an iterator over the entities in the repository of the provided asset type
+// and last seen after the since parameter. The entities are ordered by creation time and then by ID.
+// If since.IsZero(), the parameter will be ignored.
+// The iterator visits a snapshot of the entities taken when the call is made.
+func (m *memRepository) IterateEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (types.EntityIterator, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entities := m.entitiesByType(atype, since)
+	sortByCreation(entities)
+
+	snapshot := make([]*types.Entity, 0, len(entities))
+	for _, e := range entities {
+		snapshot = append(snapshot, e.toEntity())
+	}
+	return &entityIterator{ctx: ctx, entities: snapshot}, nil
+}
+
+// CountEntitiesByType counts the entities in the repository of the provided asset type and last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the number of matching entities or an error if the count fails.
+func (m *memRepository) CountEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.entitiesByType(atype, since))), nil
+}
+
+// CountEntitiesGrouped counts the entities in the repository of each asset type and last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the number of matching entities keyed by the asset type, where the types without entities are absent from the map.
+func (m *memRepository) CountEntitiesGrouped(ctx context.Context, since time.Time) (map[oam.AssetType]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[oam.AssetType]int64)
+	for _, e := range m.data.entities {
+		if e.DeletedAt.IsZero() && seenSince(e.UpdatedAt, since) {
+			counts[e.Asset.AssetType()]++
+		}
+	}
+	return counts, nil
+}
+
+// DeleteEntity removes an entity in the repository by its ID.
+// When the soft-delete mode is enabled, the entity is kept as a tombstone. Otherwise, the entity is
+// removed along with its tags and edges, as enforced by the foreign keys of the SQL databases.
+// Returns one when the entity is removed, or an error if the entity is not found.
+func (m *memRepository) DeleteEntity(ctx context.Context, id string) (int64, error) {
+	entityId, err := parseID(id)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, found := m.data.entities[entityId]
+	if !found || !e.DeletedAt.IsZero() {
+		return 0, types.NotFound("entity not found")
+	}
+
+	if m.softDelete {
+		e.DeletedAt = time.Now()
+		return 1, nil
+	}
+
+	m.removeEntity(entityId)
+	return 1, nil
+}
+
+// DeleteEntityCascade permanently removes an entity in the repository by its ID, along with its incoming and
+// outgoing edges, its tags, and the tags of its edges. Unlike DeleteEntity, the entity is removed even when
+// the soft-delete mode is enabled, including when it is already a tombstone.
+// Returns the number of records removed, or an error if the entity is not found.
+func (m *memRepository) DeleteEntityCascade(ctx context.Context, id string) (int64, error) {
+	entityId, err := parseID(id)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.data.entities[entityId]; !found {
+		return 0, types.NotFound("entity not found")
+	}
+
+	total := int64(1)
+	for _, t := range m.data.entityTags {
+		if t.OwnerID == entityId {
+			total++
+		}
+	}
+	for eid, e := range m.data.edges {
+		if e.FromEntityID != entityId && e.ToEntityID != entityId {
+			continue
+		}
+
+		total++
+		for _, t := range m.data.edgeTags {
+			if t.OwnerID == eid {
+				total++
+			}
+		}
+	}
+
+	m.removeEntity(entityId)
+	return total, nil
+}
+
+// DeleteEntitiesByType permanently removes the entities in the repository of the provided asset type that were last seen
+// before the before parameter, along with their edges, their tags, and the tags of their edges.
+// If before.IsZero(), the parameter will be ignored. The soft-deleted entities are left to PurgeDeleted.
+// Returns the number of entities removed.
+func (m *memRepository) DeleteEntitiesByType(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var total int64
+	for _, id := range sortedIDs(m.data.entities) {
+		e := m.data.entities[id]
+		if !e.DeletedAt.IsZero() || e.Asset.AssetType() != atype || (!before.IsZero() && !e.UpdatedAt.Before(before)) {
+			continue
+		}
+
+		m.removeEntity(id)
+		total++
+	}
+	return total, nil
+}
+
+// FindOrphanEntities finds the entities in the repository of the provided asset type and last seen after the since
+// parameter that have no incoming or outgoing edges. An edge to a soft-deleted entity keeps the entity from being
+// an orphan, since the entity it refers to can be restored. If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (m *memRepository) FindOrphanEntities(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	linked := m.linkedEntities()
+	var results []*types.Entity
+	for _, e := range m.entitiesByType(atype, since) {
+		if _, found := linked[e.ID]; !found {
+			results = append(results, e.toEntity())
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("no orphan entities of the specified type")
+	}
+	return results, nil
+}
+
+// DeleteOrphanEntities permanently removes the entities in the repository of the provided asset type that were last seen
+// before the before parameter and have no incoming or outgoing edges, as found by FindOrphanEntities, along with their tags.
+// If before.IsZero(), the parameter will be ignored. The soft-deleted entities are left to PurgeDeleted.
+// Returns the number of entities removed.
+func (m *memRepository) DeleteOrphanEntities(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	linked := m.linkedEntities()
+	var total int64
+	for _, id := range sortedIDs(m.data.entities) {
+		e := m.data.entities[id]
+		if !e.DeletedAt.IsZero() || e.Asset.AssetType() != atype || (!before.IsZero() && !e.UpdatedAt.Before(before)) {
+			continue
+		}
+		if _, found := linked[id]; found {
+			continue
+		}
+
+		m.removeEntity(id)
+		total++
+	}
+	return total, nil
+}
+
+// FindDeletedEntities finds the soft-deleted entities in the repository that were deleted after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// The entities are ordered by the time of deletion, and the DeletedAt field is set on each of them.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (m *memRepository) FindDeletedEntities(ctx context.Context, since time.Time) ([]*types.Entity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entities []*entity
+	for _, id := range sortedIDs(m.data.entities) {
+		if e := m.data.entities[id]; !e.DeletedAt.IsZero() && seenSince(e.DeletedAt, since) {
+			entities = append(entities, e)
+		}
+	}
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		return entities[i].DeletedAt.Before(entities[j].DeletedAt)
+	})
+
+	var results []*types.Entity
+	for _, e := range entities {
+		results = append(results, e.toEntity())
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// PurgeDeleted permanently removes the soft-deleted entities in the repository that were deleted before the before parameter.
+// If before.IsZero(), all the soft-deleted entities are removed.
+// Returns an error if the removal fails.
+func (m *memRepository) PurgeDeleted(ctx context.Context, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range sortedIDs(m.data.entities) {
+		if e := m.data.entities[id]; !e.DeletedAt.IsZero() && (before.IsZero() || e.DeletedAt.Before(before)) {
+			m.removeEntity(id)
+		}
+	}
+	return nil
+}
+
+// findEntity returns the live entity that matches the provided asset, or nil when there is no match.
+func (m *memRepository) findEntity(asset oam.Asset) *entity {
+	for _, id := range sortedIDs(m.data.entities) {
+		if e := m.data.entities[id]; e.DeletedAt.IsZero() && sameAsset(e.Asset, asset) {
+			return e
+		}
+	}
+	return nil
+}
+
+// findDeletedEntity returns the soft-deleted entity that matches the provided asset, or nil when there is no match.
+func (m *memRepository) findDeletedEntity(asset oam.Asset) *entity {
+	if !m.softDelete {
+		return nil
+	}
+
+	for _, id := range sortedIDs(m.data.entities) {
+		if e := m.data.entities[id]; !e.DeletedAt.IsZero() && sameAsset(e.Asset, asset) {
+			return e
+		}
+	}
+	return nil
+}
+
+// entitiesByType returns the live entities of the provided asset type and last seen after the since parameter.
+func (m *memRepository) entitiesByType(atype oam.AssetType, since time.Time) []*entity {
+	return m.entitiesBetween(atype, since, time.Time{})
+}
+
+// entitiesBetween returns the live entities of the provided asset type and last seen within the [from, to) window.
+func (m *memRepository) entitiesBetween(atype oam.AssetType, from, to time.Time) []*entity {
+	var entities []*entity
+
+	for _, id := range sortedIDs(m.data.entities) {
+		if e := m.data.entities[id]; e.DeletedAt.IsZero() && e.Asset.AssetType() == atype && seenBetween(e.UpdatedAt, from, to) {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+// removeEntity deletes the entity along with its tags, its edges, and the tags of those edges.
+func (m *memRepository) removeEntity(id uint64) {
+	delete(m.data.entities, id)
+
+	for tid, t := range m.data.entityTags {
+		if t.OwnerID == id {
+			delete(m.data.entityTags, tid)
+		}
+	}
+
+	for eid, e := range m.data.edges {
+		if e.FromEntityID == id || e.ToEntityID == id {
+			m.removeEdge(eid)
+		}
+	}
+}
+
+// linkedEntities returns the IDs of the entities attached to an edge, including the edges of soft-deleted entities.
+func (m *memRepository) linkedEntities() map[uint64]struct{} {
+	linked := make(map[uint64]struct{})
+	for _, e := range m.data.edges {
+		linked[e.FromEntityID] = struct{}{}
+		linked[e.ToEntityID] = struct{}{}
+	}
+	return linked
+}
+
+// sameAsset reports whether the assets share the asset type and identifying content.
+func sameAsset(a, b oam.Asset) bool {
+	return a.AssetType() == b.AssetType() && a.Key() == b.Key()
+}
+
+// sameContent reports whether the assets or relations serialize to the same JSON content.
+func sameContent(a, b interface{ JSON() ([]byte, error) }) bool {
+	ac, err := a.JSON()
+	if err != nil {
+		return false
+	}
+
+	bc, err := b.JSON()
+	if err != nil {
+		return false
+	}
+	return jsoncmp.Equal(ac, bc)
+}
+
+// orderLess returns the less function that sorts the entities as set by options.WithOrder, with ties broken by the ID,
+// or nil when the option is not provided or the field is not supported.
+func (m *memRepository) orderLess(entities []*entity) func(i, j int) bool {
+	var compare func(a, b *entity) int
+	switch m.orderBy {
+	case options.OrderByCreatedAt:
+		compare = func(a, b *entity) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	case options.OrderByUpdatedAt:
+		compare = func(a, b *entity) int { return a.UpdatedAt.Compare(b.UpdatedAt) }
+	case options.OrderByType:
+		compare = func(a, b *entity) int {
+			return strings.Compare(string(a.Asset.AssetType()), string(b.Asset.AssetType()))
+		}
+	default:
+		return nil
+	}
+
+	return func(i, j int) bool {
+		c := compare(entities[i], entities[j])
+		if c == 0 {
+			c = cmp.Compare(entities[i].ID, entities[j].ID)
+		}
+		if m.orderDesc {
+			return c > 0
+		}
+		return c < 0
+	}
+}
+
+// sortByCreation orders the entities by creation time and then by ID.
+func sortByCreation(entities []*entity) {
+	sort.SliceStable(entities, func(i, j int) bool {
+		if entities[i].CreatedAt.Equal(entities[j].CreatedAt) {
+			return entities[i].ID < entities[j].ID
+		}
+		return entities[i].CreatedAt.Before(entities[j].CreatedAt)
+	})
+}
+
+// validateAsset returns the error of the validator provided by options.WithValidator, which rejects the asset
+// before it is written. The assets are accepted when no validator was provided.
+func (m *memRepository) validateAsset(asset oam.Asset) error {
+	if m.validate == nil || asset == nil {
+		return nil
+	}
+	return m.validate(asset)
+}
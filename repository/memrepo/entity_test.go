@@ -0,0 +1,668 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/options"
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
+	oamnet "github.com/owasp-amass/open-asset-model/network"
+	"github.com/owasp-amass/open-asset-model/org"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateEntity(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	_, err := m.CreateEntity(ctx, &types.Entity{})
+	assert.Error(t, err)
+
+	ctime := time.Now().Add(-time.Hour)
+	first, err := m.CreateEntity(ctx, &types.Entity{
+		CreatedAt: ctime,
+		LastSeen:  ctime,
+		Asset:     &dns.FQDN{Name: "owasp.org"},
+	})
+	assert.NoError(t, err)
+	assert.True(t, first.CreatedAt.Equal(ctime))
+
+	// duplicate assets update the existing entity
+	second, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	assert.True(t, second.CreatedAt.Equal(ctime))
+	assert.True(t, second.LastSeen.After(ctime))
+
+	entities, err := m.CreateEntities(ctx, []*types.Entity{
+		{Asset: &dns.FQDN{Name: "owasp.org"}},
+		{Asset: &dns.FQDN{Name: "www.owasp.org"}},
+		{Asset: &dns.FQDN{Name: "www.owasp.org"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, entities[0].ID)
+	assert.Equal(t, entities[1].ID, entities[2].ID)
+
+	count, err := m.CountEntitiesByType(ctx, oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	grouped, err := m.CountEntitiesGrouped(ctx, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[oam.AssetType]int64{oam.FQDN: 2}, grouped)
+
+	_, result, err := m.UpsertEntity(ctx, &types.Entity{Asset: &dns.FQDN{Name: "www.owasp.org"}})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUnchanged, result)
+	_, result, err = m.UpsertEntity(ctx, &types.Entity{Asset: &dns.FQDN{Name: "mail.owasp.org"}})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertCreated, result)
+
+	// the content of the matched entity is replaced when it differs
+	organization, result, err := m.UpsertEntity(ctx, &types.Entity{Asset: &org.Organization{ID: "222333444", Name: "Example"}})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertCreated, result)
+	renamed, result, err := m.UpsertEntity(ctx, &types.Entity{Asset: &org.Organization{ID: "222333444", Name: "Example, Inc."}})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUpdated, result)
+	assert.Equal(t, organization.ID, renamed.ID)
+	assert.Equal(t, "Example, Inc.", renamed.Asset.(*org.Organization).Name)
+	_, result, err = m.UpsertEntity(ctx, &types.Entity{Asset: &org.Organization{ID: "222333444", Name: "Example, Inc."}})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUnchanged, result)
+
+	// an ID provided by the caller is kept, and conflicts return ErrDuplicate
+	provided, err := m.CreateEntity(ctx, &types.Entity{ID: "1000", Asset: &dns.FQDN{Name: "provided.owasp.org"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "1000", provided.ID)
+	_, err = m.CreateEntity(ctx, &types.Entity{ID: "1000", Asset: &dns.FQDN{Name: "other.owasp.org"}})
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+	_, err = m.CreateEntity(ctx, &types.Entity{ID: "1001", Asset: &dns.FQDN{Name: "provided.owasp.org"}})
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+}
+
+func TestValidator(t *testing.T) {
+	errInvalid := errors.New("invalid FQDN")
+	m := New(options.WithValidator(func(asset oam.Asset) error {
+		if fqdn, ok := asset.(*dns.FQDN); ok && strings.Contains(fqdn.Name, " ") {
+			return errInvalid
+		}
+		return nil
+	}))
+	ctx := context.Background()
+
+	valid, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+
+	invalid := &dns.FQDN{Name: "not a domain"}
+	_, err = m.CreateEntity(ctx, &types.Entity{Asset: invalid})
+	assert.ErrorIs(t, err, errInvalid)
+	_, _, err = m.UpsertEntity(ctx, &types.Entity{Asset: invalid})
+	assert.ErrorIs(t, err, errInvalid)
+	_, err = m.UpdateEntity(ctx, valid.ID, invalid)
+	assert.ErrorIs(t, err, errInvalid)
+
+	// none of the entities are written when one of the assets is rejected
+	_, err = m.CreateEntities(ctx, []*types.Entity{{Asset: &dns.FQDN{Name: "www.owasp.org"}}, {Asset: invalid}})
+	assert.ErrorIs(t, err, errInvalid)
+
+	// the writes made within a transaction are validated as well
+	err = m.WithTransaction(ctx, func(tx types.Repository) error {
+		_, err := tx.CreateAsset(ctx, invalid)
+		return err
+	})
+	assert.ErrorIs(t, err, errInvalid)
+
+	entities, err := m.FindEntitiesByType(ctx, oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, entities, 1) {
+		assert.Equal(t, "owasp.org", entities[0].Asset.(*dns.FQDN).Name)
+	}
+}
+
+func TestIDGenerator(t *testing.T) {
+	next := "1000"
+	m := New(options.WithIDGenerator(func() string { return next }))
+	ctx := context.Background()
+
+	e, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1000", e.ID)
+
+	// an existing asset keeps its ID, and a generated ID held by another entity is rejected
+	again, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	assert.Equal(t, e.ID, again.ID)
+	_, err = m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+
+	// the IDs of the edges are assigned after the generated IDs
+	next = "2000"
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	edge, err := m.CreateEdge(ctx, &types.Edge{Relation: &general.SimpleRelation{Name: "node"}, FromEntity: e, ToEntity: to})
+	assert.NoError(t, err)
+	assert.Equal(t, "2001", edge.ID)
+
+	next = "not-a-number"
+	_, err = m.CreateAsset(ctx, &dns.FQDN{Name: "docs.owasp.org"})
+	assert.Error(t, err)
+}
+
+func TestFindEntities(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	old := time.Now().Add(-time.Hour)
+	for _, name := range []string{"old.example.com", "older.example.com"} {
+		_, err := m.CreateEntity(ctx, &types.Entity{CreatedAt: old, LastSeen: old, Asset: &dns.FQDN{Name: name}})
+		assert.NoError(t, err)
+	}
+
+	since := time.Now()
+	entity, err := m.CreateAsset(ctx, &dns.FQDN{Name: "new.example.com"})
+	assert.NoError(t, err)
+
+	found, err := m.FindEntityById(ctx, entity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "new.example.com", found.Asset.Key())
+
+	_, err = m.FindEntitiesByContent(ctx, &dns.FQDN{Name: "old.example.com"}, since)
+	assert.Error(t, err)
+	entities, err := m.FindEntitiesByContent(ctx, &dns.FQDN{Name: "old.example.com"}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 1)
+
+	matches, err := m.FindEntitiesByContents(ctx, []oam.Asset{
+		&dns.FQDN{Name: "old.example.com"},
+		&dns.FQDN{Name: "new.example.com"},
+		&dns.FQDN{Name: "missing.example.com"},
+	}, since)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, entity.ID, matches[types.ContentHash(&dns.FQDN{Name: "new.example.com"})].ID)
+
+	found, err = m.FindEntityByHash(ctx, entity.ContentHash())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ID, found.ID)
+	_, err = m.FindEntityByHash(ctx, types.ContentHash(&dns.FQDN{Name: "missing.example.com"}))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = m.FindEntityByHash(ctx, "")
+	assert.Error(t, err)
+
+	exists, err := m.EntityExists(ctx, &dns.FQDN{Name: "old.example.com"})
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = m.EntityExists(ctx, &dns.FQDN{Name: "missing.example.com"})
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	_, err = m.EntityExists(ctx, nil)
+	assert.Error(t, err)
+
+	entities, err = m.FindEntitiesByType(ctx, oam.FQDN, since)
+	assert.NoError(t, err)
+	assert.Len(t, entities, 1)
+	_, err = m.FindEntitiesByType(ctx, oam.IPAddress, time.Time{})
+	assert.Error(t, err)
+
+	entities, err = m.SearchEntities(ctx, oam.FQDN, "older", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 1)
+
+	page, total, err := m.FindEntitiesByTypePaged(ctx, oam.FQDN, time.Time{}, 1, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, page, 2)
+
+	iter, err := m.IterateEntitiesByType(ctx, oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	var names []string
+	for iter.Next() {
+		names = append(names, iter.Entity().Asset.Key())
+	}
+	assert.NoError(t, iter.Err())
+	assert.NoError(t, iter.Close())
+	assert.Equal(t, []string{"old.example.com", "older.example.com", "new.example.com"}, names)
+}
+
+func TestDeleteEntity(t *testing.T) {
+	ctx := context.Background()
+
+	m := New()
+	entity, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	n, err := m.DeleteEntity(ctx, entity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = m.DeleteEntity(ctx, entity.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	assert.Equal(t, int64(0), n)
+	_, err = m.FindEntityById(ctx, entity.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = m.FindDeletedEntities(ctx, time.Time{})
+	assert.Error(t, err)
+
+	soft := New(options.WithSoftDelete())
+	entity, err = soft.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	n, err = soft.DeleteEntity(ctx, entity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = soft.FindEntityById(ctx, entity.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	deleted, err := soft.FindDeletedEntities(ctx, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 1)
+	assert.False(t, deleted[0].DeletedAt.IsZero())
+
+	// the tombstone is restored when the asset is created again
+	restored, err := soft.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ID, restored.ID)
+
+	_, err = soft.DeleteEntity(ctx, entity.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, soft.PurgeDeleted(ctx, time.Time{}))
+	_, err = soft.FindDeletedEntities(ctx, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestDeleteEntityCascade(t *testing.T) {
+	ctx := context.Background()
+
+	m := New(options.WithSoftDelete())
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	edge, err := m.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	_, err = m.CreateEntityProperty(ctx, to, &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	_, err = m.CreateEdgeProperty(ctx, edge, &general.SimpleProperty{PropertyName: "test", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	// the entity, its tag, the incoming edge, and the tag of the edge are removed
+	n, err := m.DeleteEntityCascade(ctx, to.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	_, err = m.FindEntityById(ctx, to.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = m.OutgoingEdges(ctx, from, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = m.FindDeletedEntities(ctx, time.Time{})
+	assert.Error(t, err)
+	assert.Empty(t, m.data.entityTags)
+	assert.Empty(t, m.data.edges)
+	assert.Empty(t, m.data.edgeTags)
+
+	// a tombstone is removed as well
+	_, err = m.DeleteEntity(ctx, from.ID)
+	assert.NoError(t, err)
+	n, err = m.DeleteEntityCascade(ctx, from.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Empty(t, m.data.entities)
+
+	n, err = m.DeleteEntityCascade(ctx, from.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestDeleteEntitiesByType(t *testing.T) {
+	ctx := context.Background()
+
+	m := New(options.WithSoftDelete())
+	old := time.Now().Add(-48 * time.Hour)
+	stale, err := m.CreateEntity(ctx, &types.Entity{CreatedAt: old, LastSeen: old, Asset: &dns.FQDN{Name: "stale.owasp.org"}})
+	assert.NoError(t, err)
+	fresh, err := m.CreateAsset(ctx, &dns.FQDN{Name: "fresh.owasp.org"})
+	assert.NoError(t, err)
+	ip, err := m.CreateEntity(ctx, &types.Entity{CreatedAt: old, LastSeen: old, Asset: &oamnet.IPAddress{Address: netip.MustParseAddr("192.168.1.1"), Type: "IPv4"}})
+	assert.NoError(t, err)
+	tombstone, err := m.CreateEntity(ctx, &types.Entity{CreatedAt: old, LastSeen: old, Asset: &dns.FQDN{Name: "deleted.owasp.org"}})
+	assert.NoError(t, err)
+	_, err = m.DeleteEntity(ctx, tombstone.ID)
+	assert.NoError(t, err)
+
+	_, err = m.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: stale,
+		ToEntity:   fresh,
+	})
+	assert.NoError(t, err)
+	_, err = m.CreateEntityProperty(ctx, stale, &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"})
+	assert.NoError(t, err)
+
+	// only the live FQDN entities last seen before the date are removed, along with their edges and tags
+	n, err := m.DeleteEntitiesByType(ctx, oam.FQDN, time.Now().Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = m.FindEntityById(ctx, stale.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = m.FindEntityById(ctx, fresh.ID)
+	assert.NoError(t, err)
+	_, err = m.FindEntityById(ctx, ip.ID)
+	assert.NoError(t, err)
+	_, err = m.IncomingEdges(ctx, fresh, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	assert.Empty(t, m.data.entityTags)
+
+	deleted, err := m.FindDeletedEntities(ctx, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 1)
+
+	n, err = m.DeleteEntitiesByType(ctx, oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = m.FindEntityById(ctx, fresh.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestOrphanEntities(t *testing.T) {
+	ctx := context.Background()
+
+	m := New(options.WithSoftDelete())
+	old := time.Now().Add(-48 * time.Hour)
+	orphan, err := m.CreateEntity(ctx, &types.Entity{CreatedAt: old, LastSeen: old, Asset: &dns.FQDN{Name: "orphan.owasp.org"}})
+	assert.NoError(t, err)
+	fresh, err := m.CreateAsset(ctx, &dns.FQDN{Name: "fresh.owasp.org"})
+	assert.NoError(t, err)
+	from, err := m.CreateEntity(ctx, &types.Entity{CreatedAt: old, LastSeen: old, Asset: &dns.FQDN{Name: "from.owasp.org"}})
+	assert.NoError(t, err)
+	to, err := m.CreateEntity(ctx, &types.Entity{CreatedAt: old, LastSeen: old, Asset: &dns.FQDN{Name: "to.owasp.org"}})
+	assert.NoError(t, err)
+	_, err = m.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	_, err = m.CreateEntityProperty(ctx, orphan, &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"})
+	assert.NoError(t, err)
+
+	// the edge to a soft-deleted entity keeps the other entity from being an orphan
+	_, err = m.DeleteEntity(ctx, to.ID)
+	assert.NoError(t, err)
+
+	orphans, err := m.FindOrphanEntities(ctx, oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	var ids []string
+	for _, e := range orphans {
+		ids = append(ids, e.ID)
+	}
+	assert.ElementsMatch(t, []string{orphan.ID, fresh.ID}, ids)
+	_, err = m.FindOrphanEntities(ctx, oam.IPAddress, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	// only the orphans last seen before the date are removed, along with their tags
+	n, err := m.DeleteOrphanEntities(ctx, oam.FQDN, time.Now().Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = m.FindEntityById(ctx, orphan.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	assert.Empty(t, m.data.entityTags)
+	for _, e := range []*types.Entity{fresh, from} {
+		_, err = m.FindEntityById(ctx, e.ID)
+		assert.NoError(t, err)
+	}
+}
+
+func TestFindEntitiesByTypes(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var ids []string
+	for i, asset := range []oam.Asset{
+		&oamnet.IPAddress{Address: netip.MustParseAddr("192.168.1.1"), Type: "IPv4"},
+		&oamnet.Netblock{CIDR: netip.MustParsePrefix("192.168.1.0/24"), Type: "IPv4"},
+		&oamnet.IPAddress{Address: netip.MustParseAddr("192.168.1.2"), Type: "IPv4"},
+	} {
+		e, err := m.CreateEntity(ctx, &types.Entity{
+			CreatedAt: start.Add(time.Duration(i) * time.Hour),
+			LastSeen:  start.Add(time.Duration(i) * time.Hour),
+			Asset:     asset,
+		})
+		assert.NoError(t, err)
+		ids = append(ids, e.ID)
+	}
+	_, err := m.CreateEntity(ctx, &types.Entity{Asset: &dns.FQDN{Name: "owasp.org"}})
+	assert.NoError(t, err)
+
+	// the entities of both types are interleaved by their creation time
+	entities, err := m.FindEntitiesByTypes(ctx, time.Time{}, oam.IPAddress, oam.Netblock, oam.IPAddress)
+	assert.NoError(t, err)
+	if assert.Len(t, entities, 3) {
+		for i, e := range entities {
+			assert.Equal(t, ids[i], e.ID)
+		}
+	}
+
+	entities, err = m.FindEntitiesByTypes(ctx, start.Add(time.Hour), oam.IPAddress, oam.Netblock)
+	assert.NoError(t, err)
+	assert.Len(t, entities, 2)
+
+	_, err = m.FindEntitiesByTypes(ctx, time.Time{}, oam.Organization)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = m.FindEntitiesByTypes(ctx, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestFindEntitiesByTypeBetween(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"owasp.org", "www.owasp.org", "docs.owasp.org"} {
+		_, err := m.CreateEntity(ctx, &types.Entity{
+			LastSeen: start.Add(time.Duration(i) * time.Hour),
+			Asset:    &dns.FQDN{Name: name},
+		})
+		assert.NoError(t, err)
+	}
+
+	// the window includes its start and excludes its end
+	entities, err := m.FindEntitiesByTypeBetween(ctx, oam.FQDN, start, start.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, entities, 2)
+
+	entities, err = m.FindEntitiesByTypeBetween(ctx, oam.FQDN, start.Add(time.Hour), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 2)
+	entities, err = m.FindEntitiesByTypeBetween(ctx, oam.FQDN, time.Time{}, start.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, entities, 1)
+
+	_, err = m.FindEntitiesByTypeBetween(ctx, oam.FQDN, start.Add(time.Hour), start)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestDiffEntities(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	t1 := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(24 * time.Hour)
+	for _, e := range []*types.Entity{
+		// seen by both scans
+		{CreatedAt: t1.Add(-time.Hour), LastSeen: t2.Add(time.Hour), Asset: &dns.FQDN{Name: "owasp.org"}},
+		// first seen after the first scan
+		{CreatedAt: t1.Add(time.Hour), LastSeen: t2.Add(time.Hour), Asset: &dns.FQDN{Name: "www.owasp.org"}},
+		// not seen since the first scan
+		{CreatedAt: t1.Add(-time.Hour), LastSeen: t1.Add(time.Hour), Asset: &dns.FQDN{Name: "docs.owasp.org"}},
+		// gone before the first scan
+		{CreatedAt: t1.Add(-2 * time.Hour), LastSeen: t1.Add(-time.Hour), Asset: &dns.FQDN{Name: "old.owasp.org"}},
+		// first seen after the second scan
+		{CreatedAt: t2.Add(time.Hour), LastSeen: t2.Add(time.Hour), Asset: &dns.FQDN{Name: "new.owasp.org"}},
+	} {
+		_, err := m.CreateEntity(ctx, e)
+		assert.NoError(t, err)
+	}
+
+	added, removed, err := m.DiffEntities(ctx, oam.FQDN, t1, t2)
+	assert.NoError(t, err)
+	if assert.Len(t, added, 1) {
+		assert.Equal(t, "www.owasp.org", added[0].Asset.Key())
+	}
+	if assert.Len(t, removed, 1) {
+		assert.Equal(t, "docs.owasp.org", removed[0].Asset.Key())
+	}
+
+	added, removed, err = m.DiffEntities(ctx, oam.IPAddress, t1, t2)
+	assert.NoError(t, err)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+
+	_, _, err = m.DiffEntities(ctx, oam.FQDN, t2, t1)
+	assert.Error(t, err)
+}
+
+func TestEntityOrder(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	// the entities are created in the order x, y, z, and last seen in the order y, z, x
+	created := time.Date(2004, time.January, 1, 0, 0, 0, 0, time.UTC)
+	seen := map[string]time.Duration{"x": 5 * time.Minute, "y": 3 * time.Minute, "z": 4 * time.Minute}
+	ids := make(map[string]string)
+	for i, name := range []string{"x", "y", "z"} {
+		e, err := m.CreateEntity(ctx, &types.Entity{
+			CreatedAt: created.Add(time.Duration(i) * time.Minute),
+			LastSeen:  created.Add(seen[name]),
+			Asset:     &dns.FQDN{Name: name + ".order.example.com"},
+		})
+		assert.NoError(t, err)
+		ids[e.ID] = name
+	}
+
+	for _, tc := range []struct {
+		opt      options.Option
+		expected string
+	}{
+		{options.WithOrder(options.OrderByCreatedAt, options.Ascending), "xyz"},
+		{options.WithOrder(options.OrderByCreatedAt, options.Descending), "zyx"},
+		{options.WithOrder(options.OrderByUpdatedAt, options.Ascending), "yzx"},
+		{options.WithOrder(options.OrderByUpdatedAt, options.Descending), "xzy"},
+		{options.WithOrder(options.OrderByType, options.Descending), "zyx"},
+		// the order takes precedence over the last seen order
+		{options.WithOrder(options.OrderByCreatedAt, options.Ascending), "xyz"},
+	} {
+		ordered := New(tc.opt, options.WithLastSeenOrder())
+		ordered.data = m.data
+		found, err := ordered.FindEntitiesByTypeBetween(ctx, oam.FQDN, created, created.Add(time.Hour))
+		assert.NoError(t, err)
+
+		var order string
+		for _, e := range found {
+			order += ids[e.ID]
+		}
+		assert.Equal(t, tc.expected, order)
+	}
+}
+
+func TestTouchEntity(t *testing.T) {
+	m := New(options.WithLastSeenOrder())
+	ctx := context.Background()
+
+	seen := time.Now().Add(-time.Hour)
+	var entities []*types.Entity
+	for i, name := range []string{"owasp.org", "www.owasp.org"} {
+		e, err := m.CreateEntity(ctx, &types.Entity{
+			LastSeen: seen.Add(time.Duration(i) * time.Minute),
+			Asset:    &dns.FQDN{Name: name},
+		})
+		assert.NoError(t, err)
+		entities = append(entities, e)
+	}
+
+	// the most recently seen entities are returned first
+	found, err := m.FindEntitiesByType(ctx, oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, found, 2) {
+		assert.Equal(t, entities[1].ID, found[0].ID)
+	}
+
+	assert.NoError(t, m.TouchEntity(ctx, entities[0].ID))
+	touched, err := m.FindEntityById(ctx, entities[0].ID)
+	assert.NoError(t, err)
+	assert.True(t, touched.LastSeen.After(entities[1].LastSeen))
+	assert.Equal(t, entities[0].CreatedAt, touched.CreatedAt)
+
+	found, err = m.FindEntitiesByType(ctx, oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, found, 2) {
+		assert.Equal(t, entities[0].ID, found[0].ID)
+	}
+
+	assert.ErrorIs(t, m.TouchEntity(ctx, "999"), types.ErrNotFound)
+	assert.ErrorIs(t, m.TouchEntity(ctx, "invalid"), types.ErrNotFound)
+}
+
+func TestUpdateEntity(t *testing.T) {
+	ctx := context.Background()
+
+	m := New(options.WithSoftDelete())
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	_, err = m.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	// the ID, the creation time, and the edges are kept
+	updated, err := m.UpdateEntity(ctx, to.ID, &dns.FQDN{Name: "docs.owasp.org"})
+	assert.NoError(t, err)
+	assert.Equal(t, to.ID, updated.ID)
+	assert.Equal(t, to.CreatedAt, updated.CreatedAt)
+	assert.False(t, updated.LastSeen.Before(to.LastSeen))
+
+	found, err := m.FindEntityByHash(ctx, types.ContentHash(&dns.FQDN{Name: "docs.owasp.org"}))
+	assert.NoError(t, err)
+	assert.Equal(t, to.ID, found.ID)
+	_, err = m.FindEntityByHash(ctx, to.ContentHash())
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	edges, err := m.OutgoingEdges(ctx, from, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, edges, 1) {
+		assert.Equal(t, to.ID, edges[0].ToEntity.ID)
+	}
+
+	// the asset of another entity, including a soft-deleted one, is rejected
+	_, err = m.UpdateEntity(ctx, to.ID, &dns.FQDN{Name: "owasp.org"})
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+	deleted, err := m.CreateAsset(ctx, &dns.FQDN{Name: "deleted.owasp.org"})
+	assert.NoError(t, err)
+	_, err = m.DeleteEntity(ctx, deleted.ID)
+	assert.NoError(t, err)
+	_, err = m.UpdateEntity(ctx, to.ID, &dns.FQDN{Name: "deleted.owasp.org"})
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+
+	_, err = m.UpdateEntity(ctx, to.ID, &general.Identifier{UniqueID: "id:owasp", ID: "owasp", Type: "id"})
+	assert.Error(t, err)
+	_, err = m.UpdateEntity(ctx, deleted.ID, &dns.FQDN{Name: "restored.owasp.org"})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+	_, err = m.UpdateEntity(ctx, "999", &dns.FQDN{Name: "missing.owasp.org"})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+	_, err = m.UpdateEntity(ctx, to.ID, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,112 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/garthoid/asset-db/internal/transfer"
+	"github.com/garthoid/asset-db/types"
+)
+
+// ExportJSON writes the entities, edges, and tags in the repository to w as newline-delimited JSON,
+// with one types.ExportRecord per line. The entities are written first, followed by the edges, the
+// entity tags, and the edge tags, so each record only refers to records that precede it.
+// Soft-deleted entities, along with the edges and tags attached to them, are not exported.
+// The records are taken from a snapshot of the repository, so the lock is not held while writing to w.
+func (m *memRepository) ExportJSON(ctx context.Context, w io.Writer) error {
+	records, err := m.exportRecords()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportSubgraph writes the root entity, and the entities and edges reached from it within maxDepth outgoing edges,
+// to w as newline-delimited JSON in the format of ExportJSON, along with the tags of those entities and edges.
+// The subgraph is traversed with Neighborhood, so the output only holds the records connected to the root.
+func (m *memRepository) ExportSubgraph(ctx context.Context, root *types.Entity, maxDepth int, w io.Writer) error {
+	return transfer.ExportSubgraph(ctx, m, root, maxDepth, w)
+}
+
+// ImportJSON reads the newline-delimited JSON written by ExportJSON and recreates the records in the repository.
+// The records that match existing entities, edges, or tags are skipped, so a repeated import creates nothing.
+// Returns the number of records created and skipped by kind, or an error if a record cannot be imported.
+func (m *memRepository) ImportJSON(ctx context.Context, r io.Reader) (types.ImportStats, error) {
+	return transfer.ImportJSON(ctx, m, r)
+}
+
+// exportRecords returns the export records of the live entities, edges, and tags in the order they are written.
+func (m *memRepository) exportRecords() ([]*types.ExportRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var records []*types.ExportRecord
+	for _, id := range sortedIDs(m.data.entities) {
+		e := m.data.entities[id]
+		if !e.DeletedAt.IsZero() {
+			continue
+		}
+
+		r, err := types.EntityRecord(e.toEntity())
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	for _, id := range sortedIDs(m.data.edges) {
+		e := m.data.edges[id]
+		if !m.liveEdge(e) {
+			continue
+		}
+
+		r, err := types.EdgeRecord(e.toEdge())
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	for _, id := range sortedIDs(m.data.entityTags) {
+		t := m.data.entityTags[id]
+		if e, found := m.data.entities[t.OwnerID]; !found || !e.DeletedAt.IsZero() {
+			continue
+		}
+
+		r, err := types.EntityTagRecord(t.toEntityTag(&types.Entity{ID: strconv.FormatUint(t.OwnerID, 10)}))
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	for _, id := range sortedIDs(m.data.edgeTags) {
+		t := m.data.edgeTags[id]
+		if e, found := m.data.edges[t.OwnerID]; !found || !m.liveEdge(e) {
+			continue
+		}
+
+		r, err := types.EdgeTagRecord(t.toEdgeTag(&types.Edge{ID: strconv.FormatUint(t.OwnerID, 10)}))
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
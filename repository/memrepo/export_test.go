@@ -0,0 +1,88 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportSubgraph(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	var entities []*types.Entity
+	for _, name := range []string{"owasp.org", "www.owasp.org", "mail.owasp.org", "example.com"} {
+		e, err := m.CreateAsset(ctx, &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		entities = append(entities, e)
+	}
+
+	var edges []*types.Edge
+	for _, pair := range [][2]int{{0, 1}, {1, 2}} {
+		e, err := m.CreateEdge(ctx, &types.Edge{
+			Relation:   &general.SimpleRelation{Name: "node"},
+			FromEntity: entities[pair[0]],
+			ToEntity:   entities[pair[1]],
+		})
+		assert.NoError(t, err)
+		edges = append(edges, e)
+	}
+
+	_, err := m.CreateEntityProperty(ctx, entities[0], &general.SimpleProperty{PropertyName: "export", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	_, err = m.CreateEdgeProperty(ctx, edges[0], &general.SimpleProperty{PropertyName: "export", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	kinds := func(buf *bytes.Buffer) map[string]int {
+		counts := make(map[string]int)
+		scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+		for scanner.Scan() {
+			var r types.ExportRecord
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+			counts[r.Kind]++
+		}
+		assert.NoError(t, scanner.Err())
+		return counts
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.ExportSubgraph(ctx, entities[0], 1, &buf))
+	assert.Equal(t, map[string]int{
+		types.EntityRecordKind:    2,
+		types.EdgeRecordKind:      1,
+		types.EntityTagRecordKind: 1,
+		types.EdgeTagRecordKind:   1,
+	}, kinds(&buf))
+
+	buf.Reset()
+	assert.NoError(t, m.ExportSubgraph(ctx, entities[0], 2, &buf))
+	assert.Equal(t, 3, kinds(&buf)[types.EntityRecordKind])
+
+	// the subgraph can be imported into another repository
+	dst := New()
+	stats, err := dst.ImportJSON(ctx, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, types.ImportStats{EntitiesCreated: 3, EdgesCreated: 2, EntityTagsCreated: 1, EdgeTagsCreated: 1}, stats)
+	_, err = dst.FindEntitiesByContent(ctx, &dns.FQDN{Name: "example.com"}, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	// an entity without outgoing edges is exported on its own
+	buf.Reset()
+	assert.NoError(t, m.ExportSubgraph(ctx, entities[3], 1, &buf))
+	assert.Equal(t, map[string]int{types.EntityRecordKind: 1}, kinds(&buf))
+
+	assert.Error(t, m.ExportSubgraph(ctx, nil, 1, &buf))
+	assert.Error(t, m.ExportSubgraph(ctx, entities[0], 0, &buf))
+}
@@ -0,0 +1,55 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"context"
+
+	"github.com/garthoid/asset-db/types"
+)
+
+// entityIterator implements types.EntityIterator over a snapshot of the matching entities.
+type entityIterator struct {
+	ctx      context.Context
+	entities []*types.Entity
+	current  *types.Entity
+	err      error
+}
+
+// Next implements the types.EntityIterator interface.
+func (it *entityIterator) Next() bool {
+	it.current = nil
+	if it.err != nil {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	if len(it.entities) == 0 {
+		return false
+	}
+
+	it.current = it.entities[0]
+	it.entities = it.entities[1:]
+	return true
+}
+
+// Entity implements the types.EntityIterator interface.
+func (it *entityIterator) Entity() *types.Entity {
+	return it.current
+}
+
+// Err implements the types.EntityIterator interface.
+func (it *entityIterator) Err() error {
+	return it.err
+}
+
+// Close implements the types.EntityIterator interface.
+func (it *entityIterator) Close() error {
+	it.current = nil
+	it.entities = nil
+	return nil
+}
@@ -0,0 +1,558 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// CreateEntityTag creates a new entity tag in the repository.
+// A tag on the entity with the same property type, name, and value is updated rather than duplicated.
+// Returns the created entity tag as a types.EntityTag or an error if the creation fails.
+func (m *memRepository) CreateEntityTag(ctx context.Context, entity *types.Entity, input *types.EntityTag) (*types.EntityTag, error) {
+	if entity == nil || input == nil || input.Property == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	entityId, err := parseID(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.data.entities[entityId]; !found {
+		return nil, types.NotFound("entity not found")
+	}
+
+	t := m.createTag(m.data.entityTags, entityId, input.Property, input.CreatedAt, input.LastSeen, input.ExpiresAt)
+	return t.toEntityTag(entity), nil
+}
+
+// CreateEntityTags creates the same tag on each of the entities in the repository.
+// A tag on an entity with the same property type, name, and value is updated rather than duplicated,
+// and the entities that are not found are skipped rather than failing the batch.
+// Returns the entity tags in the order of the entity IDs, along with a *types.MissingEntitiesError that lists the
+// skipped entities, or an error if the creation fails.
+func (m *memRepository) CreateEntityTags(ctx context.Context, entityIDs []string, input *types.EntityTag) ([]*types.EntityTag, error) {
+	if input == nil || input.Property == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var missing []string
+	var results []*types.EntityTag
+	seen := make(map[uint64]struct{}, len(entityIDs))
+	for _, id := range entityIDs {
+		entityId, err := parseID(id)
+		if err != nil {
+			missing = append(missing, id)
+			continue
+		}
+
+		if _, found := seen[entityId]; found {
+			continue
+		}
+		seen[entityId] = struct{}{}
+
+		if e, found := m.data.entities[entityId]; !found || !e.DeletedAt.IsZero() {
+			missing = append(missing, id)
+			continue
+		}
+
+		t := m.createTag(m.data.entityTags, entityId, input.Property, input.CreatedAt, input.LastSeen, input.ExpiresAt)
+		results = append(results, t.toEntityTag(&types.Entity{ID: id}))
+	}
+
+	if len(missing) > 0 {
+		return results, &types.MissingEntitiesError{IDs: missing}
+	}
+	return results, nil
+}
+
+// CreateEntityProperty creates a new entity tag in the repository.
+// It takes an oam.Property as input and persists it in the repository.
+// Returns the created entity tag as a types.EntityTag or an error if the creation fails.
+func (m *memRepository) CreateEntityProperty(ctx context.Context, entity *types.Entity, prop oam.Property) (*types.EntityTag, error) {
+	return m.CreateEntityTag(ctx, entity, &types.EntityTag{Property: prop})
+}
+
+// FindEntityTagById finds an entity tag in the repository by the ID.
+// Returns the discovered tag as a types.EntityTag or types.ErrTagNotFound if the tag is not found.
+func (m *memRepository) FindEntityTagById(ctx context.Context, id string) (*types.EntityTag, error) {
+	tagId, err := parseID(id)
+	if err != nil {
+		// an invalid ID cannot match a tag
+		return nil, types.ErrTagNotFound
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, found := m.data.entityTags[tagId]
+	if !found {
+		return nil, types.ErrTagNotFound
+	}
+	return t.toEntityTag(&types.Entity{ID: strconv.FormatUint(t.OwnerID, 10)}), nil
+}
+
+// FindEntityTagsByContent finds entity tags in the repository that match the provided property data and last seen after the since parameter.
+// The properties are matched by their property type, name, and value.
+// If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching entity tags as []*types.EntityTag or an error if the search fails.
+func (m *memRepository) FindEntityTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EntityTag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []*types.EntityTag
+	for _, t := range findTagsByContent(m.data.entityTags, prop, since) {
+		results = append(results, t.toEntityTag(&types.Entity{ID: strconv.FormatUint(t.OwnerID, 10)}))
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entity tags found")
+	}
+	return results, nil
+}
+
+// GetEntityTags finds all tags for the entity with the specified names and last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// If no names are specified, all tags for the specified entity are returned.
+func (m *memRepository) GetEntityTags(ctx context.Context, entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error) {
+	return m.GetEntityTagsBetween(ctx, entity, since, time.Time{}, names...)
+}
+
+// GetEntityTagsBetween finds all tags for the entity with the specified names and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no names are specified, all tags for the specified entity in the window are returned.
+func (m *memRepository) GetEntityTagsBetween(ctx context.Context, entity *types.Entity, from, to time.Time, names ...string) ([]*types.EntityTag, error) {
+	entityId, err := parseID(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []*types.EntityTag
+	for _, t := range getTags(m.data.entityTags, entityId, from, to, names, m.expiredTags) {
+		results = append(results, t.toEntityTag(entity))
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero tags found")
+	}
+	return results, nil
+}
+
+// GetEntityTagsMatching finds the tags for the entity with the provided name and value, which were last seen after the
+// since parameter. Only the name is matched when the value is empty.
+// If since.IsZero(), the parameter will be ignored.
+// Returns a slice of the matching tags as []*types.EntityTag or an error if the search fails.
+func (m *memRepository) GetEntityTagsMatching(ctx context.Context, entity *types.Entity, since time.Time, name, value string) ([]*types.EntityTag, error) {
+	if name == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	entityId, err := parseID(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+	props := types.NamedProperties(name, value)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []*types.EntityTag
+	for _, t := range getTags(m.data.entityTags, entityId, since, time.Time{}, []string{name}, m.expiredTags) {
+		for _, prop := range props {
+			if t.Property.PropertyType() == prop.PropertyType() && (value == "" || t.Property.Value() == value) {
+				results = append(results, t.toEntityTag(entity))
+				break
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero tags found")
+	}
+	return results, nil
+}
+
+// FindEntitiesByTag finds the entities in the repository with a tag of the provided name and value, which was last seen
+// after the since parameter. Only the name is matched when the value is empty.
+// If since.IsZero(), the parameter will be ignored.
+// Returns a slice of the tagged entities as []*types.Entity or an error if the search fails.
+func (m *memRepository) FindEntitiesByTag(ctx context.Context, name, value string, since time.Time) ([]*types.Entity, error) {
+	if name == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+	props := types.NamedProperties(name, value)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tagged := make(map[uint64]*entity)
+	for _, t := range m.data.entityTags {
+		if !seenSince(t.UpdatedAt, since) {
+			continue
+		}
+
+		for _, prop := range props {
+			if t.Property.PropertyType() == prop.PropertyType() && t.Property.Name() == name &&
+				(value == "" || t.Property.Value() == value) {
+				if e, found := m.data.entities[t.OwnerID]; found && e.DeletedAt.IsZero() {
+					tagged[e.ID] = e
+				}
+				break
+			}
+		}
+	}
+
+	var results []*types.Entity
+	for _, id := range sortedIDs(tagged) {
+		results = append(results, tagged[id].toEntity())
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// UpdateEntityTag sets the value of the property of the entity tag in the repository, and updates the last seen time
+// while preserving the time the tag was created.
+// Returns the updated entity tag as a types.EntityTag, types.ErrTagNotFound if the tag is not found, or an error if the update fails.
+func (m *memRepository) UpdateEntityTag(ctx context.Context, id string, value string) (*types.EntityTag, error) {
+	tagId, err := parseID(id)
+	if err != nil {
+		return nil, types.ErrTagNotFound
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, found := m.data.entityTags[tagId]
+	if !found {
+		return nil, types.ErrTagNotFound
+	}
+
+	prop, err := types.WithValue(t.Property, value)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Property = prop
+	t.UpdatedAt = time.Now()
+	return t.toEntityTag(&types.Entity{ID: strconv.FormatUint(t.OwnerID, 10)}), nil
+}
+
+// DeleteEntityTag removes an entity tag in the repository by its ID.
+// Returns one when the tag is removed, or an error if the tag is not found.
+func (m *memRepository) DeleteEntityTag(ctx context.Context, id string) (int64, error) {
+	tagId, err := parseID(id)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.data.entityTags[tagId]; !found {
+		return 0, types.NotFound("entity tag not found")
+	}
+
+	delete(m.data.entityTags, tagId)
+	return 1, nil
+}
+
+// CreateEdgeTag creates a new edge tag in the repository.
+// A tag on the edge with the same property type, name, and value is updated rather than duplicated.
+// Returns the created edge tag as a types.EdgeTag or an error if the creation fails.
+func (m *memRepository) CreateEdgeTag(ctx context.Context, edge *types.Edge, input *types.EdgeTag) (*types.EdgeTag, error) {
+	if edge == nil || input == nil || input.Property == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	edgeId, err := parseID(edge.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.data.edges[edgeId]; !found {
+		return nil, types.NotFound("edge not found")
+	}
+
+	t := m.createTag(m.data.edgeTags, edgeId, input.Property, input.CreatedAt, input.LastSeen, input.ExpiresAt)
+	return t.toEdgeTag(edge), nil
+}
+
+// CreateEdgeProperty creates a new edge tag in the repository.
+// It takes an oam.Property as input and persists it in the repository.
+// Returns the created edge tag as a types.EdgeTag or an error if the creation fails.
+func (m *memRepository) CreateEdgeProperty(ctx context.Context, edge *types.Edge, prop oam.Property) (*types.EdgeTag, error) {
+	return m.CreateEdgeTag(ctx, edge, &types.EdgeTag{Property: prop})
+}
+
+// FindEdgeTagById finds an edge tag in the repository by the ID.
+// Returns the discovered tag as a types.EdgeTag or types.ErrTagNotFound if the tag is not found.
+func (m *memRepository) FindEdgeTagById(ctx context.Context, id string) (*types.EdgeTag, error) {
+	tagId, err := parseID(id)
+	if err != nil {
+		// an invalid ID cannot match a tag
+		return nil, types.ErrTagNotFound
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, found := m.data.edgeTags[tagId]
+	if !found {
+		return nil, types.ErrTagNotFound
+	}
+
+	e, found := m.data.edges[t.OwnerID]
+	if !found || !m.liveEdge(e) {
+		return nil, types.NotFound("edge not found")
+	}
+	return t.toEdgeTag(e.toEdge()), nil
+}
+
+// FindEdgeTagsByContent finds edge tags in the repository that match the provided property data and last seen after the since parameter.
+// The properties are matched by their property type, name, and value.
+// If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching edge tags as []*types.EdgeTag or an error if the search fails.
+func (m *memRepository) FindEdgeTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EdgeTag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []*types.EdgeTag
+	for _, t := range findTagsByContent(m.data.edgeTags, prop, since) {
+		results = append(results, t.toEdgeTag(&types.Edge{ID: strconv.FormatUint(t.OwnerID, 10)}))
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero edge tags found")
+	}
+	return results, nil
+}
+
+// GetEdgeTags finds all tags for the edge with the specified names and last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// If no names are specified, all tags for the specified edge are returned.
+func (m *memRepository) GetEdgeTags(ctx context.Context, edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error) {
+	return m.GetEdgeTagsBetween(ctx, edge, since, time.Time{}, names...)
+}
+
+// GetEdgeTagsBetween finds all tags for the edge with the specified names and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no names are specified, all tags for the specified edge in the window are returned.
+func (m *memRepository) GetEdgeTagsBetween(ctx context.Context, edge *types.Edge, from, to time.Time, names ...string) ([]*types.EdgeTag, error) {
+	edgeId, err := parseID(edge.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []*types.EdgeTag
+	for _, t := range getTags(m.data.edgeTags, edgeId, from, to, names, m.expiredTags) {
+		results = append(results, t.toEdgeTag(edge))
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero tags found")
+	}
+	return results, nil
+}
+
+// UpdateEdgeTag sets the value of the property of the edge tag in the repository, and updates the last seen time
+// while preserving the time the tag was created.
+// Returns the updated edge tag as a types.EdgeTag, types.ErrTagNotFound if the tag is not found, or an error if the update fails.
+func (m *memRepository) UpdateEdgeTag(ctx context.Context, id string, value string) (*types.EdgeTag, error) {
+	tagId, err := parseID(id)
+	if err != nil {
+		return nil, types.ErrTagNotFound
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, found := m.data.edgeTags[tagId]
+	if !found {
+		return nil, types.ErrTagNotFound
+	}
+
+	prop, err := types.WithValue(t.Property, value)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Property = prop
+	t.UpdatedAt = time.Now()
+
+	edge := &types.Edge{ID: strconv.FormatUint(t.OwnerID, 10)}
+	if e, found := m.data.edges[t.OwnerID]; found {
+		edge = e.toEdge()
+	}
+	return t.toEdgeTag(edge), nil
+}
+
+// DeleteEdgeTag removes an edge tag in the repository by its ID.
+// Returns one when the tag is removed, or an error if the tag is not found.
+func (m *memRepository) DeleteEdgeTag(ctx context.Context, id string) (int64, error) {
+	tagId, err := parseID(id)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.data.edgeTags[tagId]; !found {
+		return 0, types.NotFound("edge tag not found")
+	}
+
+	delete(m.data.edgeTags, tagId)
+	return 1, nil
+}
+
+// PurgeExpiredTags removes the entity and edge tags in the repository that expired at or before the provided time.
+// A zero time removes the tags that have expired by now. Returns the number of tags removed.
+func (m *memRepository) PurgeExpiredTags(ctx context.Context, before time.Time) (int64, error) {
+	if before.IsZero() {
+		before = time.Now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	for _, tags := range []map[uint64]*tag{m.data.entityTags, m.data.edgeTags} {
+		for id, t := range tags {
+			if t.expired(before) {
+				delete(tags, id)
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// createTag adds the property to the tags of the owner, or updates the last seen time of the matching tag.
+func (m *memRepository) createTag(tags map[uint64]*tag, owner uint64, prop oam.Property, created, updated, expires time.Time) *tag {
+	now := time.Now()
+
+	// ensure that duplicate tags are not entered into the repository, and the expiration of the tag is replaced
+	for _, id := range sortedIDs(tags) {
+		if t := tags[id]; t.OwnerID == owner && sameProperty(t.Property, prop) {
+			t.UpdatedAt = now
+			t.ExpiresAt = expires
+			t.Property = prop
+			return t
+		}
+	}
+
+	t := &tag{
+		ID:        m.data.newID(),
+		CreatedAt: created,
+		UpdatedAt: updated,
+		ExpiresAt: expires,
+		Property:  prop,
+		OwnerID:   owner,
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	if t.UpdatedAt.IsZero() {
+		t.UpdatedAt = now
+	}
+
+	tags[t.ID] = t
+	return t
+}
+
+// findTagsByContent returns the tags that match the property and were last seen after the since parameter.
+func findTagsByContent(tags map[uint64]*tag, prop oam.Property, since time.Time) []*tag {
+	var results []*tag
+
+	for _, id := range sortedIDs(tags) {
+		if t := tags[id]; sameProperty(t.Property, prop) && seenSince(t.UpdatedAt, since) {
+			results = append(results, t)
+		}
+	}
+	return results
+}
+
+// getTags returns the tags of the owner with one of the names and last seen within the [from, to) window.
+func getTags(tags map[uint64]*tag, owner uint64, from, to time.Time, names []string, expired bool) []*tag {
+	var results []*tag
+
+	now := time.Now()
+	for _, id := range sortedIDs(tags) {
+		t := tags[id]
+		if t.OwnerID != owner || !seenBetween(t.UpdatedAt, from, to) || (!expired && t.expired(now)) {
+			continue
+		}
+
+		found := len(names) == 0
+		for _, name := range names {
+			if name == t.Property.Name() {
+				found = true
+				break
+			}
+		}
+
+		if found {
+			results = append(results, t)
+		}
+	}
+	return results
+}
+
+// expired reports whether the tag has expired at the provided time.
+func (t *tag) expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !t.ExpiresAt.After(now)
+}
+
+// sameProperty reports whether the properties share the property type, name, and value.
+func sameProperty(a, b oam.Property) bool {
+	return a.PropertyType() == b.PropertyType() && a.Name() == b.Name() && a.Value() == b.Value()
+}
+
+func (t *tag) toEntityTag(entity *types.Entity) *types.EntityTag {
+	return &types.EntityTag{
+		ID:        strconv.FormatUint(t.ID, 10),
+		CreatedAt: t.CreatedAt,
+		LastSeen:  t.UpdatedAt,
+		ExpiresAt: t.ExpiresAt,
+		Property:  t.Property,
+		Entity:    entity,
+	}
+}
+
+func (t *tag) toEdgeTag(edge *types.Edge) *types.EdgeTag {
+	return &types.EdgeTag{
+		ID:        strconv.FormatUint(t.ID, 10),
+		CreatedAt: t.CreatedAt,
+		LastSeen:  t.UpdatedAt,
+		ExpiresAt: t.ExpiresAt,
+		Property:  t.Property,
+		Edge:      edge,
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/options"
+	"github.com/garthoid/asset-db/types"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntityTags(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	entity, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+
+	tag, err := m.CreateEntityProperty(ctx, entity, &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	dup, err := m.CreateEntityProperty(ctx, entity, &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, tag.ID, dup.ID)
+	_, err = m.CreateEntityProperty(ctx, entity, &general.SimpleProperty{PropertyName: "other", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	found, err := m.FindEntityTagById(ctx, tag.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ID, found.Entity.ID)
+
+	tags, err := m.FindEntityTagsByContent(ctx, &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, tags, 1)
+
+	tags, err = m.GetEntityTags(ctx, entity, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, tags, 2)
+	tags, err = m.GetEntityTags(ctx, entity, time.Time{}, "other")
+	assert.NoError(t, err)
+	assert.Len(t, tags, 1)
+	_, err = m.GetEntityTags(ctx, entity, time.Now().Add(time.Minute))
+	assert.Error(t, err)
+
+	_, err = m.DeleteEntityTag(ctx, tag.ID)
+	assert.NoError(t, err)
+	_, err = m.FindEntityTagById(ctx, tag.ID)
+	assert.ErrorIs(t, err, types.ErrTagNotFound)
+	_, err = m.FindEntityTagById(ctx, "invalid")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	// the tags are removed along with the entity
+	_, err = m.DeleteEntity(ctx, entity.ID)
+	assert.NoError(t, err)
+	_, err = m.FindEntityTagsByContent(ctx, &general.SimpleProperty{PropertyName: "other", PropertyValue: "bar"}, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestEdgeTags(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	edge, err := m.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	tag, err := m.CreateEdgeProperty(ctx, edge, &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"})
+	assert.NoError(t, err)
+
+	found, err := m.FindEdgeTagById(ctx, tag.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, edge.ID, found.Edge.ID)
+
+	tags, err := m.FindEdgeTagsByContent(ctx, &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, tags, 1)
+	tags, err = m.GetEdgeTags(ctx, edge, time.Time{}, "test")
+	assert.NoError(t, err)
+	assert.Len(t, tags, 1)
+
+	_, err = m.DeleteEdgeTag(ctx, tag.ID)
+	assert.NoError(t, err)
+	_, err = m.GetEdgeTags(ctx, edge, time.Time{})
+	assert.Error(t, err)
+	_, err = m.FindEdgeTagById(ctx, tag.ID)
+	assert.ErrorIs(t, err, types.ErrTagNotFound)
+	_, err = m.FindEdgeTagById(ctx, "invalid")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestCreateEntityTags(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	e1, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	e2, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+
+	prop := &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"}
+	existing, err := m.CreateEntityProperty(ctx, e2, prop)
+	assert.NoError(t, err)
+
+	tags, err := m.CreateEntityTags(ctx, []string{e1.ID, "9999", e2.ID, e1.ID}, &types.EntityTag{Property: prop})
+	var missing *types.MissingEntitiesError
+	assert.True(t, errors.As(err, &missing))
+	assert.Equal(t, []string{"9999"}, missing.IDs)
+
+	// the tags are returned in the order of the entities, and the existing tag is not duplicated
+	assert.Len(t, tags, 2)
+	assert.Equal(t, e1.ID, tags[0].Entity.ID)
+	assert.Equal(t, e2.ID, tags[1].Entity.ID)
+	assert.Equal(t, existing.ID, tags[1].ID)
+
+	found, err := m.FindEntityTagsByContent(ctx, prop, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	_, err = m.CreateEntityTags(ctx, []string{e1.ID}, &types.EntityTag{})
+	assert.Error(t, err)
+}
+
+func TestUpdateTags(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	edge, err := m.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	created := time.Now().Add(-time.Hour)
+	etag, err := m.CreateEntityTag(ctx, from, &types.EntityTag{
+		CreatedAt: created,
+		LastSeen:  created,
+		Property:  &general.SimpleProperty{PropertyName: "test", PropertyValue: "foo"},
+	})
+	assert.NoError(t, err)
+
+	updated, err := m.UpdateEntityTag(ctx, etag.ID, "bar")
+	assert.NoError(t, err)
+	assert.Equal(t, etag.ID, updated.ID)
+	assert.Equal(t, "bar", updated.Property.Value())
+	assert.Equal(t, etag.CreatedAt, updated.CreatedAt)
+	assert.True(t, updated.LastSeen.After(etag.LastSeen))
+	// the tag returned by the create call is not modified
+	assert.Equal(t, "foo", etag.Property.Value())
+
+	found, err := m.FindEntityTagById(ctx, etag.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", found.Property.Value())
+
+	_, err = m.UpdateEntityTag(ctx, "9999", "bar")
+	assert.ErrorIs(t, err, types.ErrTagNotFound)
+
+	source, err := m.CreateEdgeProperty(ctx, edge, &general.SourceProperty{Source: "test", Confidence: 50})
+	assert.NoError(t, err)
+
+	utag, err := m.UpdateEdgeTag(ctx, source.ID, "90")
+	assert.NoError(t, err)
+	assert.Equal(t, "90", utag.Property.Value())
+	assert.Equal(t, edge.ID, utag.Edge.ID)
+	assert.Equal(t, source.CreatedAt, utag.CreatedAt)
+
+	_, err = m.UpdateEdgeTag(ctx, source.ID, "high")
+	assert.Error(t, err)
+	_, err = m.UpdateEdgeTag(ctx, "9999", "90")
+	assert.ErrorIs(t, err, types.ErrTagNotFound)
+}
+
+func TestFindEntitiesByTag(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	e1, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	e2, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	e3, err := m.CreateAsset(ctx, &dns.FQDN{Name: "api.owasp.org"})
+	assert.NoError(t, err)
+
+	_, err = m.CreateEntityProperty(ctx, e1, &general.SourceProperty{Source: "crtsh", Confidence: 100})
+	assert.NoError(t, err)
+	_, err = m.CreateEntityProperty(ctx, e2, &general.SourceProperty{Source: "crtsh", Confidence: 50})
+	assert.NoError(t, err)
+	_, err = m.CreateEntityProperty(ctx, e3, &general.SimpleProperty{PropertyName: "crtsh", PropertyValue: "100"})
+	assert.NoError(t, err)
+
+	// the name is matched across the property types when the value is empty
+	entities, err := m.FindEntitiesByTag(ctx, "crtsh", "", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 3)
+
+	entities, err = m.FindEntitiesByTag(ctx, "crtsh", "100", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 2)
+	assert.Equal(t, e1.ID, entities[0].ID)
+	assert.Equal(t, e3.ID, entities[1].ID)
+
+	_, err = m.FindEntitiesByTag(ctx, "crtsh", "", time.Now().Add(time.Minute))
+	assert.Error(t, err)
+	_, err = m.FindEntitiesByTag(ctx, "dnsdb", "", time.Time{})
+	assert.Error(t, err)
+	_, err = m.FindEntitiesByTag(ctx, "", "", time.Time{})
+	assert.Error(t, err)
+}
+
+func TestTagsBetween(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	from, err := m.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	to, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.owasp.org"})
+	assert.NoError(t, err)
+	edge, err := m.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, value := range []string{"foo", "bar"} {
+		seen := start.Add(time.Duration(i) * time.Hour)
+		prop := &general.SimpleProperty{PropertyName: "test", PropertyValue: value}
+
+		_, err := m.CreateEntityTag(ctx, from, &types.EntityTag{LastSeen: seen, Property: prop})
+		assert.NoError(t, err)
+		_, err = m.CreateEdgeTag(ctx, edge, &types.EdgeTag{LastSeen: seen, Property: prop})
+		assert.NoError(t, err)
+	}
+
+	etags, err := m.GetEntityTagsBetween(ctx, from, start.Add(time.Hour), time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, etags, 1) {
+		assert.Equal(t, "bar", etags[0].Property.Value())
+	}
+	_, err = m.GetEntityTagsBetween(ctx, from, start, start.Add(time.Hour), "missing")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	edgeTags, err := m.GetEdgeTagsBetween(ctx, edge, start, start.Add(time.Hour), "test")
+	assert.NoError(t, err)
+	if assert.Len(t, edgeTags, 1) {
+		assert.Equal(t, "foo", edgeTags[0].Property.Value())
+	}
+	_, err = m.GetEdgeTagsBetween(ctx, edge, start.Add(2*time.Hour), time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestGetEntityTagsMatching(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+	entity, err := m.CreateAsset(ctx, &dns.FQDN{Name: "matching.example.com"})
+	assert.NoError(t, err)
+
+	crtsh, err := m.CreateEntityProperty(ctx, entity, &general.SourceProperty{Source: "crtsh", Confidence: 90})
+	assert.NoError(t, err)
+	source, err := m.CreateEntityProperty(ctx, entity, &general.SimpleProperty{PropertyName: "source", PropertyValue: "crtsh"})
+	assert.NoError(t, err)
+	_, err = m.CreateEntityProperty(ctx, entity, &general.SimpleProperty{PropertyName: "source", PropertyValue: "dns"})
+	assert.NoError(t, err)
+
+	// both the name and the value are matched
+	tags, err := m.GetEntityTagsMatching(ctx, entity, time.Time{}, "source", "crtsh")
+	assert.NoError(t, err)
+	if assert.Len(t, tags, 1) {
+		assert.Equal(t, source.ID, tags[0].ID)
+	}
+
+	// only the name is matched when the value is empty
+	tags, err = m.GetEntityTagsMatching(ctx, entity, time.Time{}, "source", "")
+	assert.NoError(t, err)
+	assert.Len(t, tags, 2)
+
+	tags, err = m.GetEntityTagsMatching(ctx, entity, time.Time{}, "crtsh", "90")
+	assert.NoError(t, err)
+	if assert.Len(t, tags, 1) {
+		assert.Equal(t, crtsh.ID, tags[0].ID)
+	}
+
+	_, err = m.GetEntityTagsMatching(ctx, entity, time.Time{}, "source", "bing")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = m.GetEntityTagsMatching(ctx, entity, time.Now().Add(time.Minute), "source", "crtsh")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = m.GetEntityTagsMatching(ctx, entity, time.Time{}, "", "crtsh")
+	assert.Error(t, err)
+}
+
+func TestExpiredTags(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+	e1, err := m.CreateAsset(ctx, &dns.FQDN{Name: "expired.example.com"})
+	assert.NoError(t, err)
+	e2, err := m.CreateAsset(ctx, &dns.FQDN{Name: "www.expired.example.com"})
+	assert.NoError(t, err)
+	edge, err := m.CreateEdge(ctx, &types.Edge{
+		Relation:   &dns.BasicDNSRelation{Name: "dns_record", Header: dns.RRHeader{RRType: 5}},
+		FromEntity: e1,
+		ToEntity:   e2,
+	})
+	assert.NoError(t, err)
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	_, err = m.CreateEntityTag(ctx, e1, &types.EntityTag{
+		ExpiresAt: past,
+		Property:  &general.SimpleProperty{PropertyName: "expired", PropertyValue: "yes"},
+	})
+	assert.NoError(t, err)
+	live, err := m.CreateEntityTag(ctx, e1, &types.EntityTag{
+		ExpiresAt: future,
+		Property:  &general.SimpleProperty{PropertyName: "expired", PropertyValue: "no"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, future.Unix(), live.ExpiresAt.Unix())
+	_, err = m.CreateEdgeTag(ctx, edge, &types.EdgeTag{
+		ExpiresAt: past,
+		Property:  &general.SimpleProperty{PropertyName: "expired", PropertyValue: "yes"},
+	})
+	assert.NoError(t, err)
+
+	// the expired tags are excluded from the results
+	tags, err := m.GetEntityTags(ctx, e1, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, tags, 1) {
+		assert.Equal(t, live.ID, tags[0].ID)
+	}
+	_, err = m.GetEdgeTags(ctx, edge, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = m.GetEntityTagsMatching(ctx, e1, time.Time{}, "expired", "yes")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	// the expired tags remain available when requested
+	all := New(options.WithExpiredTags())
+	all.data = m.data
+	tags, err = all.GetEntityTags(ctx, e1, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, tags, 2)
+
+	count, err := m.PurgeExpiredTags(ctx, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	tags, err = all.GetEntityTags(ctx, e1, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, tags, 1)
+
+	// creating a duplicate replaces the expiration of the existing tag
+	dup, err := m.CreateEntityTag(ctx, e1, &types.EntityTag{
+		Property: &general.SimpleProperty{PropertyName: "expired", PropertyValue: "no"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, live.ID, dup.ID)
+	assert.True(t, dup.ExpiresAt.IsZero())
+
+	count, err = m.PurgeExpiredTags(ctx, future.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
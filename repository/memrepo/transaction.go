@@ -0,0 +1,47 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package memrepo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/garthoid/asset-db/types"
+)
+
+// WithTransaction executes the provided function against a copy of the repository data.
+// The changes made through the repository passed to fn are kept when fn returns nil and discarded otherwise.
+// Calls made on the parent repository block until the transaction completes, and calls made on a
+// repository that is already scoped to a transaction reuse that transaction.
+func (m *memRepository) WithTransaction(ctx context.Context, fn func(tx types.Repository) error) error {
+	if m.intx {
+		return fn(m)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	txrepo := &memRepository{
+		mu:          new(sync.RWMutex),
+		data:        m.data.clone(),
+		softDelete:  m.softDelete,
+		lastSeen:    m.lastSeen,
+		orderBy:     m.orderBy,
+		orderDesc:   m.orderDesc,
+		expiredTags: m.expiredTags,
+		validate:    m.validate,
+		intx:        true,
+	}
+	if err := fn(txrepo); err != nil {
+		return err
+	}
+
+	m.data = txrepo.data
+	return nil
+}
@@ -0,0 +1,22 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// labelPattern is the allowlist of the node labels and relationship types that can be written into a query.
+// Unlike the asset and property values, which are passed as query parameters, labels cannot be parameterized.
+var labelPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// checkLabel returns an error if the label does not match the allowlist pattern, so it cannot alter the query.
+func checkLabel(label string) error {
+	if !labelPattern.MatchString(label) {
+		return fmt.Errorf("the label %q is not permitted in a query", label)
+	}
+	return nil
+}
@@ -6,27 +6,75 @@ package neo4j
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/garthoid/asset-db/internal/redact"
+	"github.com/garthoid/asset-db/internal/retry"
+	"github.com/garthoid/asset-db/internal/track"
+	"github.com/garthoid/asset-db/options"
+	"github.com/google/uuid"
 	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j/config"
 )
 
 const Neo4j string = "neo4j"
 
+// defaultBatchSize is the maximum number of nodes created by a single UNWIND statement during batch operations.
+const defaultBatchSize = 100
+
+// defaultRetryBaseDelay is the delay before the first retry of a query that failed with a transient error.
+const defaultRetryBaseDelay = 100 * time.Millisecond
+
+// defaultConnectRetryDelay is the delay before the second attempt of the initial connection set by options.WithConnectRetry.
+const defaultConnectRetryDelay = time.Second
+
+// errWorkspace is returned when a workspace is requested, since the Neo4j repository does not store one.
+var errWorkspace = errors.New("the Neo4j repository does not support workspaces")
+
+// errTablePrefix is returned when a table prefix is requested, since the Neo4j repository has no tables to prefix.
+var errTablePrefix = errors.New("the Neo4j repository does not support table prefixes, use options.WithNeo4jDatabase")
+
 // neoRepository is a repository implementation using Neo4j as the underlying DBMS.
 type neoRepository struct {
-	db     neo4jdb.DriverWithContext
-	dbname string
+	db          neo4jdb.DriverWithContext
+	dbname      string
+	fetchSize   int
+	batchSize   int
+	softDelete  bool
+	lastSeen    bool
+	order       string
+	expiredTags bool
+	maxAttempts int
+	retryDelay  time.Duration
+	timeout     time.Duration
+	log         options.Logger
+	newID       func() string
+	validate    options.Validator
+	borrowed    bool
+	tx          neo4jdb.ExplicitTransaction
+	captured    *[]capturedQuery
+	open        *track.Set
 }
 
 // New creates a new instance of the asset database repository.
-func New(dbtype, dsn string) (*neoRepository, error) {
+// Connection pool settings not provided in the options keep their defaults.
+func New(dbtype, dsn string, opts ...options.Option) (*neoRepository, error) {
+	o := options.Apply(opts...)
+	if o.Workspace != "" {
+		return nil, errWorkspace
+	}
+	if o.TablePrefix != "" {
+		return nil, errTablePrefix
+	}
+
 	u, err := url.Parse(dsn)
 	if err != nil {
-		return nil, err
+		// the DSN held by the error includes the password
+		return nil, redact.Error(err)
 	}
 
 	auth := neo4jdb.NoAuth()
@@ -36,55 +84,219 @@ func New(dbtype, dsn string) (*neoRepository, error) {
 		password, _ = u.User.Password()
 		auth = neo4jdb.BasicAuth(username, password, "")
 	}
+	// the database of the options takes precedence over the path, and an empty name selects the server default
 	dbname := strings.TrimPrefix(u.Path, "/")
+	if o.Neo4jDatabase != "" {
+		dbname = o.Neo4jDatabase
+	}
 
-	// --- SUGGESTED CHANGE: START ---
-
-	// Use the original DSN. The driver natively handles bolt+s and bolt+ssc.
-	originalDSN := dsn
+	// the DSN is passed to the driver unchanged, so the "neo4j" schemes use the routing of a cluster,
+	// while the "bolt" schemes connect directly to a single instance
+	if !supportedScheme(u.Scheme) {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
 
-	// The configFunc will manually configure TLS *only* for unencrypted schemes.
 	configFunc := func(cfg *config.Config) {
 		// Apply common settings
 		cfg.MaxConnectionPoolSize = 20
 		cfg.MaxConnectionLifetime = time.Hour
 		cfg.ConnectionLivenessCheckTimeout = 10 * time.Minute
+		if o.MaxConnections > 0 {
+			cfg.MaxConnectionPoolSize = o.MaxConnections
+		}
+		if o.ConnMaxLifetime > 0 {
+			cfg.MaxConnectionLifetime = o.ConnMaxLifetime
+		}
+		if o.ConnMaxIdleTime > 0 {
+			cfg.ConnectionLivenessCheckTimeout = o.ConnMaxIdleTime
+		}
+		if o.AcquireTimeout > 0 {
+			cfg.ConnectionAcquisitionTimeout = o.AcquireTimeout
+		}
+		if o.TxRetryTimeout > 0 {
+			cfg.MaxTransactionRetryTime = o.TxRetryTimeout
+		}
+		// a liveness check timeout of zero tests each connection before it is reused
+		if o.ConnHealthCheck {
+			cfg.ConnectionLivenessCheckTimeout = 0
+		}
 
-		switch u.Scheme {
-		case "bolt+ssc", "neo4j+ssc":
-			// Let the driver handle this scheme natively
-		case "bolt+s", "neo4j+s":
-			// Let the driver handle this scheme natively
-		case "bolt", "neo4j":
-			// Driver may default to encryption, so explicitly disable it.
+		// the driver encrypts the "+s" and "+ssc" schemes, and the plain schemes are left unencrypted
+		if o.Neo4jTLSConfig != nil {
+			cfg.TlsConfig = o.Neo4jTLSConfig.Clone()
+		}
+		if u.Scheme == "bolt" || u.Scheme == "neo4j" {
 			cfg.TlsConfig = nil
 		}
 	}
-	// --- SUGGESTED CHANGE: END ---
 
-	// Create driver with appropriate configuration
-	driver, err := neo4jdb.NewDriverWithContext(originalDSN, auth, configFunc) // <-- Use originalDSN
+	driver, err := neo4jdb.NewDriverWithContext(dsn, auth, configFunc)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := driver.VerifyConnectivity(ctx); err != nil {
+	if err := verifyConnectivity(driver, o); err != nil {
 		_ = driver.Close(context.Background()) // best-effort cleanup to avoid leak
-		return nil, err
+		return nil, translateError(err)
+	}
+	return newRepository(driver, dbname, o), nil
+}
+
+// NewWithDriver creates a new instance of the asset database repository that uses the driver provided by the caller,
+// such as one with a custom TLS configuration, resolver, or logger, for the database with the provided name.
+// When the name is empty, the sessions use the default database of the server. The options that configure the
+// driver, such as the connection pool settings and the TLS configuration, are not applied to it.
+// The driver remains owned by the caller, so Close does not close it.
+func NewWithDriver(driver neo4jdb.DriverWithContext, dbname string, opts ...options.Option) (*neoRepository, error) {
+	if driver == nil {
+		return nil, errors.New("failed input validation checks")
 	}
 
-	return &neoRepository{db: driver, dbname: dbname}, nil
+	o := options.Apply(opts...)
+	if o.Workspace != "" {
+		return nil, errWorkspace
+	}
+	if o.TablePrefix != "" {
+		return nil, errTablePrefix
+	}
+	if o.Neo4jDatabase != "" {
+		dbname = o.Neo4jDatabase
+	}
+
+	if err := verifyConnectivity(driver, o); err != nil {
+		return nil, translateError(err)
+	}
+
+	neo := newRepository(driver, dbname, o)
+	neo.borrowed = true
+	return neo, nil
+}
+
+// verifyConnectivity verifies the connectivity to the server, and attempts it again after a transient error when
+// options.WithConnectRetry is provided, such as while the server is still starting. Each attempt waits up to 5 seconds.
+func verifyConnectivity(driver neo4jdb.DriverWithContext, o *options.Options) error {
+	delay := defaultConnectRetryDelay
+	if o.ConnectRetryDelay > 0 {
+		delay = o.ConnectRetryDelay
+	}
+
+	return retry.Do(context.Background(), o.ConnectAttempts, delay, isRetryable, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		return driver.VerifyConnectivity(ctx)
+	})
+}
+
+// newRepository returns the repository that uses the driver, with the settings of the options.
+func newRepository(driver neo4jdb.DriverWithContext, dbname string, o *options.Options) *neoRepository {
+	batchSize := defaultBatchSize
+	if o.BatchSize > 0 {
+		batchSize = o.BatchSize
+	}
+
+	retryDelay := defaultRetryBaseDelay
+	if o.RetryBaseDelay > 0 {
+		retryDelay = o.RetryBaseDelay
+	}
+
+	newID := uuid.NewString
+	if o.IDGenerator != nil {
+		newID = o.IDGenerator
+	}
+
+	// the driver decides the fetch size of the sessions when none is set
+	fetchSize := neo4jdb.FetchDefault
+	if o.Neo4jFetchSize > 0 {
+		fetchSize = o.Neo4jFetchSize
+	}
+
+	return &neoRepository{
+		db:          driver,
+		dbname:      dbname,
+		fetchSize:   fetchSize,
+		batchSize:   batchSize,
+		softDelete:  o.SoftDelete,
+		lastSeen:    o.LastSeenOrder,
+		order:       entityOrder(o),
+		expiredTags: o.ExpiredTags,
+		maxAttempts: o.MaxAttempts,
+		retryDelay:  retryDelay,
+		timeout:     o.QueryTimeout,
+		log:         o.Logger,
+		newID:       newID,
+		validate:    o.Validator,
+		open:        track.New(),
+	}
+}
+
+// entityOrder returns the ORDER BY clause of FindEntitiesByType set by options.WithOrder, which is empty
+// when the option is not provided or the field is not supported.
+func entityOrder(o *options.Options) string {
+	var prop string
+	switch o.OrderBy {
+	case options.OrderByCreatedAt:
+		prop = "a.created_at"
+	case options.OrderByUpdatedAt:
+		prop = "a.updated_at"
+	case options.OrderByType:
+		prop = "a.etype"
+	default:
+		return ""
+	}
+
+	if o.OrderDirection == options.Descending {
+		return " ORDER BY " + prop + " DESC, a.entity_id DESC"
+	}
+	return " ORDER BY " + prop + ", a.entity_id"
+}
+
+// supportedScheme reports whether the scheme of a DSN is one of the "bolt" and "neo4j" schemes supported by the driver.
+func supportedScheme(scheme string) bool {
+	switch scheme {
+	case "bolt", "bolt+s", "bolt+ssc", "neo4j", "neo4j+s", "neo4j+ssc":
+		return true
+	}
+	return false
 }
 
 // Close implements the Repository interface.
+// Closing a repository scoped to a transaction has no effect, since the driver is owned by the parent repository,
+// and the driver provided to NewWithDriver is left open for the caller. The iterators that were not closed are closed first,
+// so their sessions return the connections to the pool, and an error reports how many were left open. The rows of RawCypher
+// are not tracked, since their records are read before RawCypher returns and they hold no connection.
 func (neo *neoRepository) Close() error {
-	return neo.db.Close(context.Background())
+	if neo.tx != nil {
+		return nil
+	}
+
+	leaked := closeIterators(neo.open)
+	if neo.borrowed {
+		return leaked
+	}
+	return errors.Join(leaked, neo.db.Close(context.Background()))
+}
+
+// Ping verifies that the database is reachable using the provided context.
+func (neo *neoRepository) Ping(ctx context.Context) error {
+	return translateError(neo.db.VerifyConnectivity(ctx))
+}
+
+// Maintenance is a no-op, since Neo4j refreshes the statistics of its query planner on its own,
+// and the space of the deleted nodes is reused by the store, so only the context is checked.
+func (neo *neoRepository) Maintenance(ctx context.Context) error {
+	return ctx.Err()
 }
 
 // GetDBType returns the type of the database.
 func (neo *neoRepository) GetDBType() string {
 	return Neo4j
 }
+
+// Driver returns the Neo4j driver of the repository, which shares the connection pool of the repository, such as
+// for running custom sessions and transactions without opening a second pool. The queries run through the driver
+// are not part of a transaction the repository is scoped to. The driver must not be closed, since it is owned by
+// the repository, and the writes made through it bypass the checks of the Repository methods.
+func (neo *neoRepository) Driver() neo4jdb.DriverWithContext {
+	return neo.db
+}
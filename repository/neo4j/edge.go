@@ -8,7 +8,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"reflect"
 	"strings"
 	"time"
 
@@ -19,8 +18,9 @@ import (
 
 // CreateEdge creates an edge between two entities in the database.
 // The edge is established by creating a new Edge in the database, linking the two entities.
+// An edge with the same entities, relation type, and label is not duplicated, and has its properties and last seen time updated.
 // Returns the created edge as a types.Edge or an error if the link creation fails.
-func (neo *neoRepository) CreateEdge(edge *types.Edge) (*types.Edge, error) {
+func (neo *neoRepository) CreateEdge(ctx context.Context, edge *types.Edge) (*types.Edge, error) {
 	if edge == nil || edge.Relation == nil || edge.FromEntity == nil ||
 		edge.FromEntity.Asset == nil || edge.ToEntity == nil || edge.ToEntity.Asset == nil {
 		return nil, errors.New("failed input validation checks")
@@ -32,117 +32,157 @@ func (neo *neoRepository) CreateEdge(edge *types.Edge) (*types.Edge, error) {
 			edge.FromEntity.Asset.AssetType(), edge.Relation.Label(), edge.ToEntity.Asset.AssetType())
 	}
 
-	if edge.LastSeen.IsZero() {
-		edge.LastSeen = time.Now()
+	e, _, err := neo.upsertEdge(ctx, edge)
+	return e, err
+}
+
+// UpsertEdge creates the edge in the database, or updates the properties and last seen time of the existing edge with
+// the same entities, relation type, and label. The relationship is written with a MERGE on the relationship type, which
+// is the label, and the etype property, so an edge that already exists is matched rather than duplicated.
+// Returns the edge as a types.Edge, the outcome of the upsert, or an error if the upsert fails.
+func (neo *neoRepository) UpsertEdge(ctx context.Context, edge *types.Edge) (*types.Edge, types.UpsertResult, error) {
+	if edge == nil || edge.Relation == nil || edge.FromEntity == nil ||
+		edge.FromEntity.Asset == nil || edge.ToEntity == nil || edge.ToEntity.Asset == nil {
+		return nil, "", errors.New("failed input validation checks")
 	}
-	// ensure that duplicate relationships are not entered into the database
-	if e, found := neo.isDuplicateEdge(edge, edge.LastSeen); found {
-		return e, nil
+
+	if !oam.ValidRelationship(edge.FromEntity.Asset.AssetType(),
+		edge.Relation.Label(), edge.Relation.RelationType(), edge.ToEntity.Asset.AssetType()) {
+		return nil, "", fmt.Errorf("%s -%s-> %s is not valid in the taxonomy",
+			edge.FromEntity.Asset.AssetType(), edge.Relation.Label(), edge.ToEntity.Asset.AssetType())
 	}
+	return neo.upsertEdge(ctx, edge)
+}
 
-	if edge.CreatedAt.IsZero() {
-		edge.CreatedAt = time.Now()
+// upsertEdge writes the edge that has passed the input validation checks, and reports the outcome of the upsert.
+func (neo *neoRepository) upsertEdge(ctx context.Context, edge *types.Edge) (*types.Edge, types.UpsertResult, error) {
+	rtype := strings.ToUpper(edge.Relation.Label())
+	if err := checkLabel(rtype); err != nil {
+		return nil, "", err
 	}
 
-	props, err := edgePropsMap(edge)
+	if err := neo.checkEdgeEntities(ctx, edge); err != nil {
+		return nil, "", err
+	}
+
+	input := *edge
+	if input.LastSeen.IsZero() {
+		input.LastSeen = time.Now()
+	}
+	if input.CreatedAt.IsZero() {
+		input.CreatedAt = time.Now()
+	}
+
+	props, err := edgePropsMap(&input)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// the creation time of an existing edge is kept
+	updates := make(map[string]interface{}, len(props))
+	for k, v := range props {
+		if k != "created_at" {
+			updates[k] = v
+		}
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	from := fmt.Sprintf("MATCH (from:Entity {entity_id: '%s'})", edge.FromEntity.ID)
-	to := fmt.Sprintf("MATCH (to:Entity {entity_id: '%s'})", edge.ToEntity.ID)
-	query := fmt.Sprintf("%s %s CREATE (from)-[r:%s $props]->(to) RETURN r", from, to, strings.ToUpper(edge.Relation.Label()))
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query,
-		map[string]interface{}{"props": props},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-	)
+	query := fmt.Sprintf("MATCH (from:Entity {entity_id: $fid}) MATCH (to:Entity {entity_id: $tid}) "+
+		"OPTIONAL MATCH (from)-[old:%s {etype: $etype}]->(to) WITH from, to, collect(properties(old))[0] AS before "+
+		"MERGE (from)-[r:%s {etype: $etype}]->(to) ON CREATE SET r = $props ON MATCH SET r += $updates "+
+		"RETURN r, before LIMIT 1", rtype, rtype)
+	result, err := neo.executeQuery(ctx, query, map[string]interface{}{
+		"fid":     edge.FromEntity.ID,
+		"tid":     edge.ToEntity.ID,
+		"etype":   string(edge.Relation.RelationType()),
+		"props":   props,
+		"updates": updates,
+	})
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if len(result.Records) == 0 {
-		return nil, errors.New("no records returned from the query")
+		return nil, "", errors.New("no records returned from the query")
 	}
 
 	rel, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Relationship](result.Records[0], "r")
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if isnil {
-		return nil, errors.New("the record value for the relationship is nil")
+		return nil, "", errors.New("the record value for the relationship is nil")
+	}
+
+	// the properties held by the relationship before the write are nil when it was created
+	before, isnil, err := neo4jdb.GetRecordValue[map[string]interface{}](result.Records[0], "before")
+	if err != nil {
+		return nil, "", err
+	}
+
+	upsert := types.UpsertCreated
+	if !isnil {
+		upsert = types.UpsertUpdated
+		if old, err := relationshipToEdge(neo4jdb.Relationship{Type: rtype, Props: before}); err == nil &&
+			sameContent(old.Relation, edge.Relation) {
+			upsert = types.UpsertUnchanged
+		}
 	}
 
 	r, err := relationshipToEdge(rel)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	r.FromEntity = edge.FromEntity
 	r.ToEntity = edge.ToEntity
 
-	return r, nil
+	return r, upsert, nil
 }
 
-// isDuplicateEdge checks if the relationship between source and dest already exists.
-func (neo *neoRepository) isDuplicateEdge(edge *types.Edge, updated time.Time) (*types.Edge, bool) {
-	var dup bool
-	var e *types.Edge
-
-	if outs, err := neo.OutgoingEdges(edge.FromEntity, time.Time{}, edge.Relation.Label()); err == nil {
-		for _, out := range outs {
-			if edge.ToEntity.ID == out.ToEntity.ID && reflect.DeepEqual(edge.Relation, out.Relation) {
-				_ = neo.edgeSeen(out, updated)
+// checkEdgeEntities returns types.ErrEntityNotFound, naming the missing entity, unless both entities of the edge exist.
+// The soft-deleted entities no longer carry the Entity label, so they are not matched.
+func (neo *neoRepository) checkEdgeEntities(ctx context.Context, edge *types.Edge) error {
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
 
-				e, err = neo.FindEdgeById(out.ID)
-				if err != nil {
-					return nil, false
-				}
+	result, err := neo.executeRead(ctx, "MATCH (a:Entity) WHERE a.entity_id IN [$fid, $tid] RETURN a.entity_id AS eid",
+		map[string]interface{}{"fid": edge.FromEntity.ID, "tid": edge.ToEntity.ID})
+	if err != nil {
+		return err
+	}
 
-				dup = true
-				break
-			}
+	found := make(map[string]struct{}, len(result.Records))
+	for _, record := range result.Records {
+		if eid, _, err := neo4jdb.GetRecordValue[string](record, "eid"); err == nil {
+			found[eid] = struct{}{}
 		}
 	}
-
-	return e, dup
-}
-
-// edgeSeen updates the updated_at timestamp for the specified edge.
-func (neo *neoRepository) edgeSeen(rel *types.Edge, updated time.Time) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	query := fmt.Sprintf("MATCH ()-[r]->() WHERE elementId(r) = $eid SET r.updated_at = localDateTime('%s')", timeToNeo4jTime(updated))
-	_, err := neo4jdb.ExecuteQuery(ctx, neo.db, query,
-		map[string]interface{}{
-			"eid": rel.ID,
-		},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-	)
-	return err
+	if _, ok := found[edge.FromEntity.ID]; !ok {
+		return types.EdgeEntityNotFound("from", edge.FromEntity.ID)
+	}
+	if _, ok := found[edge.ToEntity.ID]; !ok {
+		return types.EdgeEntityNotFound("to", edge.ToEntity.ID)
+	}
+	return nil
 }
 
-func (neo *neoRepository) FindEdgeById(id string) (*types.Edge, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func (neo *neoRepository) FindEdgeById(ctx context.Context, id string) (*types.Edge, error) {
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db,
+	result, err := neo.executeRead(ctx,
 		"MATCH (from:Entity)-[r]->(to:Entity) WHERE elementId(r) = $eid RETURN r, from.entity_id AS fid, to.entity_id AS tid",
 		map[string]interface{}{
 			"eid": id,
 		},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
 	)
 
 	if err != nil {
 		return nil, err
 	}
 	if len(result.Records) == 0 {
-		return nil, errors.New("no edge was found")
+		return nil, types.NotFound("no edge was found")
 	}
 
 	r, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Relationship](result.Records[0], "r")
@@ -181,22 +221,22 @@ func (neo *neoRepository) FindEdgeById(id string) (*types.Edge, error) {
 // IncomingEdges finds all edges pointing to the entity of the specified labels and last seen after the since parameter.
 // If since.IsZero(), the parameter will be ignored.
 // If no labels are specified, all incoming eges are returned.
-func (neo *neoRepository) IncomingEdges(entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func (neo *neoRepository) IncomingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	return neo.IncomingEdgesBetween(ctx, entity, since, time.Time{}, labels...)
+}
+
+// IncomingEdgesBetween finds all edges pointing to the entity of the specified labels and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no labels are specified, all incoming edges in the window are returned.
+func (neo *neoRepository) IncomingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	query := "MATCH (:Entity {entity_id: $eid})<-[r]-(from:Entity) RETURN r, from.entity_id AS fid"
-	if !since.IsZero() {
-		query = fmt.Sprintf("MATCH (:Entity {entity_id: $eid})<-[r]-(from:Entity) WHERE r.updated_at >= localDateTime('%s') RETURN r, from.entity_id AS fid", timeToNeo4jTime(since))
-	}
+	query := fmt.Sprintf("MATCH (:Entity {entity_id: $eid})<-[r]-(from:Entity)%s RETURN r, from.entity_id AS fid", seenBetween("r", from, to))
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query,
-		map[string]interface{}{
-			"eid": entity.ID,
-		},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-	)
+	result, err := neo.executeRead(ctx, query, map[string]interface{}{
+		"eid": entity.ID,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -244,7 +284,7 @@ func (neo *neoRepository) IncomingEdges(entity *types.Entity, since time.Time, l
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero edges found")
+		return nil, types.NotFound("zero edges found")
 	}
 	return results, nil
 }
@@ -252,22 +292,22 @@ func (neo *neoRepository) IncomingEdges(entity *types.Entity, since time.Time, l
 // OutgoingEdges finds all edges from the entity of the specified labels and last seen after the since parameter.
 // If since.IsZero(), the parameter will be ignored.
 // If no labels are specified, all outgoing edges are returned.
-func (neo *neoRepository) OutgoingEdges(entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func (neo *neoRepository) OutgoingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	return neo.OutgoingEdgesBetween(ctx, entity, since, time.Time{}, labels...)
+}
+
+// OutgoingEdgesBetween finds all edges from the entity of the specified labels and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no labels are specified, all outgoing edges in the window are returned.
+func (neo *neoRepository) OutgoingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	query := "MATCH (:Entity {entity_id: $eid})-[r]->(to:Entity) RETURN r, to.entity_id AS tid"
-	if !since.IsZero() {
-		query = fmt.Sprintf("MATCH (:Entity {entity_id: $eid})-[r]->(to:Entity) WHERE r.updated_at >= localDateTime('%s') RETURN r, to.entity_id AS tid", timeToNeo4jTime(since))
-	}
+	query := fmt.Sprintf("MATCH (:Entity {entity_id: $eid})-[r]->(to:Entity)%s RETURN r, to.entity_id AS tid", seenBetween("r", from, to))
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query,
-		map[string]interface{}{
-			"eid": entity.ID,
-		},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-	)
+	result, err := neo.executeRead(ctx, query, map[string]interface{}{
+		"eid": entity.ID,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -315,26 +355,454 @@ func (neo *neoRepository) OutgoingEdges(entity *types.Entity, since time.Time, l
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero edges found")
+		return nil, types.NotFound("zero edges found")
+	}
+	return results, nil
+}
+
+// IncomingEdgesForAll finds the edges pointing to any of the entities of the specified labels and last seen after the since parameter.
+// The edges are found with a single query that unwinds the entity IDs, rather than a query per entity.
+// If since.IsZero(), the parameter will be ignored.
+// If no labels are specified, all incoming edges are returned.
+// Returns the edges keyed by the ID of the entity they point to, which leaves out the entities without edges.
+func (neo *neoRepository) IncomingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return neo.edgesForAll(ctx, entities, since, labels, true)
+}
+
+// OutgoingEdgesForAll finds the edges from any of the entities of the specified labels and last seen after the since parameter.
+// The edges are found with a single query that unwinds the entity IDs, rather than a query per entity.
+// If since.IsZero(), the parameter will be ignored.
+// If no labels are specified, all outgoing edges are returned.
+// Returns the edges keyed by the ID of the entity they come from, which leaves out the entities without edges.
+func (neo *neoRepository) OutgoingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return neo.edgesForAll(ctx, entities, since, labels, false)
+}
+
+// edgesForAll finds the edges pointing to any of the entities when incoming is true, or from any of them otherwise,
+// and groups the edges by the ID of that entity.
+func (neo *neoRepository) edgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels []string, incoming bool) (map[string][]*types.Edge, error) {
+	if len(entities) == 0 {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	var ids []string
+	byID := make(map[string]*types.Entity, len(entities))
+	for _, entity := range entities {
+		if entity == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+		if _, found := byID[entity.ID]; !found {
+			byID[entity.ID] = entity
+			ids = append(ids, entity.ID)
+		}
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	pattern := "(e:Entity {entity_id: eid})-[r]->(o:Entity)"
+	if incoming {
+		pattern = "(o:Entity)-[r]->(e:Entity {entity_id: eid})"
+	}
+	query := fmt.Sprintf("UNWIND $eids AS eid MATCH %s%s RETURN r, eid, o.entity_id AS oid", pattern, seenBetween("r", since, time.Time{}))
+
+	result, err := neo.executeRead(ctx, query, map[string]interface{}{
+		"eids": ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string][]*types.Edge)
+	for _, record := range result.Records {
+		r, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Relationship](record, "r")
+		if err != nil || isnil || !hasLabel(r.Type, labels) {
+			continue
+		}
+
+		eid, isnil, err := neo4jdb.GetRecordValue[string](record, "eid")
+		if err != nil || isnil {
+			continue
+		}
+		oid, isnil, err := neo4jdb.GetRecordValue[string](record, "oid")
+		if err != nil || isnil {
+			continue
+		}
+
+		edge, err := relationshipToEdge(r)
+		if err != nil {
+			continue
+		}
+		if incoming {
+			edge.FromEntity = &types.Entity{ID: oid}
+			edge.ToEntity = byID[eid]
+		} else {
+			edge.FromEntity = byID[eid]
+			edge.ToEntity = &types.Entity{ID: oid}
+		}
+		results[eid] = append(results[eid], edge)
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero edges found")
+	}
+	return results, nil
+}
+
+// hasLabel reports whether the relationship type matches any of the labels, ignoring case.
+// Every type matches when no labels are specified.
+func hasLabel(rtype string, labels []string) bool {
+	if len(labels) == 0 {
+		return true
+	}
+
+	for _, label := range labels {
+		if strings.EqualFold(label, rtype) {
+			return true
+		}
+	}
+	return false
+}
+
+// Neighborhood finds the entities reachable from the entity by following up to maxDepth outgoing edges of the
+// specified labels and last seen after the since parameter. The traversal is a variable-length path match, where
+// each edge is returned once along with the shortest distance from the entity, so the results are ordered by depth.
+// If since.IsZero(), the parameter will be ignored.
+// If no labels are specified, all outgoing edges are followed.
+// Returns the reachable entities, excluding the provided entity, and the edges that connect them, or an error if the traversal fails.
+func (neo *neoRepository) Neighborhood(ctx context.Context, entity *types.Entity, maxDepth int, since time.Time, labels ...string) ([]*types.Entity, []*types.Edge, error) {
+	if entity == nil {
+		return nil, nil, errors.New("failed input validation checks")
+	}
+	if maxDepth < 1 {
+		return nil, nil, errors.New("the maximum depth must be at least one")
+	}
+
+	conds := []string{"all(n IN nodes(p) WHERE n:Entity)"}
+	if !since.IsZero() {
+		conds = append(conds, fmt.Sprintf("all(r IN relationships(p) WHERE r.updated_at >= localDateTime('%s'))", timeToNeo4jTime(since)))
+	}
+
+	lower := make([]string, 0, len(labels))
+	for _, label := range labels {
+		lower = append(lower, strings.ToLower(label))
+	}
+	if len(lower) > 0 {
+		conds = append(conds, "all(r IN relationships(p) WHERE toLower(type(r)) IN $labels)")
+	}
+
+	query := fmt.Sprintf("MATCH p = (:Entity {entity_id: $eid})-[*1..%d]->(:Entity) WHERE %s "+
+		"UNWIND range(0, length(p) - 1) AS i WITH relationships(p)[i] AS r, i + 1 AS depth "+
+		"WITH r, min(depth) AS depth "+
+		"RETURN r, startNode(r).entity_id AS fid, endNode(r) AS to ORDER BY depth, elementId(r)", maxDepth, strings.Join(conds, " AND "))
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, query, map[string]interface{}{
+		"eid":    entity.ID,
+		"labels": lower,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entities []*types.Entity
+	var results []*types.Edge
+	visited := map[string]struct{}{entity.ID: {}}
+	for _, record := range result.Records {
+		r, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Relationship](record, "r")
+		if err != nil || isnil {
+			continue
+		}
+
+		fid, isnil, err := neo4jdb.GetRecordValue[string](record, "fid")
+		if err != nil || isnil {
+			continue
+		}
+
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "to")
+		if err != nil || isnil {
+			continue
+		}
+
+		to, err := nodeToEntity(node)
+		if err != nil {
+			continue
+		}
+
+		edge, err := relationshipToEdge(r)
+		if err != nil {
+			continue
+		}
+		edge.FromEntity = &types.Entity{ID: fid}
+		edge.ToEntity = &types.Entity{ID: to.ID}
+		results = append(results, edge)
+
+		if _, found := visited[to.ID]; !found {
+			visited[to.ID] = struct{}{}
+			entities = append(entities, to)
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, nil, types.NotFound("zero edges found")
+	}
+	return entities, results, nil
+}
+
+// FindEdgesByLabel finds the edges in the database with the provided label and last seen after the since parameter,
+// regardless of the entities they connect. The label is matched against the relationship type, which is the upper-case label.
+// If since.IsZero(), the parameter will be ignored.
+// The FromEntity and ToEntity fields of each edge only hold the entity IDs.
+// Returns a slice of matching edges as []*types.Edge or an error if the search fails.
+func (neo *neoRepository) FindEdgesByLabel(ctx context.Context, label string, since time.Time) ([]*types.Edge, error) {
+	if label == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	rtype := strings.ToUpper(label)
+	if err := checkLabel(rtype); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("MATCH (from:Entity)-[r:%s]->(to:Entity) RETURN r, from.entity_id AS fid, to.entity_id AS tid", rtype)
+	if !since.IsZero() {
+		query = fmt.Sprintf("MATCH (from:Entity)-[r:%s]->(to:Entity) WHERE r.updated_at >= localDateTime('%s') RETURN r, from.entity_id AS fid, to.entity_id AS tid", rtype, timeToNeo4jTime(since))
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.Edge
+	for _, record := range result.Records {
+		r, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Relationship](record, "r")
+		if err != nil || isnil {
+			continue
+		}
+
+		fid, isnil, err := neo4jdb.GetRecordValue[string](record, "fid")
+		if err != nil || isnil {
+			continue
+		}
+
+		tid, isnil, err := neo4jdb.GetRecordValue[string](record, "tid")
+		if err != nil || isnil {
+			continue
+		}
+
+		edge, err := relationshipToEdge(r)
+		if err != nil {
+			continue
+		}
+		edge.FromEntity = &types.Entity{ID: fid}
+		edge.ToEntity = &types.Entity{ID: tid}
+		results = append(results, edge)
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero edges found")
+	}
+	return results, nil
+}
+
+// FindEdgesSince finds the edges in the database last seen after the since parameter, which includes the edges created
+// or updated since then, such as for a consumer that mirrors the changes to the graph elsewhere. If since.IsZero(), all
+// the edges are returned. When includeTags is true, the Tags field of each edge holds its tags, which are collected by an
+// OPTIONAL MATCH in the same query rather than a query per edge, and exclude the expired tags as GetEdgeTags does.
+// The FromEntity and ToEntity fields of each edge only hold the entity IDs.
+// Returns the edges ordered by ID, or an error matching types.ErrNotFound if no edges are found.
+func (neo *neoRepository) FindEdgesSince(ctx context.Context, since time.Time, includeTags bool) ([]*types.Edge, error) {
+	query := fmt.Sprintf("MATCH (from:Entity)-[r]->(to:Entity)%s RETURN r, from.entity_id AS fid, to.entity_id AS tid ORDER BY elementId(r)", seenBetween("r", since, time.Time{}))
+	if includeTags {
+		where := " WHERE p.edge_id = elementId(r)"
+		if !neo.expiredTags {
+			where = unexpired(where, "p")
+		}
+		query = fmt.Sprintf("MATCH (from:Entity)-[r]->(to:Entity)%s OPTIONAL MATCH (p:EdgeTag)%s WITH r, from, to, collect(p) AS tags "+
+			"RETURN r, from.entity_id AS fid, to.entity_id AS tid, tags ORDER BY elementId(r)", seenBetween("r", since, time.Time{}), where)
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.Edge
+	for _, record := range result.Records {
+		r, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Relationship](record, "r")
+		if err != nil || isnil {
+			continue
+		}
+
+		fid, isnil, err := neo4jdb.GetRecordValue[string](record, "fid")
+		if err != nil || isnil {
+			continue
+		}
+
+		tid, isnil, err := neo4jdb.GetRecordValue[string](record, "tid")
+		if err != nil || isnil {
+			continue
+		}
+
+		edge, err := relationshipToEdge(r)
+		if err != nil {
+			continue
+		}
+		edge.FromEntity = &types.Entity{ID: fid}
+		edge.ToEntity = &types.Entity{ID: tid}
+
+		if includeTags {
+			nodes, _, err := neo4jdb.GetRecordValue[[]interface{}](record, "tags")
+			if err != nil {
+				return nil, err
+			}
+
+			for _, n := range nodes {
+				node, ok := n.(neo4jdb.Node)
+				if !ok {
+					continue
+				}
+
+				tag, err := nodeToEdgeTag(node)
+				if err != nil {
+					continue
+				}
+				tag.Edge = edge
+				edge.Tags = append(edge.Tags, tag)
+			}
+		}
+		results = append(results, edge)
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero edges found")
 	}
 	return results, nil
 }
 
+// ResolveEdgeEndpoints finds the distinct entities referenced by the FromEntity and ToEntity fields of the edges
+// with a single query, rather than a query per entity. The IDs that do not match a live entity are omitted.
+// Returns the entities keyed by their IDs, or an error if the search fails.
+func (neo *neoRepository) ResolveEdgeEndpoints(ctx context.Context, edges []*types.Edge) (map[string]*types.Entity, error) {
+	var ids []string
+	seen := make(map[string]struct{})
+	for _, edge := range edges {
+		if edge == nil || edge.FromEntity == nil || edge.ToEntity == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+
+		for _, id := range []string{edge.FromEntity.ID, edge.ToEntity.ID} {
+			if _, found := seen[id]; !found {
+				seen[id] = struct{}{}
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	results := make(map[string]*types.Entity, len(ids))
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, "MATCH (a:Entity) WHERE a.entity_id IN $ids RETURN a", map[string]interface{}{
+		"ids": ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range result.Records {
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
+		if err != nil || isnil {
+			continue
+		}
+
+		e, err := nodeToEntity(node)
+		if err != nil {
+			continue
+		}
+		results[e.ID] = e
+	}
+	return results, nil
+}
+
+// CountEdges counts the edges in the database last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the number of matching edges or an error if the count fails.
+func (neo *neoRepository) CountEdges(ctx context.Context, since time.Time) (int64, error) {
+	query := "MATCH (:Entity)-[r]->(:Entity) RETURN count(r) AS total"
+	if !since.IsZero() {
+		query = fmt.Sprintf("MATCH (:Entity)-[r]->(:Entity) WHERE r.updated_at >= localDateTime('%s') RETURN count(r) AS total", timeToNeo4jTime(since))
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	return neo.countQuery(ctx, query)
+}
+
+// EntityDegree counts the edges pointing to and leaving the entity that were last seen after the since parameter,
+// with a single query that measures the relationships of the node rather than returning them. If since.IsZero(),
+// the parameter will be ignored. Returns the number of incoming and outgoing edges, which are zero for an entity
+// without edges, or an error if the count fails.
+func (neo *neoRepository) EntityDegree(ctx context.Context, entity *types.Entity, since time.Time) (int64, int64, error) {
+	if entity == nil {
+		return 0, 0, errors.New("failed input validation checks")
+	}
+
+	where := seenBetween("r", since, time.Time{})
+	query := fmt.Sprintf("MATCH (n:Entity {entity_id: $eid}) RETURN size([(n)<-[r]-(:Entity)%s | r]) AS incoming, "+
+		"size([(n)-[r]->(:Entity)%s | r]) AS outgoing", where, where)
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, query, map[string]interface{}{"eid": entity.ID})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(result.Records) == 0 {
+		return 0, 0, nil
+	}
+
+	in, _, err := neo4jdb.GetRecordValue[int64](result.Records[0], "incoming")
+	if err != nil {
+		return 0, 0, err
+	}
+	out, _, err := neo4jdb.GetRecordValue[int64](result.Records[0], "outgoing")
+	if err != nil {
+		return 0, 0, err
+	}
+	return in, out, nil
+}
+
 // DeleteEdge removes an edge in the database by its ID.
 // It takes a string representing the edge ID and removes the corresponding edge from the database.
-// Returns an error if the edge is not found.
-func (neo *neoRepository) DeleteEdge(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// Returns the number of relationships deleted, which is zero when the edge is not found.
+func (neo *neoRepository) DeleteEdge(ctx context.Context, id string) (int64, error) {
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	_, err := neo4jdb.ExecuteQuery(ctx, neo.db,
+	result, err := neo.executeQuery(ctx,
 		"MATCH ()-[r]->() WHERE elementId(r) = $eid DELETE r",
 		map[string]interface{}{
 			"eid": id,
 		},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
 	)
-
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return int64(result.Summary.Counters().RelationshipsDeleted()), nil
 }
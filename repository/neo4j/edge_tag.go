@@ -20,7 +20,7 @@ import (
 // It takes an EdgeTag as input and persists it in the database.
 // The property is serialized to JSON and stored in the Content field of the EdgeTag struct.
 // Returns the created edge tag as a types.EdgeTag or an error if the creation fails.
-func (neo *neoRepository) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag) (*types.EdgeTag, error) {
+func (neo *neoRepository) CreateEdgeTag(ctx context.Context, edge *types.Edge, input *types.EdgeTag) (*types.EdgeTag, error) {
 	if input == nil {
 		return nil, errors.New("the input edge tag is nil")
 	}
@@ -33,10 +33,11 @@ func (neo *neoRepository) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag)
 			ID:        input.ID,
 			CreatedAt: input.CreatedAt,
 			LastSeen:  time.Now(),
+			ExpiresAt: input.ExpiresAt,
 			Property:  input.Property,
 			Edge:      edge,
 		}
-	} else if tags, err := neo.FindEdgeTagsByContent(input.Property, time.Time{}); err == nil && len(tags) > 0 {
+	} else if tags, err := neo.FindEdgeTagsByContent(ctx, input.Property, time.Time{}); err == nil && len(tags) > 0 {
 		// ensure that duplicate entities are not entered into the database
 		for _, t := range tags {
 			if t.Edge.ID == edge.ID {
@@ -48,6 +49,7 @@ func (neo *neoRepository) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag)
 		if tag != nil {
 			tag.Edge = edge
 			tag.LastSeen = time.Now()
+			tag.ExpiresAt = input.ExpiresAt
 		}
 	}
 
@@ -61,14 +63,12 @@ func (neo *neoRepository) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag)
 			return nil, err
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := neo.operationContext(ctx)
 		defer cancel()
 
-		result, err := neo4jdb.ExecuteQuery(ctx, neo.db,
+		result, err := neo.executeQuery(ctx,
 			"MATCH (n:EdgeTag {tag_id: $tid}) SET p = $props RETURN p",
 			map[string]interface{}{"tid": tag.ID, "props": props},
-			neo4jdb.EagerResultTransformer,
-			neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
 		)
 		if err != nil {
 			return nil, err
@@ -90,7 +90,7 @@ func (neo *neoRepository) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag)
 		}
 	} else {
 		if input.ID == "" {
-			input.ID = neo.uniqueEdgeTagID()
+			input.ID = neo.uniqueEdgeTagID(ctx)
 		}
 		if input.CreatedAt.IsZero() {
 			input.CreatedAt = time.Now()
@@ -105,15 +105,11 @@ func (neo *neoRepository) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag)
 			return nil, err
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := neo.operationContext(ctx)
 		defer cancel()
 
 		query := fmt.Sprintf("CREATE (p:EdgeTag:%s $props) RETURN p", input.Property.PropertyType())
-		result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query,
-			map[string]interface{}{"props": props},
-			neo4jdb.EagerResultTransformer,
-			neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-		)
+		result, err := neo.executeQuery(ctx, query, map[string]interface{}{"props": props})
 		if err != nil {
 			return nil, err
 		}
@@ -144,14 +140,14 @@ func (neo *neoRepository) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag)
 // It takes an oam.Property as input and persists it in the database.
 // The property is serialized to JSON and stored in the Content field of the EdgeTag struct.
 // Returns the created edge tag as a types.EdgeTag or an error if the creation fails.
-func (neo *neoRepository) CreateEdgeProperty(edge *types.Edge, prop oam.Property) (*types.EdgeTag, error) {
-	return neo.CreateEdgeTag(edge, &types.EdgeTag{Property: prop})
+func (neo *neoRepository) CreateEdgeProperty(ctx context.Context, edge *types.Edge, prop oam.Property) (*types.EdgeTag, error) {
+	return neo.CreateEdgeTag(ctx, edge, &types.EdgeTag{Property: prop})
 }
 
-func (neo *neoRepository) uniqueEdgeTagID() string {
+func (neo *neoRepository) uniqueEdgeTagID(ctx context.Context) string {
 	for {
 		id := uuid.New().String()
-		if _, err := neo.FindEdgeTagById(id); err != nil {
+		if _, err := neo.FindEdgeTagById(ctx, id); err != nil {
 			return id
 		}
 	}
@@ -159,22 +155,20 @@ func (neo *neoRepository) uniqueEdgeTagID() string {
 
 // FindEdgeTagById finds an edge tag in the database by the ID.
 // It takes a string representing the edge tag ID and retrieves the corresponding tag from the database.
-// Returns the discovered tag as a types.EdgeTag or an error if the asset is not found.
-func (neo *neoRepository) FindEdgeTagById(id string) (*types.EdgeTag, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// Returns the discovered tag as a types.EdgeTag, types.ErrTagNotFound if the tag is not found, or an error if the search fails.
+func (neo *neoRepository) FindEdgeTagById(ctx context.Context, id string) (*types.EdgeTag, error) {
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db,
+	result, err := neo.executeRead(ctx,
 		"MATCH (p:EdgeTag {tag_id: $tid}) RETURN p",
 		map[string]interface{}{"tid": id},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
 	)
 	if err != nil {
 		return nil, err
 	}
 	if len(result.Records) == 0 {
-		return nil, fmt.Errorf("the edge tag with ID %s was not found", id)
+		return nil, types.ErrTagNotFound
 	}
 
 	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "p")
@@ -192,8 +186,8 @@ func (neo *neoRepository) FindEdgeTagById(id string) (*types.EdgeTag, error) {
 // If since.IsZero(), the parameter will be ignored.
 // The property data is serialized to JSON and compared against the Content field of the EdgeTag struct.
 // Returns a slice of matching edge tags as []*types.EdgeTag or an error if the search fails.
-func (neo *neoRepository) FindEdgeTagsByContent(prop oam.Property, since time.Time) ([]*types.EdgeTag, error) {
-	qnode, err := queryNodeByPropertyKeyValue("p", "EdgeTag", prop)
+func (neo *neoRepository) FindEdgeTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EdgeTag, error) {
+	qnode, params, err := queryNodeByPropertyKeyValue("p", "EdgeTag", prop)
 	if err != nil {
 		return nil, err
 	}
@@ -203,18 +197,15 @@ func (neo *neoRepository) FindEdgeTagsByContent(prop oam.Property, since time.Ti
 		query = fmt.Sprintf("MATCH %s WHERE p.updated_at >= localDateTime('%s') RETURN p", qnode, timeToNeo4jTime(since))
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query, nil,
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-	)
+	result, err := neo.executeRead(ctx, query, params)
 	if err != nil {
 		return nil, err
 	}
 	if len(result.Records) == 0 {
-		return nil, errors.New("no edge tags found")
+		return nil, types.NotFound("no edge tags found")
 	}
 
 	var tags []*types.EdgeTag
@@ -230,7 +221,7 @@ func (neo *neoRepository) FindEdgeTagsByContent(prop oam.Property, since time.Ti
 	}
 	// Check if any tags were found
 	if len(tags) == 0 {
-		return nil, errors.New("zero edge tags found")
+		return nil, types.NotFound("zero edge tags found")
 	}
 	return tags, nil
 }
@@ -238,24 +229,29 @@ func (neo *neoRepository) FindEdgeTagsByContent(prop oam.Property, since time.Ti
 // GetEdgeTags finds all tags for the edge with the specified names and last seen after the since parameter.
 // If since.IsZero(), the parameter will be ignored.
 // If no names are specified, all tags for the specified edge are returned.
-func (neo *neoRepository) GetEdgeTags(edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error) {
-	query := fmt.Sprintf("MATCH (p:EdgeTag {edge_id: '%s'}) RETURN p", edge.ID)
-	if !since.IsZero() {
-		query = fmt.Sprintf("MATCH (p:EdgeTag {edge_id: '%s'}) WHERE p.updated_at >= localDateTime('%s') RETURN p", edge.ID, timeToNeo4jTime(since))
+func (neo *neoRepository) GetEdgeTags(ctx context.Context, edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error) {
+	return neo.GetEdgeTagsBetween(ctx, edge, since, time.Time{}, names...)
+}
+
+// GetEdgeTagsBetween finds all tags for the edge with the specified names and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no names are specified, all tags for the specified edge in the window are returned.
+func (neo *neoRepository) GetEdgeTagsBetween(ctx context.Context, edge *types.Edge, from, to time.Time, names ...string) ([]*types.EdgeTag, error) {
+	where := seenBetween("p", from, to)
+	if !neo.expiredTags {
+		where = unexpired(where, "p")
 	}
+	query := fmt.Sprintf("MATCH (p:EdgeTag {edge_id: $eid})%s RETURN p", where)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query, nil,
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-	)
+	result, err := neo.executeRead(ctx, query, map[string]interface{}{"eid": edge.ID})
 	if err != nil {
 		return nil, err
 	}
 	if len(result.Records) == 0 {
-		return nil, errors.New("no edge tags found")
+		return nil, types.NotFound("no edge tags found")
 	}
 
 	var results []*types.EdgeTag
@@ -292,26 +288,89 @@ func (neo *neoRepository) GetEdgeTags(edge *types.Edge, since time.Time, names .
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero tags found")
+		return nil, types.NotFound("zero tags found")
 	}
 	return results, nil
 }
 
+// UpdateEdgeTag sets the value of the property of the edge tag in the database, and updates the last seen time
+// while preserving the time the tag was created.
+// Returns the updated edge tag as a types.EdgeTag, types.ErrTagNotFound if the tag is not found, or an error if the update fails.
+func (neo *neoRepository) UpdateEdgeTag(ctx context.Context, id string, value string) (*types.EdgeTag, error) {
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRetryable(ctx,
+		"MATCH (p:EdgeTag {tag_id: $tid}) RETURN p",
+		map[string]interface{}{"tid": id},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) == 0 {
+		return nil, types.ErrTagNotFound
+	}
+
+	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "p")
+	if err != nil {
+		return nil, err
+	}
+	if isnil {
+		return nil, errors.New("the record value for the node is nil")
+	}
+
+	tag, err := nodeToEdgeTag(node)
+	if err != nil {
+		return nil, err
+	}
+
+	tag.Property, err = types.WithValue(tag.Property, value)
+	if err != nil {
+		return nil, err
+	}
+	tag.LastSeen = time.Now()
+
+	props, err := edgeTagPropsMap(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err = neo.executeQuery(ctx,
+		"MATCH (p:EdgeTag {tag_id: $tid}) SET p = $props RETURN p",
+		map[string]interface{}{"tid": id, "props": props},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) == 0 {
+		return nil, types.ErrTagNotFound
+	}
+
+	node, isnil, err = neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "p")
+	if err != nil {
+		return nil, err
+	}
+	if isnil {
+		return nil, errors.New("the record value for the node is nil")
+	}
+	return nodeToEdgeTag(node)
+}
+
 // DeleteEdgeTag removes an edge tag in the database by its ID.
 // It takes a string representing the edge tag ID and removes the corresponding tag from the database.
-// Returns an error if the tag is not found.
-func (neo *neoRepository) DeleteEdgeTag(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// Returns the number of nodes deleted, which is zero when the tag is not found.
+func (neo *neoRepository) DeleteEdgeTag(ctx context.Context, id string) (int64, error) {
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	_, err := neo4jdb.ExecuteQuery(ctx, neo.db,
+	result, err := neo.executeQuery(ctx,
 		"MATCH (n:EdgeTag {tag_id: $tid}) DETACH DELETE n",
 		map[string]interface{}{
 			"tid": id,
 		},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
 	)
-
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return int64(result.Summary.Counters().NodesDeleted()), nil
 }
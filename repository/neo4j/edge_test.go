@@ -7,6 +7,7 @@
 package neo4j
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -17,28 +18,28 @@ import (
 )
 
 func TestCreateEdge(t *testing.T) {
-	from, err := store.CreateEntity(&types.Entity{
+	from, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "create1.edge",
 		},
 	})
 	assert.NoError(t, err)
 
-	to, err := store.CreateEntity(&types.Entity{
+	to, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "create2.edge",
 		},
 	})
 	assert.NoError(t, err)
 
-	_, err = store.CreateEdge(&types.Edge{
+	_, err = store.CreateEdge(context.Background(), &types.Edge{
 		Relation:   &general.SimpleRelation{Name: "invalid_label"},
 		FromEntity: from,
 		ToEntity:   to,
 	})
 	assert.Error(t, err)
 
-	first, err := store.CreateEdge(&types.Edge{
+	first, err := store.CreateEdge(context.Background(), &types.Edge{
 		Relation:   &general.SimpleRelation{Name: "node"},
 		FromEntity: from,
 		ToEntity:   to,
@@ -46,7 +47,7 @@ func TestCreateEdge(t *testing.T) {
 	assert.NoError(t, err)
 
 	time.Sleep(250 * time.Millisecond)
-	second, err := store.CreateEdge(&types.Edge{
+	second, err := store.CreateEdge(context.Background(), &types.Edge{
 		Relation:   &general.SimpleRelation{Name: "node"},
 		FromEntity: from,
 		ToEntity:   to,
@@ -58,32 +59,106 @@ func TestCreateEdge(t *testing.T) {
 	}
 }
 
+func TestCreateEdgeMissingEntity(t *testing.T) {
+	ctx := context.Background()
+
+	entity, err := store.CreateAsset(ctx, &dns.FQDN{Name: "exists.missing.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+	missing := &types.Entity{ID: "00000000-0000-0000-0000-000000000000", Asset: &dns.FQDN{Name: "gone.missing.example.com"}}
+
+	_, err = store.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: missing,
+		ToEntity:   entity,
+	})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	assert.ErrorContains(t, err, "the from entity "+missing.ID)
+
+	_, err = store.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: entity,
+		ToEntity:   missing,
+	})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+	assert.ErrorContains(t, err, "the to entity "+missing.ID)
+	_, err = store.OutgoingEdges(ctx, entity, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestUpsertEdge(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "www.upsert.example.com"})
+	assert.NoError(t, err)
+	to, err := store.CreateAsset(ctx, &dns.FQDN{Name: "upsert.example.com"})
+	assert.NoError(t, err)
+	defer func() {
+		_, _ = store.DeleteEntity(ctx, from.ID)
+		_, _ = store.DeleteEntity(ctx, to.ID)
+	}()
+
+	cname := func(ttl int) *types.Edge {
+		return &types.Edge{
+			Relation: &dns.BasicDNSRelation{
+				Name:   "dns_record",
+				Header: dns.RRHeader{RRType: 5, Class: 1, TTL: ttl},
+			},
+			FromEntity: from,
+			ToEntity:   to,
+		}
+	}
+
+	first, result, err := store.UpsertEdge(ctx, cname(3600))
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertCreated, result)
+
+	// the edge with the same entities, relation type, and label is updated rather than duplicated
+	second, result, err := store.UpsertEdge(ctx, cname(300))
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUpdated, result)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, 300, second.Relation.(*dns.BasicDNSRelation).Header.TTL)
+
+	_, result, err = store.UpsertEdge(ctx, cname(300))
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUnchanged, result)
+
+	third, err := store.CreateEdge(ctx, cname(60))
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, third.ID)
+	edges, err := store.OutgoingEdges(ctx, from, time.Time{}, "dns_record")
+	assert.NoError(t, err)
+	assert.Len(t, edges, 1)
+}
+
 func TestFindEdgeById(t *testing.T) {
-	_, err := store.FindEdgeById("bad_id")
+	_, err := store.FindEdgeById(context.Background(), "bad_id")
 	assert.Error(t, err)
 
-	from, err := store.CreateEntity(&types.Entity{
+	from, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "find1.edge",
 		},
 	})
 	assert.NoError(t, err)
 
-	to, err := store.CreateEntity(&types.Entity{
+	to, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "find2.edge",
 		},
 	})
 	assert.NoError(t, err)
 
-	first, err := store.CreateEdge(&types.Edge{
+	first, err := store.CreateEdge(context.Background(), &types.Edge{
 		Relation:   &general.SimpleRelation{Name: "node"},
 		FromEntity: from,
 		ToEntity:   to,
 	})
 	assert.NoError(t, err)
 
-	second, err := store.FindEdgeById(first.ID)
+	second, err := store.FindEdgeById(context.Background(), first.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, first.ID, second.ID)
 	assert.Equal(t, first.FromEntity.ID, second.FromEntity.ID)
@@ -91,14 +166,14 @@ func TestFindEdgeById(t *testing.T) {
 }
 
 func TestIncomingEdges(t *testing.T) {
-	from, err := store.CreateEntity(&types.Entity{
+	from, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "incoming1.edge",
 		},
 	})
 	assert.NoError(t, err)
 
-	to, err := store.CreateEntity(&types.Entity{
+	to, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "incoming2.edge",
 		},
@@ -109,7 +184,7 @@ func TestIncomingEdges(t *testing.T) {
 	for i := 1; i <= 10; i++ {
 		created := now.Add(time.Duration(i*-24) * time.Hour)
 
-		_, err := store.CreateEdge(&types.Edge{
+		_, err := store.CreateEdge(context.Background(), &types.Edge{
 			CreatedAt: created,
 			LastSeen:  created,
 			Relation: &dns.BasicDNSRelation{
@@ -126,31 +201,31 @@ func TestIncomingEdges(t *testing.T) {
 		assert.NoError(t, err)
 	}
 
-	_, err = store.IncomingEdges(to, time.Time{}, "invalid_label")
+	_, err = store.IncomingEdges(context.Background(), to, time.Time{}, "invalid_label")
 	assert.Error(t, err)
 
-	edges, err := store.IncomingEdges(to, time.Time{}, "dns_record")
+	edges, err := store.IncomingEdges(context.Background(), to, time.Time{}, "dns_record")
 	assert.NoError(t, err)
 	assert.Equal(t, len(edges), 10)
 
 	for i := 1; i <= 10; i++ {
 		since := now.Add(time.Duration(i*-24) * time.Hour)
 
-		edges, err := store.IncomingEdges(to, since)
+		edges, err := store.IncomingEdges(context.Background(), to, since)
 		assert.NoError(t, err)
 		assert.Equal(t, len(edges), i)
 	}
 }
 
 func TestOutgoingEdges(t *testing.T) {
-	from, err := store.CreateEntity(&types.Entity{
+	from, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "outgoing1.edge",
 		},
 	})
 	assert.NoError(t, err)
 
-	to, err := store.CreateEntity(&types.Entity{
+	to, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "outgoing2.edge",
 		},
@@ -161,7 +236,7 @@ func TestOutgoingEdges(t *testing.T) {
 	for i := 1; i <= 10; i++ {
 		created := now.Add(time.Duration(i*-24) * time.Hour)
 
-		_, err := store.CreateEdge(&types.Edge{
+		_, err := store.CreateEdge(context.Background(), &types.Edge{
 			CreatedAt: created,
 			LastSeen:  created,
 			Relation: &dns.BasicDNSRelation{
@@ -178,47 +253,355 @@ func TestOutgoingEdges(t *testing.T) {
 		assert.NoError(t, err)
 	}
 
-	_, err = store.OutgoingEdges(from, time.Time{}, "invalid_label")
+	_, err = store.OutgoingEdges(context.Background(), from, time.Time{}, "invalid_label")
 	assert.Error(t, err)
 
-	edges, err := store.OutgoingEdges(from, time.Time{}, "dns_record")
+	edges, err := store.OutgoingEdges(context.Background(), from, time.Time{}, "dns_record")
 	assert.NoError(t, err)
 	assert.Equal(t, len(edges), 10)
 
 	for i := 1; i <= 10; i++ {
 		since := now.Add(time.Duration(i*-24) * time.Hour)
 
-		edges, err := store.OutgoingEdges(from, since)
+		edges, err := store.OutgoingEdges(context.Background(), from, since)
 		assert.NoError(t, err)
 		assert.Equal(t, len(edges), i)
 	}
 }
 
 func TestDeleteEdge(t *testing.T) {
-	from, err := store.CreateEntity(&types.Entity{
+	from, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "delete1.edge",
 		},
 	})
 	assert.NoError(t, err)
 
-	to, err := store.CreateEntity(&types.Entity{
+	to, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "delete2.edge",
 		},
 	})
 	assert.NoError(t, err)
 
-	edge, err := store.CreateEdge(&types.Edge{
+	edge, err := store.CreateEdge(context.Background(), &types.Edge{
 		Relation:   &general.SimpleRelation{Name: "node"},
 		FromEntity: from,
 		ToEntity:   to,
 	})
 	assert.NoError(t, err)
 
-	err = store.DeleteEdge(edge.ID)
+	n, err := store.DeleteEdge(context.Background(), edge.ID)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
 
-	_, err = store.FindEdgeById(edge.ID)
+	_, err = store.FindEdgeById(context.Background(), edge.ID)
 	assert.Error(t, err)
+
+	n, err = store.DeleteEdge(context.Background(), edge.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestNeighborhood(t *testing.T) {
+	ctx := context.Background()
+	// a -> b -> c -> a forms a cycle, and b -> d is a branch
+	entities := make(map[string]*types.Entity)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		e, err := store.CreateAsset(ctx, &dns.FQDN{Name: name + ".neighborhood.example.com"})
+		assert.NoError(t, err)
+		entities[name] = e
+	}
+	defer func() {
+		for _, e := range entities {
+			_, _ = store.DeleteEntity(ctx, e.ID)
+		}
+	}()
+	for _, pair := range [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}, {"b", "d"}} {
+		_, err := store.CreateEdge(ctx, &types.Edge{
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: entities[pair[0]],
+			ToEntity:   entities[pair[1]],
+		})
+		assert.NoError(t, err)
+	}
+
+	reached, edges, err := store.Neighborhood(ctx, entities["a"], 1, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 1)
+	if assert.Len(t, reached, 1) {
+		assert.Equal(t, entities["b"].ID, reached[0].ID)
+	}
+
+	reached, edges, err = store.Neighborhood(ctx, entities["a"], 2, time.Time{}, "node")
+	assert.NoError(t, err)
+	assert.Len(t, edges, 3)
+	assert.Len(t, reached, 3)
+
+	// the edge back to the first entity is returned, but the entity is not revisited
+	reached, edges, err = store.Neighborhood(ctx, entities["a"], 5, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 4)
+	assert.Len(t, reached, 3)
+	for _, e := range reached {
+		assert.NotEqual(t, entities["a"].ID, e.ID)
+	}
+
+	_, _, err = store.Neighborhood(ctx, entities["a"], 2, time.Time{}, "dns_record")
+	assert.Error(t, err)
+	_, _, err = store.Neighborhood(ctx, entities["a"], 2, time.Now().Add(time.Minute))
+	assert.Error(t, err)
+	_, _, err = store.Neighborhood(ctx, entities["d"], 2, time.Time{})
+	assert.Error(t, err)
+	_, _, err = store.Neighborhood(ctx, entities["a"], 0, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestEdgesForAll(t *testing.T) {
+	ctx := context.Background()
+	// a -> b, a -> c, and b -> c, while d has no edges
+	entities := make(map[string]*types.Entity)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		e, err := store.CreateAsset(ctx, &dns.FQDN{Name: name + ".forall.example.com"})
+		assert.NoError(t, err)
+		entities[name] = e
+	}
+	defer func() {
+		for _, e := range entities {
+			_, _ = store.DeleteEntity(ctx, e.ID)
+		}
+	}()
+	for _, pair := range [][2]string{{"a", "b"}, {"a", "c"}, {"b", "c"}} {
+		_, err := store.CreateEdge(ctx, &types.Edge{
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: entities[pair[0]],
+			ToEntity:   entities[pair[1]],
+		})
+		assert.NoError(t, err)
+	}
+
+	// the entity without edges is left out, and a repeated entity is only counted once
+	outgoing, err := store.OutgoingEdgesForAll(ctx, []*types.Entity{entities["a"], entities["b"], entities["d"], entities["a"]}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, outgoing, 2)
+	assert.Len(t, outgoing[entities["a"].ID], 2)
+	if assert.Len(t, outgoing[entities["b"].ID], 1) {
+		assert.Equal(t, entities["c"].ID, outgoing[entities["b"].ID][0].ToEntity.ID)
+	}
+
+	incoming, err := store.IncomingEdgesForAll(ctx, []*types.Entity{entities["b"], entities["c"]}, time.Time{}, "node")
+	assert.NoError(t, err)
+	assert.Len(t, incoming, 2)
+	assert.Len(t, incoming[entities["c"].ID], 2)
+	if assert.Len(t, incoming[entities["b"].ID], 1) {
+		assert.Equal(t, entities["a"].ID, incoming[entities["b"].ID][0].FromEntity.ID)
+	}
+
+	_, err = store.OutgoingEdgesForAll(ctx, []*types.Entity{entities["a"]}, time.Time{}, "dns_record")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.IncomingEdgesForAll(ctx, []*types.Entity{entities["a"], entities["d"]}, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.OutgoingEdgesForAll(ctx, []*types.Entity{entities["a"]}, time.Now().Add(time.Minute))
+	assert.Error(t, err)
+	_, err = store.OutgoingEdgesForAll(ctx, nil, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestFindEdgesByLabel(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Second)
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "from.label.entity"})
+	assert.NoError(t, err)
+	to, err := store.CreateAsset(ctx, &dns.FQDN{Name: "to.label.entity"})
+	assert.NoError(t, err)
+	defer func() {
+		_, _ = store.DeleteEntity(ctx, from.ID)
+		_, _ = store.DeleteEntity(ctx, to.ID)
+	}()
+
+	_, err = store.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	record, err := store.CreateEdge(ctx, &types.Edge{
+		Relation:   &dns.BasicDNSRelation{Name: "dns_record", Header: dns.RRHeader{RRType: 5, Class: 1}},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	_, err = store.FindEdgesByLabel(ctx, "", time.Time{})
+	assert.Error(t, err)
+
+	edges, err := store.FindEdgesByLabel(ctx, "dns_record", start)
+	assert.NoError(t, err)
+
+	var found bool
+	for _, e := range edges {
+		assert.Equal(t, "dns_record", e.Relation.Label())
+		if e.ID == record.ID {
+			found = true
+			assert.Equal(t, from.ID, e.FromEntity.ID)
+			assert.Equal(t, to.ID, e.ToEntity.ID)
+		}
+	}
+	assert.True(t, found)
+
+	_, err = store.FindEdgesByLabel(ctx, "dns_record", time.Now().Add(time.Minute))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestFindEdgesSince(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Second)
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "from.since.example.com"})
+	assert.NoError(t, err)
+	to, err := store.CreateAsset(ctx, &dns.FQDN{Name: "to.since.example.com"})
+	assert.NoError(t, err)
+	defer func() {
+		_, _ = store.DeleteEntityCascade(ctx, from.ID)
+		_, _ = store.DeleteEntityCascade(ctx, to.ID)
+	}()
+
+	old, err := store.CreateEdge(ctx, &types.Edge{
+		LastSeen:   time.Date(2002, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	record, err := store.CreateEdge(ctx, &types.Edge{
+		Relation:   dns.BasicDNSRelation{Name: "dns_record", Header: dns.RRHeader{RRType: 5, Class: 1}},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	tag, err := store.CreateEdgeProperty(ctx, record, &general.SimpleProperty{PropertyName: "source", PropertyValue: "since"})
+	assert.NoError(t, err)
+	_, err = store.CreateEdgeTag(ctx, record, &types.EdgeTag{
+		ExpiresAt: time.Now().Add(-time.Minute),
+		Property:  &general.SimpleProperty{PropertyName: "expired", PropertyValue: "since"},
+	})
+	assert.NoError(t, err)
+
+	find := func(edges []*types.Edge, id string) *types.Edge {
+		for _, e := range edges {
+			if e.ID == id {
+				return e
+			}
+		}
+		return nil
+	}
+
+	edges, err := store.FindEdgesSince(ctx, time.Time{}, false)
+	assert.NoError(t, err)
+	assert.NotNil(t, find(edges, old.ID))
+	if e := find(edges, record.ID); assert.NotNil(t, e) {
+		assert.Nil(t, e.Tags)
+	}
+
+	// the tags are loaded along with the edges, excluding the expired tags
+	edges, err = store.FindEdgesSince(ctx, start, true)
+	assert.NoError(t, err)
+	assert.Nil(t, find(edges, old.ID))
+	if e := find(edges, record.ID); assert.NotNil(t, e) {
+		assert.Equal(t, from.ID, e.FromEntity.ID)
+		assert.Equal(t, to.ID, e.ToEntity.ID)
+		if assert.Len(t, e.Tags, 1) {
+			assert.Equal(t, tag.ID, e.Tags[0].ID)
+			assert.Equal(t, "since", e.Tags[0].Property.Value())
+		}
+	}
+
+	_, err = store.FindEdgesSince(ctx, time.Now().Add(time.Minute), true)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestResolveEdgeEndpoints(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "from.resolve.entity"})
+	assert.NoError(t, err)
+	var targets []*types.Entity
+	for _, name := range []string{"a", "b"} {
+		e, err := store.CreateAsset(ctx, &dns.FQDN{Name: name + ".resolve.entity"})
+		assert.NoError(t, err)
+		targets = append(targets, e)
+
+		_, err = store.CreateEdge(ctx, &types.Edge{
+			Relation:   &general.SimpleRelation{Name: "node"},
+			FromEntity: from,
+			ToEntity:   e,
+		})
+		assert.NoError(t, err)
+	}
+	defer func() {
+		for _, e := range append(targets, from) {
+			_, _ = store.DeleteEntity(ctx, e.ID)
+		}
+	}()
+
+	_, err = store.ResolveEdgeEndpoints(ctx, []*types.Edge{nil})
+	assert.Error(t, err)
+
+	edges, err := store.OutgoingEdges(ctx, from, time.Time{})
+	assert.NoError(t, err)
+	entities, err := store.ResolveEdgeEndpoints(ctx, edges)
+	assert.NoError(t, err)
+	assert.Len(t, entities, 3)
+	for _, e := range append(targets, from) {
+		if assert.Contains(t, entities, e.ID) {
+			assert.Equal(t, e.Asset, entities[e.ID].Asset)
+		}
+	}
+
+	entities, err = store.ResolveEdgeEndpoints(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, entities)
+}
+
+func TestEdgesBetween(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "from.window.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, from.ID) }()
+
+	var tos []*types.Entity
+	for i, name := range []string{"first.to.window.example.com", "second.to.window.example.com"} {
+		to, err := store.CreateAsset(ctx, &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, to.ID) }()
+		tos = append(tos, to)
+
+		_, err = store.CreateEdge(ctx, &types.Edge{
+			LastSeen:   start.Add(time.Duration(i) * time.Hour),
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: from,
+			ToEntity:   to,
+		})
+		assert.NoError(t, err)
+	}
+
+	outs, err := store.OutgoingEdgesBetween(ctx, from, start, start.Add(time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, outs, 1) {
+		assert.Equal(t, tos[0].ID, outs[0].ToEntity.ID)
+	}
+	outs, err = store.OutgoingEdgesBetween(ctx, from, start, time.Time{}, "node")
+	assert.NoError(t, err)
+	assert.Len(t, outs, 2)
+	_, err = store.OutgoingEdgesBetween(ctx, from, start, time.Time{}, "dns_record")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	ins, err := store.IncomingEdgesBetween(ctx, tos[1], start.Add(time.Hour), start.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, ins, 1)
+	_, err = store.IncomingEdgesBetween(ctx, tos[1], start, start.Add(time.Hour))
+	assert.ErrorIs(t, err, types.ErrNotFound)
 }
@@ -8,36 +8,57 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/garthoid/asset-db/internal/jsoncmp"
 	"github.com/garthoid/asset-db/types"
 	oam "github.com/owasp-amass/open-asset-model"
 )
 
 // CreateEntity creates a new entity in the database.
-// It takes an Entity as input and persists it in the database.
+// It takes an Entity as input and persists it in the database. The ID of a new entity is provided by the caller,
+// or generated by the configured ID generator. An ID held by another asset, or an asset that already exists with
+// another ID, returns an error matching types.ErrDuplicate.
 // Returns the created entity as a types.Entity or an error if the creation fails.
-func (neo *neoRepository) CreateEntity(input *types.Entity) (*types.Entity, error) {
+func (neo *neoRepository) CreateEntity(ctx context.Context, input *types.Entity) (*types.Entity, error) {
 	if input == nil {
 		return nil, errors.New("the input entity is nil")
 	}
+	if err := neo.validateAsset(input.Asset); err != nil {
+		return nil, err
+	}
 
 	var entity *types.Entity
 	if input.ID != "" {
 		// If the entity ID is set, it means that the entity was previously created
-		// in the database, and we need to update that entity in the database
-		entity = &types.Entity{
-			ID:        input.ID,
-			CreatedAt: input.CreatedAt,
-			LastSeen:  time.Now(),
-			Asset:     input.Asset,
+		// in the database, and we need to update that entity in the database.
+		// Otherwise, the new entity is created with the ID provided by the caller
+		if e, err := neo.FindEntityById(ctx, input.ID); err == nil {
+			if e.Asset.AssetType() != input.Asset.AssetType() || e.Asset.Key() != input.Asset.Key() {
+				return nil, types.Duplicate("the entity ID is held by another asset")
+			}
+
+			entity = &types.Entity{
+				ID:        input.ID,
+				CreatedAt: input.CreatedAt,
+				LastSeen:  time.Now(),
+				Asset:     input.Asset,
+			}
+		} else if !errors.Is(err, types.ErrNotFound) {
+			return nil, err
+		} else if entities, err := neo.FindEntitiesByContent(ctx, input.Asset, time.Time{}); err == nil && len(entities) > 0 {
+			return nil, types.Duplicate("the asset already exists with another entity ID")
 		}
-	} else if entities, err := neo.FindEntitiesByContent(input.Asset, time.Time{}); err == nil && len(entities) > 0 {
+	} else if entities, err := neo.FindEntitiesByContent(ctx, input.Asset, time.Time{}); err == nil && len(entities) > 0 {
 		// ensure that duplicate entities are not entered into the database
 		entity = entities[0]
 		entity.LastSeen = time.Now()
+	} else if e, err := neo.restoreDeletedEntity(ctx, input.Asset); err == nil {
+		// a soft-deleted entity with matching content is restored rather than duplicated
+		entity = e
+		entity.LastSeen = time.Now()
 	}
 
 	if entity != nil {
@@ -50,14 +71,12 @@ func (neo *neoRepository) CreateEntity(input *types.Entity) (*types.Entity, erro
 			return nil, err
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := neo.operationContext(ctx)
 		defer cancel()
 
-		result, err := neo4jdb.ExecuteQuery(ctx, neo.db,
+		result, err := neo.executeQuery(ctx,
 			"MATCH (a:Entity {entity_id: $eid}) SET a = $props RETURN a",
 			map[string]interface{}{"eid": entity.ID, "props": props},
-			neo4jdb.EagerResultTransformer,
-			neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
 		)
 		if err != nil {
 			return nil, err
@@ -79,30 +98,39 @@ func (neo *neoRepository) CreateEntity(input *types.Entity) (*types.Entity, erro
 			entity = e
 		}
 	} else {
-		if input.ID == "" {
-			input.ID = neo.uniqueEntityID()
+		// the ID and timestamps are set on a copy, so the input may be shared by concurrent calls
+		created := *input
+		if created.ID == "" {
+			created.ID = neo.uniqueEntityID(ctx)
 		}
-		if input.CreatedAt.IsZero() {
-			input.CreatedAt = time.Now()
+		if created.CreatedAt.IsZero() {
+			created.CreatedAt = time.Now()
 		}
-		if input.LastSeen.IsZero() {
-			input.LastSeen = time.Now()
+		if created.LastSeen.IsZero() {
+			created.LastSeen = time.Now()
 		}
 
-		props, err := entityPropsMap(input)
+		props, err := entityPropsMap(&created)
 		if err != nil {
 			return nil, err
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := neo.operationContext(ctx)
 		defer cancel()
 
 		query := fmt.Sprintf("CREATE (a:Entity:%s $props) RETURN a", input.Asset.AssetType())
-		result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query,
-			map[string]interface{}{"props": props},
-			neo4jdb.EagerResultTransformer,
-			neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-		)
+		result, err := neo.executeQuery(ctx, query, map[string]interface{}{"props": props})
+		// the asset was created by another writer after the lookup, so the entity of that writer is updated,
+		// unless the failed statement has aborted the transaction of the repository
+		if input.ID == "" && neo.tx == nil && errors.Is(err, types.ErrDuplicate) {
+			if entities, ferr := neo.FindEntitiesByContent(ctx, input.Asset, time.Time{}); ferr == nil && len(entities) > 0 {
+				return neo.CreateEntity(ctx, &types.Entity{
+					ID:        entities[0].ID,
+					CreatedAt: entities[0].CreatedAt,
+					Asset:     input.Asset,
+				})
+			}
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -133,14 +161,416 @@ func (neo *neoRepository) CreateEntity(input *types.Entity) (*types.Entity, erro
 // It takes an oam.Asset as input and persists it in the database.
 // The asset is serialized to JSON and stored in the Content field of the Entity struct.
 // Returns the created entity as a types.Entity or an error if the creation fails.
-func (neo *neoRepository) CreateAsset(asset oam.Asset) (*types.Entity, error) {
-	return neo.CreateEntity(&types.Entity{Asset: asset})
+func (neo *neoRepository) CreateAsset(ctx context.Context, asset oam.Asset) (*types.Entity, error) {
+	return neo.CreateEntity(ctx, &types.Entity{Asset: asset})
+}
+
+// CreateEntities creates the provided entities in the database within a single transaction.
+// Entities that already exist in the database are updated, and the new entities of each
+// asset type are created by a single UNWIND statement per batch.
+// If any of the writes fail, the entire batch is rolled back.
+// Returns the created entities in the same order as the input or an error if the creation fails.
+func (neo *neoRepository) CreateEntities(ctx context.Context, inputs []*types.Entity) ([]*types.Entity, error) {
+	for _, input := range inputs {
+		if input == nil || input.Asset == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+		// the assets are validated before any of the entities are written
+		if err := neo.validateAsset(input.Asset); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	results := make([]*types.Entity, len(inputs))
+
+	var err error
+	if neo.tx != nil {
+		err = neo.createEntities(ctx, neo.tx, inputs, results)
+	} else {
+		session := neo.db.NewSession(ctx, neo.sessionConfig(neo4jdb.AccessModeWrite))
+		defer func() { _ = session.Close(ctx) }()
+
+		_, err = session.ExecuteWrite(ctx, func(tx neo4jdb.ManagedTransaction) (interface{}, error) {
+			return nil, neo.createEntities(ctx, tx, inputs, results)
+		}, neo.txConfig()...)
+	}
+
+	neo.logOperation("CreateEntities", start, "", len(inputs), err)
+	if err != nil {
+		return nil, err
+	}
+
+	// fill in the duplicate assets that share a node with an earlier input
+	byID := make(map[string]*types.Entity)
+	for _, e := range results {
+		if e.Asset != nil {
+			byID[e.ID] = e
+		}
+	}
+	for i, e := range results {
+		if e.Asset == nil {
+			results[i] = byID[e.ID]
+		}
+	}
+	return results, nil
+}
+
+// UpsertEntity creates the entity in the database, or updates the last seen time of the existing entity
+// with the same asset type and identifying content, along with its content when the asset differs. The write
+// is performed by a single MERGE statement, so that an entity created concurrently by another writer is not duplicated.
+// Returns the entity as a types.Entity, the outcome of the upsert, or an error if the upsert fails.
+func (neo *neoRepository) UpsertEntity(ctx context.Context, input *types.Entity) (*types.Entity, types.UpsertResult, error) {
+	if input == nil || input.Asset == nil {
+		return nil, "", errors.New("failed input validation checks")
+	}
+	if err := neo.validateAsset(input.Asset); err != nil {
+		return nil, "", err
+	}
+
+	if e, err := neo.restoreDeletedEntity(ctx, input.Asset); err == nil {
+		// a restored entity is not reported as newly created
+		e, err = neo.CreateEntity(ctx, &types.Entity{ID: e.ID, CreatedAt: e.CreatedAt, Asset: input.Asset})
+		if err != nil {
+			return nil, "", err
+		}
+		return e, types.UpsertUpdated, nil
+	}
+
+	qnode, params, err := queryNodeByAssetKey("a", input.Asset)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entity := &types.Entity{
+		ID:        neo.newID(),
+		CreatedAt: input.CreatedAt,
+		LastSeen:  input.LastSeen,
+		Asset:     input.Asset,
+	}
+	if entity.CreatedAt.IsZero() {
+		entity.CreatedAt = time.Now()
+	}
+	if entity.LastSeen.IsZero() {
+		entity.LastSeen = time.Now()
+	}
+
+	props, err := entityPropsMap(entity)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	params["props"] = props
+	params["updated"] = timeToNeo4jTime(time.Now())
+	query := fmt.Sprintf("MERGE %s ON CREATE SET a = $props, a:Entity ON MATCH SET a.updated_at = $updated RETURN a", qnode)
+	result, err := neo.executeRetryable(ctx, query, params)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(result.Records) == 0 {
+		return nil, "", errors.New("no records returned from the query")
+	}
+
+	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "a")
+	if err != nil {
+		return nil, "", err
+	}
+	if isnil {
+		return nil, "", errors.New("the record value for the node is nil")
+	}
+
+	e, err := nodeToEntity(node)
+	if err != nil {
+		return nil, "", err
+	}
+	// the generated ID is only assigned when the node is created by the MERGE
+	if e.ID == entity.ID {
+		return e, types.UpsertCreated, nil
+	}
+	if sameContent(e.Asset, input.Asset) {
+		return e, types.UpsertUnchanged, nil
+	}
+
+	e, err = neo.UpdateEntity(ctx, e.ID, input.Asset)
+	if err != nil {
+		return nil, "", err
+	}
+	return e, types.UpsertUpdated, nil
+}
+
+// sameContent reports whether the assets or relations serialize to the same JSON content.
+func sameContent(a, b interface{ JSON() ([]byte, error) }) bool {
+	ac, err := a.JSON()
+	if err != nil {
+		return false
+	}
+
+	bc, err := b.JSON()
+	if err != nil {
+		return false
+	}
+	return jsoncmp.Equal(ac, bc)
+}
+
+// TouchEntity sets the last seen time of the entity in the database to the current time,
+// which records that the asset was observed again without creating a duplicate entity or tag.
+// Returns an error matching types.ErrNotFound if the entity is not found, or an error if the update fails.
+func (neo *neoRepository) TouchEntity(ctx context.Context, id string) error {
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	query := fmt.Sprintf("MATCH (a:Entity {entity_id: $eid}) SET a.updated_at = localDateTime('%s') RETURN a.entity_id AS eid", timeToNeo4jTime(time.Now()))
+	result, err := neo.executeRetryable(ctx, query, map[string]interface{}{
+		"eid": id,
+	})
+	if err != nil {
+		return err
+	}
+	if len(result.Records) == 0 {
+		return types.NotFound("entity not found")
+	}
+	return nil
+}
+
+// UpdateEntity replaces the asset of the entity node in the database, such as when an enrichment adds fields to its content.
+// The properties of the node are written again, including the recomputed content hash, and the last seen time is set to
+// the current time, while the ID, the creation time, and the relationships of the node are kept. The asset must be of the
+// asset type of the entity, and an asset held by another entity, including a soft-deleted one, returns an error matching
+// types.ErrDuplicate. Returns an error matching types.ErrNotFound if the entity is not found.
+func (neo *neoRepository) UpdateEntity(ctx context.Context, id string, asset oam.Asset) (*types.Entity, error) {
+	if asset == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+	if err := neo.validateAsset(asset); err != nil {
+		return nil, err
+	}
+
+	e, err := neo.FindEntityById(ctx, id)
+	if errors.Is(err, types.ErrNotFound) {
+		return nil, types.ErrEntityNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if e.Asset.AssetType() != asset.AssetType() {
+		return nil, errors.New("the asset type does not match the existing entity")
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	// the content hash also matches the soft-deleted nodes, which would otherwise be restored as duplicates
+	result, err := neo.executeRead(ctx,
+		"MATCH (a) WHERE (a:Entity OR a:DeletedEntity) AND a.content_hash = $hash AND a.entity_id <> $eid RETURN count(a) AS total",
+		map[string]interface{}{"eid": id, "hash": types.ContentHash(asset)},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) == 0 {
+		return nil, errors.New("no records returned from the query")
+	}
+	if total, _, err := neo4jdb.GetRecordValue[int64](result.Records[0], "total"); err != nil {
+		return nil, err
+	} else if total > 0 {
+		return nil, types.Duplicate("the asset already exists with another entity ID")
+	}
+
+	props, err := entityPropsMap(&types.Entity{
+		ID:        id,
+		CreatedAt: e.CreatedAt,
+		LastSeen:  time.Now(),
+		Asset:     asset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the type label is kept, since the asset type cannot change
+	result, err = neo.executeQuery(ctx,
+		"MATCH (a:Entity {entity_id: $eid}) SET a = $props RETURN a",
+		map[string]interface{}{"eid": id, "props": props},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) == 0 {
+		// the entity was deleted after the lookup
+		return nil, types.ErrEntityNotFound
+	}
+
+	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "a")
+	if err != nil {
+		return nil, err
+	}
+	if isnil {
+		return nil, errors.New("the record value for the node is nil")
+	}
+	return nodeToEntity(node)
+}
+
+// createEntities performs the work of CreateEntities using the provided transaction.
+// The results slice is populated in the same order as the inputs.
+func (neo *neoRepository) createEntities(ctx context.Context, tx queryRunner, inputs, results []*types.Entity) error {
+	ids := make(map[string]int)
+	seen := make(map[string]string)
+	batches := make(map[oam.AssetType][]map[string]interface{})
+	for i, input := range inputs {
+		// duplicate assets within the batch share a single node
+		key := string(input.Asset.AssetType()) + ":" + input.Asset.Key()
+		if id, found := seen[key]; found && input.ID == "" {
+			results[i] = &types.Entity{ID: id}
+			continue
+		}
+
+		entity := &types.Entity{
+			ID:        input.ID,
+			CreatedAt: input.CreatedAt,
+			LastSeen:  time.Now(),
+			Asset:     input.Asset,
+		}
+
+		exists := input.ID != ""
+		if !exists {
+			qnode, params, err := queryNodeByAssetKey("a", input.Asset)
+			if err != nil {
+				return err
+			}
+
+			existing, err := tx.Run(ctx, "MATCH "+qnode+" RETURN a.entity_id AS eid, a.created_at AS created", params)
+			if err != nil {
+				return err
+			}
+			if record, err := existing.Single(ctx); err == nil {
+				eid, _, err := neo4jdb.GetRecordValue[string](record, "eid")
+				if err != nil {
+					return err
+				}
+				created, _, err := neo4jdb.GetRecordValue[neo4jdb.LocalDateTime](record, "created")
+				if err != nil {
+					return err
+				}
+
+				exists = true
+				entity.ID = eid
+				entity.CreatedAt = neo4jTimeToTime(created)
+			}
+		}
+		if !exists && neo.softDelete {
+			// a soft-deleted entity with matching content is restored rather than duplicated
+			query, params, err := restoreNodeQuery(input.Asset)
+			if err != nil {
+				return err
+			}
+
+			restored, err := tx.Run(ctx, query, params)
+			if err != nil {
+				return err
+			}
+			if record, err := restored.Single(ctx); err == nil {
+				node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
+				if err != nil {
+					return err
+				}
+				if isnil {
+					return errors.New("the record value for the node is nil")
+				}
+
+				e, err := nodeToEntity(node)
+				if err != nil {
+					return err
+				}
+
+				exists = true
+				entity.ID = e.ID
+				entity.CreatedAt = e.CreatedAt
+			}
+		}
+		if exists {
+			// the entity already exists in the database and needs to be updated
+			props, err := entityPropsMap(entity)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.Run(ctx, "MATCH (a:Entity {entity_id: $eid}) SET a = $props",
+				map[string]interface{}{"eid": entity.ID, "props": props}); err != nil {
+				return err
+			}
+
+			seen[key] = entity.ID
+			results[i] = entity
+			continue
+		}
+
+		entity.CreatedAt = input.CreatedAt
+		entity.LastSeen = input.LastSeen
+		entity.ID = neo.newID()
+		if entity.CreatedAt.IsZero() {
+			entity.CreatedAt = time.Now()
+		}
+		if entity.LastSeen.IsZero() {
+			entity.LastSeen = time.Now()
+		}
+
+		props, err := entityPropsMap(entity)
+		if err != nil {
+			return err
+		}
+
+		seen[key] = entity.ID
+		ids[entity.ID] = i
+		atype := input.Asset.AssetType()
+		batches[atype] = append(batches[atype], props)
+	}
+
+	for atype, rows := range batches {
+		for start := 0; start < len(rows); start += neo.batchSize {
+			end := min(start+neo.batchSize, len(rows))
+
+			query := fmt.Sprintf("UNWIND $rows AS props CREATE (a:Entity:%s) SET a = props RETURN a", atype)
+			result, err := tx.Run(ctx, query, map[string]interface{}{"rows": rows[start:end]})
+			if err != nil {
+				return err
+			}
+
+			records, err := result.Collect(ctx)
+			if err != nil {
+				return err
+			}
+			if len(records) != end-start {
+				return errors.New("the number of created nodes does not match the batch size")
+			}
+
+			for _, record := range records {
+				node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
+				if err != nil {
+					return err
+				}
+				if isnil {
+					return errors.New("the record value for the node is nil")
+				}
+
+				e, err := nodeToEntity(node)
+				if err != nil {
+					return err
+				}
+				results[ids[e.ID]] = e
+			}
+		}
+	}
+	return nil
 }
 
-func (neo *neoRepository) uniqueEntityID() string {
+func (neo *neoRepository) uniqueEntityID(ctx context.Context) string {
 	for {
-		id := uuid.New().String()
-		if _, err := neo.FindEntityById(id); err != nil {
+		id := neo.newID()
+		if _, err := neo.FindEntityById(ctx, id); err != nil {
 			return id
 		}
 	}
@@ -148,22 +578,54 @@ func (neo *neoRepository) uniqueEntityID() string {
 
 // FindEntityById finds an entity in the database by the ID.
 // It takes a string representing the entity ID and retrieves the corresponding entity from the database.
-// Returns the found entity as a types.Entity or an error if the asset is not found.
-func (neo *neoRepository) FindEntityById(id string) (*types.Entity, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// Returns the found entity as a types.Entity, an error matching types.ErrNotFound if the entity is not found,
+// or the error of the query when the lookup fails.
+func (neo *neoRepository) FindEntityById(ctx context.Context, id string) (*types.Entity, error) {
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db,
+	result, err := neo.executeRead(ctx,
 		"MATCH (a:Entity {entity_id: $eid}) RETURN a",
 		map[string]interface{}{"eid": id},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
 	)
 	if err != nil {
 		return nil, err
 	}
 	if len(result.Records) == 0 {
-		return nil, fmt.Errorf("the entity with ID %s was not found", id)
+		return nil, types.NotFound(fmt.Sprintf("the entity with ID %s was not found", id))
+	}
+
+	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "a")
+	if err != nil {
+		return nil, err
+	}
+	if isnil {
+		return nil, errors.New("the record value for the node is nil")
+	}
+	return nodeToEntity(node)
+}
+
+// FindEntityByHash finds the entity in the database with the provided content hash, as returned by types.ContentHash.
+// The hash is stored in the content_hash property of the entity node, which is indexed by the schema migrations.
+// Returns the found entity as a types.Entity, an error matching types.ErrNotFound if no entity has the hash,
+// or the error of the query when the lookup fails.
+func (neo *neoRepository) FindEntityByHash(ctx context.Context, hash string) (*types.Entity, error) {
+	if hash == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx,
+		"MATCH (a:Entity {content_hash: $hash}) RETURN a LIMIT 1",
+		map[string]interface{}{"hash": hash},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) == 0 {
+		return nil, types.NotFound(fmt.Sprintf("the entity with content hash %s was not found", hash))
 	}
 
 	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "a")
@@ -176,13 +638,86 @@ func (neo *neoRepository) FindEntityById(id string) (*types.Entity, error) {
 	return nodeToEntity(node)
 }
 
+// BackfillContentHashes sets the content_hash property of the entity nodes written before the property was added,
+// including the soft-deleted entities, so FindEntityByHash matches them once they are restored. The nodes are
+// updated in batches of the default batch size, selected in the order of their element IDs. The nodes that cannot
+// be parsed are left without the property, which the unique constraint allows more than once, and are passed over
+// by the element ID of the last node selected. It's called by the schema migrations of the assetdb package, before
+// the content hash is made unique.
+func BackfillContentHashes(ctx context.Context, driver neo4jdb.DriverWithContext, dbname string) error {
+	var last string
+	for {
+		result, err := neo4jdb.ExecuteQuery(ctx, driver,
+			"MATCH (a) WHERE (a:Entity OR a:DeletedEntity) AND a.content_hash IS NULL AND elementId(a) > $last "+
+				"RETURN a ORDER BY elementId(a) LIMIT $limit",
+			map[string]interface{}{"last": last, "limit": defaultBatchSize},
+			neo4jdb.EagerResultTransformer, neo4jdb.ExecuteQueryWithDatabase(dbname))
+		if err != nil {
+			return err
+		}
+		if len(result.Records) == 0 {
+			return nil
+		}
+
+		rows := make([]map[string]interface{}, 0, len(result.Records))
+		for _, rec := range result.Records {
+			node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](rec, "a")
+			if err != nil {
+				return err
+			}
+			if isnil {
+				return errors.New("the record value for the node is nil")
+			}
+
+			last = node.ElementId
+			if entity, err := nodeToEntity(node); err == nil {
+				rows = append(rows, map[string]interface{}{"nid": node.ElementId, "hash": entity.ContentHash()})
+			}
+		}
+
+		if _, err := neo4jdb.ExecuteQuery(ctx, driver,
+			"UNWIND $rows AS row MATCH (a) WHERE elementId(a) = row.nid SET a.content_hash = row.hash",
+			map[string]interface{}{"rows": rows},
+			neo4jdb.EagerResultTransformer, neo4jdb.ExecuteQueryWithDatabase(dbname)); err != nil {
+			return err
+		}
+	}
+}
+
+// EntityExists reports whether the database holds an entity that matches the provided asset data, as matched by
+// FindEntitiesByContent. The query returns whether a node matches, so the properties of the node are not read.
+// Returns false, without an error, if no entity matches the asset.
+func (neo *neoRepository) EntityExists(ctx context.Context, assetData oam.Asset) (bool, error) {
+	qnode, params, err := queryNodeByAssetKey("a", assetData)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, "MATCH "+qnode+" WITH a LIMIT 1 RETURN count(a) > 0 AS found", params)
+	if err != nil {
+		return false, err
+	}
+	if len(result.Records) == 0 {
+		return false, errors.New("no records returned from the query")
+	}
+
+	found, _, err := neo4jdb.GetRecordValue[bool](result.Records[0], "found")
+	if err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
 // FindEntitiesByContent finds entities in the database that match the provided asset data and last seen after
 // the since parameter. It takes an oam.Asset as input and searches for entities with matching content in the database.
 // If since.IsZero(), the parameter will be ignored.
 // The asset data is serialized to JSON and compared against the Content field of the Entity struct.
 // Returns a slice of matching entities as []*types.Entity or an error if the search fails.
-func (neo *neoRepository) FindEntitiesByContent(assetData oam.Asset, since time.Time) ([]*types.Entity, error) {
-	qnode, err := queryNodeByAssetKey("a", assetData)
+func (neo *neoRepository) FindEntitiesByContent(ctx context.Context, assetData oam.Asset, since time.Time) ([]*types.Entity, error) {
+	qnode, params, err := queryNodeByAssetKey("a", assetData)
 	if err != nil {
 		return nil, err
 	}
@@ -192,18 +727,15 @@ func (neo *neoRepository) FindEntitiesByContent(assetData oam.Asset, since time.
 		query = fmt.Sprintf("MATCH %s WHERE a.updated_at >= localDateTime('%s') RETURN a", qnode, timeToNeo4jTime(since))
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query, nil,
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-	)
+	result, err := neo.executeRead(ctx, query, params)
 	if err != nil {
 		return nil, err
 	}
 	if len(result.Records) == 0 {
-		return nil, errors.New("no entities found")
+		return nil, types.NotFound("no entities found")
 	}
 
 	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "a")
@@ -221,68 +753,737 @@ func (neo *neoRepository) FindEntitiesByContent(assetData oam.Asset, since time.
 	return []*types.Entity{e}, nil
 }
 
-// FindEntitiesByType finds all entities in the database of the provided asset type and last seen after the since parameter.
-// It takes an asset type and retrieves the corresponding entities from the database.
+// FindEntitiesByContents finds the entities in the database that match the provided assets and were last seen after the since parameter.
+// The assets are matched by a single query, which unwinds the key values of each asset type, rather than a round trip per asset.
 // If since.IsZero(), the parameter will be ignored.
-// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
-func (neo *neoRepository) FindEntitiesByType(atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
-	query := fmt.Sprintf("MATCH (a:%s) RETURN a", string(atype))
+// Returns the matching entities keyed by the types.ContentHash of the asset, where the assets without a matching
+// entity are absent from the map, or an error if the search fails.
+func (neo *neoRepository) FindEntitiesByContents(ctx context.Context, assets []oam.Asset, since time.Time) (map[string]*types.Entity, error) {
+	type keyGroup struct {
+		atype  oam.AssetType
+		prop   string
+		values []interface{}
+	}
+
+	var groups []*keyGroup
+	byType := make(map[oam.AssetType]*keyGroup)
+	for _, asset := range assets {
+		if asset == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+
+		atype, prop, value, err := assetKeyProperty(asset)
+		if err != nil {
+			return nil, err
+		}
+
+		g, found := byType[atype]
+		if !found {
+			g = &keyGroup{atype: atype, prop: prop}
+			byType[atype] = g
+			groups = append(groups, g)
+		}
+		g.values = append(g.values, value)
+	}
+
+	results := make(map[string]*types.Entity, len(assets))
+	if len(groups) == 0 {
+		return results, nil
+	}
+
+	var where string
 	if !since.IsZero() {
-		query = fmt.Sprintf("MATCH (a:%s) WHERE a.updated_at >= localDateTime('%s') RETURN a", string(atype), timeToNeo4jTime(since))
+		where = fmt.Sprintf(" WHERE a.updated_at >= localDateTime('%s')", timeToNeo4jTime(since))
+	}
+
+	var queries []string
+	params := make(map[string]interface{}, len(groups))
+	for i, g := range groups {
+		name := fmt.Sprintf("keys%d", i)
+
+		params[name] = g.values
+		queries = append(queries, fmt.Sprintf("UNWIND $%s AS key MATCH (a:%s {%s: key})%s RETURN a", name, g.atype, g.prop, where))
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query, nil,
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-	)
+	result, err := neo.executeRead(ctx, strings.Join(queries, " UNION ALL "), params)
 	if err != nil {
 		return nil, err
 	}
-	if len(result.Records) == 0 {
-		return nil, errors.New("no entities of the specified type")
-	}
 
-	var results []*types.Entity
 	for _, record := range result.Records {
 		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
-		if err != nil {
-			return nil, err
-		}
-		if isnil {
-			return nil, errors.New("the record value for the node is nil")
+		if err != nil || isnil {
+			continue
 		}
 
 		e, err := nodeToEntity(node)
 		if err != nil {
-			return nil, err
+			continue
 		}
-		results = append(results, e)
-	}
 
-	if len(results) == 0 {
-		return nil, errors.New("no entities of the specified type")
+		key := types.ContentHash(e.Asset)
+		if _, found := results[key]; !found {
+			results[key] = e
+		}
 	}
 	return results, nil
 }
 
-// DeleteEntity removes an entity in the database by its ID.
-// It takes a string representing the entity ID and removes the corresponding entity from the database.
-// Returns an error if the entity is not found.
-func (neo *neoRepository) DeleteEntity(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// FindEntitiesByType finds all entities in the database of the provided asset type and last seen after the since parameter.
+// It takes an asset type and retrieves the corresponding entities from the database.
+// If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (neo *neoRepository) FindEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	return neo.FindEntitiesByTypeBetween(ctx, atype, since, time.Time{})
+}
 
-	_, err := neo4jdb.ExecuteQuery(ctx, neo.db,
+// FindEntitiesByTypes finds all entities in the database of the provided asset types and last seen after the since parameter,
+// with a single query. The entities are ordered by their creation time, with ties broken by the entity ID, so the entities of
+// the different types are interleaved in a stable order. If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching entities as []*types.Entity or an error if no asset types are provided or the search fails.
+func (neo *neoRepository) FindEntitiesByTypes(ctx context.Context, since time.Time, atypes ...oam.AssetType) ([]*types.Entity, error) {
+	if len(atypes) == 0 {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	etypes := make([]string, 0, len(atypes))
+	for _, atype := range atypes {
+		etypes = append(etypes, string(atype))
+	}
+
+	query := "MATCH (a:Entity) WHERE a.etype IN $etypes"
+	if !since.IsZero() {
+		query += fmt.Sprintf(" AND a.updated_at >= localDateTime('%s')", timeToNeo4jTime(since))
+	}
+	query += " RETURN a ORDER BY a.created_at, a.entity_id"
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, query, map[string]interface{}{"etypes": etypes})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, record := range result.Records {
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
+		if err != nil {
+			return nil, err
+		}
+		if isnil {
+			return nil, errors.New("the record value for the node is nil")
+		}
+
+		e, err := nodeToEntity(node)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, e)
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("no entities of the specified types")
+	}
+	return results, nil
+}
+
+// FindEntitiesByTypeBetween finds all entities in the database of the provided asset type and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// The entities are sorted as set by options.WithOrder, or the most recently seen entities are returned first
+// when the repository was opened with options.WithLastSeenOrder.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (neo *neoRepository) FindEntitiesByTypeBetween(ctx context.Context, atype oam.AssetType, from, to time.Time) ([]*types.Entity, error) {
+	if err := checkLabel(string(atype)); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("MATCH (a:%s)%s RETURN a", string(atype), seenBetween("a", from, to))
+	if neo.order != "" {
+		query += neo.order
+	} else if neo.lastSeen {
+		query += " ORDER BY a.updated_at DESC"
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) == 0 {
+		return nil, types.NotFound("no entities of the specified type")
+	}
+
+	var results []*types.Entity
+	for _, record := range result.Records {
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
+		if err != nil {
+			return nil, err
+		}
+		if isnil {
+			return nil, errors.New("the record value for the node is nil")
+		}
+
+		e, err := nodeToEntity(node)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, e)
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("no entities of the specified type")
+	}
+	return results, nil
+}
+
+// DiffEntities compares the entities in the database of the provided asset type at the t1 and t2 snapshots, such as
+// the start times of two scans. The added entities were first seen within the [t1, t2) window, and the removed entities
+// were created before t1 and last seen within the window, so they have not been seen since t2.
+// Returns the added and removed entities, or an error if t2 is not after t1 or the search fails.
+func (neo *neoRepository) DiffEntities(ctx context.Context, atype oam.AssetType, t1, t2 time.Time) ([]*types.Entity, []*types.Entity, error) {
+	if err := checkLabel(string(atype)); err != nil {
+		return nil, nil, err
+	}
+
+	if !t2.After(t1) {
+		return nil, nil, errors.New("failed input validation checks")
+	}
+
+	from, to := timeToNeo4jTime(t1), timeToNeo4jTime(t2)
+	query := fmt.Sprintf("MATCH (a:%s) WHERE a.updated_at >= localDateTime('%s') AND "+
+		"((a.created_at >= localDateTime('%s') AND a.created_at < localDateTime('%s')) OR "+
+		"(a.created_at < localDateTime('%s') AND a.updated_at < localDateTime('%s'))) RETURN a ORDER BY a.created_at, a.entity_id",
+		string(atype), from, from, to, from, to)
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, query, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var added, removed []*types.Entity
+	for _, record := range result.Records {
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
+		if err != nil {
+			return nil, nil, err
+		}
+		if isnil {
+			return nil, nil, errors.New("the record value for the node is nil")
+		}
+
+		e, err := nodeToEntity(node)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if e.CreatedAt.Before(t1) {
+			removed = append(removed, e)
+		} else {
+			added = append(added, e)
+		}
+	}
+	return added, removed, nil
+}
+
+// SearchEntities finds the entities in the database of the provided asset type and last seen after the since parameter,
+// which contain the query string within any of their asset properties. If since.IsZero(), the parameter will be ignored.
+// The query is passed as a parameter and matched with CONTAINS, so it is matched literally.
+// The search cannot make use of the property indexes, so it is slower than FindEntitiesByContent.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (neo *neoRepository) SearchEntities(ctx context.Context, atype oam.AssetType, query string, since time.Time) ([]*types.Entity, error) {
+	if query == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+	if err := checkLabel(string(atype)); err != nil {
+		return nil, err
+	}
+
+	where := "any(k IN keys(a) WHERE NOT k IN ['entity_id', 'etype', 'created_at', 'updated_at'] AND toStringOrNull(a[k]) CONTAINS $query)"
+	if !since.IsZero() {
+		where = fmt.Sprintf("a.updated_at >= localDateTime('%s') AND %s", timeToNeo4jTime(since), where)
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, fmt.Sprintf("MATCH (a:%s) WHERE %s RETURN a", string(atype), where),
+		map[string]interface{}{"query": query},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, record := range result.Records {
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
+		if err != nil {
+			return nil, err
+		}
+		if isnil {
+			return nil, errors.New("the record value for the node is nil")
+		}
+
+		e, err := nodeToEntity(node)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, e)
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// FindEntitiesByTypePaged finds a page of entities in the database of the provided asset type and last seen after
+// the since parameter. The entities are ordered by creation time and then by ID, so that pages remain stable.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the matching entities within the page, the total number of matching entities, or an error if the search fails.
+func (neo *neoRepository) FindEntitiesByTypePaged(ctx context.Context, atype oam.AssetType, since time.Time, offset, limit int) ([]*types.Entity, int64, error) {
+	if err := checkLabel(string(atype)); err != nil {
+		return nil, 0, err
+	}
+
+	if offset < 0 || limit <= 0 {
+		return nil, 0, errors.New("failed input validation checks")
+	}
+
+	match := fmt.Sprintf("MATCH (a:%s)", string(atype))
+	if !since.IsZero() {
+		match = fmt.Sprintf("MATCH (a:%s) WHERE a.updated_at >= localDateTime('%s')", string(atype), timeToNeo4jTime(since))
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, match+" RETURN count(a) AS total", nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(result.Records) == 0 {
+		return nil, 0, types.NotFound("no entities of the specified type")
+	}
+
+	total, _, err := neo4jdb.GetRecordValue[int64](result.Records[0], "total")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result, err = neo.executeRead(ctx,
+		match+" RETURN a ORDER BY a.created_at, a.entity_id SKIP $offset LIMIT $limit",
+		map[string]interface{}{
+			"offset": offset,
+			"limit":  limit,
+		},
+	)
+	if err != nil {
+		return nil, total, err
+	}
+
+	var results []*types.Entity
+	for _, record := range result.Records {
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
+		if err != nil {
+			return nil, total, err
+		}
+		if isnil {
+			return nil, total, errors.New("the record value for the node is nil")
+		}
+
+		e, err := nodeToEntity(node)
+		if err != nil {
+			return nil, total, err
+		}
+		results = append(results, e)
+	}
+
+	if len(results) == 0 {
+		return nil, total, types.NotFound("no entities of the specified type")
+	}
+	return results, total, nil
+}
+
+// CountEntitiesByType counts the entities in the database of the provided asset type and last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the number of matching entities or an error if the count fails.
+func (neo *neoRepository) CountEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (int64, error) {
+	if err := checkLabel(string(atype)); err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("MATCH (a:%s) RETURN count(a) AS total", string(atype))
+	if !since.IsZero() {
+		query = fmt.Sprintf("MATCH (a:%s) WHERE a.updated_at >= localDateTime('%s') RETURN count(a) AS total", string(atype), timeToNeo4jTime(since))
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	return neo.countQuery(ctx, query)
+}
+
+// CountEntitiesGrouped counts the entities in the database of each asset type and last seen after the since parameter,
+// with a single query that aggregates the entity nodes by their asset type. If since.IsZero(), the parameter will be ignored.
+// Returns the number of matching entities keyed by the asset type, where the types without entities are absent
+// from the map, or an error if the count fails.
+func (neo *neoRepository) CountEntitiesGrouped(ctx context.Context, since time.Time) (map[oam.AssetType]int64, error) {
+	query := "MATCH (a:Entity) RETURN a.etype AS etype, count(a) AS total"
+	if !since.IsZero() {
+		query = fmt.Sprintf("MATCH (a:Entity) WHERE a.updated_at >= localDateTime('%s') RETURN a.etype AS etype, count(a) AS total", timeToNeo4jTime(since))
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[oam.AssetType]int64, len(result.Records))
+	for _, record := range result.Records {
+		etype, _, err := neo4jdb.GetRecordValue[string](record, "etype")
+		if err != nil {
+			return nil, err
+		}
+
+		total, _, err := neo4jdb.GetRecordValue[int64](record, "total")
+		if err != nil {
+			return nil, err
+		}
+		counts[oam.AssetType(etype)] = total
+	}
+	return counts, nil
+}
+
+// DeleteEntity removes an entity in the database by its ID.
+// It takes a string representing the entity ID and removes the corresponding entity from the database.
+// Returns the number of nodes deleted, which is zero when the entity is not found. When the soft-delete mode is
+// enabled, the number of nodes turned into tombstones is returned, and an error if the entity is not found.
+func (neo *neoRepository) DeleteEntity(ctx context.Context, id string) (int64, error) {
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	if neo.softDelete {
+		entity, err := neo.FindEntityById(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+
+		// the labels are swapped, so that the tombstone is excluded from the queries on live entities
+		atype := entity.Asset.AssetType()
+		query := fmt.Sprintf("MATCH (n:Entity:%s {entity_id: $eid}) REMOVE n:Entity:%s SET n:DeletedEntity, n.deleted_at = $deleted RETURN count(n) AS total", atype, atype)
+		result, err := neo.executeQuery(ctx, query, map[string]interface{}{
+			"eid":     id,
+			"deleted": timeToNeo4jTime(time.Now()),
+		})
+		if err != nil {
+			return 0, err
+		}
+		if len(result.Records) == 0 {
+			return 0, errors.New("no records returned from the query")
+		}
+
+		total, _, err := neo4jdb.GetRecordValue[int64](result.Records[0], "total")
+		return total, err
+	}
+
+	result, err := neo.executeQuery(ctx,
 		"MATCH (n:Entity {entity_id: $eid}) DETACH DELETE n",
 		map[string]interface{}{
 			"eid": id,
 		},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
 	)
+	if err != nil {
+		return 0, err
+	}
+	return int64(result.Summary.Counters().NodesDeleted()), nil
+}
 
+// DeleteEntityCascade permanently removes an entity in the database by its ID, along with its incoming and
+// outgoing edges, its tags, and the tags of its edges, within a single transaction. Unlike DeleteEntity, whose
+// DETACH DELETE leaves the tag nodes in place, the tags are removed, and the entity is removed even when the
+// soft-delete mode is enabled, including when it is already a tombstone.
+// Returns the number of nodes and relationships deleted, which is zero when the entity is not found.
+func (neo *neoRepository) DeleteEntityCascade(ctx context.Context, id string) (int64, error) {
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	var total int64
+	err := neo.WithTransaction(ctx, func(tx types.Repository) error {
+		txrepo := tx.(*neoRepository)
+
+		total = 0
+		var queries []string
+		for _, label := range []string{"Entity", "DeletedEntity"} {
+			queries = append(queries, fmt.Sprintf("MATCH (:%s {entity_id: $eid})-[r]-() WITH DISTINCT elementId(r) AS rid "+
+				"MATCH (t:EdgeTag {edge_id: rid}) DETACH DELETE t", label))
+		}
+		queries = append(queries, "MATCH (t:EntityTag {entity_id: $eid}) DETACH DELETE t")
+		for _, label := range []string{"Entity", "DeletedEntity"} {
+			queries = append(queries, fmt.Sprintf("MATCH (n:%s {entity_id: $eid}) DETACH DELETE n", label))
+		}
+
+		for _, query := range queries {
+			result, err := txrepo.executeQuery(ctx, query, map[string]interface{}{"eid": id})
+			if err != nil {
+				return err
+			}
+
+			counters := result.Summary.Counters()
+			total += int64(counters.NodesDeleted() + counters.RelationshipsDeleted())
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// DeleteEntitiesByType permanently removes the entities in the database of the provided asset type that were last seen
+// before the before parameter, along with their relationships, their tags, and the tags of their edges, within a single
+// transaction. If before.IsZero(), the parameter will be ignored. The soft-deleted entities are left to PurgeDeleted.
+// Returns the number of entities deleted.
+func (neo *neoRepository) DeleteEntitiesByType(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	if err := checkLabel(string(atype)); err != nil {
+		return 0, err
+	}
+
+	match := fmt.Sprintf("MATCH (a:%s)", string(atype))
+	if !before.IsZero() {
+		match = fmt.Sprintf("MATCH (a:%s) WHERE a.updated_at < localDateTime('%s')", string(atype), timeToNeo4jTime(before))
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	var total int64
+	err := neo.WithTransaction(ctx, func(tx types.Repository) error {
+		txrepo := tx.(*neoRepository)
+
+		for _, query := range []string{
+			match + " MATCH (a)-[r]-() WITH DISTINCT elementId(r) AS rid MATCH (t:EdgeTag {edge_id: rid}) DETACH DELETE t",
+			match + " MATCH (t:EntityTag {entity_id: a.entity_id}) DETACH DELETE t",
+			match + " DETACH DELETE a",
+		} {
+			result, err := txrepo.executeQuery(ctx, query, nil)
+			if err != nil {
+				return err
+			}
+			total = int64(result.Summary.Counters().NodesDeleted())
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// FindOrphanEntities finds the entities in the database of the provided asset type and last seen after the since
+// parameter that have no incoming or outgoing edges. An edge to a soft-deleted entity keeps the entity from being
+// an orphan, since the entity it refers to can be restored. If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (neo *neoRepository) FindOrphanEntities(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	if err := checkLabel(string(atype)); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("MATCH (a:%s) WHERE NOT (a)--() RETURN a ORDER BY a.entity_id", string(atype))
+	if !since.IsZero() {
+		query = fmt.Sprintf("MATCH (a:%s) WHERE NOT (a)--() AND a.updated_at >= localDateTime('%s') RETURN a ORDER BY a.entity_id",
+			string(atype), timeToNeo4jTime(since))
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, record := range result.Records {
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
+		if err != nil {
+			return nil, err
+		}
+		if isnil {
+			return nil, errors.New("the record value for the node is nil")
+		}
+
+		e, err := nodeToEntity(node)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, e)
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("no orphan entities of the specified type")
+	}
+	return results, nil
+}
+
+// DeleteOrphanEntities permanently removes the entities in the database of the provided asset type that were last seen
+// before the before parameter and have no incoming or outgoing edges, as found by FindOrphanEntities, along with their tags,
+// within a single transaction. If before.IsZero(), the parameter will be ignored. The soft-deleted entities are left to
+// PurgeDeleted. Returns the number of entities deleted.
+func (neo *neoRepository) DeleteOrphanEntities(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	if err := checkLabel(string(atype)); err != nil {
+		return 0, err
+	}
+
+	match := fmt.Sprintf("MATCH (a:%s) WHERE NOT (a)--()", string(atype))
+	if !before.IsZero() {
+		match = fmt.Sprintf("MATCH (a:%s) WHERE NOT (a)--() AND a.updated_at < localDateTime('%s')", string(atype), timeToNeo4jTime(before))
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	var total int64
+	err := neo.WithTransaction(ctx, func(tx types.Repository) error {
+		txrepo := tx.(*neoRepository)
+
+		for _, query := range []string{
+			match + " MATCH (t:EntityTag {entity_id: a.entity_id}) DETACH DELETE t",
+			match + " DELETE a",
+		} {
+			result, err := txrepo.executeQuery(ctx, query, nil)
+			if err != nil {
+				return err
+			}
+			total = int64(result.Summary.Counters().NodesDeleted())
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// FindDeletedEntities finds the soft-deleted entities in the database that were deleted after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// The entities are ordered by the time of deletion, and the DeletedAt field is set on each of them.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (neo *neoRepository) FindDeletedEntities(ctx context.Context, since time.Time) ([]*types.Entity, error) {
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	query := "MATCH (a:DeletedEntity) RETURN a ORDER BY a.deleted_at, a.entity_id"
+	if !since.IsZero() {
+		query = fmt.Sprintf("MATCH (a:DeletedEntity) WHERE a.deleted_at >= localDateTime('%s') RETURN a ORDER BY a.deleted_at, a.entity_id", timeToNeo4jTime(since))
+	}
+
+	result, err := neo.executeRead(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, record := range result.Records {
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
+		if err != nil {
+			return nil, err
+		}
+		if isnil {
+			return nil, errors.New("the record value for the node is nil")
+		}
+
+		e, err := nodeToEntity(node)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, e)
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// PurgeDeleted permanently removes the soft-deleted entities in the database that were deleted before the before parameter.
+// If before.IsZero(), all the soft-deleted entities are removed, along with their relationships.
+// Returns an error if the removal fails.
+func (neo *neoRepository) PurgeDeleted(ctx context.Context, before time.Time) error {
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	query := "MATCH (n:DeletedEntity) DETACH DELETE n"
+	if !before.IsZero() {
+		query = fmt.Sprintf("MATCH (n:DeletedEntity) WHERE n.deleted_at < localDateTime('%s') DETACH DELETE n", timeToNeo4jTime(before))
+	}
+
+	_, err := neo.executeQuery(ctx, query, nil)
 	return err
 }
+
+// restoreDeletedEntity restores the soft-deleted entity in the database that matches the provided asset data.
+func (neo *neoRepository) restoreDeletedEntity(ctx context.Context, asset oam.Asset) (*types.Entity, error) {
+	if !neo.softDelete {
+		return nil, errors.New("the soft-delete mode is not enabled")
+	}
+
+	query, params, err := restoreNodeQuery(asset)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeQuery(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) == 0 {
+		return nil, errors.New("no soft-deleted entity matches the asset")
+	}
+
+	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "a")
+	if err != nil {
+		return nil, err
+	}
+	if isnil {
+		return nil, errors.New("the record value for the node is nil")
+	}
+	return nodeToEntity(node)
+}
+
+// restoreNodeQuery returns the query that gives a soft-deleted node matching the provided asset its labels back,
+// along with the parameters of the query.
+func restoreNodeQuery(asset oam.Asset) (string, map[string]interface{}, error) {
+	qnode, params, err := queryNodeByAssetKey("a", asset)
+	if err != nil {
+		return "", nil, err
+	}
+
+	atype := string(asset.AssetType())
+	// the tombstone no longer carries the asset type label, so it is matched by the etype property
+	qnode = strings.Replace(qnode, "a:"+atype, "a:DeletedEntity", 1)
+	params["etype"] = atype
+	return fmt.Sprintf("MATCH %s WHERE a.etype = $etype WITH a LIMIT 1 REMOVE a:DeletedEntity, a.deleted_at SET a:Entity:%s RETURN a", qnode, atype), params, nil
+}
+
+// validateAsset returns the error of the validator provided by options.WithValidator, which rejects the asset
+// before it is written. The assets are accepted when no validator was provided.
+func (neo *neoRepository) validateAsset(asset oam.Asset) error {
+	if neo.validate == nil || asset == nil {
+		return nil
+	}
+	return neo.validate(asset)
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,7 +21,7 @@ import (
 // It takes an EntityTag as input and persists it in the database.
 // The property is serialized to JSON and stored in the Content field of the EntityTag struct.
 // Returns the created entity tag as a types.EntityTag or an error if the creation fails.
-func (neo *neoRepository) CreateEntityTag(entity *types.Entity, input *types.EntityTag) (*types.EntityTag, error) {
+func (neo *neoRepository) CreateEntityTag(ctx context.Context, entity *types.Entity, input *types.EntityTag) (*types.EntityTag, error) {
 	if input == nil {
 		return nil, errors.New("the input entity tag is nil")
 	}
@@ -33,10 +34,11 @@ func (neo *neoRepository) CreateEntityTag(entity *types.Entity, input *types.Ent
 			ID:        input.ID,
 			CreatedAt: input.CreatedAt,
 			LastSeen:  time.Now(),
+			ExpiresAt: input.ExpiresAt,
 			Property:  input.Property,
 			Entity:    entity,
 		}
-	} else if tags, err := neo.FindEntityTagsByContent(input.Property, time.Time{}); err == nil && len(tags) > 0 {
+	} else if tags, err := neo.FindEntityTagsByContent(ctx, input.Property, time.Time{}); err == nil && len(tags) > 0 {
 		// ensure that duplicate entity tags are not entered into the database
 		for _, t := range tags {
 			if t.Entity.ID == entity.ID {
@@ -48,6 +50,7 @@ func (neo *neoRepository) CreateEntityTag(entity *types.Entity, input *types.Ent
 		if tag != nil {
 			tag.Entity = entity
 			tag.LastSeen = time.Now()
+			tag.ExpiresAt = input.ExpiresAt
 		}
 	}
 
@@ -61,14 +64,12 @@ func (neo *neoRepository) CreateEntityTag(entity *types.Entity, input *types.Ent
 			return nil, err
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := neo.operationContext(ctx)
 		defer cancel()
 		// update the existing tag
-		result, err := neo4jdb.ExecuteQuery(ctx, neo.db,
+		result, err := neo.executeQuery(ctx,
 			"MATCH (n:EntityTag {tag_id: $tid}) SET p = $props RETURN p",
 			map[string]interface{}{"tid": tag.ID, "props": props},
-			neo4jdb.EagerResultTransformer,
-			neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
 		)
 		if err != nil {
 			return nil, err
@@ -90,7 +91,7 @@ func (neo *neoRepository) CreateEntityTag(entity *types.Entity, input *types.Ent
 		}
 	} else {
 		if input.ID == "" {
-			input.ID = neo.uniqueEntityTagID()
+			input.ID = neo.uniqueEntityTagID(ctx)
 		}
 		if input.CreatedAt.IsZero() {
 			input.CreatedAt = time.Now()
@@ -105,15 +106,11 @@ func (neo *neoRepository) CreateEntityTag(entity *types.Entity, input *types.Ent
 			return nil, err
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := neo.operationContext(ctx)
 		defer cancel()
 
 		query := fmt.Sprintf("CREATE (p:EntityTag:%s $props) RETURN p", input.Property.PropertyType())
-		result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query,
-			map[string]interface{}{"props": props},
-			neo4jdb.EagerResultTransformer,
-			neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-		)
+		result, err := neo.executeQuery(ctx, query, map[string]interface{}{"props": props})
 		if err != nil {
 			return nil, err
 		}
@@ -140,18 +137,134 @@ func (neo *neoRepository) CreateEntityTag(entity *types.Entity, input *types.Ent
 	return tag, nil
 }
 
+// CreateEntityTags creates the same tag on each of the entities in the database within a single statement.
+// The existing tag of an entity with the same property type, name, and value is updated rather than duplicated,
+// and the entities that are not found are skipped rather than failing the batch.
+// Returns the entity tags in the order of the entity IDs, along with a *types.MissingEntitiesError that lists the
+// skipped entities, or an error if the creation fails.
+func (neo *neoRepository) CreateEntityTags(ctx context.Context, entityIDs []string, input *types.EntityTag) ([]*types.EntityTag, error) {
+	if input == nil || input.Property == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	// ensure that duplicate entity tags are not entered into the database
+	dups := make(map[string]string)
+	if tags, err := neo.FindEntityTagsByContent(ctx, input.Property, time.Time{}); err == nil {
+		for _, t := range tags {
+			if _, found := dups[t.Entity.ID]; !found {
+				dups[t.Entity.ID] = t.ID
+			}
+		}
+	}
+
+	now := time.Now()
+	var expires interface{}
+	if !input.ExpiresAt.IsZero() {
+		expires = timeToNeo4jTime(input.ExpiresAt)
+	}
+
+	var ids []string
+	var touched []interface{}
+	var rows []interface{}
+	seen := make(map[string]struct{}, len(entityIDs))
+	for _, id := range entityIDs {
+		if _, found := seen[id]; found {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+
+		if tid, found := dups[id]; found {
+			touched = append(touched, tid)
+			continue
+		}
+
+		tag := &types.EntityTag{
+			ID:        uuid.New().String(),
+			CreatedAt: input.CreatedAt,
+			LastSeen:  input.LastSeen,
+			ExpiresAt: input.ExpiresAt,
+			Property:  input.Property,
+			Entity:    &types.Entity{ID: id},
+		}
+		if tag.CreatedAt.IsZero() {
+			tag.CreatedAt = now
+		}
+		if tag.LastSeen.IsZero() {
+			tag.LastSeen = now
+		}
+
+		props, err := entityTagPropsMap(tag)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, props)
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	var records []*neo4jdb.Record
+	if len(touched) > 0 {
+		// the tags of soft-deleted entities are not updated, since they no longer match an Entity node
+		result, err := neo.executeQuery(ctx, "UNWIND $tids AS tid MATCH (p:EntityTag {tag_id: tid}) "+
+			"MATCH (:Entity {entity_id: p.entity_id}) SET p.updated_at = $updated, p.expires_at = $expires RETURN p",
+			map[string]interface{}{"tids": touched, "updated": timeToNeo4jTime(now), "expires": expires})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, result.Records...)
+	}
+	if len(rows) > 0 {
+		query := fmt.Sprintf("UNWIND $rows AS row MATCH (:Entity {entity_id: row.entity_id}) "+
+			"CREATE (p:EntityTag:%s) SET p = row RETURN p", input.Property.PropertyType())
+		result, err := neo.executeQuery(ctx, query, map[string]interface{}{"rows": rows})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, result.Records...)
+	}
+
+	created := make(map[string]*types.EntityTag, len(records))
+	for _, record := range records {
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "p")
+		if err != nil || isnil {
+			continue
+		}
+
+		if t, err := nodeToEntityTag(node); err == nil && t != nil {
+			created[t.Entity.ID] = t
+		}
+	}
+
+	var missing []string
+	var results []*types.EntityTag
+	for _, id := range ids {
+		if t, found := created[id]; found {
+			results = append(results, t)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		return results, &types.MissingEntitiesError{IDs: missing}
+	}
+	return results, nil
+}
+
 // CreateEntityProperty creates a new entity tag in the database.
 // It takes an oam.Property as input and persists it in the database.
 // The property is serialized to JSON and stored in the Content field of the EntityTag struct.
 // Returns the created entity tag as a types.EntityTag or an error if the creation fails.
-func (neo *neoRepository) CreateEntityProperty(entity *types.Entity, prop oam.Property) (*types.EntityTag, error) {
-	return neo.CreateEntityTag(entity, &types.EntityTag{Property: prop})
+func (neo *neoRepository) CreateEntityProperty(ctx context.Context, entity *types.Entity, prop oam.Property) (*types.EntityTag, error) {
+	return neo.CreateEntityTag(ctx, entity, &types.EntityTag{Property: prop})
 }
 
-func (neo *neoRepository) uniqueEntityTagID() string {
+func (neo *neoRepository) uniqueEntityTagID(ctx context.Context) string {
 	for {
 		id := uuid.New().String()
-		if _, err := neo.FindEntityTagById(id); err != nil {
+		if _, err := neo.FindEntityTagById(ctx, id); err != nil {
 			return id
 		}
 	}
@@ -159,22 +272,20 @@ func (neo *neoRepository) uniqueEntityTagID() string {
 
 // FindEntityTagById finds an entity tag in the database by the ID.
 // It takes a string representing the entity tag ID and retrieves the corresponding tag from the database.
-// Returns the discovered tag as a types.EntityTag or an error if the asset is not found.
-func (neo *neoRepository) FindEntityTagById(id string) (*types.EntityTag, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// Returns the discovered tag as a types.EntityTag, types.ErrTagNotFound if the tag is not found, or an error if the search fails.
+func (neo *neoRepository) FindEntityTagById(ctx context.Context, id string) (*types.EntityTag, error) {
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db,
+	result, err := neo.executeRead(ctx,
 		"MATCH (p:EntityTag {tag_id: $tid}) RETURN p",
 		map[string]interface{}{"tid": id},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
 	)
 	if err != nil {
 		return nil, err
 	}
 	if len(result.Records) == 0 {
-		return nil, fmt.Errorf("the entity tag with ID %s was not found", id)
+		return nil, types.ErrTagNotFound
 	}
 
 	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "p")
@@ -192,8 +303,8 @@ func (neo *neoRepository) FindEntityTagById(id string) (*types.EntityTag, error)
 // If since.IsZero(), the parameter will be ignored.
 // The property data is serialized to JSON and compared against the Content field of the EntityTag struct.
 // Returns a slice of matching entity tags as []*types.EntityTag or an error if the search fails.
-func (neo *neoRepository) FindEntityTagsByContent(prop oam.Property, since time.Time) ([]*types.EntityTag, error) {
-	qnode, err := queryNodeByPropertyKeyValue("p", "EntityTag", prop)
+func (neo *neoRepository) FindEntityTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EntityTag, error) {
+	qnode, params, err := queryNodeByPropertyKeyValue("p", "EntityTag", prop)
 	if err != nil {
 		return nil, err
 	}
@@ -203,13 +314,10 @@ func (neo *neoRepository) FindEntityTagsByContent(prop oam.Property, since time.
 		query = fmt.Sprintf("MATCH %s WHERE p.updated_at >= localDateTime('%s') RETURN p", qnode, timeToNeo4jTime(since))
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query, nil,
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-	)
+	result, err := neo.executeRead(ctx, query, params)
 	if err != nil {
 		return nil, err
 	}
@@ -230,7 +338,7 @@ func (neo *neoRepository) FindEntityTagsByContent(prop oam.Property, since time.
 	}
 	// Check if any tags were found
 	if len(tags) == 0 {
-		return nil, errors.New("zero edge tags found")
+		return nil, types.NotFound("zero edge tags found")
 	}
 	return tags, nil
 }
@@ -238,19 +346,24 @@ func (neo *neoRepository) FindEntityTagsByContent(prop oam.Property, since time.
 // GetEntityTags finds all tags for the entity with the specified names and last seen after the since parameter.
 // If since.IsZero(), the parameter will be ignored.
 // If no names are specified, all tags for the specified entity are returned.
-func (neo *neoRepository) GetEntityTags(entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error) {
-	query := fmt.Sprintf("MATCH (p:EntityTag {entity_id: '%s'}) RETURN p", entity.ID)
-	if !since.IsZero() {
-		query = fmt.Sprintf("MATCH (p:EntityTag {entity_id: '%s'}) WHERE p.updated_at >= localDateTime('%s') RETURN p", entity.ID, timeToNeo4jTime(since))
+func (neo *neoRepository) GetEntityTags(ctx context.Context, entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error) {
+	return neo.GetEntityTagsBetween(ctx, entity, since, time.Time{}, names...)
+}
+
+// GetEntityTagsBetween finds all tags for the entity with the specified names and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no names are specified, all tags for the specified entity in the window are returned.
+func (neo *neoRepository) GetEntityTagsBetween(ctx context.Context, entity *types.Entity, from, to time.Time, names ...string) ([]*types.EntityTag, error) {
+	where := seenBetween("p", from, to)
+	if !neo.expiredTags {
+		where = unexpired(where, "p")
 	}
+	query := fmt.Sprintf("MATCH (p:EntityTag {entity_id: $eid})%s RETURN p", where)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	result, err := neo4jdb.ExecuteQuery(ctx, neo.db, query, nil,
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
-	)
+	result, err := neo.executeRead(ctx, query, map[string]interface{}{"eid": entity.ID})
 	if err != nil {
 		return nil, err
 	}
@@ -292,26 +405,251 @@ func (neo *neoRepository) GetEntityTags(entity *types.Entity, since time.Time, n
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero tags found")
+		return nil, types.NotFound("zero tags found")
+	}
+	return results, nil
+}
+
+// GetEntityTagsMatching finds the tags for the entity with the provided name and value, which were last seen after the
+// since parameter. The EntityTag nodes of each property type are matched by the properties that hold the name and the
+// value, and only the name is matched when the value is empty.
+// If since.IsZero(), the parameter will be ignored.
+// Returns a slice of the matching tags as []*types.EntityTag or an error if the search fails.
+func (neo *neoRepository) GetEntityTagsMatching(ctx context.Context, entity *types.Entity, since time.Time, name, value string) ([]*types.EntityTag, error) {
+	if name == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	where, params, err := namedTagMatch(name, value)
+	if err != nil {
+		return nil, err
+	}
+	if where == "" {
+		return nil, types.NotFound("zero tags found")
+	}
+
+	where = " WHERE " + where
+	if !since.IsZero() {
+		where += fmt.Sprintf(" AND p.updated_at >= localDateTime('%s')", timeToNeo4jTime(since))
+	}
+	if !neo.expiredTags {
+		where = unexpired(where, "p")
+	}
+	query := "MATCH (p:EntityTag {entity_id: $eid})" + where + " RETURN p ORDER BY p.created_at, p.tag_id"
+	params["eid"] = entity.ID
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.EntityTag
+	for _, record := range result.Records {
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "p")
+		if err != nil {
+			continue
+		}
+		if isnil {
+			continue
+		}
+
+		if tag, err := nodeToEntityTag(node); err == nil && tag != nil {
+			results = append(results, tag)
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero tags found")
 	}
 	return results, nil
 }
 
+// FindEntitiesByTag finds the entities in the database with a tag of the provided name and value, which was last seen
+// after the since parameter. The EntityTag nodes of each property type are matched by the properties that hold the name
+// and the value, and only the name is matched when the value is empty.
+// If since.IsZero(), the parameter will be ignored.
+// Returns a slice of the tagged entities as []*types.Entity or an error if the search fails.
+func (neo *neoRepository) FindEntitiesByTag(ctx context.Context, name, value string, since time.Time) ([]*types.Entity, error) {
+	if name == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	where, params, err := namedTagMatch(name, value)
+	if err != nil {
+		return nil, err
+	}
+	if where == "" {
+		return nil, types.NotFound("zero entities found")
+	}
+
+	query := "MATCH (p:EntityTag) WHERE " + where
+	if !since.IsZero() {
+		query += fmt.Sprintf(" AND p.updated_at >= localDateTime('%s')", timeToNeo4jTime(since))
+	}
+	query += " MATCH (a:Entity {entity_id: p.entity_id}) RETURN DISTINCT a"
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, record := range result.Records {
+		node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, "a")
+		if err != nil {
+			return nil, err
+		}
+		if isnil {
+			return nil, errors.New("the record value for the node is nil")
+		}
+
+		e, err := nodeToEntity(node)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, e)
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// namedTagMatch returns the condition on the tag node p that matches the tags with the provided name and value across the
+// property types, along with its parameters, or an empty condition when no property type can hold the value.
+// Only the name is matched when the value is empty.
+func namedTagMatch(name, value string) (string, map[string]interface{}, error) {
+	var conds []string
+	params := map[string]interface{}{"name": name}
+	for i, prop := range types.NamedProperties(name, value) {
+		nkey, vkey, v, err := propertyNameValueKeys(prop)
+		if err != nil {
+			return "", nil, err
+		}
+
+		cond := fmt.Sprintf("(p:%s AND p.%s = $name", prop.PropertyType(), nkey)
+		if value != "" {
+			param := fmt.Sprintf("value%d", i)
+			params[param] = v
+			cond += fmt.Sprintf(" AND p.%s = $%s", vkey, param)
+		}
+		conds = append(conds, cond+")")
+	}
+	if len(conds) == 0 {
+		return "", params, nil
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", params, nil
+}
+
+// UpdateEntityTag sets the value of the property of the entity tag in the database, and updates the last seen time
+// while preserving the time the tag was created.
+// Returns the updated entity tag as a types.EntityTag, types.ErrTagNotFound if the tag is not found, or an error if the update fails.
+func (neo *neoRepository) UpdateEntityTag(ctx context.Context, id string, value string) (*types.EntityTag, error) {
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRetryable(ctx,
+		"MATCH (p:EntityTag {tag_id: $tid}) RETURN p",
+		map[string]interface{}{"tid": id},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) == 0 {
+		return nil, types.ErrTagNotFound
+	}
+
+	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "p")
+	if err != nil {
+		return nil, err
+	}
+	if isnil {
+		return nil, errors.New("the record value for the node is nil")
+	}
+
+	tag, err := nodeToEntityTag(node)
+	if err != nil {
+		return nil, err
+	}
+
+	tag.Property, err = types.WithValue(tag.Property, value)
+	if err != nil {
+		return nil, err
+	}
+	tag.LastSeen = time.Now()
+
+	props, err := entityTagPropsMap(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err = neo.executeQuery(ctx,
+		"MATCH (p:EntityTag {tag_id: $tid}) SET p = $props RETURN p",
+		map[string]interface{}{"tid": id, "props": props},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) == 0 {
+		return nil, types.ErrTagNotFound
+	}
+
+	node, isnil, err = neo4jdb.GetRecordValue[neo4jdb.Node](result.Records[0], "p")
+	if err != nil {
+		return nil, err
+	}
+	if isnil {
+		return nil, errors.New("the record value for the node is nil")
+	}
+	return nodeToEntityTag(node)
+}
+
 // DeleteEntityTag removes an entity tag in the database by its ID.
 // It takes a string representing the entity tag ID and removes the corresponding tag from the database.
-// Returns an error if the tag is not found.
-func (neo *neoRepository) DeleteEntityTag(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// Returns the number of nodes deleted, which is zero when the tag is not found.
+func (neo *neoRepository) DeleteEntityTag(ctx context.Context, id string) (int64, error) {
+	ctx, cancel := neo.operationContext(ctx)
 	defer cancel()
 
-	_, err := neo4jdb.ExecuteQuery(ctx, neo.db,
+	result, err := neo.executeQuery(ctx,
 		"MATCH (n:EntityTag {tag_id: $tid}) DETACH DELETE n",
 		map[string]interface{}{
 			"tid": id,
 		},
-		neo4jdb.EagerResultTransformer,
-		neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
 	)
+	if err != nil {
+		return 0, err
+	}
+	return int64(result.Summary.Counters().NodesDeleted()), nil
+}
+
+// PurgeExpiredTags removes the entity and edge tags in the database that expired at or before the provided time.
+// A zero time removes the tags that have expired by now. Returns the number of nodes deleted.
+func (neo *neoRepository) PurgeExpiredTags(ctx context.Context, before time.Time) (int64, error) {
+	if before.IsZero() {
+		before = time.Now()
+	}
 
-	return err
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	var count int64
+	for _, label := range []string{"EntityTag", "EdgeTag"} {
+		result, err := neo.executeQuery(ctx,
+			"MATCH (n:"+label+") WHERE n.expires_at <= $before DETACH DELETE n",
+			map[string]interface{}{"before": timeToNeo4jTime(before)},
+		)
+		if err != nil {
+			return count, err
+		}
+		count += int64(result.Summary.Counters().NodesDeleted())
+	}
+	return count, nil
 }
@@ -7,21 +7,27 @@
 package neo4j
 
 import (
+	"context"
 	"fmt"
 	"net/netip"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/garthoid/asset-db/options"
 	"github.com/garthoid/asset-db/types"
+	"github.com/google/uuid"
 	oam "github.com/owasp-amass/open-asset-model"
 	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
 	oamnet "github.com/owasp-amass/open-asset-model/network"
 	"github.com/owasp-amass/open-asset-model/org"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestCreateEntity(t *testing.T) {
-	entity, err := store.CreateEntity(&types.Entity{
+	entity, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "create1.entity",
 		},
@@ -29,7 +35,7 @@ func TestCreateEntity(t *testing.T) {
 	assert.NoError(t, err)
 
 	time.Sleep(250 * time.Millisecond)
-	newer, err := store.CreateEntity(&types.Entity{
+	newer, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "create1.entity",
 		},
@@ -53,7 +59,7 @@ func TestCreateEntity(t *testing.T) {
 	}
 
 	time.Sleep(250 * time.Millisecond)
-	second, err := store.CreateEntity(&types.Entity{
+	second, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "create2.entity",
 		},
@@ -67,14 +73,14 @@ func TestCreateEntity(t *testing.T) {
 }
 
 func TestFindEntityById(t *testing.T) {
-	entity, err := store.CreateEntity(&types.Entity{
+	entity, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "find1.entity",
 		},
 	})
 	assert.NoError(t, err)
 
-	same, err := store.FindEntityById(entity.ID)
+	same, err := store.FindEntityById(context.Background(), entity.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, entity.ID, same.ID)
 
@@ -90,13 +96,13 @@ func TestFindEntityById(t *testing.T) {
 func TestFindEntitiesByContent(t *testing.T) {
 	fqdn := &dns.FQDN{Name: "findcontent.entity"}
 
-	_, err := store.FindEntitiesByContent(fqdn, time.Time{})
+	_, err := store.FindEntitiesByContent(context.Background(), fqdn, time.Time{})
 	assert.Error(t, err)
 
-	entity, err := store.CreateAsset(fqdn)
+	entity, err := store.CreateAsset(context.Background(), fqdn)
 	assert.NoError(t, err)
 
-	e, err := store.FindEntitiesByContent(fqdn, entity.CreatedAt.Add(-1*time.Second))
+	e, err := store.FindEntitiesByContent(context.Background(), fqdn, entity.CreatedAt.Add(-1*time.Second))
 	assert.NoError(t, err)
 	same := e[0]
 	assert.Equal(t, entity.ID, same.ID)
@@ -109,7 +115,7 @@ func TestFindEntitiesByContent(t *testing.T) {
 		t.Errorf("Failed to return an entity with the correct name")
 	}
 
-	_, err = store.FindEntitiesByContent(fqdn, entity.CreatedAt.Add(250*time.Millisecond))
+	_, err = store.FindEntitiesByContent(context.Background(), fqdn, entity.CreatedAt.Add(250*time.Millisecond))
 	assert.Error(t, err)
 }
 
@@ -120,14 +126,14 @@ func TestFindEntitiesByType(t *testing.T) {
 		addr, err := netip.ParseAddr(fmt.Sprintf("192.168.1.%d", i))
 		assert.NoError(t, err)
 
-		_, err = store.CreateAsset(&oamnet.IPAddress{
+		_, err = store.CreateAsset(context.Background(), &oamnet.IPAddress{
 			Address: addr,
 			Type:    "IPv4",
 		})
 		assert.NoError(t, err)
 	}
 
-	entities, err := store.FindEntitiesByType(oam.IPAddress, time.Time{})
+	entities, err := store.FindEntitiesByType(context.Background(), oam.IPAddress, time.Time{})
 	assert.NoError(t, err)
 
 	if len(entities) < 10 {
@@ -135,11 +141,11 @@ func TestFindEntitiesByType(t *testing.T) {
 	}
 
 	for i := 1; i <= 10; i++ {
-		_, err := store.CreateAsset(&org.Organization{Name: fmt.Sprintf("findtype%d.entity", i)})
+		_, err := store.CreateAsset(context.Background(), &org.Organization{Name: fmt.Sprintf("findtype%d.entity", i)})
 		assert.NoError(t, err)
 	}
 
-	entities, err = store.FindEntitiesByType(oam.Organization, now)
+	entities, err = store.FindEntitiesByType(context.Background(), oam.Organization, now)
 	assert.NoError(t, err)
 
 	if len(entities) < 10 {
@@ -148,16 +154,962 @@ func TestFindEntitiesByType(t *testing.T) {
 }
 
 func TestDeleteEntity(t *testing.T) {
-	entity, err := store.CreateEntity(&types.Entity{
+	entity, err := store.CreateEntity(context.Background(), &types.Entity{
 		Asset: &dns.FQDN{
 			Name: "delete.entity",
 		},
 	})
 	assert.NoError(t, err)
 
-	err = store.DeleteEntity(entity.ID)
+	n, err := store.DeleteEntity(context.Background(), entity.ID)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
 
-	_, err = store.FindEntityById(entity.ID)
+	_, err = store.FindEntityById(context.Background(), entity.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	// deleting the entity again removes nothing
+	n, err = store.DeleteEntity(context.Background(), entity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	found, err := store.FindEntityById(context.Background(), "not-an-id")
+	assert.Nil(t, found)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestDeleteEntityCascade(t *testing.T) {
+	soft := *store
+	soft.softDelete = true
+
+	from, err := soft.CreateAsset(context.Background(), &dns.FQDN{Name: "from.cascade.entity"})
+	assert.NoError(t, err)
+	to, err := soft.CreateAsset(context.Background(), &dns.FQDN{Name: "to.cascade.entity"})
+	assert.NoError(t, err)
+	edge, err := soft.CreateEdge(context.Background(), &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	_, err = soft.CreateEntityProperty(context.Background(), to, &general.SimpleProperty{PropertyName: "cascade", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	_, err = soft.CreateEdgeProperty(context.Background(), edge, &general.SimpleProperty{PropertyName: "cascade", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	// the entity, its tag, the incoming relationship, and the tag of the edge are removed, even in the soft-delete mode
+	n, err := soft.DeleteEntityCascade(context.Background(), to.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	_, err = soft.FindEntityById(context.Background(), to.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	// a tombstone is removed as well
+	_, err = soft.DeleteEntity(context.Background(), from.ID)
+	assert.NoError(t, err)
+	n, err = soft.DeleteEntityCascade(context.Background(), from.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	n, err = soft.DeleteEntityCascade(context.Background(), from.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestDeleteEntitiesByType(t *testing.T) {
+	old := time.Now().AddDate(-10, 0, 0)
+	stale, err := store.CreateEntity(context.Background(), &types.Entity{
+		CreatedAt: old,
+		LastSeen:  old,
+		Asset:     &dns.FQDN{Name: "stale.retention.example.com"},
+	})
+	assert.NoError(t, err)
+	fresh, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "fresh.retention.example.com"})
+	assert.NoError(t, err)
+	edge, err := store.CreateEdge(context.Background(), &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: fresh,
+		ToEntity:   stale,
+	})
+	assert.NoError(t, err)
+	_, err = store.CreateEntityProperty(context.Background(), stale, &general.SimpleProperty{PropertyName: "retention", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	_, err = store.CreateEdgeProperty(context.Background(), edge, &general.SimpleProperty{PropertyName: "retention", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	// only the entities last seen before the date are removed, along with their edges and tags
+	n, err := store.DeleteEntitiesByType(context.Background(), oam.FQDN, time.Now().AddDate(-5, 0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = store.FindEntityById(context.Background(), stale.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.FindEntityById(context.Background(), fresh.ID)
+	assert.NoError(t, err)
+	_, err = store.OutgoingEdges(context.Background(), fresh, time.Time{})
+	assert.Error(t, err)
+
+	_, err = store.FindEdgeTagsByContent(context.Background(), &general.SimpleProperty{PropertyName: "retention", PropertyValue: "bar"}, time.Time{})
+	assert.Error(t, err)
+
+	n, err = store.DeleteEntitiesByType(context.Background(), oam.FQDN, time.Now().AddDate(-5, 0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+	_, err = store.DeleteEntity(context.Background(), fresh.ID)
+	assert.NoError(t, err)
+}
+
+func TestCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := store.CreateAsset(ctx, &dns.FQDN{Name: "canceled.entity"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = store.FindEntitiesByType(ctx, oam.FQDN, time.Time{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCreateEntities(t *testing.T) {
+	existing, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "batch0.entity"})
+	assert.NoError(t, err)
+
+	var inputs []*types.Entity
+	for i := 0; i < 250; i++ {
+		inputs = append(inputs, &types.Entity{Asset: &dns.FQDN{Name: fmt.Sprintf("batch%d.entity", i)}})
+	}
+	// duplicates within the batch should share a single node
+	inputs = append(inputs, &types.Entity{Asset: &dns.FQDN{Name: "batch1.entity"}})
+
+	entities, err := store.CreateEntities(context.Background(), inputs)
+	assert.NoError(t, err)
+	assert.Len(t, entities, len(inputs))
+
+	for i, e := range entities {
+		assert.NotEmpty(t, e.ID)
+		assert.Equal(t, inputs[i].Asset, e.Asset)
+	}
+	assert.Equal(t, existing.ID, entities[0].ID)
+	assert.Equal(t, entities[1].ID, entities[len(entities)-1].ID)
+
+	found, err := store.FindEntityById(context.Background(), entities[100].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "batch100.entity", found.Asset.(*dns.FQDN).Name)
+}
+
+func TestFindEntitiesByTypePaged(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 1; i <= 25; i++ {
+		_, err := store.CreateEntity(context.Background(), &types.Entity{
+			CreatedAt: start.Add(time.Duration(i) * time.Second),
+			LastSeen:  start.Add(time.Duration(i) * time.Second),
+			Asset:     &general.Identifier{UniqueID: fmt.Sprintf("page:%d", i), ID: strconv.Itoa(i), Type: "page"},
+		})
+		assert.NoError(t, err)
+	}
+
+	seen := make(map[string]struct{})
+	var prev time.Time
+	for offset := 0; offset < 25; offset += 10 {
+		entities, total, err := store.FindEntitiesByTypePaged(context.Background(), oam.Identifier, time.Time{}, offset, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(25), total)
+		assert.Len(t, entities, min(10, 25-offset))
+
+		for _, e := range entities {
+			_, found := seen[e.ID]
+			assert.False(t, found)
+			seen[e.ID] = struct{}{}
+			assert.False(t, e.CreatedAt.Before(prev))
+			prev = e.CreatedAt
+		}
+	}
+	assert.Len(t, seen, 25)
+
+	_, total, err := store.FindEntitiesByTypePaged(context.Background(), oam.Identifier, time.Time{}, 25, 10)
+	assert.Error(t, err)
+	assert.Equal(t, int64(25), total)
+
+	_, total, err = store.FindEntitiesByTypePaged(context.Background(), oam.Identifier, start.Add(21*time.Second), 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+
+	_, _, err = store.FindEntitiesByTypePaged(context.Background(), oam.Identifier, time.Time{}, 0, 0)
+	assert.Error(t, err)
+}
+
+func TestIterateEntitiesByType(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 1; i <= 10; i++ {
+		_, err := store.CreateEntity(context.Background(), &types.Entity{
+			CreatedAt: start.Add(time.Duration(i) * time.Second),
+			LastSeen:  start.Add(time.Duration(i) * time.Second),
+			Asset:     &general.Identifier{UniqueID: fmt.Sprintf("iter:%d", i), ID: strconv.Itoa(i), Type: "iter"},
+		})
+		assert.NoError(t, err)
+	}
+
+	iter, err := store.IterateEntitiesByType(context.Background(), oam.Identifier, time.Time{})
+	assert.NoError(t, err)
+
+	var count int
+	var prev time.Time
+	for iter.Next() {
+		e := iter.Entity()
+		assert.False(t, e.CreatedAt.Before(prev))
+		prev = e.CreatedAt
+
+		if id, ok := e.Asset.(*general.Identifier); ok && id.Type == "iter" {
+			count++
+		}
+	}
+	assert.NoError(t, iter.Err())
+	assert.NoError(t, iter.Close())
+	assert.Equal(t, 10, count)
+	assert.False(t, iter.Next())
+
+	// closing before the iteration completes releases the results
+	iter, err = store.IterateEntitiesByType(context.Background(), oam.Identifier, start.Add(5*time.Second))
+	assert.NoError(t, err)
+	assert.True(t, iter.Next())
+	assert.NotNil(t, iter.Entity())
+	assert.NoError(t, iter.Close())
+}
+
+func TestWithTransaction(t *testing.T) {
+	var created *types.Entity
+	err := store.WithTransaction(context.Background(), func(tx types.Repository) error {
+		from, err := tx.CreateAsset(context.Background(), &dns.FQDN{Name: "commit.txn.example.com"})
+		if err != nil {
+			return err
+		}
+
+		to, err := tx.CreateAsset(context.Background(), &dns.FQDN{Name: "target.txn.example.com"})
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.CreateEdge(context.Background(), &types.Edge{
+			Relation:   &general.SimpleRelation{Name: "node"},
+			FromEntity: from,
+			ToEntity:   to,
+		}); err != nil {
+			return err
+		}
+
+		// nested calls reuse the outer transaction
+		return tx.WithTransaction(context.Background(), func(nested types.Repository) error {
+			created, err = nested.CreateAsset(context.Background(), &dns.FQDN{Name: "nested.txn.example.com"})
+			return err
+		})
+	})
+	assert.NoError(t, err)
+
+	_, err = store.FindEntityById(context.Background(), created.ID)
+	assert.NoError(t, err)
+
+	from, err := store.FindEntitiesByContent(context.Background(), &dns.FQDN{Name: "commit.txn.example.com"}, time.Time{})
+	assert.NoError(t, err)
+	edges, err := store.OutgoingEdges(context.Background(), from[0], time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 1)
+
+	rollback := fmt.Errorf("rollback")
+	err = store.WithTransaction(context.Background(), func(tx types.Repository) error {
+		if _, err := tx.CreateAsset(context.Background(), &dns.FQDN{Name: "rollback.txn.example.com"}); err != nil {
+			return err
+		}
+
+		return tx.WithTransaction(context.Background(), func(nested types.Repository) error {
+			if _, err := nested.CreateAsset(context.Background(), &dns.FQDN{Name: "rollback2.txn.example.com"}); err != nil {
+				return err
+			}
+			return rollback
+		})
+	})
+	assert.ErrorIs(t, err, rollback)
+
+	_, err = store.FindEntitiesByContent(context.Background(), &dns.FQDN{Name: "rollback.txn.example.com"}, time.Time{})
 	assert.Error(t, err)
+	_, err = store.FindEntitiesByContent(context.Background(), &dns.FQDN{Name: "rollback2.txn.example.com"}, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestSoftDelete(t *testing.T) {
+	soft := *store
+	soft.softDelete = true
+	start := time.Now().Add(-time.Second)
+
+	from, err := soft.CreateAsset(context.Background(), &dns.FQDN{Name: "from.soft.example.com"})
+	assert.NoError(t, err)
+	to, err := soft.CreateAsset(context.Background(), &dns.FQDN{Name: "to.soft.example.com"})
+	assert.NoError(t, err)
+	_, err = soft.CreateEdge(context.Background(), &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	_, err = soft.DeleteEntity(context.Background(), to.ID)
+	assert.NoError(t, err)
+	_, err = soft.FindEntityById(context.Background(), to.ID)
+	assert.Error(t, err)
+	_, err = soft.FindEntitiesByContent(context.Background(), to.Asset, time.Time{})
+	assert.Error(t, err)
+	_, err = soft.OutgoingEdges(context.Background(), from, time.Time{})
+	assert.Error(t, err)
+
+	deleted, err := soft.FindDeletedEntities(context.Background(), start)
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 1)
+	assert.Equal(t, to.ID, deleted[0].ID)
+	assert.False(t, deleted[0].DeletedAt.IsZero())
+
+	// creating the asset again restores the tombstone
+	restored, err := soft.CreateAsset(context.Background(), to.Asset)
+	assert.NoError(t, err)
+	assert.Equal(t, to.ID, restored.ID)
+	_, err = soft.FindDeletedEntities(context.Background(), start)
+	assert.Error(t, err)
+	edges, err := soft.OutgoingEdges(context.Background(), from, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 1)
+
+	_, err = soft.DeleteEntity(context.Background(), to.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, soft.PurgeDeleted(context.Background(), start))
+	_, err = soft.FindDeletedEntities(context.Background(), start)
+	assert.NoError(t, err)
+
+	assert.NoError(t, soft.PurgeDeleted(context.Background(), time.Now().Add(time.Minute)))
+	_, err = soft.FindDeletedEntities(context.Background(), start)
+	assert.Error(t, err)
+	_, err = store.OutgoingEdges(context.Background(), from, time.Time{})
+	assert.Error(t, err)
+	_, err = store.DeleteEntity(context.Background(), from.ID)
+	assert.NoError(t, err)
+}
+
+func TestUpsertEntity(t *testing.T) {
+	asset := &dns.FQDN{Name: "upsert.example.com"}
+
+	first, result, err := store.UpsertEntity(context.Background(), &types.Entity{Asset: asset})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertCreated, result)
+
+	time.Sleep(100 * time.Millisecond)
+	second, result, err := store.UpsertEntity(context.Background(), &types.Entity{Asset: asset})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUnchanged, result)
+	assert.Equal(t, first.ID, second.ID)
+	assert.True(t, second.LastSeen.After(first.LastSeen))
+
+	entities, err := store.FindEntitiesByContent(context.Background(), asset, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 1)
+	_, err = store.DeleteEntity(context.Background(), first.ID)
+	assert.NoError(t, err)
+
+	// the content of the matched entity is replaced when it differs
+	organization, result, err := store.UpsertEntity(context.Background(), &types.Entity{Asset: &org.Organization{ID: "upsert-org", Name: "Example"}})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertCreated, result)
+	renamed, result, err := store.UpsertEntity(context.Background(), &types.Entity{Asset: &org.Organization{ID: "upsert-org", Name: "Example, Inc."}})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUpdated, result)
+	assert.Equal(t, organization.ID, renamed.ID)
+
+	found, err := store.FindEntityById(context.Background(), organization.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Example, Inc.", found.Asset.(*org.Organization).Name)
+	_, err = store.DeleteEntity(context.Background(), organization.ID)
+	assert.NoError(t, err)
+}
+
+func TestCreateEntityWithID(t *testing.T) {
+	ctx := context.Background()
+	generated := *store
+	generated.newID = func() string { return "generated-" + uuid.NewString() }
+
+	entity, err := generated.CreateEntity(ctx, &types.Entity{Asset: &dns.FQDN{Name: "generated.example.com"}})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(entity.ID, "generated-"))
+
+	// an ID provided by the caller is kept rather than generated
+	id := uuid.NewString()
+	provided, err := store.CreateEntity(ctx, &types.Entity{ID: id, Asset: &dns.FQDN{Name: "provided.example.com"}})
+	assert.NoError(t, err)
+	assert.Equal(t, id, provided.ID)
+
+	updated, err := store.CreateEntity(ctx, &types.Entity{ID: id, Asset: &dns.FQDN{Name: "provided.example.com"}})
+	assert.NoError(t, err)
+	assert.Equal(t, id, updated.ID)
+
+	// the ID is held by another asset
+	_, err = store.CreateEntity(ctx, &types.Entity{ID: id, Asset: &dns.FQDN{Name: "other.example.com"}})
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+
+	// the asset already exists with another ID
+	_, err = store.CreateEntity(ctx, &types.Entity{ID: uuid.NewString(), Asset: &dns.FQDN{Name: "provided.example.com"}})
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+
+	for _, e := range []*types.Entity{entity, provided} {
+		_, err = store.DeleteEntity(ctx, e.ID)
+		assert.NoError(t, err)
+	}
+}
+
+func TestCountMethods(t *testing.T) {
+	entities, err := store.CountEntitiesByType(context.Background(), oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	start := time.Now()
+
+	var created []*types.Entity
+	for i := 0; i < 3; i++ {
+		e, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: fmt.Sprintf("%d.count.example.com", i)})
+		assert.NoError(t, err)
+		created = append(created, e)
+	}
+	_, err = store.CreateEdge(context.Background(), &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: created[0],
+		ToEntity:   created[1],
+	})
+	assert.NoError(t, err)
+
+	total, err := store.CountEntitiesByType(context.Background(), oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, entities+3, total)
+	total, err = store.CountEntitiesByType(context.Background(), oam.FQDN, start)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	total, err = store.CountEdges(context.Background(), start)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	in, out, err := store.EntityDegree(context.Background(), created[0], time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 1}, []int64{in, out})
+	in, out, err = store.EntityDegree(context.Background(), created[1], start)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 0}, []int64{in, out})
+	in, out, err = store.EntityDegree(context.Background(), created[2], time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 0}, []int64{in, out})
+	in, out, err = store.EntityDegree(context.Background(), created[0], time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 0}, []int64{in, out})
+
+	grouped, err := store.CountEntitiesGrouped(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, entities+3, grouped[oam.FQDN])
+	grouped, err = store.CountEntitiesGrouped(context.Background(), start)
+	assert.NoError(t, err)
+	assert.Equal(t, map[oam.AssetType]int64{oam.FQDN: 3}, grouped)
+	grouped, err = store.CountEntitiesGrouped(context.Background(), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, grouped)
+
+	total, err = store.CountEntitiesByType(context.Background(), oam.FQDN, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+	total, err = store.CountEdges(context.Background(), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+
+	for _, e := range created {
+		_, err = store.DeleteEntity(context.Background(), e.ID)
+		assert.NoError(t, err)
+	}
+}
+
+func TestOrphanEntities(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+
+	var created []*types.Entity
+	for _, name := range []string{"a.orphan.example.com", "b.orphan.example.com", "c.orphan.example.com"} {
+		e, err := store.CreateAsset(ctx, &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		created = append(created, e)
+	}
+	defer func() {
+		for _, e := range created {
+			_, _ = store.DeleteEntity(ctx, e.ID)
+		}
+	}()
+	_, err := store.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: created[0],
+		ToEntity:   created[1],
+	})
+	assert.NoError(t, err)
+
+	orphans, err := store.FindOrphanEntities(ctx, oam.FQDN, start)
+	assert.NoError(t, err)
+	if assert.Len(t, orphans, 1) {
+		assert.Equal(t, created[2].ID, orphans[0].ID)
+	}
+	_, err = store.FindOrphanEntities(ctx, oam.FQDN, time.Now().Add(time.Minute))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	// the orphans last seen after the before parameter are kept
+	_, err = store.DeleteOrphanEntities(ctx, oam.FQDN, start)
+	assert.NoError(t, err)
+	_, err = store.FindEntityById(ctx, created[2].ID)
+	assert.NoError(t, err)
+
+	total, err := store.DeleteOrphanEntities(ctx, oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, total, int64(1))
+	_, err = store.FindEntityById(ctx, created[2].ID)
+	assert.Error(t, err)
+	for _, e := range created[:2] {
+		_, err = store.FindEntityById(ctx, e.ID)
+		assert.NoError(t, err)
+	}
+}
+
+func TestSearchEntities(t *testing.T) {
+	var created []*types.Entity
+	for _, name := range []string{"a.search.example.com", "b.search.example.com", "100%_off.search.example.org"} {
+		e, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		created = append(created, e)
+	}
+	defer func() {
+		for _, e := range created {
+			_, _ = store.DeleteEntity(context.Background(), e.ID)
+		}
+	}()
+
+	entities, err := store.SearchEntities(context.Background(), oam.FQDN, ".search.example.com", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 2)
+
+	// the wildcard characters are matched literally
+	entities, err = store.SearchEntities(context.Background(), oam.FQDN, "0%_", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 1)
+	assert.Equal(t, created[2].ID, entities[0].ID)
+
+	_, err = store.SearchEntities(context.Background(), oam.FQDN, "a_search", time.Time{})
+	assert.Error(t, err)
+	_, err = store.SearchEntities(context.Background(), oam.FQDN, "' OR '1'='1", time.Time{})
+	assert.Error(t, err)
+	_, err = store.SearchEntities(context.Background(), oam.FQDN, ".search.example.com", time.Now().Add(time.Minute))
+	assert.Error(t, err)
+	_, err = store.SearchEntities(context.Background(), oam.FQDN, "", time.Time{})
+	assert.Error(t, err)
+}
+
+func TestFindEntitiesByContents(t *testing.T) {
+	ip, _ := netip.ParseAddr("198.51.100.24")
+	assets := []oam.Asset{
+		&dns.FQDN{Name: "batch.contents.example.com"},
+		&oamnet.IPAddress{Address: ip, Type: "IPv4"},
+		&oamnet.AutonomousSystem{Number: 64512},
+	}
+
+	var created []*types.Entity
+	for _, asset := range assets {
+		e, err := store.CreateAsset(context.Background(), asset)
+		assert.NoError(t, err)
+		created = append(created, e)
+	}
+	defer func() {
+		for _, e := range created {
+			_, _ = store.DeleteEntity(context.Background(), e.ID)
+		}
+	}()
+
+	missing := &dns.FQDN{Name: "missing.contents.example.com"}
+	entities, err := store.FindEntitiesByContents(context.Background(), append(assets, missing), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, len(assets))
+	for i, asset := range assets {
+		if e, found := entities[types.ContentHash(asset)]; assert.True(t, found) {
+			assert.Equal(t, created[i].ID, e.ID)
+		}
+	}
+	_, found := entities[types.ContentHash(missing)]
+	assert.False(t, found)
+
+	entities, err = store.FindEntitiesByContents(context.Background(), assets, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, entities)
+
+	entities, err = store.FindEntitiesByContents(context.Background(), nil, time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, entities)
+}
+
+func TestFindEntityByHash(t *testing.T) {
+	entity, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "hash.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(context.Background(), entity.ID) }()
+
+	batch, err := store.CreateEntities(context.Background(), []*types.Entity{
+		{Asset: &dns.FQDN{Name: "batch.hash.example.com"}},
+	})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(context.Background(), batch[0].ID) }()
+
+	upserted, _, err := store.UpsertEntity(context.Background(), &types.Entity{Asset: &dns.FQDN{Name: "upsert.hash.example.com"}})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(context.Background(), upserted.ID) }()
+
+	for _, e := range []*types.Entity{entity, batch[0], upserted} {
+		found, err := store.FindEntityByHash(context.Background(), e.ContentHash())
+		if assert.NoError(t, err) {
+			assert.Equal(t, e.ID, found.ID)
+			assert.Equal(t, e.Asset.Key(), found.Asset.Key())
+		}
+	}
+
+	_, err = store.FindEntityByHash(context.Background(), types.ContentHash(&dns.FQDN{Name: "missing.hash.example.com"}))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.FindEntityByHash(context.Background(), "")
+	assert.Error(t, err)
+}
+
+func TestEntityExists(t *testing.T) {
+	entity, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "exists.example.com"})
+	assert.NoError(t, err)
+
+	found, err := store.EntityExists(context.Background(), &dns.FQDN{Name: "exists.example.com"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = store.EntityExists(context.Background(), &dns.FQDN{Name: "missing.exists.example.com"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = store.EntityExists(context.Background(), nil)
+	assert.Error(t, err)
+
+	// the deleted entity no longer exists
+	_, err = store.DeleteEntity(context.Background(), entity.ID)
+	assert.NoError(t, err)
+	found, err = store.EntityExists(context.Background(), &dns.FQDN{Name: "exists.example.com"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestFindEntitiesByTypes(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2003, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	var ids []string
+	for i, asset := range []oam.Asset{
+		&oamnet.IPAddress{Address: netip.MustParseAddr("203.0.113.201"), Type: "IPv4"},
+		&oamnet.Netblock{CIDR: netip.MustParsePrefix("203.0.113.192/27"), Type: "IPv4"},
+		&oamnet.IPAddress{Address: netip.MustParseAddr("203.0.113.202"), Type: "IPv4"},
+	} {
+		e, err := store.CreateEntity(ctx, &types.Entity{
+			CreatedAt: start.Add(time.Duration(i) * time.Hour),
+			LastSeen:  start.Add(time.Duration(i) * time.Hour),
+			Asset:     asset,
+		})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, e.ID) }()
+		ids = append(ids, e.ID)
+	}
+
+	// the entities of both types are interleaved by their creation time
+	entities, err := store.FindEntitiesByTypes(ctx, start, oam.IPAddress, oam.Netblock)
+	assert.NoError(t, err)
+	var found []string
+	for _, e := range entities {
+		for _, id := range ids {
+			if e.ID == id {
+				found = append(found, id)
+			}
+		}
+	}
+	assert.Equal(t, ids, found)
+
+	_, err = store.FindEntitiesByTypes(ctx, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestFindEntitiesByTypeBetween(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	var ids []string
+	for i, name := range []string{"first.window.example.com", "second.window.example.com"} {
+		e, err := store.CreateEntity(ctx, &types.Entity{
+			LastSeen: start.Add(time.Duration(i) * time.Hour),
+			Asset:    &dns.FQDN{Name: name},
+		})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, e.ID) }()
+		ids = append(ids, e.ID)
+	}
+
+	// the window includes its start and excludes its end
+	entities, err := store.FindEntitiesByTypeBetween(ctx, oam.FQDN, start, start.Add(time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, entities, 1) {
+		assert.Equal(t, ids[0], entities[0].ID)
+	}
+
+	entities, err = store.FindEntitiesByTypeBetween(ctx, oam.FQDN, time.Time{}, start.Add(2*time.Hour))
+	assert.NoError(t, err)
+	var found int
+	for _, e := range entities {
+		if e.ID == ids[0] || e.ID == ids[1] {
+			found++
+		}
+	}
+	assert.Equal(t, 2, found)
+
+	_, err = store.FindEntitiesByTypeBetween(ctx, oam.FQDN, start.Add(2*time.Hour), start.Add(3*time.Hour))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestDiffEntities(t *testing.T) {
+	ctx := context.Background()
+	t1 := time.Date(2002, time.January, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(24 * time.Hour)
+
+	for _, e := range []*types.Entity{
+		{CreatedAt: t1.Add(-time.Hour), LastSeen: t2.Add(time.Hour), Asset: &dns.FQDN{Name: "both.diff.example.com"}},
+		{CreatedAt: t1.Add(time.Hour), LastSeen: t2.Add(time.Hour), Asset: &dns.FQDN{Name: "added.diff.example.com"}},
+		{CreatedAt: t1.Add(-time.Hour), LastSeen: t1.Add(time.Hour), Asset: &dns.FQDN{Name: "removed.diff.example.com"}},
+		{CreatedAt: t1.Add(-2 * time.Hour), LastSeen: t1.Add(-time.Hour), Asset: &dns.FQDN{Name: "old.diff.example.com"}},
+	} {
+		entity, err := store.CreateEntity(ctx, e)
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+	}
+
+	added, removed, err := store.DiffEntities(ctx, oam.FQDN, t1, t2)
+	assert.NoError(t, err)
+	if assert.Len(t, added, 1) {
+		assert.Equal(t, "added.diff.example.com", added[0].Asset.Key())
+	}
+	if assert.Len(t, removed, 1) {
+		assert.Equal(t, "removed.diff.example.com", removed[0].Asset.Key())
+	}
+
+	_, _, err = store.DiffEntities(ctx, oam.FQDN, t2, t1)
+	assert.Error(t, err)
+}
+
+func TestEntityOrder(t *testing.T) {
+	ctx := context.Background()
+	// the entities are created in the order x, y, z, and last seen in the order y, z, x
+	created := time.Date(2004, time.January, 1, 0, 0, 0, 0, time.UTC)
+	seen := map[string]time.Duration{"x": 5 * time.Minute, "y": 3 * time.Minute, "z": 4 * time.Minute}
+	ids := make(map[string]string)
+	for i, name := range []string{"x", "y", "z"} {
+		e, err := store.CreateEntity(ctx, &types.Entity{
+			CreatedAt: created.Add(time.Duration(i) * time.Minute),
+			LastSeen:  created.Add(seen[name]),
+			Asset:     &dns.FQDN{Name: name + ".order.example.com"},
+		})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, e.ID) }()
+		ids[e.ID] = name
+	}
+
+	for _, tc := range []struct {
+		opt      options.Option
+		expected string
+	}{
+		{options.WithOrder(options.OrderByCreatedAt, options.Ascending), "xyz"},
+		{options.WithOrder(options.OrderByCreatedAt, options.Descending), "zyx"},
+		{options.WithOrder(options.OrderByUpdatedAt, options.Ascending), "yzx"},
+		{options.WithOrder(options.OrderByUpdatedAt, options.Descending), "xzy"},
+		{options.WithOrder(options.OrderByType, options.Descending), "zyx"},
+		// the order takes precedence over the last seen order
+		{options.WithOrder(options.OrderByCreatedAt, options.Ascending), "xyz"},
+	} {
+		o := options.Apply(tc.opt, options.WithLastSeenOrder())
+		ordered := *store
+		ordered.lastSeen = o.LastSeenOrder
+		ordered.order = entityOrder(o)
+		found, err := ordered.FindEntitiesByTypeBetween(ctx, oam.FQDN, created, created.Add(time.Hour))
+		assert.NoError(t, err)
+
+		var order string
+		for _, e := range found {
+			order += ids[e.ID]
+		}
+		assert.Equal(t, tc.expected, order)
+	}
+}
+
+func TestTouchEntity(t *testing.T) {
+	ctx := context.Background()
+	ordered := *store
+	ordered.lastSeen = true
+
+	seen := time.Date(2003, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var entities []*types.Entity
+	for i, name := range []string{"first.touch.example.com", "second.touch.example.com"} {
+		e, err := store.CreateEntity(ctx, &types.Entity{
+			LastSeen: seen.Add(time.Duration(i) * time.Minute),
+			Asset:    &dns.FQDN{Name: name},
+		})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, e.ID) }()
+		entities = append(entities, e)
+	}
+
+	// the most recently seen entities are returned first
+	found, err := ordered.FindEntitiesByTypeBetween(ctx, oam.FQDN, seen, seen.Add(time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, found, 2) {
+		assert.Equal(t, entities[1].ID, found[0].ID)
+	}
+
+	start := time.Now().Add(-time.Second)
+	assert.NoError(t, store.TouchEntity(ctx, entities[0].ID))
+	touched, err := store.FindEntityById(ctx, entities[0].ID)
+	assert.NoError(t, err)
+	assert.False(t, touched.LastSeen.Before(start))
+
+	found, err = ordered.FindEntitiesByType(ctx, oam.FQDN, start)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, found) {
+		assert.Equal(t, entities[0].ID, found[0].ID)
+	}
+
+	assert.ErrorIs(t, store.TouchEntity(ctx, "999999999"), types.ErrNotFound)
+}
+
+func TestUpdateEntity(t *testing.T) {
+	ctx := context.Background()
+	soft := *store
+	soft.softDelete = true
+
+	var entities []*types.Entity
+	for _, name := range []string{"from.update.example.com", "to.update.example.com", "deleted.update.example.com"} {
+		e, err := soft.CreateAsset(ctx, &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntityCascade(ctx, e.ID) }()
+		entities = append(entities, e)
+	}
+	from, to, deleted := entities[0], entities[1], entities[2]
+	_, err := soft.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	_, err = soft.DeleteEntity(ctx, deleted.ID)
+	assert.NoError(t, err)
+
+	// the ID, the creation time, and the edges are kept
+	updated, err := soft.UpdateEntity(ctx, to.ID, &dns.FQDN{Name: "docs.update.example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, to.ID, updated.ID)
+	assert.WithinDuration(t, to.CreatedAt, updated.CreatedAt, time.Second)
+	assert.False(t, updated.LastSeen.Before(to.LastSeen))
+
+	found, err := soft.FindEntityByHash(ctx, types.ContentHash(&dns.FQDN{Name: "docs.update.example.com"}))
+	assert.NoError(t, err)
+	assert.Equal(t, to.ID, found.ID)
+	assert.Equal(t, "docs.update.example.com", found.Asset.Key())
+	_, err = soft.FindEntityByHash(ctx, to.ContentHash())
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	edges, err := soft.OutgoingEdges(ctx, from, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, edges, 1) {
+		assert.Equal(t, to.ID, edges[0].ToEntity.ID)
+	}
+
+	// the asset of another entity, including a soft-deleted one, is rejected
+	_, err = soft.UpdateEntity(ctx, to.ID, from.Asset)
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+	_, err = soft.UpdateEntity(ctx, to.ID, deleted.Asset)
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+
+	_, err = soft.UpdateEntity(ctx, to.ID, &general.Identifier{UniqueID: "id:update", ID: "update", Type: "id"})
+	assert.Error(t, err)
+	_, err = soft.UpdateEntity(ctx, deleted.ID, &dns.FQDN{Name: "restored.update.example.com"})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+	_, err = soft.UpdateEntity(ctx, "999999999", &dns.FQDN{Name: "missing.update.example.com"})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+}
+
+func TestQueryInjection(t *testing.T) {
+	ctx := context.Background()
+
+	// the asset content is passed as a query parameter, so it is treated as data
+	name := "inj`ect'}) DETACH DELETE a //}.example.com"
+	entity, err := store.CreateEntity(ctx, &types.Entity{Asset: &dns.FQDN{Name: name}})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+
+	found, err := store.FindEntitiesByContent(ctx, &dns.FQDN{Name: name}, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, entity.ID, found[0].ID)
+		assert.Equal(t, name, found[0].Asset.(*dns.FQDN).Name)
+	}
+
+	// the labels cannot be parameterized, so those outside the allowlist are rejected
+	_, err = store.FindEdgesByLabel(ctx, "dns_record`]->() DETACH DELETE r //", time.Time{})
+	assert.Error(t, err)
+	_, err = store.FindEntitiesByType(ctx, oam.AssetType("FQDN) DETACH DELETE a //"), time.Time{})
+	assert.Error(t, err)
+}
+
+func TestRawCypher(t *testing.T) {
+	ctx := context.Background()
+	entity, err := store.CreateAsset(ctx, &dns.FQDN{Name: "raw.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+
+	var q types.CypherQuerier = store
+	rows, err := q.RawCypher(ctx, "MATCH (a:Entity {entity_id: $eid}) RETURN a.entity_id AS id, a.etype AS etype",
+		map[string]interface{}{"eid": entity.ID})
+	assert.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	assert.Equal(t, []string{"id", "etype"}, rows.Columns())
+
+	var count int
+	for rows.Next() {
+		count++
+		assert.Equal(t, []interface{}{entity.ID, string(oam.FQDN)}, rows.Values())
+	}
+	assert.NoError(t, rows.Err())
+	assert.Equal(t, 1, count)
+
+	_, err = store.RawCypher(ctx, "MATCH (a:Entity RETURN a", nil)
+	assert.Error(t, err)
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	before, err := store.Stats(ctx)
+	assert.NoError(t, err)
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "from.stats.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, from.ID) }()
+	to, err := store.CreateAsset(ctx, &dns.FQDN{Name: "to.stats.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, to.ID) }()
+
+	edge, err := store.CreateEdge(ctx, &types.Edge{Relation: &general.SimpleRelation{Name: "node"}, FromEntity: from, ToEntity: to})
+	assert.NoError(t, err)
+	_, err = store.CreateEntityProperty(ctx, from, &general.SimpleProperty{PropertyName: "stats", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	_, err = store.CreateEdgeProperty(ctx, edge, &general.SimpleProperty{PropertyName: "stats", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	after, err := store.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, before.EntitiesByType[oam.FQDN]+2, after.EntitiesByType[oam.FQDN])
+	assert.Equal(t, before.EdgesByLabel["node"]+1, after.EdgesByLabel["node"])
+	assert.Equal(t, before.EntityTags+1, after.EntityTags)
+	assert.Equal(t, before.EdgeTags+1, after.EdgeTags)
+	assert.False(t, after.OldestCreatedAt.IsZero())
+	assert.False(t, after.NewestLastSeen.Before(to.LastSeen.Truncate(time.Second)))
 }
@@ -0,0 +1,78 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/garthoid/asset-db/types"
+	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// poolTimeoutPrefix starts the message of the error returned by the driver when no connection became free within the acquisition timeout.
+const poolTimeoutPrefix = "Timeout while waiting for connection"
+
+// translateError wraps the error returned by the Neo4j driver in a *types.Error, when it can be classified,
+// so that errors.Is works the same as with the SQL databases. The original error remains available to
+// errors.Is and errors.As.
+func translateError(err error) error {
+	var classified *types.Error
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+
+	if kind := errorKind(err); kind != nil {
+		return &types.Error{Kind: kind, Err: err}
+	}
+	return err
+}
+
+// errorKind returns the sentinel error that classifies the error, or nil if the error is not recognized.
+func errorKind(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+
+	var neoErr *neo4jdb.Neo4jError
+	if errors.As(err, &neoErr) {
+		if neoErr.Code != "Neo.ClientError.Schema.ConstraintValidationFailed" {
+			return nil
+		}
+		// the uniqueness constraints report the node that already exists
+		if strings.Contains(neoErr.Msg, "already exists") {
+			return types.ErrDuplicate
+		}
+		return types.ErrConstraint
+	}
+
+	var connErr *neo4jdb.ConnectivityError
+	// the driver does not export the error of a pool timeout, which it reports within a connectivity error
+	if errors.As(err, &connErr) && connErr.Inner != nil && strings.HasPrefix(connErr.Inner.Error(), poolTimeoutPrefix) {
+		return types.ErrPoolTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &connErr) || errors.As(err, &netErr) {
+		return types.ErrConnection
+	}
+	return nil
+}
+
+// isRetryable reports whether the driver considers the error transient. The driver does not
+// unwrap errors, so the classification is performed on the original error.
+func isRetryable(err error) bool {
+	var classified *types.Error
+	if errors.As(err, &classified) {
+		// the acquisition timeout bounds the wait of the call, so it is not extended by the retries
+		if classified.Kind == types.ErrPoolTimeout {
+			return false
+		}
+		err = classified.Err
+	}
+	return neo4jdb.IsRetryable(err)
+}
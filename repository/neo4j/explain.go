@@ -0,0 +1,71 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/garthoid/asset-db/internal/explain"
+	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// capturedQuery is a query issued during the dry run of Explain, along with its parameters.
+type capturedQuery struct {
+	query  string
+	params map[string]interface{}
+}
+
+// Explain returns the plans of the queries issued by the read method of the repository named by method, called with
+// the args that follow the context, such as Explain(ctx, "FindEntitiesByType", oam.FQDN, time.Time{}). The method is
+// called in a dry run, where its queries are collected but not executed, and each query is then run with PROFILE, so
+// the plan reports the rows and database hits of each operator. Since the dry run returns no records, the queries a
+// method only issues after reading a record are not included. Each plan follows the text of its query.
+// Returns an error if the method is not a read method of the Repository, or the args do not match its parameters.
+func (neo *neoRepository) Explain(ctx context.Context, method string, args ...interface{}) (string, error) {
+	var queries []capturedQuery
+	dryrun := *neo
+	dryrun.captured = &queries
+
+	// the error of the method is expected once a query is collected, since the dry run returns no records
+	err := explain.Call(ctx, &dryrun, method, args...)
+	if len(queries) == 0 {
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%s did not issue a query", method)
+	}
+
+	var b strings.Builder
+	for i, q := range queries {
+		result, err := neo.execute(ctx, neo4jdb.AccessModeRead, "PROFILE "+q.query, q.params)
+		if err != nil {
+			return "", err
+		}
+
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(q.query + "\n")
+		if result.Summary != nil && result.Summary.Profile() != nil {
+			writePlan(&b, result.Summary.Profile(), 0)
+		}
+	}
+	return b.String(), nil
+}
+
+// writePlan writes a line for each operator of the profiled plan, with the children indented below their parent.
+func writePlan(b *strings.Builder, plan neo4jdb.ProfiledPlan, depth int) {
+	line := fmt.Sprintf("%s%s (rows=%d, db hits=%d)", strings.Repeat("  ", depth), plan.Operator(), plan.Records(), plan.DbHits())
+	if details, ok := plan.Arguments()["Details"].(string); ok && details != "" {
+		line += " " + details
+	}
+	b.WriteString(line + "\n")
+
+	for _, child := range plan.Children() {
+		writePlan(b, child, depth+1)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build integration
+
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+
+	entity, err := store.CreateAsset(ctx, &dns.FQDN{Name: "explain.owasp.org"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+
+	plan, err := store.Explain(ctx, "FindEntitiesByType", oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.Contains(t, plan, "ProduceResults")
+	assert.Contains(t, plan, "db hits=")
+	assert.Greater(t, len(strings.Split(strings.TrimSpace(plan), "\n")), 1)
+
+	plan, err = store.Explain(ctx, "OutgoingEdges", entity, time.Time{}, "dns_record")
+	assert.NoError(t, err)
+	assert.Contains(t, plan, "ProduceResults")
+
+	_, err = store.Explain(ctx, "DeleteEntity", entity.ID)
+	assert.Error(t, err)
+	_, err = store.Explain(ctx, "FindEntityById", 42)
+	assert.Error(t, err)
+
+	// the dry run does not affect the repository
+	found, err := store.FindEntityById(ctx, entity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ID, found.ID)
+
+	var _ types.Explainer = store
+}
@@ -0,0 +1,177 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/garthoid/asset-db/internal/transfer"
+	"github.com/garthoid/asset-db/types"
+	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// ExportJSON writes the entities, edges, and tags in the database to w as newline-delimited JSON,
+// with one types.ExportRecord per line. The entities are written first, followed by the edges, the
+// entity tags, and the edge tags, so each record only refers to records that precede it.
+// Soft-deleted entities, along with the edges and tags attached to them, are not exported.
+// The records are streamed from a single read transaction, which is bound by the provided context
+// rather than the per-query timeout used by other calls.
+func (neo *neoRepository) ExportJSON(ctx context.Context, w io.Writer) error {
+	start := time.Now()
+
+	var runner queryRunner = neo.tx
+	if neo.tx == nil {
+		session := neo.db.NewSession(ctx, neo.sessionConfig(neo4jdb.AccessModeRead))
+		defer func() { _ = session.Close(context.Background()) }()
+
+		tx, err := session.BeginTransaction(ctx)
+		if err != nil {
+			return translateError(err)
+		}
+		defer func() { _ = tx.Close(context.Background()) }()
+		runner = tx
+	}
+
+	rows, err := exportQueries(ctx, runner, json.NewEncoder(w))
+	neo.logOperation("ExportJSON", start, "", rows, err)
+	return translateError(err)
+}
+
+// ExportSubgraph writes the root entity, and the entities and edges reached from it within maxDepth outgoing edges,
+// to w as newline-delimited JSON in the format of ExportJSON, along with the tags of those entities and edges.
+// The subgraph is traversed with Neighborhood, so the output only holds the records connected to the root.
+func (neo *neoRepository) ExportSubgraph(ctx context.Context, root *types.Entity, maxDepth int, w io.Writer) error {
+	return transfer.ExportSubgraph(ctx, neo, root, maxDepth, w)
+}
+
+// ImportJSON reads the newline-delimited JSON written by ExportJSON and recreates the records in the database.
+// The records that match existing entities, edges, or tags are skipped, so a repeated import creates nothing.
+// Returns the number of records created and skipped by kind, or an error if a record cannot be imported.
+func (neo *neoRepository) ImportJSON(ctx context.Context, r io.Reader) (types.ImportStats, error) {
+	return transfer.ImportJSON(ctx, neo, r)
+}
+
+// exportQueries runs the query of each record kind in order, and writes the records to the encoder.
+// Returns the number of records written.
+func exportQueries(ctx context.Context, runner queryRunner, enc *json.Encoder) (int, error) {
+	queries := []struct {
+		query  string
+		record func(record *neo4jdb.Record) (*types.ExportRecord, error)
+	}{
+		{
+			query: "MATCH (a:Entity) RETURN a ORDER BY a.created_at, a.entity_id",
+			record: func(record *neo4jdb.Record) (*types.ExportRecord, error) {
+				node, err := recordNode(record, "a")
+				if err != nil {
+					return nil, err
+				}
+
+				entity, err := nodeToEntity(node)
+				if err != nil {
+					return nil, err
+				}
+				return types.EntityRecord(entity)
+			},
+		},
+		{
+			query: "MATCH (from:Entity)-[r]->(to:Entity) RETURN r, from.entity_id AS fid, to.entity_id AS tid ORDER BY r.created_at, elementId(r)",
+			record: func(record *neo4jdb.Record) (*types.ExportRecord, error) {
+				rel, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Relationship](record, "r")
+				if err != nil {
+					return nil, err
+				}
+				if isnil {
+					return nil, errors.New("the record value for the relationship is nil")
+				}
+
+				fid, _, err := neo4jdb.GetRecordValue[string](record, "fid")
+				if err != nil {
+					return nil, err
+				}
+				tid, _, err := neo4jdb.GetRecordValue[string](record, "tid")
+				if err != nil {
+					return nil, err
+				}
+
+				edge, err := relationshipToEdge(rel)
+				if err != nil {
+					return nil, err
+				}
+				edge.FromEntity = &types.Entity{ID: fid}
+				edge.ToEntity = &types.Entity{ID: tid}
+				return types.EdgeRecord(edge)
+			},
+		},
+		{
+			query: "MATCH (p:EntityTag) MATCH (:Entity {entity_id: p.entity_id}) RETURN p ORDER BY p.created_at, p.tag_id",
+			record: func(record *neo4jdb.Record) (*types.ExportRecord, error) {
+				node, err := recordNode(record, "p")
+				if err != nil {
+					return nil, err
+				}
+
+				tag, err := nodeToEntityTag(node)
+				if err != nil {
+					return nil, err
+				}
+				return types.EntityTagRecord(tag)
+			},
+		},
+		{
+			query: "MATCH (:Entity)-[r]->(:Entity) MATCH (p:EdgeTag {edge_id: elementId(r)}) RETURN p ORDER BY p.created_at, p.tag_id",
+			record: func(record *neo4jdb.Record) (*types.ExportRecord, error) {
+				node, err := recordNode(record, "p")
+				if err != nil {
+					return nil, err
+				}
+
+				tag, err := nodeToEdgeTag(node)
+				if err != nil {
+					return nil, err
+				}
+				return types.EdgeTagRecord(tag)
+			},
+		},
+	}
+
+	var rows int
+	for _, q := range queries {
+		result, err := runner.Run(ctx, q.query, nil)
+		if err != nil {
+			return rows, err
+		}
+
+		for result.Next(ctx) {
+			r, err := q.record(result.Record())
+			if err != nil {
+				return rows, err
+			}
+			if err := enc.Encode(r); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+		if err := result.Err(); err != nil {
+			return rows, err
+		}
+	}
+	return rows, nil
+}
+
+// recordNode returns the node of the record with the provided key.
+func recordNode(record *neo4jdb.Record, key string) (neo4jdb.Node, error) {
+	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](record, key)
+	if err != nil {
+		return node, err
+	}
+	if isnil {
+		return node, errors.New("the record value for the node is nil")
+	}
+	return node, nil
+}
@@ -0,0 +1,146 @@
+//go:build integration
+
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportJSON(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "export.owasp.org"})
+	assert.NoError(t, err)
+	to, err := store.CreateAsset(ctx, &dns.FQDN{Name: "www.export.owasp.org"})
+	assert.NoError(t, err)
+
+	edge, err := store.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	etag, err := store.CreateEntityProperty(ctx, from, &general.SimpleProperty{PropertyName: "export", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	edgetag, err := store.CreateEdgeProperty(ctx, edge, &general.SimpleProperty{PropertyName: "export", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, store.ExportJSON(ctx, &buf))
+
+	// the records of each kind follow those of the kinds they refer to
+	order := map[string]int{
+		types.EntityRecordKind:    0,
+		types.EdgeRecordKind:      1,
+		types.EntityTagRecordKind: 2,
+		types.EdgeTagRecordKind:   3,
+	}
+	last := 0
+	found := make(map[string]*types.ExportRecord)
+
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var r types.ExportRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		assert.GreaterOrEqual(t, order[r.Kind], last)
+		last = order[r.Kind]
+		found[r.Kind+":"+r.ID] = &r
+	}
+	assert.NoError(t, scanner.Err())
+
+	if r, ok := found[types.EntityRecordKind+":"+from.ID]; assert.True(t, ok) {
+		assert.Equal(t, "FQDN", r.Type)
+		assert.JSONEq(t, `{"name":"export.owasp.org"}`, string(r.Content))
+		assert.WithinDuration(t, from.CreatedAt, r.CreatedAt, time.Second)
+		assert.WithinDuration(t, from.LastSeen, r.LastSeen, time.Second)
+	}
+	if r, ok := found[types.EdgeRecordKind+":"+edge.ID]; assert.True(t, ok) {
+		assert.Equal(t, "SimpleRelation", r.Type)
+		assert.Equal(t, from.ID, r.FromEntityID)
+		assert.Equal(t, to.ID, r.ToEntityID)
+	}
+	if r, ok := found[types.EntityTagRecordKind+":"+etag.ID]; assert.True(t, ok) {
+		assert.Equal(t, from.ID, r.EntityID)
+	}
+	if r, ok := found[types.EdgeTagRecordKind+":"+edgetag.ID]; assert.True(t, ok) {
+		assert.Equal(t, edge.ID, r.EdgeID)
+	}
+}
+
+func TestImportJSON(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "import.owasp.org"})
+	assert.NoError(t, err)
+	_, err = store.CreateEntityProperty(ctx, from, &general.SimpleProperty{PropertyName: "import", PropertyValue: "foo"})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, store.ExportJSON(ctx, &buf))
+
+	// the exported records already exist, so the import of the export does not create any
+	stats, err := store.ImportJSON(ctx, &buf)
+	assert.NoError(t, err)
+	assert.Zero(t, stats.EntitiesCreated+stats.EdgesCreated+stats.EntityTagsCreated+stats.EdgeTagsCreated)
+	assert.GreaterOrEqual(t, stats.EntitiesSkipped, 1)
+	assert.GreaterOrEqual(t, stats.EntityTagsSkipped, 1)
+}
+
+func TestExportSubgraph(t *testing.T) {
+	ctx := context.Background()
+
+	root, err := store.CreateAsset(ctx, &dns.FQDN{Name: "subgraph.owasp.org"})
+	assert.NoError(t, err)
+	child, err := store.CreateAsset(ctx, &dns.FQDN{Name: "www.subgraph.owasp.org"})
+	assert.NoError(t, err)
+	other, err := store.CreateAsset(ctx, &dns.FQDN{Name: "other.subgraph.owasp.org"})
+	assert.NoError(t, err)
+
+	edge, err := store.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: root,
+		ToEntity:   child,
+	})
+	assert.NoError(t, err)
+
+	etag, err := store.CreateEntityProperty(ctx, child, &general.SimpleProperty{PropertyName: "subgraph", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	edgetag, err := store.CreateEdgeProperty(ctx, edge, &general.SimpleProperty{PropertyName: "subgraph", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, store.ExportSubgraph(ctx, root, 1, &buf))
+
+	found := make(map[string]*types.ExportRecord)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var r types.ExportRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		found[r.Kind+":"+r.ID] = &r
+	}
+	assert.NoError(t, scanner.Err())
+
+	assert.Contains(t, found, types.EntityRecordKind+":"+root.ID)
+	assert.Contains(t, found, types.EntityRecordKind+":"+child.ID)
+	assert.NotContains(t, found, types.EntityRecordKind+":"+other.ID)
+	assert.Contains(t, found, types.EdgeRecordKind+":"+edge.ID)
+	assert.Contains(t, found, types.EntityTagRecordKind+":"+etag.ID)
+	assert.Contains(t, found, types.EdgeTagRecordKind+":"+edgetag.ID)
+
+	assert.Error(t, store.ExportSubgraph(ctx, root, 0, &buf))
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/netip"
+	"time"
 
 	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/garthoid/asset-db/types"
@@ -103,10 +104,17 @@ func nodeToEntity(node neo4jdb.Node) (*types.Entity, error) {
 		return nil, errors.New("asset type not supported")
 	}
 
+	// the deleted_at property is only present on soft-deleted entities
+	var deleted time.Time
+	if t, err := neo4jdb.GetProperty[neo4jdb.LocalDateTime](node, "deleted_at"); err == nil {
+		deleted = neo4jTimeToTime(t)
+	}
+
 	return &types.Entity{
 		ID:        id,
 		CreatedAt: created,
 		LastSeen:  updated,
+		DeletedAt: deleted,
 		Asset:     asset,
 	}, nil
 }
@@ -5,6 +5,8 @@
 package neo4j
 
 import (
+	"time"
+
 	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/garthoid/asset-db/types"
 	oam "github.com/owasp-amass/open-asset-model"
@@ -32,6 +34,7 @@ func nodeToEntityTag(node neo4jdb.Node) (*types.EntityTag, error) {
 		return nil, err
 	}
 	updated := neo4jTimeToTime(t)
+	expires := nodeExpiresAt(node)
 
 	ttype, err := neo4jdb.GetProperty[string](node, "ttype")
 	if err != nil {
@@ -48,6 +51,7 @@ func nodeToEntityTag(node neo4jdb.Node) (*types.EntityTag, error) {
 		ID:        id,
 		CreatedAt: created,
 		LastSeen:  updated,
+		ExpiresAt: expires,
 		Property:  prop,
 		Entity:    &types.Entity{ID: eid},
 	}, nil
@@ -75,6 +79,7 @@ func nodeToEdgeTag(node neo4jdb.Node) (*types.EdgeTag, error) {
 		return nil, err
 	}
 	updated := neo4jTimeToTime(t)
+	expires := nodeExpiresAt(node)
 
 	ttype, err := neo4jdb.GetProperty[string](node, "ttype")
 	if err != nil {
@@ -91,7 +96,16 @@ func nodeToEdgeTag(node neo4jdb.Node) (*types.EdgeTag, error) {
 		ID:        id,
 		CreatedAt: created,
 		LastSeen:  updated,
+		ExpiresAt: expires,
 		Property:  prop,
 		Edge:      &types.Edge{ID: eid},
 	}, nil
 }
+
+// nodeExpiresAt returns the expiration time of the tag node, or a zero time when the tag does not expire.
+func nodeExpiresAt(node neo4jdb.Node) time.Time {
+	if t, ok := node.Props["expires_at"].(neo4jdb.LocalDateTime); ok {
+		return neo4jTimeToTime(t)
+	}
+	return time.Time{}
+}
@@ -0,0 +1,152 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/garthoid/asset-db/internal/track"
+	"github.com/garthoid/asset-db/types"
+	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// entityIterator implements types.EntityIterator by pulling one record at a time from a streaming result.
+type entityIterator struct {
+	ctx      context.Context
+	neo      *neoRepository
+	query    string
+	start    time.Time
+	rows     int
+	session  neo4jdb.SessionWithContext
+	result   neo4jdb.ResultWithContext
+	current  *types.Entity
+	err      error
+	open     *track.Set
+	once     sync.Once
+	closeErr error
+}
+
+// IterateEntitiesByType returns an iterator over the entities in the database of the provided asset type
+// and last seen after the since parameter. The entities are ordered by creation time and then by ID.
+// If since.IsZero(), the parameter will be ignored.
+// The iteration is bound by the provided context rather than the per-query timeout used by other calls,
+// and the session remains open until the iterator is closed.
+// Within a transaction, the iterator must be closed before other calls are made on the scoped repository.
+// An iterator left open outside of a transaction is closed by the Close method of the repository.
+func (neo *neoRepository) IterateEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (types.EntityIterator, error) {
+	if err := checkLabel(string(atype)); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("MATCH (a:%s) RETURN a ORDER BY a.created_at, a.entity_id", string(atype))
+	if !since.IsZero() {
+		query = fmt.Sprintf("MATCH (a:%s) WHERE a.updated_at >= localDateTime('%s') RETURN a ORDER BY a.created_at, a.entity_id", string(atype), timeToNeo4jTime(since))
+	}
+
+	start := time.Now()
+	if neo.tx != nil {
+		result, err := neo.tx.Run(ctx, query, nil)
+		if err != nil {
+			neo.logOperation("IterateEntitiesByType", start, query, 0, err)
+			return nil, err
+		}
+		return &entityIterator{ctx: ctx, neo: neo, query: query, start: start, result: result}, nil
+	}
+
+	session := neo.db.NewSession(ctx, neo.sessionConfig(neo4jdb.AccessModeRead))
+
+	result, err := session.Run(ctx, query, nil)
+	if err != nil {
+		_ = session.Close(ctx)
+		neo.logOperation("IterateEntitiesByType", start, query, 0, err)
+		return nil, err
+	}
+
+	it := &entityIterator{
+		ctx:     ctx,
+		neo:     neo,
+		query:   query,
+		start:   start,
+		session: session,
+		result:  result,
+		open:    neo.open,
+	}
+	neo.open.Add(it)
+	return it, nil
+}
+
+// Next implements the types.EntityIterator interface.
+func (it *entityIterator) Next() bool {
+	it.current = nil
+	if it.err != nil {
+		return false
+	}
+
+	if !it.result.Next(it.ctx) {
+		it.err = it.result.Err()
+		return false
+	}
+
+	node, isnil, err := neo4jdb.GetRecordValue[neo4jdb.Node](it.result.Record(), "a")
+	if err != nil {
+		it.err = err
+		return false
+	}
+	if isnil {
+		it.err = errors.New("the record value for the node is nil")
+		return false
+	}
+
+	e, err := nodeToEntity(node)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	it.rows++
+	it.current = e
+	return true
+}
+
+// Entity implements the types.EntityIterator interface.
+func (it *entityIterator) Entity() *types.Entity {
+	return it.current
+}
+
+// Err implements the types.EntityIterator interface.
+func (it *entityIterator) Err() error {
+	return it.err
+}
+
+// Close implements the types.EntityIterator interface.
+// The iteration is logged as a single operation, which spans from the creation of the iterator.
+// The iterator may be closed more than once, including by the Close method of the repository.
+func (it *entityIterator) Close() error {
+	it.once.Do(func() {
+		it.current = nil
+		_, _ = it.result.Consume(it.ctx)
+		it.neo.logOperation("IterateEntitiesByType", it.start, it.query, it.rows, it.err)
+		// iterators created within a transaction do not own a session
+		if it.session != nil {
+			it.closeErr = it.session.Close(context.Background())
+		}
+		it.open.Remove(it)
+	})
+	return it.closeErr
+}
+
+// closeIterators closes the iterators that were left open, and returns an error that reports how many there were.
+func closeIterators(open *track.Set) error {
+	n, err := open.CloseAll()
+	if n == 0 {
+		return err
+	}
+	return errors.Join(fmt.Errorf("%d iterators were left open, and were closed along with the repository", n), err)
+}
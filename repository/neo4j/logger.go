@@ -0,0 +1,40 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"time"
+
+	"github.com/garthoid/asset-db/internal/logging"
+)
+
+// logOperation emits a record for the operation that started at the provided time.
+// When the method is empty, it is taken from the repository method found on the call stack.
+// The query field is omitted for operations that consist of several statements.
+// Nothing is logged when no logger was provided in the options.
+func (neo *neoRepository) logOperation(method string, start time.Time, query string, rows int, err error) {
+	if neo.log == nil {
+		return
+	}
+
+	if method == "" {
+		method = logging.Operation("(*neoRepository).")
+	}
+	fields := map[string]interface{}{
+		"method":   method,
+		"duration": time.Since(start),
+		"rows":     rows,
+	}
+	if query != "" {
+		fields["query"] = query
+	}
+
+	if err != nil {
+		fields["error"] = err
+		neo.log.Error("query failed", fields)
+		return
+	}
+	neo.log.Debug("query executed", fields)
+}
@@ -5,6 +5,8 @@
 package neo4j
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j/dbtype"
@@ -21,3 +23,30 @@ func timeToNeo4jTime(t time.Time) dbtype.LocalDateTime {
 	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
 	return dbtype.LocalDateTime(t)
 }
+
+// seenBetween returns the WHERE clause that limits the variable to the records last seen within the [from, to) window.
+// A zero from or to leaves that side of the window open, and the clause is empty when both are zero.
+func seenBetween(v string, from, to time.Time) string {
+	var conds []string
+	if !from.IsZero() {
+		conds = append(conds, fmt.Sprintf("%s.updated_at >= localDateTime('%s')", v, timeToNeo4jTime(from)))
+	}
+	if !to.IsZero() {
+		conds = append(conds, fmt.Sprintf("%s.updated_at < localDateTime('%s')", v, timeToNeo4jTime(to)))
+	}
+
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// unexpired adds the condition that limits the variable to the tags that do not expire, or have not expired by now,
+// to the WHERE clause, which may be empty.
+func unexpired(where, v string) string {
+	cond := fmt.Sprintf("(%s.expires_at IS NULL OR %s.expires_at > localDateTime('%s'))", v, v, timeToNeo4jTime(time.Now()))
+	if where == "" {
+		return " WHERE " + cond
+	}
+	return where + " AND " + cond
+}
@@ -40,6 +40,7 @@ func entityPropsMap(entity *types.Entity) (map[string]interface{}, error) {
 	m["entity_id"] = entity.ID
 	m["created_at"] = timeToNeo4jTime(entity.CreatedAt)
 	m["updated_at"] = timeToNeo4jTime(entity.LastSeen)
+	m["content_hash"] = types.ContentHash(entity.Asset)
 
 	switch v := entity.Asset.(type) {
 	case *account.Account:
@@ -212,59 +213,65 @@ func entityPropsMap(entity *types.Entity) (map[string]interface{}, error) {
 	return m, nil
 }
 
-func queryNodeByAssetKey(varname string, asset oam.Asset) (string, error) {
+// queryNodeByAssetKey returns the pattern that matches the node of the asset by its identifying property, along
+// with the parameters that hold the value of the property, so the asset content is not written into the query.
+func queryNodeByAssetKey(varname string, asset oam.Asset) (string, map[string]interface{}, error) {
+	atype, prop, value, err := assetKeyProperty(asset)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("(%s:%s {%s: $key})", varname, atype, prop), map[string]interface{}{"key": value}, nil
+}
+
+// assetKeyProperty returns the label of the asset node, along with the name and value of the property that identifies it.
+func assetKeyProperty(asset oam.Asset) (oam.AssetType, string, interface{}, error) {
 	if asset == nil {
-		return "", errors.New("the asset is nil")
+		return "", "", nil, errors.New("the asset is nil")
 	}
 
-	var node string
 	switch v := asset.(type) {
 	case *account.Account:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.Account, "unique_id", v.ID)
+		return oam.Account, "unique_id", v.ID, nil
 	case *oamreg.AutnumRecord:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.AutnumRecord, "handle", v.Handle)
+		return oam.AutnumRecord, "handle", v.Handle, nil
 	case *oamnet.AutonomousSystem:
-		node = fmt.Sprintf("(%s:%s {%s: %d})", varname, oam.AutonomousSystem, "number", v.Number)
+		return oam.AutonomousSystem, "number", int64(v.Number), nil
 	case *contact.ContactRecord:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.ContactRecord, "discovered_at", v.DiscoveredAt)
+		return oam.ContactRecord, "discovered_at", v.DiscoveredAt, nil
 	case *oamreg.DomainRecord:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.DomainRecord, "domain", v.Domain)
+		return oam.DomainRecord, "domain", v.Domain, nil
 	case *file.File:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.File, "url", v.URL)
+		return oam.File, "url", v.URL, nil
 	case *dns.FQDN:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.FQDN, "name", v.Name)
+		return oam.FQDN, "name", v.Name, nil
 	case *financial.FundsTransfer:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.FundsTransfer, "unique_id", v.ID)
+		return oam.FundsTransfer, "unique_id", v.ID, nil
 	case *general.Identifier:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.Identifier, "unique_id", v.UniqueID)
+		return oam.Identifier, "unique_id", v.UniqueID, nil
 	case *oamnet.IPAddress:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.IPAddress, "address", v.Address.String())
+		return oam.IPAddress, "address", v.Address.String(), nil
 	case *oamreg.IPNetRecord:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.IPNetRecord, "handle", v.Handle)
+		return oam.IPNetRecord, "handle", v.Handle, nil
 	case *contact.Location:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.Location, "address", v.Address)
+		return oam.Location, "address", v.Address, nil
 	case *oamnet.Netblock:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.Netblock, "cidr", v.CIDR.String())
+		return oam.Netblock, "cidr", v.CIDR.String(), nil
 	case *org.Organization:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.Organization, "unique_id", v.ID)
+		return oam.Organization, "unique_id", v.ID, nil
 	case *people.Person:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.Person, "unique_id", v.ID)
+		return oam.Person, "unique_id", v.ID, nil
 	case *contact.Phone:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.Phone, "raw", v.Raw)
+		return oam.Phone, "raw", v.Raw, nil
 	case *platform.Product:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.Product, "unique_id", v.ID)
+		return oam.Product, "unique_id", v.ID, nil
 	case *platform.ProductRelease:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.ProductRelease, "name", v.Name)
+		return oam.ProductRelease, "name", v.Name, nil
 	case *platform.Service:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.Service, "unique_id", v.ID)
+		return oam.Service, "unique_id", v.ID, nil
 	case *oamcert.TLSCertificate:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.TLSCertificate, "serial_number", v.SerialNumber)
+		return oam.TLSCertificate, "serial_number", v.SerialNumber, nil
 	case *url.URL:
-		node = fmt.Sprintf("(%s:%s {%s: '%s'})", varname, oam.URL, "url", v.Raw)
+		return oam.URL, "url", v.Raw, nil
 	}
-	if node == "" {
-		return "", errors.New("asset type not supported")
-	}
-
-	return node, nil
+	return "", "", nil, errors.New("asset type not supported")
 }
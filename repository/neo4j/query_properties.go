@@ -30,6 +30,9 @@ func entityTagPropsMap(tag *types.EntityTag) (map[string]interface{}, error) {
 	m["created_at"] = timeToNeo4jTime(tag.CreatedAt)
 	m["updated_at"] = timeToNeo4jTime(tag.LastSeen)
 	m["entity_id"] = tag.Entity.ID
+	if !tag.ExpiresAt.IsZero() {
+		m["expires_at"] = timeToNeo4jTime(tag.ExpiresAt)
+	}
 
 	// Add the properties of the property
 	props, err := propertyPropsMap(tag.Property)
@@ -59,6 +62,9 @@ func edgeTagPropsMap(tag *types.EdgeTag) (map[string]interface{}, error) {
 	m["created_at"] = timeToNeo4jTime(tag.CreatedAt)
 	m["updated_at"] = timeToNeo4jTime(tag.LastSeen)
 	m["edge_id"] = tag.Edge.ID
+	if !tag.ExpiresAt.IsZero() {
+		m["expires_at"] = timeToNeo4jTime(tag.ExpiresAt)
+	}
 
 	// Add the properties of the property
 	props, err := propertyPropsMap(tag.Property)
@@ -107,27 +113,35 @@ func propertyPropsMap(prop oam.Property) (map[string]interface{}, error) {
 	return m, nil
 }
 
-func queryNodeByPropertyKeyValue(varname, label string, prop oam.Property) (string, error) {
-	if prop == nil {
-		return "", errors.New("the property is nil")
-	}
-
-	var node string
+// propertyNameValueKeys returns the node properties that hold the fields returned by the Property Name and
+// Value methods, along with the value in the form that it is stored in the node.
+func propertyNameValueKeys(prop oam.Property) (string, string, interface{}, error) {
 	switch v := prop.(type) {
 	case *types.CacheProperty:
-		node = fmt.Sprintf("(%s:%s {%s: '%s', %s: %s})", varname, label, "cache_id", v.ID, "ref_id", v.RefID)
+		return "cache_id", "ref_id", v.RefID, nil
 	case *dns.DNSRecordProperty:
-		node = fmt.Sprintf("(%s:%s {%s: '%s', %s: %s})", varname, label, "property_name", v.PropertyName, "data", v.Data)
+		return "property_name", "data", v.Data, nil
 	case *general.SimpleProperty:
-		node = fmt.Sprintf("(%s:%s {%s: '%s', %s: '%s'})", varname, label, "property_name", v.PropertyName, "property_value", v.PropertyValue)
+		return "property_name", "property_value", v.PropertyValue, nil
 	case *general.SourceProperty:
-		node = fmt.Sprintf("(%s:%s {%s: '%s', %s: %d})", varname, label, "name", v.Source, "confidence", v.Confidence)
+		return "name", "confidence", v.Confidence, nil
 	case *platform.VulnProperty:
-		node = fmt.Sprintf("(%s:%s {%s: '%s', %s: '%s'})", varname, label, "vuln_id", v.ID, "desc", v.Description)
+		return "vuln_id", "desc", v.Description, nil
 	}
-	if node == "" {
-		return "", errors.New("asset type not supported")
+	return "", "", nil, errors.New("property type not supported")
+}
+
+// queryNodeByPropertyKeyValue returns the pattern that matches the tag node of the property by its name and value, along
+// with the parameters that hold the name and value, so the property content is not written into the query.
+func queryNodeByPropertyKeyValue(varname, label string, prop oam.Property) (string, map[string]interface{}, error) {
+	if prop == nil {
+		return "", nil, errors.New("the property is nil")
 	}
 
-	return node, nil
+	nkey, vkey, value, err := propertyNameValueKeys(prop)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("(%s:%s {%s: $name, %s: $value})", varname, label, nkey, vkey),
+		map[string]interface{}{"name": prop.Name(), "value": value}, nil
 }
@@ -0,0 +1,72 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"context"
+
+	"github.com/garthoid/asset-db/types"
+	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// recordRows implements types.Rows over the records of an eager result.
+type recordRows struct {
+	keys    []string
+	records []*neo4jdb.Record
+	next    int
+	current *neo4jdb.Record
+}
+
+// RawCypher runs the Cypher query against the database and returns its records as rows, with the params bound to the
+// parameters of the query. It is intended for the ad-hoc queries that the Repository methods do not support, and is
+// UNSAFE: the query is passed to the database as written, so a query that modifies the data bypasses the soft-delete,
+// content hash, and duplicate checks of the Repository methods. The params must never be interpolated into the query.
+// The query is routed to the leader of a cluster, and is not attempted again after a transient error, since it may
+// write. Within a transaction, the query runs within the transaction. The records are read before RawCypher returns,
+// so the rows do not hold a connection, and the values are those provided by the driver, such as neo4j.Node.
+func (neo *neoRepository) RawCypher(ctx context.Context, cypher string, params map[string]interface{}) (types.Rows, error) {
+	result, err := neo.executeQuery(ctx, cypher, params)
+	if err != nil {
+		return nil, err
+	}
+	return &recordRows{keys: result.Keys, records: result.Records}, nil
+}
+
+// Columns implements the types.Rows interface.
+func (r *recordRows) Columns() []string {
+	return r.keys
+}
+
+// Next implements the types.Rows interface.
+func (r *recordRows) Next() bool {
+	r.current = nil
+	if r.next >= len(r.records) {
+		return false
+	}
+
+	r.current = r.records[r.next]
+	r.next++
+	return true
+}
+
+// Values implements the types.Rows interface.
+func (r *recordRows) Values() []interface{} {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Values
+}
+
+// Err implements the types.Rows interface.
+func (r *recordRows) Err() error {
+	return nil
+}
+
+// Close implements the types.Rows interface.
+func (r *recordRows) Close() error {
+	r.current = nil
+	r.records = nil
+	return nil
+}
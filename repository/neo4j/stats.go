@@ -0,0 +1,74 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"context"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/dbtype"
+)
+
+// Stats returns a snapshot of the contents of the database, with an aggregate query for each of the counts.
+// The entities are counted by asset type and the edges by label, which is the lower-case relationship type,
+// excluding the soft-deleted entities and their edges, while the tags are counted as stored, including the expired tags.
+// Neo4j does not report the size of the store to a query, so SizeBytes is zero.
+// Returns the statistics, or an error if any of the queries fails.
+func (neo *neoRepository) Stats(ctx context.Context) (*types.DBStats, error) {
+	entities, err := neo.CountEntitiesGrouped(ctx, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := neo.operationContext(ctx)
+	defer cancel()
+
+	result, err := neo.executeRead(ctx, "MATCH (:Entity)-[r]->(:Entity) RETURN toLower(type(r)) AS label, count(r) AS total", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	edges := make(map[string]int64, len(result.Records))
+	for _, record := range result.Records {
+		label, _, err := neo4jdb.GetRecordValue[string](record, "label")
+		if err != nil {
+			return nil, err
+		}
+
+		total, _, err := neo4jdb.GetRecordValue[int64](record, "total")
+		if err != nil {
+			return nil, err
+		}
+		edges[label] = total
+	}
+
+	stats := &types.DBStats{
+		EntitiesByType: entities,
+		EdgesByLabel:   edges,
+	}
+	if stats.EntityTags, err = neo.countQuery(ctx, "MATCH (p:EntityTag) RETURN count(p) AS total"); err != nil {
+		return nil, err
+	}
+	if stats.EdgeTags, err = neo.countQuery(ctx, "MATCH (p:EdgeTag) RETURN count(p) AS total"); err != nil {
+		return nil, err
+	}
+
+	result, err = neo.executeRead(ctx, "MATCH (a:Entity) RETURN min(a.created_at) AS oldest, max(a.updated_at) AS newest", nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) > 0 {
+		// the aggregates are null when the database holds no entities
+		if oldest, isnil, err := neo4jdb.GetRecordValue[dbtype.LocalDateTime](result.Records[0], "oldest"); err == nil && !isnil {
+			stats.OldestCreatedAt = neo4jTimeToTime(oldest)
+		}
+		if newest, isnil, err := neo4jdb.GetRecordValue[dbtype.LocalDateTime](result.Records[0], "newest"); err == nil && !isnil {
+			stats.NewestLastSeen = neo4jTimeToTime(newest)
+		}
+	}
+	return stats, nil
+}
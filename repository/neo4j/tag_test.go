@@ -7,6 +7,8 @@
 package neo4j
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -18,7 +20,7 @@ import (
 )
 
 func TestEntityTag(t *testing.T) {
-	entity, err := store.CreateAsset(&dns.FQDN{Name: "utica.edu"})
+	entity, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "utica.edu"})
 	assert.NoError(t, err)
 
 	now := time.Now().Truncate(time.Second)
@@ -27,7 +29,7 @@ func TestEntityTag(t *testing.T) {
 		PropertyValue: "foo",
 	}
 
-	ct, err := store.CreateEntityProperty(entity, prop)
+	ct, err := store.CreateEntityProperty(context.Background(), entity, prop)
 	assert.NoError(t, err)
 	assert.Equal(t, ct.Property.Name(), prop.PropertyName)
 	assert.Equal(t, ct.Property.Value(), prop.PropertyValue)
@@ -39,7 +41,7 @@ func TestEntityTag(t *testing.T) {
 		t.Errorf("tag.LastSeen: %s, expected to be after: %s", ct.LastSeen.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano))
 	}
 
-	tag, err := store.FindEntityTagById(ct.ID)
+	tag, err := store.FindEntityTagById(context.Background(), ct.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, ct.CreatedAt, tag.CreatedAt)
 	assert.Equal(t, ct.LastSeen, tag.LastSeen)
@@ -47,7 +49,7 @@ func TestEntityTag(t *testing.T) {
 	assert.Equal(t, ct.Property.Value(), tag.Property.Value())
 
 	time.Sleep(time.Second)
-	ct2, err := store.CreateEntityProperty(entity, prop)
+	ct2, err := store.CreateEntityProperty(context.Background(), entity, prop)
 	assert.NoError(t, err)
 	if ct2.LastSeen.UnixNano() < ct.LastSeen.UnixNano() {
 		t.Errorf("ct2.LastSeen: %s, ct.LastSeen: %s", ct2.LastSeen.Format(time.RFC3339Nano), ct.LastSeen.Format(time.RFC3339Nano))
@@ -55,7 +57,7 @@ func TestEntityTag(t *testing.T) {
 
 	time.Sleep(time.Second)
 	prop.PropertyValue = "bar"
-	ct3, err := store.CreateEntityProperty(entity, prop)
+	ct3, err := store.CreateEntityProperty(context.Background(), entity, prop)
 	assert.NoError(t, err)
 	assert.Equal(t, ct3.Property.Value(), prop.PropertyValue)
 	if ct3.CreatedAt.UnixNano() < ct2.CreatedAt.UnixNano() {
@@ -65,7 +67,7 @@ func TestEntityTag(t *testing.T) {
 		t.Errorf("ct3.LastSeen: %s, ct2.LastSeen: %s", ct3.LastSeen.Format(time.RFC3339Nano), ct2.LastSeen.Format(time.RFC3339Nano))
 	}
 
-	tags, err := store.GetEntityTags(entity, now, "test")
+	tags, err := store.GetEntityTags(context.Background(), entity, now, "test")
 	assert.NoError(t, err)
 
 	var found bool
@@ -77,21 +79,29 @@ func TestEntityTag(t *testing.T) {
 	}
 	assert.Equal(t, found, true)
 
-	err = store.DeleteEntityTag(ct3.ID)
+	n, err := store.DeleteEntityTag(context.Background(), ct3.ID)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
 
-	_, err = store.FindEntityTagById(ct3.ID)
-	assert.Error(t, err)
+	// deleting the tag again removes nothing
+	n, err = store.DeleteEntityTag(context.Background(), ct3.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	_, err = store.FindEntityTagById(context.Background(), ct3.ID)
+	assert.ErrorIs(t, err, types.ErrTagNotFound)
+	_, err = store.FindEntityTagById(context.Background(), "invalid")
+	assert.ErrorIs(t, err, types.ErrNotFound)
 }
 
 func TestEdgeTag(t *testing.T) {
-	e1, err := store.CreateAsset(&dns.FQDN{Name: "owasp.org"})
+	e1, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err)
 
-	e2, err := store.CreateAsset(&dns.FQDN{Name: "www.owasp.org"})
+	e2, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "www.owasp.org"})
 	assert.NoError(t, err)
 
-	edge, err := store.CreateEdge(&types.Edge{
+	edge, err := store.CreateEdge(context.Background(), &types.Edge{
 		Relation: &dns.BasicDNSRelation{
 			Name:   "dns_record",
 			Header: dns.RRHeader{RRType: 5},
@@ -107,7 +117,7 @@ func TestEdgeTag(t *testing.T) {
 		PropertyValue: "foo",
 	}
 
-	ct, err := store.CreateEdgeProperty(edge, prop)
+	ct, err := store.CreateEdgeProperty(context.Background(), edge, prop)
 	assert.NoError(t, err)
 	assert.Equal(t, ct.Property.Name(), prop.PropertyName)
 	assert.Equal(t, ct.Property.Value(), prop.PropertyValue)
@@ -119,7 +129,7 @@ func TestEdgeTag(t *testing.T) {
 		t.Errorf("tag.LastSeen: %s, expected to be after: %s", ct.LastSeen.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano))
 	}
 
-	tag, err := store.FindEdgeTagById(ct.ID)
+	tag, err := store.FindEdgeTagById(context.Background(), ct.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, ct.CreatedAt, tag.CreatedAt)
 	assert.Equal(t, ct.LastSeen, tag.LastSeen)
@@ -127,7 +137,7 @@ func TestEdgeTag(t *testing.T) {
 	assert.Equal(t, ct.Property.Value(), tag.Property.Value())
 
 	time.Sleep(time.Second)
-	ct2, err := store.CreateEdgeProperty(edge, prop)
+	ct2, err := store.CreateEdgeProperty(context.Background(), edge, prop)
 	assert.NoError(t, err)
 	if ct2.LastSeen.UnixNano() < ct.LastSeen.UnixNano() {
 		t.Errorf("ct2.LastSeen: %s, ct.LastSeen: %s", ct2.LastSeen.Format(time.RFC3339Nano), ct.LastSeen.Format(time.RFC3339Nano))
@@ -135,7 +145,7 @@ func TestEdgeTag(t *testing.T) {
 
 	time.Sleep(time.Second)
 	prop.PropertyValue = "bar"
-	ct3, err := store.CreateEdgeProperty(edge, prop)
+	ct3, err := store.CreateEdgeProperty(context.Background(), edge, prop)
 	assert.NoError(t, err)
 	assert.Equal(t, ct3.Property.Value(), prop.PropertyValue)
 	if ct3.CreatedAt.UnixNano() < ct2.CreatedAt.UnixNano() {
@@ -145,7 +155,7 @@ func TestEdgeTag(t *testing.T) {
 		t.Errorf("ct3.LastSeen: %s, ct2.LastSeen: %s", ct3.LastSeen.Format(time.RFC3339Nano), ct2.LastSeen.Format(time.RFC3339Nano))
 	}
 
-	tags, err := store.GetEdgeTags(edge, now, "test")
+	tags, err := store.GetEdgeTags(context.Background(), edge, now, "test")
 	assert.NoError(t, err)
 
 	var found bool
@@ -157,9 +167,290 @@ func TestEdgeTag(t *testing.T) {
 	}
 	assert.Equal(t, found, true)
 
-	err = store.DeleteEdgeTag(ct3.ID)
+	n, err := store.DeleteEdgeTag(context.Background(), ct3.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// deleting the tag again removes nothing
+	n, err = store.DeleteEdgeTag(context.Background(), ct3.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	_, err = store.FindEdgeTagById(context.Background(), ct3.ID)
+	assert.ErrorIs(t, err, types.ErrTagNotFound)
+	_, err = store.FindEdgeTagById(context.Background(), "invalid")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestCreateEntityTags(t *testing.T) {
+	e1, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "tags1.utica.edu"})
+	assert.NoError(t, err)
+	e2, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "tags2.utica.edu"})
+	assert.NoError(t, err)
+
+	prop := &general.SimpleProperty{
+		PropertyName:  "bulk",
+		PropertyValue: "foo",
+	}
+	existing, err := store.CreateEntityProperty(context.Background(), e2, prop)
+	assert.NoError(t, err)
+
+	tags, err := store.CreateEntityTags(context.Background(), []string{e1.ID, "9999999", e2.ID}, &types.EntityTag{Property: prop})
+	var missing *types.MissingEntitiesError
+	assert.True(t, errors.As(err, &missing))
+	assert.Equal(t, []string{"9999999"}, missing.IDs)
+
+	// the tags are returned in the order of the entities, and the existing tag is not duplicated
+	assert.Len(t, tags, 2)
+	assert.Equal(t, e1.ID, tags[0].Entity.ID)
+	assert.Equal(t, e2.ID, tags[1].Entity.ID)
+	assert.Equal(t, existing.ID, tags[1].ID)
+
+	found, err := store.FindEntityTagsByContent(context.Background(), prop, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, found, 2)
+}
+
+func TestUpdateTags(t *testing.T) {
+	e1, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "update1.utica.edu"})
+	assert.NoError(t, err)
+	e2, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "update2.utica.edu"})
+	assert.NoError(t, err)
+
+	edge, err := store.CreateEdge(context.Background(), &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: e1,
+		ToEntity:   e2,
+	})
+	assert.NoError(t, err)
+
+	etag, err := store.CreateEntityProperty(context.Background(), e1, &general.SimpleProperty{
+		PropertyName:  "update",
+		PropertyValue: "foo",
+	})
 	assert.NoError(t, err)
 
-	_, err = store.FindEdgeTagById(ct3.ID)
+	time.Sleep(time.Second)
+	updated, err := store.UpdateEntityTag(context.Background(), etag.ID, "bar")
+	assert.NoError(t, err)
+	assert.Equal(t, etag.ID, updated.ID)
+	assert.Equal(t, "bar", updated.Property.Value())
+	assert.Equal(t, etag.CreatedAt, updated.CreatedAt)
+	if !updated.LastSeen.After(etag.LastSeen) {
+		t.Errorf("updated.LastSeen: %s, expected to be after: %s", updated.LastSeen.Format(time.RFC3339Nano), etag.LastSeen.Format(time.RFC3339Nano))
+	}
+
+	found, err := store.FindEntityTagById(context.Background(), etag.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", found.Property.Value())
+	assert.Equal(t, etag.CreatedAt, found.CreatedAt)
+
+	_, err = store.UpdateEntityTag(context.Background(), "9999999", "bar")
+	assert.ErrorIs(t, err, types.ErrTagNotFound)
+
+	gtag, err := store.CreateEdgeProperty(context.Background(), edge, &general.SimpleProperty{
+		PropertyName:  "update",
+		PropertyValue: "foo",
+	})
+	assert.NoError(t, err)
+
+	utag, err := store.UpdateEdgeTag(context.Background(), gtag.ID, "bar")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", utag.Property.Value())
+	assert.Equal(t, gtag.CreatedAt, utag.CreatedAt)
+	assert.Equal(t, edge.ID, utag.Edge.ID)
+
+	_, err = store.UpdateEdgeTag(context.Background(), "9999999", "bar")
+	assert.ErrorIs(t, err, types.ErrTagNotFound)
+}
+
+func TestFindEntitiesByTag(t *testing.T) {
+	e1, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "bytag1.utica.edu"})
+	assert.NoError(t, err)
+	e2, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "bytag2.utica.edu"})
+	assert.NoError(t, err)
+	e3, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "bytag3.utica.edu"})
+	assert.NoError(t, err)
+
+	_, err = store.CreateEntityProperty(context.Background(), e1, &general.SourceProperty{Source: "bytag_source", Confidence: 100})
+	assert.NoError(t, err)
+	_, err = store.CreateEntityProperty(context.Background(), e2, &general.SourceProperty{Source: "bytag_source", Confidence: 50})
+	assert.NoError(t, err)
+	_, err = store.CreateEntityProperty(context.Background(), e3, &general.SimpleProperty{PropertyName: "bytag_source", PropertyValue: "100"})
+	assert.NoError(t, err)
+
+	// the name is matched across the property types when the value is empty
+	entities, err := store.FindEntitiesByTag(context.Background(), "bytag_source", "", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 3)
+
+	entities, err = store.FindEntitiesByTag(context.Background(), "bytag_source", "100", time.Time{})
+	assert.NoError(t, err)
+	var ids []string
+	for _, e := range entities {
+		ids = append(ids, e.ID)
+	}
+	assert.ElementsMatch(t, []string{e1.ID, e3.ID}, ids)
+
+	_, err = store.FindEntitiesByTag(context.Background(), "bytag_source", "", time.Now().Add(time.Hour))
 	assert.Error(t, err)
+	_, err = store.FindEntitiesByTag(context.Background(), "bytag_missing", "", time.Time{})
+	assert.Error(t, err)
+}
+
+func TestTagsBetween(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "from.tags.window.example.com"})
+	assert.NoError(t, err)
+	to, err := store.CreateAsset(ctx, &dns.FQDN{Name: "to.tags.window.example.com"})
+	assert.NoError(t, err)
+	defer func() {
+		_, _ = store.DeleteEntity(ctx, from.ID)
+		_, _ = store.DeleteEntity(ctx, to.ID)
+	}()
+
+	edge, err := store.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	for i, name := range []string{"first", "second"} {
+		seen := start.Add(time.Duration(i) * time.Hour)
+		prop := &general.SimpleProperty{PropertyName: name, PropertyValue: "window"}
+
+		_, err := store.CreateEntityTag(ctx, from, &types.EntityTag{LastSeen: seen, Property: prop})
+		assert.NoError(t, err)
+		_, err = store.CreateEdgeTag(ctx, edge, &types.EdgeTag{LastSeen: seen, Property: prop})
+		assert.NoError(t, err)
+	}
+
+	etags, err := store.GetEntityTagsBetween(ctx, from, start.Add(time.Hour), time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, etags, 1) {
+		assert.Equal(t, "second", etags[0].Property.Name())
+	}
+	_, err = store.GetEntityTagsBetween(ctx, from, time.Time{}, start)
+	assert.Error(t, err)
+
+	edgeTags, err := store.GetEdgeTagsBetween(ctx, edge, start, start.Add(time.Hour), "first")
+	assert.NoError(t, err)
+	if assert.Len(t, edgeTags, 1) {
+		assert.Equal(t, "first", edgeTags[0].Property.Name())
+	}
+	_, err = store.GetEdgeTagsBetween(ctx, edge, start.Add(2*time.Hour), time.Time{})
+	assert.Error(t, err)
+}
+
+func TestGetEntityTagsMatching(t *testing.T) {
+	ctx := context.Background()
+	entity, err := store.CreateAsset(ctx, &dns.FQDN{Name: "matching.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+
+	crtsh, err := store.CreateEntityProperty(ctx, entity, &general.SourceProperty{Source: "crtsh", Confidence: 90})
+	assert.NoError(t, err)
+	source, err := store.CreateEntityProperty(ctx, entity, &general.SimpleProperty{PropertyName: "source", PropertyValue: "crtsh"})
+	assert.NoError(t, err)
+	_, err = store.CreateEntityProperty(ctx, entity, &general.SimpleProperty{PropertyName: "source", PropertyValue: "dns"})
+	assert.NoError(t, err)
+
+	// both the name and the value are matched
+	tags, err := store.GetEntityTagsMatching(ctx, entity, time.Time{}, "source", "crtsh")
+	assert.NoError(t, err)
+	if assert.Len(t, tags, 1) {
+		assert.Equal(t, source.ID, tags[0].ID)
+	}
+
+	// only the name is matched when the value is empty
+	tags, err = store.GetEntityTagsMatching(ctx, entity, time.Time{}, "source", "")
+	assert.NoError(t, err)
+	assert.Len(t, tags, 2)
+
+	tags, err = store.GetEntityTagsMatching(ctx, entity, time.Time{}, "crtsh", "90")
+	assert.NoError(t, err)
+	if assert.Len(t, tags, 1) {
+		assert.Equal(t, crtsh.ID, tags[0].ID)
+	}
+
+	_, err = store.GetEntityTagsMatching(ctx, entity, time.Time{}, "source", "bing")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.GetEntityTagsMatching(ctx, entity, time.Now().Add(time.Minute), "source", "crtsh")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.GetEntityTagsMatching(ctx, entity, time.Time{}, "", "crtsh")
+	assert.Error(t, err)
+}
+
+func TestExpiredTags(t *testing.T) {
+	ctx := context.Background()
+	e1, err := store.CreateAsset(ctx, &dns.FQDN{Name: "expired.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, e1.ID) }()
+	e2, err := store.CreateAsset(ctx, &dns.FQDN{Name: "www.expired.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, e2.ID) }()
+	edge, err := store.CreateEdge(ctx, &types.Edge{
+		Relation:   &dns.BasicDNSRelation{Name: "dns_record", Header: dns.RRHeader{RRType: 5}},
+		FromEntity: e1,
+		ToEntity:   e2,
+	})
+	assert.NoError(t, err)
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	_, err = store.CreateEntityTag(ctx, e1, &types.EntityTag{
+		ExpiresAt: past,
+		Property:  &general.SimpleProperty{PropertyName: "expired", PropertyValue: "yes"},
+	})
+	assert.NoError(t, err)
+	live, err := store.CreateEntityTag(ctx, e1, &types.EntityTag{
+		ExpiresAt: future,
+		Property:  &general.SimpleProperty{PropertyName: "expired", PropertyValue: "no"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, future.Unix(), live.ExpiresAt.Unix())
+	_, err = store.CreateEdgeTag(ctx, edge, &types.EdgeTag{
+		ExpiresAt: past,
+		Property:  &general.SimpleProperty{PropertyName: "expired", PropertyValue: "yes"},
+	})
+	assert.NoError(t, err)
+
+	// the expired tags are excluded from the results
+	tags, err := store.GetEntityTags(ctx, e1, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, tags, 1) {
+		assert.Equal(t, live.ID, tags[0].ID)
+		assert.Equal(t, future.Unix(), tags[0].ExpiresAt.Unix())
+	}
+	_, err = store.GetEdgeTags(ctx, edge, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.GetEntityTagsMatching(ctx, e1, time.Time{}, "expired", "yes")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	// creating a duplicate of an expired tag updates it rather than adding another
+	revived, err := store.CreateEntityTag(ctx, e1, &types.EntityTag{
+		ExpiresAt: future,
+		Property:  &general.SimpleProperty{PropertyName: "expired", PropertyValue: "yes"},
+	})
+	assert.NoError(t, err)
+	tags, err = store.GetEntityTags(ctx, e1, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, tags, 2)
+
+	_, err = store.CreateEntityTag(ctx, e1, &types.EntityTag{
+		ExpiresAt: past,
+		Property:  &general.SimpleProperty{PropertyName: "expired", PropertyValue: "yes"},
+	})
+	assert.NoError(t, err)
+	count, err := store.PurgeExpiredTags(ctx, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	_, err = store.FindEntityTagById(ctx, revived.ID)
+	assert.ErrorIs(t, err, types.ErrTagNotFound)
+	_, err = store.FindEntityTagById(ctx, live.ID)
+	assert.NoError(t, err)
 }
@@ -0,0 +1,205 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/garthoid/asset-db/internal/retry"
+	"github.com/garthoid/asset-db/internal/timeout"
+	"github.com/garthoid/asset-db/types"
+	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// defaultOperationTimeout bounds each operation of the repository when no query timeout is set.
+const defaultOperationTimeout = 30 * time.Second
+
+// queryRunner is satisfied by both managed and explicit Neo4j transactions.
+type queryRunner interface {
+	Run(ctx context.Context, cypher string, params map[string]interface{}) (neo4jdb.ResultWithContext, error)
+}
+
+// WithTransaction executes the provided function within a single explicit write transaction.
+// The repository passed to fn routes all of its calls through the transaction, which is committed
+// when fn returns nil and rolled back otherwise. Calls made on a repository that is already scoped
+// to a transaction reuse that transaction. The scoped repository must not be used concurrently.
+// When a query timeout is set, it bounds the whole transaction, rather than each of the calls made within it.
+func (neo *neoRepository) WithTransaction(ctx context.Context, fn func(tx types.Repository) error) error {
+	if neo.tx != nil {
+		return fn(neo)
+	}
+
+	ctx, cancel := timeout.Context(ctx, neo.timeout)
+	defer cancel()
+
+	session := neo.db.NewSession(ctx, neo.sessionConfig(neo4jdb.AccessModeWrite))
+	defer func() { _ = session.Close(context.Background()) }()
+
+	tx, err := session.BeginTransaction(ctx, neo.txConfig()...)
+	if err != nil {
+		return translateError(err)
+	}
+	// rolls back the transaction if it was not committed, including when fn panics
+	defer func() { _ = tx.Close(context.Background()) }()
+
+	txrepo := *neo
+	txrepo.tx = tx
+	if err := fn(&txrepo); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+	return translateError(tx.Commit(ctx))
+}
+
+// sessionConfig returns the configuration of the sessions opened with the access mode, which use the database
+// of the repository and pull the records of each result in batches of the size set by options.WithNeo4jFetchSize.
+func (neo *neoRepository) sessionConfig(mode neo4jdb.AccessMode) neo4jdb.SessionConfig {
+	return neo4jdb.SessionConfig{
+		AccessMode:   mode,
+		DatabaseName: neo.dbname,
+		FetchSize:    neo.fetchSize,
+	}
+}
+
+// txConfig returns the configuration of the transactions, which sets the transaction timeout of the server
+// to the query timeout, when one is set.
+func (neo *neoRepository) txConfig() []func(*neo4jdb.TransactionConfig) {
+	if neo.timeout <= 0 {
+		return nil
+	}
+	return []func(*neo4jdb.TransactionConfig){neo4jdb.WithTxTimeout(neo.timeout)}
+}
+
+// countQuery executes the provided read query and returns the value of the total column in the single record.
+func (neo *neoRepository) countQuery(ctx context.Context, query string) (int64, error) {
+	result, err := neo.executeRead(ctx, query, nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(result.Records) == 0 {
+		return 0, errors.New("no records returned from the query")
+	}
+
+	total, _, err := neo4jdb.GetRecordValue[int64](result.Records[0], "total")
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// executeRead runs the read-only query, which is routed to the readers of a cluster and attempted
+// again after a transient error when retries are enabled.
+func (neo *neoRepository) executeRead(ctx context.Context, query string, params map[string]interface{}) (*neo4jdb.EagerResult, error) {
+	return neo.retryable(ctx, neo4jdb.AccessModeRead, query, params)
+}
+
+// executeRetryable runs the idempotent write query, which is attempted again after a transient error when retries are enabled.
+func (neo *neoRepository) executeRetryable(ctx context.Context, query string, params map[string]interface{}) (*neo4jdb.EagerResult, error) {
+	return neo.retryable(ctx, neo4jdb.AccessModeWrite, query, params)
+}
+
+// retryable runs the query with the access mode, and attempts it again after a transient error when retries are enabled.
+// Queries within a transaction are not retried, since the transaction does not survive the failure.
+func (neo *neoRepository) retryable(ctx context.Context, mode neo4jdb.AccessMode, query string, params map[string]interface{}) (*neo4jdb.EagerResult, error) {
+	if neo.tx != nil {
+		return neo.execute(ctx, mode, query, params)
+	}
+
+	var result *neo4jdb.EagerResult
+	err := retry.Do(ctx, neo.maxAttempts, neo.retryDelay, isRetryable, func() error {
+		var err error
+		result, err = neo.execute(ctx, mode, query, params)
+		return err
+	})
+	return result, err
+}
+
+// executeQuery runs the write query, which is routed to the leader of a cluster.
+func (neo *neoRepository) executeQuery(ctx context.Context, query string, params map[string]interface{}) (*neo4jdb.EagerResult, error) {
+	return neo.execute(ctx, neo4jdb.AccessModeWrite, query, params)
+}
+
+// operationContext returns the context of an operation of the repository, which is bound by the query timeout
+// when one is set and the context has no deadline, or by the default timeout of the operations otherwise.
+// The query timeout is applied here, rather than only by execute, since the default timeout would give the
+// context a deadline that keeps the query timeout from taking effect.
+func (neo *neoRepository) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if neo.timeout > 0 {
+		return timeout.Context(ctx, neo.timeout)
+	}
+	return context.WithTimeout(ctx, defaultOperationTimeout)
+}
+
+// execute runs the query with the access mode and logs the outcome when a logger was provided in the options.
+// The query is bound by the query timeout, when the context has no deadline.
+func (neo *neoRepository) execute(ctx context.Context, mode neo4jdb.AccessMode, query string, params map[string]interface{}) (*neo4jdb.EagerResult, error) {
+	if neo.captured != nil {
+		// the queries of the dry run of Explain are collected rather than executed
+		*neo.captured = append(*neo.captured, capturedQuery{query: query, params: params})
+		return &neo4jdb.EagerResult{}, nil
+	}
+
+	ctx, cancel := timeout.Context(ctx, neo.timeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := neo.runQuery(ctx, mode, query, params)
+
+	var rows int
+	if result != nil {
+		rows = len(result.Records)
+	}
+	neo.logOperation("", start, query, rows, err)
+	return result, translateError(err)
+}
+
+// runQuery runs the query within the transaction the repository is scoped to, if any, in which case the
+// access mode of the transaction applies. Otherwise, the query is executed by the driver within its own
+// managed transaction, which is routed to the readers or the leader of a cluster by the access mode.
+// The driver retries a managed transaction that fails with a transient error, such as a deadlock or the
+// switch of a cluster leader, for up to the time set by options.WithTxRetryTimeout.
+func (neo *neoRepository) runQuery(ctx context.Context, mode neo4jdb.AccessMode, query string, params map[string]interface{}) (*neo4jdb.EagerResult, error) {
+	if neo.tx == nil {
+		routing := neo4jdb.ExecuteQueryWithWritersRouting()
+		if mode == neo4jdb.AccessModeRead {
+			routing = neo4jdb.ExecuteQueryWithReadersRouting()
+		}
+
+		return neo4jdb.ExecuteQuery(ctx, neo.db, query, params,
+			neo4jdb.EagerResultTransformer,
+			neo4jdb.ExecuteQueryWithDatabase(neo.dbname),
+			neo4jdb.ExecuteQueryWithTransactionConfig(neo.txConfig()...),
+			routing,
+		)
+	}
+
+	result, err := neo.tx.Run(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := result.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := result.Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := result.Consume(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &neo4jdb.EagerResult{
+		Keys:    keys,
+		Records: records,
+		Summary: summary,
+	}, nil
+}
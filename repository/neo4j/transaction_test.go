@@ -0,0 +1,66 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// deadlineTx records the deadline of the context of each query run by runQuery, without a server.
+type deadlineTx struct {
+	neo4jdb.ExplicitTransaction
+	deadline time.Time
+	ok       bool
+}
+
+func (tx *deadlineTx) Run(ctx context.Context, cypher string, params map[string]interface{}) (neo4jdb.ResultWithContext, error) {
+	tx.deadline, tx.ok = ctx.Deadline()
+	return nil, errors.New("the query was not run")
+}
+
+func TestQueryDeadline(t *testing.T) {
+	callerDeadline := time.Now().Add(5 * time.Minute)
+
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		deadline bool
+		want     time.Time
+	}{
+		{"a query timeout longer than the default", 2 * time.Minute, false, time.Now().Add(2 * time.Minute)},
+		{"a query timeout shorter than the default", 5 * time.Second, false, time.Now().Add(5 * time.Second)},
+		{"no query timeout", 0, false, time.Now().Add(defaultOperationTimeout)},
+		{"the deadline of the context", 2 * time.Minute, true, callerDeadline},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := &deadlineTx{}
+			neo := &neoRepository{timeout: test.timeout, tx: tx}
+
+			ctx := context.Background()
+			if test.deadline {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, callerDeadline)
+				defer cancel()
+			}
+
+			if _, err := neo.FindEntityById(ctx, "1"); err == nil {
+				t.Fatal("Expected the query to fail")
+			}
+			if !tx.ok {
+				t.Fatal("The context of the query has no deadline")
+			}
+			if diff := tx.deadline.Sub(test.want); diff < -time.Second || diff > time.Second {
+				t.Errorf("The deadline of the query is %v, expected %v", tx.deadline, test.want)
+			}
+		})
+	}
+}
@@ -5,57 +5,185 @@
 package repository
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
 	"strings"
-	"time"
 
+	"github.com/garthoid/asset-db/events"
+	"github.com/garthoid/asset-db/metrics"
+	"github.com/garthoid/asset-db/options"
+	"github.com/garthoid/asset-db/readonly"
 	"github.com/garthoid/asset-db/repository/neo4j"
 	"github.com/garthoid/asset-db/repository/sqlrepo"
+	"github.com/garthoid/asset-db/tracing"
 	"github.com/garthoid/asset-db/types"
-	oam "github.com/owasp-amass/open-asset-model"
+	neo4jdb "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 )
 
 // Repository defines the methods for interacting with the asset database.
 // It provides operations for creating, retrieving, tagging, and linking assets.
-type Repository interface {
-	GetDBType() string
-	CreateEntity(entity *types.Entity) (*types.Entity, error)
-	CreateAsset(asset oam.Asset) (*types.Entity, error)
-	FindEntityById(id string) (*types.Entity, error)
-	FindEntitiesByContent(asset oam.Asset, since time.Time) ([]*types.Entity, error)
-	FindEntitiesByType(atype oam.AssetType, since time.Time) ([]*types.Entity, error)
-	DeleteEntity(id string) error
-	CreateEdge(edge *types.Edge) (*types.Edge, error)
-	FindEdgeById(id string) (*types.Edge, error)
-	IncomingEdges(entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error)
-	OutgoingEdges(entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error)
-	DeleteEdge(id string) error
-	CreateEntityTag(entity *types.Entity, tag *types.EntityTag) (*types.EntityTag, error)
-	CreateEntityProperty(entity *types.Entity, property oam.Property) (*types.EntityTag, error)
-	FindEntityTagById(id string) (*types.EntityTag, error)
-	FindEntityTagsByContent(prop oam.Property, since time.Time) ([]*types.EntityTag, error)
-	GetEntityTags(entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error)
-	DeleteEntityTag(id string) error
-	CreateEdgeTag(edge *types.Edge, tag *types.EdgeTag) (*types.EdgeTag, error)
-	CreateEdgeProperty(edge *types.Edge, property oam.Property) (*types.EdgeTag, error)
-	FindEdgeTagById(id string) (*types.EdgeTag, error)
-	FindEdgeTagsByContent(prop oam.Property, since time.Time) ([]*types.EdgeTag, error)
-	GetEdgeTags(edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error)
-	DeleteEdgeTag(id string) error
-	Close() error
+// The interface is declared in the types package so that the repository implementations can refer to it.
+type Repository = types.Repository
+
+// SQLBackend is implemented by the repositories of the SQL databases, and provides the GORM handle that shares the
+// connection pool of the repository, such as for running custom transactions. The repositories returned by
+// NewReadOnly and NewTracedRepository, and those instrumented by options.WithMetrics, do not implement it.
+type SQLBackend interface {
+	DB() *gorm.DB
+}
+
+// Neo4jBackend is implemented by the Neo4j repository, and provides the driver that shares the connection pool of the
+// repository, such as for running custom sessions. The repositories returned by NewReadOnly and NewTracedRepository,
+// and those instrumented by options.WithMetrics, do not implement it.
+type Neo4jBackend interface {
+	Driver() neo4jdb.DriverWithContext
+}
+
+// dbtypes lists the database types supported by New, in the order they are listed by the errors of ParseType.
+// A new backend is added to this list, which keeps SupportedDBTypes and ParseType in sync with New.
+var dbtypes = []string{sqlrepo.Postgres, sqlrepo.MySQL, sqlrepo.SQLite, sqlrepo.SQLiteMemory, neo4j.Neo4j}
+
+// SupportedDBTypes returns the constants of the database types supported by New, such as for validating
+// the database type selected by the flags of a command. The returned slice can be modified by the caller.
+func SupportedDBTypes() []string {
+	return slices.Clone(dbtypes)
+}
+
+// ParseType returns the constant of the database type matching dbtype, which is compared case-insensitively,
+// such as sqlrepo.Postgres for "Postgres". Returns an error listing the supported database types when dbtype
+// is not one of them, so that the type can be validated before any connection is attempted.
+func ParseType(dbtype string) (string, error) {
+	for _, t := range dbtypes {
+		if strings.EqualFold(dbtype, t) {
+			return t, nil
+		}
+	}
+
+	quoted := make([]string, 0, len(dbtypes))
+	for _, t := range dbtypes {
+		quoted = append(quoted, fmt.Sprintf("%q", t))
+	}
+	return "", fmt.Errorf("unsupported dbtype %q, want one of %s", dbtype, strings.Join(quoted, ", "))
 }
 
 // New creates a new instance of the asset database repository.
-func New(dbtype, dsn string) (Repository, error) {
-	switch strings.ToLower(dbtype) {
-	case strings.ToLower(neo4j.Neo4j):
-		return neo4j.New(dbtype, dsn)
-	case strings.ToLower(sqlrepo.Postgres):
-		fallthrough
-	case strings.ToLower(sqlrepo.SQLite):
-		fallthrough
-	case strings.ToLower(sqlrepo.SQLiteMemory):
-		return sqlrepo.New(dbtype, dsn)
-	}
-	return nil, errors.New("unknown DB type")
+// The database type is validated with ParseType before connecting to the database.
+// The options can be used to tune the connection pool and batch settings of the repository.
+// When options.WithReadOnly is provided, the returned repository rejects the writes, as with NewReadOnly.
+// When options.WithMetrics is provided, the returned repository is instrumented with Prometheus collectors.
+func New(dbtype, dsn string, opts ...options.Option) (Repository, error) {
+	db, err := newRepository(dbtype, dsn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return decorate(db, options.Apply(opts...))
+}
+
+// NewWithSQLDB creates a new instance of the asset database repository for the SQL database type, which shares the
+// *sql.DB provided by the caller rather than opening a connection pool. The handle remains owned by the caller, so
+// closing the repository leaves it open. The options are handled as by New, apart from the connection pool settings.
+func NewWithSQLDB(dbtype string, db *sql.DB, opts ...options.Option) (Repository, error) {
+	dbtype, err := sqlType(dbtype)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := sqlrepo.NewWithSQLDB(dbtype, db, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return decorate(r, options.Apply(opts...))
+}
+
+// NewWithDialector creates a new instance of the asset database repository for the SQL database type, which opens
+// the GORM dialector provided by the caller. The handle opened by the dialector is closed along with the repository.
+// The options are handled as by New.
+func NewWithDialector(dbtype string, dialector gorm.Dialector, opts ...options.Option) (Repository, error) {
+	dbtype, err := sqlType(dbtype)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := sqlrepo.NewWithDialector(dbtype, dialector, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return decorate(r, options.Apply(opts...))
+}
+
+// NewWithNeo4jDriver creates a new instance of the asset database repository that uses the Neo4j driver provided by
+// the caller, for the database with the provided name. The driver remains owned by the caller, so closing the
+// repository leaves it open. The options are handled as by New, apart from those that configure the driver.
+func NewWithNeo4jDriver(driver neo4jdb.DriverWithContext, dbname string, opts ...options.Option) (Repository, error) {
+	r, err := neo4j.NewWithDriver(driver, dbname, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return decorate(r, options.Apply(opts...))
+}
+
+// sqlType returns the constant of the SQL database type matching dbtype, or an error for Neo4j and the unsupported types.
+func sqlType(dbtype string) (string, error) {
+	dbtype, err := ParseType(dbtype)
+	if err != nil {
+		return "", err
+	}
+	if dbtype == neo4j.Neo4j {
+		return "", errors.New("the Neo4j database type does not use a SQL handle")
+	}
+	return dbtype, nil
+}
+
+// decorate wraps the repository as selected by the options, with the read-only mode, the event handler,
+// and the Prometheus collectors.
+func decorate(db Repository, o *options.Options) (Repository, error) {
+	if o.ReadOnly {
+		db = readonly.New(db)
+	}
+	if o.EventHandler != nil {
+		e, err := events.New(db, o.EventHandler)
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		db = e
+	}
+	if o.Registerer != nil {
+		m, err := metrics.New(db, o.Registerer)
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		return m, nil
+	}
+	return db, nil
+}
+
+// NewTracedRepository returns a repository that produces an OpenTelemetry span for each operation of r.
+// The decorator can wrap any repository, including one instrumented by options.WithMetrics or a cache.
+func NewTracedRepository(r Repository, tracer trace.Tracer) Repository {
+	return tracing.New(r, tracer)
+}
+
+// NewReadOnly returns a repository that only permits the read operations of r, such as for handing the
+// repository to code that must not modify the data. The methods that create, update, or delete records
+// return an error matching types.ErrReadOnly without calling r, and the reads pass through to r.
+func NewReadOnly(r Repository) Repository {
+	return readonly.New(r)
+}
+
+func newRepository(dbtype, dsn string, opts ...options.Option) (Repository, error) {
+	dbtype, err := ParseType(dbtype)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbtype == neo4j.Neo4j {
+		return neo4j.New(dbtype, dsn, opts...)
+	}
+	return sqlrepo.New(dbtype, dsn, opts...)
 }
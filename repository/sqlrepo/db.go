@@ -5,75 +5,540 @@
 package sqlrepo
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
 	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/garthoid/asset-db/internal/retry"
+	"github.com/garthoid/asset-db/internal/track"
+	"github.com/garthoid/asset-db/options"
 	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 const (
 	Postgres     string = "postgres"
+	MySQL        string = "mysql"
 	SQLite       string = "sqlite"
 	SQLiteMemory string = "sqlite_memory"
 )
 
+// defaultBatchSize is the maximum number of rows inserted by a single statement during batch operations.
+const defaultBatchSize = 100
+
+// defaultRetryBaseDelay is the delay before the first retry of an operation that failed with a transient error.
+const defaultRetryBaseDelay = 100 * time.Millisecond
+
+// defaultConnectRetryDelay is the delay before the second attempt of the initial connection set by options.WithConnectRetry.
+const defaultConnectRetryDelay = time.Second
+
 // sqlRepository is a repository implementation using GORM as the underlying ORM.
 type sqlRepository struct {
-	db     *gorm.DB
-	dbtype string
+	db          *gorm.DB
+	dbtype      string
+	batchSize   int
+	softDelete  bool
+	lastSeen    bool
+	order       string
+	expiredTags bool
+	marshal     options.Marshaler
+	validate    options.Validator
+	maxAttempts int
+	retryDelay  time.Duration
+	workspace   string
+	prefix      string
+	intx        bool
+	borrowed    bool
+	unpin       func() error
+	open        *track.Set
 }
 
 // New creates a new instance of the asset database repository.
-func New(dbtype, dsn string) (*sqlRepository, error) {
-	db, err := newDatabase(dbtype, dsn)
+// Connection pool settings not provided in the options keep their defaults.
+func New(dbtype, dsn string, opts ...options.Option) (*sqlRepository, error) {
+	o := options.Apply(opts...)
+	// the options are checked before the pool is opened, so an invalid option leaves nothing to close
+	if err := checkOptions(o); err != nil {
+		return nil, err
+	}
+
+	// the connection is pinned before the pool is opened, so the database exists for the lifetime of the repository
+	unpin := func() error { return nil }
+	if dbtype == SQLiteMemory {
+		var err error
+		if unpin, err = pinMemoryDatabase(dsn, o); err != nil {
+			return nil, translateError(err)
+		}
+	}
+
+	db, err := connect(dbtype, dsn, o)
+	if err != nil {
+		_ = unpin()
+		return nil, translateError(err)
+	}
+
+	repo, err := newRepository(dbtype, db, o)
+	if err != nil {
+		closeDatabase(db)
+		_ = unpin()
+		return nil, err
+	}
+	repo.unpin = unpin
+	return repo, nil
+}
+
+// NewWithDialector creates a new instance of the asset database repository with the GORM dialector provided by the caller,
+// such as one created with a custom driver configuration. The connection pool settings of the options are applied to the
+// handle opened by the dialector, which is closed along with the repository. A dialector that holds a connection pool
+// owned by the caller closes that pool as well, so NewWithSQLDB is used to share the pool instead.
+func NewWithDialector(dbtype string, dialector gorm.Dialector, opts ...options.Option) (*sqlRepository, error) {
+	o := options.Apply(opts...)
+	if err := checkOptions(o); err != nil {
+		return nil, err
+	}
+
+	conns, idles, err := poolDefaults(dbtype)
 	if err != nil {
 		return nil, err
 	}
 
+	db, err := gorm.Open(dialector, gormConfig(o))
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sqlDB, o, conns, idles)
+
+	repo, err := newRepository(dbtype, db, o)
+	if err != nil {
+		closeDatabase(db)
+		return nil, err
+	}
+	return repo, nil
+}
+
+// closeDatabase closes the connection pool of the GORM database, such as when the repository that would
+// own it fails to be created.
+func closeDatabase(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+}
+
+// NewWithSQLDB creates a new instance of the asset database repository that shares the *sql.DB provided by the caller, such as
+// one opened with stdlib.OpenDBFromPool for a pgxpool.Pool, rather than opening a second connection pool. The handle remains
+// owned by the caller, so Close does not close it, and the connection pool settings of the options are not applied to it.
+// The settings applied to each session by the DSN-based constructors, such as the TLS configuration and the Postgres schema
+// and pragmas of the options, are left to the configuration of the handle.
+func NewWithSQLDB(dbtype string, db *sql.DB, opts ...options.Option) (*sqlRepository, error) {
+	if db == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	var dialector gorm.Dialector
+	switch dbtype {
+	case Postgres:
+		dialector = postgres.New(postgres.Config{Conn: db})
+	case MySQL:
+		dialector = mysql.New(mysql.Config{Conn: db})
+	case SQLite, SQLiteMemory:
+		dialector = &sqlite.Dialector{Conn: db}
+	default:
+		return nil, errors.New("unknown DB type")
+	}
+
+	o := options.Apply(opts...)
+	if err := checkOptions(o); err != nil {
+		return nil, err
+	}
+	gdb, err := gorm.Open(dialector, gormConfig(o))
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	repo, err := newRepository(dbtype, gdb, o)
+	if err != nil {
+		return nil, err
+	}
+	repo.borrowed = true
+	return repo, nil
+}
+
+// errIDGenerator is returned when an ID generator is provided, since the entity IDs are assigned by the database.
+var errIDGenerator = errors.New("the SQL repository does not support ID generators, since the database assigns the entity IDs")
+
+// checkOptions returns an error for the options that cannot be used by the SQL repository.
+func checkOptions(o *options.Options) error {
+	if o.IDGenerator != nil {
+		return errIDGenerator
+	}
+	return CheckTablePrefix(o.TablePrefix)
+}
+
+// newRepository registers the callbacks of the repository with the GORM database, and returns the repository that uses it.
+func newRepository(dbtype string, db *gorm.DB, o *options.Options) (*sqlRepository, error) {
+	if err := registerErrorTranslation(db); err != nil {
+		return nil, err
+	}
+	if err := registerQueryTimeout(db, o.QueryTimeout); err != nil {
+		return nil, err
+	}
+	if err := installAcquirePool(db, o); err != nil {
+		return nil, err
+	}
+	if err := registerWorkspace(db, o.Workspace); err != nil {
+		return nil, err
+	}
+	if err := registerExplain(db); err != nil {
+		return nil, err
+	}
+
+	batchSize := defaultBatchSize
+	if o.BatchSize > 0 {
+		batchSize = o.BatchSize
+	}
+
+	retryDelay := defaultRetryBaseDelay
+	if o.RetryBaseDelay > 0 {
+		retryDelay = o.RetryBaseDelay
+	}
+
 	return &sqlRepository{
-		db:     db,
-		dbtype: dbtype,
+		db:          db,
+		dbtype:      dbtype,
+		batchSize:   batchSize,
+		softDelete:  o.SoftDelete,
+		lastSeen:    o.LastSeenOrder,
+		order:       entityOrder(o),
+		expiredTags: o.ExpiredTags,
+		marshal:     o.Marshaler,
+		validate:    o.Validator,
+		maxAttempts: o.MaxAttempts,
+		retryDelay:  retryDelay,
+		workspace:   o.Workspace,
+		prefix:      o.TablePrefix,
+		unpin:       func() error { return nil },
+		open:        track.New(),
 	}, nil
 }
 
+// entityOrder returns the ORDER BY clause of FindEntitiesByType set by options.WithOrder, which is empty
+// when the option is not provided or the field is not supported.
+func entityOrder(o *options.Options) string {
+	var column string
+	switch o.OrderBy {
+	case options.OrderByCreatedAt:
+		column = "created_at"
+	case options.OrderByUpdatedAt:
+		column = "updated_at"
+	case options.OrderByType:
+		column = "etype"
+	default:
+		return ""
+	}
+
+	if o.OrderDirection == options.Descending {
+		return column + " DESC, entity_id DESC"
+	}
+	return column + ", entity_id"
+}
+
+// poolDefaults returns the maximum number of open and idle connections used for the database type when the options do not specify them.
+func poolDefaults(dbtype string) (int, int, error) {
+	switch dbtype {
+	case Postgres, MySQL:
+		return 5, 2, nil
+	case SQLite, SQLiteMemory:
+		return 1, 1, nil
+	}
+	return 0, 0, errors.New("unknown DB type")
+}
+
+// connect opens the database, which pings the server, and attempts it again after a connection error when
+// options.WithConnectRetry is provided, such as while the server is still starting. The certificates that
+// fail to verify are not retried, since the connection attempts of pgx report them as connection errors.
+func connect(dbtype, dsn string, o *options.Options) (*gorm.DB, error) {
+	delay := defaultConnectRetryDelay
+	if o.ConnectRetryDelay > 0 {
+		delay = o.ConnectRetryDelay
+	}
+
+	retryable := func(err error) bool {
+		return isConnectionError(err) && !isCertificateError(err)
+	}
+
+	var db *gorm.DB
+	err := retry.Do(context.Background(), o.ConnectAttempts, delay, retryable, func() error {
+		var err error
+		db, err = newDatabase(dbtype, dsn, o)
+		return err
+	})
+	return db, err
+}
+
 // newDatabase creates a new GORM database connection based on the provided database type and data source name (dsn).
-func newDatabase(dbtype, dsn string) (*gorm.DB, error) {
+func newDatabase(dbtype, dsn string, o *options.Options) (*gorm.DB, error) {
 	switch dbtype {
 	case Postgres:
-		return postgresDatabase(dsn)
+		return postgresDatabase(dsn, o)
+	case MySQL:
+		return mysqlDatabase(dsn, o)
 	case SQLite:
-		return sqliteDatabase(dsn, 1, 1)
+		return sqliteDatabase(dsn, o)
 	case SQLiteMemory:
-		return sqliteDatabase(dsn, 1, 1)
+		return sqliteDatabase(dsn, o)
 	}
 	return nil, errors.New("unknown DB type")
 }
 
 // postgresDatabase creates a new PostgreSQL database connection using the provided data source name (dsn).
-func postgresDatabase(dsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+func postgresDatabase(dsn string, o *options.Options) (*gorm.DB, error) {
+	dialector, err := PostgresDialector(dsn, o)
 	if err != nil {
 		return nil, err
 	}
 
+	db, err := gorm.Open(dialector, gormConfig(o))
+	if err != nil {
+		return nil, certificateError(err)
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
 
-	sqlDB.SetMaxIdleConns(2)
-	sqlDB.SetMaxOpenConns(5)
-	sqlDB.SetConnMaxLifetime(time.Hour)
-	sqlDB.SetConnMaxIdleTime(10 * time.Minute)
+	configurePool(sqlDB, o, 5, 2)
+	return db, nil
+}
+
+// PostgresDialector returns the GORM dialector for the provided Postgres data source name (dsn).
+// When the options provide a TLS configuration, a schema, a query timeout, or the read-only mode, the connection is
+// opened with a pgx configuration that uses the TLS configuration in place of the settings derived from the sslmode
+// parameter of the DSN, and sets the search_path, the statement_timeout, and default_transaction_read_only of each session.
+func PostgresDialector(dsn string, o *options.Options) (gorm.Dialector, error) {
+	if o.TLSConfig == nil && o.Schema == "" && o.QueryTimeout <= 0 && !o.ReadOnly {
+		return postgres.Open(dsn), nil
+	}
+
+	cfg, err := postgresConnConfig(dsn, o)
+	if err != nil {
+		return nil, err
+	}
+	return postgres.New(postgres.Config{Conn: stdlib.OpenDB(*cfg)}), nil
+}
+
+// postgresConnConfig parses the DSN and applies the TLS configuration, the schema, the query timeout, and the read-only mode of the options.
+// The schema and the query timeout take precedence over a search_path or a statement_timeout provided by the DSN.
+func postgresConnConfig(dsn string, o *options.Options) (*pgx.ConnConfig, error) {
+	var cfg *pgx.ConnConfig
+	var err error
+	if o.TLSConfig != nil {
+		cfg, err = postgresConfig(dsn, o.TLSConfig)
+	} else {
+		cfg, err = pgx.ParseConfig(dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Schema != "" {
+		cfg.RuntimeParams["search_path"] = pgx.Identifier{o.Schema}.Sanitize()
+	}
+	if o.QueryTimeout > 0 {
+		// the timeout is rounded up to milliseconds, since a statement_timeout of zero disables it
+		ms := (o.QueryTimeout + time.Millisecond - 1) / time.Millisecond
+		cfg.RuntimeParams["statement_timeout"] = strconv.FormatInt(int64(ms), 10)
+	}
+	if o.ReadOnly {
+		cfg.RuntimeParams["default_transaction_read_only"] = "on"
+	}
+	return cfg, nil
+}
+
+// postgresConfig parses the DSN and applies the TLS configuration to each of the hosts.
+// The fallbacks without TLS, which pgx creates for the sslmode values of prefer and allow, are removed.
+func postgresConfig(dsn string, tlsConfig *tls.Config) (*pgx.ConnConfig, error) {
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := cfg.TLSConfig != nil
+	for _, fb := range cfg.Fallbacks {
+		if fb.TLSConfig != nil {
+			encrypted = true
+		}
+	}
+	if !encrypted {
+		return nil, errors.New("the TLS configuration cannot be used when the DSN specifies sslmode=disable")
+	}
+
+	forHost := func(host string) *tls.Config {
+		c := tlsConfig.Clone()
+		if c.ServerName == "" {
+			c.ServerName = host
+		}
+		return c
+	}
+
+	type addr struct {
+		host string
+		port uint16
+	}
+	seen := map[addr]struct{}{{cfg.Host, cfg.Port}: {}}
+
+	cfg.TLSConfig = forHost(cfg.Host)
+	var fallbacks []*pgconn.FallbackConfig
+	for _, fb := range cfg.Fallbacks {
+		if _, found := seen[addr{fb.Host, fb.Port}]; found {
+			continue
+		}
+		seen[addr{fb.Host, fb.Port}] = struct{}{}
+
+		fb.TLSConfig = forHost(fb.Host)
+		fallbacks = append(fallbacks, fb)
+	}
+	cfg.Fallbacks = fallbacks
+	return cfg, nil
+}
+
+// certificateError identifies the errors caused by a failure to verify the certificate of the server.
+func certificateError(err error) error {
+	if isCertificateError(err) {
+		return fmt.Errorf("failed to verify the certificate of the Postgres server: %w", err)
+	}
+	return err
+}
+
+// isCertificateError reports whether the error was caused by a failure to verify the certificate of the server.
+func isCertificateError(err error) bool {
+	var verr *tls.CertificateVerificationError
+	var unknown x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+
+	return errors.As(err, &verr) || errors.As(err, &unknown) || errors.As(err, &hostname) || errors.As(err, &invalid)
+}
+
+// mysqlDatabase creates a new MySQL database connection using the provided data source name (dsn).
+// The DSN is adjusted so that DATETIME columns are scanned into time.Time values in UTC.
+func mysqlDatabase(dsn string, o *options.Options) (*gorm.DB, error) {
+	cfg, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+
+	db, err := gorm.Open(mysql.Open(cfg.FormatDSN()), gormConfig(o))
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	configurePool(sqlDB, o, 5, 2)
 	return db, nil
 }
 
 // sqliteDatabase creates a new SQLite database connection using the provided data source name (dsn).
-func sqliteDatabase(dsn string, conns, idles int) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+func sqliteDatabase(dsn string, o *options.Options) (*gorm.DB, error) {
+	db, err := gorm.Open(SQLiteDialector(dsn, o), gormConfig(o))
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	configurePool(sqlDB, o, 1, 1)
+	return db, nil
+}
+
+// SQLiteDialector returns the GORM dialector for the provided SQLite data source name (dsn).
+// The pragmas of the options are added to the DSN, so the driver applies them to each new connection.
+// Foreign keys are enforced unless the options or the DSN provide the foreign_keys pragma.
+func SQLiteDialector(dsn string, o *options.Options) gorm.Dialector {
+	return sqlite.Open(sqliteDSN(dsn, o.SQLitePragmas))
+}
+
+// sqliteDSN appends the pragmas to the query of the DSN as _pragma parameters, in the order of their names.
+func sqliteDSN(dsn string, pragmas map[string]string) string {
+	params := make(url.Values)
+	if _, found := pragmas["foreign_keys"]; !found && !dsnPragma(dsn, "foreign_keys") {
+		params.Add("_pragma", "foreign_keys(ON)")
+	}
+
+	keys := make([]string, 0, len(pragmas))
+	for k := range pragmas {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		params.Add("_pragma", fmt.Sprintf("%s(%s)", k, pragmas[k]))
+	}
+	if len(params) == 0 {
+		return dsn
+	}
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + params.Encode()
+}
+
+// dsnPragma reports whether the query of the DSN already provides the named pragma.
+func dsnPragma(dsn, name string) bool {
+	pos := strings.IndexRune(dsn, '?')
+	if pos < 0 {
+		return false
+	}
+
+	q, err := url.ParseQuery(dsn[pos+1:])
+	if err != nil {
+		return false
+	}
+
+	for _, v := range q["_pragma"] {
+		if p := strings.ToLower(strings.TrimSpace(v)); strings.HasPrefix(p, name+"(") || strings.HasPrefix(p, name+"=") || p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pinMemoryDatabase opens a connection to the in-memory SQLite database outside of the connection pool.
+// SQLite discards an in-memory database when its last connection is closed, which the pool does when its
+// connections expire or become idle, so the pinned connection keeps the schema and the data until the
+// returned function is called by Close. The DSN must use the shared cache for the connections to share the database.
+func pinMemoryDatabase(dsn string, o *options.Options) (func() error, error) {
+	db, err := gorm.Open(SQLiteDialector(dsn, o), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
@@ -83,22 +548,95 @@ func sqliteDatabase(dsn string, conns, idles int) (*gorm.DB, error) {
 		return nil, err
 	}
 
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+
+	return func() error {
+		err := conn.Close()
+		if cerr := sqlDB.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
+
+// configurePool applies the connection pool settings to the database handle.
+// The conns and idles parameters are used when the options do not specify a value.
+func configurePool(sqlDB *sql.DB, o *options.Options, conns, idles int) {
+	if o.MaxConnections > 0 {
+		conns = o.MaxConnections
+	}
+	if o.MaxIdleConnections > 0 {
+		idles = o.MaxIdleConnections
+	}
+	lifetime := time.Hour
+	if o.ConnMaxLifetime > 0 {
+		lifetime = o.ConnMaxLifetime
+	}
+	idletime := 10 * time.Minute
+	if o.ConnMaxIdleTime > 0 {
+		idletime = o.ConnMaxIdleTime
+	}
+	// the idle connections are kept for at least the acquire timeout, so a call waiting for a connection can reuse one
+	if o.AcquireTimeout > idletime {
+		idletime = o.AcquireTimeout
+	}
+
 	sqlDB.SetMaxOpenConns(conns)
 	sqlDB.SetMaxIdleConns(idles)
-	sqlDB.SetConnMaxLifetime(time.Hour)
-	sqlDB.SetConnMaxIdleTime(10 * time.Minute)
-	return db, nil
+	sqlDB.SetConnMaxLifetime(lifetime)
+	sqlDB.SetConnMaxIdleTime(idletime)
 }
 
 // Close implements the Repository interface.
+// Closing a repository scoped to a transaction has no effect, since the connection pool is owned by the parent repository,
+// and the handle provided to NewWithSQLDB is left open for the caller. The iterators and the rows of Raw that were not closed
+// are closed first, so their connections are returned to the pool, and an error reports how many were left open.
 func (sql *sqlRepository) Close() error {
-	if db, err := sql.db.DB(); err == nil {
-		return db.Close()
+	if sql.intx {
+		return nil
 	}
-	return errors.New("failed to obtain access to the database handle")
+
+	leaked := closeIterators(sql.open)
+	if sql.borrowed {
+		return leaked
+	}
+
+	db, err := sql.db.DB()
+	if err != nil {
+		_ = sql.unpin()
+		return errors.Join(leaked, errors.New("failed to obtain access to the database handle"))
+	}
+
+	err = db.Close()
+	// the in-memory database is discarded once the pinned connection is closed
+	if uerr := sql.unpin(); err == nil {
+		err = uerr
+	}
+	return errors.Join(leaked, err)
+}
+
+// Ping verifies that the database is reachable, establishing a connection if necessary.
+func (sql *sqlRepository) Ping(ctx context.Context) error {
+	db, err := sql.db.DB()
+	if err != nil {
+		return err
+	}
+	return translateError(db.PingContext(ctx))
 }
 
 // GetDBType returns the type of the database.
 func (sql *sqlRepository) GetDBType() string {
 	return sql.dbtype
 }
+
+// DB returns the GORM handle of the repository, which shares the connection pool of the repository, such as for
+// running custom transactions without opening a second pool. The *sql.DB of the pool is returned by its DB method.
+// Within a transaction, the handle is that of the transaction. The handle must not be closed, since the pool is
+// owned by the repository, and the writes made through it bypass the checks of the Repository methods.
+func (sql *sqlRepository) DB() *gorm.DB {
+	return sql.db
+}
@@ -5,21 +5,25 @@
 package sqlrepo
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"reflect"
 	"strconv"
 	"time"
 
+	"github.com/garthoid/asset-db/internal/jsoncmp"
 	"github.com/garthoid/asset-db/types"
 	oam "github.com/owasp-amass/open-asset-model"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateEdge creates an edge between two entities in the database.
 // The edge is established by creating a new Edge in the database, linking the two entities.
+// An edge with the same entities, relation type, and label is not duplicated, and has its content and last seen time updated.
 // Returns the created edge as a types.Edge or an error if the link creation fails.
-func (sql *sqlRepository) CreateEdge(edge *types.Edge) (*types.Edge, error) {
+func (sql *sqlRepository) CreateEdge(ctx context.Context, edge *types.Edge) (*types.Edge, error) {
 	if edge == nil || edge.Relation == nil || edge.FromEntity == nil ||
 		edge.FromEntity.Asset == nil || edge.ToEntity == nil || edge.ToEntity.Asset == nil {
 		return nil, errors.New("failed input validation checks")
@@ -31,119 +35,181 @@ func (sql *sqlRepository) CreateEdge(edge *types.Edge) (*types.Edge, error) {
 			edge.FromEntity.Asset.AssetType(), edge.Relation.Label(), edge.ToEntity.Asset.AssetType())
 	}
 
-	var updated time.Time
-	if edge.LastSeen.IsZero() {
-		updated = time.Now().UTC()
-	} else {
-		updated = edge.LastSeen.UTC()
+	e, _, err := sql.upsertEdge(ctx, edge)
+	return e, err
+}
+
+// UpsertEdge creates the edge in the database, or updates the content and last seen time of the existing edge with
+// the same entities, relation type, and label. The insert is performed with an ON CONFLICT clause on the unique index
+// of those columns, so that an edge created concurrently by another writer is not duplicated.
+// Returns the edge as a types.Edge, the outcome of the upsert, or an error if the upsert fails.
+func (sql *sqlRepository) UpsertEdge(ctx context.Context, edge *types.Edge) (*types.Edge, types.UpsertResult, error) {
+	if edge == nil || edge.Relation == nil || edge.FromEntity == nil ||
+		edge.FromEntity.Asset == nil || edge.ToEntity == nil || edge.ToEntity.Asset == nil {
+		return nil, "", errors.New("failed input validation checks")
 	}
-	// ensure that duplicate relationships are not entered into the database
-	if e, found := sql.isDuplicateEdge(edge, updated); found {
-		return e, nil
+
+	if !oam.ValidRelationship(edge.FromEntity.Asset.AssetType(),
+		edge.Relation.Label(), edge.Relation.RelationType(), edge.ToEntity.Asset.AssetType()) {
+		return nil, "", fmt.Errorf("%s -%s-> %s is not valid in the taxonomy",
+			edge.FromEntity.Asset.AssetType(), edge.Relation.Label(), edge.ToEntity.Asset.AssetType())
 	}
+	return sql.upsertEdge(ctx, edge)
+}
 
+// upsertEdge writes the edge that has passed the input validation checks, and reports the outcome of the upsert.
+func (sql *sqlRepository) upsertEdge(ctx context.Context, edge *types.Edge) (*types.Edge, types.UpsertResult, error) {
 	fromEntityId, err := strconv.ParseUint(edge.FromEntity.ID, 10, 64)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	toEntityId, err := strconv.ParseUint(edge.ToEntity.ID, 10, 64)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	if err := sql.checkEdgeEntities(ctx, edge, fromEntityId, toEntityId); err != nil {
+		return nil, "", err
 	}
 
 	jsonContent, err := edge.Relation.JSON()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	r := Edge{
+	row := Edge{
 		Type:         string(edge.Relation.RelationType()),
 		Content:      jsonContent,
+		Label:        edge.Relation.Label(),
 		FromEntityID: fromEntityId,
 		ToEntityID:   toEntityId,
-		UpdatedAt:    updated,
-	}
-	if edge.CreatedAt.IsZero() {
-		r.CreatedAt = time.Now().UTC()
-	} else {
-		r.CreatedAt = edge.CreatedAt.UTC()
-	}
-
-	result := sql.db.Create(&r)
-	if err := result.Error; err != nil {
-		return nil, err
-	}
-	return toEdge(r), nil
-}
-
-// isDuplicateEdge checks if the relationship between source and dest already exists.
-func (sql *sqlRepository) isDuplicateEdge(edge *types.Edge, updated time.Time) (*types.Edge, bool) {
-	var dup bool
-	var e *types.Edge
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+	}
+	if !edge.CreatedAt.IsZero() {
+		row.CreatedAt = edge.CreatedAt.UTC()
+	}
+	if !edge.LastSeen.IsZero() {
+		row.UpdatedAt = edge.LastSeen.UTC()
+	}
+
+	var result types.UpsertResult
+	var upserted *types.Edge
+	// the upsert is idempotent, so the entire transaction can be retried
+	err = sql.retry(ctx, func() error {
+		return sql.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if found, err := findEdgeByLabel(tx, &row); err != nil {
+				return err
+			} else if found != nil {
+				result = edgeUpdateResult(found, &row)
+				upserted, err = updateEdge(tx, found, &row)
+				return err
+			}
 
-	if outs, err := sql.OutgoingEdges(edge.FromEntity, time.Time{}, edge.Relation.Label()); err == nil {
-		for _, out := range outs {
-			if edge.ToEntity.ID == out.ToEntity.ID && reflect.DeepEqual(edge.Relation, out.Relation) {
-				_ = sql.edgeSeen(out, updated)
+			r := row
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&r)
+			if err := res.Error; err != nil {
+				return err
+			}
 
-				e, err = sql.FindEdgeById(out.ID)
+			if res.RowsAffected == 0 {
+				// the edge was created by another writer after the lookup
+				found, err := findEdgeByLabel(tx, &row)
 				if err != nil {
-					return nil, false
+					return err
 				}
-
-				dup = true
-				break
+				if found == nil {
+					return types.NotFound("the conflicting edge was not found")
+				}
+				result = edgeUpdateResult(found, &row)
+				upserted, err = updateEdge(tx, found, &row)
+				return err
 			}
-		}
+
+			result = types.UpsertCreated
+			upserted = toEdge(r)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, "", err
 	}
-	return e, dup
+	return upserted, result, nil
 }
 
-// edgeSeen updates the updated_at timestamp for the specified edge.
-func (sql *sqlRepository) edgeSeen(rel *types.Edge, updated time.Time) error {
-	id, err := strconv.ParseUint(rel.ID, 10, 64)
-	if err != nil {
-		return err
+// edgeUpdateResult returns the outcome of the upsert that updates the existing edge with the content of the row.
+func edgeUpdateResult(existing, row *Edge) types.UpsertResult {
+	if jsoncmp.Equal(existing.Content, row.Content) {
+		return types.UpsertUnchanged
 	}
+	return types.UpsertUpdated
+}
 
-	jsonContent, err := rel.Relation.JSON()
-	if err != nil {
+// checkEdgeEntities returns types.ErrEntityNotFound, naming the missing entity, unless both entities of the edge exist.
+// The soft-deleted entities are excluded, so the foreign keys cannot be satisfied by an entity that has been deleted.
+func (sql *sqlRepository) checkEdgeEntities(ctx context.Context, edge *types.Edge, fromEntityId, toEntityId uint64) error {
+	var ids []uint64
+	if err := sql.db.WithContext(ctx).Model(&Entity{}).Where("entity_id IN ?", []uint64{fromEntityId, toEntityId}).Pluck("entity_id", &ids).Error; err != nil {
 		return err
 	}
 
-	fromEntityId, err := strconv.ParseUint(rel.FromEntity.ID, 10, 64)
-	if err != nil {
-		return err
+	found := make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		found[id] = struct{}{}
+	}
+	if _, ok := found[fromEntityId]; !ok {
+		return types.EdgeEntityNotFound("from", edge.FromEntity.ID)
 	}
+	if _, ok := found[toEntityId]; !ok {
+		return types.EdgeEntityNotFound("to", edge.ToEntity.ID)
+	}
+	return nil
+}
 
-	toEntityId, err := strconv.ParseUint(rel.ToEntity.ID, 10, 64)
-	if err != nil {
-		return err
+// findEdgeByLabel returns the edge with the entities, relation type, and label of the row, or nil if there is none.
+// The edges written before the label column was added have a NULL label, so their label is taken from the content.
+func findEdgeByLabel(tx *gorm.DB, row *Edge) (*Edge, error) {
+	var edges []Edge
+	if err := tx.Where("from_entity_id = ? AND to_entity_id = ? AND etype = ? AND (label = ? OR label IS NULL)",
+		row.FromEntityID, row.ToEntityID, row.Type, row.Label).Find(&edges).Error; err != nil {
+		return nil, err
 	}
 
-	r := Edge{
-		ID:           id,
-		Type:         string(rel.Relation.RelationType()),
-		Content:      jsonContent,
-		FromEntityID: fromEntityId,
-		ToEntityID:   toEntityId,
-		CreatedAt:    rel.CreatedAt,
-		UpdatedAt:    updated,
+	var found *Edge
+	for i, e := range edges {
+		if e.Label == row.Label {
+			return &edges[i], nil
+		}
+		if rel, err := e.Parse(); err == nil && rel.Label() == row.Label && found == nil {
+			found = &edges[i]
+		}
 	}
+	return found, nil
+}
 
-	result := sql.db.Save(&r)
-	if err := result.Error; err != nil {
-		return err
+// updateEdge sets the content, label, and last seen time of the existing edge to those of the row.
+func updateEdge(tx *gorm.DB, existing, row *Edge) (*types.Edge, error) {
+	if err := tx.Model(&Edge{}).Where("edge_id = ?", existing.ID).Updates(map[string]interface{}{
+		"content":    row.Content,
+		"label":      row.Label,
+		"updated_at": row.UpdatedAt,
+	}).Error; err != nil {
+		return nil, err
 	}
-	return nil
+
+	existing.Content = row.Content
+	existing.Label = row.Label
+	existing.UpdatedAt = row.UpdatedAt
+	return toEdge(*existing), nil
 }
 
-func (sql *sqlRepository) FindEdgeById(id string) (*types.Edge, error) {
+func (sql *sqlRepository) FindEdgeById(ctx context.Context, id string) (*types.Edge, error) {
 	var rel Edge
 
-	result := sql.db.Where("edge_id = ?", id).First(&rel)
-	if err := result.Error; err != nil {
+	if err := sql.retry(ctx, func() error {
+		return sql.liveEdges(ctx).Where("edge_id = ?", id).First(&rel).Error
+	}); err != nil {
 		return nil, err
 	}
 
@@ -153,20 +219,23 @@ func (sql *sqlRepository) FindEdgeById(id string) (*types.Edge, error) {
 // IncomingEdges finds all edges pointing to the entity of the specified labels and last seen after the since parameter.
 // If since.IsZero(), the parameter will be ignored.
 // If no labels are specified, all incoming eges are returned.
-func (sql *sqlRepository) IncomingEdges(entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+func (sql *sqlRepository) IncomingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	return sql.IncomingEdgesBetween(ctx, entity, since, time.Time{}, labels...)
+}
+
+// IncomingEdgesBetween finds all edges pointing to the entity of the specified labels and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no labels are specified, all incoming edges in the window are returned.
+func (sql *sqlRepository) IncomingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
 	entityId, err := strconv.ParseInt(entity.ID, 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
 	var edges []Edge
-	var result *gorm.DB
-	if since.IsZero() {
-		result = sql.db.Where("to_entity_id = ?", entityId).Find(&edges)
-	} else {
-		result = sql.db.Where("to_entity_id = ? AND updated_at >= ?", entityId, since.UTC()).Find(&edges)
-	}
-	if err := result.Error; err != nil {
+	if err := sql.retry(ctx, func() error {
+		return seenBetween(sql.liveEdges(ctx).Where("to_entity_id = ?", entityId), from, to).Find(&edges).Error
+	}); err != nil {
 		return nil, err
 	}
 
@@ -189,7 +258,7 @@ func (sql *sqlRepository) IncomingEdges(entity *types.Entity, since time.Time, l
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero edges found")
+		return nil, types.NotFound("zero edges found")
 	}
 	return toEdges(results), nil
 }
@@ -197,20 +266,23 @@ func (sql *sqlRepository) IncomingEdges(entity *types.Entity, since time.Time, l
 // OutgoingEdges finds all edges from the entity of the specified labels and last seen after the since parameter.
 // If since.IsZero(), the parameter will be ignored.
 // If no labels are specified, all outgoing edges are returned.
-func (sql *sqlRepository) OutgoingEdges(entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+func (sql *sqlRepository) OutgoingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	return sql.OutgoingEdgesBetween(ctx, entity, since, time.Time{}, labels...)
+}
+
+// OutgoingEdgesBetween finds all edges from the entity of the specified labels and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no labels are specified, all outgoing edges in the window are returned.
+func (sql *sqlRepository) OutgoingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
 	entityId, err := strconv.ParseInt(entity.ID, 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
 	var edges []Edge
-	var result *gorm.DB
-	if since.IsZero() {
-		result = sql.db.Where("from_entity_id = ?", entityId).Find(&edges)
-	} else {
-		result = sql.db.Where("from_entity_id = ? AND updated_at >= ?", entityId, since.UTC()).Find(&edges)
-	}
-	if err := result.Error; err != nil {
+	if err := sql.retry(ctx, func() error {
+		return seenBetween(sql.liveEdges(ctx).Where("from_entity_id = ?", entityId), from, to).Find(&edges).Error
+	}); err != nil {
 		return nil, err
 	}
 
@@ -233,25 +305,448 @@ func (sql *sqlRepository) OutgoingEdges(entity *types.Entity, since time.Time, l
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero edges found")
+		return nil, types.NotFound("zero edges found")
 	}
 	return toEdges(results), nil
 }
 
+// Neighborhood finds the entities reachable from the entity by following up to maxDepth outgoing edges of the
+// specified labels and last seen after the since parameter. The graph is traversed breadth-first with a query per
+// level, and each entity is visited once, so the traversal does not follow cycles.
+// If since.IsZero(), the parameter will be ignored.
+// If no labels are specified, all outgoing edges are followed.
+// Returns the reachable entities, excluding the provided entity, and the edges that connect them, or an error if the traversal fails.
+func (sql *sqlRepository) Neighborhood(ctx context.Context, entity *types.Entity, maxDepth int, since time.Time, labels ...string) ([]*types.Entity, []*types.Edge, error) {
+	if entity == nil {
+		return nil, nil, errors.New("failed input validation checks")
+	}
+	if maxDepth < 1 {
+		return nil, nil, errors.New("the maximum depth must be at least one")
+	}
+
+	entityId, err := strconv.ParseUint(entity.ID, 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reached []uint64
+	var results []*types.Edge
+	frontier := []uint64{entityId}
+	visited := map[uint64]struct{}{entityId: {}}
+
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		edges, err := sql.edgesAt(ctx, "from_entity_id", frontier, since)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var next []uint64
+		for _, edge := range edges {
+			e := toEdge(edge)
+			if e == nil || !hasLabel(e.Relation, labels) {
+				continue
+			}
+			results = append(results, e)
+
+			if _, found := visited[edge.ToEntityID]; !found {
+				visited[edge.ToEntityID] = struct{}{}
+				next = append(next, edge.ToEntityID)
+			}
+		}
+
+		reached = append(reached, next...)
+		frontier = next
+	}
+
+	if len(results) == 0 {
+		return nil, nil, types.NotFound("zero edges found")
+	}
+
+	entities, err := sql.entitiesByIds(ctx, reached)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entities, results, nil
+}
+
+// IncomingEdgesForAll finds the edges pointing to any of the entities of the specified labels and last seen after the since parameter.
+// The edges are found with a query per batch of entities, rather than a query per entity.
+// If since.IsZero(), the parameter will be ignored.
+// If no labels are specified, all incoming edges are returned.
+// Returns the edges keyed by the ID of the entity they point to, which leaves out the entities without edges.
+func (sql *sqlRepository) IncomingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return sql.edgesForAll(ctx, "to_entity_id", entities, since, labels)
+}
+
+// OutgoingEdgesForAll finds the edges from any of the entities of the specified labels and last seen after the since parameter.
+// The edges are found with a query per batch of entities, rather than a query per entity.
+// If since.IsZero(), the parameter will be ignored.
+// If no labels are specified, all outgoing edges are returned.
+// Returns the edges keyed by the ID of the entity they come from, which leaves out the entities without edges.
+func (sql *sqlRepository) OutgoingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	return sql.edgesForAll(ctx, "from_entity_id", entities, since, labels)
+}
+
+// edgesForAll finds the edges whose entity ID column matches any of the entities, and groups the edges by that entity.
+func (sql *sqlRepository) edgesForAll(ctx context.Context, column string, entities []*types.Entity, since time.Time, labels []string) (map[string][]*types.Edge, error) {
+	if len(entities) == 0 {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	var ids []uint64
+	seen := make(map[uint64]struct{}, len(entities))
+	for _, entity := range entities {
+		if entity == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+
+		id, err := strconv.ParseUint(entity.ID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if _, found := seen[id]; !found {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+
+	edges, err := sql.edgesAt(ctx, column, ids, since)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string][]*types.Edge)
+	for _, edge := range edges {
+		e := toEdge(edge)
+		if e == nil || !hasLabel(e.Relation, labels) {
+			continue
+		}
+
+		key := e.FromEntity.ID
+		if column == "to_entity_id" {
+			key = e.ToEntity.ID
+		}
+		results[key] = append(results[key], e)
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero edges found")
+	}
+	return results, nil
+}
+
+// edgesAt returns the live edges whose entity ID column, from_entity_id or to_entity_id, holds any of the IDs,
+// and last seen after the since parameter.
+func (sql *sqlRepository) edgesAt(ctx context.Context, column string, ids []uint64, since time.Time) ([]Edge, error) {
+	var results []Edge
+
+	for start := 0; start < len(ids); start += sql.batchSize {
+		end := min(start+sql.batchSize, len(ids))
+
+		tx := sql.liveEdges(ctx).Where(column+" IN ?", ids[start:end])
+		if !since.IsZero() {
+			tx = tx.Where("updated_at >= ?", since.UTC())
+		}
+
+		var edges []Edge
+		tx = tx.Order("edge_id").Session(&gorm.Session{})
+		if err := sql.retry(ctx, func() error {
+			return tx.Find(&edges).Error
+		}); err != nil {
+			return nil, err
+		}
+		results = append(results, edges...)
+	}
+	return results, nil
+}
+
+// entitiesByIds returns the entities with the provided IDs, in the same order.
+func (sql *sqlRepository) entitiesByIds(ctx context.Context, ids []uint64) ([]*types.Entity, error) {
+	found := make(map[uint64]*types.Entity, len(ids))
+
+	for start := 0; start < len(ids); start += sql.batchSize {
+		end := min(start+sql.batchSize, len(ids))
+
+		var entities []Entity
+		tx := sql.db.WithContext(ctx).Where("entity_id IN ?", ids[start:end]).Session(&gorm.Session{})
+		if err := sql.retry(ctx, func() error {
+			return tx.Find(&entities).Error
+		}); err != nil {
+			return nil, err
+		}
+
+		for _, e := range entities {
+			if assetData, err := e.Parse(); err == nil {
+				found[e.ID] = &types.Entity{
+					ID:        strconv.FormatUint(e.ID, 10),
+					CreatedAt: e.CreatedAt.In(time.UTC).Local(),
+					LastSeen:  e.UpdatedAt.In(time.UTC).Local(),
+					Asset:     assetData,
+				}
+			}
+		}
+	}
+
+	results := make([]*types.Entity, 0, len(ids))
+	for _, id := range ids {
+		if e, ok := found[id]; ok {
+			results = append(results, e)
+		}
+	}
+	return results, nil
+}
+
+// hasLabel reports whether the relation has one of the labels, where an empty list of labels matches all relations.
+func hasLabel(rel oam.Relation, labels []string) bool {
+	if len(labels) == 0 {
+		return true
+	}
+
+	for _, label := range labels {
+		if label == rel.Label() {
+			return true
+		}
+	}
+	return false
+}
+
+// FindEdgesByLabel finds the edges in the database with the provided label and last seen after the since parameter,
+// regardless of the entities they connect. The label is matched against the content of the relation.
+// If since.IsZero(), the parameter will be ignored.
+// The FromEntity and ToEntity fields of each edge only hold the entity IDs.
+// Returns a slice of matching edges as []*types.Edge or an error if the search fails.
+func (sql *sqlRepository) FindEdgesByLabel(ctx context.Context, label string, since time.Time) ([]*types.Edge, error) {
+	if label == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	tx := sql.liveEdges(ctx).Where(datatypes.JSONQuery("content").Equals(label, "label"))
+	if !since.IsZero() {
+		tx = tx.Where("updated_at >= ?", since.UTC())
+	}
+
+	tx = tx.Session(&gorm.Session{})
+
+	var edges []Edge
+	if err := sql.retry(ctx, func() error {
+		return tx.Order("edge_id").Find(&edges).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(edges) == 0 {
+		return nil, types.NotFound("zero edges found")
+	}
+	return toEdges(edges), nil
+}
+
+// FindEdgesSince finds the edges in the database last seen after the since parameter, which includes the edges created
+// or updated since then, such as for a consumer that mirrors the changes to the graph elsewhere. If since.IsZero(), all
+// the edges are returned. When includeTags is true, the Tags field of each edge holds its tags, which are read with a
+// query per batch of edges rather than a query per edge, and exclude the expired tags as GetEdgeTags does.
+// The FromEntity and ToEntity fields of each edge only hold the entity IDs.
+// Returns the edges ordered by ID, or an error matching types.ErrNotFound if no edges are found.
+func (sql *sqlRepository) FindEdgesSince(ctx context.Context, since time.Time, includeTags bool) ([]*types.Edge, error) {
+	tx := sql.liveEdges(ctx)
+	if !since.IsZero() {
+		tx = tx.Where("updated_at >= ?", since.UTC())
+	}
+
+	tx = tx.Session(&gorm.Session{})
+
+	var edges []Edge
+	if err := sql.retry(ctx, func() error {
+		return tx.Order("edge_id").Find(&edges).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(edges) == 0 {
+		return nil, types.NotFound("zero edges found")
+	}
+
+	results := toEdges(edges)
+	if includeTags {
+		if err := sql.setEdgeTags(ctx, results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// setEdgeTags sets the Tags field of the edges to the tags of each edge, with a query per batch of edges.
+// The tags that cannot be parsed are skipped.
+func (sql *sqlRepository) setEdgeTags(ctx context.Context, edges []*types.Edge) error {
+	ids := make([]uint64, 0, len(edges))
+	byId := make(map[uint64]*types.Edge, len(edges))
+	for _, edge := range edges {
+		edgeId, err := strconv.ParseUint(edge.ID, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		ids = append(ids, edgeId)
+		byId[edgeId] = edge
+	}
+
+	for start := 0; start < len(ids); start += sql.batchSize {
+		end := min(start+sql.batchSize, len(ids))
+
+		tx := sql.db.WithContext(ctx).Where("edge_id IN ?", ids[start:end])
+		if !sql.expiredTags {
+			tx = unexpired(tx)
+		}
+
+		var tags []EdgeTag
+		tx = tx.Order("tag_id").Session(&gorm.Session{})
+		if err := sql.retry(ctx, func() error {
+			return tx.Find(&tags).Error
+		}); err != nil {
+			return err
+		}
+
+		for _, tag := range tags {
+			t := &tag
+
+			prop, err := t.Parse()
+			if err != nil {
+				continue
+			}
+
+			edge := byId[t.EdgeID]
+			edge.Tags = append(edge.Tags, &types.EdgeTag{
+				ID:        strconv.FormatUint(t.ID, 10),
+				CreatedAt: t.CreatedAt.In(time.UTC).Local(),
+				LastSeen:  t.UpdatedAt.In(time.UTC).Local(),
+				ExpiresAt: expiration(t.ExpiresAt),
+				Property:  prop,
+				Edge:      edge,
+			})
+		}
+	}
+	return nil
+}
+
+// ResolveEdgeEndpoints finds the distinct entities referenced by the FromEntity and ToEntity fields of the edges,
+// with a query per batch of IDs rather than a query per entity. The IDs that do not match a live entity are omitted.
+// Returns the entities keyed by their IDs, or an error if the search fails.
+func (sql *sqlRepository) ResolveEdgeEndpoints(ctx context.Context, edges []*types.Edge) (map[string]*types.Entity, error) {
+	var ids []uint64
+	seen := make(map[uint64]struct{})
+	for _, edge := range edges {
+		if edge == nil || edge.FromEntity == nil || edge.ToEntity == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+
+		for _, id := range []string{edge.FromEntity.ID, edge.ToEntity.ID} {
+			// an invalid ID cannot match an entity
+			if entityId, err := strconv.ParseUint(id, 10, 64); err == nil {
+				if _, found := seen[entityId]; !found {
+					seen[entityId] = struct{}{}
+					ids = append(ids, entityId)
+				}
+			}
+		}
+	}
+
+	entities, err := sql.entitiesByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]*types.Entity, len(entities))
+	for _, e := range entities {
+		results[e.ID] = e
+	}
+	return results, nil
+}
+
+// CountEdges counts the edges in the database last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the number of matching edges or an error if the count fails.
+func (sql *sqlRepository) CountEdges(ctx context.Context, since time.Time) (int64, error) {
+	tx := sql.liveEdges(ctx).Model(&Edge{})
+	if !since.IsZero() {
+		tx = tx.Where("updated_at >= ?", since.UTC())
+	}
+
+	tx = tx.Session(&gorm.Session{})
+
+	var total int64
+	if err := sql.retry(ctx, func() error {
+		return tx.Count(&total).Error
+	}); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// EntityDegree counts the edges pointing to and leaving the entity that were last seen after the since parameter,
+// with a count of each direction rather than reading the edges. If since.IsZero(), the parameter will be ignored.
+// Returns the number of incoming and outgoing edges, which are zero for an entity without edges, or an error if the count fails.
+func (sql *sqlRepository) EntityDegree(ctx context.Context, entity *types.Entity, since time.Time) (int64, int64, error) {
+	if entity == nil {
+		return 0, 0, errors.New("failed input validation checks")
+	}
+
+	entityId, err := strconv.ParseUint(entity.ID, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	count := func(column string) (int64, error) {
+		tx := sql.liveEdges(ctx).Model(&Edge{}).Where(column+" = ?", entityId)
+		if !since.IsZero() {
+			tx = tx.Where("updated_at >= ?", since.UTC())
+		}
+		tx = tx.Session(&gorm.Session{})
+
+		var total int64
+		err := sql.retry(ctx, func() error {
+			return tx.Count(&total).Error
+		})
+		return total, err
+	}
+
+	in, err := count("to_entity_id")
+	if err != nil {
+		return 0, 0, err
+	}
+	out, err := count("from_entity_id")
+	if err != nil {
+		return 0, 0, err
+	}
+	return in, out, nil
+}
+
 // DeleteEdge removes an edge in the database by its ID.
 // It takes a string representing the edge ID and removes the corresponding edge from the database.
-// Returns an error if the edge is not found.
-func (sql *sqlRepository) DeleteEdge(id string) error {
+// Returns the number of rows removed, which is zero when the edge is not found.
+func (sql *sqlRepository) DeleteEdge(ctx context.Context, id string) (int64, error) {
 	relId, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return sql.deleteEdges([]uint64{relId})
+	return sql.deleteEdges(ctx, []uint64{relId})
 }
 
 // deleteEdges removes all rows in the Edges table with primary keys in the provided slice.
-func (sql *sqlRepository) deleteEdges(ids []uint64) error {
-	return sql.db.Exec("DELETE FROM edges WHERE edge_id IN ?", ids).Error
+// Returns the number of rows removed.
+func (sql *sqlRepository) deleteEdges(ctx context.Context, ids []uint64) (int64, error) {
+	result := sql.db.WithContext(ctx).Exec(sql.prefixed("DELETE FROM edges WHERE edge_id IN ? AND workspace = ?"), ids, sql.workspace)
+	return result.RowsAffected, result.Error
+}
+
+// liveEdges returns a query on the Edges table, which excludes the edges attached to
+// soft-deleted entities when the soft-delete mode is enabled.
+func (sql *sqlRepository) liveEdges(ctx context.Context) *gorm.DB {
+	tx := sql.db.WithContext(ctx)
+	if !sql.softDelete {
+		return tx
+	}
+
+	deleted := sql.db.Unscoped().Model(&Entity{}).Select("entity_id").Where("deleted_at IS NOT NULL")
+	return tx.Where("from_entity_id NOT IN (?) AND to_entity_id NOT IN (?)", deleted, deleted)
 }
 
 // toEdge converts a database Edge to a types.Edge.
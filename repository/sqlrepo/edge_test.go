@@ -7,12 +7,14 @@
 package sqlrepo
 
 import (
+	"context"
 	"net/netip"
 	"testing"
 	"time"
 
 	"github.com/garthoid/asset-db/types"
 	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
 	"github.com/owasp-amass/open-asset-model/network"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,12 +23,12 @@ func TestUnfilteredRelations(t *testing.T) {
 	source := dns.FQDN{Name: "owasp.com"}
 	dest1 := dns.FQDN{Name: "www.example.owasp.org"}
 
-	sourceEntity, err := store.CreateAsset(source)
+	sourceEntity, err := store.CreateAsset(context.Background(), source)
 	if err != nil {
 		t.Fatalf("failed to create asset: %s", err)
 	}
 
-	dest1Entity, err := store.CreateAsset(dest1)
+	dest1Entity, err := store.CreateAsset(context.Background(), dest1)
 	if err != nil {
 		t.Fatalf("failed to create asset: %s", err)
 	}
@@ -47,7 +49,7 @@ func TestUnfilteredRelations(t *testing.T) {
 	ip, _ := netip.ParseAddr("192.168.1.100")
 	dest2 := network.IPAddress{Address: ip, Type: "IPv4"}
 
-	dest2Entity, err := store.CreateAsset(dest2)
+	dest2Entity, err := store.CreateAsset(context.Background(), dest2)
 	if err != nil {
 		t.Fatalf("failed to create asset: %s", err)
 	}
@@ -65,36 +67,36 @@ func TestUnfilteredRelations(t *testing.T) {
 		ToEntity:   dest2Entity,
 	}
 
-	_, err = store.CreateEdge(edge1)
+	_, err = store.CreateEdge(context.Background(), edge1)
 	assert.NoError(t, err)
-	r2Rel, err := store.CreateEdge(edge2)
+	r2Rel, err := store.CreateEdge(context.Background(), edge2)
 	assert.NoError(t, err)
 
 	// Outgoing relations with no filter returns all outgoing relations.
-	outs, err := store.OutgoingEdges(sourceEntity, time.Time{})
+	outs, err := store.OutgoingEdges(context.Background(), sourceEntity, time.Time{})
 	assert.NoError(t, err)
 	assert.Equal(t, len(outs), 2)
 
 	// Outgoing relations with a filter returns
-	outs, err = store.OutgoingEdges(sourceEntity, time.Time{}, edge1.Relation.Label())
+	outs, err = store.OutgoingEdges(context.Background(), sourceEntity, time.Time{}, edge1.Relation.Label())
 	assert.NoError(t, err)
 	assert.Equal(t, sourceEntity.ID, outs[0].FromEntity.ID)
 	assert.Equal(t, edge1.Relation.Label(), outs[0].Relation.Label())
 
 	// Incoming relations with a filter returns
-	ins, err := store.IncomingEdges(dest1Entity, time.Time{}, edge1.Relation.Label())
+	ins, err := store.IncomingEdges(context.Background(), dest1Entity, time.Time{}, edge1.Relation.Label())
 	assert.NoError(t, err)
 	assert.Equal(t, sourceEntity.ID, ins[0].FromEntity.ID)
 	assert.Equal(t, edge1.Relation.Label(), ins[0].Relation.Label())
 
 	// Outgoing with source -> a_record -> dest2Asset
-	outs, err = store.OutgoingEdges(sourceEntity, time.Time{}, edge2.Relation.Label())
+	outs, err = store.OutgoingEdges(context.Background(), sourceEntity, time.Time{}, edge2.Relation.Label())
 	assert.NoError(t, err)
 	assert.Equal(t, sourceEntity.ID, outs[0].FromEntity.ID)
 	assert.Equal(t, edge2.Relation.Label(), outs[0].Relation.Label())
 
 	// Incoming for source -> a_record -> dest2asset
-	ins, err = store.IncomingEdges(dest2Entity, time.Time{}, edge2.Relation.Label())
+	ins, err = store.IncomingEdges(context.Background(), dest2Entity, time.Time{}, edge2.Relation.Label())
 	assert.NoError(t, err)
 	assert.Equal(t, sourceEntity.ID, ins[0].FromEntity.ID)
 	assert.Equal(t, edge2.Relation.Label(), ins[0].Relation.Label())
@@ -103,10 +105,394 @@ func TestUnfilteredRelations(t *testing.T) {
 	time.Sleep(1000 * time.Millisecond)
 
 	// Store a duplicate relation and validate last_seen is updated
-	rr, err := store.CreateEdge(edge2)
+	rr, err := store.CreateEdge(context.Background(), edge2)
 	assert.NoError(t, err)
 	assert.NotNil(t, rr)
 	if rr.LastSeen.UnixNano() <= r2Rel.LastSeen.UnixNano() {
 		t.Errorf("rr.LastSeen: %s, r2Rel.LastSeen: %s", rr.LastSeen.Format(time.RFC3339Nano), r2Rel.LastSeen.Format(time.RFC3339Nano))
 	}
 }
+
+func TestNeighborhood(t *testing.T) {
+	ctx := context.Background()
+	// a -> b -> c -> a forms a cycle, and b -> d is a branch
+	entities := make(map[string]*types.Entity)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		e, err := store.CreateAsset(ctx, &dns.FQDN{Name: name + ".neighborhood.example.com"})
+		assert.NoError(t, err)
+		entities[name] = e
+	}
+	defer func() {
+		for _, e := range entities {
+			_, _ = store.DeleteEntity(ctx, e.ID)
+		}
+	}()
+	for _, pair := range [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}, {"b", "d"}} {
+		_, err := store.CreateEdge(ctx, &types.Edge{
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: entities[pair[0]],
+			ToEntity:   entities[pair[1]],
+		})
+		assert.NoError(t, err)
+	}
+
+	reached, edges, err := store.Neighborhood(ctx, entities["a"], 1, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 1)
+	if assert.Len(t, reached, 1) {
+		assert.Equal(t, entities["b"].ID, reached[0].ID)
+	}
+
+	reached, edges, err = store.Neighborhood(ctx, entities["a"], 2, time.Time{}, "node")
+	assert.NoError(t, err)
+	assert.Len(t, edges, 3)
+	assert.Len(t, reached, 3)
+
+	// the edge back to the first entity is returned, but the entity is not revisited
+	reached, edges, err = store.Neighborhood(ctx, entities["a"], 5, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 4)
+	assert.Len(t, reached, 3)
+	for _, e := range reached {
+		assert.NotEqual(t, entities["a"].ID, e.ID)
+	}
+
+	_, _, err = store.Neighborhood(ctx, entities["a"], 2, time.Time{}, "dns_record")
+	assert.Error(t, err)
+	_, _, err = store.Neighborhood(ctx, entities["a"], 2, time.Now().Add(time.Minute))
+	assert.Error(t, err)
+	_, _, err = store.Neighborhood(ctx, entities["d"], 2, time.Time{})
+	assert.Error(t, err)
+	_, _, err = store.Neighborhood(ctx, entities["a"], 0, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestEdgesForAll(t *testing.T) {
+	ctx := context.Background()
+	// a -> b, a -> c, and b -> c, while d has no edges
+	entities := make(map[string]*types.Entity)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		e, err := store.CreateAsset(ctx, &dns.FQDN{Name: name + ".forall.example.com"})
+		assert.NoError(t, err)
+		entities[name] = e
+	}
+	defer func() {
+		for _, e := range entities {
+			_, _ = store.DeleteEntity(ctx, e.ID)
+		}
+	}()
+	for _, pair := range [][2]string{{"a", "b"}, {"a", "c"}, {"b", "c"}} {
+		_, err := store.CreateEdge(ctx, &types.Edge{
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: entities[pair[0]],
+			ToEntity:   entities[pair[1]],
+		})
+		assert.NoError(t, err)
+	}
+
+	// the entity without edges is left out, and a repeated entity is only counted once
+	outgoing, err := store.OutgoingEdgesForAll(ctx, []*types.Entity{entities["a"], entities["b"], entities["d"], entities["a"]}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, outgoing, 2)
+	assert.Len(t, outgoing[entities["a"].ID], 2)
+	if assert.Len(t, outgoing[entities["b"].ID], 1) {
+		assert.Equal(t, entities["c"].ID, outgoing[entities["b"].ID][0].ToEntity.ID)
+	}
+
+	incoming, err := store.IncomingEdgesForAll(ctx, []*types.Entity{entities["b"], entities["c"]}, time.Time{}, "node")
+	assert.NoError(t, err)
+	assert.Len(t, incoming, 2)
+	assert.Len(t, incoming[entities["c"].ID], 2)
+	if assert.Len(t, incoming[entities["b"].ID], 1) {
+		assert.Equal(t, entities["a"].ID, incoming[entities["b"].ID][0].FromEntity.ID)
+	}
+
+	_, err = store.OutgoingEdgesForAll(ctx, []*types.Entity{entities["a"]}, time.Time{}, "dns_record")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.IncomingEdgesForAll(ctx, []*types.Entity{entities["a"], entities["d"]}, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.OutgoingEdgesForAll(ctx, []*types.Entity{entities["a"]}, time.Now().Add(time.Minute))
+	assert.Error(t, err)
+	_, err = store.OutgoingEdgesForAll(ctx, nil, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestFindEdgesByLabel(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Second)
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "from.label.example.com"})
+	assert.NoError(t, err)
+	to, err := store.CreateAsset(ctx, &dns.FQDN{Name: "to.label.example.com"})
+	assert.NoError(t, err)
+	defer func() {
+		_, _ = store.DeleteEntity(ctx, from.ID)
+		_, _ = store.DeleteEntity(ctx, to.ID)
+	}()
+
+	_, err = store.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	record, err := store.CreateEdge(ctx, &types.Edge{
+		Relation:   dns.BasicDNSRelation{Name: "dns_record", Header: dns.RRHeader{RRType: 5, Class: 1}},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	_, err = store.FindEdgesByLabel(ctx, "", time.Time{})
+	assert.Error(t, err)
+
+	edges, err := store.FindEdgesByLabel(ctx, "dns_record", start)
+	assert.NoError(t, err)
+
+	var found bool
+	for _, e := range edges {
+		assert.Equal(t, "dns_record", e.Relation.Label())
+		if e.ID == record.ID {
+			found = true
+			assert.Equal(t, from.ID, e.FromEntity.ID)
+			assert.Equal(t, to.ID, e.ToEntity.ID)
+		}
+	}
+	assert.True(t, found)
+
+	_, err = store.FindEdgesByLabel(ctx, "dns_record", time.Now().Add(time.Minute))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestFindEdgesSince(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Second)
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "from.since.example.com"})
+	assert.NoError(t, err)
+	to, err := store.CreateAsset(ctx, &dns.FQDN{Name: "to.since.example.com"})
+	assert.NoError(t, err)
+	defer func() {
+		_, _ = store.DeleteEntityCascade(ctx, from.ID)
+		_, _ = store.DeleteEntityCascade(ctx, to.ID)
+	}()
+
+	old, err := store.CreateEdge(ctx, &types.Edge{
+		LastSeen:   time.Date(2002, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	record, err := store.CreateEdge(ctx, &types.Edge{
+		Relation:   dns.BasicDNSRelation{Name: "dns_record", Header: dns.RRHeader{RRType: 5, Class: 1}},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	tag, err := store.CreateEdgeProperty(ctx, record, &general.SimpleProperty{PropertyName: "source", PropertyValue: "since"})
+	assert.NoError(t, err)
+	_, err = store.CreateEdgeTag(ctx, record, &types.EdgeTag{
+		ExpiresAt: time.Now().Add(-time.Minute),
+		Property:  &general.SimpleProperty{PropertyName: "expired", PropertyValue: "since"},
+	})
+	assert.NoError(t, err)
+
+	find := func(edges []*types.Edge, id string) *types.Edge {
+		for _, e := range edges {
+			if e.ID == id {
+				return e
+			}
+		}
+		return nil
+	}
+
+	edges, err := store.FindEdgesSince(ctx, time.Time{}, false)
+	assert.NoError(t, err)
+	assert.NotNil(t, find(edges, old.ID))
+	if e := find(edges, record.ID); assert.NotNil(t, e) {
+		assert.Nil(t, e.Tags)
+	}
+
+	// the tags are loaded along with the edges, excluding the expired tags
+	edges, err = store.FindEdgesSince(ctx, start, true)
+	assert.NoError(t, err)
+	assert.Nil(t, find(edges, old.ID))
+	if e := find(edges, record.ID); assert.NotNil(t, e) {
+		assert.Equal(t, from.ID, e.FromEntity.ID)
+		assert.Equal(t, to.ID, e.ToEntity.ID)
+		if assert.Len(t, e.Tags, 1) {
+			assert.Equal(t, tag.ID, e.Tags[0].ID)
+			assert.Equal(t, "since", e.Tags[0].Property.Value())
+		}
+	}
+
+	_, err = store.FindEdgesSince(ctx, time.Now().Add(time.Minute), true)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestResolveEdgeEndpoints(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "from.resolve.example.com"})
+	assert.NoError(t, err)
+	var targets []*types.Entity
+	for _, name := range []string{"a", "b"} {
+		e, err := store.CreateAsset(ctx, &dns.FQDN{Name: name + ".resolve.example.com"})
+		assert.NoError(t, err)
+		targets = append(targets, e)
+
+		_, err = store.CreateEdge(ctx, &types.Edge{
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: from,
+			ToEntity:   e,
+		})
+		assert.NoError(t, err)
+	}
+	defer func() {
+		for _, e := range append(targets, from) {
+			_, _ = store.DeleteEntity(ctx, e.ID)
+		}
+	}()
+
+	_, err = store.ResolveEdgeEndpoints(ctx, []*types.Edge{nil})
+	assert.Error(t, err)
+
+	edges, err := store.OutgoingEdges(ctx, from, time.Time{})
+	assert.NoError(t, err)
+	entities, err := store.ResolveEdgeEndpoints(ctx, edges)
+	assert.NoError(t, err)
+	assert.Len(t, entities, 3)
+	for _, e := range append(targets, from) {
+		if assert.Contains(t, entities, e.ID) {
+			assert.Equal(t, e.Asset, entities[e.ID].Asset)
+		}
+	}
+
+	entities, err = store.ResolveEdgeEndpoints(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, entities)
+}
+
+func TestCreateEdgeMissingEntity(t *testing.T) {
+	ctx := context.Background()
+
+	entity, err := store.CreateAsset(ctx, &dns.FQDN{Name: "exists.missing.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+	missing := &types.Entity{ID: "999999999", Asset: &dns.FQDN{Name: "gone.missing.example.com"}}
+
+	_, err = store.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: missing,
+		ToEntity:   entity,
+	})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	assert.ErrorContains(t, err, "the from entity "+missing.ID)
+
+	_, err = store.CreateEdge(ctx, &types.Edge{
+		Relation:   general.SimpleRelation{Name: "node"},
+		FromEntity: entity,
+		ToEntity:   missing,
+	})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+	assert.ErrorContains(t, err, "the to entity "+missing.ID)
+	_, err = store.OutgoingEdges(ctx, entity, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestUpsertEdge(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "www.upsert.example.com"})
+	assert.NoError(t, err)
+	to, err := store.CreateAsset(ctx, &dns.FQDN{Name: "upsert.example.com"})
+	assert.NoError(t, err)
+	defer func() {
+		_, _ = store.DeleteEntity(ctx, from.ID)
+		_, _ = store.DeleteEntity(ctx, to.ID)
+	}()
+
+	cname := func(ttl int) *types.Edge {
+		return &types.Edge{
+			Relation: dns.BasicDNSRelation{
+				Name:   "dns_record",
+				Header: dns.RRHeader{RRType: 5, Class: 1, TTL: ttl},
+			},
+			FromEntity: from,
+			ToEntity:   to,
+		}
+	}
+
+	first, result, err := store.UpsertEdge(ctx, cname(3600))
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertCreated, result)
+
+	// the edge with the same entities, relation type, and label is updated rather than duplicated
+	second, result, err := store.UpsertEdge(ctx, cname(300))
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUpdated, result)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, 300, second.Relation.(*dns.BasicDNSRelation).Header.TTL)
+
+	_, result, err = store.UpsertEdge(ctx, cname(300))
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUnchanged, result)
+
+	third, err := store.CreateEdge(ctx, cname(60))
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, third.ID)
+	edges, err := store.OutgoingEdges(ctx, from, time.Time{}, "dns_record")
+	assert.NoError(t, err)
+	assert.Len(t, edges, 1)
+
+	// the edges written before the label column was added are matched on their content
+	assert.NoError(t, store.db.Exec("UPDATE edges SET label = NULL WHERE edge_id = ?", first.ID).Error)
+	fourth, result, err := store.UpsertEdge(ctx, cname(3600))
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUpdated, result)
+	assert.Equal(t, first.ID, fourth.ID)
+}
+
+func TestEdgesBetween(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "from.window.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, from.ID) }()
+
+	var tos []*types.Entity
+	for i, name := range []string{"first.to.window.example.com", "second.to.window.example.com"} {
+		to, err := store.CreateAsset(ctx, &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, to.ID) }()
+		tos = append(tos, to)
+
+		_, err = store.CreateEdge(ctx, &types.Edge{
+			LastSeen:   start.Add(time.Duration(i) * time.Hour),
+			Relation:   general.SimpleRelation{Name: "node"},
+			FromEntity: from,
+			ToEntity:   to,
+		})
+		assert.NoError(t, err)
+	}
+
+	outs, err := store.OutgoingEdgesBetween(ctx, from, start, start.Add(time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, outs, 1) {
+		assert.Equal(t, tos[0].ID, outs[0].ToEntity.ID)
+	}
+	outs, err = store.OutgoingEdgesBetween(ctx, from, start, time.Time{}, "node")
+	assert.NoError(t, err)
+	assert.Len(t, outs, 2)
+	_, err = store.OutgoingEdgesBetween(ctx, from, start, time.Time{}, "dns_record")
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	ins, err := store.IncomingEdgesBetween(ctx, tos[1], start.Add(time.Hour), start.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, ins, 1)
+	_, err = store.IncomingEdgesBetween(ctx, tos[1], start, start.Add(time.Hour))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
@@ -5,28 +5,39 @@
 package sqlrepo
 
 import (
+	"context"
 	"errors"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/garthoid/asset-db/internal/jsoncmp"
 	"github.com/garthoid/asset-db/types"
 	oam "github.com/owasp-amass/open-asset-model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateEntity creates a new entity in the database.
 // It takes an Entity as input and persists it in the database.
-// The asset is serialized to JSON and stored in the Content field of the Entity struct.
+// The asset is serialized to JSON by the configured marshaler and stored in the Content field of the Entity struct.
+// An ID provided by the caller that is held by another asset, or an asset that already exists with another ID,
+// returns an error matching types.ErrDuplicate.
 // Returns the created entity as a types.Entity or an error if the creation fails.
-func (sql *sqlRepository) CreateEntity(input *types.Entity) (*types.Entity, error) {
-	jsonContent, err := input.Asset.JSON()
+func (sql *sqlRepository) CreateEntity(ctx context.Context, input *types.Entity) (*types.Entity, error) {
+	if err := sql.validateAsset(input.Asset); err != nil {
+		return nil, err
+	}
+
+	jsonContent, err := sql.marshalAsset(input.Asset)
 	if err != nil {
 		return nil, err
 	}
 
 	entity := Entity{
-		Type:    string(input.Asset.AssetType()),
-		Content: jsonContent,
+		Type:        string(input.Asset.AssetType()),
+		Content:     jsonContent,
+		ContentHash: types.ContentHash(input.Asset),
 	}
 
 	if input.ID != "" {
@@ -36,11 +47,14 @@ func (sql *sqlRepository) CreateEntity(input *types.Entity) (*types.Entity, erro
 		if err != nil {
 			return nil, err
 		}
+		if err := sql.checkEntityID(ctx, entityId, input.Asset); err != nil {
+			return nil, err
+		}
 
 		entity.ID = entityId
 		entity.UpdatedAt = time.Now().UTC()
 		entity.CreatedAt = input.CreatedAt.UTC()
-	} else if entities, err := sql.FindEntitiesByContent(input.Asset, time.Time{}); err == nil && len(entities) > 0 {
+	} else if entities, err := sql.FindEntitiesByContent(ctx, input.Asset, time.Time{}); err == nil && len(entities) > 0 {
 		// ensure that duplicate entities are not entered into the database
 		e := entities[0]
 
@@ -51,6 +65,11 @@ func (sql *sqlRepository) CreateEntity(input *types.Entity) (*types.Entity, erro
 				entity.UpdatedAt = time.Now().UTC()
 			}
 		}
+	} else if e, err := sql.findDeletedEntityByContent(ctx, input.Asset); err == nil {
+		// a soft-deleted entity with matching content is restored rather than duplicated
+		entity.ID = e.ID
+		entity.CreatedAt = e.CreatedAt
+		entity.UpdatedAt = time.Now().UTC()
 	} else {
 		if input.CreatedAt.IsZero() {
 			entity.CreatedAt = time.Now().UTC()
@@ -65,8 +84,22 @@ func (sql *sqlRepository) CreateEntity(input *types.Entity) (*types.Entity, erro
 		}
 	}
 
-	result := sql.db.Save(&entity)
+	// the unscoped save clears the deleted_at timestamp of a restored entity
+	inserted := entity.ID == 0
+	result := sql.db.WithContext(ctx).Unscoped().Save(&entity)
 	if err := result.Error; err != nil {
+		// the asset was created by another writer after the lookup, so the entity of that writer is updated,
+		// unless the failed statement has aborted the transaction of the repository
+		if inserted && !sql.intx && errors.Is(err, types.ErrDuplicate) {
+			if entities, ferr := sql.FindEntitiesByContent(ctx, input.Asset, time.Time{}); ferr == nil && len(entities) > 0 {
+				return sql.CreateEntity(ctx, &types.Entity{
+					ID:        entities[0].ID,
+					CreatedAt: entities[0].CreatedAt,
+					LastSeen:  input.LastSeen,
+					Asset:     input.Asset,
+				})
+			}
+		}
 		return nil, err
 	}
 
@@ -80,24 +113,375 @@ func (sql *sqlRepository) CreateEntity(input *types.Entity) (*types.Entity, erro
 
 // CreateAsset creates a new entity in the database.
 // It takes an oam.Asset as input and persists it in the database.
-// The asset is serialized to JSON and stored in the Content field of the Entity struct.
+// The asset is serialized to JSON by the configured marshaler and stored in the Content field of the Entity struct.
 // Returns the created entity as a types.Entity or an error if the creation fails.
-func (sql *sqlRepository) CreateAsset(asset oam.Asset) (*types.Entity, error) {
-	return sql.CreateEntity(&types.Entity{Asset: asset})
+func (sql *sqlRepository) CreateAsset(ctx context.Context, asset oam.Asset) (*types.Entity, error) {
+	return sql.CreateEntity(ctx, &types.Entity{Asset: asset})
+}
+
+// CreateEntities creates the provided entities in the database within a single transaction.
+// Entities that already exist in the database are updated, and the new entities are inserted in batches.
+// If any of the inserts fail, the entire batch is rolled back.
+// Returns the created entities in the same order as the input or an error if the creation fails.
+func (sql *sqlRepository) CreateEntities(ctx context.Context, inputs []*types.Entity) ([]*types.Entity, error) {
+	for _, input := range inputs {
+		if input == nil || input.Asset == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+		// the assets are validated before any of the entities are written
+		if err := sql.validateAsset(input.Asset); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]*types.Entity, len(inputs))
+	err := sql.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txrepo := *sql
+		txrepo.db = tx
+		txrepo.intx = true
+
+		var rows []*Entity
+		var positions [][]int
+		seen := make(map[string]int)
+		for i, input := range inputs {
+			// duplicate assets within the batch share a single row
+			key := string(input.Asset.AssetType()) + ":" + input.Asset.Key()
+			if idx, found := seen[key]; found && input.ID == "" {
+				positions[idx] = append(positions[idx], i)
+				continue
+			}
+
+			if input.ID != "" {
+				e, err := txrepo.CreateEntity(ctx, input)
+				if err != nil {
+					return err
+				}
+				results[i] = e
+				continue
+			} else if entities, err := txrepo.FindEntitiesByContent(ctx, input.Asset, time.Time{}); err == nil && len(entities) > 0 {
+				e, err := txrepo.CreateEntity(ctx, input)
+				if err != nil {
+					return err
+				}
+				results[i] = e
+				continue
+			} else if _, err := txrepo.findDeletedEntityByContent(ctx, input.Asset); err == nil {
+				e, err := txrepo.CreateEntity(ctx, input)
+				if err != nil {
+					return err
+				}
+				results[i] = e
+				continue
+			}
+
+			jsonContent, err := sql.marshalAsset(input.Asset)
+			if err != nil {
+				return err
+			}
+
+			row := &Entity{
+				Type:        string(input.Asset.AssetType()),
+				Content:     jsonContent,
+				ContentHash: types.ContentHash(input.Asset),
+				CreatedAt:   time.Now().UTC(),
+				UpdatedAt:   time.Now().UTC(),
+			}
+			if !input.CreatedAt.IsZero() {
+				row.CreatedAt = input.CreatedAt.UTC()
+			}
+			if !input.LastSeen.IsZero() {
+				row.UpdatedAt = input.LastSeen.UTC()
+			}
+
+			seen[key] = len(rows)
+			rows = append(rows, row)
+			positions = append(positions, []int{i})
+		}
+
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(rows, sql.batchSize).Error; err != nil {
+			return err
+		}
+
+		for idx, row := range rows {
+			for _, i := range positions[idx] {
+				results[i] = &types.Entity{
+					ID:        strconv.FormatUint(row.ID, 10),
+					CreatedAt: row.CreatedAt.In(time.UTC).Local(),
+					LastSeen:  row.UpdatedAt.In(time.UTC).Local(),
+					Asset:     inputs[i].Asset,
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// UpsertEntity creates the entity in the database, or updates the last seen time of the existing entity
+// with the same asset type and identifying content, along with its content when the asset differs.
+// The insert is performed with an ON CONFLICT clause, so that an entity created concurrently by another
+// writer is not duplicated.
+// Returns the entity as a types.Entity, the outcome of the upsert, or an error if the upsert fails.
+func (sql *sqlRepository) UpsertEntity(ctx context.Context, input *types.Entity) (*types.Entity, types.UpsertResult, error) {
+	if input == nil || input.Asset == nil {
+		return nil, "", errors.New("failed input validation checks")
+	}
+	if err := sql.validateAsset(input.Asset); err != nil {
+		return nil, "", err
+	}
+
+	jsonContent, err := sql.marshalAsset(input.Asset)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var result types.UpsertResult
+	var entity *types.Entity
+	// the upsert is idempotent, so the entire transaction can be retried
+	err = sql.retry(ctx, func() error {
+		return sql.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			txrepo := *sql
+			txrepo.db = tx
+			txrepo.intx = true
+
+			if entities, err := txrepo.FindEntitiesByContent(ctx, input.Asset, time.Time{}); err == nil && len(entities) > 0 {
+				entity, result, err = txrepo.refreshEntity(ctx, entities[0], input.Asset, jsonContent)
+				return err
+			} else if _, err := txrepo.findDeletedEntityByContent(ctx, input.Asset); err == nil {
+				// a restored entity is not reported as newly created
+				result = types.UpsertUpdated
+				entity, err = txrepo.CreateEntity(ctx, &types.Entity{Asset: input.Asset})
+				return err
+			}
+
+			row := Entity{
+				Type:        string(input.Asset.AssetType()),
+				Content:     jsonContent,
+				ContentHash: types.ContentHash(input.Asset),
+				CreatedAt:   time.Now().UTC(),
+				UpdatedAt:   time.Now().UTC(),
+			}
+			if !input.CreatedAt.IsZero() {
+				row.CreatedAt = input.CreatedAt.UTC()
+			}
+			if !input.LastSeen.IsZero() {
+				row.UpdatedAt = input.LastSeen.UTC()
+			}
+
+			insert := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+			if err := insert.Error; err != nil {
+				return err
+			}
+
+			if insert.RowsAffected == 0 {
+				// the entity was created by another writer after the lookup
+				entities, err := txrepo.FindEntitiesByContent(ctx, input.Asset, time.Time{})
+				if err != nil {
+					return err
+				}
+				entity, result, err = txrepo.refreshEntity(ctx, entities[0], input.Asset, jsonContent)
+				return err
+			}
+
+			result = types.UpsertCreated
+			entity = &types.Entity{
+				ID:        strconv.FormatUint(row.ID, 10),
+				CreatedAt: row.CreatedAt.In(time.UTC).Local(),
+				LastSeen:  row.UpdatedAt.In(time.UTC).Local(),
+				Asset:     input.Asset,
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return entity, result, nil
+}
+
+// refreshEntity updates the last seen time of the existing entity matched by an upsert, and replaces
+// its content when the stored content differs from the JSON content of the asset.
+func (sql *sqlRepository) refreshEntity(ctx context.Context, existing *types.Entity, asset oam.Asset, content []byte) (*types.Entity, types.UpsertResult, error) {
+	if stored, err := sql.marshalAsset(existing.Asset); err == nil && jsoncmp.Equal(stored, content) {
+		entity, err := sql.touchEntity(ctx, existing)
+		if err != nil {
+			return nil, "", err
+		}
+		return entity, types.UpsertUnchanged, nil
+	}
+
+	entity, err := sql.CreateEntity(ctx, &types.Entity{Asset: asset})
+	if err != nil {
+		return nil, "", err
+	}
+	return entity, types.UpsertUpdated, nil
+}
+
+// TouchEntity sets the last seen time of the entity in the database to the current time,
+// which records that the asset was observed again without creating a duplicate entity or tag.
+// Returns an error matching types.ErrNotFound if the entity is not found, or an error if the update fails.
+func (sql *sqlRepository) TouchEntity(ctx context.Context, id string) error {
+	entityId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		// an invalid ID cannot match an entity
+		return types.NotFound("entity not found")
+	}
+
+	var affected int64
+	if err := sql.retry(ctx, func() error {
+		result := sql.db.WithContext(ctx).Model(&Entity{}).Where("entity_id = ?", entityId).UpdateColumn("updated_at", time.Now().UTC())
+		affected = result.RowsAffected
+		return result.Error
+	}); err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return types.NotFound("entity not found")
+	}
+	return nil
+}
+
+// UpdateEntity replaces the asset of the entity in the database, such as when an enrichment adds fields to its content.
+// The content is serialized again, the content hash is recomputed, and the last seen time is set to the current time,
+// while the ID, the creation time, and the edges and tags of the entity are kept. The asset must be of the asset type
+// of the entity, and an asset held by another entity, including a soft-deleted one, returns an error matching
+// types.ErrDuplicate. Returns an error matching types.ErrNotFound if the entity is not found.
+func (sql *sqlRepository) UpdateEntity(ctx context.Context, id string, asset oam.Asset) (*types.Entity, error) {
+	if asset == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+	if err := sql.validateAsset(asset); err != nil {
+		return nil, err
+	}
+
+	entityId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		// an invalid ID cannot match an entity
+		return nil, types.ErrEntityNotFound
+	}
+
+	jsonContent, err := sql.marshalAsset(asset)
+	if err != nil {
+		return nil, err
+	}
+
+	var row Entity
+	err = sql.WithTransaction(ctx, func(r types.Repository) error {
+		txrepo := r.(*sqlRepository)
+		tx := txrepo.db.WithContext(ctx)
+
+		if err := tx.Where("entity_id = ?", entityId).First(&row).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return types.ErrEntityNotFound
+		} else if err != nil {
+			return err
+		}
+		if row.Type != string(asset.AssetType()) {
+			return errors.New("the asset type does not match the existing entity")
+		}
+
+		if entities, err := txrepo.FindEntitiesByContent(ctx, asset, time.Time{}); err == nil {
+			for _, e := range entities {
+				if e.ID != id {
+					return types.Duplicate("the asset already exists with another entity ID")
+				}
+			}
+		} else if !errors.Is(err, types.ErrNotFound) {
+			return err
+		}
+		if e, err := txrepo.findDeletedEntityByContent(ctx, asset); err == nil && e.ID != entityId {
+			return types.Duplicate("the asset already exists with another entity ID")
+		}
+
+		row.Content = jsonContent
+		row.ContentHash = types.ContentHash(asset)
+		row.UpdatedAt = time.Now().UTC()
+		return tx.Model(&row).Updates(map[string]interface{}{
+			"content":      row.Content,
+			"content_hash": row.ContentHash,
+			"updated_at":   row.UpdatedAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Entity{
+		ID:        id,
+		CreatedAt: row.CreatedAt.In(time.UTC).Local(),
+		LastSeen:  row.UpdatedAt.In(time.UTC).Local(),
+		Asset:     asset,
+	}, nil
+}
+
+// touchEntity sets the last seen time of the provided entity to the current time.
+func (sql *sqlRepository) touchEntity(ctx context.Context, entity *types.Entity) (*types.Entity, error) {
+	entityId, err := strconv.ParseUint(entity.ID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	// the column is set directly, since GORM would otherwise overwrite the value of UpdatedAt
+	result := sql.db.WithContext(ctx).Model(&Entity{ID: entityId}).UpdateColumn("updated_at", now)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+
+	entity.LastSeen = now.Local()
+	return entity, nil
 }
 
 // FindEntityById finds an entity in the database by the ID.
 // It takes a string representing the entity ID and retrieves the corresponding entity from the database.
-// Returns the found entity as a types.Entity or an error if the asset is not found.
-func (sql *sqlRepository) FindEntityById(id string) (*types.Entity, error) {
+// Returns the found entity as a types.Entity, an error matching types.ErrNotFound if the entity is not found,
+// including when the ID is not valid, or the error of the query when the lookup fails.
+func (sql *sqlRepository) FindEntityById(ctx context.Context, id string) (*types.Entity, error) {
 	entityId, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		return nil, err
+		// an invalid ID cannot match an entity
+		return nil, types.NotFound("entity not found")
 	}
 
 	entity := Entity{ID: entityId}
-	result := sql.db.First(&entity)
-	if err := result.Error; err != nil {
+	if err := sql.retry(ctx, func() error {
+		return sql.db.WithContext(ctx).First(&entity).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	assetData, err := entity.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Entity{
+		ID:        strconv.FormatUint(entity.ID, 10),
+		CreatedAt: entity.CreatedAt.In(time.UTC).Local(),
+		LastSeen:  entity.UpdatedAt.In(time.UTC).Local(),
+		Asset:     assetData,
+	}, nil
+}
+
+// FindEntityByHash finds the entity in the database with the provided content hash, as returned by types.ContentHash.
+// The lookup uses the indexed content_hash column, so it does not depend on the content indexes of the asset type.
+// Returns the found entity as a types.Entity, an error matching types.ErrNotFound if no entity has the hash,
+// or the error of the query when the lookup fails.
+func (sql *sqlRepository) FindEntityByHash(ctx context.Context, hash string) (*types.Entity, error) {
+	if hash == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	var entity Entity
+	if err := sql.retry(ctx, func() error {
+		return sql.db.WithContext(ctx).Where("content_hash = ?", hash).Order("entity_id").First(&entity).Error
+	}); err != nil {
 		return nil, err
 	}
 
@@ -114,13 +498,48 @@ func (sql *sqlRepository) FindEntityById(id string) (*types.Entity, error) {
 	}, nil
 }
 
+// EntityExists reports whether the database holds an entity that matches the provided asset data, as matched by
+// FindEntitiesByContent. The query selects a constant from at most one row, so the content of the entity is neither
+// read nor parsed. Returns false, without an error, if no entity matches the asset.
+func (sql *sqlRepository) EntityExists(ctx context.Context, assetData oam.Asset) (bool, error) {
+	if assetData == nil {
+		return false, errors.New("failed input validation checks")
+	}
+
+	jsonContent, err := sql.marshalAsset(assetData)
+	if err != nil {
+		return false, err
+	}
+
+	entity := Entity{
+		Type:    string(assetData.AssetType()),
+		Content: jsonContent,
+	}
+
+	jsonQuery, err := entity.JSONQuery()
+	if err != nil {
+		return false, err
+	}
+
+	var hits []int
+	tx := sql.db.WithContext(ctx).Model(&Entity{}).Select("1").
+		Where("etype = ?", entity.Type).Where(jsonQuery).Limit(1).Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		hits = nil
+		return tx.Scan(&hits).Error
+	}); err != nil {
+		return false, err
+	}
+	return len(hits) > 0, nil
+}
+
 // FindEntitiesByContent finds entities in the database that match the provided asset data and last seen after
 // the since parameter. It takes an oam.Asset as input and searches for entities with matching content in the database.
 // If since.IsZero(), the parameter will be ignored.
 // The asset data is serialized to JSON and compared against the Content field of the Entity struct.
 // Returns a slice of matching entities as []*types.Entity or an error if the search fails.
-func (sql *sqlRepository) FindEntitiesByContent(assetData oam.Asset, since time.Time) ([]*types.Entity, error) {
-	jsonContent, err := assetData.JSON()
+func (sql *sqlRepository) FindEntitiesByContent(ctx context.Context, assetData oam.Asset, since time.Time) ([]*types.Entity, error) {
+	jsonContent, err := sql.marshalAsset(assetData)
 	if err != nil {
 		return nil, err
 	}
@@ -135,14 +554,16 @@ func (sql *sqlRepository) FindEntitiesByContent(assetData oam.Asset, since time.
 		return nil, err
 	}
 
-	tx := sql.db.Where("etype = ?", entity.Type)
+	tx := sql.db.WithContext(ctx).Where("etype = ?", entity.Type)
 	if !since.IsZero() {
 		tx = tx.Where("updated_at >= ?", since.UTC())
 	}
 
 	var entities []Entity
-	tx = tx.Where(jsonQuery).Find(&entities)
-	if err := tx.Error; err != nil {
+	tx = tx.Where(jsonQuery).Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&entities).Error
+	}); err != nil {
 		return nil, err
 	}
 
@@ -159,7 +580,86 @@ func (sql *sqlRepository) FindEntitiesByContent(assetData oam.Asset, since time.
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero entities found")
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// FindEntitiesByContents finds the entities in the database that match the provided assets and were last seen after the since parameter.
+// The assets are matched in batches by a single query each, rather than a round trip per asset.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the matching entities keyed by the types.ContentHash of the asset, where the assets without a matching
+// entity are absent from the map, or an error if the search fails.
+func (sql *sqlRepository) FindEntitiesByContents(ctx context.Context, assets []oam.Asset, since time.Time) (map[string]*types.Entity, error) {
+	seen := make(map[string]struct{}, len(assets))
+	conds := make([]*gorm.DB, 0, len(assets))
+
+	for _, asset := range assets {
+		if asset == nil {
+			return nil, errors.New("failed input validation checks")
+		}
+
+		key := types.ContentHash(asset)
+		if _, found := seen[key]; found {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		jsonContent, err := sql.marshalAsset(asset)
+		if err != nil {
+			return nil, err
+		}
+
+		entity := Entity{
+			Type:    string(asset.AssetType()),
+			Content: jsonContent,
+		}
+
+		jsonQuery, err := entity.JSONQuery()
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, sql.db.Where("etype = ?", entity.Type).Where(jsonQuery))
+	}
+
+	results := make(map[string]*types.Entity, len(conds))
+	for start := 0; start < len(conds); start += sql.batchSize {
+		end := min(start+sql.batchSize, len(conds))
+
+		match := sql.db.Where(conds[start])
+		for _, cond := range conds[start+1 : end] {
+			match = match.Or(cond)
+		}
+
+		tx := sql.db.WithContext(ctx).Where(match)
+		if !since.IsZero() {
+			tx = tx.Where("updated_at >= ?", since.UTC())
+		}
+
+		var entities []Entity
+		tx = tx.Order("entity_id").Session(&gorm.Session{})
+		if err := sql.retry(ctx, func() error {
+			return tx.Find(&entities).Error
+		}); err != nil {
+			return nil, err
+		}
+
+		for _, e := range entities {
+			assetData, err := e.Parse()
+			if err != nil {
+				continue
+			}
+
+			key := types.ContentHash(assetData)
+			if _, found := results[key]; !found {
+				results[key] = &types.Entity{
+					ID:        strconv.FormatUint(e.ID, 10),
+					CreatedAt: e.CreatedAt.In(time.UTC).Local(),
+					LastSeen:  e.UpdatedAt.In(time.UTC).Local(),
+					Asset:     assetData,
+				}
+			}
+		}
 	}
 	return results, nil
 }
@@ -168,16 +668,167 @@ func (sql *sqlRepository) FindEntitiesByContent(assetData oam.Asset, since time.
 // It takes an asset type and retrieves the corresponding entities from the database.
 // If since.IsZero(), the parameter will be ignored.
 // Returns a slice of matching entities as []*types.Entity or an error if the search fails.
-func (sql *sqlRepository) FindEntitiesByType(atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+func (sql *sqlRepository) FindEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	return sql.FindEntitiesByTypeBetween(ctx, atype, since, time.Time{})
+}
+
+// FindEntitiesByTypes finds all entities in the database of the provided asset types and last seen after the since parameter,
+// with a single query. The entities are ordered by their creation time, with ties broken by the entity ID, so the entities of
+// the different types are interleaved in a stable order. If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching entities as []*types.Entity or an error if no asset types are provided or the search fails.
+func (sql *sqlRepository) FindEntitiesByTypes(ctx context.Context, since time.Time, atypes ...oam.AssetType) ([]*types.Entity, error) {
+	if len(atypes) == 0 {
+		return nil, errors.New("failed input validation checks")
+	}
+
 	var entities []Entity
-	var result *gorm.DB
+	tx := seenBetween(sql.db.WithContext(ctx).Where("etype IN ?", atypes), since, time.Time{})
+	tx = tx.Order("created_at").Order("entity_id").Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&entities).Error
+	}); err != nil {
+		return nil, err
+	}
 
-	if since.IsZero() {
-		result = sql.db.Where("etype = ?", atype).Find(&entities)
-	} else {
-		result = sql.db.Where("etype = ? AND updated_at >= ?", atype, since.UTC()).Find(&entities)
+	var results []*types.Entity
+	for _, e := range entities {
+		if f, err := e.Parse(); err == nil {
+			results = append(results, &types.Entity{
+				ID:        strconv.FormatUint(e.ID, 10),
+				CreatedAt: e.CreatedAt.In(time.UTC).Local(),
+				LastSeen:  e.UpdatedAt.In(time.UTC).Local(),
+				Asset:     f,
+			})
+		}
 	}
-	if err := result.Error; err != nil {
+
+	if len(results) == 0 {
+		return nil, types.NotFound("no entities of the specified types")
+	}
+	return results, nil
+}
+
+// FindEntitiesByTypeBetween finds all entities in the database of the provided asset type and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// The entities are sorted as set by options.WithOrder, or the most recently seen entities are returned first
+// when the repository was opened with options.WithLastSeenOrder.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (sql *sqlRepository) FindEntitiesByTypeBetween(ctx context.Context, atype oam.AssetType, from, to time.Time) ([]*types.Entity, error) {
+	tx := seenBetween(sql.db.WithContext(ctx).Where("etype = ?", atype), from, to)
+	if sql.order != "" {
+		tx = tx.Order(sql.order)
+	} else if sql.lastSeen {
+		tx = tx.Order("updated_at DESC")
+	}
+
+	var entities []Entity
+	tx = tx.Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&entities).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, e := range entities {
+		if f, err := e.Parse(); err == nil {
+			results = append(results, &types.Entity{
+				ID:        strconv.FormatUint(e.ID, 10),
+				CreatedAt: e.CreatedAt.In(time.UTC).Local(),
+				LastSeen:  e.UpdatedAt.In(time.UTC).Local(),
+				Asset:     f,
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("no entities of the specified type")
+	}
+	return results, nil
+}
+
+// DiffEntities compares the entities in the database of the provided asset type at the t1 and t2 snapshots, such as
+// the start times of two scans. The added entities were first seen within the [t1, t2) window, and the removed entities
+// were created before t1 and last seen within the window, so they have not been seen since t2.
+// Returns the added and removed entities, or an error if t2 is not after t1 or the search fails.
+func (sql *sqlRepository) DiffEntities(ctx context.Context, atype oam.AssetType, t1, t2 time.Time) ([]*types.Entity, []*types.Entity, error) {
+	if !t2.After(t1) {
+		return nil, nil, errors.New("failed input validation checks")
+	}
+	from, to := t1.UTC(), t2.UTC()
+
+	var entities []Entity
+	tx := sql.db.WithContext(ctx).Where("etype = ? AND updated_at >= ?", atype, from).
+		Where(sql.db.Where("created_at >= ? AND created_at < ?", from, to).Or("created_at < ? AND updated_at < ?", from, to)).
+		Order("entity_id").Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&entities).Error
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	var added, removed []*types.Entity
+	for _, e := range entities {
+		f, err := e.Parse()
+		if err != nil {
+			continue
+		}
+
+		entity := &types.Entity{
+			ID:        strconv.FormatUint(e.ID, 10),
+			CreatedAt: e.CreatedAt.In(time.UTC).Local(),
+			LastSeen:  e.UpdatedAt.In(time.UTC).Local(),
+			Asset:     f,
+		}
+		if e.CreatedAt.Before(from) {
+			removed = append(removed, entity)
+		} else {
+			added = append(added, entity)
+		}
+	}
+	return added, removed, nil
+}
+
+// seenBetween limits the query to the rows last seen within the [from, to) window.
+// A zero from or to leaves that side of the window open.
+func seenBetween(tx *gorm.DB, from, to time.Time) *gorm.DB {
+	if !from.IsZero() {
+		tx = tx.Where("updated_at >= ?", from.UTC())
+	}
+	if !to.IsZero() {
+		tx = tx.Where("updated_at < ?", to.UTC())
+	}
+	return tx
+}
+
+// SearchEntities finds the entities in the database of the provided asset type and last seen after the since parameter,
+// which contain the query string within their serialized content. If since.IsZero(), the parameter will be ignored.
+// The LIKE wildcard characters in the query are escaped, so the query is matched literally.
+// The search cannot make use of the content indexes, so it is slower than FindEntitiesByContent.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (sql *sqlRepository) SearchEntities(ctx context.Context, atype oam.AssetType, query string, since time.Time) ([]*types.Entity, error) {
+	if query == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	content := "content"
+	switch sql.dbtype {
+	case Postgres:
+		content = "content::text"
+	case MySQL:
+		content = "CAST(content AS CHAR)"
+	}
+
+	tx := sql.db.WithContext(ctx).Where("etype = ?", atype)
+	if !since.IsZero() {
+		tx = tx.Where("updated_at >= ?", since.UTC())
+	}
+
+	var entities []Entity
+	tx = tx.Where(content+" LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(query)+"%").Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&entities).Error
+	}); err != nil {
 		return nil, err
 	}
 
@@ -194,21 +845,421 @@ func (sql *sqlRepository) FindEntitiesByType(atype oam.AssetType, since time.Tim
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("no entities of the specified type")
+		return nil, types.NotFound("zero entities found")
 	}
 	return results, nil
 }
 
+// likeEscaper escapes the LIKE wildcard characters, using the escape character that works across the supported dialects.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// FindEntitiesByTypePaged finds a page of entities in the database of the provided asset type and last seen after
+// the since parameter. The entities are ordered by creation time and then by ID, so that pages remain stable.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the matching entities within the page, the total number of matching entities, or an error if the search fails.
+func (sql *sqlRepository) FindEntitiesByTypePaged(ctx context.Context, atype oam.AssetType, since time.Time, offset, limit int) ([]*types.Entity, int64, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, 0, errors.New("failed input validation checks")
+	}
+
+	tx := sql.db.WithContext(ctx).Model(&Entity{}).Where("etype = ?", atype)
+	if !since.IsZero() {
+		tx = tx.Where("updated_at >= ?", since.UTC())
+	}
+	tx = tx.Session(&gorm.Session{})
+
+	var total int64
+	if err := sql.retry(ctx, func() error {
+		return tx.Count(&total).Error
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	var entities []Entity
+	if err := sql.retry(ctx, func() error {
+		return tx.Order("created_at, entity_id").Offset(offset).Limit(limit).Find(&entities).Error
+	}); err != nil {
+		return nil, total, err
+	}
+
+	var results []*types.Entity
+	for _, e := range entities {
+		if f, err := e.Parse(); err == nil {
+			results = append(results, &types.Entity{
+				ID:        strconv.FormatUint(e.ID, 10),
+				CreatedAt: e.CreatedAt.In(time.UTC).Local(),
+				LastSeen:  e.UpdatedAt.In(time.UTC).Local(),
+				Asset:     f,
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, total, types.NotFound("no entities of the specified type")
+	}
+	return results, total, nil
+}
+
+// CountEntitiesByType counts the entities in the database of the provided asset type and last seen after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// Returns the number of matching entities or an error if the count fails.
+func (sql *sqlRepository) CountEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (int64, error) {
+	tx := sql.db.WithContext(ctx).Model(&Entity{}).Where("etype = ?", atype)
+	if !since.IsZero() {
+		tx = tx.Where("updated_at >= ?", since.UTC())
+	}
+	tx = tx.Session(&gorm.Session{})
+
+	var total int64
+	if err := sql.retry(ctx, func() error {
+		return tx.Count(&total).Error
+	}); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// CountEntitiesGrouped counts the entities in the database of each asset type and last seen after the since parameter,
+// with a single query grouped by the asset type. If since.IsZero(), the parameter will be ignored.
+// Returns the number of matching entities keyed by the asset type, where the types without entities are absent
+// from the map, or an error if the count fails.
+func (sql *sqlRepository) CountEntitiesGrouped(ctx context.Context, since time.Time) (map[oam.AssetType]int64, error) {
+	tx := sql.db.WithContext(ctx).Model(&Entity{}).Select("etype, count(*) AS total").Group("etype")
+	if !since.IsZero() {
+		tx = tx.Where("updated_at >= ?", since.UTC())
+	}
+	tx = tx.Session(&gorm.Session{})
+
+	var rows []struct {
+		Etype string
+		Total int64
+	}
+	if err := sql.retry(ctx, func() error {
+		rows = nil
+		return tx.Scan(&rows).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[oam.AssetType]int64, len(rows))
+	for _, r := range rows {
+		counts[oam.AssetType(r.Etype)] = r.Total
+	}
+	return counts, nil
+}
+
 // DeleteEntity removes an entity in the database by its ID.
 // It takes a string representing the entity ID and removes the corresponding entity from the database.
-// Returns an error if the entity is not found.
-func (sql *sqlRepository) DeleteEntity(id string) error {
+// Returns the number of rows removed, which is zero when the entity is not found.
+func (sql *sqlRepository) DeleteEntity(ctx context.Context, id string) (int64, error) {
 	entityId, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	entity := Entity{ID: entityId}
-	result := sql.db.Delete(&entity)
+	tx := sql.db.WithContext(ctx).Model(&Entity{ID: entityId})
+	if sql.softDelete {
+		// the timestamp is set directly, so that the last seen time of the entity is preserved,
+		// and entities that were already deleted are excluded by the scope of the model
+		result := tx.UpdateColumn("deleted_at", time.Now().UTC())
+		return result.RowsAffected, result.Error
+	}
+
+	result := tx.Unscoped().Delete(&Entity{ID: entityId})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteEntityCascade permanently removes an entity in the database by its ID, along with its incoming and
+// outgoing edges, its tags, and the tags of its edges, within a single transaction. Unlike DeleteEntity,
+// the entity is removed even when the soft-delete mode is enabled, and the dependent rows are deleted
+// explicitly rather than by the foreign keys of the schema, which a SQLite DSN may not enforce.
+// Returns the number of rows removed from all the tables, which is zero when the entity is not found.
+func (sql *sqlRepository) DeleteEntityCascade(ctx context.Context, id string) (int64, error) {
+	entityId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	err = sql.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		total = 0
+		for _, stmt := range []string{
+			"DELETE FROM edge_tags WHERE workspace = @ws AND edge_id IN " +
+				"(SELECT edge_id FROM edges WHERE from_entity_id = @id OR to_entity_id = @id)",
+			"DELETE FROM edges WHERE workspace = @ws AND (from_entity_id = @id OR to_entity_id = @id)",
+			"DELETE FROM entity_tags WHERE workspace = @ws AND entity_id = @id",
+			"DELETE FROM entities WHERE workspace = @ws AND entity_id = @id",
+		} {
+			result := tx.Exec(sql.prefixed(stmt), map[string]interface{}{"id": entityId, "ws": sql.workspace})
+			if result.Error != nil {
+				return result.Error
+			}
+			total += result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return total, nil
+}
+
+// DeleteEntitiesByType permanently removes the entities in the database of the provided asset type that were last seen
+// before the before parameter, along with their edges, their tags, and the tags of their edges, within a single transaction.
+// If before.IsZero(), the parameter will be ignored. The soft-deleted entities are left to PurgeDeleted.
+// Returns the number of entities deleted.
+func (sql *sqlRepository) DeleteEntitiesByType(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	condition := "workspace = @ws AND etype = @type AND deleted_at IS NULL"
+	if !before.IsZero() {
+		condition += " AND updated_at < @before"
+	}
+	params := map[string]interface{}{"ws": sql.workspace, "type": string(atype), "before": before.UTC()}
+
+	var total int64
+	err := sql.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range deleteByTypeStatements(condition) {
+			result := tx.Exec(sql.prefixed(stmt), params)
+			if result.Error != nil {
+				return result.Error
+			}
+			total = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return total, nil
+}
+
+// deleteByTypeStatements returns the statements of DeleteEntitiesByType, which remove the entities that match the condition
+// along with their edges and tags. The entities are deleted by the condition itself rather than by a subquery, since MySQL
+// rejects a DELETE whose subquery reads the table being deleted from.
+func deleteByTypeStatements(condition string) []string {
+	entities := "SELECT entity_id FROM entities WHERE " + condition
+	return []string{
+		"DELETE FROM edge_tags WHERE edge_id IN (SELECT edge_id FROM edges WHERE from_entity_id IN (" +
+			entities + ") OR to_entity_id IN (" + entities + "))",
+		"DELETE FROM edges WHERE from_entity_id IN (" + entities + ") OR to_entity_id IN (" + entities + ")",
+		"DELETE FROM entity_tags WHERE entity_id IN (" + entities + ")",
+		"DELETE FROM entities WHERE " + condition,
+	}
+}
+
+// orphanCondition limits the entities to those without edges. The edges attached to soft-deleted entities are counted,
+// since the entities they refer to can be restored.
+const orphanCondition = "NOT EXISTS (SELECT 1 FROM edges WHERE edges.from_entity_id = entities.entity_id) AND " +
+	"NOT EXISTS (SELECT 1 FROM edges WHERE edges.to_entity_id = entities.entity_id)"
+
+// FindOrphanEntities finds the entities in the database of the provided asset type and last seen after the since
+// parameter that have no incoming or outgoing edges. An edge to a soft-deleted entity keeps the entity from being
+// an orphan, since the entity it refers to can be restored. If since.IsZero(), the parameter will be ignored.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (sql *sqlRepository) FindOrphanEntities(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	tx := sql.db.WithContext(ctx).Where("etype = ?", atype).Where(sql.prefixed(orphanCondition))
+	if !since.IsZero() {
+		tx = tx.Where("updated_at >= ?", since.UTC())
+	}
+
+	var entities []Entity
+	tx = tx.Order("entity_id").Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&entities).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, e := range entities {
+		if f, err := e.Parse(); err == nil {
+			results = append(results, &types.Entity{
+				ID:        strconv.FormatUint(e.ID, 10),
+				CreatedAt: e.CreatedAt.In(time.UTC).Local(),
+				LastSeen:  e.UpdatedAt.In(time.UTC).Local(),
+				Asset:     f,
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("no orphan entities of the specified type")
+	}
+	return results, nil
+}
+
+// DeleteOrphanEntities permanently removes the entities in the database of the provided asset type that were last seen
+// before the before parameter and have no incoming or outgoing edges, as found by FindOrphanEntities, along with their tags,
+// within a single transaction. If before.IsZero(), the parameter will be ignored. The soft-deleted entities are left to
+// PurgeDeleted. Returns the number of entities deleted.
+func (sql *sqlRepository) DeleteOrphanEntities(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	entities := "SELECT entity_id FROM entities WHERE workspace = @ws AND etype = @type AND deleted_at IS NULL AND " + orphanCondition
+	if !before.IsZero() {
+		entities += " AND updated_at < @before"
+	}
+	params := map[string]interface{}{"ws": sql.workspace, "type": string(atype), "before": before.UTC()}
+
+	var total int64
+	err := sql.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// the orphans are collected before the deletes, since MySQL rejects a DELETE whose subquery
+		// reads the table being deleted from
+		var ids []uint64
+		if err := tx.Raw(sql.prefixed(entities), params).Scan(&ids).Error; err != nil {
+			return err
+		}
+
+		for start := 0; start < len(ids); start += sql.batchSize {
+			end := min(start+sql.batchSize, len(ids))
+
+			if err := tx.Where("entity_id IN ?", ids[start:end]).Delete(&EntityTag{}).Error; err != nil {
+				return err
+			}
+			result := tx.Unscoped().Where("entity_id IN ?", ids[start:end]).Delete(&Entity{})
+			if result.Error != nil {
+				return result.Error
+			}
+			total += result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return total, nil
+}
+
+// FindDeletedEntities finds the soft-deleted entities in the database that were deleted after the since parameter.
+// If since.IsZero(), the parameter will be ignored.
+// The entities are ordered by the time of deletion, and the DeletedAt field is set on each of them.
+// Returns a slice of matching entities as []*types.Entity or an error if the search fails.
+func (sql *sqlRepository) FindDeletedEntities(ctx context.Context, since time.Time) ([]*types.Entity, error) {
+	tx := sql.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL")
+	if !since.IsZero() {
+		tx = tx.Where("deleted_at >= ?", since.UTC())
+	}
+
+	tx = tx.Session(&gorm.Session{})
+
+	var entities []Entity
+	if err := sql.retry(ctx, func() error {
+		return tx.Order("deleted_at, entity_id").Find(&entities).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, e := range entities {
+		if f, err := e.Parse(); err == nil {
+			results = append(results, &types.Entity{
+				ID:        strconv.FormatUint(e.ID, 10),
+				CreatedAt: e.CreatedAt.In(time.UTC).Local(),
+				LastSeen:  e.UpdatedAt.In(time.UTC).Local(),
+				DeletedAt: e.DeletedAt.Time.In(time.UTC).Local(),
+				Asset:     f,
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// PurgeDeleted permanently removes the soft-deleted entities in the database that were deleted before the before parameter.
+// If before.IsZero(), all the soft-deleted entities are removed.
+// Returns an error if the removal fails.
+func (sql *sqlRepository) PurgeDeleted(ctx context.Context, before time.Time) error {
+	tx := sql.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL")
+	if !before.IsZero() {
+		tx = tx.Where("deleted_at < ?", before.UTC())
+	}
+
+	result := tx.Delete(&Entity{})
 	return result.Error
 }
+
+// findDeletedEntityByContent finds the soft-deleted entity in the database that matches the provided asset data.
+func (sql *sqlRepository) findDeletedEntityByContent(ctx context.Context, assetData oam.Asset) (*Entity, error) {
+	if !sql.softDelete {
+		return nil, errors.New("the soft-delete mode is not enabled")
+	}
+
+	jsonContent, err := sql.marshalAsset(assetData)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := Entity{
+		Type:    string(assetData.AssetType()),
+		Content: jsonContent,
+	}
+
+	jsonQuery, err := entity.JSONQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	var e Entity
+	result := sql.db.WithContext(ctx).Unscoped().Where("etype = ? AND deleted_at IS NOT NULL", entity.Type).Where(jsonQuery).First(&e)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// checkEntityID returns an error matching types.ErrDuplicate when the ID provided by the caller is held by an entity
+// of another asset or another workspace, or when the asset already exists with another ID, so the save neither
+// overwrites nor duplicates an entity.
+func (sql *sqlRepository) checkEntityID(ctx context.Context, id uint64, asset oam.Asset) error {
+	// the raw statement is not limited to the workspace, since the save would otherwise overwrite the entity of
+	// another workspace that the scoped query below does not find
+	var owners []string
+	if err := sql.db.WithContext(ctx).Raw(sql.prefixed("SELECT workspace FROM entities WHERE entity_id = ?"), id).
+		Scan(&owners).Error; err != nil {
+		return err
+	}
+	if len(owners) > 0 && owners[0] != sql.workspace {
+		return types.Duplicate("the entity ID is held by another workspace")
+	}
+
+	var rows []Entity
+	if err := sql.db.WithContext(ctx).Unscoped().Where("entity_id = ?", id).Limit(1).Find(&rows).Error; err != nil {
+		return err
+	}
+
+	if len(rows) > 0 {
+		existing, err := rows[0].Parse()
+		if err != nil {
+			return err
+		}
+		if existing.AssetType() != asset.AssetType() || existing.Key() != asset.Key() {
+			return types.Duplicate("the entity ID is held by another asset")
+		}
+		return nil
+	}
+
+	if entities, err := sql.FindEntitiesByContent(ctx, asset, time.Time{}); err == nil && len(entities) > 0 {
+		return types.Duplicate("the asset already exists with another entity ID")
+	} else if _, err := sql.findDeletedEntityByContent(ctx, asset); err == nil {
+		return types.Duplicate("the asset already exists with another entity ID")
+	}
+	return nil
+}
+
+// validateAsset returns the error of the validator provided by options.WithValidator, which rejects the asset
+// before it is written. The assets are accepted when no validator was provided.
+func (sql *sqlRepository) validateAsset(asset oam.Asset) error {
+	if sql.validate == nil || asset == nil {
+		return nil
+	}
+	return sql.validate(asset)
+}
+
+// marshalAsset serializes the asset to the JSON content stored with the entity, using the marshaler
+// provided by options.WithMarshaler, or the JSON method of the asset when none was provided.
+func (sql *sqlRepository) marshalAsset(asset oam.Asset) ([]byte, error) {
+	if sql.marshal != nil {
+		return sql.marshal(asset)
+	}
+	return asset.JSON()
+}
@@ -7,24 +7,31 @@
 package sqlrepo
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/netip"
 	"os"
+	"strconv"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/glebarez/sqlite"
+	mysqlmigrations "github.com/garthoid/asset-db/migrations/mysql"
 	pgmigrations "github.com/garthoid/asset-db/migrations/postgres"
 	sqlitemigrations "github.com/garthoid/asset-db/migrations/sqlite3"
+	"github.com/garthoid/asset-db/options"
 	"github.com/garthoid/asset-db/types"
 	oam "github.com/owasp-amass/open-asset-model"
 	"github.com/owasp-amass/open-asset-model/dns"
 	"github.com/owasp-amass/open-asset-model/general"
 	"github.com/owasp-amass/open-asset-model/network"
+	"github.com/owasp-amass/open-asset-model/org"
 	oamreg "github.com/owasp-amass/open-asset-model/registration"
 	migrate "github.com/rubenv/sql-migrate"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -118,6 +125,54 @@ func teardownPostgres(dsn string) {
 	}
 }
 
+func setupMySQL(dsn string) (*gorm.DB, error) {
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	migrationsSource := migrate.EmbedFileSystemMigrationSource{
+		FileSystem: mysqlmigrations.Migrations(),
+		Root:       "/",
+	}
+
+	sqlDb, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDb.Close()
+
+	_, err = migrate.Exec(sqlDb, "mysql", migrationsSource, migrate.Up)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func teardownMySQL(dsn string) {
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	if err != nil {
+		panic(err)
+	}
+
+	migrationsSource := migrate.EmbedFileSystemMigrationSource{
+		FileSystem: mysqlmigrations.Migrations(),
+		Root:       "/",
+	}
+
+	sqlDb, err := db.DB()
+	if err != nil {
+		panic(err)
+	}
+	defer sqlDb.Close()
+
+	_, err = migrate.Exec(sqlDb, "mysql", migrationsSource, migrate.Down)
+	if err != nil {
+		panic(err)
+	}
+}
+
 func TestMain(m *testing.M) {
 	user := "postgres"
 	if u, ok := os.LookupEnv("POSTGRES_USER"); ok {
@@ -134,6 +189,21 @@ func TestMain(m *testing.M) {
 		pgdbname = pdb
 	}
 
+	myuser := "mysql"
+	if u, ok := os.LookupEnv("MYSQL_USER"); ok {
+		myuser = u
+	}
+
+	mypassword := "mysql"
+	if p, ok := os.LookupEnv("MYSQL_PASSWORD"); ok {
+		mypassword = p
+	}
+
+	mydbname := "assetdb"
+	if mdb, ok := os.LookupEnv("MYSQL_DATABASE"); ok {
+		mydbname = mdb
+	}
+
 	sqlitedbname := "test.db"
 	if sdb, ok := os.LookupEnv("SQLITE3_DB"); ok {
 		sqlitedbname = sdb
@@ -146,6 +216,12 @@ func TestMain(m *testing.M) {
 			dsn:      fmt.Sprintf("host=localhost port=5432 user=%s password=%s dbname=%s", user, password, pgdbname),
 			teardown: teardownPostgres,
 		},
+		{
+			name:     MySQL,
+			setup:    setupMySQL,
+			dsn:      fmt.Sprintf("%s:%s@tcp(localhost:3306)/%s", myuser, mypassword, mydbname),
+			teardown: teardownMySQL,
+		},
 		{
 			name:     SQLite,
 			setup:    setupSqlite,
@@ -181,13 +257,13 @@ func TestMain(m *testing.M) {
 func TestLastSeenUpdates(t *testing.T) {
 	ip, _ := netip.ParseAddr("45.73.25.1")
 	asset := &network.IPAddress{Address: ip, Type: "IPv4"}
-	a1, err := store.CreateAsset(asset)
+	a1, err := store.CreateAsset(context.Background(), asset)
 	assert.NoError(t, err)
 
 	// Nanoseconds are truncated by the database, so we need to sleep for a bit.
 	time.Sleep(1000 * time.Millisecond)
 
-	a2, err := store.CreateAsset(asset)
+	a2, err := store.CreateAsset(context.Background(), asset)
 	assert.NoError(t, err)
 	assert.Equal(t, a1.ID, a2.ID)
 	assert.Equal(t, a1.CreatedAt, a2.CreatedAt)
@@ -244,11 +320,11 @@ func TestRepository(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.description, func(t *testing.T) {
-			sourceEntity, err := store.CreateAsset(tc.sourceAsset)
+			sourceEntity, err := store.CreateAsset(context.Background(), tc.sourceAsset)
 			assert.NoError(t, err)
 			assert.NotEqual(t, sourceEntity, nil)
 
-			foundAsset, err := store.FindEntityById(sourceEntity.ID)
+			foundAsset, err := store.FindEntityById(context.Background(), sourceEntity.ID)
 			assert.NoError(t, err)
 			assert.NotEqual(t, foundAsset, nil)
 
@@ -260,7 +336,7 @@ func TestRepository(t *testing.T) {
 				t.Fatalf("failed to find entity by id: expected entity %s, got %s", sourceEntity.Asset, foundAsset.Asset)
 			}
 
-			foundAssetByContent, err := store.FindEntitiesByContent(sourceEntity.Asset, start)
+			foundAssetByContent, err := store.FindEntitiesByContent(context.Background(), sourceEntity.Asset, start)
 			assert.NoError(t, err)
 			assert.NotEqual(t, foundAssetByContent, nil)
 
@@ -272,7 +348,7 @@ func TestRepository(t *testing.T) {
 				t.Fatalf("failed to find entity by content: expected entity %s, got %s", sourceEntity.Asset, foundAssetByContent[0].Asset)
 			}
 
-			foundEntityByType, err := store.FindEntitiesByType(sourceEntity.Asset.AssetType(), start)
+			foundEntityByType, err := store.FindEntitiesByType(context.Background(), sourceEntity.Asset.AssetType(), start)
 			if err != nil {
 				t.Fatalf("failed to find entity by type: %s", err)
 			}
@@ -303,7 +379,7 @@ func TestRepository(t *testing.T) {
 				t.Fatalf("failed to find entity by type: did not receive entity %s", sourceEntity.Asset)
 			}
 
-			destinationEntity, err := store.CreateAsset(tc.destinationAsset)
+			destinationEntity, err := store.CreateAsset(context.Background(), tc.destinationAsset)
 			assert.NoError(t, err)
 			assert.NotEqual(t, destinationEntity, nil)
 
@@ -313,11 +389,11 @@ func TestRepository(t *testing.T) {
 				ToEntity:   destinationEntity,
 			}
 
-			e, err := store.CreateEdge(edge)
+			e, err := store.CreateEdge(context.Background(), edge)
 			assert.NoError(t, err)
 			assert.NotEqual(t, e, nil)
 
-			incoming, err := store.IncomingEdges(destinationEntity, start, tc.relation.Label())
+			incoming, err := store.IncomingEdges(context.Background(), destinationEntity, start, tc.relation.Label())
 			assert.NoError(t, err)
 			assert.NotEqual(t, incoming, nil)
 
@@ -333,7 +409,7 @@ func TestRepository(t *testing.T) {
 				t.Fatalf("failed to query incoming edges: expected destination entity id %s, got %s", destinationEntity.ID, incoming[0].ToEntity.ID)
 			}
 
-			outgoing, err := store.OutgoingEdges(sourceEntity, start, tc.relation.Label())
+			outgoing, err := store.OutgoingEdges(context.Background(), sourceEntity, start, tc.relation.Label())
 			assert.NoError(t, err)
 			assert.NotEqual(t, outgoing, nil)
 
@@ -349,13 +425,20 @@ func TestRepository(t *testing.T) {
 				t.Fatalf("failed to query outgoing edges: expected destination entity id %s, got %s", destinationEntity.ID, outgoing[0].ToEntity.ID)
 			}
 
-			err = store.DeleteEdge(e.ID)
+			n, err := store.DeleteEdge(context.Background(), e.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), n)
+
+			n, err = store.DeleteEntity(context.Background(), destinationEntity.ID)
 			assert.NoError(t, err)
+			assert.Equal(t, int64(1), n)
 
-			err = store.DeleteEntity(destinationEntity.ID)
+			// deleting the entity again removes nothing
+			n, err = store.DeleteEntity(context.Background(), destinationEntity.ID)
 			assert.NoError(t, err)
+			assert.Equal(t, int64(0), n)
 
-			if _, err = store.FindEntityById(destinationEntity.ID); err == nil {
+			if _, err = store.FindEntityById(context.Background(), destinationEntity.ID); err == nil {
 				t.Fatal("failed to delete entity: the entity was not removed from the database")
 			}
 		})
@@ -374,3 +457,1088 @@ func TestGetDBType(t *testing.T) {
 		t.Errorf("Unexpected result. Expected: %s, Got: %s", expected, result)
 	}
 }
+
+func TestCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := store.CreateAsset(ctx, &dns.FQDN{Name: "canceled.example.com"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = store.FindEntitiesByType(ctx, oam.FQDN, time.Time{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCreateEntities(t *testing.T) {
+	existing, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "batch0.example.com"})
+	assert.NoError(t, err)
+
+	var inputs []*types.Entity
+	for i := 0; i < 250; i++ {
+		inputs = append(inputs, &types.Entity{Asset: &dns.FQDN{Name: fmt.Sprintf("batch%d.example.com", i)}})
+	}
+	// duplicates within the batch should share a single row
+	inputs = append(inputs, &types.Entity{Asset: &dns.FQDN{Name: "batch1.example.com"}})
+
+	entities, err := store.CreateEntities(context.Background(), inputs)
+	assert.NoError(t, err)
+	assert.Len(t, entities, len(inputs))
+
+	for i, e := range entities {
+		assert.NotEmpty(t, e.ID)
+		assert.Equal(t, inputs[i].Asset, e.Asset)
+	}
+	assert.Equal(t, existing.ID, entities[0].ID)
+	assert.Equal(t, entities[1].ID, entities[len(entities)-1].ID)
+
+	found, err := store.FindEntityById(context.Background(), entities[100].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "batch100.example.com", found.Asset.(*dns.FQDN).Name)
+}
+
+func TestCreateEntitiesRollback(t *testing.T) {
+	inputs := []*types.Entity{
+		{Asset: &dns.FQDN{Name: "rollback.example.com"}},
+		{ID: "not-a-number", Asset: &dns.FQDN{Name: "rollback2.example.com"}},
+	}
+
+	_, err := store.CreateEntities(context.Background(), inputs)
+	assert.Error(t, err)
+
+	_, err = store.FindEntitiesByContent(context.Background(), inputs[0].Asset, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestFindEntitiesByTypePaged(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 1; i <= 25; i++ {
+		_, err := store.CreateEntity(context.Background(), &types.Entity{
+			CreatedAt: start.Add(time.Duration(i) * time.Second),
+			LastSeen:  start.Add(time.Duration(i) * time.Second),
+			Asset:     &general.Identifier{UniqueID: fmt.Sprintf("page:%d", i), ID: strconv.Itoa(i), Type: "page"},
+		})
+		assert.NoError(t, err)
+	}
+
+	seen := make(map[string]struct{})
+	var prev time.Time
+	for offset := 0; offset < 25; offset += 10 {
+		entities, total, err := store.FindEntitiesByTypePaged(context.Background(), oam.Identifier, time.Time{}, offset, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(25), total)
+		assert.Len(t, entities, min(10, 25-offset))
+
+		for _, e := range entities {
+			_, found := seen[e.ID]
+			assert.False(t, found)
+			seen[e.ID] = struct{}{}
+			assert.False(t, e.CreatedAt.Before(prev))
+			prev = e.CreatedAt
+		}
+	}
+	assert.Len(t, seen, 25)
+
+	_, total, err := store.FindEntitiesByTypePaged(context.Background(), oam.Identifier, time.Time{}, 25, 10)
+	assert.Error(t, err)
+	assert.Equal(t, int64(25), total)
+
+	_, total, err = store.FindEntitiesByTypePaged(context.Background(), oam.Identifier, start.Add(21*time.Second), 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+
+	_, _, err = store.FindEntitiesByTypePaged(context.Background(), oam.Identifier, time.Time{}, 0, 0)
+	assert.Error(t, err)
+}
+
+func TestIterateEntitiesByType(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 1; i <= 10; i++ {
+		_, err := store.CreateEntity(context.Background(), &types.Entity{
+			CreatedAt: start.Add(time.Duration(i) * time.Second),
+			LastSeen:  start.Add(time.Duration(i) * time.Second),
+			Asset:     &general.Identifier{UniqueID: fmt.Sprintf("iter:%d", i), ID: strconv.Itoa(i), Type: "iter"},
+		})
+		assert.NoError(t, err)
+	}
+
+	iter, err := store.IterateEntitiesByType(context.Background(), oam.Identifier, time.Time{})
+	assert.NoError(t, err)
+
+	var count int
+	var prev time.Time
+	for iter.Next() {
+		e := iter.Entity()
+		assert.False(t, e.CreatedAt.Before(prev))
+		prev = e.CreatedAt
+
+		if id, ok := e.Asset.(*general.Identifier); ok && id.Type == "iter" {
+			count++
+		}
+	}
+	assert.NoError(t, iter.Err())
+	assert.NoError(t, iter.Close())
+	assert.Equal(t, 10, count)
+	assert.False(t, iter.Next())
+
+	// closing before the iteration completes releases the results
+	iter, err = store.IterateEntitiesByType(context.Background(), oam.Identifier, start.Add(5*time.Second))
+	assert.NoError(t, err)
+	assert.True(t, iter.Next())
+	assert.NotNil(t, iter.Entity())
+	assert.NoError(t, iter.Close())
+}
+
+func TestWithTransaction(t *testing.T) {
+	var created *types.Entity
+	err := store.WithTransaction(context.Background(), func(tx types.Repository) error {
+		from, err := tx.CreateAsset(context.Background(), &dns.FQDN{Name: "commit.txn.example.com"})
+		if err != nil {
+			return err
+		}
+
+		to, err := tx.CreateAsset(context.Background(), &dns.FQDN{Name: "target.txn.example.com"})
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.CreateEdge(context.Background(), &types.Edge{
+			Relation:   &general.SimpleRelation{Name: "node"},
+			FromEntity: from,
+			ToEntity:   to,
+		}); err != nil {
+			return err
+		}
+
+		// nested calls reuse the outer transaction
+		return tx.WithTransaction(context.Background(), func(nested types.Repository) error {
+			created, err = nested.CreateAsset(context.Background(), &dns.FQDN{Name: "nested.txn.example.com"})
+			return err
+		})
+	})
+	assert.NoError(t, err)
+
+	_, err = store.FindEntityById(context.Background(), created.ID)
+	assert.NoError(t, err)
+
+	from, err := store.FindEntitiesByContent(context.Background(), &dns.FQDN{Name: "commit.txn.example.com"}, time.Time{})
+	assert.NoError(t, err)
+	edges, err := store.OutgoingEdges(context.Background(), from[0], time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 1)
+
+	rollback := fmt.Errorf("rollback")
+	err = store.WithTransaction(context.Background(), func(tx types.Repository) error {
+		if _, err := tx.CreateAsset(context.Background(), &dns.FQDN{Name: "rollback.txn.example.com"}); err != nil {
+			return err
+		}
+
+		return tx.WithTransaction(context.Background(), func(nested types.Repository) error {
+			if _, err := nested.CreateAsset(context.Background(), &dns.FQDN{Name: "rollback2.txn.example.com"}); err != nil {
+				return err
+			}
+			return rollback
+		})
+	})
+	assert.ErrorIs(t, err, rollback)
+
+	_, err = store.FindEntitiesByContent(context.Background(), &dns.FQDN{Name: "rollback.txn.example.com"}, time.Time{})
+	assert.Error(t, err)
+	_, err = store.FindEntitiesByContent(context.Background(), &dns.FQDN{Name: "rollback2.txn.example.com"}, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestSoftDelete(t *testing.T) {
+	soft := *store
+	soft.softDelete = true
+	start := time.Now().Add(-time.Second)
+
+	from, err := soft.CreateAsset(context.Background(), &dns.FQDN{Name: "from.soft.example.com"})
+	assert.NoError(t, err)
+	to, err := soft.CreateAsset(context.Background(), &dns.FQDN{Name: "to.soft.example.com"})
+	assert.NoError(t, err)
+	_, err = soft.CreateEdge(context.Background(), &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+
+	n, err := soft.DeleteEntity(context.Background(), to.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = soft.FindEntityById(context.Background(), to.ID)
+	assert.Error(t, err)
+
+	// the tombstone is not deleted again
+	n, err = soft.DeleteEntity(context.Background(), to.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+	_, err = soft.FindEntitiesByContent(context.Background(), to.Asset, time.Time{})
+	assert.Error(t, err)
+	_, err = soft.OutgoingEdges(context.Background(), from, time.Time{})
+	assert.Error(t, err)
+
+	deleted, err := soft.FindDeletedEntities(context.Background(), start)
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 1)
+	assert.Equal(t, to.ID, deleted[0].ID)
+	assert.False(t, deleted[0].DeletedAt.IsZero())
+
+	// creating the asset again restores the tombstone
+	restored, err := soft.CreateAsset(context.Background(), to.Asset)
+	assert.NoError(t, err)
+	assert.Equal(t, to.ID, restored.ID)
+	_, err = soft.FindDeletedEntities(context.Background(), start)
+	assert.Error(t, err)
+	edges, err := soft.OutgoingEdges(context.Background(), from, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, edges, 1)
+
+	_, err = soft.DeleteEntity(context.Background(), to.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, soft.PurgeDeleted(context.Background(), start))
+	_, err = soft.FindDeletedEntities(context.Background(), start)
+	assert.NoError(t, err)
+
+	assert.NoError(t, soft.PurgeDeleted(context.Background(), time.Now().Add(time.Minute)))
+	_, err = soft.FindDeletedEntities(context.Background(), start)
+	assert.Error(t, err)
+	_, err = store.FindEntityById(context.Background(), to.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.DeleteEntity(context.Background(), from.ID)
+	assert.NoError(t, err)
+}
+
+func TestDeleteEntityCascade(t *testing.T) {
+	soft := *store
+	soft.softDelete = true
+
+	from, err := soft.CreateAsset(context.Background(), &dns.FQDN{Name: "from.cascade.example.com"})
+	assert.NoError(t, err)
+	to, err := soft.CreateAsset(context.Background(), &dns.FQDN{Name: "to.cascade.example.com"})
+	assert.NoError(t, err)
+	edge, err := soft.CreateEdge(context.Background(), &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	_, err = soft.CreateEntityProperty(context.Background(), to, &general.SimpleProperty{PropertyName: "cascade", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	_, err = soft.CreateEdgeProperty(context.Background(), edge, &general.SimpleProperty{PropertyName: "cascade", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	// the entity, its tag, the incoming edge, and the tag of the edge are removed, even in the soft-delete mode
+	n, err := soft.DeleteEntityCascade(context.Background(), to.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	_, err = soft.FindEntityById(context.Background(), to.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = soft.OutgoingEdges(context.Background(), from, time.Time{})
+	assert.Error(t, err)
+
+	var count int64
+	assert.NoError(t, store.db.Unscoped().Model(&Entity{}).Where("entity_id = ?", to.ID).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	assert.NoError(t, store.db.Model(&EntityTag{}).Where("entity_id = ?", to.ID).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	assert.NoError(t, store.db.Model(&EdgeTag{}).Where("edge_id = ?", edge.ID).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	// a tombstone is removed as well
+	_, err = soft.DeleteEntity(context.Background(), from.ID)
+	assert.NoError(t, err)
+	n, err = soft.DeleteEntityCascade(context.Background(), from.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	n, err = soft.DeleteEntityCascade(context.Background(), from.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestDeleteEntitiesByType(t *testing.T) {
+	old := time.Now().AddDate(-10, 0, 0)
+	stale, err := store.CreateEntity(context.Background(), &types.Entity{
+		CreatedAt: old,
+		LastSeen:  old,
+		Asset:     &dns.FQDN{Name: "stale.retention.example.com"},
+	})
+	assert.NoError(t, err)
+	fresh, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "fresh.retention.example.com"})
+	assert.NoError(t, err)
+	edge, err := store.CreateEdge(context.Background(), &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: fresh,
+		ToEntity:   stale,
+	})
+	assert.NoError(t, err)
+	_, err = store.CreateEntityProperty(context.Background(), stale, &general.SimpleProperty{PropertyName: "retention", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	_, err = store.CreateEdgeProperty(context.Background(), edge, &general.SimpleProperty{PropertyName: "retention", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	// only the entities last seen before the date are removed, along with their edges and tags; on MySQL, this
+	// also checks that the entities are not deleted with a subquery that reads the entities table
+	n, err := store.DeleteEntitiesByType(context.Background(), oam.FQDN, time.Now().AddDate(-5, 0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = store.FindEntityById(context.Background(), stale.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.FindEntityById(context.Background(), fresh.ID)
+	assert.NoError(t, err)
+	_, err = store.OutgoingEdges(context.Background(), fresh, time.Time{})
+	assert.Error(t, err)
+
+	var count int64
+	assert.NoError(t, store.db.Model(&EntityTag{}).Where("entity_id = ?", stale.ID).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	assert.NoError(t, store.db.Model(&EdgeTag{}).Where("edge_id = ?", edge.ID).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	n, err = store.DeleteEntitiesByType(context.Background(), oam.FQDN, time.Now().AddDate(-5, 0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	// without a date, every entity of the type is removed
+	n, err = store.DeleteEntitiesByType(context.Background(), oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, n, int64(1))
+	_, err = store.FindEntityById(context.Background(), fresh.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+// workspaceRepository returns a repository of the workspace that shares the connection pool of the store.
+func workspaceRepository(t *testing.T, ws string) *sqlRepository {
+	sqlDB, err := store.db.DB()
+	if err != nil {
+		t.Fatalf("Failed to obtain the handle of the store: %v", err)
+	}
+
+	repo, err := NewWithSQLDB(store.dbtype, sqlDB, options.WithWorkspace(ws))
+	if err != nil {
+		t.Fatalf("Failed to create the repository of workspace %s: %v", ws, err)
+	}
+	return repo
+}
+
+func TestCreateEntityOtherWorkspace(t *testing.T) {
+	ctx := context.Background()
+	a, b := workspaceRepository(t, "entity_a"), workspaceRepository(t, "entity_b")
+
+	owned, err := a.CreateAsset(ctx, &dns.FQDN{Name: "a.workspace.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = a.DeleteEntity(ctx, owned.ID) }()
+
+	// the ID of an entity held by another workspace is not reused, so that entity is not overwritten
+	_, err = b.CreateEntity(ctx, &types.Entity{ID: owned.ID, Asset: &dns.FQDN{Name: "b.workspace.example.com"}})
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+
+	found, err := a.FindEntityById(ctx, owned.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "a.workspace.example.com", found.Asset.Key())
+	_, err = b.FindEntityById(ctx, owned.ID)
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestUpsertEntity(t *testing.T) {
+	asset := &dns.FQDN{Name: "upsert.example.com"}
+
+	first, result, err := store.UpsertEntity(context.Background(), &types.Entity{Asset: asset})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertCreated, result)
+
+	time.Sleep(100 * time.Millisecond)
+	second, result, err := store.UpsertEntity(context.Background(), &types.Entity{Asset: asset})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUnchanged, result)
+	assert.Equal(t, first.ID, second.ID)
+	assert.True(t, second.LastSeen.After(first.LastSeen))
+
+	entities, err := store.FindEntitiesByContent(context.Background(), asset, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 1)
+	_, err = store.DeleteEntity(context.Background(), first.ID)
+	assert.NoError(t, err)
+
+	// the content of the matched entity is replaced when it differs
+	organization, result, err := store.UpsertEntity(context.Background(), &types.Entity{Asset: &org.Organization{ID: "upsert-org", Name: "Example"}})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertCreated, result)
+	renamed, result, err := store.UpsertEntity(context.Background(), &types.Entity{Asset: &org.Organization{ID: "upsert-org", Name: "Example, Inc."}})
+	assert.NoError(t, err)
+	assert.Equal(t, types.UpsertUpdated, result)
+	assert.Equal(t, organization.ID, renamed.ID)
+
+	found, err := store.FindEntityById(context.Background(), organization.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Example, Inc.", found.Asset.(*org.Organization).Name)
+	_, err = store.DeleteEntity(context.Background(), organization.ID)
+	assert.NoError(t, err)
+}
+
+func TestCreateEntityWithID(t *testing.T) {
+	ctx := context.Background()
+
+	entity, err := store.CreateEntity(ctx, &types.Entity{Asset: &dns.FQDN{Name: "provided.example.com"}})
+	assert.NoError(t, err)
+
+	updated, err := store.CreateEntity(ctx, &types.Entity{ID: entity.ID, Asset: &dns.FQDN{Name: "provided.example.com"}})
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ID, updated.ID)
+
+	// the ID is held by another asset
+	_, err = store.CreateEntity(ctx, &types.Entity{ID: entity.ID, Asset: &dns.FQDN{Name: "other.example.com"}})
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+
+	// the asset already exists with another ID
+	_, err = store.CreateEntity(ctx, &types.Entity{ID: "987654321", Asset: &dns.FQDN{Name: "provided.example.com"}})
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+
+	found, err := store.FindEntityById(ctx, entity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "provided.example.com", found.Asset.Key())
+	_, err = store.DeleteEntity(ctx, entity.ID)
+	assert.NoError(t, err)
+}
+
+func TestCountMethods(t *testing.T) {
+	entities, err := store.CountEntitiesByType(context.Background(), oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	start := time.Now()
+
+	var created []*types.Entity
+	for i := 0; i < 3; i++ {
+		e, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: fmt.Sprintf("%d.count.example.com", i)})
+		assert.NoError(t, err)
+		created = append(created, e)
+	}
+	_, err = store.CreateEdge(context.Background(), &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: created[0],
+		ToEntity:   created[1],
+	})
+	assert.NoError(t, err)
+
+	total, err := store.CountEntitiesByType(context.Background(), oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, entities+3, total)
+	total, err = store.CountEntitiesByType(context.Background(), oam.FQDN, start)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	total, err = store.CountEdges(context.Background(), start)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	in, out, err := store.EntityDegree(context.Background(), created[0], time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 1}, []int64{in, out})
+	in, out, err = store.EntityDegree(context.Background(), created[1], start)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 0}, []int64{in, out})
+	in, out, err = store.EntityDegree(context.Background(), created[2], time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 0}, []int64{in, out})
+	in, out, err = store.EntityDegree(context.Background(), created[0], time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 0}, []int64{in, out})
+
+	grouped, err := store.CountEntitiesGrouped(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, entities+3, grouped[oam.FQDN])
+	grouped, err = store.CountEntitiesGrouped(context.Background(), start)
+	assert.NoError(t, err)
+	assert.Equal(t, map[oam.AssetType]int64{oam.FQDN: 3}, grouped)
+	grouped, err = store.CountEntitiesGrouped(context.Background(), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, grouped)
+
+	total, err = store.CountEntitiesByType(context.Background(), oam.FQDN, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+	total, err = store.CountEdges(context.Background(), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+
+	for _, e := range created {
+		_, err = store.DeleteEntity(context.Background(), e.ID)
+		assert.NoError(t, err)
+	}
+}
+
+func TestOrphanEntities(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+
+	var created []*types.Entity
+	for _, name := range []string{"a.orphan.example.com", "b.orphan.example.com", "c.orphan.example.com"} {
+		e, err := store.CreateAsset(ctx, &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		created = append(created, e)
+	}
+	defer func() {
+		for _, e := range created {
+			_, _ = store.DeleteEntity(ctx, e.ID)
+		}
+	}()
+	_, err := store.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: created[0],
+		ToEntity:   created[1],
+	})
+	assert.NoError(t, err)
+	_, err = store.CreateEntityProperty(ctx, created[2], &general.SimpleProperty{PropertyName: "orphan", PropertyValue: "foo"})
+	assert.NoError(t, err)
+
+	orphans, err := store.FindOrphanEntities(ctx, oam.FQDN, start)
+	assert.NoError(t, err)
+	if assert.Len(t, orphans, 1) {
+		assert.Equal(t, created[2].ID, orphans[0].ID)
+	}
+	_, err = store.FindOrphanEntities(ctx, oam.FQDN, time.Now().Add(time.Minute))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	// the orphans last seen after the before parameter are kept; on MySQL, this also checks that the
+	// orphans are not deleted with a subquery that reads the entities table
+	_, err = store.DeleteOrphanEntities(ctx, oam.FQDN, start)
+	assert.NoError(t, err)
+	_, err = store.FindEntityById(ctx, created[2].ID)
+	assert.NoError(t, err)
+
+	total, err := store.DeleteOrphanEntities(ctx, oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, total, int64(1))
+	_, err = store.FindEntityById(ctx, created[2].ID)
+	assert.Error(t, err)
+	orphanID, _ := strconv.ParseUint(created[2].ID, 10, 64)
+	var count int64
+	assert.NoError(t, store.db.Model(&EntityTag{}).Where("entity_id = ?", orphanID).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	for _, e := range created[:2] {
+		_, err = store.FindEntityById(ctx, e.ID)
+		assert.NoError(t, err)
+	}
+}
+
+func TestSearchEntities(t *testing.T) {
+	var created []*types.Entity
+	for _, name := range []string{"a.search.example.com", "b.search.example.com", "100%_off.search.example.org"} {
+		e, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		created = append(created, e)
+	}
+	defer func() {
+		for _, e := range created {
+			_, _ = store.DeleteEntity(context.Background(), e.ID)
+		}
+	}()
+
+	entities, err := store.SearchEntities(context.Background(), oam.FQDN, ".search.example.com", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 2)
+
+	// the wildcard characters are matched literally
+	entities, err = store.SearchEntities(context.Background(), oam.FQDN, "0%_", time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, 1)
+	assert.Equal(t, created[2].ID, entities[0].ID)
+
+	_, err = store.SearchEntities(context.Background(), oam.FQDN, "a_search", time.Time{})
+	assert.Error(t, err)
+	_, err = store.SearchEntities(context.Background(), oam.FQDN, "' OR '1'='1", time.Time{})
+	assert.Error(t, err)
+	_, err = store.SearchEntities(context.Background(), oam.FQDN, ".search.example.com", time.Now().Add(time.Minute))
+	assert.Error(t, err)
+	_, err = store.SearchEntities(context.Background(), oam.FQDN, "", time.Time{})
+	assert.Error(t, err)
+}
+
+func TestFindEntitiesByContents(t *testing.T) {
+	ip, _ := netip.ParseAddr("198.51.100.24")
+	assets := []oam.Asset{
+		&dns.FQDN{Name: "batch.contents.example.com"},
+		&network.IPAddress{Address: ip, Type: "IPv4"},
+		&network.AutonomousSystem{Number: 64512},
+	}
+
+	var created []*types.Entity
+	for _, asset := range assets {
+		e, err := store.CreateAsset(context.Background(), asset)
+		assert.NoError(t, err)
+		created = append(created, e)
+	}
+	defer func() {
+		for _, e := range created {
+			_, _ = store.DeleteEntity(context.Background(), e.ID)
+		}
+	}()
+
+	missing := &dns.FQDN{Name: "missing.contents.example.com"}
+	entities, err := store.FindEntitiesByContents(context.Background(), append(assets, missing), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entities, len(assets))
+	for i, asset := range assets {
+		if e, found := entities[types.ContentHash(asset)]; assert.True(t, found) {
+			assert.Equal(t, created[i].ID, e.ID)
+		}
+	}
+	_, found := entities[types.ContentHash(missing)]
+	assert.False(t, found)
+
+	entities, err = store.FindEntitiesByContents(context.Background(), assets, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, entities)
+
+	entities, err = store.FindEntitiesByContents(context.Background(), nil, time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, entities)
+}
+
+func TestFindEntityByHash(t *testing.T) {
+	entity, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "hash.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(context.Background(), entity.ID) }()
+
+	batch, err := store.CreateEntities(context.Background(), []*types.Entity{
+		{Asset: &dns.FQDN{Name: "batch.hash.example.com"}},
+	})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(context.Background(), batch[0].ID) }()
+
+	upserted, _, err := store.UpsertEntity(context.Background(), &types.Entity{Asset: &dns.FQDN{Name: "upsert.hash.example.com"}})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(context.Background(), upserted.ID) }()
+
+	for _, e := range []*types.Entity{entity, batch[0], upserted} {
+		found, err := store.FindEntityByHash(context.Background(), e.ContentHash())
+		if assert.NoError(t, err) {
+			assert.Equal(t, e.ID, found.ID)
+			assert.Equal(t, e.Asset.Key(), found.Asset.Key())
+		}
+	}
+
+	_, err = store.FindEntityByHash(context.Background(), types.ContentHash(&dns.FQDN{Name: "missing.hash.example.com"}))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+	_, err = store.FindEntityByHash(context.Background(), "")
+	assert.Error(t, err)
+}
+
+func TestEntityExists(t *testing.T) {
+	entity, err := store.CreateAsset(context.Background(), &dns.FQDN{Name: "exists.example.com"})
+	assert.NoError(t, err)
+
+	found, err := store.EntityExists(context.Background(), &dns.FQDN{Name: "exists.example.com"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = store.EntityExists(context.Background(), &dns.FQDN{Name: "missing.exists.example.com"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = store.EntityExists(context.Background(), nil)
+	assert.Error(t, err)
+
+	// the deleted entity no longer exists
+	_, err = store.DeleteEntity(context.Background(), entity.ID)
+	assert.NoError(t, err)
+	found, err = store.EntityExists(context.Background(), &dns.FQDN{Name: "exists.example.com"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestFindEntitiesByTypes(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2003, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	var ids []string
+	for i, asset := range []oam.Asset{
+		&network.IPAddress{Address: netip.MustParseAddr("198.51.100.201"), Type: "IPv4"},
+		&network.Netblock{CIDR: netip.MustParsePrefix("198.51.100.192/27"), Type: "IPv4"},
+		&network.IPAddress{Address: netip.MustParseAddr("198.51.100.202"), Type: "IPv4"},
+	} {
+		e, err := store.CreateEntity(ctx, &types.Entity{
+			CreatedAt: start.Add(time.Duration(i) * time.Hour),
+			LastSeen:  start.Add(time.Duration(i) * time.Hour),
+			Asset:     asset,
+		})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, e.ID) }()
+		ids = append(ids, e.ID)
+	}
+
+	// the entities of both types are interleaved by their creation time
+	entities, err := store.FindEntitiesByTypes(ctx, start, oam.IPAddress, oam.Netblock)
+	assert.NoError(t, err)
+	var found []string
+	for _, e := range entities {
+		for _, id := range ids {
+			if e.ID == id {
+				found = append(found, id)
+			}
+		}
+	}
+	assert.Equal(t, ids, found)
+
+	_, err = store.FindEntitiesByTypes(ctx, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestFindEntitiesByTypeBetween(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	var ids []string
+	for i, name := range []string{"first.window.example.com", "second.window.example.com"} {
+		e, err := store.CreateEntity(ctx, &types.Entity{
+			LastSeen: start.Add(time.Duration(i) * time.Hour),
+			Asset:    &dns.FQDN{Name: name},
+		})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, e.ID) }()
+		ids = append(ids, e.ID)
+	}
+
+	// the window includes its start and excludes its end
+	entities, err := store.FindEntitiesByTypeBetween(ctx, oam.FQDN, start, start.Add(time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, entities, 1) {
+		assert.Equal(t, ids[0], entities[0].ID)
+	}
+
+	entities, err = store.FindEntitiesByTypeBetween(ctx, oam.FQDN, time.Time{}, start.Add(2*time.Hour))
+	assert.NoError(t, err)
+	var found int
+	for _, e := range entities {
+		if e.ID == ids[0] || e.ID == ids[1] {
+			found++
+		}
+	}
+	assert.Equal(t, 2, found)
+
+	_, err = store.FindEntitiesByTypeBetween(ctx, oam.FQDN, start.Add(2*time.Hour), start.Add(3*time.Hour))
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
+
+func TestDiffEntities(t *testing.T) {
+	ctx := context.Background()
+	t1 := time.Date(2002, time.January, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(24 * time.Hour)
+
+	for _, e := range []*types.Entity{
+		{CreatedAt: t1.Add(-time.Hour), LastSeen: t2.Add(time.Hour), Asset: &dns.FQDN{Name: "both.diff.example.com"}},
+		{CreatedAt: t1.Add(time.Hour), LastSeen: t2.Add(time.Hour), Asset: &dns.FQDN{Name: "added.diff.example.com"}},
+		{CreatedAt: t1.Add(-time.Hour), LastSeen: t1.Add(time.Hour), Asset: &dns.FQDN{Name: "removed.diff.example.com"}},
+		{CreatedAt: t1.Add(-2 * time.Hour), LastSeen: t1.Add(-time.Hour), Asset: &dns.FQDN{Name: "old.diff.example.com"}},
+	} {
+		entity, err := store.CreateEntity(ctx, e)
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+	}
+
+	added, removed, err := store.DiffEntities(ctx, oam.FQDN, t1, t2)
+	assert.NoError(t, err)
+	if assert.Len(t, added, 1) {
+		assert.Equal(t, "added.diff.example.com", added[0].Asset.Key())
+	}
+	if assert.Len(t, removed, 1) {
+		assert.Equal(t, "removed.diff.example.com", removed[0].Asset.Key())
+	}
+
+	_, _, err = store.DiffEntities(ctx, oam.FQDN, t2, t1)
+	assert.Error(t, err)
+}
+
+func TestEntityOrder(t *testing.T) {
+	ctx := context.Background()
+	// the entities are created in the order x, y, z, and last seen in the order y, z, x
+	created := time.Date(2004, time.January, 1, 0, 0, 0, 0, time.UTC)
+	seen := map[string]time.Duration{"x": 5 * time.Minute, "y": 3 * time.Minute, "z": 4 * time.Minute}
+	ids := make(map[string]string)
+	for i, name := range []string{"x", "y", "z"} {
+		e, err := store.CreateEntity(ctx, &types.Entity{
+			CreatedAt: created.Add(time.Duration(i) * time.Minute),
+			LastSeen:  created.Add(seen[name]),
+			Asset:     &dns.FQDN{Name: name + ".order.example.com"},
+		})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, e.ID) }()
+		ids[e.ID] = name
+	}
+
+	for _, tc := range []struct {
+		opt      options.Option
+		expected string
+	}{
+		{options.WithOrder(options.OrderByCreatedAt, options.Ascending), "xyz"},
+		{options.WithOrder(options.OrderByCreatedAt, options.Descending), "zyx"},
+		{options.WithOrder(options.OrderByUpdatedAt, options.Ascending), "yzx"},
+		{options.WithOrder(options.OrderByUpdatedAt, options.Descending), "xzy"},
+		{options.WithOrder(options.OrderByType, options.Descending), "zyx"},
+		// the order takes precedence over the last seen order
+		{options.WithOrder(options.OrderByCreatedAt, options.Ascending), "xyz"},
+	} {
+		o := options.Apply(tc.opt, options.WithLastSeenOrder())
+		ordered := *store
+		ordered.lastSeen = o.LastSeenOrder
+		ordered.order = entityOrder(o)
+		found, err := ordered.FindEntitiesByTypeBetween(ctx, oam.FQDN, created, created.Add(time.Hour))
+		assert.NoError(t, err)
+
+		var order string
+		for _, e := range found {
+			order += ids[e.ID]
+		}
+		assert.Equal(t, tc.expected, order)
+	}
+}
+
+func TestTouchEntity(t *testing.T) {
+	ctx := context.Background()
+	ordered := *store
+	ordered.lastSeen = true
+
+	seen := time.Date(2003, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var entities []*types.Entity
+	for i, name := range []string{"first.touch.example.com", "second.touch.example.com"} {
+		e, err := store.CreateEntity(ctx, &types.Entity{
+			LastSeen: seen.Add(time.Duration(i) * time.Minute),
+			Asset:    &dns.FQDN{Name: name},
+		})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntity(ctx, e.ID) }()
+		entities = append(entities, e)
+	}
+
+	// the most recently seen entities are returned first
+	found, err := ordered.FindEntitiesByTypeBetween(ctx, oam.FQDN, seen, seen.Add(time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, found, 2) {
+		assert.Equal(t, entities[1].ID, found[0].ID)
+	}
+
+	start := time.Now().Add(-time.Second)
+	assert.NoError(t, store.TouchEntity(ctx, entities[0].ID))
+	touched, err := store.FindEntityById(ctx, entities[0].ID)
+	assert.NoError(t, err)
+	assert.False(t, touched.LastSeen.Before(start))
+
+	found, err = ordered.FindEntitiesByType(ctx, oam.FQDN, start)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, found) {
+		assert.Equal(t, entities[0].ID, found[0].ID)
+	}
+
+	assert.ErrorIs(t, store.TouchEntity(ctx, "999999999"), types.ErrNotFound)
+}
+
+func TestUpdateEntity(t *testing.T) {
+	ctx := context.Background()
+	soft := *store
+	soft.softDelete = true
+
+	var entities []*types.Entity
+	for _, name := range []string{"from.update.example.com", "to.update.example.com", "deleted.update.example.com"} {
+		e, err := soft.CreateAsset(ctx, &dns.FQDN{Name: name})
+		assert.NoError(t, err)
+		defer func() { _, _ = store.DeleteEntityCascade(ctx, e.ID) }()
+		entities = append(entities, e)
+	}
+	from, to, deleted := entities[0], entities[1], entities[2]
+	_, err := soft.CreateEdge(ctx, &types.Edge{
+		Relation:   &general.SimpleRelation{Name: "node"},
+		FromEntity: from,
+		ToEntity:   to,
+	})
+	assert.NoError(t, err)
+	_, err = soft.DeleteEntity(ctx, deleted.ID)
+	assert.NoError(t, err)
+
+	// the ID, the creation time, and the edges are kept
+	updated, err := soft.UpdateEntity(ctx, to.ID, &dns.FQDN{Name: "docs.update.example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, to.ID, updated.ID)
+	assert.WithinDuration(t, to.CreatedAt, updated.CreatedAt, time.Second)
+	assert.False(t, updated.LastSeen.Before(to.LastSeen))
+
+	found, err := soft.FindEntityByHash(ctx, types.ContentHash(&dns.FQDN{Name: "docs.update.example.com"}))
+	assert.NoError(t, err)
+	assert.Equal(t, to.ID, found.ID)
+	assert.Equal(t, "docs.update.example.com", found.Asset.Key())
+	_, err = soft.FindEntityByHash(ctx, to.ContentHash())
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	edges, err := soft.OutgoingEdges(ctx, from, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, edges, 1) {
+		assert.Equal(t, to.ID, edges[0].ToEntity.ID)
+	}
+
+	// the asset of another entity, including a soft-deleted one, is rejected
+	_, err = soft.UpdateEntity(ctx, to.ID, from.Asset)
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+	_, err = soft.UpdateEntity(ctx, to.ID, deleted.Asset)
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+
+	_, err = soft.UpdateEntity(ctx, to.ID, &general.Identifier{UniqueID: "id:update", ID: "update", Type: "id"})
+	assert.Error(t, err)
+	_, err = soft.UpdateEntity(ctx, deleted.ID, &dns.FQDN{Name: "restored.update.example.com"})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+	_, err = soft.UpdateEntity(ctx, "999999999", &dns.FQDN{Name: "missing.update.example.com"})
+	assert.ErrorIs(t, err, types.ErrEntityNotFound)
+}
+
+func TestQueryInjection(t *testing.T) {
+	ctx := context.Background()
+
+	// the content is bound to placeholders, so the quotes are matched literally rather than widening the query
+	name := "inject' OR '1'='1"
+	entity, err := store.CreateEntity(ctx, &types.Entity{Asset: &dns.FQDN{Name: name}})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+
+	other, err := store.CreateEntity(ctx, &types.Entity{Asset: &dns.FQDN{Name: "inject.example.com"}})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, other.ID) }()
+
+	found, err := store.FindEntitiesByContent(ctx, &dns.FQDN{Name: name}, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, entity.ID, found[0].ID)
+		assert.Equal(t, name, found[0].Asset.(*dns.FQDN).Name)
+	}
+
+	_, err = store.FindEntitiesByContent(ctx, &dns.FQDN{Name: "' OR '1'='1"}, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotFound)
+
+	prop := &general.SimpleProperty{PropertyName: "injection", PropertyValue: "' OR '1'='1"}
+	tag, err := store.CreateEntityProperty(ctx, entity, prop)
+	assert.NoError(t, err)
+	_, err = store.CreateEntityProperty(ctx, other, &general.SimpleProperty{PropertyName: "injection", PropertyValue: "benign"})
+	assert.NoError(t, err)
+
+	tags, err := store.FindEntityTagsByContent(ctx, prop, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, tags, 1) {
+		assert.Equal(t, tag.ID, tags[0].ID)
+	}
+}
+
+func TestMarshaler(t *testing.T) {
+	ctx := context.Background()
+	canonical := *store
+
+	var calls int
+	canonical.marshal = func(asset oam.Asset) ([]byte, error) {
+		calls++
+		content, err := asset.JSON()
+		if err != nil {
+			return nil, err
+		}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(content, &fields); err != nil {
+			return nil, err
+		}
+		// the keys of a map are written in sorted order
+		return json.Marshal(fields)
+	}
+
+	asset := &general.Identifier{UniqueID: "marshal:1", ID: "1", Type: "marshal"}
+	entity, err := canonical.CreateEntity(ctx, &types.Entity{Asset: asset})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+	assert.NotZero(t, calls)
+
+	var row Entity
+	assert.NoError(t, store.db.Where("entity_id = ?", entity.ID).First(&row).Error)
+	expected, err := canonical.marshal(asset)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(row.Content))
+	assert.Equal(t, types.ContentHash(asset), row.ContentHash)
+
+	found, err := canonical.FindEntitiesByContent(ctx, asset, time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, entity.ID, found[0].ID)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	ctx := context.Background()
+	entity, err := store.CreateAsset(ctx, &dns.FQDN{Name: "raw.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+
+	var q types.RawQuerier = store
+	rows, err := q.Raw(ctx, "SELECT entity_id, etype FROM entities WHERE content_hash = ?", types.ContentHash(entity.Asset))
+	assert.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	assert.Equal(t, []string{"entity_id", "etype"}, rows.Columns())
+
+	var count int
+	for rows.Next() {
+		count++
+		values := rows.Values()
+		if assert.Len(t, values, 2) {
+			assert.Equal(t, entity.ID, fmt.Sprint(values[0]))
+			assert.Equal(t, string(oam.FQDN), fmt.Sprintf("%s", values[1]))
+		}
+	}
+	assert.NoError(t, rows.Err())
+	assert.Equal(t, 1, count)
+	assert.NoError(t, rows.Close())
+
+	_, err = store.Raw(ctx, "SELECT * FROM missing_table")
+	assert.Error(t, err)
+}
+
+func TestMaintenanceAfterDeletes(t *testing.T) {
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 10; i++ {
+		entity, err := store.CreateAsset(ctx, &dns.FQDN{Name: fmt.Sprintf("host%d.maintenance.example.com", i)})
+		assert.NoError(t, err)
+		ids = append(ids, entity.ID)
+	}
+	for _, id := range ids[1:] {
+		_, err := store.DeleteEntity(ctx, id)
+		assert.NoError(t, err)
+	}
+
+	// the space of the deleted rows is reclaimed without affecting the remaining entities
+	assert.NoError(t, store.Maintenance(ctx))
+	entity, err := store.FindEntityById(ctx, ids[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "host0.maintenance.example.com", entity.Asset.Key())
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	before, err := store.Stats(ctx)
+	assert.NoError(t, err)
+
+	from, err := store.CreateAsset(ctx, &dns.FQDN{Name: "from.stats.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, from.ID) }()
+	to, err := store.CreateAsset(ctx, &dns.FQDN{Name: "to.stats.example.com"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, to.ID) }()
+
+	edge, err := store.CreateEdge(ctx, &types.Edge{Relation: &general.SimpleRelation{Name: "node"}, FromEntity: from, ToEntity: to})
+	assert.NoError(t, err)
+	_, err = store.CreateEntityProperty(ctx, from, &general.SimpleProperty{PropertyName: "stats", PropertyValue: "foo"})
+	assert.NoError(t, err)
+	_, err = store.CreateEdgeProperty(ctx, edge, &general.SimpleProperty{PropertyName: "stats", PropertyValue: "bar"})
+	assert.NoError(t, err)
+
+	after, err := store.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, before.EntitiesByType[oam.FQDN]+2, after.EntitiesByType[oam.FQDN])
+	assert.Equal(t, before.EdgesByLabel["node"]+1, after.EdgesByLabel["node"])
+	assert.Equal(t, before.EntityTags+1, after.EntityTags)
+	assert.Equal(t, before.EdgeTags+1, after.EdgeTags)
+	assert.False(t, after.OldestCreatedAt.IsZero())
+	assert.False(t, after.NewestLastSeen.Before(to.LastSeen.Truncate(time.Second)))
+	assert.Greater(t, after.SizeBytes, int64(0))
+}
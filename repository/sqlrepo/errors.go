@@ -0,0 +1,132 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/garthoid/asset-db/types"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// registerErrorTranslation adds the callbacks that classify the error of each statement with the
+// sentinel errors of the types package, so that errors.Is works the same across the SQL databases.
+func registerErrorTranslation(db *gorm.DB) error {
+	translate := func(tx *gorm.DB) {
+		tx.Error = translateError(tx.Error)
+	}
+
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().After("gorm:create").Register("assetdb:translate_error", translate),
+		cb.Query().After("gorm:query").Register("assetdb:translate_error", translate),
+		cb.Update().After("gorm:update").Register("assetdb:translate_error", translate),
+		cb.Delete().After("gorm:delete").Register("assetdb:translate_error", translate),
+		cb.Row().After("gorm:row").Register("assetdb:translate_error", translate),
+		cb.Raw().After("gorm:raw").Register("assetdb:translate_error", translate),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translateError wraps the error returned by GORM or a SQL driver in a *types.Error, when it can be classified.
+// The original error remains available to errors.Is and errors.As.
+func translateError(err error) error {
+	var classified *types.Error
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+
+	if kind := errorKind(err); kind != nil {
+		return &types.Error{Kind: kind, Err: err}
+	}
+	return err
+}
+
+// errorKind returns the sentinel error that classifies the error, or nil if the error is not recognized.
+func errorKind(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return types.ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return types.ErrDuplicate
+	case errors.Is(err, gorm.ErrForeignKeyViolated) || errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return types.ErrConstraint
+	case isConnectionError(err):
+		return types.ErrConnection
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// the class 23 codes are the integrity constraint violations
+		if pgErr.Code == "23505" {
+			return types.ErrDuplicate
+		} else if strings.HasPrefix(pgErr.Code, "23") {
+			return types.ErrConstraint
+		}
+		return nil
+	}
+
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1062, 1586:
+			return types.ErrDuplicate
+		case 1048, 1216, 1217, 1451, 1452, 3819:
+			return types.ErrConstraint
+		}
+		return nil
+	}
+
+	// the SQLite driver reports the extended result codes
+	var liteErr interface{ Code() int }
+	if errors.As(err, &liteErr) {
+		if code := liteErr.Code(); code == 1555 || code == 2067 {
+			return types.ErrDuplicate
+		} else if code&0xff == 19 {
+			return types.ErrConstraint
+		}
+	}
+	return nil
+}
+
+// isConnectionError reports whether the connection to the database was dropped or could not be established.
+func isConnectionError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysqldriver.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// connection exceptions and server shutdowns
+		return strings.HasPrefix(pgErr.Code, "08") ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+
+	var connErr *pgconn.ConnectError
+	if errors.As(err, &connErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,71 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/garthoid/asset-db/types"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTranslateError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		kind error
+	}{
+		{gorm.ErrRecordNotFound, types.ErrNotFound},
+		{gorm.ErrDuplicatedKey, types.ErrDuplicate},
+		{&pgconn.PgError{Code: "23505"}, types.ErrDuplicate},
+		{&pgconn.PgError{Code: "23503"}, types.ErrConstraint},
+		{&pgconn.PgError{Code: "08006"}, types.ErrConnection},
+		{&mysqldriver.MySQLError{Number: 1062}, types.ErrDuplicate},
+		{&mysqldriver.MySQLError{Number: 1452}, types.ErrConstraint},
+		{driver.ErrBadConn, types.ErrConnection},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, types.ErrConnection},
+	} {
+		err := translateError(tc.err)
+		assert.ErrorIs(t, err, tc.kind, tc.err.Error())
+		// the original error and its message are kept
+		assert.ErrorIs(t, err, tc.err)
+		assert.Equal(t, tc.err.Error(), err.Error())
+	}
+
+	for _, err := range []error{
+		nil,
+		context.Canceled,
+		&pgconn.PgError{Code: "42601"},
+		errors.New("unknown"),
+	} {
+		var classified *types.Error
+		assert.False(t, errors.As(translateError(err), &classified))
+	}
+}
+
+func TestSQLiteErrors(t *testing.T) {
+	repo, err := New(SQLiteMemory, "file:errors?mode=memory&cache=shared")
+	assert.NoError(t, err)
+	defer func() { _ = repo.Close() }()
+
+	db := repo.db.WithContext(context.Background())
+	assert.NoError(t, db.Exec("CREATE TABLE errors_test (id INTEGER PRIMARY KEY, value TEXT NOT NULL)").Error)
+	assert.NoError(t, db.Exec("INSERT INTO errors_test (id, value) VALUES (1, 'foo')").Error)
+
+	err = db.Exec("INSERT INTO errors_test (id, value) VALUES (1, 'bar')").Error
+	assert.ErrorIs(t, err, types.ErrDuplicate)
+	err = db.Exec("INSERT INTO errors_test (id, value) VALUES (2, NULL)").Error
+	assert.ErrorIs(t, err, types.ErrConstraint)
+
+	var value string
+	err = db.Raw("SELECT value FROM errors_test WHERE id = 3").First(&value).Error
+	assert.ErrorIs(t, err, types.ErrNotFound)
+}
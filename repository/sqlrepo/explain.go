@@ -0,0 +1,155 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/garthoid/asset-db/internal/explain"
+	"gorm.io/gorm"
+)
+
+// explainCapture is the key of the context value that collects the statements built during the dry run of Explain.
+type explainCapture struct{}
+
+// capturedStatement is a statement built during the dry run of Explain, along with the values bound to its placeholders.
+type capturedStatement struct {
+	query string
+	vars  []interface{}
+}
+
+// registerExplain adds the callbacks that collect the statements built during the dry run of Explain.
+// The statements of a dry run are not executed, so only the read statements are collected.
+func registerExplain(db *gorm.DB) error {
+	capture := func(tx *gorm.DB) {
+		statements, ok := tx.Statement.Context.Value(explainCapture{}).(*[]capturedStatement)
+		if !ok || !tx.DryRun || tx.Statement.SQL.Len() == 0 {
+			return
+		}
+
+		*statements = append(*statements, capturedStatement{
+			query: tx.Statement.SQL.String(),
+			vars:  append([]interface{}(nil), tx.Statement.Vars...),
+		})
+	}
+
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Query().After("gorm:query").Register("assetdb:explain", capture),
+		cb.Row().After("gorm:row").Register("assetdb:explain", capture),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Explain returns the plans of the statements issued by the read method of the repository named by method, called with
+// the args that follow the context, such as Explain(ctx, "FindEntitiesByType", oam.FQDN, time.Time{}). The method is
+// called in a dry run, where its statements are built but not executed, and each statement is then run with EXPLAIN
+// ANALYZE on Postgres and MySQL, or EXPLAIN QUERY PLAN on SQLite. Since the dry run returns no rows, the statements
+// a method only issues after reading a row are not included. Each plan follows the text of its statement.
+// Returns an error if the method is not a read method of the Repository, or the args do not match its parameters.
+func (sql *sqlRepository) Explain(ctx context.Context, method string, args ...interface{}) (string, error) {
+	var statements []capturedStatement
+	dryrun := *sql
+	dryrun.db = sql.db.Session(&gorm.Session{DryRun: true})
+
+	// the error of the method is expected once a statement is built, since the dry run returns no rows
+	err := explain.Call(context.WithValue(ctx, explainCapture{}, &statements), &dryrun, method, args...)
+	if len(statements) == 0 {
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%s did not issue a statement", method)
+	}
+
+	var b strings.Builder
+	for i, s := range statements {
+		plan, err := sql.explainStatement(ctx, s)
+		if err != nil {
+			return "", err
+		}
+
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(s.query + "\n" + plan)
+	}
+	return b.String(), nil
+}
+
+// explainStatement runs the statement with the EXPLAIN command of the database, and returns the plan as text.
+func (sql *sqlRepository) explainStatement(ctx context.Context, s capturedStatement) (string, error) {
+	var command string
+	switch sql.dbtype {
+	case Postgres, MySQL:
+		command = "EXPLAIN ANALYZE "
+	case SQLite, SQLiteMemory:
+		command = "EXPLAIN QUERY PLAN "
+	default:
+		return "", errors.New("unknown DB type")
+	}
+
+	// the statement is already bound to the placeholders of the database, so it is passed to the driver unchanged
+	rows, err := sql.db.WithContext(ctx).Statement.ConnPool.QueryContext(ctx, command+s.query, s.vars...)
+	if err != nil {
+		return "", translateError(err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	// the rows of EXPLAIN QUERY PLAN are indented below their parent, as in the sqlite3 shell
+	depth := map[string]int{"0": 0}
+	var b strings.Builder
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+
+		if command == "EXPLAIN QUERY PLAN " && len(values) == 4 {
+			level := depth[planText(values[1])] + 1
+			depth[planText(values[0])] = level
+			b.WriteString(strings.Repeat("  ", level-1) + planText(values[3]) + "\n")
+			continue
+		}
+
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = planText(v)
+		}
+		b.WriteString(strings.TrimRight(strings.Join(parts, " "), "\n") + "\n")
+	}
+	if err := rows.Err(); err != nil {
+		return "", translateError(err)
+	}
+	return b.String(), nil
+}
+
+// planText returns the text of a value in the rows of a plan, where the drivers may return the text as bytes.
+func planText(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(t)
+	case string:
+		return t
+	}
+	return fmt.Sprint(v)
+}
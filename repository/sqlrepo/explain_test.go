@@ -0,0 +1,85 @@
+//go:build integration
+
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+
+	entity, err := store.CreateAsset(ctx, &dns.FQDN{Name: "explain.owasp.org"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+
+	plan, err := store.Explain(ctx, "FindEntitiesByType", oam.FQDN, time.Time{})
+	assert.NoError(t, err)
+	assert.Contains(t, plan, store.prefixed("entities"))
+	assert.Greater(t, len(strings.Split(strings.TrimSpace(plan), "\n")), 1)
+
+	plan, err = store.Explain(ctx, "OutgoingEdges", entity, time.Time{}, "dns_record")
+	assert.NoError(t, err)
+	assert.Contains(t, plan, store.prefixed("edges"))
+
+	// an untyped string is converted to the asset type of the parameter
+	_, err = store.Explain(ctx, "CountEntitiesByType", "FQDN", time.Time{})
+	assert.NoError(t, err)
+
+	_, err = store.Explain(ctx, "DeleteEntity", entity.ID)
+	assert.Error(t, err)
+	_, err = store.Explain(ctx, "FindEntityById")
+	assert.Error(t, err)
+	_, err = store.Explain(ctx, "FindEntityById", 42)
+	assert.Error(t, err)
+
+	// the dry run does not affect the repository
+	found, err := store.FindEntityById(ctx, entity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ID, found.ID)
+
+	var _ types.Explainer = store
+}
+
+func TestExplainPagedUsesIndex(t *testing.T) {
+	if store.dbtype == MySQL {
+		t.Skip("the plans are checked on Postgres and SQLite")
+	}
+	ctx := context.Background()
+
+	entity, err := store.CreateAsset(ctx, &dns.FQDN{Name: "paged.explain.owasp.org"})
+	assert.NoError(t, err)
+	defer func() { _, _ = store.DeleteEntity(ctx, entity.ID) }()
+
+	var plan string
+	err = store.WithTransaction(ctx, func(tx types.Repository) error {
+		repo := tx.(*sqlRepository)
+		// the planner prefers a sequential scan of a small table, so it is disabled for the transaction
+		if repo.dbtype == Postgres {
+			if err := repo.db.Exec("SET LOCAL enable_seqscan = off").Error; err != nil {
+				return err
+			}
+		}
+
+		var err error
+		plan, err = repo.Explain(ctx, "FindEntitiesByTypePaged", oam.FQDN, time.Time{}, 0, 10)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, plan, store.prefixed("idx_entities_etype_created_at"))
+	// the rows are read in the order of the index, rather than sorted after the scan
+	assert.NotContains(t, plan, "USE TEMP B-TREE FOR ORDER BY")
+	assert.NotContains(t, plan, "Sort Key")
+}
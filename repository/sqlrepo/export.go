@@ -0,0 +1,118 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/garthoid/asset-db/internal/transfer"
+	"github.com/garthoid/asset-db/types"
+	"gorm.io/gorm"
+)
+
+// ExportJSON writes the entities, edges, and tags in the database to w as newline-delimited JSON,
+// with one types.ExportRecord per line. The entities are written first, followed by the edges, the
+// entity tags, and the edge tags, so each record only refers to records that precede it.
+// Soft-deleted entities, along with the edges and tags attached to them, are not exported.
+// The rows are streamed from the database, and the content is written as stored, without being parsed.
+func (sql *sqlRepository) ExportJSON(ctx context.Context, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	tx := sql.db.WithContext(ctx)
+
+	if err := exportRows(tx.Model(&Entity{}).Order("entity_id"), enc, func(e *Entity) *types.ExportRecord {
+		return &types.ExportRecord{
+			Kind:      types.EntityRecordKind,
+			ID:        strconv.FormatUint(e.ID, 10),
+			Type:      e.Type,
+			Content:   json.RawMessage(e.Content),
+			CreatedAt: e.CreatedAt.UTC(),
+			LastSeen:  e.UpdatedAt.UTC(),
+		}
+	}); err != nil {
+		return err
+	}
+
+	if err := exportRows(sql.liveEdges(ctx).Model(&Edge{}).Order("edge_id"), enc, func(e *Edge) *types.ExportRecord {
+		return &types.ExportRecord{
+			Kind:         types.EdgeRecordKind,
+			ID:           strconv.FormatUint(e.ID, 10),
+			Type:         e.Type,
+			Content:      json.RawMessage(e.Content),
+			CreatedAt:    e.CreatedAt.UTC(),
+			LastSeen:     e.UpdatedAt.UTC(),
+			FromEntityID: strconv.FormatUint(e.FromEntityID, 10),
+			ToEntityID:   strconv.FormatUint(e.ToEntityID, 10),
+		}
+	}); err != nil {
+		return err
+	}
+
+	live := sql.db.Model(&Entity{}).Select("entity_id")
+	if err := exportRows(tx.Model(&EntityTag{}).Where("entity_id IN (?)", live).Order("tag_id"), enc, func(t *EntityTag) *types.ExportRecord {
+		return &types.ExportRecord{
+			Kind:      types.EntityTagRecordKind,
+			ID:        strconv.FormatUint(t.ID, 10),
+			Type:      t.Type,
+			Content:   json.RawMessage(t.Content),
+			CreatedAt: t.CreatedAt.UTC(),
+			LastSeen:  t.UpdatedAt.UTC(),
+			ExpiresAt: expiration(t.ExpiresAt).UTC(),
+			EntityID:  strconv.FormatUint(t.EntityID, 10),
+		}
+	}); err != nil {
+		return err
+	}
+
+	edges := sql.liveEdges(ctx).Model(&Edge{}).Select("edge_id")
+	return exportRows(tx.Model(&EdgeTag{}).Where("edge_id IN (?)", edges).Order("tag_id"), enc, func(t *EdgeTag) *types.ExportRecord {
+		return &types.ExportRecord{
+			Kind:      types.EdgeTagRecordKind,
+			ID:        strconv.FormatUint(t.ID, 10),
+			Type:      t.Type,
+			Content:   json.RawMessage(t.Content),
+			CreatedAt: t.CreatedAt.UTC(),
+			LastSeen:  t.UpdatedAt.UTC(),
+			ExpiresAt: expiration(t.ExpiresAt).UTC(),
+			EdgeID:    strconv.FormatUint(t.EdgeID, 10),
+		}
+	})
+}
+
+// ExportSubgraph writes the root entity, and the entities and edges reached from it within maxDepth outgoing edges,
+// to w as newline-delimited JSON in the format of ExportJSON, along with the tags of those entities and edges.
+// The subgraph is traversed with Neighborhood, so the output only holds the records connected to the root.
+func (sql *sqlRepository) ExportSubgraph(ctx context.Context, root *types.Entity, maxDepth int, w io.Writer) error {
+	return transfer.ExportSubgraph(ctx, sql, root, maxDepth, w)
+}
+
+// ImportJSON reads the newline-delimited JSON written by ExportJSON and recreates the records in the database.
+// The records that match existing entities, edges, or tags are skipped, so a repeated import creates nothing.
+// Returns the number of records created and skipped by kind, or an error if a record cannot be imported.
+func (sql *sqlRepository) ImportJSON(ctx context.Context, r io.Reader) (types.ImportStats, error) {
+	return transfer.ImportJSON(ctx, sql, r)
+}
+
+// exportRows scans the rows of the query one at a time, and writes the record of each row to the encoder.
+func exportRows[T any](tx *gorm.DB, enc *json.Encoder, record func(row *T) *types.ExportRecord) error {
+	rows, err := tx.Rows()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var row T
+		if err := tx.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := enc.Encode(record(&row)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
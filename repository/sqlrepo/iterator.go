@@ -0,0 +1,118 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/garthoid/asset-db/internal/track"
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"gorm.io/gorm"
+)
+
+// entityIterator implements types.EntityIterator by scanning one row at a time from sql.Rows.
+type entityIterator struct {
+	db       *gorm.DB
+	rows     *dbsql.Rows
+	current  *types.Entity
+	err      error
+	open     *track.Set
+	once     sync.Once
+	closeErr error
+}
+
+// IterateEntitiesByType returns an iterator over the entities in the database of the provided asset type
+// and last seen after the since parameter. The entities are ordered by creation time and then by ID.
+// If since.IsZero(), the parameter will be ignored.
+// The iterator holds a database connection until it is closed, so other calls made during the iteration
+// may block when the connection pool is exhausted (e.g. SQLite uses a single connection).
+// Within a transaction, the iterator must be closed before other calls are made on the scoped repository.
+// An iterator left open outside of a transaction is closed by the Close method of the repository.
+func (sql *sqlRepository) IterateEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (types.EntityIterator, error) {
+	tx := sql.db.WithContext(ctx).Model(&Entity{}).Where("etype = ?", atype)
+	if !since.IsZero() {
+		tx = tx.Where("updated_at >= ?", since.UTC())
+	}
+
+	rows, err := tx.Order("created_at, entity_id").Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	it := &entityIterator{db: tx, rows: rows}
+	// the rows of a transaction are released along with the transaction
+	if !sql.intx {
+		it.open = sql.open
+		sql.open.Add(it)
+	}
+	return it, nil
+}
+
+// Next implements the types.EntityIterator interface.
+func (it *entityIterator) Next() bool {
+	it.current = nil
+	if it.err != nil {
+		return false
+	}
+
+	for it.rows.Next() {
+		var e Entity
+		if err := it.db.ScanRows(it.rows, &e); err != nil {
+			it.err = err
+			return false
+		}
+
+		if f, err := e.Parse(); err == nil {
+			it.current = &types.Entity{
+				ID:        strconv.FormatUint(e.ID, 10),
+				CreatedAt: e.CreatedAt.In(time.UTC).Local(),
+				LastSeen:  e.UpdatedAt.In(time.UTC).Local(),
+				Asset:     f,
+			}
+			return true
+		}
+	}
+
+	it.err = it.rows.Err()
+	return false
+}
+
+// Entity implements the types.EntityIterator interface.
+func (it *entityIterator) Entity() *types.Entity {
+	return it.current
+}
+
+// Err implements the types.EntityIterator interface.
+func (it *entityIterator) Err() error {
+	return it.err
+}
+
+// Close implements the types.EntityIterator interface.
+// The iterator may be closed more than once, including by the Close method of the repository.
+func (it *entityIterator) Close() error {
+	it.once.Do(func() {
+		it.current = nil
+		it.closeErr = it.rows.Close()
+		it.open.Remove(it)
+	})
+	return it.closeErr
+}
+
+// closeIterators closes the iterators and the rows of Raw that were left open, and returns an error that reports
+// how many there were.
+func closeIterators(open *track.Set) error {
+	n, err := open.CloseAll()
+	if n == 0 {
+		return err
+	}
+	return errors.Join(fmt.Errorf("%d iterators or rows were left open, and were closed along with the repository", n), err)
+}
@@ -0,0 +1,73 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/garthoid/asset-db/internal/logging"
+	"github.com/garthoid/asset-db/options"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// gormLogger forwards the GORM logs to the logger provided in the repository options.
+type gormLogger struct {
+	log options.Logger
+}
+
+// gormConfig returns the GORM configuration, which is silent unless a logger was provided in the options.
+// The tables of the models are named with the table prefix of the options, as created by the schema migrations.
+func gormConfig(o *options.Options) *gorm.Config {
+	naming := schema.NamingStrategy{TablePrefix: o.TablePrefix}
+	if o.Logger == nil {
+		return &gorm.Config{Logger: logger.Default.LogMode(logger.Silent), NamingStrategy: naming}
+	}
+	return &gorm.Config{Logger: &gormLogger{log: o.Logger}, NamingStrategy: naming}
+}
+
+// LogMode implements the GORM logger.Interface.
+// The level is determined by the provided logger, so the mode is ignored.
+func (g *gormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return g
+}
+
+// Info implements the GORM logger.Interface.
+func (g *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	g.log.Info(fmt.Sprintf(msg, data...), nil)
+}
+
+// Warn implements the GORM logger.Interface.
+func (g *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	g.log.Info(fmt.Sprintf(msg, data...), nil)
+}
+
+// Error implements the GORM logger.Interface.
+func (g *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	g.log.Error(fmt.Sprintf(msg, data...), nil)
+}
+
+// Trace implements the GORM logger.Interface, and emits a record for each executed statement.
+func (g *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	query, rows := fc()
+	fields := map[string]interface{}{
+		"method":   logging.Operation("(*sqlRepository)."),
+		"query":    query,
+		"duration": time.Since(begin),
+		"rows":     rows,
+	}
+
+	// lookups that find nothing are reported to the caller, and are not a failure of the database
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		fields["error"] = err
+		g.log.Error("query failed", fields)
+		return
+	}
+	g.log.Debug("query executed", fields)
+}
@@ -0,0 +1,62 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/options"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type record struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+type testLogger struct {
+	records []record
+}
+
+func (l *testLogger) Debug(msg string, fields map[string]interface{}) {
+	l.records = append(l.records, record{level: "debug", msg: msg, fields: fields})
+}
+
+func (l *testLogger) Info(msg string, fields map[string]interface{}) {
+	l.records = append(l.records, record{level: "info", msg: msg, fields: fields})
+}
+
+func (l *testLogger) Error(msg string, fields map[string]interface{}) {
+	l.records = append(l.records, record{level: "error", msg: msg, fields: fields})
+}
+
+func TestLogger(t *testing.T) {
+	l := new(testLogger)
+	repo, err := New(SQLiteMemory, "file:logger?mode=memory&cache=shared", options.WithLogger(l))
+	assert.NoError(t, err)
+	defer func() { _ = repo.Close() }()
+
+	// the schema was not migrated, so the query fails
+	_, err = repo.FindEntityById(context.Background(), "1")
+	assert.Error(t, err)
+	assert.Len(t, l.records, 1)
+	assert.Equal(t, "error", l.records[0].level)
+	assert.Equal(t, "FindEntityById", l.records[0].fields["method"])
+	assert.Contains(t, l.records[0].fields["query"], "entities")
+	assert.Contains(t, l.records[0].fields, "duration")
+	assert.Contains(t, l.records[0].fields, "error")
+
+	// lookups that find nothing are not logged as failures
+	g := &gormLogger{log: l}
+	g.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 1", 0 }, gorm.ErrRecordNotFound)
+	assert.Len(t, l.records, 2)
+	assert.Equal(t, "debug", l.records[1].level)
+	assert.Equal(t, int64(0), l.records[1].fields["rows"])
+	assert.NotContains(t, l.records[1].fields, "error")
+}
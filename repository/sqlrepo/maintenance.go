@@ -0,0 +1,44 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"errors"
+)
+
+// Maintenance reclaims the space left by the deleted rows and refreshes the statistics of the query planner.
+// Postgres runs VACUUM (SKIP_LOCKED, ANALYZE) on the tables of the schema, which does not block the reads and writes,
+// and skips the tables that are locked by another session, such as by a migration. SQLite runs VACUUM, which rebuilds
+// the database file, so it holds the write lock until it completes, needs free disk space of up to twice the size of
+// the database, and fails while another connection has a transaction open, followed by ANALYZE. MySQL runs ANALYZE
+// TABLE, since OPTIMIZE TABLE rebuilds the tables, so the space of InnoDB is left to be reclaimed by the operator.
+// The whole database is maintained, regardless of the workspace. Returns an error within a transaction, since VACUUM
+// cannot run within a transaction block.
+func (sql *sqlRepository) Maintenance(ctx context.Context) error {
+	if sql.intx {
+		return errors.New("maintenance cannot be run within a transaction")
+	}
+
+	var stmts []string
+	switch sql.dbtype {
+	case Postgres:
+		stmts = []string{"VACUUM (SKIP_LOCKED, ANALYZE) entities, entity_tags, edges, edge_tags"}
+	case MySQL:
+		stmts = []string{"ANALYZE TABLE entities, entity_tags, edges, edge_tags"}
+	case SQLite, SQLiteMemory:
+		stmts = []string{"VACUUM", "ANALYZE"}
+	default:
+		return errors.New("unknown DB type")
+	}
+
+	db := sql.db.WithContext(ctx)
+	for _, stmt := range stmts {
+		if err := db.Exec(sql.prefixed(stmt)).Error; err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
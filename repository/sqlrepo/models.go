@@ -5,7 +5,6 @@
 package sqlrepo
 
 import (
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -25,25 +24,31 @@ import (
 	oamreg "github.com/owasp-amass/open-asset-model/registration"
 	"github.com/owasp-amass/open-asset-model/url"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // Entity represents an entity stored in the database.
 type Entity struct {
-	ID        uint64    `gorm:"primaryKey;column:entity_id"`
-	CreatedAt time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:created_at"`
-	UpdatedAt time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:updated_at"`
-	Type      string    `gorm:"column:etype"`
-	Content   datatypes.JSON
+	ID          uint64    `gorm:"primaryKey;column:entity_id"`
+	CreatedAt   time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:created_at"`
+	UpdatedAt   time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:updated_at"`
+	Type        string    `gorm:"column:etype"`
+	Content     datatypes.JSON
+	ContentHash string         `gorm:"index;column:content_hash"`
+	DeletedAt   gorm.DeletedAt `gorm:"index;column:deleted_at"`
+	Workspace   string         `gorm:"<-:create;column:workspace"`
 }
 
 // EntityTag represents additional metadata added to an entity in the asset database.
 type EntityTag struct {
-	ID        uint64    `gorm:"primaryKey;column:tag_id"`
-	CreatedAt time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:created_at"`
-	UpdatedAt time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:updated_at"`
-	Type      string    `gorm:"column:ttype"`
+	ID        uint64     `gorm:"primaryKey;column:tag_id"`
+	CreatedAt time.Time  `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:created_at"`
+	UpdatedAt time.Time  `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:updated_at"`
+	Type      string     `gorm:"column:ttype"`
+	ExpiresAt *time.Time `gorm:"index;column:expires_at"`
 	Content   datatypes.JSON
 	EntityID  uint64 `gorm:"column:entity_id"`
+	Workspace string `gorm:"<-:create;column:workspace"`
 }
 
 // Edge represents a relationship between two entities stored in the database.
@@ -53,139 +58,30 @@ type Edge struct {
 	UpdatedAt    time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:updated_at"`
 	Type         string    `gorm:"column:etype"`
 	Content      datatypes.JSON
+	Label        string `gorm:"column:label"`
 	FromEntityID uint64 `gorm:"column:from_entity_id"`
 	ToEntityID   uint64 `gorm:"column:to_entity_id"`
+	Workspace    string `gorm:"<-:create;column:workspace"`
 	FromEntity   Entity
 	ToEntity     Entity
 }
 
 // EdgeTag represents additional metadata added to an edge in the asset database.
 type EdgeTag struct {
-	ID        uint64    `gorm:"primaryKey;column:tag_id"`
-	CreatedAt time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:created_at"`
-	UpdatedAt time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:updated_at"`
-	Type      string    `gorm:"column:ttype"`
+	ID        uint64     `gorm:"primaryKey;column:tag_id"`
+	CreatedAt time.Time  `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:created_at"`
+	UpdatedAt time.Time  `gorm:"type:datetime;default:CURRENT_TIMESTAMP();column:updated_at"`
+	Type      string     `gorm:"column:ttype"`
+	ExpiresAt *time.Time `gorm:"index;column:expires_at"`
 	Content   datatypes.JSON
 	EdgeID    uint64 `gorm:"column:edge_id"`
+	Workspace string `gorm:"<-:create;column:workspace"`
 }
 
 // Parse parses the content of the entity into the corresponding Open Asset Model (OAM) asset type.
 // It returns the parsed asset and an error, if any.
 func (e *Entity) Parse() (oam.Asset, error) {
-	var err error
-	var asset oam.Asset
-
-	switch e.Type {
-	case string(oam.Account):
-		var a account.Account
-
-		err = json.Unmarshal(e.Content, &a)
-		asset = &a
-	case string(oam.AutnumRecord):
-		var ar oamreg.AutnumRecord
-
-		err = json.Unmarshal(e.Content, &ar)
-		asset = &ar
-	case string(oam.AutonomousSystem):
-		var as network.AutonomousSystem
-
-		err = json.Unmarshal(e.Content, &as)
-		asset = &as
-	case string(oam.ContactRecord):
-		var cr contact.ContactRecord
-
-		err = json.Unmarshal(e.Content, &cr)
-		asset = &cr
-	case string(oam.DomainRecord):
-		var dr oamreg.DomainRecord
-
-		err = json.Unmarshal(e.Content, &dr)
-		asset = &dr
-	case string(oam.File):
-		var f oamfile.File
-
-		err = json.Unmarshal(e.Content, &f)
-		asset = &f
-	case string(oam.FQDN):
-		var fqdn dns.FQDN
-
-		err = json.Unmarshal(e.Content, &fqdn)
-		asset = &fqdn
-	case string(oam.FundsTransfer):
-		var ft financial.FundsTransfer
-
-		err = json.Unmarshal(e.Content, &ft)
-		asset = &ft
-	case string(oam.Identifier):
-		var id general.Identifier
-
-		err = json.Unmarshal(e.Content, &id)
-		asset = &id
-	case string(oam.IPAddress):
-		var ip network.IPAddress
-
-		err = json.Unmarshal(e.Content, &ip)
-		asset = &ip
-	case string(oam.IPNetRecord):
-		var ipnetrec oamreg.IPNetRecord
-
-		err = json.Unmarshal(e.Content, &ipnetrec)
-		asset = &ipnetrec
-	case string(oam.Location):
-		var location contact.Location
-
-		err = json.Unmarshal(e.Content, &location)
-		asset = &location
-	case string(oam.Netblock):
-		var netblock network.Netblock
-
-		err = json.Unmarshal(e.Content, &netblock)
-		asset = &netblock
-	case string(oam.Organization):
-		var organization org.Organization
-
-		err = json.Unmarshal(e.Content, &organization)
-		asset = &organization
-	case string(oam.Person):
-		var person people.Person
-
-		err = json.Unmarshal(e.Content, &person)
-		asset = &person
-	case string(oam.Phone):
-		var phone contact.Phone
-
-		err = json.Unmarshal(e.Content, &phone)
-		asset = &phone
-	case string(oam.Product):
-		var p platform.Product
-
-		err = json.Unmarshal(e.Content, &p)
-		asset = &p
-	case string(oam.ProductRelease):
-		var pr platform.ProductRelease
-
-		err = json.Unmarshal(e.Content, &pr)
-		asset = &pr
-	case string(oam.Service):
-		var serv platform.Service
-
-		err = json.Unmarshal(e.Content, &serv)
-		asset = &serv
-	case string(oam.TLSCertificate):
-		var tlsCertificate oamtls.TLSCertificate
-
-		err = json.Unmarshal(e.Content, &tlsCertificate)
-		asset = &tlsCertificate
-	case string(oam.URL):
-		var url url.URL
-
-		err = json.Unmarshal(e.Content, &url)
-		asset = &url
-	default:
-		return nil, fmt.Errorf("unknown asset type: %s", e.Type)
-	}
-
-	return asset, err
+	return types.ParseAsset(e.Type, e.Content)
 }
 
 // JSONQuery generates a JSON query expression based on the entity's content.
@@ -248,89 +144,19 @@ func (e *Entity) JSONQuery() (*datatypes.JSONQueryExpression, error) {
 // Parse parses the content of the edge into the corresponding Open Asset Model (OAM) relation type.
 // It returns the parsed relation and an error, if any.
 func (e *Edge) Parse() (oam.Relation, error) {
-	var err error
-	var rel oam.Relation
-
-	switch e.Type {
-	case string(oam.BasicDNSRelation):
-		var bdr dns.BasicDNSRelation
-
-		err = json.Unmarshal(e.Content, &bdr)
-		rel = &bdr
-	case string(oam.PortRelation):
-		var pr general.PortRelation
-
-		err = json.Unmarshal(e.Content, &pr)
-		rel = &pr
-	case string(oam.PrefDNSRelation):
-		var pdr dns.PrefDNSRelation
-
-		err = json.Unmarshal(e.Content, &pdr)
-		rel = &pdr
-	case string(oam.SimpleRelation):
-		var sr general.SimpleRelation
-
-		err = json.Unmarshal(e.Content, &sr)
-		rel = &sr
-	case string(oam.SRVDNSRelation):
-		var sdr dns.SRVDNSRelation
-
-		err = json.Unmarshal(e.Content, &sdr)
-		rel = &sdr
-	default:
-		return nil, fmt.Errorf("unknown relation type: %s", e.Type)
-	}
-
-	return rel, err
+	return types.ParseRelation(e.Type, e.Content)
 }
 
 // Parse parses the content of the entity tag into the corresponding Open Asset Model (OAM) property type.
 // It returns the parsed property and an error, if any.
 func (e *EntityTag) Parse() (oam.Property, error) {
-	return parseProperty(e.Type, e.Content)
+	return types.ParseProperty(e.Type, e.Content)
 }
 
 // Parse parses the content of the edge tag into the corresponding Open Asset Model (OAM) property type.
 // It returns the parsed property and an error, if any.
 func (e *EdgeTag) Parse() (oam.Property, error) {
-	return parseProperty(e.Type, e.Content)
-}
-
-func parseProperty(ptype string, content datatypes.JSON) (oam.Property, error) {
-	var err error
-	var prop oam.Property
-
-	switch ptype {
-	case string(types.CachePropertyType):
-		var cp types.CacheProperty
-
-		err = json.Unmarshal(content, &cp)
-		prop = &cp
-	case string(oam.DNSRecordProperty):
-		var dp dns.DNSRecordProperty
-
-		err = json.Unmarshal(content, &dp)
-		prop = &dp
-	case string(oam.SimpleProperty):
-		var sp general.SimpleProperty
-
-		err = json.Unmarshal(content, &sp)
-		prop = &sp
-	case string(oam.SourceProperty):
-		var sp general.SourceProperty
-
-		err = json.Unmarshal(content, &sp)
-		prop = &sp
-	case string(oam.VulnProperty):
-		var vp platform.VulnProperty
-
-		err = json.Unmarshal(content, &vp)
-		prop = &vp
-	default:
-		return nil, fmt.Errorf("unknown property type: %s", ptype)
-	}
-
-	return prop, err
+	return types.ParseProperty(e.Type, e.Content)
 }
 
 // NameJSONQuery generates the JSON query for the field returned by the Property Name method.
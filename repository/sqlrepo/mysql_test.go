@@ -0,0 +1,35 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var deleteTarget = regexp.MustCompile(`^DELETE FROM (\w+) WHERE (.*)$`)
+
+// TestMySQLDeleteStatements checks that the delete statements run on MySQL, which rejects a DELETE whose
+// subquery reads the table being deleted from (error 1093).
+func TestMySQLDeleteStatements(t *testing.T) {
+	for _, condition := range []string{
+		"workspace = @ws AND etype = @type AND deleted_at IS NULL",
+		"workspace = @ws AND etype = @type AND deleted_at IS NULL AND updated_at < @before",
+	} {
+		stmts := deleteByTypeStatements(condition)
+		assert.Equal(t, "DELETE FROM entities WHERE "+condition, stmts[len(stmts)-1])
+
+		for _, stmt := range stmts {
+			m := deleteTarget.FindStringSubmatch(stmt)
+			if assert.NotNil(t, m, stmt) {
+				assert.NotContains(t, m[2], "FROM "+m[1]+" ", stmt)
+				assert.False(t, strings.HasSuffix(m[2], "FROM "+m[1]), stmt)
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	dbsql "database/sql"
+	"fmt"
+	"time"
+
+	"github.com/garthoid/asset-db/options"
+	"github.com/garthoid/asset-db/types"
+	"gorm.io/gorm"
+)
+
+// acquirePool is the connection pool of GORM when an acquire timeout or the connection health check is set.
+// Each statement is executed on a connection acquired from the database handle under a deadline of the timeout, so a call
+// waiting for a free connection fails with an error matching types.ErrPoolTimeout, while the statement itself remains
+// bound by the context of the call alone. With the health check, each connection is pinged before it is used.
+type acquirePool struct {
+	db          *dbsql.DB
+	timeout     time.Duration
+	healthCheck bool
+}
+
+// installAcquirePool replaces the connection pool of the GORM handle with an acquirePool,
+// when the options set an acquire timeout or the connection health check.
+func installAcquirePool(db *gorm.DB, o *options.Options) error {
+	if o.AcquireTimeout <= 0 && !o.ConnHealthCheck {
+		return nil
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	pool := &acquirePool{db: sqlDB, timeout: o.AcquireTimeout, healthCheck: o.ConnHealthCheck}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+	return nil
+}
+
+// conn acquires a connection from the database handle, waiting no longer than the acquire timeout.
+func (p *acquirePool) conn(ctx context.Context) (*dbsql.Conn, error) {
+	if p.timeout <= 0 {
+		return p.acquire(ctx)
+	}
+
+	actx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	conn, err := p.acquire(actx)
+	if err == nil {
+		return conn, nil
+	}
+	// the error of the context of the call is kept, so a canceled call is not reported as a pool timeout
+	if ctx.Err() == nil && actx.Err() != nil {
+		return nil, &types.Error{
+			Kind: types.ErrPoolTimeout,
+			Err:  fmt.Errorf("no connection became free within the acquire timeout of %s: %w", p.timeout, err),
+		}
+	}
+	return nil, err
+}
+
+// acquire returns a connection of the database handle. With the health check, a connection that fails its ping, such as
+// one left dead by the failover of the server, is discarded and replaced. The replacement is repeated while the pool holds
+// idle connections, which may have failed as well, and the error of the ping is returned when a new connection fails it.
+func (p *acquirePool) acquire(ctx context.Context) (*dbsql.Conn, error) {
+	for {
+		fresh := p.db.Stats().Idle == 0
+
+		conn, err := p.db.Conn(ctx)
+		if err != nil || !p.healthCheck {
+			return conn, err
+		}
+
+		err = conn.PingContext(ctx)
+		if err == nil {
+			return conn, nil
+		}
+		// the pool discards the connection once the driver reports it as bad
+		_ = conn.Close()
+		if fresh || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+}
+
+// release returns the connection to the pool once the rows or the transaction that hold it are closed,
+// since closing the connection waits for them.
+func release(conn *dbsql.Conn) {
+	go func() { _ = conn.Close() }()
+}
+
+// PrepareContext implements the gorm.ConnPool interface. The statements prepared on the database handle
+// acquire a connection each time they are executed.
+func (p *acquirePool) PrepareContext(ctx context.Context, query string) (*dbsql.Stmt, error) {
+	return p.db.PrepareContext(ctx, query)
+}
+
+// ExecContext implements the gorm.ConnPool interface.
+func (p *acquirePool) ExecContext(ctx context.Context, query string, args ...interface{}) (dbsql.Result, error) {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	return conn.ExecContext(ctx, query, args...)
+}
+
+// QueryContext implements the gorm.ConnPool interface.
+func (p *acquirePool) QueryContext(ctx context.Context, query string, args ...interface{}) (*dbsql.Rows, error) {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	release(conn)
+	return rows, nil
+}
+
+// QueryRowContext implements the gorm.ConnPool interface.
+func (p *acquirePool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *dbsql.Row {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		// a row cannot be created with an error, so the query is issued under an expired deadline,
+		// and the Scan of the row reports context.DeadlineExceeded
+		ectx, cancel := context.WithDeadline(ctx, time.Now())
+		defer cancel()
+		return p.db.QueryRowContext(ectx, query, args...)
+	}
+
+	row := conn.QueryRowContext(ctx, query, args...)
+	release(conn)
+	return row
+}
+
+// BeginTx implements the gorm.TxBeginner interface, so the transactions of GORM acquire their connection under the acquire timeout.
+func (p *acquirePool) BeginTx(ctx context.Context, opts *dbsql.TxOptions) (*dbsql.Tx, error) {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	release(conn)
+	return tx, nil
+}
+
+// GetDBConn implements the gorm.GetDBConnector interface, so the database handle remains available to the DB method of GORM.
+func (p *acquirePool) GetDBConn() (*dbsql.DB, error) {
+	return p.db, nil
+}
@@ -0,0 +1,131 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failoverConnector opens connections that stop working once the server has failed over.
+type failoverConnector struct {
+	mu         sync.Mutex
+	generation int
+	opened     int
+}
+
+func (c *failoverConnector) Connect(context.Context) (driver.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.opened++
+	return &failoverConn{connector: c, generation: c.generation}, nil
+}
+
+func (c *failoverConnector) Driver() driver.Driver {
+	return nil
+}
+
+// failover makes the connections opened before it dead.
+func (c *failoverConnector) failover() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+}
+
+func (c *failoverConnector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.opened
+}
+
+type failoverConn struct {
+	connector  *failoverConnector
+	generation int
+}
+
+func (c *failoverConn) dead() bool {
+	c.connector.mu.Lock()
+	defer c.connector.mu.Unlock()
+
+	return c.generation != c.connector.generation
+}
+
+func (c *failoverConn) Ping(context.Context) error {
+	if c.dead() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *failoverConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if c.dead() {
+		// the statement was sent, so the pool cannot retry it on another connection
+		return nil, errors.New("connection reset by peer")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *failoverConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *failoverConn) Close() error {
+	return nil
+}
+
+func (c *failoverConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+// fillPool leaves n idle connections in the pool.
+func fillPool(t *testing.T, db *dbsql.DB, n int) {
+	var conns []*dbsql.Conn
+	for i := 0; i < n; i++ {
+		conn, err := db.Conn(context.Background())
+		assert.NoError(t, err)
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		assert.NoError(t, conn.Close())
+	}
+}
+
+func TestConnHealthCheck(t *testing.T) {
+	connector := &failoverConnector{}
+	db := dbsql.OpenDB(connector)
+	defer func() { _ = db.Close() }()
+	db.SetMaxIdleConns(3)
+
+	// without the health check, the dead connections fail the statements after a failover
+	fillPool(t, db, 3)
+	connector.failover()
+	unchecked := &acquirePool{db: db}
+	_, err := unchecked.ExecContext(context.Background(), "UPDATE entities SET updated_at = now()")
+	assert.Error(t, err)
+
+	// the health check discards the dead connections and opens a new one
+	fillPool(t, db, 3)
+	opened := connector.count()
+	connector.failover()
+	checked := &acquirePool{db: db, healthCheck: true}
+	_, err = checked.ExecContext(context.Background(), "UPDATE entities SET updated_at = now()")
+	assert.NoError(t, err)
+	assert.Equal(t, opened+1, connector.count())
+	assert.Equal(t, 1, db.Stats().OpenConnections)
+
+	// the healthy connections are reused
+	_, err = checked.ExecContext(context.Background(), "UPDATE entities SET updated_at = now()")
+	assert.NoError(t, err)
+	assert.Equal(t, opened+1, connector.count())
+}
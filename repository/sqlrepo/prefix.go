@@ -0,0 +1,40 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"errors"
+	"regexp"
+)
+
+// tablePrefixPattern matches the table prefixes that can be written into a statement without quoting.
+var tablePrefixPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// schemaNamePattern matches the names of the tables, indexes, constraints, and triggers created by the schema migrations,
+// along with the function and the notification channel of the Postgres change notifications.
+var schemaNamePattern = regexp.MustCompile(`\b(entities|entity_tags|edges|edge_tags|(?:idx|fk|trg)_\w+|asset_db_\w+)\b`)
+
+// CheckTablePrefix returns an error if the table prefix set by options.WithTablePrefix cannot be used in the names of the schema,
+// since the prefix is written into the statements. An empty prefix is valid.
+func CheckTablePrefix(prefix string) error {
+	if prefix != "" && !tablePrefixPattern.MatchString(prefix) {
+		return errors.New("the table prefix must only hold letters, digits, and underscores, and not start with a digit")
+	}
+	return nil
+}
+
+// PrefixSchema returns the statement with the table prefix added to the names of the schema, such as the tables and
+// indexes created by the schema migrations. The statement must refer to the tables by their unqualified names.
+func PrefixSchema(prefix, stmt string) string {
+	if prefix == "" {
+		return stmt
+	}
+	return schemaNamePattern.ReplaceAllString(stmt, prefix+"$1")
+}
+
+// prefixed returns the statement with the table prefix of the repository added to the names of the schema.
+func (sql *sqlRepository) prefixed(stmt string) string {
+	return PrefixSchema(sql.prefix, stmt)
+}
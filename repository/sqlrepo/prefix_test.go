@@ -0,0 +1,40 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"testing"
+
+	"github.com/garthoid/asset-db/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTablePrefix(t *testing.T) {
+	for _, prefix := range []string{"", "assetdb_", "_tenant1", "A"} {
+		assert.NoError(t, CheckTablePrefix(prefix), prefix)
+	}
+	for _, prefix := range []string{"1x", "asset-db", "a b", "a;DROP"} {
+		assert.Error(t, CheckTablePrefix(prefix), prefix)
+	}
+
+	_, err := New(SQLiteMemory, "file:prefix?mode=memory&cache=shared", options.WithTablePrefix("asset-db"))
+	assert.Error(t, err)
+
+	// the prefix is checked before connecting, so no server is needed
+	_, err = New(Postgres, "host=127.0.0.1 port=1 user=none dbname=none", options.WithTablePrefix("asset-db"))
+	assert.ErrorContains(t, err, "table prefix")
+}
+
+func TestPrefixSchema(t *testing.T) {
+	stmt := "DELETE FROM edges WHERE from_entity_id IN (SELECT entity_id FROM entities)"
+	assert.Equal(t, stmt, PrefixSchema("", stmt))
+	assert.Equal(t, "DELETE FROM assetdb_edges WHERE from_entity_id IN (SELECT entity_id FROM assetdb_entities)",
+		PrefixSchema("assetdb_", stmt))
+
+	// the names of the indexes, constraints, and triggers are prefixed, but not the columns
+	assert.Equal(t, "CREATE INDEX assetdb_idx_entity_tags_entity_id ON assetdb_entity_tags (entity_id)",
+		PrefixSchema("assetdb_", "CREATE INDEX idx_entity_tags_entity_id ON entity_tags (entity_id)"))
+	assert.Equal(t, "LISTEN assetdb_asset_db_changes", PrefixSchema("assetdb_", "LISTEN asset_db_changes"))
+}
@@ -0,0 +1,105 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	dbsql "database/sql"
+	"sync"
+
+	"github.com/garthoid/asset-db/internal/track"
+	"github.com/garthoid/asset-db/types"
+)
+
+// rawRows implements types.Rows by scanning one row at a time from sql.Rows.
+type rawRows struct {
+	rows     *dbsql.Rows
+	columns  []string
+	values   []interface{}
+	err      error
+	open     *track.Set
+	once     sync.Once
+	closeErr error
+}
+
+// Raw runs the query against the database and returns its rows, with the args bound to the placeholders of the query.
+// It is intended for the ad-hoc queries that the Repository methods do not support, and is UNSAFE: the query is passed
+// to the database as written, so it must be written for the SQL dialect and schema of the database, and a query that
+// modifies the data bypasses the soft-delete, content hash, and duplicate checks of the Repository methods.
+// The args must never be interpolated into the query, and the query is not limited to the workspace of the repository.
+// Within a transaction, the query runs within the transaction.
+// The rows hold a database connection until they are closed, so they must be closed before other calls are made.
+// Rows left open outside of a transaction are closed by the Close method of the repository.
+func (sql *sqlRepository) Raw(ctx context.Context, query string, args ...interface{}) (types.Rows, error) {
+	rows, err := sql.db.WithContext(ctx).Raw(query, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	r := &rawRows{rows: rows, columns: columns}
+	// the rows of a transaction are released along with the transaction
+	if !sql.intx {
+		r.open = sql.open
+		sql.open.Add(r)
+	}
+	return r, nil
+}
+
+// Columns implements the types.Rows interface.
+func (r *rawRows) Columns() []string {
+	return r.columns
+}
+
+// Next implements the types.Rows interface.
+func (r *rawRows) Next() bool {
+	r.values = nil
+	if r.err != nil {
+		return false
+	}
+
+	if !r.rows.Next() {
+		r.err = r.rows.Err()
+		return false
+	}
+
+	values := make([]interface{}, len(r.columns))
+	dest := make([]interface{}, len(r.columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		r.err = err
+		return false
+	}
+
+	r.values = values
+	return true
+}
+
+// Values implements the types.Rows interface.
+// The values are as provided by the driver, and the byte slices are copies that remain valid after the next row.
+func (r *rawRows) Values() []interface{} {
+	return r.values
+}
+
+// Err implements the types.Rows interface.
+func (r *rawRows) Err() error {
+	return r.err
+}
+
+// Close implements the types.Rows interface.
+// The rows may be closed more than once, including by the Close method of the repository.
+func (r *rawRows) Close() error {
+	r.once.Do(func() {
+		r.closeErr = r.rows.Close()
+		r.open.Remove(r)
+	})
+	return r.closeErr
+}
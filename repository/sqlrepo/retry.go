@@ -0,0 +1,50 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/garthoid/asset-db/internal/retry"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// retry executes the provided idempotent operation, which is attempted again after a transient error
+// when retries are enabled. Operations within a transaction are not retried, since the transaction
+// does not survive the failure of its connection.
+func (sql *sqlRepository) retry(ctx context.Context, fn func() error) error {
+	if sql.intx {
+		return fn()
+	}
+	return retry.Do(ctx, sql.maxAttempts, sql.retryDelay, isRetryable, fn)
+}
+
+// isRetryable classifies the errors returned by the SQL drivers, and reports whether the error is transient.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// the connection was dropped or could not be established
+	if isConnectionError(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// serialization failures and deadlocks
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		// lock wait timeouts and deadlocks
+		return myErr.Number == 1205 || myErr.Number == 1213
+	}
+
+	return pgconn.SafeToRetry(err)
+}
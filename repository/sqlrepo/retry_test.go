@@ -0,0 +1,72 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/options"
+	"github.com/garthoid/asset-db/types"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestIsRetryable(t *testing.T) {
+	for _, err := range []error{
+		driver.ErrBadConn,
+		mysqldriver.ErrInvalidConn,
+		fmt.Errorf("read: %w", syscall.ECONNRESET),
+		&pgconn.PgError{Code: "08006"},
+		&pgconn.PgError{Code: "40001"},
+		&mysqldriver.MySQLError{Number: 1213},
+	} {
+		assert.True(t, isRetryable(err), err.Error())
+	}
+
+	for _, err := range []error{
+		nil,
+		context.Canceled,
+		gorm.ErrRecordNotFound,
+		&pgconn.PgError{Code: "23505"},
+		&mysqldriver.MySQLError{Number: 1062},
+	} {
+		assert.False(t, isRetryable(err))
+	}
+}
+
+func TestRetry(t *testing.T) {
+	repo, err := New(SQLiteMemory, "file:retry?mode=memory&cache=shared", options.WithRetry(3, time.Millisecond))
+	assert.NoError(t, err)
+	defer func() { _ = repo.Close() }()
+
+	var calls int
+	err = repo.retry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return driver.ErrBadConn
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	// operations within a transaction are attempted once
+	calls = 0
+	err = repo.WithTransaction(context.Background(), func(tx types.Repository) error {
+		return tx.(*sqlRepository).retry(context.Background(), func() error {
+			calls++
+			return driver.ErrBadConn
+		})
+	})
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,151 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	"gorm.io/gorm"
+)
+
+// Stats returns a snapshot of the contents of the database, with an aggregate query for each of the counts.
+// The entities are counted by asset type and the edges by label, excluding the soft-deleted entities and their edges,
+// while the tags are counted as stored, including the expired tags that remain until PurgeExpiredTags removes them.
+// The counts and timestamps are limited to the workspace of the repository, but the size is that of the whole database.
+// The queries are not run within a single transaction, so the counts may disagree while the data is being modified.
+// Returns the statistics, or an error if any of the queries fails.
+func (sql *sqlRepository) Stats(ctx context.Context) (*types.DBStats, error) {
+	entities, err := sql.CountEntitiesGrouped(ctx, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	edges, err := sql.countEdgesByLabel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &types.DBStats{
+		EntitiesByType: entities,
+		EdgesByLabel:   edges,
+	}
+	if err := sql.countRows(ctx, &EntityTag{}, &stats.EntityTags); err != nil {
+		return nil, err
+	}
+	if err := sql.countRows(ctx, &EdgeTag{}, &stats.EdgeTags); err != nil {
+		return nil, err
+	}
+	if err := sql.entityTimeRange(ctx, stats); err != nil {
+		return nil, err
+	}
+
+	size, err := sql.databaseSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.SizeBytes = size
+	return stats, nil
+}
+
+// countEdgesByLabel counts the live edges by the label in the content of the relation, with a single grouped query.
+func (sql *sqlRepository) countEdgesByLabel(ctx context.Context) (map[string]int64, error) {
+	label, err := sql.labelExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := sql.liveEdges(ctx).Model(&Edge{}).Select(label + " AS label, count(*) AS total").Group(label)
+	tx = tx.Session(&gorm.Session{})
+
+	var rows []struct {
+		Label string
+		Total int64
+	}
+	if err := sql.retry(ctx, func() error {
+		rows = nil
+		return tx.Scan(&rows).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		counts[r.Label] = r.Total
+	}
+	return counts, nil
+}
+
+// labelExpr returns the SQL expression that extracts the label from the content of an edge.
+func (sql *sqlRepository) labelExpr() (string, error) {
+	switch sql.dbtype {
+	case Postgres:
+		return "content->>'label'", nil
+	case MySQL:
+		return "JSON_UNQUOTE(JSON_EXTRACT(content, '$.label'))", nil
+	case SQLite, SQLiteMemory:
+		return "json_extract(content, '$.label')", nil
+	}
+	return "", errors.New("unknown DB type")
+}
+
+// countRows counts the rows of the model in the workspace of the repository.
+func (sql *sqlRepository) countRows(ctx context.Context, model interface{}, total *int64) error {
+	tx := sql.db.WithContext(ctx).Model(model).Session(&gorm.Session{})
+
+	return sql.retry(ctx, func() error {
+		return tx.Count(total).Error
+	})
+}
+
+// entityTimeRange sets the earliest creation time and the latest last seen time of the entities in the stats.
+// The rows are read through the model rather than with MIN and MAX, so the timestamps are parsed as the column type
+// by every driver, including SQLite, which returns the aggregates of the columns as text.
+func (sql *sqlRepository) entityTimeRange(ctx context.Context, stats *types.DBStats) error {
+	var oldest, newest []Entity
+	if err := sql.retry(ctx, func() error {
+		tx := sql.db.WithContext(ctx)
+		if err := tx.Select("created_at").Order("created_at").Limit(1).Find(&oldest).Error; err != nil {
+			return err
+		}
+		return tx.Select("updated_at").Order("updated_at DESC").Limit(1).Find(&newest).Error
+	}); err != nil {
+		return err
+	}
+
+	if len(oldest) > 0 {
+		stats.OldestCreatedAt = oldest[0].CreatedAt.In(time.UTC).Local()
+	}
+	if len(newest) > 0 {
+		stats.NewestLastSeen = newest[0].UpdatedAt.In(time.UTC).Local()
+	}
+	return nil
+}
+
+// databaseSize returns the size of the database on disk, as reported by the database.
+// The size of an in-memory SQLite database is that of the pages it holds in memory.
+func (sql *sqlRepository) databaseSize(ctx context.Context) (int64, error) {
+	var query string
+	switch sql.dbtype {
+	case Postgres:
+		query = "SELECT pg_database_size(current_database())"
+	case MySQL:
+		query = "SELECT COALESCE(SUM(data_length + index_length), 0) FROM information_schema.tables WHERE table_schema = DATABASE()"
+	case SQLite, SQLiteMemory:
+		query = "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()"
+	default:
+		return 0, errors.New("unknown DB type")
+	}
+
+	var size int64
+	if err := sql.retry(ctx, func() error {
+		return sql.db.WithContext(ctx).Raw(query).Scan(&size).Error
+	}); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
@@ -0,0 +1,159 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// notifyChannel is the Postgres channel of the notifications sent by the triggers of the schema migrations.
+const notifyChannel = "asset_db_changes"
+
+// listenRetryDelay is the delay before the listener is reconnected after its connection is lost.
+const listenRetryDelay = time.Second
+
+// changeNotification is the payload of the notifications sent by the triggers.
+type changeNotification struct {
+	Op        string `json:"op"`
+	Kind      string `json:"kind"`
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Workspace string `json:"workspace"`
+}
+
+// Subscribe listens on the asset_db_changes channel of Postgres, named with the table prefix of the repository, and delivers
+// an event for each entity or edge that is created, updated, or deleted by any process, as notified by the triggers added
+// by the schema migrations. Postgres sends the notifications of a transaction once it is committed, and the soft-deletes
+// are delivered as deletes. The listener holds a connection of the pool, and is reconnected when the connection is lost,
+// so the notifications sent while it is reconnecting are missed. The events of other workspaces are skipped. The channel
+// is closed once the context is done. Returns an error if the database is not Postgres, or if the listener cannot be started.
+func (sql *sqlRepository) Subscribe(ctx context.Context) (<-chan types.Event, error) {
+	if sql.dbtype != Postgres {
+		return nil, errors.New("subscriptions are only supported by Postgres")
+	}
+	if sql.intx {
+		return nil, errors.New("subscriptions are not supported within a transaction")
+	}
+
+	db, err := sql.db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// the first listener is started before returning, so that a failure is reported to the caller
+	conn, err := sql.listen(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan types.Event)
+	go func() {
+		defer close(ch)
+
+		for conn != nil {
+			_ = sql.receive(ctx, conn, ch)
+			_ = conn.Close()
+			conn = sql.relisten(ctx, db)
+		}
+	}()
+	return ch, nil
+}
+
+// listen acquires a connection of the pool, and starts listening on the notification channel.
+func (sql *sqlRepository) listen(ctx context.Context, db *dbsql.DB) (*dbsql.Conn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Raw(func(driverConn interface{}) error {
+		if _, ok := driverConn.(*stdlib.Conn); !ok {
+			return errors.New("the connection does not use the pgx driver")
+		}
+		return nil
+	}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, sql.prefixed("LISTEN "+notifyChannel)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// relisten starts a new listener once the delay has passed, until it succeeds or the context is done.
+// Returns nil once the context is done.
+func (sql *sqlRepository) relisten(ctx context.Context, db *dbsql.DB) *dbsql.Conn {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(listenRetryDelay):
+		}
+
+		if conn, err := sql.listen(ctx, db); err == nil {
+			return conn
+		}
+	}
+}
+
+// receive delivers the events of the notifications received by the connection until the context is done or the
+// connection fails. The connection is always discarded afterwards, so that a listening connection is not returned
+// to the pool.
+func (sql *sqlRepository) receive(ctx context.Context, conn *dbsql.Conn, ch chan<- types.Event) error {
+	return conn.Raw(func(driverConn interface{}) error {
+		pc := driverConn.(*stdlib.Conn).Conn()
+
+		for {
+			n, err := pc.WaitForNotification(ctx)
+			if err != nil {
+				return driver.ErrBadConn
+			}
+
+			ev, ok := sql.notificationEvent(n.Payload)
+			if !ok {
+				continue
+			}
+
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return driver.ErrBadConn
+			}
+		}
+	})
+}
+
+// notificationEvent returns the event of the notification payload.
+// Returns false if the payload cannot be parsed, or if the change was made in another workspace.
+func (sql *sqlRepository) notificationEvent(payload string) (types.Event, bool) {
+	var n changeNotification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil || n.Workspace != sql.workspace {
+		return types.Event{}, false
+	}
+
+	var op types.EventOp
+	switch n.Op {
+	case "insert":
+		op = types.EventCreate
+	case "update":
+		op = types.EventUpdate
+	case "delete":
+		op = types.EventDelete
+	default:
+		return types.Event{}, false
+	}
+	return types.Event{Op: op, Kind: n.Kind, ID: n.ID, Type: n.Type}, true
+}
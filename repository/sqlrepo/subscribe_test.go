@@ -0,0 +1,52 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/garthoid/asset-db/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeRequiresPostgres(t *testing.T) {
+	for _, dbtype := range []string{MySQL, SQLite} {
+		repo := &sqlRepository{dbtype: dbtype}
+
+		ch, err := repo.Subscribe(context.Background())
+		assert.Error(t, err)
+		assert.Nil(t, ch)
+	}
+
+	_, err := (&sqlRepository{dbtype: Postgres, intx: true}).Subscribe(context.Background())
+	assert.Error(t, err)
+}
+
+func TestNotificationEvent(t *testing.T) {
+	repo := &sqlRepository{dbtype: Postgres, workspace: "ws1"}
+
+	ev, ok := repo.notificationEvent(`{"op":"insert","kind":"entity","id":"42","type":"FQDN","workspace":"ws1"}`)
+	assert.True(t, ok)
+	assert.Equal(t, types.Event{Op: types.EventCreate, Kind: types.EntityRecordKind, ID: "42", Type: "FQDN"}, ev)
+
+	ev, ok = repo.notificationEvent(`{"op":"update","kind":"edge","id":"7","type":"SimpleRelation","workspace":"ws1"}`)
+	assert.True(t, ok)
+	assert.Equal(t, types.Event{Op: types.EventUpdate, Kind: types.EdgeRecordKind, ID: "7", Type: "SimpleRelation"}, ev)
+
+	ev, ok = repo.notificationEvent(`{"op":"delete","kind":"entity","id":"42","type":"FQDN","workspace":"ws1"}`)
+	assert.True(t, ok)
+	assert.Equal(t, types.EventDelete, ev.Op)
+
+	// the changes made in other workspaces are skipped
+	_, ok = repo.notificationEvent(`{"op":"insert","kind":"entity","id":"43","type":"FQDN","workspace":"ws2"}`)
+	assert.False(t, ok)
+
+	_, ok = repo.notificationEvent(`{"op":"truncate","kind":"entity","workspace":"ws1"}`)
+	assert.False(t, ok)
+
+	_, ok = repo.notificationEvent("not json")
+	assert.False(t, ok)
+}
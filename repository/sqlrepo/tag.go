@@ -5,6 +5,7 @@
 package sqlrepo
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"time"
@@ -18,11 +19,14 @@ import (
 // It takes an EntityTag as input and persists it in the database.
 // The property is serialized to JSON and stored in the Content field of the EntityTag struct.
 // Returns the created entity tag as a types.EntityTag or an error if the creation fails.
-func (sql *sqlRepository) CreateEntityTag(entity *types.Entity, input *types.EntityTag) (*types.EntityTag, error) {
+func (sql *sqlRepository) CreateEntityTag(ctx context.Context, entity *types.Entity, input *types.EntityTag) (*types.EntityTag, error) {
 	entityid, err := strconv.ParseUint(entity.ID, 10, 64)
 	if err != nil {
 		return nil, err
 	}
+	if err := sql.checkOwner(ctx, &Entity{}, "entity_id", entityid); err != nil {
+		return nil, err
+	}
 
 	jsonContent, err := input.Property.JSON()
 	if err != nil {
@@ -30,13 +34,14 @@ func (sql *sqlRepository) CreateEntityTag(entity *types.Entity, input *types.Ent
 	}
 
 	tag := EntityTag{
-		Type:     string(input.Property.PropertyType()),
-		Content:  jsonContent,
-		EntityID: entityid,
+		Type:      string(input.Property.PropertyType()),
+		Content:   jsonContent,
+		EntityID:  entityid,
+		ExpiresAt: expiresAt(input.ExpiresAt),
 	}
 
-	// ensure that duplicate entity tags are not entered into the database
-	if tags, err := sql.GetEntityTags(entity, time.Time{}, input.Property.Name()); err == nil && len(tags) > 0 {
+	// ensure that duplicate entity tags are not entered into the database, including the tags that have expired
+	if tags, err := sql.entityTagsBetween(ctx, entity, time.Time{}, time.Time{}, true, input.Property.Name()); err == nil && len(tags) > 0 {
 		for _, t := range tags {
 			if input.Property.PropertyType() == t.Property.PropertyType() && input.Property.Value() == t.Property.Value() {
 				if id, err := strconv.ParseUint(t.ID, 10, 64); err == nil {
@@ -61,7 +66,7 @@ func (sql *sqlRepository) CreateEntityTag(entity *types.Entity, input *types.Ent
 		}
 	}
 
-	result := sql.db.Save(&tag)
+	result := sql.db.WithContext(ctx).Save(&tag)
 	if err := result.Error; err != nil {
 		return nil, err
 	}
@@ -70,31 +75,194 @@ func (sql *sqlRepository) CreateEntityTag(entity *types.Entity, input *types.Ent
 		ID:        strconv.FormatUint(tag.ID, 10),
 		CreatedAt: tag.CreatedAt.In(time.UTC).Local(),
 		LastSeen:  tag.UpdatedAt.In(time.UTC).Local(),
+		ExpiresAt: expiration(tag.ExpiresAt),
 		Property:  input.Property,
 		Entity:    entity,
 	}, nil
 }
 
+// CreateEntityTags creates the same tag on each of the entities in the database within a single transaction.
+// The new tags are inserted in batches, and the existing tag of an entity with the same property type, name, and value
+// is updated rather than duplicated. The entities that are not found are skipped rather than failing the batch.
+// Returns the entity tags in the order of the entity IDs, along with a *types.MissingEntitiesError that lists the
+// skipped entities, or an error if the creation fails.
+func (sql *sqlRepository) CreateEntityTags(ctx context.Context, entityIDs []string, input *types.EntityTag) ([]*types.EntityTag, error) {
+	if input == nil || input.Property == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	jsonContent, err := input.Property.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	template := EntityTag{
+		Type:    string(input.Property.PropertyType()),
+		Content: jsonContent,
+	}
+
+	nameQuery, err := template.NameJSONQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	var invalid []string
+	seen := make(map[uint64]struct{}, len(entityIDs))
+	for _, id := range entityIDs {
+		entityId, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			// an invalid ID cannot match an entity
+			invalid = append(invalid, id)
+			continue
+		}
+
+		if _, found := seen[entityId]; !found {
+			seen[entityId] = struct{}{}
+			ids = append(ids, entityId)
+		}
+	}
+
+	var missing []string
+	var results []*types.EntityTag
+	err = sql.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing := make(map[uint64]struct{}, len(ids))
+		dups := make(map[uint64]EntityTag)
+
+		for start := 0; start < len(ids); start += sql.batchSize {
+			chunk := ids[start:min(start+sql.batchSize, len(ids))]
+
+			var found []uint64
+			if err := tx.Model(&Entity{}).Where("entity_id IN ?", chunk).Pluck("entity_id", &found).Error; err != nil {
+				return err
+			}
+			for _, id := range found {
+				existing[id] = struct{}{}
+			}
+
+			// ensure that duplicate entity tags are not entered into the database
+			var tags []EntityTag
+			if err := tx.Where("entity_id IN ? AND ttype = ?", chunk, template.Type).Where(nameQuery).Find(&tags).Error; err != nil {
+				return err
+			}
+			for _, t := range tags {
+				if prop, err := t.Parse(); err == nil && prop.Value() == input.Property.Value() {
+					if _, found := dups[t.EntityID]; !found {
+						dups[t.EntityID] = t
+					}
+				}
+			}
+		}
+
+		now := time.Now().UTC()
+		expires := expiresAt(input.ExpiresAt)
+		var touched []uint64
+		var rows []*EntityTag
+		for _, id := range ids {
+			if _, found := existing[id]; !found {
+				continue
+			}
+			if t, found := dups[id]; found {
+				touched = append(touched, t.ID)
+				continue
+			}
+
+			row := &EntityTag{
+				Type:      template.Type,
+				Content:   jsonContent,
+				EntityID:  id,
+				CreatedAt: now,
+				UpdatedAt: now,
+				ExpiresAt: expires,
+			}
+			if !input.CreatedAt.IsZero() {
+				row.CreatedAt = input.CreatedAt.UTC()
+			}
+			if !input.LastSeen.IsZero() {
+				row.UpdatedAt = input.LastSeen.UTC()
+			}
+			rows = append(rows, row)
+		}
+
+		for start := 0; start < len(touched); start += sql.batchSize {
+			chunk := touched[start:min(start+sql.batchSize, len(touched))]
+
+			if err := tx.Model(&EntityTag{}).Where("tag_id IN ?", chunk).Updates(map[string]interface{}{
+				"updated_at": now,
+				"expires_at": expires,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if len(rows) > 0 {
+			if err := tx.CreateInBatches(rows, sql.batchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		created := make(map[uint64]*EntityTag, len(rows))
+		for _, row := range rows {
+			created[row.EntityID] = row
+		}
+
+		for _, id := range ids {
+			if _, found := existing[id]; !found {
+				missing = append(missing, strconv.FormatUint(id, 10))
+				continue
+			}
+
+			row := created[id]
+			if t, dup := dups[id]; dup {
+				t.UpdatedAt = now
+				t.ExpiresAt = expires
+				row = &t
+			}
+
+			results = append(results, &types.EntityTag{
+				ID:        strconv.FormatUint(row.ID, 10),
+				CreatedAt: row.CreatedAt.In(time.UTC).Local(),
+				LastSeen:  row.UpdatedAt.In(time.UTC).Local(),
+				ExpiresAt: expiration(row.ExpiresAt),
+				Property:  input.Property,
+				Entity:    &types.Entity{ID: strconv.FormatUint(id, 10)},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if missing = append(invalid, missing...); len(missing) > 0 {
+		return results, &types.MissingEntitiesError{IDs: missing}
+	}
+	return results, nil
+}
+
 // CreateEntityProperty creates a new entity tag in the database.
 // It takes an oam.Property as input and persists it in the database.
 // The property is serialized to JSON and stored in the Content field of the EntityTag struct.
 // Returns the created entity tag as a types.EntityTag or an error if the creation fails.
-func (sql *sqlRepository) CreateEntityProperty(entity *types.Entity, prop oam.Property) (*types.EntityTag, error) {
-	return sql.CreateEntityTag(entity, &types.EntityTag{Property: prop})
+func (sql *sqlRepository) CreateEntityProperty(ctx context.Context, entity *types.Entity, prop oam.Property) (*types.EntityTag, error) {
+	return sql.CreateEntityTag(ctx, entity, &types.EntityTag{Property: prop})
 }
 
 // FindEntityTagById finds an entity tag in the database by the ID.
 // It takes a string representing the entity tag ID and retrieves the corresponding tag from the database.
-// Returns the discovered tag as a types.EntityTag or an error if the asset is not found.
-func (sql *sqlRepository) FindEntityTagById(id string) (*types.EntityTag, error) {
+// Returns the discovered tag as a types.EntityTag, types.ErrTagNotFound if the tag is not found, or an error if the search fails.
+func (sql *sqlRepository) FindEntityTagById(ctx context.Context, id string) (*types.EntityTag, error) {
 	tagId, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		return nil, err
+		// an invalid ID cannot match a tag
+		return nil, types.ErrTagNotFound
 	}
 
 	tag := EntityTag{ID: tagId}
-	result := sql.db.First(&tag)
-	if err := result.Error; err != nil {
+	if err := sql.retry(ctx, func() error {
+		return sql.db.WithContext(ctx).First(&tag).Error
+	}); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, types.ErrTagNotFound
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -107,6 +275,7 @@ func (sql *sqlRepository) FindEntityTagById(id string) (*types.EntityTag, error)
 		ID:        strconv.FormatUint(tag.ID, 10),
 		CreatedAt: tag.CreatedAt.In(time.UTC).Local(),
 		LastSeen:  tag.UpdatedAt.In(time.UTC).Local(),
+		ExpiresAt: expiration(tag.ExpiresAt),
 		Property:  data,
 		Entity:    &types.Entity{ID: strconv.FormatUint(tag.EntityID, 10)},
 	}, nil
@@ -117,7 +286,7 @@ func (sql *sqlRepository) FindEntityTagById(id string) (*types.EntityTag, error)
 // If since.IsZero(), the parameter will be ignored.
 // The property data is serialized to JSON and compared against the Content field of the EntityTag struct.
 // Returns a slice of matching entity tags as []*types.EntityTag or an error if the search fails.
-func (sql *sqlRepository) FindEntityTagsByContent(prop oam.Property, since time.Time) ([]*types.EntityTag, error) {
+func (sql *sqlRepository) FindEntityTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EntityTag, error) {
 	jsonContent, err := prop.JSON()
 	if err != nil {
 		return nil, err
@@ -138,14 +307,16 @@ func (sql *sqlRepository) FindEntityTagsByContent(prop oam.Property, since time.
 		return nil, err
 	}
 
-	tx := sql.db.Where("ttype = ?", tag.Type)
+	tx := sql.db.WithContext(ctx).Where("ttype = ?", tag.Type)
 	if !since.IsZero() {
 		tx = tx.Where("updated_at >= ?", since.UTC())
 	}
 
 	var tags []EntityTag
-	tx = tx.Where(nameQuery).Where(valueQuery).Find(&tags)
-	if err := tx.Error; err != nil {
+	tx = tx.Where(nameQuery).Where(valueQuery).Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&tags).Error
+	}); err != nil {
 		return nil, err
 	}
 
@@ -156,6 +327,7 @@ func (sql *sqlRepository) FindEntityTagsByContent(prop oam.Property, since time.
 				ID:        strconv.FormatUint(t.ID, 10),
 				CreatedAt: t.CreatedAt.In(time.UTC).Local(),
 				LastSeen:  t.UpdatedAt.In(time.UTC).Local(),
+				ExpiresAt: expiration(t.ExpiresAt),
 				Property:  propData,
 				Entity:    &types.Entity{ID: strconv.FormatUint(t.EntityID, 10)},
 			})
@@ -163,7 +335,7 @@ func (sql *sqlRepository) FindEntityTagsByContent(prop oam.Property, since time.
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero entity tags found")
+		return nil, types.NotFound("zero entity tags found")
 	}
 	return results, nil
 }
@@ -171,20 +343,35 @@ func (sql *sqlRepository) FindEntityTagsByContent(prop oam.Property, since time.
 // GetEntityTags finds all tags for the entity with the specified names and last seen after the since parameter.
 // If since.IsZero(), the parameter will be ignored.
 // If no names are specified, all tags for the specified entity are returned.
-func (sql *sqlRepository) GetEntityTags(entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error) {
+func (sql *sqlRepository) GetEntityTags(ctx context.Context, entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error) {
+	return sql.GetEntityTagsBetween(ctx, entity, since, time.Time{}, names...)
+}
+
+// GetEntityTagsBetween finds all tags for the entity with the specified names and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no names are specified, all tags for the specified entity in the window are returned.
+func (sql *sqlRepository) GetEntityTagsBetween(ctx context.Context, entity *types.Entity, from, to time.Time, names ...string) ([]*types.EntityTag, error) {
+	return sql.entityTagsBetween(ctx, entity, from, to, sql.expiredTags, names...)
+}
+
+// entityTagsBetween finds the tags for the entity with the specified names and last seen within the [from, to) window,
+// and only includes the tags that have expired when the expired parameter is true.
+func (sql *sqlRepository) entityTagsBetween(ctx context.Context, entity *types.Entity, from, to time.Time, expired bool, names ...string) ([]*types.EntityTag, error) {
 	entityId, err := strconv.ParseInt(entity.ID, 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
-	var tags []EntityTag
-	var result *gorm.DB
-	if since.IsZero() {
-		result = sql.db.Where("entity_id = ?", entityId).Find(&tags)
-	} else {
-		result = sql.db.Where("entity_id = ? AND updated_at >= ?", entityId, since.UTC()).Find(&tags)
+	tx := seenBetween(sql.db.WithContext(ctx).Where("entity_id = ?", entityId), from, to)
+	if !expired {
+		tx = unexpired(tx)
 	}
-	if err := result.Error; err != nil {
+
+	var tags []EntityTag
+	tx = tx.Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&tags).Error
+	}); err != nil {
 		return nil, err
 	}
 
@@ -212,6 +399,7 @@ func (sql *sqlRepository) GetEntityTags(entity *types.Entity, since time.Time, n
 					ID:        strconv.Itoa(int(t.ID)),
 					CreatedAt: t.CreatedAt.In(time.UTC).Local(),
 					LastSeen:  t.UpdatedAt.In(time.UTC).Local(),
+					ExpiresAt: expiration(t.ExpiresAt),
 					Property:  prop,
 					Entity:    entity,
 				})
@@ -220,37 +408,230 @@ func (sql *sqlRepository) GetEntityTags(entity *types.Entity, since time.Time, n
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero tags found")
+		return nil, types.NotFound("zero tags found")
 	}
 	return results, nil
 }
 
+// GetEntityTagsMatching finds the tags for the entity with the provided name and value, which were last seen after the
+// since parameter. The tags of each property type are matched by the fields returned by the Property Name and Value
+// methods, and only the name is matched when the value is empty.
+// If since.IsZero(), the parameter will be ignored.
+// Returns a slice of the matching tags as []*types.EntityTag or an error if the search fails.
+func (sql *sqlRepository) GetEntityTagsMatching(ctx context.Context, entity *types.Entity, since time.Time, name, value string) ([]*types.EntityTag, error) {
+	if name == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	entityId, err := strconv.ParseUint(entity.ID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := sql.namedTagMatch(name, value)
+	if err != nil {
+		return nil, err
+	}
+	if match == nil {
+		return nil, types.NotFound("zero tags found")
+	}
+
+	tx := sql.db.WithContext(ctx).Where("entity_id = ?", entityId).Where(match)
+	if !since.IsZero() {
+		tx = tx.Where("updated_at >= ?", since.UTC())
+	}
+	if !sql.expiredTags {
+		tx = unexpired(tx)
+	}
+
+	var tags []EntityTag
+	tx = tx.Order("tag_id").Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&tags).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	var results []*types.EntityTag
+	for _, t := range tags {
+		if prop, err := t.Parse(); err == nil {
+			results = append(results, &types.EntityTag{
+				ID:        strconv.FormatUint(t.ID, 10),
+				CreatedAt: t.CreatedAt.In(time.UTC).Local(),
+				LastSeen:  t.UpdatedAt.In(time.UTC).Local(),
+				ExpiresAt: expiration(t.ExpiresAt),
+				Property:  prop,
+				Entity:    entity,
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero tags found")
+	}
+	return results, nil
+}
+
+// FindEntitiesByTag finds the entities in the database with a tag of the provided name and value, which was last seen
+// after the since parameter. The tags of each property type are matched by the field returned by the Property Name method,
+// and by the field returned by the Property Value method, unless the value is empty and only the name is matched.
+// If since.IsZero(), the parameter will be ignored.
+// Returns a slice of the tagged entities as []*types.Entity or an error if the search fails.
+func (sql *sqlRepository) FindEntitiesByTag(ctx context.Context, name, value string, since time.Time) ([]*types.Entity, error) {
+	if name == "" {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	match, err := sql.namedTagMatch(name, value)
+	if err != nil {
+		return nil, err
+	}
+	if match == nil {
+		return nil, types.NotFound("zero entities found")
+	}
+
+	tags := sql.db.Model(&EntityTag{}).Select("entity_id").Where(match)
+	if !since.IsZero() {
+		tags = tags.Where("updated_at >= ?", since.UTC())
+	}
+
+	var entities []Entity
+	tx := sql.db.WithContext(ctx).Where("entity_id IN (?)", tags).Order("entity_id").Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&entities).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	var results []*types.Entity
+	for _, e := range entities {
+		if assetData, err := e.Parse(); err == nil {
+			results = append(results, &types.Entity{
+				ID:        strconv.FormatUint(e.ID, 10),
+				CreatedAt: e.CreatedAt.In(time.UTC).Local(),
+				LastSeen:  e.UpdatedAt.In(time.UTC).Local(),
+				Asset:     assetData,
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, types.NotFound("zero entities found")
+	}
+	return results, nil
+}
+
+// namedTagMatch returns the condition that matches the tags with the provided name and value across the property types,
+// or nil when no property type can hold the value. Only the name is matched when the value is empty.
+func (sql *sqlRepository) namedTagMatch(name, value string) (*gorm.DB, error) {
+	var match *gorm.DB
+	for _, prop := range types.NamedProperties(name, value) {
+		nameQuery, err := propertyNameJSONQuery(prop)
+		if err != nil {
+			return nil, err
+		}
+
+		cond := sql.db.Where("ttype = ?", string(prop.PropertyType())).Where(nameQuery)
+		if value != "" {
+			valueQuery, err := propertyValueJSONQuery(prop)
+			if err != nil {
+				return nil, err
+			}
+			cond = cond.Where(valueQuery)
+		}
+
+		if match == nil {
+			match = sql.db.Where(cond)
+		} else {
+			match = match.Or(cond)
+		}
+	}
+	return match, nil
+}
+
+// UpdateEntityTag sets the value of the property of the entity tag in the database, and updates the updated_at
+// time while preserving the created_at time of the tag.
+// Returns the updated entity tag as a types.EntityTag, types.ErrTagNotFound if the tag is not found, or an error if the update fails.
+func (sql *sqlRepository) UpdateEntityTag(ctx context.Context, id string, value string) (*types.EntityTag, error) {
+	tagId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, types.ErrTagNotFound
+	}
+
+	var prop oam.Property
+	tag := EntityTag{ID: tagId}
+	err = sql.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&tag).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return types.ErrTagNotFound
+		} else if err != nil {
+			return err
+		}
+
+		current, err := tag.Parse()
+		if err != nil {
+			return err
+		}
+
+		prop, err = types.WithValue(current, value)
+		if err != nil {
+			return err
+		}
+
+		jsonContent, err := prop.JSON()
+		if err != nil {
+			return err
+		}
+
+		tag.Content = jsonContent
+		tag.UpdatedAt = time.Now().UTC()
+		return tx.Model(&tag).Updates(map[string]interface{}{
+			"content":    tag.Content,
+			"updated_at": tag.UpdatedAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.EntityTag{
+		ID:        strconv.FormatUint(tag.ID, 10),
+		CreatedAt: tag.CreatedAt.In(time.UTC).Local(),
+		LastSeen:  tag.UpdatedAt.In(time.UTC).Local(),
+		ExpiresAt: expiration(tag.ExpiresAt),
+		Property:  prop,
+		Entity:    &types.Entity{ID: strconv.FormatUint(tag.EntityID, 10)},
+	}, nil
+}
+
 // DeleteEntityTag removes an entity tag in the database by its ID.
 // It takes a string representing the entity tag ID and removes the corresponding tag from the database.
-// Returns an error if the tag is not found.
-func (sql *sqlRepository) DeleteEntityTag(id string) error {
+// Returns the number of rows removed, which is zero when the tag is not found.
+func (sql *sqlRepository) DeleteEntityTag(ctx context.Context, id string) (int64, error) {
 	tagId, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	tag := EntityTag{ID: tagId}
-	result := sql.db.Delete(&tag)
+	result := sql.db.WithContext(ctx).Delete(&tag)
 	if err := result.Error; err != nil {
-		return err
+		return 0, err
 	}
-	return nil
+	return result.RowsAffected, nil
 }
 
 // CreateEdgeTag creates a new edge tag in the database.
 // It takes an EdgeTag as input and persists it in the database.
 // The property is serialized to JSON and stored in the Content field of the EdgeTag struct.
 // Returns the created edge tag as a types.EdgeTag or an error if the creation fails.
-func (sql *sqlRepository) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag) (*types.EdgeTag, error) {
+func (sql *sqlRepository) CreateEdgeTag(ctx context.Context, edge *types.Edge, input *types.EdgeTag) (*types.EdgeTag, error) {
 	edgeid, err := strconv.ParseUint(edge.ID, 10, 64)
 	if err != nil {
 		return nil, err
 	}
+	if err := sql.checkOwner(ctx, &Edge{}, "edge_id", edgeid); err != nil {
+		return nil, err
+	}
 
 	jsonContent, err := input.Property.JSON()
 	if err != nil {
@@ -258,13 +639,14 @@ func (sql *sqlRepository) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag)
 	}
 
 	tag := EdgeTag{
-		Type:    string(input.Property.PropertyType()),
-		Content: jsonContent,
-		EdgeID:  edgeid,
+		Type:      string(input.Property.PropertyType()),
+		Content:   jsonContent,
+		EdgeID:    edgeid,
+		ExpiresAt: expiresAt(input.ExpiresAt),
 	}
 
-	// ensure that duplicate edge tags are not entered into the database
-	if tags, err := sql.GetEdgeTags(edge, time.Time{}, input.Property.Name()); err == nil && len(tags) > 0 {
+	// ensure that duplicate edge tags are not entered into the database, including the tags that have expired
+	if tags, err := sql.edgeTagsBetween(ctx, edge, time.Time{}, time.Time{}, true, input.Property.Name()); err == nil && len(tags) > 0 {
 		for _, t := range tags {
 			if input.Property.PropertyType() == t.Property.PropertyType() && input.Property.Value() == t.Property.Value() {
 				if id, err := strconv.ParseUint(t.ID, 10, 64); err == nil {
@@ -289,7 +671,7 @@ func (sql *sqlRepository) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag)
 		}
 	}
 
-	result := sql.db.Save(&tag)
+	result := sql.db.WithContext(ctx).Save(&tag)
 	if err := result.Error; err != nil {
 		return nil, err
 	}
@@ -298,31 +680,49 @@ func (sql *sqlRepository) CreateEdgeTag(edge *types.Edge, input *types.EdgeTag)
 		ID:        strconv.FormatUint(tag.ID, 10),
 		CreatedAt: tag.CreatedAt.In(time.UTC).Local(),
 		LastSeen:  tag.UpdatedAt.In(time.UTC).Local(),
+		ExpiresAt: expiration(tag.ExpiresAt),
 		Property:  input.Property,
 		Edge:      edge,
 	}, nil
 }
 
+// checkOwner returns an error matching types.ErrNotFound when no entity or edge of the workspace, as selected by
+// the model, holds the ID, such as one of another workspace, so a tag is not attached to it.
+func (sql *sqlRepository) checkOwner(ctx context.Context, model interface{}, column string, id uint64) error {
+	var count int64
+	if err := sql.db.WithContext(ctx).Unscoped().Model(model).Where(column+" = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return types.NotFound("the owner of the tag was not found in the workspace")
+	}
+	return nil
+}
+
 // CreateEdgeProperty creates a new edge tag in the database.
 // It takes an oam.Property as input and persists it in the database.
 // The property is serialized to JSON and stored in the Content field of the EdgeTag struct.
 // Returns the created edge tag as a types.EdgeTag or an error if the creation fails.
-func (sql *sqlRepository) CreateEdgeProperty(edge *types.Edge, prop oam.Property) (*types.EdgeTag, error) {
-	return sql.CreateEdgeTag(edge, &types.EdgeTag{Property: prop})
+func (sql *sqlRepository) CreateEdgeProperty(ctx context.Context, edge *types.Edge, prop oam.Property) (*types.EdgeTag, error) {
+	return sql.CreateEdgeTag(ctx, edge, &types.EdgeTag{Property: prop})
 }
 
 // FindEdgeTagById finds an edge tag in the database by the ID.
 // It takes a string representing the edge tag ID and retrieves the corresponding tag from the database.
-// Returns the discovered tag as a types.EdgeTag or an error if the asset is not found.
-func (sql *sqlRepository) FindEdgeTagById(id string) (*types.EdgeTag, error) {
+// Returns the discovered tag as a types.EdgeTag, types.ErrTagNotFound if the tag is not found, or an error if the search fails.
+func (sql *sqlRepository) FindEdgeTagById(ctx context.Context, id string) (*types.EdgeTag, error) {
 	tagId, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		return nil, err
+		// an invalid ID cannot match a tag
+		return nil, types.ErrTagNotFound
 	}
 
 	tag := EdgeTag{ID: tagId}
-	result := sql.db.First(&tag)
-	if err := result.Error; err != nil {
+	if err := sql.retry(ctx, func() error {
+		return sql.db.WithContext(ctx).First(&tag).Error
+	}); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, types.ErrTagNotFound
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -331,7 +731,7 @@ func (sql *sqlRepository) FindEdgeTagById(id string) (*types.EdgeTag, error) {
 		return nil, err
 	}
 
-	edge, err := sql.FindEdgeById(strconv.FormatUint(tag.EdgeID, 10))
+	edge, err := sql.FindEdgeById(ctx, strconv.FormatUint(tag.EdgeID, 10))
 	if err != nil {
 		return nil, err
 	}
@@ -340,6 +740,7 @@ func (sql *sqlRepository) FindEdgeTagById(id string) (*types.EdgeTag, error) {
 		ID:        strconv.FormatUint(tag.ID, 10),
 		CreatedAt: tag.CreatedAt.In(time.UTC).Local(),
 		LastSeen:  tag.UpdatedAt.In(time.UTC).Local(),
+		ExpiresAt: expiration(tag.ExpiresAt),
 		Property:  data,
 		Edge:      edge,
 	}, nil
@@ -350,7 +751,7 @@ func (sql *sqlRepository) FindEdgeTagById(id string) (*types.EdgeTag, error) {
 // If since.IsZero(), the parameter will be ignored.
 // The property data is serialized to JSON and compared against the Content field of the EdgeTag struct.
 // Returns a slice of matching edge tags as []*types.EdgeTag or an error if the search fails.
-func (sql *sqlRepository) FindEdgeTagsByContent(prop oam.Property, since time.Time) ([]*types.EdgeTag, error) {
+func (sql *sqlRepository) FindEdgeTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EdgeTag, error) {
 	jsonContent, err := prop.JSON()
 	if err != nil {
 		return nil, err
@@ -371,14 +772,16 @@ func (sql *sqlRepository) FindEdgeTagsByContent(prop oam.Property, since time.Ti
 		return nil, err
 	}
 
-	tx := sql.db.Where("ttype = ?", tag.Type)
+	tx := sql.db.WithContext(ctx).Where("ttype = ?", tag.Type)
 	if !since.IsZero() {
 		tx = tx.Where("updated_at >= ?", since.UTC())
 	}
 
 	var tags []EdgeTag
-	tx = tx.Where(nameQuery).Where(valueQuery).Find(&tags)
-	if err := tx.Error; err != nil {
+	tx = tx.Where(nameQuery).Where(valueQuery).Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&tags).Error
+	}); err != nil {
 		return nil, err
 	}
 
@@ -389,6 +792,7 @@ func (sql *sqlRepository) FindEdgeTagsByContent(prop oam.Property, since time.Ti
 				ID:        strconv.FormatUint(t.ID, 10),
 				CreatedAt: t.CreatedAt.In(time.UTC).Local(),
 				LastSeen:  t.UpdatedAt.In(time.UTC).Local(),
+				ExpiresAt: expiration(t.ExpiresAt),
 				Property:  propData,
 				Edge:      &types.Edge{ID: strconv.FormatUint(t.EdgeID, 10)},
 			})
@@ -396,7 +800,7 @@ func (sql *sqlRepository) FindEdgeTagsByContent(prop oam.Property, since time.Ti
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero edge tags found")
+		return nil, types.NotFound("zero edge tags found")
 	}
 	return results, nil
 }
@@ -404,20 +808,35 @@ func (sql *sqlRepository) FindEdgeTagsByContent(prop oam.Property, since time.Ti
 // GetEdgeTags finds all tags for the edge with the specified names and last seen after the since parameter.
 // If since.IsZero(), the parameter will be ignored.
 // If no names are specified, all tags for the specified edge are returned.
-func (sql *sqlRepository) GetEdgeTags(edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error) {
+func (sql *sqlRepository) GetEdgeTags(ctx context.Context, edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error) {
+	return sql.GetEdgeTagsBetween(ctx, edge, since, time.Time{}, names...)
+}
+
+// GetEdgeTagsBetween finds all tags for the edge with the specified names and last seen within the [from, to) window.
+// If from.IsZero() or to.IsZero(), that side of the window is left open, and a window where to is not after from is empty.
+// If no names are specified, all tags for the specified edge in the window are returned.
+func (sql *sqlRepository) GetEdgeTagsBetween(ctx context.Context, edge *types.Edge, from, to time.Time, names ...string) ([]*types.EdgeTag, error) {
+	return sql.edgeTagsBetween(ctx, edge, from, to, sql.expiredTags, names...)
+}
+
+// edgeTagsBetween finds the tags for the edge with the specified names and last seen within the [from, to) window,
+// and only includes the tags that have expired when the expired parameter is true.
+func (sql *sqlRepository) edgeTagsBetween(ctx context.Context, edge *types.Edge, from, to time.Time, expired bool, names ...string) ([]*types.EdgeTag, error) {
 	edgeId, err := strconv.ParseInt(edge.ID, 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
-	var tags []EdgeTag
-	var result *gorm.DB
-	if since.IsZero() {
-		result = sql.db.Where("edge_id = ?", edgeId).Find(&tags)
-	} else {
-		result = sql.db.Where("edge_id = ? AND updated_at >= ?", edgeId, since.UTC()).Find(&tags)
+	tx := seenBetween(sql.db.WithContext(ctx).Where("edge_id = ?", edgeId), from, to)
+	if !expired {
+		tx = unexpired(tx)
 	}
-	if err := result.Error; err != nil {
+
+	var tags []EdgeTag
+	tx = tx.Session(&gorm.Session{})
+	if err := sql.retry(ctx, func() error {
+		return tx.Find(&tags).Error
+	}); err != nil {
 		return nil, err
 	}
 
@@ -445,6 +864,7 @@ func (sql *sqlRepository) GetEdgeTags(edge *types.Edge, since time.Time, names .
 					ID:        strconv.Itoa(int(t.ID)),
 					CreatedAt: t.CreatedAt.In(time.UTC).Local(),
 					LastSeen:  t.UpdatedAt.In(time.UTC).Local(),
+					ExpiresAt: expiration(t.ExpiresAt),
 					Property:  prop,
 					Edge:      edge,
 				})
@@ -453,24 +873,133 @@ func (sql *sqlRepository) GetEdgeTags(edge *types.Edge, since time.Time, names .
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("zero tags found")
+		return nil, types.NotFound("zero tags found")
 	}
 	return results, nil
 }
 
+// UpdateEdgeTag sets the value of the property of the edge tag in the database, and updates the updated_at
+// time while preserving the created_at time of the tag.
+// Returns the updated edge tag as a types.EdgeTag, types.ErrTagNotFound if the tag is not found, or an error if the update fails.
+func (sql *sqlRepository) UpdateEdgeTag(ctx context.Context, id string, value string) (*types.EdgeTag, error) {
+	tagId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, types.ErrTagNotFound
+	}
+
+	var prop oam.Property
+	tag := EdgeTag{ID: tagId}
+	err = sql.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&tag).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return types.ErrTagNotFound
+		} else if err != nil {
+			return err
+		}
+
+		current, err := tag.Parse()
+		if err != nil {
+			return err
+		}
+
+		prop, err = types.WithValue(current, value)
+		if err != nil {
+			return err
+		}
+
+		jsonContent, err := prop.JSON()
+		if err != nil {
+			return err
+		}
+
+		tag.Content = jsonContent
+		tag.UpdatedAt = time.Now().UTC()
+		return tx.Model(&tag).Updates(map[string]interface{}{
+			"content":    tag.Content,
+			"updated_at": tag.UpdatedAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	edge, err := sql.FindEdgeById(ctx, strconv.FormatUint(tag.EdgeID, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.EdgeTag{
+		ID:        strconv.FormatUint(tag.ID, 10),
+		CreatedAt: tag.CreatedAt.In(time.UTC).Local(),
+		LastSeen:  tag.UpdatedAt.In(time.UTC).Local(),
+		ExpiresAt: expiration(tag.ExpiresAt),
+		Property:  prop,
+		Edge:      edge,
+	}, nil
+}
+
 // DeleteEdgeTag removes an edge tag in the database by its ID.
 // It takes a string representing the edge tag ID and removes the corresponding tag from the database.
-// Returns an error if the tag is not found.
-func (sql *sqlRepository) DeleteEdgeTag(id string) error {
+// Returns the number of rows removed, which is zero when the tag is not found.
+func (sql *sqlRepository) DeleteEdgeTag(ctx context.Context, id string) (int64, error) {
 	tagId, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	tag := EdgeTag{ID: tagId}
-	result := sql.db.Delete(&tag)
+	result := sql.db.WithContext(ctx).Delete(&tag)
 	if err := result.Error; err != nil {
-		return err
+		return 0, err
 	}
-	return nil
+	return result.RowsAffected, nil
+}
+
+// PurgeExpiredTags removes the entity and edge tags in the database that expired at or before the provided time,
+// within a single transaction. A zero time removes the tags that have expired by now.
+// Returns the number of tags removed.
+func (sql *sqlRepository) PurgeExpiredTags(ctx context.Context, before time.Time) (int64, error) {
+	if before.IsZero() {
+		before = time.Now()
+	}
+
+	var count int64
+	err := sql.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		count = 0
+
+		for _, model := range []interface{}{&EntityTag{}, &EdgeTag{}} {
+			result := tx.Where("expires_at IS NOT NULL AND expires_at <= ?", before.UTC()).Delete(model)
+			if err := result.Error; err != nil {
+				return err
+			}
+			count += result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// unexpired limits the query to the tags that do not expire, or have not expired by now.
+func unexpired(tx *gorm.DB) *gorm.DB {
+	return tx.Where("expires_at IS NULL OR expires_at > ?", time.Now().UTC())
+}
+
+// expiresAt returns the expiration time stored with a tag, which is nil when the tag does not expire.
+func expiresAt(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	utc := t.UTC()
+	return &utc
+}
+
+// expiration returns the expiration time of a stored tag, which is the zero time when the tag does not expire.
+func expiration(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.In(time.UTC).Local()
 }
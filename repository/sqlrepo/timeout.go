@@ -0,0 +1,56 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/garthoid/asset-db/internal/timeout"
+	"gorm.io/gorm"
+)
+
+// queryTimeoutCancel is the key of the instance setting that holds the cancel function of the statement context.
+const queryTimeoutCancel = "assetdb:query_timeout_cancel"
+
+// registerQueryTimeout adds the callbacks that bound the context of each statement by the query timeout,
+// when the context has no deadline. The row callbacks are not bound, since the rows are read after the
+// callbacks return, so the streaming calls are bound by the provided context alone.
+func registerQueryTimeout(db *gorm.DB, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	start := func(tx *gorm.DB) {
+		ctx, cancel := timeout.Context(tx.Statement.Context, d)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(queryTimeoutCancel, cancel)
+	}
+	finish := func(tx *gorm.DB) {
+		if cancel, ok := tx.InstanceGet(queryTimeoutCancel); ok {
+			cancel.(context.CancelFunc)()
+		}
+	}
+
+	cb := db.Callback()
+	for _, err := range []error{
+		// the statements that write rows are bound along with the transaction GORM wraps them in
+		cb.Create().Before("gorm:begin_transaction").Register("assetdb:query_timeout", start),
+		cb.Create().After("gorm:commit_or_rollback_transaction").Register("assetdb:query_timeout_cancel", finish),
+		cb.Update().Before("gorm:begin_transaction").Register("assetdb:query_timeout", start),
+		cb.Update().After("gorm:commit_or_rollback_transaction").Register("assetdb:query_timeout_cancel", finish),
+		cb.Delete().Before("gorm:begin_transaction").Register("assetdb:query_timeout", start),
+		cb.Delete().After("gorm:commit_or_rollback_transaction").Register("assetdb:query_timeout_cancel", finish),
+		cb.Query().Before("gorm:query").Register("assetdb:query_timeout", start),
+		cb.Query().After("gorm:query").Register("assetdb:query_timeout_cancel", finish),
+		cb.Raw().Before("gorm:raw").Register("assetdb:query_timeout", start),
+		cb.Raw().After("gorm:raw").Register("assetdb:query_timeout_cancel", finish),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
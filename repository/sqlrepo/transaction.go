@@ -0,0 +1,29 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"context"
+
+	"github.com/garthoid/asset-db/types"
+	"gorm.io/gorm"
+)
+
+// WithTransaction executes the provided function within a single database transaction.
+// The repository passed to fn performs all of its operations using the transaction, which is
+// committed when fn returns nil and rolled back otherwise. Calls made on a repository that is
+// already scoped to a transaction reuse that transaction.
+func (sql *sqlRepository) WithTransaction(ctx context.Context, fn func(tx types.Repository) error) error {
+	if sql.intx {
+		return fn(sql)
+	}
+
+	return translateError(sql.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txrepo := *sql
+		txrepo.db = tx
+		txrepo.intx = true
+		return fn(&txrepo)
+	}))
+}
@@ -0,0 +1,59 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package sqlrepo
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// registerWorkspace adds the callbacks that limit each statement on the tables of the models to the workspace,
+// and set the workspace of the rows that are created, so repositories with different workspaces do not see
+// each other's data. The raw statements are not limited, so the statements of the repository written as raw
+// SQL add the condition themselves.
+func registerWorkspace(db *gorm.DB, workspace string) error {
+	where := func(tx *gorm.DB) {
+		if tx.Statement.Schema == nil || tx.Statement.Schema.LookUpField("workspace") == nil {
+			return
+		}
+		tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "workspace"}, Value: workspace},
+		}})
+	}
+	set := func(tx *gorm.DB) {
+		if tx.Statement.Schema == nil {
+			return
+		}
+		field := tx.Statement.Schema.LookUpField("workspace")
+		if field == nil {
+			return
+		}
+
+		switch rv := reflect.Indirect(tx.Statement.ReflectValue); rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				_ = field.Set(tx.Statement.Context, rv.Index(i), workspace)
+			}
+		case reflect.Struct:
+			_ = field.Set(tx.Statement.Context, rv, workspace)
+		}
+	}
+
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("assetdb:workspace", set),
+		cb.Query().Before("gorm:query").Register("assetdb:workspace", where),
+		cb.Update().Before("gorm:update").Register("assetdb:workspace", where),
+		cb.Delete().Before("gorm:delete").Register("assetdb:workspace", where),
+		cb.Row().Before("gorm:row").Register("assetdb:workspace", where),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,667 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing decorates a repository with OpenTelemetry spans for each of its operations.
+// The spans are named "assetdb.<Method>" and carry the "db.system" and "db.operation" attributes,
+// along with the "assetdb.entity.type" attribute when the operation concerns a specific asset type.
+type Tracing struct {
+	db     types.Repository
+	tracer trace.Tracer
+	system string
+}
+
+// New returns a repository that starts a span with the provided tracer for each operation of the provided repository.
+// The spans are children of the span carried by the context passed to the operation.
+func New(db types.Repository, tracer trace.Tracer) *Tracing {
+	return &Tracing{
+		db:     db,
+		tracer: tracer,
+		system: dbSystem(db.GetDBType()),
+	}
+}
+
+// dbSystem returns the OpenTelemetry database system name for the provided repository type.
+func dbSystem(dbtype string) string {
+	switch dbtype {
+	case "postgres":
+		return "postgresql"
+	case "sqlite", "sqlite_memory":
+		return "sqlite"
+	}
+	return dbtype
+}
+
+func (tr *Tracing) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tr.tracer.Start(ctx, "assetdb."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", tr.system),
+			attribute.String("db.operation", method),
+		),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func typeAttr(atype oam.AssetType) []attribute.KeyValue {
+	return []attribute.KeyValue{attribute.String("assetdb.entity.type", string(atype))}
+}
+
+func typesAttr(atypes []oam.AssetType) []attribute.KeyValue {
+	names := make([]string, 0, len(atypes))
+	for _, atype := range atypes {
+		names = append(names, string(atype))
+	}
+	return []attribute.KeyValue{attribute.StringSlice("assetdb.entity.types", names)}
+}
+
+func assetType(asset oam.Asset) []attribute.KeyValue {
+	if asset == nil {
+		return nil
+	}
+	return typeAttr(asset.AssetType())
+}
+
+func entityType(entity *types.Entity) []attribute.KeyValue {
+	if entity == nil {
+		return nil
+	}
+	return assetType(entity.Asset)
+}
+
+// GetDBType implements the Repository interface.
+func (tr *Tracing) GetDBType() string {
+	return tr.db.GetDBType()
+}
+
+// Ping implements the Repository interface.
+func (tr *Tracing) Ping(ctx context.Context) error {
+	ctx, span := tr.start(ctx, "Ping")
+	err := tr.db.Ping(ctx)
+	end(span, err)
+	return err
+}
+
+// Maintenance implements the Repository interface.
+func (tr *Tracing) Maintenance(ctx context.Context) error {
+	ctx, span := tr.start(ctx, "Maintenance")
+	err := tr.db.Maintenance(ctx)
+	end(span, err)
+	return err
+}
+
+// Stats implements the Repository interface.
+func (tr *Tracing) Stats(ctx context.Context) (*types.DBStats, error) {
+	ctx, span := tr.start(ctx, "Stats")
+	stats, err := tr.db.Stats(ctx)
+	end(span, err)
+	return stats, err
+}
+
+// CreateEntity implements the Repository interface.
+func (tr *Tracing) CreateEntity(ctx context.Context, entity *types.Entity) (*types.Entity, error) {
+	ctx, span := tr.start(ctx, "CreateEntity", entityType(entity)...)
+	e, err := tr.db.CreateEntity(ctx, entity)
+	end(span, err)
+	return e, err
+}
+
+// CreateAsset implements the Repository interface.
+func (tr *Tracing) CreateAsset(ctx context.Context, asset oam.Asset) (*types.Entity, error) {
+	ctx, span := tr.start(ctx, "CreateAsset", assetType(asset)...)
+	e, err := tr.db.CreateAsset(ctx, asset)
+	end(span, err)
+	return e, err
+}
+
+// CreateEntities implements the Repository interface.
+func (tr *Tracing) CreateEntities(ctx context.Context, entities []*types.Entity) ([]*types.Entity, error) {
+	ctx, span := tr.start(ctx, "CreateEntities")
+	results, err := tr.db.CreateEntities(ctx, entities)
+	end(span, err)
+	return results, err
+}
+
+// UpsertEntity implements the Repository interface.
+func (tr *Tracing) UpsertEntity(ctx context.Context, entity *types.Entity) (*types.Entity, types.UpsertResult, error) {
+	ctx, span := tr.start(ctx, "UpsertEntity", entityType(entity)...)
+	e, result, err := tr.db.UpsertEntity(ctx, entity)
+	end(span, err)
+	return e, result, err
+}
+
+// UpdateEntity implements the Repository interface.
+func (tr *Tracing) UpdateEntity(ctx context.Context, id string, asset oam.Asset) (*types.Entity, error) {
+	ctx, span := tr.start(ctx, "UpdateEntity", assetType(asset)...)
+	e, err := tr.db.UpdateEntity(ctx, id, asset)
+	end(span, err)
+	return e, err
+}
+
+// TouchEntity implements the Repository interface.
+func (tr *Tracing) TouchEntity(ctx context.Context, id string) error {
+	ctx, span := tr.start(ctx, "TouchEntity")
+	err := tr.db.TouchEntity(ctx, id)
+	end(span, err)
+	return err
+}
+
+// FindEntityById implements the Repository interface.
+func (tr *Tracing) FindEntityById(ctx context.Context, id string) (*types.Entity, error) {
+	ctx, span := tr.start(ctx, "FindEntityById")
+	e, err := tr.db.FindEntityById(ctx, id)
+	end(span, err)
+	return e, err
+}
+
+// FindEntityByHash implements the Repository interface.
+func (tr *Tracing) FindEntityByHash(ctx context.Context, hash string) (*types.Entity, error) {
+	ctx, span := tr.start(ctx, "FindEntityByHash")
+	e, err := tr.db.FindEntityByHash(ctx, hash)
+	end(span, err)
+	return e, err
+}
+
+// EntityExists implements the Repository interface.
+func (tr *Tracing) EntityExists(ctx context.Context, asset oam.Asset) (bool, error) {
+	ctx, span := tr.start(ctx, "EntityExists", assetType(asset)...)
+	found, err := tr.db.EntityExists(ctx, asset)
+	end(span, err)
+	return found, err
+}
+
+// FindEntitiesByContent implements the Repository interface.
+func (tr *Tracing) FindEntitiesByContent(ctx context.Context, asset oam.Asset, since time.Time) ([]*types.Entity, error) {
+	ctx, span := tr.start(ctx, "FindEntitiesByContent", assetType(asset)...)
+	results, err := tr.db.FindEntitiesByContent(ctx, asset, since)
+	end(span, err)
+	return results, err
+}
+
+// FindEntitiesByContents implements the Repository interface.
+func (tr *Tracing) FindEntitiesByContents(ctx context.Context, assets []oam.Asset, since time.Time) (map[string]*types.Entity, error) {
+	ctx, span := tr.start(ctx, "FindEntitiesByContents")
+	results, err := tr.db.FindEntitiesByContents(ctx, assets, since)
+	end(span, err)
+	return results, err
+}
+
+// FindEntitiesByType implements the Repository interface.
+func (tr *Tracing) FindEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	ctx, span := tr.start(ctx, "FindEntitiesByType", typeAttr(atype)...)
+	results, err := tr.db.FindEntitiesByType(ctx, atype, since)
+	end(span, err)
+	return results, err
+}
+
+// FindEntitiesByTypes implements the Repository interface.
+func (tr *Tracing) FindEntitiesByTypes(ctx context.Context, since time.Time, atypes ...oam.AssetType) ([]*types.Entity, error) {
+	ctx, span := tr.start(ctx, "FindEntitiesByTypes", typesAttr(atypes)...)
+	results, err := tr.db.FindEntitiesByTypes(ctx, since, atypes...)
+	end(span, err)
+	return results, err
+}
+
+// FindEntitiesByTypeBetween implements the Repository interface.
+func (tr *Tracing) FindEntitiesByTypeBetween(ctx context.Context, atype oam.AssetType, from, to time.Time) ([]*types.Entity, error) {
+	ctx, span := tr.start(ctx, "FindEntitiesByTypeBetween", typeAttr(atype)...)
+	results, err := tr.db.FindEntitiesByTypeBetween(ctx, atype, from, to)
+	end(span, err)
+	return results, err
+}
+
+// DiffEntities implements the Repository interface.
+func (tr *Tracing) DiffEntities(ctx context.Context, atype oam.AssetType, t1, t2 time.Time) ([]*types.Entity, []*types.Entity, error) {
+	ctx, span := tr.start(ctx, "DiffEntities", typeAttr(atype)...)
+	added, removed, err := tr.db.DiffEntities(ctx, atype, t1, t2)
+	end(span, err)
+	return added, removed, err
+}
+
+// SearchEntities implements the Repository interface.
+func (tr *Tracing) SearchEntities(ctx context.Context, atype oam.AssetType, query string, since time.Time) ([]*types.Entity, error) {
+	ctx, span := tr.start(ctx, "SearchEntities", typeAttr(atype)...)
+	results, err := tr.db.SearchEntities(ctx, atype, query, since)
+	end(span, err)
+	return results, err
+}
+
+// FindEntitiesByTypePaged implements the Repository interface.
+func (tr *Tracing) FindEntitiesByTypePaged(ctx context.Context, atype oam.AssetType, since time.Time, offset, limit int) ([]*types.Entity, int64, error) {
+	ctx, span := tr.start(ctx, "FindEntitiesByTypePaged", typeAttr(atype)...)
+	results, total, err := tr.db.FindEntitiesByTypePaged(ctx, atype, since, offset, limit)
+	end(span, err)
+	return results, total, err
+}
+
+// IterateEntitiesByType implements the Repository interface.
+func (tr *Tracing) IterateEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (types.EntityIterator, error) {
+	ctx, span := tr.start(ctx, "IterateEntitiesByType", typeAttr(atype)...)
+	iter, err := tr.db.IterateEntitiesByType(ctx, atype, since)
+	end(span, err)
+	return iter, err
+}
+
+// CountEntitiesByType implements the Repository interface.
+func (tr *Tracing) CountEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (int64, error) {
+	ctx, span := tr.start(ctx, "CountEntitiesByType", typeAttr(atype)...)
+	count, err := tr.db.CountEntitiesByType(ctx, atype, since)
+	end(span, err)
+	return count, err
+}
+
+// CountEntitiesGrouped implements the Repository interface.
+func (tr *Tracing) CountEntitiesGrouped(ctx context.Context, since time.Time) (map[oam.AssetType]int64, error) {
+	ctx, span := tr.start(ctx, "CountEntitiesGrouped")
+	counts, err := tr.db.CountEntitiesGrouped(ctx, since)
+	end(span, err)
+	return counts, err
+}
+
+// DeleteEntity implements the Repository interface.
+func (tr *Tracing) DeleteEntity(ctx context.Context, id string) (int64, error) {
+	ctx, span := tr.start(ctx, "DeleteEntity")
+	n, err := tr.db.DeleteEntity(ctx, id)
+	end(span, err)
+	return n, err
+}
+
+// DeleteEntityCascade implements the Repository interface.
+func (tr *Tracing) DeleteEntityCascade(ctx context.Context, id string) (int64, error) {
+	ctx, span := tr.start(ctx, "DeleteEntityCascade")
+	n, err := tr.db.DeleteEntityCascade(ctx, id)
+	end(span, err)
+	return n, err
+}
+
+// DeleteEntitiesByType implements the Repository interface.
+func (tr *Tracing) DeleteEntitiesByType(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	ctx, span := tr.start(ctx, "DeleteEntitiesByType", typeAttr(atype)...)
+	n, err := tr.db.DeleteEntitiesByType(ctx, atype, before)
+	end(span, err)
+	return n, err
+}
+
+// FindOrphanEntities implements the Repository interface.
+func (tr *Tracing) FindOrphanEntities(ctx context.Context, atype oam.AssetType, since time.Time) ([]*types.Entity, error) {
+	ctx, span := tr.start(ctx, "FindOrphanEntities", typeAttr(atype)...)
+	results, err := tr.db.FindOrphanEntities(ctx, atype, since)
+	end(span, err)
+	return results, err
+}
+
+// DeleteOrphanEntities implements the Repository interface.
+func (tr *Tracing) DeleteOrphanEntities(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error) {
+	ctx, span := tr.start(ctx, "DeleteOrphanEntities", typeAttr(atype)...)
+	n, err := tr.db.DeleteOrphanEntities(ctx, atype, before)
+	end(span, err)
+	return n, err
+}
+
+// FindDeletedEntities implements the Repository interface.
+func (tr *Tracing) FindDeletedEntities(ctx context.Context, since time.Time) ([]*types.Entity, error) {
+	ctx, span := tr.start(ctx, "FindDeletedEntities")
+	results, err := tr.db.FindDeletedEntities(ctx, since)
+	end(span, err)
+	return results, err
+}
+
+// PurgeDeleted implements the Repository interface.
+func (tr *Tracing) PurgeDeleted(ctx context.Context, before time.Time) error {
+	ctx, span := tr.start(ctx, "PurgeDeleted")
+	err := tr.db.PurgeDeleted(ctx, before)
+	end(span, err)
+	return err
+}
+
+// CreateEdge implements the Repository interface.
+func (tr *Tracing) CreateEdge(ctx context.Context, edge *types.Edge) (*types.Edge, error) {
+	ctx, span := tr.start(ctx, "CreateEdge")
+	e, err := tr.db.CreateEdge(ctx, edge)
+	end(span, err)
+	return e, err
+}
+
+// UpsertEdge implements the Repository interface.
+func (tr *Tracing) UpsertEdge(ctx context.Context, edge *types.Edge) (*types.Edge, types.UpsertResult, error) {
+	ctx, span := tr.start(ctx, "UpsertEdge")
+	e, result, err := tr.db.UpsertEdge(ctx, edge)
+	end(span, err)
+	return e, result, err
+}
+
+// FindEdgeById implements the Repository interface.
+func (tr *Tracing) FindEdgeById(ctx context.Context, id string) (*types.Edge, error) {
+	ctx, span := tr.start(ctx, "FindEdgeById")
+	e, err := tr.db.FindEdgeById(ctx, id)
+	end(span, err)
+	return e, err
+}
+
+// IncomingEdges implements the Repository interface.
+func (tr *Tracing) IncomingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	ctx, span := tr.start(ctx, "IncomingEdges", entityType(entity)...)
+	results, err := tr.db.IncomingEdges(ctx, entity, since, labels...)
+	end(span, err)
+	return results, err
+}
+
+// OutgoingEdges implements the Repository interface.
+func (tr *Tracing) OutgoingEdges(ctx context.Context, entity *types.Entity, since time.Time, labels ...string) ([]*types.Edge, error) {
+	ctx, span := tr.start(ctx, "OutgoingEdges", entityType(entity)...)
+	results, err := tr.db.OutgoingEdges(ctx, entity, since, labels...)
+	end(span, err)
+	return results, err
+}
+
+// IncomingEdgesBetween implements the Repository interface.
+func (tr *Tracing) IncomingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	ctx, span := tr.start(ctx, "IncomingEdgesBetween", entityType(entity)...)
+	results, err := tr.db.IncomingEdgesBetween(ctx, entity, from, to, labels...)
+	end(span, err)
+	return results, err
+}
+
+// OutgoingEdgesBetween implements the Repository interface.
+func (tr *Tracing) OutgoingEdgesBetween(ctx context.Context, entity *types.Entity, from, to time.Time, labels ...string) ([]*types.Edge, error) {
+	ctx, span := tr.start(ctx, "OutgoingEdgesBetween", entityType(entity)...)
+	results, err := tr.db.OutgoingEdgesBetween(ctx, entity, from, to, labels...)
+	end(span, err)
+	return results, err
+}
+
+// IncomingEdgesForAll implements the Repository interface.
+func (tr *Tracing) IncomingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	ctx, span := tr.start(ctx, "IncomingEdgesForAll")
+	results, err := tr.db.IncomingEdgesForAll(ctx, entities, since, labels...)
+	end(span, err)
+	return results, err
+}
+
+// OutgoingEdgesForAll implements the Repository interface.
+func (tr *Tracing) OutgoingEdgesForAll(ctx context.Context, entities []*types.Entity, since time.Time, labels ...string) (map[string][]*types.Edge, error) {
+	ctx, span := tr.start(ctx, "OutgoingEdgesForAll")
+	results, err := tr.db.OutgoingEdgesForAll(ctx, entities, since, labels...)
+	end(span, err)
+	return results, err
+}
+
+// Neighborhood implements the Repository interface.
+func (tr *Tracing) Neighborhood(ctx context.Context, entity *types.Entity, maxDepth int, since time.Time, labels ...string) ([]*types.Entity, []*types.Edge, error) {
+	ctx, span := tr.start(ctx, "Neighborhood", entityType(entity)...)
+	entities, edges, err := tr.db.Neighborhood(ctx, entity, maxDepth, since, labels...)
+	end(span, err)
+	return entities, edges, err
+}
+
+// FindEdgesByLabel implements the Repository interface.
+func (tr *Tracing) FindEdgesByLabel(ctx context.Context, label string, since time.Time) ([]*types.Edge, error) {
+	ctx, span := tr.start(ctx, "FindEdgesByLabel")
+	results, err := tr.db.FindEdgesByLabel(ctx, label, since)
+	end(span, err)
+	return results, err
+}
+
+// FindEdgesSince implements the Repository interface.
+func (tr *Tracing) FindEdgesSince(ctx context.Context, since time.Time, includeTags bool) ([]*types.Edge, error) {
+	ctx, span := tr.start(ctx, "FindEdgesSince")
+	results, err := tr.db.FindEdgesSince(ctx, since, includeTags)
+	end(span, err)
+	return results, err
+}
+
+// ResolveEdgeEndpoints implements the Repository interface.
+func (tr *Tracing) ResolveEdgeEndpoints(ctx context.Context, edges []*types.Edge) (map[string]*types.Entity, error) {
+	ctx, span := tr.start(ctx, "ResolveEdgeEndpoints")
+	results, err := tr.db.ResolveEdgeEndpoints(ctx, edges)
+	end(span, err)
+	return results, err
+}
+
+// CountEdges implements the Repository interface.
+func (tr *Tracing) CountEdges(ctx context.Context, since time.Time) (int64, error) {
+	ctx, span := tr.start(ctx, "CountEdges")
+	count, err := tr.db.CountEdges(ctx, since)
+	end(span, err)
+	return count, err
+}
+
+// EntityDegree implements the Repository interface.
+func (tr *Tracing) EntityDegree(ctx context.Context, entity *types.Entity, since time.Time) (int64, int64, error) {
+	ctx, span := tr.start(ctx, "EntityDegree", entityType(entity)...)
+	in, out, err := tr.db.EntityDegree(ctx, entity, since)
+	end(span, err)
+	return in, out, err
+}
+
+// DeleteEdge implements the Repository interface.
+func (tr *Tracing) DeleteEdge(ctx context.Context, id string) (int64, error) {
+	ctx, span := tr.start(ctx, "DeleteEdge")
+	n, err := tr.db.DeleteEdge(ctx, id)
+	end(span, err)
+	return n, err
+}
+
+// CreateEntityTag implements the Repository interface.
+func (tr *Tracing) CreateEntityTag(ctx context.Context, entity *types.Entity, tag *types.EntityTag) (*types.EntityTag, error) {
+	ctx, span := tr.start(ctx, "CreateEntityTag", entityType(entity)...)
+	t, err := tr.db.CreateEntityTag(ctx, entity, tag)
+	end(span, err)
+	return t, err
+}
+
+// CreateEntityTags implements the Repository interface.
+func (tr *Tracing) CreateEntityTags(ctx context.Context, entityIDs []string, tag *types.EntityTag) ([]*types.EntityTag, error) {
+	ctx, span := tr.start(ctx, "CreateEntityTags")
+	tags, err := tr.db.CreateEntityTags(ctx, entityIDs, tag)
+	end(span, err)
+	return tags, err
+}
+
+// CreateEntityProperty implements the Repository interface.
+func (tr *Tracing) CreateEntityProperty(ctx context.Context, entity *types.Entity, property oam.Property) (*types.EntityTag, error) {
+	ctx, span := tr.start(ctx, "CreateEntityProperty", entityType(entity)...)
+	t, err := tr.db.CreateEntityProperty(ctx, entity, property)
+	end(span, err)
+	return t, err
+}
+
+// FindEntityTagById implements the Repository interface.
+func (tr *Tracing) FindEntityTagById(ctx context.Context, id string) (*types.EntityTag, error) {
+	ctx, span := tr.start(ctx, "FindEntityTagById")
+	t, err := tr.db.FindEntityTagById(ctx, id)
+	end(span, err)
+	return t, err
+}
+
+// FindEntityTagsByContent implements the Repository interface.
+func (tr *Tracing) FindEntityTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EntityTag, error) {
+	ctx, span := tr.start(ctx, "FindEntityTagsByContent")
+	results, err := tr.db.FindEntityTagsByContent(ctx, prop, since)
+	end(span, err)
+	return results, err
+}
+
+// GetEntityTags implements the Repository interface.
+func (tr *Tracing) GetEntityTags(ctx context.Context, entity *types.Entity, since time.Time, names ...string) ([]*types.EntityTag, error) {
+	ctx, span := tr.start(ctx, "GetEntityTags", entityType(entity)...)
+	results, err := tr.db.GetEntityTags(ctx, entity, since, names...)
+	end(span, err)
+	return results, err
+}
+
+// GetEntityTagsBetween implements the Repository interface.
+func (tr *Tracing) GetEntityTagsBetween(ctx context.Context, entity *types.Entity, from, to time.Time, names ...string) ([]*types.EntityTag, error) {
+	ctx, span := tr.start(ctx, "GetEntityTagsBetween", entityType(entity)...)
+	results, err := tr.db.GetEntityTagsBetween(ctx, entity, from, to, names...)
+	end(span, err)
+	return results, err
+}
+
+// GetEntityTagsMatching implements the Repository interface.
+func (tr *Tracing) GetEntityTagsMatching(ctx context.Context, entity *types.Entity, since time.Time, name, value string) ([]*types.EntityTag, error) {
+	ctx, span := tr.start(ctx, "GetEntityTagsMatching", entityType(entity)...)
+	results, err := tr.db.GetEntityTagsMatching(ctx, entity, since, name, value)
+	end(span, err)
+	return results, err
+}
+
+// FindEntitiesByTag implements the Repository interface.
+func (tr *Tracing) FindEntitiesByTag(ctx context.Context, name, value string, since time.Time) ([]*types.Entity, error) {
+	ctx, span := tr.start(ctx, "FindEntitiesByTag")
+	entities, err := tr.db.FindEntitiesByTag(ctx, name, value, since)
+	end(span, err)
+	return entities, err
+}
+
+// UpdateEntityTag implements the Repository interface.
+func (tr *Tracing) UpdateEntityTag(ctx context.Context, id string, value string) (*types.EntityTag, error) {
+	ctx, span := tr.start(ctx, "UpdateEntityTag")
+	t, err := tr.db.UpdateEntityTag(ctx, id, value)
+	end(span, err)
+	return t, err
+}
+
+// DeleteEntityTag implements the Repository interface.
+func (tr *Tracing) DeleteEntityTag(ctx context.Context, id string) (int64, error) {
+	ctx, span := tr.start(ctx, "DeleteEntityTag")
+	n, err := tr.db.DeleteEntityTag(ctx, id)
+	end(span, err)
+	return n, err
+}
+
+// CreateEdgeTag implements the Repository interface.
+func (tr *Tracing) CreateEdgeTag(ctx context.Context, edge *types.Edge, tag *types.EdgeTag) (*types.EdgeTag, error) {
+	ctx, span := tr.start(ctx, "CreateEdgeTag")
+	t, err := tr.db.CreateEdgeTag(ctx, edge, tag)
+	end(span, err)
+	return t, err
+}
+
+// CreateEdgeProperty implements the Repository interface.
+func (tr *Tracing) CreateEdgeProperty(ctx context.Context, edge *types.Edge, property oam.Property) (*types.EdgeTag, error) {
+	ctx, span := tr.start(ctx, "CreateEdgeProperty")
+	t, err := tr.db.CreateEdgeProperty(ctx, edge, property)
+	end(span, err)
+	return t, err
+}
+
+// FindEdgeTagById implements the Repository interface.
+func (tr *Tracing) FindEdgeTagById(ctx context.Context, id string) (*types.EdgeTag, error) {
+	ctx, span := tr.start(ctx, "FindEdgeTagById")
+	t, err := tr.db.FindEdgeTagById(ctx, id)
+	end(span, err)
+	return t, err
+}
+
+// FindEdgeTagsByContent implements the Repository interface.
+func (tr *Tracing) FindEdgeTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*types.EdgeTag, error) {
+	ctx, span := tr.start(ctx, "FindEdgeTagsByContent")
+	results, err := tr.db.FindEdgeTagsByContent(ctx, prop, since)
+	end(span, err)
+	return results, err
+}
+
+// GetEdgeTags implements the Repository interface.
+func (tr *Tracing) GetEdgeTags(ctx context.Context, edge *types.Edge, since time.Time, names ...string) ([]*types.EdgeTag, error) {
+	ctx, span := tr.start(ctx, "GetEdgeTags")
+	results, err := tr.db.GetEdgeTags(ctx, edge, since, names...)
+	end(span, err)
+	return results, err
+}
+
+// GetEdgeTagsBetween implements the Repository interface.
+func (tr *Tracing) GetEdgeTagsBetween(ctx context.Context, edge *types.Edge, from, to time.Time, names ...string) ([]*types.EdgeTag, error) {
+	ctx, span := tr.start(ctx, "GetEdgeTagsBetween")
+	results, err := tr.db.GetEdgeTagsBetween(ctx, edge, from, to, names...)
+	end(span, err)
+	return results, err
+}
+
+// UpdateEdgeTag implements the Repository interface.
+func (tr *Tracing) UpdateEdgeTag(ctx context.Context, id string, value string) (*types.EdgeTag, error) {
+	ctx, span := tr.start(ctx, "UpdateEdgeTag")
+	t, err := tr.db.UpdateEdgeTag(ctx, id, value)
+	end(span, err)
+	return t, err
+}
+
+// DeleteEdgeTag implements the Repository interface.
+func (tr *Tracing) DeleteEdgeTag(ctx context.Context, id string) (int64, error) {
+	ctx, span := tr.start(ctx, "DeleteEdgeTag")
+	n, err := tr.db.DeleteEdgeTag(ctx, id)
+	end(span, err)
+	return n, err
+}
+
+// PurgeExpiredTags implements the Repository interface.
+func (tr *Tracing) PurgeExpiredTags(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tr.start(ctx, "PurgeExpiredTags")
+	n, err := tr.db.PurgeExpiredTags(ctx, before)
+	end(span, err)
+	return n, err
+}
+
+// ExportJSON implements the Repository interface.
+func (tr *Tracing) ExportJSON(ctx context.Context, w io.Writer) error {
+	ctx, span := tr.start(ctx, "ExportJSON")
+	err := tr.db.ExportJSON(ctx, w)
+	end(span, err)
+	return err
+}
+
+// ExportSubgraph implements the Repository interface.
+func (tr *Tracing) ExportSubgraph(ctx context.Context, root *types.Entity, maxDepth int, w io.Writer) error {
+	ctx, span := tr.start(ctx, "ExportSubgraph")
+	err := tr.db.ExportSubgraph(ctx, root, maxDepth, w)
+	end(span, err)
+	return err
+}
+
+// ImportJSON implements the Repository interface.
+// The import is traced as a whole, since the records are created through the wrapped repository.
+func (tr *Tracing) ImportJSON(ctx context.Context, r io.Reader) (types.ImportStats, error) {
+	ctx, span := tr.start(ctx, "ImportJSON")
+	stats, err := tr.db.ImportJSON(ctx, r)
+	end(span, err)
+	return stats, err
+}
+
+// WithTransaction implements the Repository interface.
+// The transaction is traced as a whole, and the operations made with the scoped repository are traced individually.
+// Since the scoped repository is passed without a context, those spans are children of the context provided to each call.
+func (tr *Tracing) WithTransaction(ctx context.Context, fn func(tx types.Repository) error) error {
+	ctx, span := tr.start(ctx, "WithTransaction")
+	err := tr.db.WithTransaction(ctx, func(tx types.Repository) error {
+		return fn(&Tracing{db: tx, tracer: tr.tracer, system: tr.system})
+	})
+	end(span, err)
+	return err
+}
+
+// Close implements the Repository interface.
+func (tr *Tracing) Close() error {
+	return tr.db.Close()
+}
@@ -0,0 +1,116 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/garthoid/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// stubRepository implements the few methods exercised by the tests.
+type stubRepository struct {
+	types.Repository
+	err  error
+	seen trace.SpanContext
+}
+
+func (s *stubRepository) GetDBType() string {
+	return "postgres"
+}
+
+func (s *stubRepository) CreateAsset(ctx context.Context, asset oam.Asset) (*types.Entity, error) {
+	s.seen = trace.SpanContextFromContext(ctx)
+	return &types.Entity{ID: "1", Asset: asset}, s.err
+}
+
+func (s *stubRepository) CountEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (int64, error) {
+	return 0, s.err
+}
+
+func (s *stubRepository) WithTransaction(ctx context.Context, fn func(tx types.Repository) error) error {
+	return fn(s)
+}
+
+func TestTracingImplementsRepository(t *testing.T) {
+	var _ types.Repository = (*Tracing)(nil)
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]string {
+	attrs := make(map[attribute.Key]string)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value.Emit()
+	}
+	return attrs
+}
+
+func TestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	stub := &stubRepository{}
+	tr := New(stub, tracer)
+
+	ctx, parent := tracer.Start(context.Background(), "handler")
+	_, err := tr.CreateAsset(ctx, &dns.FQDN{Name: "owasp.org"})
+	assert.NoError(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	span := spans[0]
+	assert.Equal(t, "assetdb.CreateAsset", span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	// the span is a child of the incoming context, and is propagated to the wrapped repository
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.Equal(t, span.SpanContext().SpanID(), stub.seen.SpanID())
+	assert.Equal(t, map[attribute.Key]string{
+		"db.system":           "postgresql",
+		"db.operation":        "CreateAsset",
+		"assetdb.entity.type": string(oam.FQDN),
+	}, attributes(span))
+
+	stub.err = errors.New("failed")
+	_, err = tr.CountEntitiesByType(context.Background(), oam.IPAddress, time.Time{})
+	assert.Error(t, err)
+
+	span = recorder.Ended()[2]
+	assert.Equal(t, "assetdb.CountEntitiesByType", span.Name())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Equal(t, "failed", span.Status().Description)
+	if assert.Len(t, span.Events(), 1) {
+		assert.Equal(t, "exception", span.Events()[0].Name)
+	}
+}
+
+func TestWithTransaction(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	tr := New(&stubRepository{}, tracer)
+
+	err := tr.WithTransaction(context.Background(), func(tx types.Repository) error {
+		_, ok := tx.(*Tracing)
+		assert.True(t, ok)
+		_, err := tx.CreateAsset(context.Background(), &dns.FQDN{Name: "owasp.org"})
+		return err
+	})
+	assert.NoError(t, err)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "assetdb.CreateAsset", spans[0].Name())
+		assert.Equal(t, "assetdb.WithTransaction", spans[1].Name())
+	}
+}
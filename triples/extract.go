@@ -5,6 +5,7 @@
 package triples
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/netip"
@@ -64,12 +65,12 @@ type Prop struct {
 	Property  oam.Property     `json:"property"`
 }
 
-func Extract(db repository.Repository, triples []*Triple) (*Results, error) {
+func Extract(ctx context.Context, db repository.Repository, triples []*Triple) (*Results, error) {
 	if len(triples) == 0 {
 		return nil, errors.New("no triples provided for extraction")
 	}
 
-	ent, err := findFirstSubject(db, triples[0].Subject)
+	ent, err := findFirstSubject(ctx, db, triples[0].Subject)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find first subject: %w", err)
 	}
@@ -84,7 +85,7 @@ func Extract(db repository.Repository, triples []*Triple) (*Results, error) {
 		Relations: []*Link{},
 	}
 
-	rels, err := performWalk(db, triples, 0, []*Link{{Node: n}})
+	rels, err := performWalk(ctx, db, triples, 0, []*Link{{Node: n}})
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +96,7 @@ func Extract(db repository.Repository, triples []*Triple) (*Results, error) {
 	return &Results{Node: n}, nil
 }
 
-func performWalk(db repository.Repository, triples []*Triple, idx int, links []*Link) ([]*Link, error) {
+func performWalk(ctx context.Context, db repository.Repository, triples []*Triple, idx int, links []*Link) ([]*Link, error) {
 	var rels []*Link
 	triple := triples[idx]
 
@@ -108,12 +109,12 @@ func performWalk(db repository.Repository, triples []*Triple, idx int, links []*
 			(triple.Subject.Key == "*" || valueMatch(ent.Asset.Key(), triple.Subject.Key,
 				triple.Subject.Regexp)) && allAttrsMatch(ent.Asset, triple.Subject.Attributes) {
 
-			if subjectProps, ok := entityPropsMatch(db, ent, triple.Subject.Properties); ok {
-				if entRels, err := predAndObject(db, ent, triple); err == nil && len(entRels) > 0 {
+			if subjectProps, ok := entityPropsMatch(ctx, db, ent, triple.Subject.Properties); ok {
+				if entRels, err := predAndObject(ctx, db, ent, triple); err == nil && len(entRels) > 0 {
 					var include bool
 
 					if idx+1 < len(triples) {
-						if entRels, err := performWalk(db, triples, idx+1, entRels); err == nil && len(entRels) > 0 {
+						if entRels, err := performWalk(ctx, db, triples, idx+1, entRels); err == nil && len(entRels) > 0 {
 							include = true // continue walking if there are more triples to process
 							n.Node.Relations = append(n.Node.Relations, entRels...)
 						}
@@ -138,7 +139,7 @@ func performWalk(db repository.Repository, triples []*Triple, idx int, links []*
 	return rels, err
 }
 
-func predAndObject(db repository.Repository, ent *dbt.Entity, triple *Triple) ([]*Link, error) {
+func predAndObject(ctx context.Context, db repository.Repository, ent *dbt.Entity, triple *Triple) ([]*Link, error) {
 	if ent == nil || triple == nil {
 		return nil, errors.New("entity or triple cannot be nil")
 	}
@@ -151,9 +152,9 @@ func predAndObject(db repository.Repository, ent *dbt.Entity, triple *Triple) ([
 	var err error
 	var edges []*dbt.Edge
 	if triple.Direction == DirectionIncoming {
-		edges, err = db.IncomingEdges(ent, triple.Predicate.Since, labels...)
+		edges, err = db.IncomingEdges(ctx, ent, triple.Predicate.Since, labels...)
 	} else {
-		edges, err = db.OutgoingEdges(ent, triple.Predicate.Since, labels...)
+		edges, err = db.OutgoingEdges(ctx, ent, triple.Predicate.Since, labels...)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get edges for entity %s: %v", ent.ID, err)
@@ -166,7 +167,7 @@ func predAndObject(db repository.Repository, ent *dbt.Entity, triple *Triple) ([
 			triple.Predicate.Type == edge.Relation.RelationType()) && (triple.Predicate.Label == "*" ||
 			valueMatch(edge.Relation.Label(), triple.Predicate.Label, triple.Predicate.Regexp)) &&
 			allAttrsMatch(edge.Relation, triple.Predicate.Attributes) {
-			if linkProps, ok := edgePropsMatch(db, edge, triple.Predicate.Properties); ok {
+			if linkProps, ok := edgePropsMatch(ctx, db, edge, triple.Predicate.Properties); ok {
 				var objent *dbt.Entity
 
 				if triple.Direction == DirectionIncoming {
@@ -175,14 +176,14 @@ func predAndObject(db repository.Repository, ent *dbt.Entity, triple *Triple) ([
 					objent = edge.ToEntity
 				}
 
-				if obj, err := db.FindEntityById(objent.ID); err == nil && obj != nil {
+				if obj, err := db.FindEntityById(ctx, objent.ID); err == nil && obj != nil {
 					// perform filtering based on the object in the triple and the entity asset
 					if (triple.Object.Since.IsZero() || !obj.LastSeen.Before(triple.Object.Since)) &&
 						(triple.Object.Type == "*" || triple.Object.Type == obj.Asset.AssetType()) &&
 						(triple.Object.Key == "*" || valueMatch(obj.Asset.Key(), triple.Object.Key,
 							triple.Object.Regexp)) && allAttrsMatch(obj.Asset, triple.Object.Attributes) {
 
-						if objectProps, ok := entityPropsMatch(db, obj, triple.Object.Properties); ok {
+						if objectProps, ok := entityPropsMatch(ctx, db, obj, triple.Object.Properties); ok {
 							results = append(results, &Link{
 								ID:        edge.ID,
 								Type:      edge.Relation.RelationType(),
@@ -214,7 +215,7 @@ func predAndObject(db repository.Repository, ent *dbt.Entity, triple *Triple) ([
 	return results, nil
 }
 
-func findFirstSubject(db repository.Repository, subject *Node) (*dbt.Entity, error) {
+func findFirstSubject(ctx context.Context, db repository.Repository, subject *Node) (*dbt.Entity, error) {
 	if subject == nil {
 		return nil, errors.New("subject cannot be nil")
 	}
@@ -224,7 +225,7 @@ func findFirstSubject(db repository.Repository, subject *Node) (*dbt.Entity, err
 		return nil, fmt.Errorf("failed to convert subject to asset: %v", err)
 	}
 
-	ents, err := db.FindEntitiesByContent(asset, subject.Since)
+	ents, err := db.FindEntitiesByContent(ctx, asset, subject.Since)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find the subject in the database: %v", err)
 	}
@@ -297,7 +298,7 @@ func subjectToAsset(subject *Node) (oam.Asset, error) {
 	return nil, fmt.Errorf("unknown asset type: %s", subtype)
 }
 
-func entityPropsMatch(db repository.Repository, ent *dbt.Entity, propstrs []*Property) ([]*Prop, bool) {
+func entityPropsMatch(ctx context.Context, db repository.Repository, ent *dbt.Entity, propstrs []*Property) ([]*Prop, bool) {
 	var names []string
 	for _, p := range propstrs {
 		if p.Name != "*" && p.Regexp == nil {
@@ -315,7 +316,7 @@ func entityPropsMatch(db repository.Repository, ent *dbt.Entity, propstrs []*Pro
 		}
 	}
 
-	tags, err := db.GetEntityTags(ent, since, names...)
+	tags, err := db.GetEntityTags(ctx, ent, since, names...)
 	if err != nil || len(tags) == 0 {
 		// return an empty slice if no tags are found or an error occurs
 		return []*Prop{}, len(propstrs) == 0
@@ -364,7 +365,7 @@ func entityPropsMatch(db repository.Repository, ent *dbt.Entity, propstrs []*Pro
 	return matchedProps, len(matchedProps) >= set.Len()
 }
 
-func edgePropsMatch(db repository.Repository, edge *dbt.Edge, propstrs []*Property) ([]*Prop, bool) {
+func edgePropsMatch(ctx context.Context, db repository.Repository, edge *dbt.Edge, propstrs []*Property) ([]*Prop, bool) {
 	var names []string
 	for _, p := range propstrs {
 		if p.Name != "*" && p.Regexp == nil {
@@ -382,7 +383,7 @@ func edgePropsMatch(db repository.Repository, edge *dbt.Edge, propstrs []*Proper
 		}
 	}
 
-	tags, err := db.GetEdgeTags(edge, since, names...)
+	tags, err := db.GetEdgeTags(ctx, edge, since, names...)
 	if err != nil || len(tags) == 0 {
 		// indicate failure if no tags are found or an error occurs
 		return []*Prop{}, len(propstrs) == 0
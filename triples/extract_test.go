@@ -5,6 +5,7 @@
 package triples
 
 import (
+	"context"
 	"fmt"
 	"net/netip"
 	"testing"
@@ -30,13 +31,13 @@ func TestExtract(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	// create assets and relations for testing
-	fentity, err := db.CreateAsset(oamdns.FQDN{Name: "owasp.org"})
+	fentity, err := db.CreateAsset(context.Background(), oamdns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err, "Failed to create FQDN asset")
 	assert.NotNil(t, fentity, "FQDN entity should not be nil")
-	sentity, err := db.CreateAsset(oamdns.FQDN{Name: "www.owasp.org"})
+	sentity, err := db.CreateAsset(context.Background(), oamdns.FQDN{Name: "www.owasp.org"})
 	assert.NoError(t, err, "Failed to create subdomain asset")
 	assert.NotNil(t, sentity, "Subdomain entity should not be nil")
-	edge, err := db.CreateEdge(&dbt.Edge{
+	edge, err := db.CreateEdge(context.Background(), &dbt.Edge{
 		Relation:   oamgen.SimpleRelation{Name: "node"},
 		FromEntity: fentity,
 		ToEntity:   sentity,
@@ -51,7 +52,7 @@ func TestExtract(t *testing.T) {
 	assert.Equal(t, DirectionOutgoing, triple.Direction, "Triple direction should be outgoing")
 
 	// extract associations from the database using the triple
-	results, err := Extract(db, []*Triple{triple})
+	results, err := Extract(context.Background(), db, []*Triple{triple})
 	assert.NoError(t, err, "Failed to extract associations")
 	assert.NotNil(t, results, "Results should not be nil")
 
@@ -73,7 +74,7 @@ func TestExtract(t *testing.T) {
 	assert.NotNil(t, triple, "Parsed second triple should not be nil")
 
 	// attempt to extract associations from the database using the second triple
-	results, err = Extract(db, []*Triple{triple})
+	results, err = Extract(context.Background(), db, []*Triple{triple})
 	assert.Error(t, err, "Expected an error when extracting associations with the second triple")
 	assert.Nil(t, results, "Results should be nil when an error occurs")
 
@@ -83,15 +84,15 @@ func TestExtract(t *testing.T) {
 	assert.NotNil(t, triple, "Parsed third triple should not be nil")
 
 	// attempt to extract associations from the database using the third triple
-	results, err = Extract(db, []*Triple{triple})
+	results, err = Extract(context.Background(), db, []*Triple{triple})
 	assert.Error(t, err, "Expected an error when extracting associations with the third triple")
 	assert.Nil(t, results, "Results should be nil when an error occurs")
 
 	// add a new asset and relation to the database
-	nentity, err := db.CreateAsset(oamnet.IPAddress{Address: netip.MustParseAddr("192.168.1.2")})
+	nentity, err := db.CreateAsset(context.Background(), oamnet.IPAddress{Address: netip.MustParseAddr("192.168.1.2")})
 	assert.NoError(t, err, "Failed to create IP address asset")
 	assert.NotNil(t, nentity, "IP address entity should not be nil")
-	edge, err = db.CreateEdge(&dbt.Edge{
+	edge, err = db.CreateEdge(context.Background(), &dbt.Edge{
 		Relation: oamdns.BasicDNSRelation{
 			Name: "dns_record",
 			Header: oamdns.RRHeader{
@@ -114,7 +115,7 @@ func TestExtract(t *testing.T) {
 	assert.Equal(t, DirectionOutgoing, triple.Direction, "Fourth triple direction should be outgoing")
 
 	// extract associations from the database using the fourth triple
-	results, err = Extract(db, []*Triple{triple})
+	results, err = Extract(context.Background(), db, []*Triple{triple})
 	assert.NoError(t, err, "Failed to extract associations with the fourth triple")
 	assert.NotNil(t, results, "Results should not be nil for the fourth triple")
 }
@@ -131,13 +132,13 @@ func TestPredAndObject(t *testing.T) {
 	ipstr := "192.168.1.1"
 	cidr := "192.168.1.0/24"
 	// create an asset and relations for an FQDN that resolves to an IP address
-	fentity, err := db.CreateAsset(oamdns.FQDN{Name: "owasp.org"})
+	fentity, err := db.CreateAsset(context.Background(), oamdns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err, "Failed to create FQDN asset")
 	assert.NotNil(t, fentity, "FQDN entity should not be nil")
-	ipentity, err := db.CreateAsset(oamnet.IPAddress{Address: netip.MustParseAddr(ipstr)})
+	ipentity, err := db.CreateAsset(context.Background(), oamnet.IPAddress{Address: netip.MustParseAddr(ipstr)})
 	assert.NoError(t, err, "Failed to create the fqdn asset")
 	assert.NotNil(t, ipentity, "The entity should not be nil")
-	edge1, err := db.CreateEdge(&dbt.Edge{
+	edge1, err := db.CreateEdge(context.Background(), &dbt.Edge{
 		Relation: oamdns.BasicDNSRelation{
 			Name: "dns_record",
 			Header: oamdns.RRHeader{
@@ -151,10 +152,10 @@ func TestPredAndObject(t *testing.T) {
 	})
 	assert.NoError(t, err, "Failed to create edge")
 	assert.NotNil(t, edge1, "Edge should not be nil")
-	nentity, err := db.CreateAsset(oamnet.Netblock{CIDR: netip.MustParsePrefix(cidr), Type: "IPv4"})
+	nentity, err := db.CreateAsset(context.Background(), oamnet.Netblock{CIDR: netip.MustParsePrefix(cidr), Type: "IPv4"})
 	assert.NoError(t, err, "Failed to create the netblock asset")
 	assert.NotNil(t, nentity, "The netblock entity should not be nil")
-	edge2, err := db.CreateEdge(&dbt.Edge{
+	edge2, err := db.CreateEdge(context.Background(), &dbt.Edge{
 		Relation:   oamgen.SimpleRelation{Name: "contains"},
 		FromEntity: nentity,
 		ToEntity:   ipentity,
@@ -168,7 +169,7 @@ func TestPredAndObject(t *testing.T) {
 	assert.NotNil(t, triple, "Parsed first triple should not be nil")
 
 	// test for failure when the triple does not match the associations
-	links, err := predAndObject(db, fentity, triple)
+	links, err := predAndObject(context.Background(), db, fentity, triple)
 	assert.Error(t, err, "Expect failure when the triple does not match the associations")
 	assert.Nil(t, links, "The result should be nil when an error is returned")
 
@@ -179,7 +180,7 @@ func TestPredAndObject(t *testing.T) {
 	assert.NotNil(t, triple, "Parsed second triple should not be nil")
 
 	// test for success when the triple properly matches the associations
-	links, err = predAndObject(db, fentity, triple)
+	links, err = predAndObject(context.Background(), db, fentity, triple)
 	assert.NoError(t, err, "Expected to extract an edge")
 	assert.Equal(t, 1, len(links), "Expected one edge to be returned")
 
@@ -190,7 +191,7 @@ func TestPredAndObject(t *testing.T) {
 	assert.NotNil(t, triple, "Parsed third triple should not be nil")
 
 	// test for success when the triple does match the associations
-	links, err = predAndObject(db, ipentity, triple)
+	links, err = predAndObject(context.Background(), db, ipentity, triple)
 	assert.NoError(t, err, "Expected to extract an edge")
 	assert.Equal(t, 1, len(links), "Expected one edge to be returned")
 
@@ -200,7 +201,7 @@ func TestPredAndObject(t *testing.T) {
 	assert.NotNil(t, triple, "Parsed fourth triple should not be nil")
 
 	// test for failure when the triple does not match the associations
-	links, err = predAndObject(db, ipentity, triple)
+	links, err = predAndObject(context.Background(), db, ipentity, triple)
 	assert.Error(t, err, "Expected to fail the extraction")
 	assert.Nil(t, links, "The result should be nil when an error is returned")
 }
@@ -214,17 +215,17 @@ func TestFindFirstSubject(t *testing.T) {
 
 	ipstr := "192.168.1.2"
 	// add a new asset to the database
-	nentity, err := db.CreateAsset(oamnet.IPAddress{Address: netip.MustParseAddr(ipstr)})
+	nentity, err := db.CreateAsset(context.Background(), oamnet.IPAddress{Address: netip.MustParseAddr(ipstr)})
 	assert.NoError(t, err, "Failed to create IP address asset")
 	assert.NotNil(t, nentity, "IP address entity should not be nil")
 
 	// attempt to find a nil subject in the database
-	entity, err := findFirstSubject(db, nil)
+	entity, err := findFirstSubject(context.Background(), db, nil)
 	assert.Error(t, err, "Expected an error when finding a nil subject")
 	assert.Nil(t, entity, "Entity should be nil when an error occurs")
 
 	// attempt to find an invalid subject in the database
-	entity, err = findFirstSubject(db, &Node{
+	entity, err = findFirstSubject(context.Background(), db, &Node{
 		Type: oam.IPAddress,
 		Key:  "192.168.1.TWO",
 	})
@@ -232,7 +233,7 @@ func TestFindFirstSubject(t *testing.T) {
 	assert.Nil(t, entity, "Entity should be nil when an error occurs")
 
 	// attempt to find a subject that does not exist
-	entity, err = findFirstSubject(db, &Node{
+	entity, err = findFirstSubject(context.Background(), db, &Node{
 		Type: oam.IPAddress,
 		Key:  "192.168.1.1",
 	})
@@ -240,7 +241,7 @@ func TestFindFirstSubject(t *testing.T) {
 	assert.Nil(t, entity, "Entity should be nil when an error occurs")
 
 	// attempt to find a valid subject in the database
-	entity, err = findFirstSubject(db, &Node{
+	entity, err = findFirstSubject(context.Background(), db, &Node{
 		Type: oam.IPAddress,
 		Key:  ipstr,
 	})
@@ -259,12 +260,12 @@ func TestEntityPropsMatch(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	// create an asset and property for testing
-	fentity, err := db.CreateAsset(oamdns.FQDN{Name: "owasp.org"})
+	fentity, err := db.CreateAsset(context.Background(), oamdns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err, "Failed to create FQDN asset")
 	assert.NotNil(t, fentity, "FQDN entity should not be nil")
 
 	// test for success and an empty property list when no properties are specified and none are associated
-	props, ok := entityPropsMatch(db, fentity, nil)
+	props, ok := entityPropsMatch(context.Background(), db, fentity, nil)
 	assert.True(t, ok, "Expected entity properties to match when no properties are specified")
 	assert.Equal(t, 0, len(props), "Expected no matching properties when none are associated")
 
@@ -272,16 +273,16 @@ func TestEntityPropsMatch(t *testing.T) {
 	p, err := parseProperty("[sourceproperty:test,confidence:100]")
 	assert.NoError(t, err, "Failed to parse property")
 	assert.NotNil(t, p, "Parsed property should not be nil")
-	props, ok = entityPropsMatch(db, fentity, []*Property{p})
+	props, ok = entityPropsMatch(context.Background(), db, fentity, []*Property{p})
 	assert.False(t, ok, "Expected entity properties not to match when a non-associated property is specified")
 	assert.Equal(t, 0, len(props), "Expected no matching properties when none are associated")
 
 	pname := "test"
 	// add a property to the entity and test for success when that property is specified
-	tag, err := db.CreateEntityProperty(fentity, &oamgen.SourceProperty{Source: pname, Confidence: 100})
+	tag, err := db.CreateEntityProperty(context.Background(), fentity, &oamgen.SourceProperty{Source: pname, Confidence: 100})
 	assert.NoError(t, err, "Failed to create entity property")
 	assert.NotNil(t, tag, "Entity property should not be nil")
-	props, ok = entityPropsMatch(db, fentity, []*Property{p})
+	props, ok = entityPropsMatch(context.Background(), db, fentity, []*Property{p})
 	assert.True(t, ok, "Expected entity properties to match when the associated property is specified")
 	assert.Equal(t, 1, len(props), "Expected one matching property when the associated property is specified")
 	assert.Equal(t, pname, props[0].Property.Name(), "Property name should match the associated property name")
@@ -292,10 +293,10 @@ func TestEntityPropsMatch(t *testing.T) {
 	p2, err := parseProperty("[sourceproperty:test2,confidence:100]")
 	assert.NoError(t, err, "Failed to parse second property")
 	assert.NotNil(t, p2, "Parsed second property should not be nil")
-	tag, err = db.CreateEntityProperty(fentity, &oamgen.SourceProperty{Source: pname2, Confidence: 100})
+	tag, err = db.CreateEntityProperty(context.Background(), fentity, &oamgen.SourceProperty{Source: pname2, Confidence: 100})
 	assert.NoError(t, err, "Failed to create second entity property")
 	assert.NotNil(t, tag, "Second entity property should not be nil")
-	props, ok = entityPropsMatch(db, fentity, []*Property{p, p2})
+	props, ok = entityPropsMatch(context.Background(), db, fentity, []*Property{p, p2})
 	assert.True(t, ok, "Expected entity properties to match when both associated properties are specified")
 	assert.Equal(t, 2, len(props), "Expected two matching properties when both associated properties are specified")
 
@@ -303,7 +304,7 @@ func TestEntityPropsMatch(t *testing.T) {
 	p3, err := parseProperty("[sourceproperty:#/test.*/#,confidence:100,since:" + now.Format(time.DateOnly) + "]")
 	assert.NoError(t, err, "Failed to parse third property")
 	assert.NotNil(t, p3, "Parsed third property should not be nil")
-	props, ok = entityPropsMatch(db, fentity, []*Property{p3})
+	props, ok = entityPropsMatch(context.Background(), db, fentity, []*Property{p3})
 	assert.True(t, ok, "Expected entity properties to match when the specification matches both associated properties")
 	assert.Equal(t, 2, len(props), "Expected two matching properties when the specification matches both associated properties")
 }
@@ -319,13 +320,13 @@ func TestEdgePropsMatch(t *testing.T) {
 
 	ipstr := "192.168.1.1"
 	// create an asset and relations for an FQDN that resolves to an IP address
-	fentity, err := db.CreateAsset(oamdns.FQDN{Name: "owasp.org"})
+	fentity, err := db.CreateAsset(context.Background(), oamdns.FQDN{Name: "owasp.org"})
 	assert.NoError(t, err, "Failed to create FQDN asset")
 	assert.NotNil(t, fentity, "FQDN entity should not be nil")
-	nentity, err := db.CreateAsset(oamnet.IPAddress{Address: netip.MustParseAddr(ipstr)})
+	nentity, err := db.CreateAsset(context.Background(), oamnet.IPAddress{Address: netip.MustParseAddr(ipstr)})
 	assert.NoError(t, err, "Failed to create subdomain asset")
 	assert.NotNil(t, nentity, "Subdomain entity should not be nil")
-	edge1, err := db.CreateEdge(&dbt.Edge{
+	edge1, err := db.CreateEdge(context.Background(), &dbt.Edge{
 		Relation: oamdns.BasicDNSRelation{
 			Name: "dns_record",
 			Header: oamdns.RRHeader{
@@ -341,7 +342,7 @@ func TestEdgePropsMatch(t *testing.T) {
 	assert.NotNil(t, edge1, "Edge should not be nil")
 
 	// test for success and an empty property list when no properties are specified and none are associated
-	props, ok := edgePropsMatch(db, edge1, nil)
+	props, ok := edgePropsMatch(context.Background(), db, edge1, nil)
 	assert.True(t, ok, "Expected edge properties to match when no properties are specified")
 	assert.Equal(t, 0, len(props), "Expected no matching properties when none are associated")
 
@@ -350,15 +351,15 @@ func TestEdgePropsMatch(t *testing.T) {
 	p, err := parseProperty("[sourceproperty:test,confidence:100]")
 	assert.NoError(t, err, "Failed to parse property")
 	assert.NotNil(t, p, "Parsed property should not be nil")
-	props, ok = edgePropsMatch(db, edge1, []*Property{p})
+	props, ok = edgePropsMatch(context.Background(), db, edge1, []*Property{p})
 	assert.False(t, ok, "Expected edge properties not to match when a non-associated property is specified")
 	assert.Equal(t, 0, len(props), "Expected no matching properties when none are associated")
 
 	// add a property to the edge and test for success when that property is specified
-	tag, err := db.CreateEdgeProperty(edge1, &oamgen.SourceProperty{Source: pname, Confidence: 100})
+	tag, err := db.CreateEdgeProperty(context.Background(), edge1, &oamgen.SourceProperty{Source: pname, Confidence: 100})
 	assert.NoError(t, err, "Failed to create edge property")
 	assert.NotNil(t, tag, "Edge property should not be nil")
-	props, ok = edgePropsMatch(db, edge1, []*Property{p})
+	props, ok = edgePropsMatch(context.Background(), db, edge1, []*Property{p})
 	assert.True(t, ok, "Expected edge properties to match when the associated property is specified")
 	assert.Equal(t, 1, len(props), "Expected one matching property when the associated property is specified")
 	assert.Equal(t, pname, props[0].Property.Name(), "Property name should match the associated property name")
@@ -369,10 +370,10 @@ func TestEdgePropsMatch(t *testing.T) {
 	p2, err := parseProperty("[sourceproperty:test2,confidence:100]")
 	assert.NoError(t, err, "Failed to parse second property")
 	assert.NotNil(t, p2, "Parsed second property should not be nil")
-	tag, err = db.CreateEdgeProperty(edge1, &oamgen.SourceProperty{Source: pname2, Confidence: 100})
+	tag, err = db.CreateEdgeProperty(context.Background(), edge1, &oamgen.SourceProperty{Source: pname2, Confidence: 100})
 	assert.NoError(t, err, "Failed to create second edge property")
 	assert.NotNil(t, tag, "Second edge property should not be nil")
-	props, ok = edgePropsMatch(db, edge1, []*Property{p, p2})
+	props, ok = edgePropsMatch(context.Background(), db, edge1, []*Property{p, p2})
 	assert.True(t, ok, "Expected edge properties to match when both associated properties are specified")
 	assert.Equal(t, 2, len(props), "Expected two matching properties when both associated properties are specified")
 
@@ -380,7 +381,7 @@ func TestEdgePropsMatch(t *testing.T) {
 	p3, err := parseProperty("[sourceproperty:#/test.*/#,confidence:100,since:" + now.Format(time.DateOnly) + "]")
 	assert.NoError(t, err, "Failed to parse third property")
 	assert.NotNil(t, p3, "Parsed third property should not be nil")
-	props, ok = edgePropsMatch(db, edge1, []*Property{p3})
+	props, ok = edgePropsMatch(context.Background(), db, edge1, []*Property{p3})
 	assert.True(t, ok, "Expected edge properties to match when the specification matches both associated properties")
 	assert.Equal(t, 2, len(props), "Expected two matching properties when the specification matches both associated properties")
 }
@@ -0,0 +1,79 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The sentinel errors classify the errors returned by the repositories, so that callers can use errors.Is
+// rather than matching the error text, which differs between the database backends.
+var (
+	// ErrNotFound is matched when the requested entities, edges, or tags do not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is matched when a write violates a uniqueness constraint.
+	ErrDuplicate = errors.New("duplicate")
+	// ErrConnection is matched when the database could not be reached or the connection was lost.
+	ErrConnection = errors.New("connection failed")
+	// ErrConstraint is matched when a write violates a foreign key, not-null, or check constraint.
+	ErrConstraint = errors.New("constraint violation")
+	// ErrReadOnly is matched when a write is attempted through a read-only repository.
+	ErrReadOnly = errors.New("read only")
+	// ErrPoolTimeout is matched when no connection of the pool became free within the acquire timeout.
+	ErrPoolTimeout = errors.New("pool timeout")
+)
+
+// ErrTagNotFound is returned by FindEntityTagById, FindEdgeTagById, UpdateEntityTag, and UpdateEdgeTag
+// when the tag ID does not exist. It also matches ErrNotFound.
+var ErrTagNotFound = NotFound("tag not found")
+
+// ErrEntityNotFound is returned by CreateEdge when the entity at either end of the edge does not exist,
+// or has been soft-deleted, and by UpdateEntity when the entity ID does not exist. It also matches ErrNotFound.
+var ErrEntityNotFound = NotFound("entity not found")
+
+// Error is an error of a repository that is classified by one of the sentinel errors, such as ErrNotFound.
+// The message of the wrapped error is kept, and errors.Is matches both the sentinel and the wrapped error.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the sentinel error and the wrapped error.
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// NotFound returns an error with the message that matches ErrNotFound.
+func NotFound(msg string) error {
+	return &Error{Kind: ErrNotFound, Err: errors.New(msg)}
+}
+
+// EdgeEntityNotFound returns the error of CreateEdge for the entity at the end of the edge, which is "from" or "to",
+// that does not exist. The message names the entity ID, and the error matches ErrEntityNotFound and ErrNotFound.
+func EdgeEntityNotFound(end, id string) error {
+	return &Error{Kind: ErrEntityNotFound, Err: fmt.Errorf("the %s entity %s of the edge does not exist", end, id)}
+}
+
+// Duplicate returns an error with the message that matches ErrDuplicate.
+func Duplicate(msg string) error {
+	return &Error{Kind: ErrDuplicate, Err: errors.New(msg)}
+}
+
+// MissingEntitiesError reports the entities that were skipped by a bulk operation, such as
+// CreateEntityTags, since they were not found. It is returned along with the results for the
+// entities that were found, and can be detected with errors.As.
+type MissingEntitiesError struct {
+	IDs []string
+}
+
+func (e *MissingEntitiesError) Error() string {
+	return fmt.Sprintf("%d entities were not found: %s", len(e.IDs), strings.Join(e.IDs, ", "))
+}
@@ -0,0 +1,26 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+// EventOp is the operation reported by an Event.
+type EventOp string
+
+// The operations reported by the events.
+const (
+	EventCreate EventOp = "create"
+	EventUpdate EventOp = "update"
+	EventDelete EventOp = "delete"
+)
+
+// Event reports that an entity or an edge was created, updated, or deleted, as delivered to the handler
+// set by options.WithEventHandler. Kind is EntityRecordKind or EdgeRecordKind, and Type holds the asset type
+// of the entity or the relation type of the edge. Type is empty when it is not known without another query,
+// such as for TouchEntity, and ID is empty for the deletes that only report a count, such as DeleteEntitiesByType.
+type Event struct {
+	Op   EventOp
+	Kind string
+	ID   string
+	Type string
+}
@@ -0,0 +1,139 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// The kinds of the records written by ExportJSON.
+const (
+	EntityRecordKind    = "entity"
+	EdgeRecordKind      = "edge"
+	EntityTagRecordKind = "entity_tag"
+	EdgeTagRecordKind   = "edge_tag"
+)
+
+// ExportRecord is a line of the newline-delimited JSON written by ExportJSON.
+// The Type field holds the asset, relation, or property type that the content is parsed with.
+// The IDs are those of the exporting repository, and the timestamps are in UTC.
+type ExportRecord struct {
+	Kind         string          `json:"kind"`
+	ID           string          `json:"id"`
+	Type         string          `json:"type"`
+	Content      json.RawMessage `json:"content"`
+	CreatedAt    time.Time       `json:"created_at"`
+	LastSeen     time.Time       `json:"last_seen"`
+	ExpiresAt    time.Time       `json:"expires_at,omitzero"`
+	FromEntityID string          `json:"from_entity_id,omitempty"`
+	ToEntityID   string          `json:"to_entity_id,omitempty"`
+	EntityID     string          `json:"entity_id,omitempty"`
+	EdgeID       string          `json:"edge_id,omitempty"`
+}
+
+// EntityRecord returns the export record of the entity.
+func EntityRecord(e *Entity) (*ExportRecord, error) {
+	if e == nil || e.Asset == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	content, err := e.Asset.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExportRecord{
+		Kind:      EntityRecordKind,
+		ID:        e.ID,
+		Type:      string(e.Asset.AssetType()),
+		Content:   content,
+		CreatedAt: e.CreatedAt.UTC(),
+		LastSeen:  e.LastSeen.UTC(),
+	}, nil
+}
+
+// EdgeRecord returns the export record of the edge.
+func EdgeRecord(e *Edge) (*ExportRecord, error) {
+	if e == nil || e.Relation == nil || e.FromEntity == nil || e.ToEntity == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	content, err := e.Relation.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExportRecord{
+		Kind:         EdgeRecordKind,
+		ID:           e.ID,
+		Type:         string(e.Relation.RelationType()),
+		Content:      content,
+		CreatedAt:    e.CreatedAt.UTC(),
+		LastSeen:     e.LastSeen.UTC(),
+		FromEntityID: e.FromEntity.ID,
+		ToEntityID:   e.ToEntity.ID,
+	}, nil
+}
+
+// EntityTagRecord returns the export record of the entity tag.
+func EntityTagRecord(t *EntityTag) (*ExportRecord, error) {
+	if t == nil || t.Property == nil || t.Entity == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	content, err := t.Property.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExportRecord{
+		Kind:      EntityTagRecordKind,
+		ID:        t.ID,
+		Type:      string(t.Property.PropertyType()),
+		Content:   content,
+		CreatedAt: t.CreatedAt.UTC(),
+		LastSeen:  t.LastSeen.UTC(),
+		ExpiresAt: t.ExpiresAt.UTC(),
+		EntityID:  t.Entity.ID,
+	}, nil
+}
+
+// EdgeTagRecord returns the export record of the edge tag.
+func EdgeTagRecord(t *EdgeTag) (*ExportRecord, error) {
+	if t == nil || t.Property == nil || t.Edge == nil {
+		return nil, errors.New("failed input validation checks")
+	}
+
+	content, err := t.Property.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExportRecord{
+		Kind:      EdgeTagRecordKind,
+		ID:        t.ID,
+		Type:      string(t.Property.PropertyType()),
+		Content:   content,
+		CreatedAt: t.CreatedAt.UTC(),
+		LastSeen:  t.LastSeen.UTC(),
+		ExpiresAt: t.ExpiresAt.UTC(),
+		EdgeID:    t.Edge.ID,
+	}, nil
+}
+
+// ImportStats counts the records read by ImportJSON, by kind. A record is skipped when the
+// repository already holds a matching entity, edge, or tag, so a repeated import creates nothing.
+type ImportStats struct {
+	EntitiesCreated   int
+	EntitiesSkipped   int
+	EdgesCreated      int
+	EdgesSkipped      int
+	EntityTagsCreated int
+	EntityTagsSkipped int
+	EdgeTagsCreated   int
+	EdgeTagsSkipped   int
+}
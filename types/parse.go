@@ -0,0 +1,222 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/account"
+	oamtls "github.com/owasp-amass/open-asset-model/certificate"
+	"github.com/owasp-amass/open-asset-model/contact"
+	"github.com/owasp-amass/open-asset-model/dns"
+	oamfile "github.com/owasp-amass/open-asset-model/file"
+	"github.com/owasp-amass/open-asset-model/financial"
+	"github.com/owasp-amass/open-asset-model/general"
+	"github.com/owasp-amass/open-asset-model/network"
+	"github.com/owasp-amass/open-asset-model/org"
+	"github.com/owasp-amass/open-asset-model/people"
+	"github.com/owasp-amass/open-asset-model/platform"
+	oamreg "github.com/owasp-amass/open-asset-model/registration"
+	"github.com/owasp-amass/open-asset-model/url"
+)
+
+// ParseAsset parses the JSON content into the Open Asset Model (OAM) asset of the provided type.
+// It returns the parsed asset and an error, if any.
+func ParseAsset(atype string, content []byte) (oam.Asset, error) {
+	var err error
+	var asset oam.Asset
+
+	switch atype {
+	case string(oam.Account):
+		var a account.Account
+
+		err = json.Unmarshal(content, &a)
+		asset = &a
+	case string(oam.AutnumRecord):
+		var ar oamreg.AutnumRecord
+
+		err = json.Unmarshal(content, &ar)
+		asset = &ar
+	case string(oam.AutonomousSystem):
+		var as network.AutonomousSystem
+
+		err = json.Unmarshal(content, &as)
+		asset = &as
+	case string(oam.ContactRecord):
+		var cr contact.ContactRecord
+
+		err = json.Unmarshal(content, &cr)
+		asset = &cr
+	case string(oam.DomainRecord):
+		var dr oamreg.DomainRecord
+
+		err = json.Unmarshal(content, &dr)
+		asset = &dr
+	case string(oam.File):
+		var f oamfile.File
+
+		err = json.Unmarshal(content, &f)
+		asset = &f
+	case string(oam.FQDN):
+		var fqdn dns.FQDN
+
+		err = json.Unmarshal(content, &fqdn)
+		asset = &fqdn
+	case string(oam.FundsTransfer):
+		var ft financial.FundsTransfer
+
+		err = json.Unmarshal(content, &ft)
+		asset = &ft
+	case string(oam.Identifier):
+		var id general.Identifier
+
+		err = json.Unmarshal(content, &id)
+		asset = &id
+	case string(oam.IPAddress):
+		var ip network.IPAddress
+
+		err = json.Unmarshal(content, &ip)
+		asset = &ip
+	case string(oam.IPNetRecord):
+		var ipnetrec oamreg.IPNetRecord
+
+		err = json.Unmarshal(content, &ipnetrec)
+		asset = &ipnetrec
+	case string(oam.Location):
+		var location contact.Location
+
+		err = json.Unmarshal(content, &location)
+		asset = &location
+	case string(oam.Netblock):
+		var netblock network.Netblock
+
+		err = json.Unmarshal(content, &netblock)
+		asset = &netblock
+	case string(oam.Organization):
+		var organization org.Organization
+
+		err = json.Unmarshal(content, &organization)
+		asset = &organization
+	case string(oam.Person):
+		var person people.Person
+
+		err = json.Unmarshal(content, &person)
+		asset = &person
+	case string(oam.Phone):
+		var phone contact.Phone
+
+		err = json.Unmarshal(content, &phone)
+		asset = &phone
+	case string(oam.Product):
+		var p platform.Product
+
+		err = json.Unmarshal(content, &p)
+		asset = &p
+	case string(oam.ProductRelease):
+		var pr platform.ProductRelease
+
+		err = json.Unmarshal(content, &pr)
+		asset = &pr
+	case string(oam.Service):
+		var serv platform.Service
+
+		err = json.Unmarshal(content, &serv)
+		asset = &serv
+	case string(oam.TLSCertificate):
+		var tlsCertificate oamtls.TLSCertificate
+
+		err = json.Unmarshal(content, &tlsCertificate)
+		asset = &tlsCertificate
+	case string(oam.URL):
+		var url url.URL
+
+		err = json.Unmarshal(content, &url)
+		asset = &url
+	default:
+		return nil, fmt.Errorf("unknown asset type: %s", atype)
+	}
+
+	return asset, err
+}
+
+// ParseRelation parses the JSON content into the Open Asset Model (OAM) relation of the provided type.
+// It returns the parsed relation and an error, if any.
+func ParseRelation(rtype string, content []byte) (oam.Relation, error) {
+	var err error
+	var rel oam.Relation
+
+	switch rtype {
+	case string(oam.BasicDNSRelation):
+		var bdr dns.BasicDNSRelation
+
+		err = json.Unmarshal(content, &bdr)
+		rel = &bdr
+	case string(oam.PortRelation):
+		var pr general.PortRelation
+
+		err = json.Unmarshal(content, &pr)
+		rel = &pr
+	case string(oam.PrefDNSRelation):
+		var pdr dns.PrefDNSRelation
+
+		err = json.Unmarshal(content, &pdr)
+		rel = &pdr
+	case string(oam.SimpleRelation):
+		var sr general.SimpleRelation
+
+		err = json.Unmarshal(content, &sr)
+		rel = &sr
+	case string(oam.SRVDNSRelation):
+		var sdr dns.SRVDNSRelation
+
+		err = json.Unmarshal(content, &sdr)
+		rel = &sdr
+	default:
+		return nil, fmt.Errorf("unknown relation type: %s", rtype)
+	}
+
+	return rel, err
+}
+
+// ParseProperty parses the JSON content into the Open Asset Model (OAM) property of the provided type.
+// It returns the parsed property and an error, if any.
+func ParseProperty(ptype string, content []byte) (oam.Property, error) {
+	var err error
+	var prop oam.Property
+
+	switch ptype {
+	case string(CachePropertyType):
+		var cp CacheProperty
+
+		err = json.Unmarshal(content, &cp)
+		prop = &cp
+	case string(oam.DNSRecordProperty):
+		var dp dns.DNSRecordProperty
+
+		err = json.Unmarshal(content, &dp)
+		prop = &dp
+	case string(oam.SimpleProperty):
+		var sp general.SimpleProperty
+
+		err = json.Unmarshal(content, &sp)
+		prop = &sp
+	case string(oam.SourceProperty):
+		var sp general.SourceProperty
+
+		err = json.Unmarshal(content, &sp)
+		prop = &sp
+	case string(oam.VulnProperty):
+		var vp platform.VulnProperty
+
+		err = json.Unmarshal(content, &vp)
+		prop = &vp
+	default:
+		return nil, fmt.Errorf("unknown property type: %s", ptype)
+	}
+
+	return prop, err
+}
@@ -0,0 +1,70 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"fmt"
+	"strconv"
+
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/dns"
+	"github.com/owasp-amass/open-asset-model/general"
+	"github.com/owasp-amass/open-asset-model/platform"
+)
+
+// WithValue returns a copy of the property with the field reported by Value set to the value.
+// The value of a SourceProperty is the confidence, which must be an integer.
+func WithValue(prop oam.Property, value string) (oam.Property, error) {
+	switch p := prop.(type) {
+	case *general.SimpleProperty:
+		c := *p
+		c.PropertyValue = value
+		return &c, nil
+	case *general.SourceProperty:
+		confidence, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("the confidence of a source property must be an integer: %w", err)
+		}
+		c := *p
+		c.Confidence = confidence
+		return &c, nil
+	case *dns.DNSRecordProperty:
+		c := *p
+		c.Data = value
+		return &c, nil
+	case *platform.VulnProperty:
+		c := *p
+		c.Description = value
+		return &c, nil
+	case *CacheProperty:
+		c := *p
+		c.RefID = value
+		return &c, nil
+	}
+	return nil, fmt.Errorf("unsupported property type: %T", prop)
+}
+
+// NamedProperties returns a property of each Open Asset Model property type with the name, which allows the
+// repositories to match tags by name across the property types. When the value is not empty, it is set on each
+// property, and the property types that cannot hold the value, such as a non-integer confidence, are omitted.
+func NamedProperties(name, value string) []oam.Property {
+	props := []oam.Property{
+		&general.SimpleProperty{PropertyName: name},
+		&general.SourceProperty{Source: name},
+		&dns.DNSRecordProperty{PropertyName: name},
+		&platform.VulnProperty{ID: name},
+	}
+	if value == "" {
+		return props
+	}
+
+	var results []oam.Property
+	for _, prop := range props {
+		if p, err := WithValue(prop, value); err == nil {
+			results = append(results, p)
+		}
+	}
+	return results
+}
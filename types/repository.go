@@ -0,0 +1,123 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"context"
+	"io"
+	"time"
+
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// Repository defines the methods for interacting with the asset database.
+// It provides operations for creating, retrieving, tagging, and linking assets.
+// Each operation accepts a context.Context that can be used to cancel the call or enforce a deadline.
+// The Delete methods return the number of entities, edges, or tags that were deleted.
+// The Between methods filter on the last seen time within the [from, to) window, where a zero time leaves that side open.
+// The GetEntityTags and GetEdgeTags methods exclude the expired tags, unless the repository was opened with options.WithExpiredTags.
+type Repository interface {
+	GetDBType() string
+	Ping(ctx context.Context) error
+	Maintenance(ctx context.Context) error
+	Stats(ctx context.Context) (*DBStats, error)
+	CreateEntity(ctx context.Context, entity *Entity) (*Entity, error)
+	CreateAsset(ctx context.Context, asset oam.Asset) (*Entity, error)
+	CreateEntities(ctx context.Context, entities []*Entity) ([]*Entity, error)
+	UpsertEntity(ctx context.Context, entity *Entity) (*Entity, UpsertResult, error)
+	UpdateEntity(ctx context.Context, id string, asset oam.Asset) (*Entity, error)
+	TouchEntity(ctx context.Context, id string) error
+	FindEntityById(ctx context.Context, id string) (*Entity, error)
+	FindEntitiesByContent(ctx context.Context, asset oam.Asset, since time.Time) ([]*Entity, error)
+	FindEntitiesByContents(ctx context.Context, assets []oam.Asset, since time.Time) (map[string]*Entity, error)
+	FindEntityByHash(ctx context.Context, hash string) (*Entity, error)
+	EntityExists(ctx context.Context, asset oam.Asset) (bool, error)
+	FindEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) ([]*Entity, error)
+	FindEntitiesByTypes(ctx context.Context, since time.Time, atypes ...oam.AssetType) ([]*Entity, error)
+	FindEntitiesByTypeBetween(ctx context.Context, atype oam.AssetType, from, to time.Time) ([]*Entity, error)
+	DiffEntities(ctx context.Context, atype oam.AssetType, t1, t2 time.Time) ([]*Entity, []*Entity, error)
+	SearchEntities(ctx context.Context, atype oam.AssetType, query string, since time.Time) ([]*Entity, error)
+	FindEntitiesByTypePaged(ctx context.Context, atype oam.AssetType, since time.Time, offset, limit int) ([]*Entity, int64, error)
+	IterateEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (EntityIterator, error)
+	CountEntitiesByType(ctx context.Context, atype oam.AssetType, since time.Time) (int64, error)
+	CountEntitiesGrouped(ctx context.Context, since time.Time) (map[oam.AssetType]int64, error)
+	DeleteEntity(ctx context.Context, id string) (int64, error)
+	DeleteEntityCascade(ctx context.Context, id string) (int64, error)
+	DeleteEntitiesByType(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error)
+	FindOrphanEntities(ctx context.Context, atype oam.AssetType, since time.Time) ([]*Entity, error)
+	DeleteOrphanEntities(ctx context.Context, atype oam.AssetType, before time.Time) (int64, error)
+	FindDeletedEntities(ctx context.Context, since time.Time) ([]*Entity, error)
+	PurgeDeleted(ctx context.Context, before time.Time) error
+	CreateEdge(ctx context.Context, edge *Edge) (*Edge, error)
+	UpsertEdge(ctx context.Context, edge *Edge) (*Edge, UpsertResult, error)
+	FindEdgeById(ctx context.Context, id string) (*Edge, error)
+	IncomingEdges(ctx context.Context, entity *Entity, since time.Time, labels ...string) ([]*Edge, error)
+	OutgoingEdges(ctx context.Context, entity *Entity, since time.Time, labels ...string) ([]*Edge, error)
+	IncomingEdgesBetween(ctx context.Context, entity *Entity, from, to time.Time, labels ...string) ([]*Edge, error)
+	OutgoingEdgesBetween(ctx context.Context, entity *Entity, from, to time.Time, labels ...string) ([]*Edge, error)
+	IncomingEdgesForAll(ctx context.Context, entities []*Entity, since time.Time, labels ...string) (map[string][]*Edge, error)
+	OutgoingEdgesForAll(ctx context.Context, entities []*Entity, since time.Time, labels ...string) (map[string][]*Edge, error)
+	Neighborhood(ctx context.Context, entity *Entity, maxDepth int, since time.Time, labels ...string) ([]*Entity, []*Edge, error)
+	FindEdgesByLabel(ctx context.Context, label string, since time.Time) ([]*Edge, error)
+	FindEdgesSince(ctx context.Context, since time.Time, includeTags bool) ([]*Edge, error)
+	ResolveEdgeEndpoints(ctx context.Context, edges []*Edge) (map[string]*Entity, error)
+	CountEdges(ctx context.Context, since time.Time) (int64, error)
+	EntityDegree(ctx context.Context, entity *Entity, since time.Time) (int64, int64, error)
+	DeleteEdge(ctx context.Context, id string) (int64, error)
+	CreateEntityTag(ctx context.Context, entity *Entity, tag *EntityTag) (*EntityTag, error)
+	CreateEntityTags(ctx context.Context, entityIDs []string, tag *EntityTag) ([]*EntityTag, error)
+	CreateEntityProperty(ctx context.Context, entity *Entity, property oam.Property) (*EntityTag, error)
+	FindEntityTagById(ctx context.Context, id string) (*EntityTag, error)
+	FindEntityTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*EntityTag, error)
+	GetEntityTags(ctx context.Context, entity *Entity, since time.Time, names ...string) ([]*EntityTag, error)
+	GetEntityTagsBetween(ctx context.Context, entity *Entity, from, to time.Time, names ...string) ([]*EntityTag, error)
+	GetEntityTagsMatching(ctx context.Context, entity *Entity, since time.Time, name, value string) ([]*EntityTag, error)
+	FindEntitiesByTag(ctx context.Context, name, value string, since time.Time) ([]*Entity, error)
+	UpdateEntityTag(ctx context.Context, id string, value string) (*EntityTag, error)
+	DeleteEntityTag(ctx context.Context, id string) (int64, error)
+	CreateEdgeTag(ctx context.Context, edge *Edge, tag *EdgeTag) (*EdgeTag, error)
+	CreateEdgeProperty(ctx context.Context, edge *Edge, property oam.Property) (*EdgeTag, error)
+	FindEdgeTagById(ctx context.Context, id string) (*EdgeTag, error)
+	FindEdgeTagsByContent(ctx context.Context, prop oam.Property, since time.Time) ([]*EdgeTag, error)
+	GetEdgeTags(ctx context.Context, edge *Edge, since time.Time, names ...string) ([]*EdgeTag, error)
+	GetEdgeTagsBetween(ctx context.Context, edge *Edge, from, to time.Time, names ...string) ([]*EdgeTag, error)
+	UpdateEdgeTag(ctx context.Context, id string, value string) (*EdgeTag, error)
+	DeleteEdgeTag(ctx context.Context, id string) (int64, error)
+	PurgeExpiredTags(ctx context.Context, before time.Time) (int64, error)
+	ExportJSON(ctx context.Context, w io.Writer) error
+	ExportSubgraph(ctx context.Context, root *Entity, maxDepth int, w io.Writer) error
+	ImportJSON(ctx context.Context, r io.Reader) (ImportStats, error)
+	WithTransaction(ctx context.Context, fn func(tx Repository) error) error
+	Close() error
+}
+
+// RawQuerier is implemented by the SQL repositories, and runs a query that the Repository methods do not support.
+// The query is passed to the database as written, with the args bound to its placeholders, so it is not checked
+// against the schema, and a query that modifies the data bypasses the invariants kept by the Repository methods.
+type RawQuerier interface {
+	Raw(ctx context.Context, query string, args ...interface{}) (Rows, error)
+}
+
+// Subscriber is implemented by the Postgres repository, and delivers the events of the changes made to the entities and
+// edges by any process connected to the database, as notified by the triggers added by the schema migrations. The
+// channel is closed once the context is done.
+type Subscriber interface {
+	Subscribe(ctx context.Context) (<-chan Event, error)
+}
+
+// Explainer is implemented by the SQL and Neo4j repositories, and returns the plans of the queries issued by a read
+// method of the Repository, such as FindEntitiesByContent, called with the args that follow the context. The plans
+// are produced by running the queries with EXPLAIN ANALYZE on Postgres and MySQL, EXPLAIN QUERY PLAN on SQLite, and
+// PROFILE on Neo4j, so the missing indexes that lead to full scans can be found.
+type Explainer interface {
+	Explain(ctx context.Context, method string, args ...interface{}) (string, error)
+}
+
+// CypherQuerier is implemented by the Neo4j repository, and runs a Cypher query that the Repository methods do not
+// support. The query is passed to the database as written, with the params bound to its parameters, so it is not
+// checked against the schema, and a query that modifies the data bypasses the invariants kept by the Repository methods.
+type CypherQuerier interface {
+	RawCypher(ctx context.Context, cypher string, params map[string]interface{}) (Rows, error)
+}
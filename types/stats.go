@@ -0,0 +1,25 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"time"
+
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// DBStats is a snapshot of the contents of a repository returned by Stats, such as for a status endpoint.
+// The asset types and labels without records are absent from the maps. SizeBytes is the size of the database
+// on disk, and is zero when the database does not report it. OldestCreatedAt and NewestLastSeen are zero
+// when the repository holds no entities.
+type DBStats struct {
+	EntitiesByType  map[oam.AssetType]int64
+	EdgesByLabel    map[string]int64
+	EntityTags      int64
+	EdgeTags        int64
+	SizeBytes       int64
+	OldestCreatedAt time.Time
+	NewestLastSeen  time.Time
+}
@@ -5,29 +5,42 @@
 package types
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	oam "github.com/owasp-amass/open-asset-model"
 )
 
 // Entity represents an entity in the asset database.
+// DeletedAt is only set for the soft-deleted entities returned by FindDeletedEntities.
 type Entity struct {
 	ID        string
 	CreatedAt time.Time
 	LastSeen  time.Time
+	DeletedAt time.Time
 	Asset     oam.Asset
 }
 
 // EntityTag represents additional metadata added to an entity in the asset database.
+// ExpiresAt is the time at which the tag expires, and a zero time means that the tag does not expire.
 type EntityTag struct {
 	ID        string
 	CreatedAt time.Time
 	LastSeen  time.Time
+	ExpiresAt time.Time
 	Property  oam.Property
 	Entity    *Entity
 }
 
+// Expired reports whether the tag has expired at the provided time.
+func (t *EntityTag) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !t.ExpiresAt.After(now)
+}
+
 // Edge represents a relationship between two entities in the asset database.
+// Tags is only set by FindEdgesSince when the tags are requested, and is nil otherwise.
 type Edge struct {
 	ID         string
 	CreatedAt  time.Time
@@ -35,13 +48,71 @@ type Edge struct {
 	Relation   oam.Relation
 	FromEntity *Entity
 	ToEntity   *Entity
+	Tags       []*EdgeTag
 }
 
 // EdgeTag represents additional metadata added to an edge in the asset database.
+// ExpiresAt is the time at which the tag expires, and a zero time means that the tag does not expire.
 type EdgeTag struct {
 	ID        string
 	CreatedAt time.Time
 	LastSeen  time.Time
+	ExpiresAt time.Time
 	Property  oam.Property
 	Edge      *Edge
 }
+
+// Expired reports whether the tag has expired at the provided time.
+func (t *EdgeTag) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !t.ExpiresAt.After(now)
+}
+
+// EntityIterator streams entities from the asset database one at a time.
+// Next must be called before the first entity is accessed, and Close must
+// be called to release the underlying resources once iteration is complete.
+type EntityIterator interface {
+	Next() bool
+	Entity() *Entity
+	Err() error
+	Close() error
+}
+
+// Rows streams the rows returned by a raw query one at a time, such as from the Raw method of the SQL
+// repositories or the RawCypher method of the Neo4j repository. Columns returns the names of the columns,
+// and Values returns the values of the current row in the same order, as provided by the database driver.
+// Next must be called before the first row is accessed, and Close must be called to release the
+// underlying resources once iteration is complete.
+type Rows interface {
+	Columns() []string
+	Next() bool
+	Values() []interface{}
+	Err() error
+	Close() error
+}
+
+// ContentHash returns the key of the asset in the results of FindEntitiesByContents, which is also
+// the hash stored with each entity and looked up by FindEntityByHash. Assets have the same content
+// hash when they share the asset type and key, which is how the repositories match the content of the entities.
+func ContentHash(asset oam.Asset) string {
+	sum := sha256.Sum256([]byte(string(asset.AssetType()) + ":" + asset.Key()))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashAsset returns the content hash that CreateEntity stores with the entity of the asset, without a database,
+// so the assets of a pipeline can be deduplicated before they are written. The hash is computed as by ContentHash,
+// and the configured marshaler does not affect it. Returns an error when the asset is nil.
+func HashAsset(asset oam.Asset) (string, error) {
+	if asset == nil {
+		return "", errors.New("failed input validation checks")
+	}
+	return ContentHash(asset), nil
+}
+
+// ContentHash returns the content hash of the entity's asset, which is stored with the entity
+// and can be looked up with FindEntityByHash. Returns an empty string when the asset is nil.
+func (e *Entity) ContentHash() string {
+	if e == nil || e.Asset == nil {
+		return ""
+	}
+	return ContentHash(e.Asset)
+}
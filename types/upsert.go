@@ -0,0 +1,19 @@
+// Copyright © by Jeff Foley 2017-2025. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+// UpsertResult is the outcome of UpsertEntity and UpsertEdge, which is empty when the upsert fails.
+type UpsertResult string
+
+// The outcomes reported by the upserts.
+const (
+	// UpsertCreated reports that the entity or edge did not exist, and was created.
+	UpsertCreated UpsertResult = "created"
+	// UpsertUpdated reports that the entity or edge existed with different content, which was replaced,
+	// or that a soft-deleted entity with the same identifying content was restored.
+	UpsertUpdated UpsertResult = "updated"
+	// UpsertUnchanged reports that the entity or edge existed with the same content, so only the last seen time moved.
+	UpsertUnchanged UpsertResult = "unchanged"
+)